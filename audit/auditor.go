@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"errors"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Auditor records every event its handlers process to a Sink, to answer
+// "did we handle this event?" after the fact. Wrap each handler with it:
+//
+//	listener.RegisterHandler("ThingHappened", auditor.Handler("sendReceipt", sendReceipt))
+//
+// Records are written after the handler returns, before the event is
+// deleted or requeued, so a slow sink slows down processing. Failing to
+// write a record never fails the handler; the error goes to OnError.
+// Events that no handler is registered for aren't recorded.
+type Auditor struct {
+	sink    Sink
+	onError gomainevents.ErrorHandler
+
+	// Hook for tests
+	now func() time.Time
+}
+
+type Config struct {
+	// Where records are written. Required
+	Sink Sink
+
+	// Called when a record can't be written. Optional
+	OnError gomainevents.ErrorHandler
+}
+
+func NewAuditor(config *Config) (*Auditor, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Sink {
+		return nil, errors.New("Sink is required")
+	}
+
+	return &Auditor{
+		sink:    config.Sink,
+		onError: config.OnError,
+		now:     time.Now,
+	}, nil
+}
+
+// Handler wraps an EventHandler, recording each event it processes under
+// the given handler name.
+func (a *Auditor) Handler(name string, fn gomainevents.EventHandler) gomainevents.EventHandler {
+	return func(event gomainevents.Event) error {
+		start := a.now()
+		err := fn(event)
+
+		record := Record{
+			EventID:    EventID(event),
+			EventName:  event.Name(),
+			Handler:    name,
+			Outcome:    OutcomeHandled,
+			RetryCount: retryCount(event),
+			StartedAt:  start,
+			Duration:   a.now().Sub(start),
+		}
+
+		if err != nil {
+			record.Outcome = OutcomeFailed
+			record.Error = err.Error()
+		}
+
+		if sinkErr := a.sink.Record(record); sinkErr != nil && nil != a.onError {
+			a.onError(sinkErr)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	id         string
+	retryCount int
+}
+
+func (e testEvent) Name() string {
+	return "Domain\\Event"
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func (e testEvent) MessageID() string {
+	return e.id
+}
+
+func (e testEvent) RetryCount() int {
+	return e.retryCount
+}
+
+type numberedEvent struct{}
+
+func (e numberedEvent) Name() string {
+	return "Domain\\Event"
+}
+
+func (e numberedEvent) Data() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (e numberedEvent) ID() int64 {
+	return 12
+}
+
+type wrappedEvent struct {
+	gomainevents.Event
+}
+
+func (e wrappedEvent) Unwrap() gomainevents.Event {
+	return e.Event
+}
+
+type recorder struct {
+	records []Record
+	err     error
+}
+
+func (r *recorder) Record(record Record) error {
+	r.records = append(r.records, record)
+	return r.err
+}
+
+func newTestAuditor(sink Sink, onError gomainevents.ErrorHandler) *Auditor {
+	auditor, _ := NewAuditor(&Config{Sink: sink, OnError: onError})
+
+	now := time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+	auditor.now = func() time.Time {
+		now = now.Add(250 * time.Millisecond)
+		return now
+	}
+
+	return auditor
+}
+
+func TestNewAuditor(t *testing.T) {
+	auditor, err := NewAuditor(nil)
+	assert.Nil(t, auditor)
+	assert.NotNil(t, err)
+
+	auditor, err = NewAuditor(&Config{})
+	assert.Nil(t, auditor)
+	assert.NotNil(t, err)
+
+	auditor, err = NewAuditor(&Config{Sink: &recorder{}})
+	assert.NotNil(t, auditor)
+	assert.Nil(t, err)
+}
+
+func TestAuditorRecordsHandledEvents(t *testing.T) {
+	sink := &recorder{}
+	auditor := newTestAuditor(sink, nil)
+
+	handler := auditor.Handler("sendReceipt", func(gomainevents.Event) error { return nil })
+	require.Nil(t, handler(testEvent{id: "1234", retryCount: 2}))
+
+	assert.Equal(t, []Record{{
+		EventID:    "1234",
+		EventName:  "Domain\\Event",
+		Handler:    "sendReceipt",
+		Outcome:    OutcomeHandled,
+		RetryCount: 2,
+		StartedAt:  time.Date(2018, 3, 8, 11, 11, 11, int(250*time.Millisecond), time.UTC),
+		Duration:   250 * time.Millisecond,
+	}}, sink.records)
+}
+
+func TestAuditorRecordsFailures(t *testing.T) {
+	sink := &recorder{}
+	auditor := newTestAuditor(sink, nil)
+
+	handler := auditor.Handler("sendReceipt", func(gomainevents.Event) error { return errors.New("Nope") })
+	assert.EqualError(t, handler(testEvent{id: "1234"}), "Nope")
+
+	require.Len(t, sink.records, 1)
+	assert.Equal(t, OutcomeFailed, sink.records[0].Outcome)
+	assert.Equal(t, "Nope", sink.records[0].Error)
+}
+
+func TestAuditorReportsSinkErrors(t *testing.T) {
+	sink := &recorder{err: errors.New("Disk full")}
+	reported := []error{}
+	auditor := newTestAuditor(sink, func(err error) { reported = append(reported, err) })
+
+	// The handler's outcome stands
+	handler := auditor.Handler("sendReceipt", func(gomainevents.Event) error { return nil })
+	assert.Nil(t, handler(testEvent{}))
+	assert.Equal(t, []error{sink.err}, reported)
+
+	// Reporting is optional
+	auditor = newTestAuditor(sink, nil)
+	assert.Nil(t, auditor.Handler("sendReceipt", func(gomainevents.Event) error { return nil })(testEvent{}))
+}
+
+func TestEventID(t *testing.T) {
+	assert.Equal(t, "1234", EventID(testEvent{id: "1234"}))
+	assert.Equal(t, "1234", EventID(wrappedEvent{testEvent{id: "1234"}}))
+	assert.Equal(t, "12", EventID(numberedEvent{}))
+	assert.Equal(t, "", EventID(recordEvent{}))
+
+	assert.Equal(t, 3, retryCount(wrappedEvent{testEvent{retryCount: 3}}))
+	assert.Equal(t, 0, retryCount(recordEvent{}))
+}
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Outcome is how handling an event turned out.
+type Outcome string
+
+const (
+	OutcomeHandled Outcome = "handled"
+	OutcomeFailed  Outcome = "failed"
+)
+
+// Record is an audit entry for one handler processing one event.
+type Record struct {
+	// ID the provider gave the event, if it has one. See EventID.
+	EventID   string
+	EventName string
+
+	// Name the handler was wrapped with.
+	Handler string
+
+	Outcome Outcome
+
+	// Message of the error the handler returned, if it failed.
+	Error string
+
+	// Times the event was delivered before, if the provider keeps count.
+	RetryCount int
+
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+// Data returns the record as event data, with the duration in milliseconds.
+// Sinks that write JSON use the same fields.
+func (r Record) Data() map[string]interface{} {
+	data := map[string]interface{}{
+		"eventName":  r.EventName,
+		"handler":    r.Handler,
+		"outcome":    string(r.Outcome),
+		"retryCount": r.RetryCount,
+		"startedAt":  r.StartedAt.UTC().Format(time.RFC3339Nano),
+		"durationMs": durationMs(r.Duration),
+	}
+
+	if "" != r.EventID {
+		data["eventId"] = r.EventID
+	}
+
+	if "" != r.Error {
+		data["error"] = r.Error
+	}
+
+	return data
+}
+
+// EventID returns the ID the provider gave an event. See
+// gomainevents.EventID.
+func EventID(event gomainevents.Event) string {
+	return gomainevents.EventID(event)
+}
+
+// retryCount returns how many times an event was delivered before, if its
+// provider keeps count.
+func retryCount(event gomainevents.Event) int {
+	for nil != event {
+		if counted, ok := event.(interface{ RetryCount() int }); ok {
+			return counted.RetryCount()
+		}
+
+		wrapper, ok := event.(interface{ Unwrap() gomainevents.Event })
+		if !ok {
+			break
+		}
+
+		event = wrapper.Unwrap()
+	}
+
+	return 0
+}
+
+func durationMs(d time.Duration) float64 {
+	return d.Seconds() * 1000
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// defaultEventName is the name records are published under.
+const defaultEventName = "GomaineventsAuditRecord"
+
+// Sink stores audit records.
+type Sink interface {
+	Record(Record) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(Record) error
+
+func (fn SinkFunc) Record(record Record) error {
+	return fn(record)
+}
+
+// WriterSink writes records as JSON, one per line, e.g. to a log stream.
+type WriterSink struct {
+	writer io.Writer
+
+	// Guards writes to a writer that may not be safe to share.
+	mu sync.Mutex
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{writer: w}
+}
+
+func (s *WriterSink) Record(record Record) error {
+	line, err := json.Marshal(record.Data())
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.writer.Write(append(line, '\n'))
+
+	return err
+}
+
+// PublisherSink publishes records as events, so that they can be stored
+// by any Publisher, e.g. archived to S3 with s3archive.
+type PublisherSink struct {
+	publisher gomainevents.Publisher
+	eventName string
+}
+
+// NewPublisherSink returns a sink that publishes records as events named
+// eventName, or "GomaineventsAuditRecord" if it's empty. Don't publish them
+// somewhere the audited Listener reads from.
+func NewPublisherSink(publisher gomainevents.Publisher, eventName string) *PublisherSink {
+	if "" == eventName {
+		eventName = defaultEventName
+	}
+
+	return &PublisherSink{publisher: publisher, eventName: eventName}
+}
+
+func (s *PublisherSink) Record(record Record) error {
+	return s.publisher.Publish(recordEvent{name: s.eventName, record: record})
+}
+
+type recordEvent struct {
+	name   string
+	record Record
+}
+
+func (e recordEvent) Name() string {
+	return e.name
+}
+
+func (e recordEvent) Data() map[string]interface{} {
+	return e.record.Data()
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRecord = Record{
+	EventID:    "1234",
+	EventName:  "Domain\\Event",
+	Handler:    "sendReceipt",
+	Outcome:    OutcomeFailed,
+	Error:      "Nope",
+	RetryCount: 2,
+	StartedAt:  time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC),
+	Duration:   1500 * time.Microsecond,
+}
+
+func TestWriterSink(t *testing.T) {
+	out := &bytes.Buffer{}
+	sink := NewWriterSink(out)
+
+	require.Nil(t, sink.Record(testRecord))
+	require.Nil(t, sink.Record(Record{EventName: "Other", Handler: "h", Outcome: OutcomeHandled, StartedAt: testRecord.StartedAt}))
+
+	assert.Equal(t, `{"durationMs":1.5,"error":"Nope","eventId":"1234","eventName":"Domain\\Event","handler":"sendReceipt","outcome":"failed","retryCount":2,"startedAt":"2018-03-08T11:11:11Z"}`+"\n"+
+		`{"durationMs":0,"eventName":"Other","handler":"h","outcome":"handled","retryCount":0,"startedAt":"2018-03-08T11:11:11Z"}`+"\n", out.String())
+}
+
+type publishedEvents []gomainevents.Event
+
+func (p *publishedEvents) Publish(event gomainevents.Event) error {
+	*p = append(*p, event)
+	return nil
+}
+
+func TestPublisherSink(t *testing.T) {
+	published := &publishedEvents{}
+
+	require.Nil(t, NewPublisherSink(published, "").Record(testRecord))
+	require.Nil(t, NewPublisherSink(published, "HandlerAudited").Record(testRecord))
+
+	require.Len(t, *published, 2)
+	assert.Equal(t, "GomaineventsAuditRecord", (*published)[0].Name())
+	assert.Equal(t, "HandlerAudited", (*published)[1].Name())
+	assert.Equal(t, testRecord.Data(), (*published)[0].Data())
+}
+
+func TestNewSQLSink(t *testing.T) {
+	sink, err := NewSQLSink(nil)
+	assert.Nil(t, sink)
+	assert.NotNil(t, err)
+
+	sink, err = NewSQLSink(&SQLSinkConfig{})
+	assert.Nil(t, sink)
+	assert.NotNil(t, err)
+}
+
+func TestSQLSink(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.Nil(t, err)
+	defer db.Close()
+
+	sink, err := NewSQLSink(&SQLSinkConfig{DB: db})
+	require.Nil(t, err)
+
+	mock.ExpectExec(`INSERT INTO "event_audit" (event_id, event_name, handler, outcome, error, retry_count, started_at, duration_ms) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`).
+		WithArgs("1234", "Domain\\Event", "sendReceipt", "failed", "Nope", 2, testRecord.StartedAt, 1.5).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	require.Nil(t, sink.Record(testRecord))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	sink, _ = NewSQLSink(&SQLSinkConfig{DB: db, Dialect: MySQL, Table: "app.audit"})
+	mock.ExpectExec("INSERT INTO `app`.`audit` (event_id, event_name, handler, outcome, error, retry_count, started_at, duration_ms) VALUES (?, ?, ?, ?, ?, ?, ?, ?)").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+
+	require.Nil(t, sink.Record(testRecord))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const defaultTable = "event_audit"
+
+// Dialect is the flavor of SQL spoken by the database.
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	MySQL
+)
+
+// SQLSink inserts records into a table with these columns, shown for
+// PostgreSQL:
+//
+//	CREATE TABLE event_audit (
+//	    id          BIGSERIAL PRIMARY KEY,
+//	    event_id    TEXT NOT NULL,
+//	    event_name  TEXT NOT NULL,
+//	    handler     TEXT NOT NULL,
+//	    outcome     TEXT NOT NULL,
+//	    error       TEXT NOT NULL,
+//	    retry_count INT NOT NULL,
+//	    started_at  TIMESTAMP NOT NULL,
+//	    duration_ms DOUBLE PRECISION NOT NULL
+//	);
+//
+// An index on event_id makes looking up what happened to an event quick.
+// started_at is stored in UTC.
+type SQLSink struct {
+	db    *sql.DB
+	query string
+}
+
+type SQLSinkConfig struct {
+	// Database to write to. Required
+	DB *sql.DB
+
+	// SQL flavor of the database. Defaults to Postgres.
+	Dialect Dialect
+
+	// Table, optionally schema qualified. Defaults to "event_audit".
+	Table string
+}
+
+func NewSQLSink(config *SQLSinkConfig) (*SQLSink, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.DB {
+		return nil, errors.New("DB is required")
+	}
+
+	table := defaultTable
+	if "" != config.Table {
+		table = config.Table
+	}
+
+	return &SQLSink{
+		db: config.DB,
+		query: fmt.Sprintf(
+			"INSERT INTO %s (event_id, event_name, handler, outcome, error, retry_count, started_at, duration_ms) VALUES (%s)",
+			config.Dialect.quote(table),
+			config.Dialect.placeholders(8),
+		),
+	}, nil
+}
+
+func (s *SQLSink) Record(record Record) error {
+	_, err := s.db.Exec(
+		s.query,
+		record.EventID,
+		record.EventName,
+		record.Handler,
+		string(record.Outcome),
+		record.Error,
+		record.RetryCount,
+		record.StartedAt.UTC(),
+		durationMs(record.Duration),
+	)
+
+	return err
+}
+
+// placeholders returns count query parameters.
+func (d Dialect) placeholders(count int) string {
+	params := make([]string, count)
+	for i := range params {
+		params[i] = "$" + strconv.Itoa(i+1)
+		if MySQL == d {
+			params[i] = "?"
+		}
+	}
+
+	return strings.Join(params, ", ")
+}
+
+// quote quotes an optionally schema qualified identifier.
+func (d Dialect) quote(name string) string {
+	quote := `"`
+	if MySQL == d {
+		quote = "`"
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.Replace(part, quote, quote+quote, -1) + quote
+	}
+
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,314 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultCount        = 1000
+	defaultEventName    = "GomaineventsBenchmark"
+	defaultDrainTimeout = 30 * time.Second
+
+	// Keys of the data every synthetic event carries.
+	keyRun      = "benchRun"
+	keySequence = "benchSequence"
+	keySentAt   = "benchSentAt"
+	keyPayload  = "benchPayload"
+)
+
+// Benchmark publishes synthetic events at a steady rate and consumes them
+// through a Listener, measuring how long each one took to get from Publish
+// to its handler and how many got through per second. It validates
+// providers and Listener changes against performance regressions.
+//
+// The Listener logs every event it handles, which costs more than most
+// providers do. Send the standard logger somewhere cheap while measuring.
+//
+// A Listener can't be stopped, so the benchmark stops the provider once
+// every event has been consumed, which ends the Listener's workers. Use a
+// fresh provider for each run.
+type Benchmark struct {
+	publisher    gomainevents.Publisher
+	provider     gomainevents.Provider
+	rate         float64
+	count        int
+	payload      string
+	eventName    string
+	handlerDelay time.Duration
+	drainTimeout time.Duration
+
+	// Identifies this run's events, so that leftovers from earlier runs on
+	// the same queue aren't counted.
+	run string
+
+	now func() time.Time
+}
+
+type Config struct {
+	// Publisher the events are published with. Required
+	Publisher gomainevents.Publisher
+
+	// Provider the events are consumed from, which should receive what
+	// Publisher publishes. Required
+	Provider gomainevents.Provider
+
+	// Events published per second. Defaults to publishing as fast as the
+	// publisher allows.
+	Rate float64
+
+	// Number of events to publish. Defaults to 1000.
+	Count int
+
+	// Bytes of padding added to each event's data.
+	PayloadSize int
+
+	// Name of the synthetic events. Defaults to GomaineventsBenchmark.
+	EventName string
+
+	// Simulated work done by the handler for each event.
+	HandlerDelay time.Duration
+
+	// How long to wait for events to be consumed once they have all been
+	// published. Defaults to 30 seconds.
+	DrainTimeout time.Duration
+}
+
+func NewBenchmark(config *Config) (*Benchmark, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Publisher {
+		return nil, errors.New("Publisher is required")
+	}
+
+	if nil == config.Provider {
+		return nil, errors.New("Provider is required")
+	}
+
+	if config.Rate < 0 {
+		return nil, errors.New("Rate can't be negative")
+	}
+
+	count := defaultCount
+	if config.Count > 0 {
+		count = config.Count
+	}
+
+	eventName := defaultEventName
+	if "" != config.EventName {
+		eventName = config.EventName
+	}
+
+	drainTimeout := defaultDrainTimeout
+	if config.DrainTimeout > 0 {
+		drainTimeout = config.DrainTimeout
+	}
+
+	return &Benchmark{
+		publisher:    config.Publisher,
+		provider:     config.Provider,
+		rate:         config.Rate,
+		count:        count,
+		payload:      strings.Repeat("x", config.PayloadSize),
+		eventName:    eventName,
+		handlerDelay: config.HandlerDelay,
+		drainTimeout: drainTimeout,
+		run:          fmt.Sprintf("%d", time.Now().UnixNano()),
+		now:          time.Now,
+	}, nil
+}
+
+// Run publishes the events and waits for them to be consumed, or for the
+// drain timeout or the context to run out. Events that never arrive are
+// counted as lost rather than failing the run; it only fails if nothing
+// could be published at all.
+func (b *Benchmark) Run(ctx context.Context) (*Result, error) {
+	recorder := newRecorder(b.run, b.now)
+
+	listener := gomainevents.NewListener(b.provider)
+	listener.RegisterHandler(b.eventName, func(event gomainevents.Event) error {
+		if b.handlerDelay > 0 {
+			time.Sleep(b.handlerDelay)
+		}
+
+		recorder.consumed(event)
+		return nil
+	})
+	listener.RegisterErrorHandler(recorder.error)
+
+	go listener.Listen()
+	defer b.provider.Stop()
+
+	start := b.now()
+	published, publishErrors, err := b.publish(ctx)
+	publishTime := b.now().Sub(start)
+
+	if published == 0 && nil != err {
+		return nil, err
+	}
+
+	drainCtx, cancel := context.WithTimeout(ctx, b.drainTimeout)
+	defer cancel()
+
+	recorder.wait(drainCtx, published)
+
+	return recorder.result(published, publishErrors, publishTime, b.now().Sub(start)), nil
+}
+
+// publish sends the events at the configured rate, returning how many were
+// published and how many failed, along with the last failure.
+func (b *Benchmark) publish(ctx context.Context) (int, int, error) {
+	var ticker *time.Ticker
+	if b.rate > 0 {
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / b.rate))
+		defer ticker.Stop()
+	}
+
+	published, failed := 0, 0
+	var lastErr error
+	for i := 0; i < b.count; i++ {
+		if nil != ticker && i > 0 {
+			select {
+			case <-ctx.Done():
+				return published, failed, ctx.Err()
+			case <-ticker.C:
+			}
+		} else if ctx.Err() != nil {
+			return published, failed, ctx.Err()
+		}
+
+		if err := b.publisher.Publish(b.event(i)); err != nil {
+			failed++
+			lastErr = err
+			continue
+		}
+
+		published++
+	}
+
+	return published, failed, lastErr
+}
+
+func (b *Benchmark) event(sequence int) event {
+	data := map[string]interface{}{
+		keyRun:      b.run,
+		keySequence: sequence,
+		keySentAt:   b.now().UTC().Format(time.RFC3339Nano),
+	}
+	if "" != b.payload {
+		data[keyPayload] = b.payload
+	}
+
+	return event{name: b.eventName, data: data}
+}
+
+// event is a synthetic event.
+type event struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e event) Name() string {
+	return e.name
+}
+
+func (e event) Data() map[string]interface{} {
+	return e.data
+}
+
+// recorder keeps track of the events consumed during a run.
+type recorder struct {
+	run string
+	now func() time.Time
+
+	mu         sync.Mutex
+	latencies  []time.Duration
+	seen       map[string]bool
+	duplicates int
+	errors     int
+	changed    chan bool
+}
+
+func newRecorder(run string, now func() time.Time) *recorder {
+	return &recorder{
+		run:     run,
+		now:     now,
+		seen:    map[string]bool{},
+		changed: make(chan bool, 1),
+	}
+}
+
+// consumed records an event reaching its handler.
+func (r *recorder) consumed(event gomainevents.Event) {
+	data := event.Data()
+	if run, _ := data[keyRun].(string); run != r.run {
+		// Left over from another run
+		return
+	}
+
+	sentAt, err := time.Parse(time.RFC3339Nano, fmt.Sprint(data[keySentAt]))
+	if err != nil {
+		r.error(err)
+		return
+	}
+
+	latency := r.now().Sub(sentAt)
+	sequence := fmt.Sprint(data[keySequence])
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.seen[sequence] {
+		r.duplicates++
+		return
+	}
+
+	r.seen[sequence] = true
+	r.latencies = append(r.latencies, latency)
+
+	select {
+	case r.changed <- true:
+	default:
+	}
+}
+
+func (r *recorder) error(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.errors++
+}
+
+// wait waits until n events have been consumed or the context is done.
+func (r *recorder) wait(ctx context.Context, n int) {
+	for {
+		r.mu.Lock()
+		consumed := len(r.latencies)
+		r.mu.Unlock()
+
+		if consumed >= n {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.changed:
+		}
+	}
+}
+
+func (r *recorder) result(published int, publishErrors int, publishTime time.Duration, elapsed time.Duration) *Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return newResult(published, publishErrors, publishTime, elapsed, r.latencies, r.duplicates, r.errors)
+}
@@ -0,0 +1,125 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	// The Listener and provider log every event
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+func TestNewBenchmark(t *testing.T) {
+	provider := memory.NewProvider(nil)
+	publisher := memory.NewPublisher(provider)
+
+	benchmark, err := NewBenchmark(nil)
+	assert.Nil(t, benchmark)
+	assert.NotNil(t, err)
+
+	benchmark, err = NewBenchmark(&Config{Provider: provider})
+	assert.Nil(t, benchmark)
+	assert.NotNil(t, err)
+
+	benchmark, err = NewBenchmark(&Config{Publisher: publisher})
+	assert.Nil(t, benchmark)
+	assert.NotNil(t, err)
+
+	benchmark, err = NewBenchmark(&Config{Publisher: publisher, Provider: provider, Rate: -1})
+	assert.Nil(t, benchmark)
+	assert.NotNil(t, err)
+
+	benchmark, err = NewBenchmark(&Config{Publisher: publisher, Provider: provider})
+	assert.NotNil(t, benchmark)
+	assert.Nil(t, err)
+	assert.Equal(t, defaultCount, benchmark.count)
+	assert.Equal(t, defaultEventName, benchmark.eventName)
+}
+
+func TestRunThroughMemoryProvider(t *testing.T) {
+	provider := memory.NewProvider(nil)
+	benchmark, err := NewBenchmark(&Config{
+		Publisher:   memory.NewPublisher(provider),
+		Provider:    provider,
+		Rate:        1000,
+		Count:       50,
+		PayloadSize: 64,
+	})
+	require.Nil(t, err)
+
+	result, err := benchmark.Run(context.Background())
+
+	require.Nil(t, err)
+	assert.Equal(t, 50, result.Published)
+	assert.Equal(t, 50, result.Consumed)
+	assert.Equal(t, 0, result.Lost)
+	assert.True(t, result.Latency.Max >= result.Latency.P50)
+	assert.True(t, result.Throughput > 0)
+
+	// Paced at 1000/s, 50 events take at least 49ms to publish
+	assert.True(t, result.PublishTime >= 45*time.Millisecond, result.PublishTime.String())
+}
+
+type failingPublisher struct{}
+
+func (p failingPublisher) Publish(gomainevents.Event) error {
+	return errors.New("Topic does not exist")
+}
+
+func TestRunFailsWhenNothingPublishes(t *testing.T) {
+	benchmark, _ := NewBenchmark(&Config{
+		Publisher: failingPublisher{},
+		Provider:  memory.NewProvider(nil),
+		Count:     3,
+	})
+
+	result, err := benchmark.Run(context.Background())
+
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "Topic does not exist")
+}
+
+func TestRecorderIgnoresOtherRunsAndCountsDuplicates(t *testing.T) {
+	now := time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+	recorder := newRecorder("this", func() time.Time { return now })
+
+	sentAt := now.Add(-20 * time.Millisecond).Format(time.RFC3339Nano)
+	recorder.consumed(event{data: map[string]interface{}{keyRun: "this", keySequence: 1, keySentAt: sentAt}})
+	recorder.consumed(event{data: map[string]interface{}{keyRun: "this", keySequence: float64(1), keySentAt: sentAt}})
+	recorder.consumed(event{data: map[string]interface{}{keyRun: "that", keySequence: 2, keySentAt: sentAt}})
+
+	result := recorder.result(2, 0, time.Second, time.Second)
+
+	assert.Equal(t, 1, result.Consumed)
+	assert.Equal(t, 1, result.Duplicates)
+	assert.Equal(t, 1, result.Lost)
+	assert.Equal(t, 20*time.Millisecond, result.Latency.P99)
+}
+
+func TestLatencyPercentiles(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	latency := newLatency(latencies)
+
+	assert.Equal(t, time.Millisecond, latency.Min)
+	assert.Equal(t, 50*time.Millisecond, latency.P50)
+	assert.Equal(t, 90*time.Millisecond, latency.P90)
+	assert.Equal(t, 99*time.Millisecond, latency.P99)
+	assert.Equal(t, 100*time.Millisecond, latency.Max)
+	assert.Equal(t, 50500*time.Microsecond, latency.Mean)
+}
@@ -0,0 +1,126 @@
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Result summarises a benchmark run.
+type Result struct {
+	// Events published, and publishes that failed.
+	Published     int
+	PublishErrors int
+
+	// Events that reached the handler, counting each event once.
+	Consumed int
+
+	// Events published but never consumed before the drain timeout.
+	Lost int
+
+	// Events that reached the handler more than once.
+	Duplicates int
+
+	// Errors reported by the Listener and provider.
+	Errors int
+
+	// How long publishing took, and how long the whole run took.
+	PublishTime time.Duration
+	Elapsed     time.Duration
+
+	// Events per second published, and consumed over the whole run.
+	PublishRate float64
+	Throughput  float64
+
+	// Time from Publish to the handler.
+	Latency Latency
+}
+
+// Latency is the distribution of the time events took from Publish to
+// their handler.
+type Latency struct {
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+}
+
+func newResult(published int, publishErrors int, publishTime time.Duration, elapsed time.Duration, latencies []time.Duration, duplicates int, errors int) *Result {
+	result := &Result{
+		Published:     published,
+		PublishErrors: publishErrors,
+		Consumed:      len(latencies),
+		Lost:          published - len(latencies),
+		Duplicates:    duplicates,
+		Errors:        errors,
+		PublishTime:   publishTime,
+		Elapsed:       elapsed,
+		PublishRate:   perSecond(published, publishTime),
+		Throughput:    perSecond(len(latencies), elapsed),
+		Latency:       newLatency(latencies),
+	}
+	if result.Lost < 0 {
+		result.Lost = 0
+	}
+
+	return result
+}
+
+func newLatency(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+
+	sorted := append([]time.Duration{}, latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+
+	return Latency{
+		Min:  sorted[0],
+		Mean: total / time.Duration(len(sorted)),
+		P50:  percentile(sorted, 0.50),
+		P90:  percentile(sorted, 0.90),
+		P99:  percentile(sorted, 0.99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+
+	return sorted[rank]
+}
+
+func perSecond(n int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+
+	return float64(n) / d.Seconds()
+}
+
+// String formats the result as a short report.
+func (r *Result) String() string {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "Published:   %d in %s (%.1f/s), %d failed\n", r.Published, r.PublishTime.Round(time.Millisecond), r.PublishRate, r.PublishErrors)
+	fmt.Fprintf(b, "Consumed:    %d in %s (%.1f/s), %d lost, %d duplicates, %d errors\n", r.Consumed, r.Elapsed.Round(time.Millisecond), r.Throughput, r.Lost, r.Duplicates, r.Errors)
+	fmt.Fprintf(b, "Latency:     min %s, mean %s, p50 %s, p90 %s, p99 %s, max %s\n",
+		r.Latency.Min, r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max)
+
+	return b.String()
+}
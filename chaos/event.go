@@ -0,0 +1,29 @@
+package chaos
+
+import (
+	"sync"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Event wraps an event from the underlying provider. Duplicates share their
+// original's outcome, so only the first Delete or Requeue of any copy
+// reaches the provider.
+type Event struct {
+	gomainevents.Event
+
+	duplicate bool
+	outcome   *sync.Once
+}
+
+// Unwrap returns the event as the underlying provider provided it, for
+// handlers that need provider-specific details.
+func (e Event) Unwrap() gomainevents.Event {
+	return e.Event
+}
+
+// Duplicate reports whether this is an injected duplicate of an event that
+// was already delivered.
+func (e Event) Duplicate() bool {
+	return e.duplicate
+}
@@ -0,0 +1,23 @@
+package chaos
+
+import (
+	"fmt"
+)
+
+// Faults that can be injected.
+const (
+	FaultDecode = "decode"
+	FaultDelete = "delete"
+)
+
+// InjectedError is reported on the error channel, wrapped in a
+// *gomainevents.ProviderError, whenever a fault is injected, so that tests
+// can tell them apart from real errors with errors.As.
+type InjectedError struct {
+	Fault     string
+	EventName string
+}
+
+func (e *InjectedError) Error() string {
+	return fmt.Sprintf("Injected %s failure: %s", e.Fault, e.EventName)
+}
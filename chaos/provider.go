@@ -0,0 +1,376 @@
+package chaos
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultMaxDelay      = 5 * time.Second
+	defaultReorderWindow = 50 * time.Millisecond
+)
+
+// Provider wraps another provider and injects the kinds of failures real
+// brokers produce, so that handlers can be checked for idempotency and
+// retry behaviour before production checks them instead. It is meant for
+// tests and staging environments.
+//
+// Each fault happens at random at its configured rate, from 0 (never) to 1
+// (always):
+//
+//   - Duplicates deliver an event a second time.
+//   - Delays hold an event back for up to MaxDelay.
+//   - Decode errors report an *InjectedError instead of delivering the
+//     event, and requeue it with the underlying provider.
+//   - Delete failures report an *InjectedError instead of deleting the
+//     event, leaving it to the underlying provider to redeliver.
+//
+// With a ReorderSize above 1, events are also shuffled in groups of that
+// size.
+type Provider struct {
+	provider gomainevents.Provider
+
+	duplicateRate     float64
+	delayRate         float64
+	maxDelay          time.Duration
+	decodeErrorRate   float64
+	deleteFailureRate float64
+	reorderSize       int
+	reorderWindow     time.Duration
+
+	randMu sync.Mutex
+	rand   *rand.Rand
+
+	// Events waiting to be shuffled and delivered.
+	staged chan gomainevents.Event
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+}
+
+type Config struct {
+	// Provider to wrap. Required
+	Provider gomainevents.Provider
+
+	// Rate at which events are delivered twice.
+	DuplicateRate float64
+
+	// Rate at which events are delayed, by up to MaxDelay. MaxDelay
+	// defaults to 5 seconds.
+	DelayRate float64
+	MaxDelay  time.Duration
+
+	// Rate at which events fail to decode.
+	DecodeErrorRate float64
+
+	// Rate at which deleting an event fails.
+	DeleteFailureRate float64
+
+	// Number of events shuffled together. Groups that don't fill up within
+	// ReorderWindow are shuffled anyway; it defaults to 50ms. Values of 0
+	// and 1 leave events in order.
+	ReorderSize   int
+	ReorderWindow time.Duration
+
+	// Seed for the random number generator, for reproducible runs.
+	// Defaults to the current time.
+	Seed int64
+}
+
+func NewProvider(config *Config) (*Provider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Provider {
+		return nil, errors.New("Provider is required")
+	}
+
+	for _, rate := range []float64{config.DuplicateRate, config.DelayRate, config.DecodeErrorRate, config.DeleteFailureRate} {
+		if rate < 0 || rate > 1 {
+			return nil, errors.New("Rates must be between 0 and 1")
+		}
+	}
+
+	maxDelay := defaultMaxDelay
+	if config.MaxDelay > 0 {
+		maxDelay = config.MaxDelay
+	}
+
+	reorderWindow := defaultReorderWindow
+	if config.ReorderWindow > 0 {
+		reorderWindow = config.ReorderWindow
+	}
+
+	seed := config.Seed
+	if 0 == seed {
+		seed = time.Now().UnixNano()
+	}
+
+	return &Provider{
+		provider:          config.Provider,
+		duplicateRate:     config.DuplicateRate,
+		delayRate:         config.DelayRate,
+		maxDelay:          maxDelay,
+		decodeErrorRate:   config.DecodeErrorRate,
+		deleteFailureRate: config.DeleteFailureRate,
+		reorderSize:       config.ReorderSize,
+		reorderWindow:     reorderWindow,
+		rand:              rand.New(rand.NewSource(seed)),
+		staged:            make(chan gomainevents.Event, 100),
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}, nil
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	events, errs := p.provider.Start()
+
+	go func() {
+		for err := range errs {
+			p.reportError(gomainevents.PhaseUnknown, "", err)
+		}
+	}()
+
+	go func() {
+		for event := range events {
+			p.intake(event)
+		}
+	}()
+
+	go p.release()
+
+	return p.events, p.errors
+}
+
+// intake decides what happens to an event fresh from the underlying
+// provider.
+func (p *Provider) intake(event gomainevents.Event) {
+	if p.chance(p.decodeErrorRate) {
+		p.reportError(gomainevents.PhaseDecode, "", &InjectedError{Fault: FaultDecode, EventName: event.Name()})
+
+		if err := p.provider.Requeue(event); err != nil {
+			p.reportError(gomainevents.PhaseRequeue, "", err)
+		}
+
+		return
+	}
+
+	if p.chance(p.delayRate) {
+		delay := p.duration(p.maxDelay)
+		p.debugPrint("Delaying %s by %s\n", event.Name(), delay)
+
+		time.AfterFunc(delay, func() {
+			p.stage(event)
+		})
+
+		return
+	}
+
+	p.stage(event)
+}
+
+// stage queues an event to be shuffled and delivered.
+func (p *Provider) stage(event gomainevents.Event) {
+	select {
+	case p.staged <- event:
+	case <-p.done:
+	}
+}
+
+// release delivers staged events, shuffled in groups of reorderSize.
+func (p *Provider) release() {
+	group := []gomainevents.Event{}
+	timer := time.NewTimer(p.reorderWindow)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case event := <-p.staged:
+			group = append(group, event)
+			if len(group) < p.reorderSize {
+				continue
+			}
+		case <-timer.C:
+			timer.Reset(p.reorderWindow)
+			if len(group) == 0 {
+				continue
+			}
+		}
+
+		p.shuffle(group)
+		for _, event := range group {
+			if !p.deliver(event) {
+				return
+			}
+		}
+
+		group = group[:0]
+	}
+}
+
+// deliver passes an event to the Listener, along with a duplicate now and
+// then, returning false if the provider was stopped first.
+func (p *Provider) deliver(event gomainevents.Event) bool {
+	evt := Event{Event: event, outcome: &sync.Once{}}
+	if !p.send(evt) {
+		return false
+	}
+
+	if !p.chance(p.duplicateRate) {
+		return true
+	}
+
+	p.debugPrint("Duplicating %s\n", event.Name())
+	evt.duplicate = true
+
+	return p.send(evt)
+}
+
+// send passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) send(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// Delete an event that we're done with
+func (p *Provider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to chaos flavor
+
+	if p.chance(p.deleteFailureRate) {
+		p.reportError(gomainevents.PhaseDelete, "", &InjectedError{Fault: FaultDelete, EventName: evt.Name()})
+		return
+	}
+
+	evt.outcome.Do(func() {
+		p.provider.Delete(evt.Event)
+	})
+}
+
+// Requeue an event for later
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to chaos flavor
+
+	var err gomainevents.RequeuingEventFailedError
+	evt.outcome.Do(func() {
+		err = p.provider.Requeue(evt.Event)
+	})
+
+	return err
+}
+
+// Defer an event, if the wrapped provider can. See
+// gomainevents.DeferringProvider.
+func (p *Provider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to chaos flavor
+
+	deferring, ok := p.provider.(gomainevents.DeferringProvider)
+	if !ok {
+		return gomainevents.ErrDeferNotSupported
+	}
+
+	var err error
+	evt.outcome.Do(func() {
+		err = deferring.Defer(evt.Event, delay)
+	})
+
+	return err
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	close(p.done)
+	p.provider.Stop()
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+// chance returns true with the given probability.
+func (p *Provider) chance(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	p.randMu.Lock()
+	defer p.randMu.Unlock()
+
+	return p.rand.Float64() < rate
+}
+
+// duration returns a random duration up to max.
+func (p *Provider) duration(max time.Duration) time.Duration {
+	p.randMu.Lock()
+	defer p.randMu.Unlock()
+
+	return time.Duration(p.rand.Int63n(int64(max)))
+}
+
+func (p *Provider) shuffle(events []gomainevents.Event) {
+	p.randMu.Lock()
+	defer p.randMu.Unlock()
+
+	p.rand.Shuffle(len(events), func(i, j int) {
+		events[i], events[j] = events[j], events[i]
+	})
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, "", messageID, err):
+	default:
+	}
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-chaos] "+format, values...)
+	}
+}
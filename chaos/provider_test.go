@@ -0,0 +1,226 @@
+package chaos
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+// mockProvider hands out its events and records what happens to them.
+type mockProvider struct {
+	events chan gomainevents.Event
+	errors chan error
+
+	mu       sync.Mutex
+	deleted  []string
+	requeued []string
+}
+
+func newMockProvider(names ...string) *mockProvider {
+	m := &mockProvider{
+		events: make(chan gomainevents.Event, len(names)),
+		errors: make(chan error, 1),
+	}
+	for _, name := range names {
+		m.events <- testEvent{name: name}
+	}
+
+	return m
+}
+
+func (m *mockProvider) Start() (<-chan gomainevents.Event, <-chan error) {
+	return m.events, m.errors
+}
+
+func (m *mockProvider) Delete(event gomainevents.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleted = append(m.deleted, event.Name())
+}
+
+func (m *mockProvider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requeued = append(m.requeued, event.Name())
+	return nil
+}
+
+func (m *mockProvider) requeuedNames() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string{}, m.requeued...)
+}
+
+func (m *mockProvider) Stop() {
+	close(m.events)
+	close(m.errors)
+}
+
+func newTestProvider(t *testing.T, config *Config) *Provider {
+	config.Seed = 1
+
+	provider, err := NewProvider(config)
+	require.Nil(t, err)
+	provider.debug = false
+
+	return provider
+}
+
+func receive(t *testing.T, events <-chan gomainevents.Event, n int) []Event {
+	received := []Event{}
+	for i := 0; i < n; i++ {
+		select {
+		case event := <-events:
+			received = append(received, event.(Event))
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for event")
+		}
+	}
+
+	return received
+}
+
+func names(events []Event) []string {
+	names := []string{}
+	for _, event := range events {
+		names = append(names, event.Name())
+	}
+
+	return names
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&Config{Provider: newMockProvider()})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&Config{Provider: newMockProvider(), DuplicateRate: 1.5})
+	assert.Nil(t, provider)
+	assert.EqualError(t, err, "Rates must be between 0 and 1")
+
+	provider, err = NewProvider(&Config{})
+	assert.Nil(t, provider)
+	assert.EqualError(t, err, "Provider is required")
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+}
+
+func TestProviderPassesEventsThrough(t *testing.T) {
+	underlying := newMockProvider("First", "Second")
+	provider := newTestProvider(t, &Config{Provider: underlying})
+	defer provider.Stop()
+
+	events, _ := provider.Start()
+
+	received := receive(t, events, 2)
+	assert.Equal(t, []string{"First", "Second"}, names(received))
+	assert.Equal(t, testEvent{name: "First"}, received[0].Unwrap())
+
+	provider.Delete(received[0])
+	assert.Nil(t, provider.Requeue(received[1]))
+	assert.Equal(t, []string{"First"}, underlying.deleted)
+	assert.Equal(t, []string{"Second"}, underlying.requeued)
+}
+
+func TestProviderDuplicates(t *testing.T) {
+	underlying := newMockProvider("Thing")
+	provider := newTestProvider(t, &Config{Provider: underlying, DuplicateRate: 1})
+	defer provider.Stop()
+
+	events, _ := provider.Start()
+
+	received := receive(t, events, 2)
+	assert.Equal(t, []string{"Thing", "Thing"}, names(received))
+	assert.False(t, received[0].Duplicate())
+	assert.True(t, received[1].Duplicate())
+
+	// Only the first outcome reaches the underlying provider
+	provider.Delete(received[1])
+	provider.Delete(received[0])
+	assert.Equal(t, []string{"Thing"}, underlying.deleted)
+}
+
+func TestProviderInjectsDecodeErrors(t *testing.T) {
+	underlying := newMockProvider("Thing")
+	provider := newTestProvider(t, &Config{Provider: underlying, DecodeErrorRate: 1})
+	defer provider.Stop()
+
+	provider.Start()
+
+	assert.Eventually(t, func() bool {
+		return len(underlying.requeuedNames()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestProviderInjectsDeleteFailures(t *testing.T) {
+	underlying := newMockProvider("Thing")
+	provider := newTestProvider(t, &Config{Provider: underlying, DeleteFailureRate: 1})
+	defer provider.Stop()
+
+	events, errs := provider.Start()
+	received := receive(t, events, 1)
+
+	provider.Delete(received[0])
+	assert.Empty(t, underlying.deleted)
+
+	select {
+	case err := <-errs:
+		var injected *InjectedError
+		require.True(t, errors.As(err, &injected))
+		assert.Equal(t, &InjectedError{Fault: FaultDelete, EventName: "Thing"}, injected)
+		assert.Equal(t, gomainevents.PhaseDelete, err.(*gomainevents.ProviderError).Phase)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error")
+	}
+}
+
+func TestProviderDelays(t *testing.T) {
+	underlying := newMockProvider("Thing")
+	provider := newTestProvider(t, &Config{Provider: underlying, DelayRate: 1, MaxDelay: 50 * time.Millisecond})
+	defer provider.Stop()
+
+	events, _ := provider.Start()
+	assert.Equal(t, []string{"Thing"}, names(receive(t, events, 1)))
+}
+
+func TestProviderReorders(t *testing.T) {
+	sent := []string{"A", "B", "C", "D", "E", "F", "G", "H"}
+	underlying := newMockProvider(sent...)
+	provider := newTestProvider(t, &Config{Provider: underlying, ReorderSize: 4, ReorderWindow: time.Hour})
+	defer provider.Stop()
+
+	events, _ := provider.Start()
+	received := names(receive(t, events, len(sent)))
+
+	// Shuffled within each group of four
+	assert.NotEqual(t, sent, received)
+
+	first, second := append([]string{}, received[:4]...), append([]string{}, received[4:]...)
+	sort.Strings(first)
+	sort.Strings(second)
+	assert.Equal(t, sent[:4], first)
+	assert.Equal(t, sent[4:], second)
+}
@@ -0,0 +1,46 @@
+package gomainevents
+
+import (
+	"time"
+)
+
+// Clock tells the time and waits. Delays, backoff, heartbeats and flush
+// timers are measured on one, so that tests can swap in a fake clock, e.g.
+// gomaineventstest.Clock, and step through time instead of sleeping.
+type Clock interface {
+	Now() time.Time
+
+	// After returns a channel that receives the time once d has passed.
+	After(d time.Duration) <-chan time.Time
+
+	// AfterFunc calls fn once d has passed. It returns a function that
+	// cancels the call, reporting whether it was still pending.
+	AfterFunc(d time.Duration, fn func()) (cancel func() bool)
+}
+
+// SystemClock is the real clock. It's the default wherever a Clock can be
+// configured.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (systemClock) AfterFunc(d time.Duration, fn func()) func() bool {
+	return time.AfterFunc(d, fn).Stop
+}
+
+// ClockOrDefault returns the clock, or SystemClock if there isn't one.
+func ClockOrDefault(clock Clock) Clock {
+	if nil == clock {
+		return SystemClock
+	}
+
+	return clock
+}
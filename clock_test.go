@@ -0,0 +1,34 @@
+package gomainevents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemClock(t *testing.T) {
+	before := time.Now()
+	assert.False(t, SystemClock.Now().Before(before))
+
+	select {
+	case <-SystemClock.After(time.Millisecond):
+	case <-time.After(5 * time.Second):
+		t.Fatal("After never fired")
+	}
+
+	called := make(chan bool, 1)
+	SystemClock.AfterFunc(time.Millisecond, func() { called <- true })
+	assert.True(t, <-called)
+
+	cancel := SystemClock.AfterFunc(time.Hour, func() { t.Error("Cancelled call ran") })
+	assert.True(t, cancel())
+	assert.False(t, cancel())
+}
+
+func TestClockOrDefault(t *testing.T) {
+	assert.Equal(t, SystemClock, ClockOrDefault(nil))
+
+	clock := fixedClock{Clock: SystemClock}
+	assert.Equal(t, clock, ClockOrDefault(clock))
+}
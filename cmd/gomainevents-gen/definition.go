@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// eventDefinition is an event to generate code for.
+type eventDefinition struct {
+	// Name the event is published with.
+	Name string
+
+	// Go type generated for it.
+	TypeName string
+
+	Description string
+	Fields      []fieldDefinition
+}
+
+// fieldDefinition is a field of an event's data.
+type fieldDefinition struct {
+	// Key in the event data.
+	Name string
+
+	// Go field generated for it.
+	GoName      string
+	Type        string
+	Optional    bool
+	Description string
+}
+
+// dslFile is the YAML format for describing events:
+//
+//	events:
+//	  - name: Manuscript\Submitted
+//	    description: An author submitted a manuscript.
+//	    fields:
+//	      - name: manuscriptId
+//	        type: int64
+//	      - name: submittedAt
+//	        type: time
+//	      - name: coAuthors
+//	        type: "[]string"
+//	        optional: true
+//
+// Types are Go types, with time for time.Time and any for interface{}.
+type dslFile struct {
+	Events []struct {
+		Name        string `yaml:"name"`
+		Type        string `yaml:"type"`
+		Description string `yaml:"description"`
+		Fields      []struct {
+			Name        string `yaml:"name"`
+			GoName      string `yaml:"goName"`
+			Type        string `yaml:"type"`
+			Optional    bool   `yaml:"optional"`
+			Description string `yaml:"description"`
+		} `yaml:"fields"`
+	} `yaml:"events"`
+}
+
+// loadDefinitions reads the events in a YAML file, or the event described
+// by a JSON Schema file.
+func loadDefinitions(path string) ([]eventDefinition, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []eventDefinition
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		events, err = parseDSL(contents)
+	case ".json":
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		var event eventDefinition
+		event, err = parseJSONSchema(name, contents)
+		events = []eventDefinition{event}
+	default:
+		return nil, fmt.Errorf("%s: expected a .yaml, .yml or .json file", path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return events, nil
+}
+
+func parseDSL(contents []byte) ([]eventDefinition, error) {
+	file := &dslFile{}
+	if err := yaml.Unmarshal(contents, file); err != nil {
+		return nil, err
+	}
+
+	events := []eventDefinition{}
+	for _, evt := range file.Events {
+		if "" == evt.Name {
+			return nil, fmt.Errorf("Event %d has no name", len(events)+1)
+		}
+
+		event := eventDefinition{
+			Name:        evt.Name,
+			TypeName:    evt.Type,
+			Description: evt.Description,
+		}
+		if "" == event.TypeName {
+			event.TypeName = eventTypeName(evt.Name)
+		}
+
+		for _, f := range evt.Fields {
+			if "" == f.Name {
+				return nil, fmt.Errorf("%s: Field %d has no name", evt.Name, len(event.Fields)+1)
+			}
+
+			goType, err := dslType(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %s", evt.Name, f.Name, err)
+			}
+
+			field := fieldDefinition{
+				Name:        f.Name,
+				GoName:      f.GoName,
+				Type:        goType,
+				Optional:    f.Optional,
+				Description: f.Description,
+			}
+			if "" == field.GoName {
+				field.GoName = exportedName(f.Name)
+			}
+
+			event.Fields = append(event.Fields, field)
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// dslType turns a type in the YAML format into a Go type.
+func dslType(t string) (string, error) {
+	t = strings.TrimSpace(t)
+
+	switch {
+	case strings.HasPrefix(t, "[]"):
+		elem, err := dslType(t[2:])
+		return "[]" + elem, err
+	case strings.HasPrefix(t, "map[string]"):
+		elem, err := dslType(t[len("map[string]"):])
+		return "map[string]" + elem, err
+	}
+
+	switch t {
+	case "string", "bool", "int", "int32", "int64", "uint", "uint32", "uint64", "float32", "float64":
+		return t, nil
+	case "time":
+		return "time.Time", nil
+	case "any":
+		return "interface{}", nil
+	case "":
+		return "", errors.New("Type is required")
+	}
+
+	return "", fmt.Errorf("Unknown type %q", t)
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+	"text/template"
+)
+
+// generate returns the Go source for a package of events.
+func generate(packageName string, events []eventDefinition) ([]byte, error) {
+	if !token.IsIdentifier(packageName) {
+		return nil, fmt.Errorf("Invalid package name %q", packageName)
+	}
+
+	if err := check(events); err != nil {
+		return nil, err
+	}
+
+	usesTime := false
+	for _, event := range events {
+		for _, field := range event.Fields {
+			usesTime = usesTime || strings.Contains(field.Type, "time.Time")
+		}
+	}
+
+	var source bytes.Buffer
+	err := fileTemplate.Execute(&source, map[string]interface{}{
+		"Package":  packageName,
+		"UsesTime": usesTime,
+		"Events":   events,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Generated invalid code: %s", err)
+	}
+
+	return formatted, nil
+}
+
+// check makes sure the generated names are usable and don't clash.
+func check(events []eventDefinition) error {
+	types := map[string]string{}
+	for _, event := range events {
+		if !token.IsIdentifier(event.TypeName) || !token.IsExported(event.TypeName) {
+			return fmt.Errorf("%s: Invalid type name %q", event.Name, event.TypeName)
+		}
+
+		if other, ok := types[event.TypeName]; ok {
+			return fmt.Errorf("%s and %s would both be generated as %s; set a type for one of them", other, event.Name, event.TypeName)
+		}
+		types[event.TypeName] = event.Name
+
+		fields := map[string]string{}
+		for _, field := range event.Fields {
+			switch {
+			case !token.IsIdentifier(field.GoName) || !token.IsExported(field.GoName):
+				return fmt.Errorf("%s.%s: Invalid Go name %q", event.Name, field.Name, field.GoName)
+			case "Name" == field.GoName || "Data" == field.GoName:
+				return fmt.Errorf("%s.%s: %s clashes with the Event methods; give it another Go name", event.Name, field.Name, field.GoName)
+			}
+
+			if other, ok := fields[field.GoName]; ok {
+				return fmt.Errorf("%s: %s and %s would both be generated as %s", event.Name, other, field.Name, field.GoName)
+			}
+			fields[field.GoName] = field.Name
+		}
+	}
+
+	return nil
+}
+
+// pointer reports whether an optional field is generated as a pointer, so
+// that leaving it out can be told apart from its zero value. Slices, maps
+// and interfaces already can be.
+func (f fieldDefinition) pointer() bool {
+	return f.Optional && !strings.HasPrefix(f.Type, "[]") && !strings.HasPrefix(f.Type, "map[") && "interface{}" != f.Type
+}
+
+// GoType is the type of the generated struct field.
+func (f fieldDefinition) GoType() string {
+	if f.pointer() {
+		return "*" + f.Type
+	}
+
+	return f.Type
+}
+
+// Tag is the struct tag of the generated field.
+func (f fieldDefinition) Tag() string {
+	if f.Optional {
+		return fmt.Sprintf("`json:%q`", f.Name+",omitempty")
+	}
+
+	return fmt.Sprintf("`json:%q`", f.Name)
+}
+
+// Param is the name of the field's constructor parameter.
+func (f fieldDefinition) Param() string {
+	return paramName(f.GoName)
+}
+
+// Value is how the field is read into the event data.
+func (f fieldDefinition) Value() string {
+	if f.pointer() {
+		return "*e." + f.GoName
+	}
+
+	return "e." + f.GoName
+}
+
+// Required returns the fields that are always set, which the constructor
+// takes.
+func (e eventDefinition) Required() []fieldDefinition {
+	required := []fieldDefinition{}
+	for _, field := range e.Fields {
+		if !field.Optional {
+			required = append(required, field)
+		}
+	}
+
+	return required
+}
+
+// Optional returns the fields that may be left out.
+func (e eventDefinition) Optional() []fieldDefinition {
+	optional := []fieldDefinition{}
+	for _, field := range e.Fields {
+		if field.Optional {
+			optional = append(optional, field)
+		}
+	}
+
+	return optional
+}
+
+func comment(indent string, text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(indent+"// "+strings.TrimSpace(line), " ")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+var fileTemplate = template.Must(template.New("file").Funcs(template.FuncMap{
+	"comment": comment,
+	"quote":   func(s string) string { return fmt.Sprintf("%q", s) },
+}).Parse(`// Code generated by gomainevents-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"
+{{- if .UsesTime}}
+	"time"
+{{- end}}
+
+	"github.com/researchsquare/gomainevents"
+)
+{{range .Events}}
+// {{.TypeName}}Name is the name {{.TypeName}} events are published with.
+const {{.TypeName}}Name = {{quote .Name}}
+
+// {{.TypeName}} is the {{.Name}} event.
+{{- if .Description}}
+{{comment "" .Description}}
+{{- end}}
+type {{.TypeName}} struct {
+{{- range .Fields}}
+{{- if .Description}}
+{{comment "\t" .Description}}
+{{- end}}
+	{{.GoName}} {{.GoType}} {{.Tag}}
+{{- end}}
+}
+
+// New{{.TypeName}} returns a {{.TypeName}} with its required fields set.
+func New{{.TypeName}}({{range $i, $f := .Required}}{{if $i}}, {{end}}{{$f.Param}} {{$f.GoType}}{{end}}) {{.TypeName}} {
+	return {{.TypeName}}{
+{{- range .Required}}
+		{{.GoName}}: {{.Param}},
+{{- end}}
+	}
+}
+
+func (e {{.TypeName}}) Name() string {
+	return {{.TypeName}}Name
+}
+
+func (e {{.TypeName}}) Data() map[string]interface{} {
+	data := map[string]interface{}{
+{{- range .Required}}
+		{{quote .Name}}: {{.Value}},
+{{- end}}
+	}
+{{range .Optional}}
+	if nil != e.{{.GoName}} {
+		data[{{quote .Name}}] = {{.Value}}
+	}
+{{end}}
+	return data
+}
+
+// Decode{{.TypeName}} reads a {{.TypeName}} from a received event.
+func Decode{{.TypeName}}(event gomainevents.Event) ({{.TypeName}}, error) {
+	var e {{.TypeName}}
+	if {{.TypeName}}Name != event.Name() {
+		return e, fmt.Errorf("Expected a %s event, not %s", {{.TypeName}}Name, event.Name())
+	}
+
+	if err := gomainevents.DecodeData(event, &e); err != nil {
+		return e, fmt.Errorf("Unable to decode %s event: %s", {{.TypeName}}Name, err)
+	}
+
+	return e, nil
+}
+
+// Handle{{.TypeName}} turns a handler of {{.TypeName}} events into an
+// EventHandler. Events that can't be decoded fail without calling it.
+func Handle{{.TypeName}}(fn func({{.TypeName}}) error) gomainevents.EventHandler {
+	return func(event gomainevents.Event) error {
+		e, err := Decode{{.TypeName}}(event)
+		if err != nil {
+			return err
+		}
+
+		return fn(e)
+	}
+}
+
+// Register{{.TypeName}}Handler registers a handler of {{.TypeName}} events
+// with a listener.
+func Register{{.TypeName}}Handler(listener *gomainevents.Listener, fn func({{.TypeName}}) error) {
+	listener.RegisterHandler({{.TypeName}}Name, Handle{{.TypeName}}(fn))
+}
+{{end}}`))
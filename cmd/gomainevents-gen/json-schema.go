@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonSchema is the part of a JSON Schema that code is generated from. It
+// is read with the YAML decoder, which accepts JSON, so that properties
+// keep their order.
+type jsonSchema struct {
+	Title       string      `yaml:"title"`
+	Description string      `yaml:"description"`
+	Type        interface{} `yaml:"type"`
+	Format      string      `yaml:"format"`
+	Items       *jsonSchema `yaml:"items"`
+	Properties  yaml.Node   `yaml:"properties"`
+	Required    []string    `yaml:"required"`
+
+	// Go name for a property, when the generated one won't do.
+	GoName string `yaml:"x-go-name"`
+}
+
+// parseJSONSchema reads the event a JSON Schema describes, named after its
+// title, or the file it was read from if it has none.
+func parseJSONSchema(fileName string, contents []byte) (eventDefinition, error) {
+	root := &jsonSchema{}
+	if err := yaml.Unmarshal(contents, root); err != nil {
+		return eventDefinition{}, err
+	}
+
+	event := eventDefinition{
+		Name:        root.Title,
+		Description: root.Description,
+	}
+	if "" == event.Name {
+		event.Name = fileName
+	}
+	event.TypeName = eventTypeName(event.Name)
+
+	if yaml.MappingNode != root.Properties.Kind {
+		if 0 == root.Properties.Kind {
+			return event, nil
+		}
+
+		return eventDefinition{}, errors.New("Properties must be an object")
+	}
+
+	required := map[string]bool{}
+	for _, name := range root.Required {
+		required[name] = true
+	}
+
+	content := root.Properties.Content
+	for i := 0; i+1 < len(content); i += 2 {
+		name := content[i].Value
+
+		property := &jsonSchema{}
+		if err := content[i+1].Decode(property); err != nil {
+			return eventDefinition{}, fmt.Errorf("%s: %s", name, err)
+		}
+
+		goType, nullable := property.goType()
+
+		field := fieldDefinition{
+			Name:        name,
+			GoName:      property.GoName,
+			Type:        goType,
+			Optional:    !required[name] || nullable,
+			Description: property.Description,
+		}
+		if "" == field.GoName {
+			field.GoName = exportedName(name)
+		}
+
+		event.Fields = append(event.Fields, field)
+	}
+
+	return event, nil
+}
+
+// goType returns the Go type for values matching the schema, and whether
+// they may be null.
+func (s *jsonSchema) goType() (string, bool) {
+	types := []string{}
+	switch t := s.Type.(type) {
+	case string:
+		types = append(types, t)
+	case []interface{}:
+		for _, name := range t {
+			if name, ok := name.(string); ok {
+				types = append(types, name)
+			}
+		}
+	}
+
+	nullable := false
+	nonNull := []string{}
+	for _, t := range types {
+		if "null" == t {
+			nullable = true
+		} else {
+			nonNull = append(nonNull, t)
+		}
+	}
+
+	// Values that may be one of several types can only be held as they are.
+	if len(nonNull) != 1 {
+		return "interface{}", nullable
+	}
+
+	switch nonNull[0] {
+	case "string":
+		if "date-time" == s.Format {
+			return "time.Time", nullable
+		}
+
+		return "string", nullable
+	case "integer":
+		return "int64", nullable
+	case "number":
+		return "float64", nullable
+	case "boolean":
+		return "bool", nullable
+	case "array":
+		elem := "interface{}"
+		if nil != s.Items {
+			elem, _ = s.Items.goType()
+		}
+
+		return "[]" + elem, nullable
+	case "object":
+		return "map[string]interface{}", nullable
+	}
+
+	return "interface{}", nullable
+}
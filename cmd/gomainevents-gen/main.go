@@ -0,0 +1,90 @@
+// Command gomainevents-gen generates typed events from schema definitions,
+// so that handlers and publishers don't have to pick through
+// map[string]interface{} payloads.
+//
+// Usage:
+//
+//	gomainevents-gen -package events -out events_gen.go events.yaml schemas/*.json
+//
+// Definitions are either YAML files listing events and their fields, or JSON
+// Schema files, one event per file, e.g. the schemas registered with the
+// schema package's registry. For each event it generates:
+//
+//   - a struct implementing gomainevents.Event, with a field per property
+//   - a constant holding the event's name
+//   - a constructor taking the required fields
+//   - a function decoding the struct from a received event
+//   - functions adapting a typed handler to an EventHandler and registering
+//     it with a Listener
+//
+// It is meant to be run with go generate:
+//
+//	//go:generate gomainevents-gen -package events -out events_gen.go events.yaml
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+
+		fmt.Fprintf(os.Stderr, "gomainevents-gen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("gomainevents-gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: gomainevents-gen [flags] <definition files>")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+
+	packageName := flags.String("package", os.Getenv("GOPACKAGE"), "Package of the generated code. Defaults to the package go generate is run in")
+	out := flags.String("out", "", "File to write the generated code to. Defaults to stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("At least one definition file is required")
+	}
+
+	if "" == *packageName {
+		return errors.New("-package is required")
+	}
+
+	events := []eventDefinition{}
+	for _, path := range flags.Args() {
+		definitions, err := loadDefinitions(path)
+		if err != nil {
+			return err
+		}
+
+		events = append(events, definitions...)
+	}
+
+	source, err := generate(*packageName, events)
+	if err != nil {
+		return err
+	}
+
+	if "" == *out {
+		_, err = stdout.Write(source)
+		return err
+	}
+
+	return os.WriteFile(*out, source, 0644)
+}
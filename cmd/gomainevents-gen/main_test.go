@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDSL = `
+events:
+  - name: Manuscript\Submitted
+    description: An author submitted a manuscript.
+    fields:
+      - name: manuscriptId
+        type: int64
+        description: ID of the manuscript.
+      - name: submittedAt
+        type: time
+      - name: coAuthors
+        type: "[]string"
+        optional: true
+      - name: note
+        type: string
+        optional: true
+  - name: Manuscript\Withdrawn
+    type: ManuscriptWithdrawn
+    fields:
+      - name: manuscriptId
+        type: int64
+      - name: type
+        goName: WithdrawalType
+        type: map[string]any
+`
+
+const testJSONSchema = `{
+	"title": "Review\\Completed",
+	"type": "object",
+	"required": ["reviewId", "scores"],
+	"properties": {
+		"reviewId": {"type": "integer"},
+		"scores": {"type": "array", "items": {"type": "number"}},
+		"completedAt": {"type": "string", "format": "date-time"},
+		"comment": {"type": ["string", "null"]},
+		"extra": {"type": "object"}
+	}
+}`
+
+func writeFile(t *testing.T, name string, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dsl := writeFile(t, "events.yaml", testDSL)
+	schema := writeFile(t, "review.json", testJSONSchema)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	require.NoError(t, run([]string{"-package", "events", dsl, schema}, stdout, stderr))
+
+	source := stdout.String()
+	_, err := parser.ParseFile(token.NewFileSet(), "events_gen.go", source, parser.ParseComments)
+	require.NoError(t, err)
+
+	for _, expected := range []string{
+		"// Code generated by gomainevents-gen. DO NOT EDIT.",
+		"package events",
+		`const SubmittedName = "Manuscript\\Submitted"`,
+		"// Submitted is the Manuscript\\Submitted event.\n// An author submitted a manuscript.\ntype Submitted struct {",
+		"\t// ID of the manuscript.\n\tManuscriptID int64 ",
+		"`json:\"coAuthors,omitempty\"`",
+		"Note         *string ",
+		"func NewSubmitted(manuscriptID int64, submittedAt time.Time) Submitted {",
+		"\tif nil != e.Note {\n\t\tdata[\"note\"] = *e.Note\n\t}",
+		"func DecodeSubmitted(event gomainevents.Event) (Submitted, error) {",
+		"func HandleSubmitted(fn func(Submitted) error) gomainevents.EventHandler {",
+		"func RegisterSubmittedHandler(listener *gomainevents.Listener, fn func(Submitted) error) {",
+		"type ManuscriptWithdrawn struct {",
+		"WithdrawalType map[string]interface{} `json:\"type\"`",
+		"type Completed struct {",
+		"func NewCompleted(reviewID int64, scores []float64) Completed {",
+		"CompletedAt *time.Time ",
+		"Comment     *string ",
+		"Extra       map[string]interface{} `json:\"extra,omitempty\"`",
+	} {
+		assert.Contains(t, source, expected)
+	}
+}
+
+func TestRunWritesFile(t *testing.T) {
+	dsl := writeFile(t, "events.yaml", testDSL)
+	out := filepath.Join(t.TempDir(), "events_gen.go")
+
+	os.Setenv("GOPACKAGE", "generated")
+	defer os.Unsetenv("GOPACKAGE")
+
+	require.NoError(t, run([]string{"-out", out, dsl}, &bytes.Buffer{}, &bytes.Buffer{}))
+
+	source, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Contains(t, string(source), "package generated\n")
+}
+
+func TestRunErrors(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	assert.EqualError(t, run([]string{"-package", "events"}, &bytes.Buffer{}, stderr), "At least one definition file is required")
+	assert.Contains(t, stderr.String(), "Usage: gomainevents-gen")
+
+	assert.Equal(t, flag.ErrHelp, run([]string{"-h"}, &bytes.Buffer{}, stderr))
+
+	dsl := writeFile(t, "events.yaml", testDSL)
+	assert.EqualError(t, run([]string{dsl}, &bytes.Buffer{}, stderr), "-package is required")
+	assert.EqualError(t, run([]string{"-package", "my-events", dsl}, &bytes.Buffer{}, stderr), `Invalid package name "my-events"`)
+
+	text := writeFile(t, "events.txt", "")
+	assert.EqualError(t, run([]string{"-package", "events", text}, &bytes.Buffer{}, stderr), text+": expected a .yaml, .yml or .json file")
+
+	tests := map[string]string{
+		"events:\n  - fields: []\n": "Event 1 has no name",
+		"events:\n  - name: A\n    fields:\n      - name: b\n        type: uuid\n":                                       `A.b: Unknown type "uuid"`,
+		"events:\n  - name: A\n    fields:\n      - name: b\n":                                                           "A.b: Type is required",
+		"events:\n  - name: A\n    fields:\n      - name: name\n        type: string\n":                                  "A.name: Name clashes with the Event methods; give it another Go name",
+		"events:\n  - name: A\n    fields:\n      - name: a_b\n        type: int\n      - name: aB\n        type: int\n": "A: a_b and aB would both be generated as AB",
+		"events:\n  - name: x.A\n  - name: y.A\n":                                                                        `x.A and y.A would both be generated as A; set a type for one of them`,
+	}
+
+	for contents, expected := range tests {
+		path := writeFile(t, "events.yaml", contents)
+
+		err := run([]string{"-package", "events", path}, &bytes.Buffer{}, stderr)
+		if assert.Error(t, err, contents) {
+			assert.Contains(t, err.Error(), expected)
+		}
+	}
+}
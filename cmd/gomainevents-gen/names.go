@@ -0,0 +1,120 @@
+package main
+
+import (
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// initialisms are written in capitals in Go names, as golint expects.
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ARN": true, "CPU": true, "CSS": true,
+	"DNS": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UI": true, "URI": true, "URL": true,
+	"UTC": true, "UUID": true, "XML": true,
+}
+
+// eventTypeName returns the Go type for an event name, which may be
+// namespaced, e.g. "Manuscript\Submitted" or "manuscript.submitted".
+func eventTypeName(eventName string) string {
+	if i := strings.LastIndexAny(eventName, `\./:`); i >= 0 {
+		eventName = eventName[i+1:]
+	}
+
+	return exportedName(eventName)
+}
+
+// exportedName turns a field or event name in camelCase, snake_case or
+// kebab-case into an exported Go name, e.g. "manuscriptId" into
+// "ManuscriptID".
+func exportedName(name string) string {
+	var b strings.Builder
+	for _, word := range splitWords(name) {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+
+		runes := []rune(word)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+
+	if b.Len() == 0 || !unicode.IsLetter([]rune(b.String())[0]) {
+		return "X" + b.String()
+	}
+
+	return b.String()
+}
+
+// paramName returns the name of a constructor parameter for an exported
+// name, e.g. "manuscriptID" for "ManuscriptID" and "url" for "URL".
+func paramName(exported string) string {
+	words := splitWords(exported)
+	if upper := strings.ToUpper(words[0]); initialisms[upper] || upper == words[0] {
+		words[0] = strings.ToLower(words[0])
+	} else {
+		runes := []rune(words[0])
+		words[0] = string(unicode.ToLower(runes[0])) + string(runes[1:])
+	}
+
+	name := strings.Join(words, "")
+	if token.IsKeyword(name) || isReserved(name) {
+		return name + "Value"
+	}
+
+	return name
+}
+
+// splitWords splits a name on separators and at the start of each
+// capitalized word. Runs of capitals are kept together, so "HTTPServer"
+// becomes "HTTP" and "Server".
+func splitWords(name string) []string {
+	words := []string{}
+	runes := []rune(name)
+	start := -1
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+			}
+			start = -1
+			continue
+		}
+
+		if start < 0 {
+			start = i
+			continue
+		}
+
+		prev := runes[i-1]
+		nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower)) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+// isReserved reports whether a name is predeclared or one of the packages
+// generated code imports.
+func isReserved(name string) bool {
+	switch name {
+	case "fmt", "json", "time", "gomainevents":
+		return true
+	case "bool", "byte", "error", "float32", "float64", "int", "int32",
+		"int64", "string", "uint", "len", "cap", "new", "make", "append",
+		"copy", "delete", "panic", "print", "nil", "true", "false":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportedName(t *testing.T) {
+	tests := map[string]string{
+		"manuscriptId":  "ManuscriptID",
+		"manuscript_id": "ManuscriptID",
+		"callback-url":  "CallbackURL",
+		"HTTPServer":    "HTTPServer",
+		"reviewer2Name": "Reviewer2Name",
+		"2fa":           "X2fa",
+		"userIDs":       "UserIDs",
+	}
+
+	for name, expected := range tests {
+		assert.Equal(t, expected, exportedName(name), name)
+	}
+}
+
+func TestEventTypeName(t *testing.T) {
+	assert.Equal(t, "Submitted", eventTypeName(`Manuscript\Submitted`))
+	assert.Equal(t, "ManuscriptSubmitted", eventTypeName("events.manuscript_submitted"))
+	assert.Equal(t, "ThingHappened", eventTypeName("ThingHappened"))
+}
+
+func TestParamName(t *testing.T) {
+	assert.Equal(t, "manuscriptID", paramName("ManuscriptID"))
+	assert.Equal(t, "url", paramName("URL"))
+	assert.Equal(t, "httpServer", paramName("HTTPServer"))
+	assert.Equal(t, "typeValue", paramName("Type"))
+	assert.Equal(t, "timeValue", paramName("Time"))
+}
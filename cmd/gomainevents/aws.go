@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+const defaultRegion = "us-east-1"
+
+// newSQSClient returns an SQS client using the shared credentials, for the
+// region and, if set, endpoint given on the command line.
+func newSQSClient(region string, endpoint string) (sqsiface.SQSAPI, error) {
+	sess, err := newSession(region, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return awssqs.New(sess), nil
+}
+
+// newS3Client returns an S3 client for fetching events offloaded to S3.
+func newS3Client(region string, endpoint string) (s3iface.S3API, error) {
+	sess, err := newSession(region, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return awss3.New(sess), nil
+}
+
+func newSession(region string, endpoint string) (*session.Session, error) {
+	config := &aws.Config{Region: aws.String(region)}
+	if "" != endpoint {
+		config.Endpoint = aws.String(endpoint)
+	}
+
+	return session.NewSession(config)
+}
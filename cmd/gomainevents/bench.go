@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/bench"
+	"github.com/researchsquare/gomainevents/memory"
+	"github.com/researchsquare/gomainevents/sns"
+	"github.com/researchsquare/gomainevents/sqs"
+)
+
+// benchOptions are the flags of the bench command.
+type benchOptions struct {
+	target   string
+	region   string
+	endpoint string
+	topicARN string
+	queueURL string
+
+	rate         float64
+	count        int
+	payloadSize  int
+	handlerDelay time.Duration
+	drainTimeout time.Duration
+	verbose      bool
+}
+
+func runBench(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	opts := &benchOptions{}
+
+	flags := newFlagSet("bench", stderr)
+	flags.StringVar(&opts.target, "target", "memory", "What to benchmark: memory, sqs, or sns-sqs to publish to a topic the queue is subscribed to")
+	flags.StringVar(&opts.region, "region", defaultRegion, "AWS region")
+	flags.StringVar(&opts.endpoint, "endpoint", "", "AWS endpoint, e.g. http://localhost:4566 for LocalStack")
+	flags.StringVar(&opts.topicARN, "topic-arn", "", "SNS topic ARN, for the sns-sqs target")
+	flags.StringVar(&opts.queueURL, "queue-url", "", "SQS queue URL, for the sqs and sns-sqs targets")
+	flags.Float64Var(&opts.rate, "rate", 100, "Events published per second, or 0 for as fast as possible")
+	flags.IntVar(&opts.count, "count", 1000, "Number of events to publish")
+	flags.IntVar(&opts.payloadSize, "payload", 0, "Bytes of padding added to each event")
+	flags.DurationVar(&opts.handlerDelay, "handler-delay", 0, "Simulated work done for each event")
+	flags.DurationVar(&opts.drainTimeout, "drain-timeout", 30*time.Second, "How long to wait for events to be consumed after publishing")
+	flags.BoolVar(&opts.verbose, "verbose", false, "Keep the library's logging, which slows everything down")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	publisher, provider, err := newBenchTarget(opts)
+	if err != nil {
+		return err
+	}
+
+	if !opts.verbose {
+		log.SetOutput(ioutil.Discard)
+		defer log.SetOutput(os.Stderr)
+	}
+
+	benchmark, err := bench.NewBenchmark(&bench.Config{
+		Publisher:    publisher,
+		Provider:     provider,
+		Rate:         opts.rate,
+		Count:        opts.count,
+		PayloadSize:  opts.payloadSize,
+		HandlerDelay: opts.handlerDelay,
+		DrainTimeout: opts.drainTimeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := benchmark.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(stdout, result)
+
+	return nil
+}
+
+// newBenchTarget returns the publisher and provider to benchmark.
+func newBenchTarget(opts *benchOptions) (gomainevents.Publisher, gomainevents.Provider, error) {
+	if "memory" == opts.target {
+		provider := memory.NewProvider(nil)
+		return memory.NewPublisher(provider), provider, nil
+	}
+
+	if "sqs" != opts.target && "sns-sqs" != opts.target {
+		return nil, nil, fmt.Errorf("Unknown target %q", opts.target)
+	}
+
+	client, err := newSQSClient(opts.region, opts.endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider, err := sqs.NewProvider(&sqs.Config{
+		SQSClient: client,
+		QueueURL:  opts.queueURL,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if "sqs" == opts.target {
+		publisher, err := sqs.NewPublisher(&sqs.PublisherConfig{
+			SQSClient: client,
+			QueueURL:  opts.queueURL,
+		})
+
+		return publisher, provider, err
+	}
+
+	publisher, err := sns.NewPublisher(&sns.Config{
+		Region:   opts.region,
+		Endpoint: opts.endpoint,
+		TopicARN: opts.topicARN,
+	})
+
+	return publisher, provider, err
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchMemory(t *testing.T) {
+	stdout := &bytes.Buffer{}
+
+	err := run([]string{"bench", "-rate", "0", "-count", "20"}, nil, stdout, ioutil.Discard)
+
+	require.Nil(t, err)
+	assert.Contains(t, stdout.String(), "Published:   20")
+	assert.Contains(t, stdout.String(), "Consumed:    20")
+}
+
+func TestBenchUnknownTarget(t *testing.T) {
+	assert.NotNil(t, run([]string{"bench", "-target", "carrier-pigeon"}, nil, ioutil.Discard, ioutil.Discard))
+}
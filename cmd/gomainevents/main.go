@@ -0,0 +1,76 @@
+// Command gomainevents publishes and inspects domain events from the command
+// line, for backfills, manual testing and poking at queues while on call.
+//
+// Usage:
+//
+//	gomainevents <command> [flags]
+//
+// Run a command with -h to see its flags.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// command is a subcommand of the CLI.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{name: "publish", summary: "Publish events from flags or a JSONL file", run: runPublish},
+	{name: "tail", summary: "Show the events waiting on an SQS queue without deleting them", run: runTail},
+	{name: "redrive", summary: "Move messages from a dead letter queue back to their queue", run: runRedrive},
+	{name: "bench", summary: "Measure publish-to-handler latency and throughput", run: runBench},
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+
+		fmt.Fprintf(os.Stderr, "gomainevents: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		return flag.ErrHelp
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdin, stdout, stderr)
+		}
+	}
+
+	usage(stderr)
+	return fmt.Errorf("Unknown command %q", args[0])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gomainevents <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// newFlagSet returns a flag set for a command that returns errors rather
+// than exiting, so that commands can be tested.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("gomainevents "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	return flags
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/eventbridge"
+	"github.com/researchsquare/gomainevents/jsonl"
+	"github.com/researchsquare/gomainevents/sns"
+	"github.com/researchsquare/gomainevents/sqs"
+)
+
+// maxLineSize is the longest line read from a JSONL file.
+const maxLineSize = 10 * 1024 * 1024
+
+// publishOptions are the flags of the publish command.
+type publishOptions struct {
+	target   string
+	region   string
+	endpoint string
+
+	topicARN string
+	queueURL string
+	groupID  string
+	eventBus string
+	source   string
+	path     string
+
+	name   string
+	data   string
+	file   string
+	dryRun bool
+}
+
+// event is an event given on the command line or read from a file.
+type event struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e event) Name() string {
+	return e.name
+}
+
+func (e event) Data() map[string]interface{} {
+	return e.data
+}
+
+// encodedEvent is a line of a JSONL file, in the format the jsonl package
+// writes.
+type encodedEvent struct {
+	Name string                 `json:"name"`
+	Data map[string]interface{} `json:"data"`
+}
+
+func runPublish(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	opts := &publishOptions{}
+
+	flags := newFlagSet("publish", stderr)
+	flags.StringVar(&opts.target, "target", "", "Where to publish: sns, sqs, eventbridge, jsonl or log. Required")
+	flags.StringVar(&opts.region, "region", defaultRegion, "AWS region")
+	flags.StringVar(&opts.endpoint, "endpoint", "", "AWS endpoint, e.g. http://localhost:4566 for LocalStack")
+	flags.StringVar(&opts.topicARN, "topic-arn", "", "SNS topic ARN, for the sns target")
+	flags.StringVar(&opts.queueURL, "queue-url", "", "SQS queue URL, for the sqs target")
+	flags.StringVar(&opts.groupID, "group-id", "", "Message group, for FIFO queues")
+	flags.StringVar(&opts.eventBus, "event-bus", "", "EventBridge bus, for the eventbridge target. Defaults to the default bus")
+	flags.StringVar(&opts.source, "source", "", "EventBridge source, for the eventbridge target")
+	flags.StringVar(&opts.path, "path", "", "File to append to, for the jsonl target. Defaults to stdout")
+	flags.StringVar(&opts.name, "name", "", "Name of a single event to publish")
+	flags.StringVar(&opts.data, "data", "{}", "JSON data of the single event")
+	flags.StringVar(&opts.file, "file", "", "JSONL file of events to publish, one {\"name\", \"data\"} object per line, or - for stdin")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "Log the events instead of publishing them")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if ("" == opts.name) == ("" == opts.file) {
+		return errors.New("Either -name or -file is required")
+	}
+
+	publisher, err := newPublisher(opts, stdout)
+	if err != nil {
+		return err
+	}
+
+	if closer, ok := publisher.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	published := 0
+	defer func() {
+		fmt.Fprintf(stderr, "Published %d events\n", published)
+	}()
+
+	publish := func(evt event) error {
+		if err := publisher.Publish(evt); err != nil {
+			return err
+		}
+
+		published++
+		return nil
+	}
+
+	if "" != opts.name {
+		evt, err := parseEvent(opts.name, opts.data)
+		if err != nil {
+			return err
+		}
+
+		return publish(evt)
+	}
+
+	input := stdin
+	if "-" != opts.file {
+		file, err := os.Open(opts.file)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		input = file
+	}
+
+	return readEvents(input, func(line int, evt event) error {
+		if err := publish(evt); err != nil {
+			return fmt.Errorf("Line %d: %s", line, err)
+		}
+
+		return nil
+	})
+}
+
+// newPublisher returns the publisher for the target.
+func newPublisher(opts *publishOptions, stdout io.Writer) (gomainevents.Publisher, error) {
+	if opts.dryRun {
+		return gomainevents.NewDryRunPublisher(nil), nil
+	}
+
+	switch opts.target {
+	case "sns":
+		return sns.NewPublisher(&sns.Config{
+			Region:   opts.region,
+			Endpoint: opts.endpoint,
+			TopicARN: opts.topicARN,
+		})
+	case "sqs":
+		client, err := newSQSClient(opts.region, opts.endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return sqs.NewPublisher(&sqs.PublisherConfig{
+			SQSClient:      client,
+			QueueURL:       opts.queueURL,
+			MessageGroupID: opts.groupID,
+		})
+	case "eventbridge":
+		return eventbridge.NewPublisher(&eventbridge.Config{
+			Region:       opts.region,
+			Endpoint:     opts.endpoint,
+			EventBusName: opts.eventBus,
+			Source:       opts.source,
+		})
+	case "jsonl":
+		if "" == opts.path {
+			return jsonl.NewPublisher(&jsonl.PublisherConfig{Writer: stdout})
+		}
+
+		return jsonl.NewPublisher(&jsonl.PublisherConfig{Path: opts.path})
+	case "log":
+		return gomainevents.NewLogPublisher(&gomainevents.LogPublisherConfig{Writer: stdout}), nil
+	case "":
+		return nil, errors.New("-target is required")
+	default:
+		return nil, fmt.Errorf("Unknown target %q", opts.target)
+	}
+}
+
+// parseEvent builds an event from a name and a JSON object of data.
+func parseEvent(name string, data string) (event, error) {
+	evt := event{name: name}
+	if err := decodeJSON([]byte(data), &evt.data); err != nil {
+		return event{}, fmt.Errorf("Invalid data: %s", err)
+	}
+
+	if nil == evt.data {
+		evt.data = map[string]interface{}{}
+	}
+
+	return evt, nil
+}
+
+// readEvents calls fn with each event of a JSONL file, along with its line
+// number, stopping at the first error. Blank lines are skipped.
+func readEvents(r io.Reader, fn func(line int, evt event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		encoded := &encodedEvent{}
+		if err := decodeJSON(raw, encoded); err != nil {
+			return fmt.Errorf("Line %d: %s", line, err)
+		}
+
+		if "" == strings.TrimSpace(encoded.Name) {
+			return fmt.Errorf("Line %d: Event name is required", line)
+		}
+
+		if nil == encoded.Data {
+			encoded.Data = map[string]interface{}{}
+		}
+
+		if err := fn(line, event{name: encoded.Name, data: encoded.Data}); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// decodeJSON decodes JSON, keeping numbers as they were written so that
+// large IDs survive the round trip.
+func decodeJSON(raw []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	if decoder.More() {
+		return errors.New("Unexpected data after the JSON object")
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishSingleEvent(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	err := run([]string{
+		"publish", "-target", "jsonl",
+		"-name", "Domain\\Event",
+		"-data", `{"id": 9007199254740993, "occurredOn": "2018-03-08 11:11:11"}`,
+	}, nil, stdout, stderr)
+
+	require.Nil(t, err)
+	assert.Equal(t, `{"name":"Domain\\Event","data":{"id":9007199254740993,"occurredOn":"2018-03-08 11:11:11"}}`+"\n", stdout.String())
+	assert.Contains(t, stderr.String(), "Published 1 events")
+}
+
+func TestPublishFile(t *testing.T) {
+	input := strings.Join([]string{
+		`{"name": "First", "data": {"n": 1}}`,
+		``,
+		`{"name": "Second"}`,
+	}, "\n")
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	err := run([]string{"publish", "-target", "jsonl", "-path", path, "-file", "-"}, strings.NewReader(input), stdout, stderr)
+	require.Nil(t, err)
+
+	written, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(written)), "\n")
+	require.Len(t, lines, 2)
+
+	second := map[string]interface{}{}
+	require.Nil(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, "Second", second["name"])
+	assert.Equal(t, map[string]interface{}{}, second["data"])
+
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "Published 2 events")
+}
+
+func TestPublishFileStopsAtBadLine(t *testing.T) {
+	input := "{\"name\": \"First\"}\nnot json\n{\"name\": \"Third\"}\n"
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	err := run([]string{"publish", "-target", "jsonl", "-file", "-"}, strings.NewReader(input), stdout, stderr)
+
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Line 2")
+	assert.Equal(t, 1, strings.Count(stdout.String(), "\n"))
+	assert.Contains(t, stderr.String(), "Published 1 events")
+}
+
+func TestPublishValidatesFlags(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	// Neither or both of -name and -file
+	assert.NotNil(t, run([]string{"publish", "-target", "log"}, nil, ioutil.Discard, stderr))
+	assert.NotNil(t, run([]string{"publish", "-target", "log", "-name", "A", "-file", "-"}, nil, ioutil.Discard, stderr))
+
+	// Missing and unknown targets
+	assert.NotNil(t, run([]string{"publish", "-name", "A"}, nil, ioutil.Discard, stderr))
+	assert.NotNil(t, run([]string{"publish", "-target", "carrier-pigeon", "-name", "A"}, nil, ioutil.Discard, stderr))
+
+	// Data must be a JSON object
+	assert.NotNil(t, run([]string{"publish", "-target", "log", "-name", "A", "-data", "[1, 2]"}, nil, ioutil.Discard, stderr))
+	assert.NotNil(t, run([]string{"publish", "-target", "log", "-name", "A", "-data", "{} {}"}, nil, ioutil.Discard, stderr))
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	assert.NotNil(t, run([]string{"frobnicate"}, nil, ioutil.Discard, stderr))
+	assert.Contains(t, stderr.String(), "publish")
+
+	assert.Equal(t, flag.ErrHelp, run(nil, nil, ioutil.Discard, stderr))
+}
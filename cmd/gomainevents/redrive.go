@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/researchsquare/gomainevents/sqs"
+)
+
+// redriveOptions are the flags of the redrive command.
+type redriveOptions struct {
+	region   string
+	endpoint string
+
+	deadLetterURL     string
+	queueURL          string
+	names             stringList
+	rate              float64
+	max               int
+	visibilityTimeout time.Duration
+	dryRun            bool
+}
+
+func runRedrive(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	opts := &redriveOptions{}
+
+	flags := newFlagSet("redrive", stderr)
+	flags.StringVar(&opts.region, "region", defaultRegion, "AWS region")
+	flags.StringVar(&opts.endpoint, "endpoint", "", "AWS endpoint, e.g. http://localhost:4566 for LocalStack")
+	flags.StringVar(&opts.deadLetterURL, "dlq-url", "", "Dead letter queue URL to move messages from. Required")
+	flags.StringVar(&opts.queueURL, "queue-url", "", "Queue URL to move messages to. Required")
+	flags.Var(&opts.names, "name", "Only move events with this name. Can be given more than once")
+	flags.Float64Var(&opts.rate, "rate", 0, "Most messages moved per second. Defaults to no limit")
+	flags.IntVar(&opts.max, "max", 0, "Stop after moving this many messages. Defaults to no limit")
+	flags.DurationVar(&opts.visibilityTimeout, "visibility-timeout", 5*time.Minute, "How long messages are hidden from other consumers of the dead letter queue while redriving")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "Show what would be moved without moving anything")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if "" == opts.deadLetterURL || "" == opts.queueURL {
+		return errors.New("-dlq-url and -queue-url are required")
+	}
+
+	client, err := newSQSClient(opts.region, opts.endpoint)
+	if err != nil {
+		return err
+	}
+
+	s3Client, err := newS3Client(opts.region, opts.endpoint)
+	if err != nil {
+		return err
+	}
+
+	config := redriveConfig(opts, stdout)
+	config.SQSClient = client
+	config.S3Client = s3Client
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return redrive(ctx, config, stderr)
+}
+
+// redriveConfig turns the flags into a redrive that reports each message on
+// stdout.
+func redriveConfig(opts *redriveOptions, stdout io.Writer) *sqs.RedriveConfig {
+	action := "move"
+	if opts.dryRun {
+		action = "would move"
+	}
+
+	return &sqs.RedriveConfig{
+		DeadLetterQueueURL: opts.deadLetterURL,
+		QueueURL:           opts.queueURL,
+		EventNames:         opts.names,
+		RatePerSecond:      opts.rate,
+		MaxMessages:        opts.max,
+		VisibilityTimeout:  opts.visibilityTimeout,
+		DryRun:             opts.dryRun,
+		OnMessage: func(message sqs.RedriveMessage) {
+			verb := "skip"
+			if message.Moved {
+				verb = action
+			}
+
+			name := message.EventName
+			if nil != message.Err {
+				name = fmt.Sprintf("(could not decode: %s)", message.Err)
+			}
+
+			fmt.Fprintf(stdout, "%s %s %s\n", verb, message.MessageID, name)
+		},
+	}
+}
+
+func redrive(ctx context.Context, config *sqs.RedriveConfig, stderr io.Writer) error {
+	redriver, err := sqs.NewRedriver(config)
+	if err != nil {
+		return err
+	}
+
+	result, err := redriver.Run(ctx)
+
+	if config.DryRun {
+		fmt.Fprintf(stderr, "Would move %d messages and leave %d\n", result.Moved, result.Skipped)
+	} else {
+		fmt.Fprintf(stderr, "Moved %d messages and left %d\n", result.Moved, result.Skipped)
+	}
+
+	return err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedriveDryRunReportsEachMessage(t *testing.T) {
+	queue := newMockQueue("Created", "Updated")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	config := redriveConfig(&redriveOptions{
+		deadLetterURL: "dlq",
+		queueURL:      "queue",
+		names:         stringList{"Updated"},
+		dryRun:        true,
+	}, stdout)
+	config.SQSClient = queue
+
+	err := redrive(context.Background(), config, stderr)
+
+	require.Nil(t, err)
+	assert.Equal(t, "skip message-0 Created\nwould move message-1 Updated\n", stdout.String())
+	assert.Contains(t, stderr.String(), "Would move 1 messages and leave 1")
+	assert.Len(t, queue.released, 2)
+}
+
+func TestRedriveRequiresQueues(t *testing.T) {
+	assert.NotNil(t, run([]string{"redrive", "-queue-url", "queue"}, nil, ioutil.Discard, ioutil.Discard))
+	assert.NotNil(t, run([]string{"redrive", "-dlq-url", "dlq"}, nil, ioutil.Discard, ioutil.Discard))
+}
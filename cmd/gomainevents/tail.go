@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/researchsquare/gomainevents/sqs"
+)
+
+// maxReceiveCount is the most messages SQS hands out per receive, and the
+// most entries it accepts per batch call.
+const maxReceiveCount = 10
+
+// tailOptions are the flags of the tail command.
+type tailOptions struct {
+	region   string
+	endpoint string
+	queueURL string
+
+	names             stringList
+	max               int
+	follow            bool
+	visibilityTimeout time.Duration
+	wait              time.Duration
+	compact           bool
+}
+
+// stringList is a flag that can be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func runTail(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	opts := &tailOptions{}
+
+	flags := newFlagSet("tail", stderr)
+	flags.StringVar(&opts.region, "region", defaultRegion, "AWS region")
+	flags.StringVar(&opts.endpoint, "endpoint", "", "AWS endpoint, e.g. http://localhost:4566 for LocalStack")
+	flags.StringVar(&opts.queueURL, "queue-url", "", "SQS queue URL. Required")
+	flags.Var(&opts.names, "name", "Only show events with this name. Can be given more than once")
+	flags.IntVar(&opts.max, "max", 0, "Stop after showing this many events. Defaults to no limit")
+	flags.BoolVar(&opts.follow, "follow", false, "Keep polling for new events once the queue is drained")
+	flags.DurationVar(&opts.visibilityTimeout, "visibility-timeout", 5*time.Minute, "How long received messages are hidden from consumers while tailing")
+	flags.DurationVar(&opts.wait, "wait", 5*time.Second, "How long each poll waits for messages, up to 20s")
+	flags.BoolVar(&opts.compact, "compact", false, "Print each event on a single line")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if "" == opts.queueURL {
+		return errors.New("-queue-url is required")
+	}
+
+	if opts.wait < 0 || opts.wait > 20*time.Second {
+		return errors.New("-wait must be between 0 and 20s")
+	}
+
+	client, err := newSQSClient(opts.region, opts.endpoint)
+	if err != nil {
+		return err
+	}
+
+	s3Client, err := newS3Client(opts.region, opts.endpoint)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return tail(ctx, client, s3Client, opts, stdout, stderr)
+}
+
+// tailer prints the events on a queue without deleting them. Received
+// messages are kept invisible so that each poll moves further through the
+// backlog, then made visible again when the tail is over.
+type tailer struct {
+	client   sqsiface.SQSAPI
+	provider *sqs.Provider
+	opts     *tailOptions
+	names    map[string]bool
+	stdout   io.Writer
+	stderr   io.Writer
+
+	// Receipt handles of the messages held invisible, by message ID.
+	held map[string]string
+
+	received int
+	shown    int
+}
+
+func tail(ctx context.Context, client sqsiface.SQSAPI, s3Client s3iface.S3API, opts *tailOptions, stdout, stderr io.Writer) error {
+	// The provider is only used to follow events offloaded to S3.
+	provider, err := sqs.NewProvider(&sqs.Config{
+		SQSClient: client,
+		S3Client:  s3Client,
+		QueueURL:  opts.queueURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	t := &tailer{
+		client:   client,
+		provider: provider,
+		opts:     opts,
+		names:    map[string]bool{},
+		stdout:   stdout,
+		stderr:   stderr,
+		held:     map[string]string{},
+	}
+	for _, name := range opts.names {
+		t.names[name] = true
+	}
+
+	err = t.run(ctx)
+
+	if releaseErr := t.release(); err == nil {
+		err = releaseErr
+	}
+
+	fmt.Fprintf(stderr, "Showed %d of %d events\n", t.shown, t.received)
+
+	return err
+}
+
+func (t *tailer) run(ctx context.Context) error {
+	for {
+		if ctx.Err() != nil || t.done() {
+			return nil
+		}
+
+		resp, err := t.client.ReceiveMessage(&awssqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(t.opts.queueURL),
+			MaxNumberOfMessages:   aws.Int64(maxReceiveCount),
+			VisibilityTimeout:     aws.Int64(int64(t.opts.visibilityTimeout / time.Second)),
+			WaitTimeSeconds:       aws.Int64(int64(t.opts.wait / time.Second)),
+			AttributeNames:        aws.StringSlice([]string{"All"}),
+			MessageAttributeNames: aws.StringSlice([]string{"All"}),
+		})
+		if err != nil {
+			return err
+		}
+
+		fresh := 0
+		for _, message := range resp.Messages {
+			id := aws.StringValue(message.MessageId)
+			_, seen := t.held[id]
+
+			// Keep the newest receipt handle, the only one that can
+			// release the message.
+			t.held[id] = aws.StringValue(message.ReceiptHandle)
+			if seen {
+				continue
+			}
+
+			fresh++
+			t.received++
+
+			if !t.done() {
+				t.show(message)
+			}
+		}
+
+		if fresh == 0 && !t.opts.follow {
+			return nil
+		}
+	}
+}
+
+// done reports whether enough events have been shown.
+func (t *tailer) done() bool {
+	return t.opts.max > 0 && t.shown >= t.opts.max
+}
+
+// show prints a message's event if it passes the filters.
+func (t *tailer) show(message *awssqs.Message) {
+	event, err := sqs.DecodeEvent(t.provider, message)
+	if err != nil {
+		fmt.Fprintf(t.stderr, "Could not decode message %s: %s\n", aws.StringValue(message.MessageId), err)
+		return
+	}
+
+	if len(t.names) > 0 && !t.names[event.Name()] {
+		return
+	}
+
+	t.shown++
+
+	header := fmt.Sprintf("%s %s retries=%d", event.Name(), aws.StringValue(message.MessageId), event.RetryCount())
+	if sent := event.SentAt(); !sent.IsZero() {
+		header = sent.UTC().Format(time.RFC3339) + " " + header
+	}
+
+	var data []byte
+	if t.opts.compact {
+		data, err = json.Marshal(event.Data())
+	} else {
+		data, err = json.MarshalIndent(event.Data(), "  ", "  ")
+	}
+	if err != nil {
+		fmt.Fprintf(t.stderr, "Could not encode event %s: %s\n", aws.StringValue(message.MessageId), err)
+		return
+	}
+
+	if t.opts.compact {
+		fmt.Fprintf(t.stdout, "%s %s\n", header, data)
+	} else {
+		fmt.Fprintf(t.stdout, "%s\n  %s\n", header, data)
+	}
+}
+
+// release makes every held message visible to consumers again.
+func (t *tailer) release() error {
+	entries := []*awssqs.ChangeMessageVisibilityBatchRequestEntry{}
+	for _, receiptHandle := range t.held {
+		entries = append(entries, &awssqs.ChangeMessageVisibilityBatchRequestEntry{
+			Id:                aws.String(strconv.Itoa(len(entries))),
+			ReceiptHandle:     aws.String(receiptHandle),
+			VisibilityTimeout: aws.Int64(0),
+		})
+	}
+
+	failed := 0
+	for start := 0; start < len(entries); start += maxReceiveCount {
+		end := start + maxReceiveCount
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		resp, err := t.client.ChangeMessageVisibilityBatch(&awssqs.ChangeMessageVisibilityBatchInput{
+			QueueUrl: aws.String(t.opts.queueURL),
+			Entries:  entries[start:end],
+		})
+		if err != nil {
+			return err
+		}
+
+		failed += len(resp.Failed)
+	}
+
+	t.held = map[string]string{}
+
+	if failed > 0 {
+		return fmt.Errorf("Could not release %d messages. They will be visible again after the visibility timeout", failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockQueue hands out its messages in order, as an SQS queue does while
+// received messages stay invisible.
+type mockQueue struct {
+	sqsiface.SQSAPI
+	messages []*awssqs.Message
+	next     int
+	receives []*awssqs.ReceiveMessageInput
+	released []string
+}
+
+func newMockQueue(names ...string) *mockQueue {
+	q := &mockQueue{}
+	for i, name := range names {
+		id := fmt.Sprintf("message-%d", i)
+		body := fmt.Sprintf(`{"Message":"{\"name\":\"%s\",\"data\":{\"n\":%d}}"}`, name, i)
+
+		q.messages = append(q.messages, &awssqs.Message{
+			MessageId:     aws.String(id),
+			ReceiptHandle: aws.String("receipt-" + id),
+			Body:          aws.String(body),
+			Attributes:    aws.StringMap(map[string]string{"SentTimestamp": "1520507471000"}),
+		})
+	}
+
+	return q
+}
+
+func (q *mockQueue) ReceiveMessage(in *awssqs.ReceiveMessageInput) (*awssqs.ReceiveMessageOutput, error) {
+	q.receives = append(q.receives, in)
+
+	end := q.next + int(aws.Int64Value(in.MaxNumberOfMessages))
+	if end > len(q.messages) {
+		end = len(q.messages)
+	}
+
+	messages := q.messages[q.next:end]
+	q.next = end
+
+	return &awssqs.ReceiveMessageOutput{Messages: messages}, nil
+}
+
+func (q *mockQueue) ChangeMessageVisibilityBatch(in *awssqs.ChangeMessageVisibilityBatchInput) (*awssqs.ChangeMessageVisibilityBatchOutput, error) {
+	for _, entry := range in.Entries {
+		q.released = append(q.released, aws.StringValue(entry.ReceiptHandle))
+	}
+
+	return &awssqs.ChangeMessageVisibilityBatchOutput{}, nil
+}
+
+func TestTailShowsAndReleasesEverything(t *testing.T) {
+	names := []string{}
+	for i := 0; i < 12; i++ {
+		names = append(names, "Created")
+	}
+
+	queue := newMockQueue(names...)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	err := tail(context.Background(), queue, nil, &tailOptions{
+		queueURL:          "queueueueueueue",
+		visibilityTimeout: time.Minute,
+		compact:           true,
+	}, stdout, stderr)
+
+	require.Nil(t, err)
+	assert.Equal(t, 12, strings.Count(stdout.String(), "\n"))
+	assert.Contains(t, stdout.String(), `2018-03-08T11:11:11Z Created message-0 retries=0 {"n":0}`)
+	assert.Contains(t, stderr.String(), "Showed 12 of 12 events")
+
+	// Messages are hidden while tailing, then made visible again
+	assert.Equal(t, int64(60), aws.Int64Value(queue.receives[0].VisibilityTimeout))
+	assert.Len(t, queue.released, 12)
+}
+
+func TestTailFiltersByName(t *testing.T) {
+	queue := newMockQueue("Created", "Updated", "Deleted", "Updated")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	err := tail(context.Background(), queue, nil, &tailOptions{
+		queueURL: "queueueueueueue",
+		names:    stringList{"Updated", "Deleted"},
+		compact:  true,
+	}, stdout, stderr)
+
+	require.Nil(t, err)
+	assert.NotContains(t, stdout.String(), "Created")
+	assert.Equal(t, 3, strings.Count(stdout.String(), "\n"))
+	assert.Contains(t, stderr.String(), "Showed 3 of 4 events")
+	assert.Len(t, queue.released, 4)
+}
+
+func TestTailStopsAtMax(t *testing.T) {
+	queue := newMockQueue("A", "B", "C", "D", "E")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	err := tail(context.Background(), queue, nil, &tailOptions{
+		queueURL: "queueueueueueue",
+		max:      2,
+	}, stdout, stderr)
+
+	require.Nil(t, err)
+	assert.Contains(t, stdout.String(), "A message-0")
+	assert.Contains(t, stdout.String(), "B message-1")
+	assert.NotContains(t, stdout.String(), "C message-2")
+
+	// Everything received is released, shown or not
+	sort.Strings(queue.released)
+	assert.Equal(t, []string{"receipt-message-0", "receipt-message-1", "receipt-message-2", "receipt-message-3", "receipt-message-4"}, queue.released)
+}
+
+func TestTailSkipsRedeliveries(t *testing.T) {
+	queue := newMockQueue("A", "B")
+
+	// The first message becomes visible again and is received twice
+	redelivered := *queue.messages[0]
+	redelivered.ReceiptHandle = aws.String("receipt-again")
+	queue.messages = append(queue.messages, &redelivered)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	err := tail(context.Background(), queue, nil, &tailOptions{queueURL: "queueueueueueue"}, stdout, stderr)
+
+	require.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(stdout.String(), "A message-0"))
+	assert.Contains(t, stderr.String(), "Showed 2 of 2 events")
+
+	// Only the newest receipt handle can change its visibility
+	assert.Contains(t, queue.released, "receipt-again")
+	assert.NotContains(t, queue.released, "receipt-message-0")
+}
+
+func TestTailReportsUndecodableMessages(t *testing.T) {
+	queue := newMockQueue("A")
+	queue.messages[0].Body = aws.String("not json")
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	err := tail(context.Background(), queue, nil, &tailOptions{queueURL: "queueueueueueue"}, stdout, stderr)
+
+	require.Nil(t, err)
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "Could not decode message message-0")
+	assert.Len(t, queue.released, 1)
+}
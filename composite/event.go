@@ -0,0 +1,26 @@
+package composite
+
+import (
+	"github.com/researchsquare/gomainevents"
+)
+
+// Event wraps an event from one of a composite provider's sources, so that
+// it can be handed back to the right one.
+type Event struct {
+	gomainevents.Event
+
+	// Index of the source the event came from.
+	source int
+}
+
+// Unwrap returns the event as its source provided it, for handlers that
+// need provider-specific details.
+func (e Event) Unwrap() gomainevents.Event {
+	return e.Event
+}
+
+// Priority returns the priority of the source the event came from, where
+// 0 is the highest.
+func (e Event) Priority() int {
+	return e.source
+}
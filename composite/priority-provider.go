@@ -0,0 +1,288 @@
+package composite
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// PriorityProvider combines several providers into one stream of events.
+// Whenever the Listener is ready for an event, it gets one from the
+// highest-priority provider that has one waiting, so that a priority queue
+// is always drained before a bulk queue, for example. Events are wrapped in
+// an Event so that Delete and Requeue reach the provider they came from.
+//
+// One event from each provider is held back while choosing. Any still held
+// when the provider stops are neither deleted nor requeued, so they are
+// redelivered however their provider handles abandoned events. Once every
+// provider has closed its channel, a fatal error is reported and the
+// channels are closed, so that the Listener stops.
+type PriorityProvider struct {
+	providers []gomainevents.Provider
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+	closed  bool
+}
+
+type PriorityConfig struct {
+	// Providers to combine, highest priority first. Required
+	Providers []gomainevents.Provider
+}
+
+func NewPriorityProvider(config *PriorityConfig) (*PriorityProvider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if len(config.Providers) == 0 {
+		return nil, errors.New("Providers are required")
+	}
+
+	return &PriorityProvider{
+		providers: append([]gomainevents.Provider{}, config.Providers...),
+
+		// Unbuffered, so that the choice between providers is made when the
+		// Listener is ready rather than when an event arrives.
+		events: make(chan gomainevents.Event),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}, nil
+}
+
+// Return a channel that can be used to retrieve events
+func (p *PriorityProvider) Start() (<-chan gomainevents.Event, <-chan error) {
+	sources := make([]<-chan gomainevents.Event, len(p.providers))
+	for i, provider := range p.providers {
+		events, errs := provider.Start()
+		sources[i] = events
+
+		go func() {
+			for err := range errs {
+				p.reportError(gomainevents.PhaseUnknown, "", err)
+			}
+		}()
+	}
+
+	go p.run(sources)
+
+	return p.events, p.errors
+}
+
+// run hands events to the Listener, highest priority first. One event is
+// held back from each source, so that whenever the Listener is ready the
+// best of them can be chosen, and a high-priority event that turns up while
+// a low-priority one is waiting goes first.
+func (p *PriorityProvider) run(sources []<-chan gomainevents.Event) {
+	heads := make([]gomainevents.Event, len(sources))
+
+	for {
+		// Take whatever is already waiting, so that the choice below is
+		// as up to date as possible.
+		for i, source := range sources {
+			if nil != heads[i] || nil == source {
+				continue
+			}
+
+			select {
+			case event, ok := <-source:
+				if !ok {
+					sources[i] = nil
+					continue
+				}
+
+				heads[i] = event
+			default:
+			}
+		}
+
+		best := -1
+		open := false
+		for i := range sources {
+			if best < 0 && nil != heads[i] {
+				best = i
+			}
+
+			open = open || nil != sources[i]
+		}
+
+		if best < 0 && !open {
+			p.reportFatal(errors.New("Every provider closed its channel"))
+			p.closeChannels()
+			return
+		}
+
+		if !p.wait(sources, heads, best) {
+			return
+		}
+	}
+}
+
+// wait blocks until the best event has been delivered, or another source
+// has an event, updating heads and sources. It returns false if the provider
+// was stopped.
+func (p *PriorityProvider) wait(sources []<-chan gomainevents.Event, heads []gomainevents.Event, best int) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	// The first case is the done channel and the last the delivery. A
+	// case with a zero Chan is ignored, which leaves out sources that are
+	// closed or already have an event held back.
+	cases := make([]reflect.SelectCase, len(sources)+2)
+	cases[0] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(p.done)}
+	for i, source := range sources {
+		cases[i+1] = reflect.SelectCase{Dir: reflect.SelectRecv}
+		if nil == heads[i] && nil != source {
+			cases[i+1].Chan = reflect.ValueOf(source)
+		}
+	}
+
+	deliver := len(cases) - 1
+	cases[deliver] = reflect.SelectCase{Dir: reflect.SelectSend}
+	if best >= 0 {
+		cases[deliver].Chan = reflect.ValueOf(p.events)
+		cases[deliver].Send = reflect.ValueOf(gomainevents.Event(Event{Event: heads[best], source: best}))
+	}
+
+	chosen, value, ok := reflect.Select(cases)
+	switch {
+	case chosen == 0:
+		return false
+	case chosen == deliver:
+		heads[best] = nil
+	case !ok:
+		sources[chosen-1] = nil
+	default:
+		heads[chosen-1] = value.Interface().(gomainevents.Event)
+	}
+
+	return true
+}
+
+// Delete an event that we're done with
+func (p *PriorityProvider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to composite flavor
+
+	p.providers[evt.source].Delete(evt.Event)
+}
+
+// Requeue an event for later
+func (p *PriorityProvider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to composite flavor
+
+	return p.providers[evt.source].Requeue(evt.Event)
+}
+
+// Defer an event, if the provider it came from can. See
+// gomainevents.DeferringProvider.
+func (p *PriorityProvider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to composite flavor
+
+	deferring, ok := p.providers[evt.source].(gomainevents.DeferringProvider)
+	if !ok {
+		return gomainevents.ErrDeferNotSupported
+	}
+
+	return deferring.Defer(evt.Event, delay)
+}
+
+// Stop the channel
+func (p *PriorityProvider) Stop() {
+	close(p.done)
+
+	for _, provider := range p.providers {
+		provider.Stop()
+	}
+
+	p.closeChannels()
+}
+
+// closeChannels closes the events and errors channels, unless they already
+// have been.
+func (p *PriorityProvider) closeChannels() {
+	p.closeMu.Lock()
+	defer p.closeMu.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	close(p.events)
+	close(p.errors)
+	p.closed = true
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *PriorityProvider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	if p.closed {
+		return
+	}
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, "", messageID, err):
+	default:
+	}
+}
+
+// reportFatal passes on an error that stops the provider, waiting until it
+// is read or the provider is stopped.
+func (p *PriorityProvider) reportFatal(err error) {
+	p.debugPrint("Fatal error: %s\n", err)
+
+	providerErr := gomainevents.NewProviderError(gomainevents.PhaseReceive, "", "", err)
+	providerErr.Retryable = false
+	providerErr.Fatal = true
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	if p.closed {
+		return
+	}
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- providerErr:
+	}
+}
+
+func (p *PriorityProvider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-composite] "+format, values...)
+	}
+}
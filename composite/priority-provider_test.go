@@ -0,0 +1,256 @@
+package composite
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+// mockProvider hands out whatever is sent on its channels and records what
+// happens to each event.
+type mockProvider struct {
+	events chan gomainevents.Event
+	errors chan error
+
+	mu       sync.Mutex
+	deleted  []string
+	requeued []string
+	stopped  bool
+	closed   bool
+}
+
+func newMockProvider(names ...string) *mockProvider {
+	m := &mockProvider{
+		events: make(chan gomainevents.Event, 10),
+		errors: make(chan error, 1),
+	}
+	for _, name := range names {
+		m.events <- testEvent{name: name}
+	}
+
+	return m
+}
+
+func (m *mockProvider) Start() (<-chan gomainevents.Event, <-chan error) {
+	return m.events, m.errors
+}
+
+func (m *mockProvider) Delete(event gomainevents.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleted = append(m.deleted, event.(testEvent).name)
+}
+
+func (m *mockProvider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requeued = append(m.requeued, event.(testEvent).name)
+	return nil
+}
+
+func (m *mockProvider) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stopped = true
+	if !m.closed {
+		close(m.events)
+	}
+	close(m.errors)
+}
+
+// close closes the events channel, as providers do when they run out.
+func (m *mockProvider) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	close(m.events)
+}
+
+func receive(t *testing.T, events <-chan gomainevents.Event) Event {
+	select {
+	case event := <-events:
+		return event.(Event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	return Event{}
+}
+
+func TestNewPriorityProvider(t *testing.T) {
+	provider, err := NewPriorityProvider(&PriorityConfig{Providers: []gomainevents.Provider{newMockProvider()}})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewPriorityProvider(&PriorityConfig{})
+	assert.Nil(t, provider)
+	assert.EqualError(t, err, "Providers are required")
+
+	provider, err = NewPriorityProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+}
+
+func TestPriorityProviderDrainsHigherPriorityFirst(t *testing.T) {
+	high := newMockProvider("High1", "High2")
+	low := newMockProvider("Low1", "Low2")
+
+	provider, err := NewPriorityProvider(&PriorityConfig{Providers: []gomainevents.Provider{high, low}})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+
+	names := []string{}
+	for i := 0; i < 3; i++ {
+		names = append(names, receive(t, events).Name())
+	}
+	assert.Equal(t, []string{"High1", "High2", "Low1"}, names)
+
+	// A high-priority event jumps the rest of the bulk queue
+	high.events <- testEvent{name: "High3"}
+	time.Sleep(10 * time.Millisecond)
+
+	event := receive(t, events)
+	assert.Equal(t, "High3", event.Name())
+	assert.Equal(t, 0, event.Priority())
+
+	event = receive(t, events)
+	assert.Equal(t, "Low2", event.Name())
+	assert.Equal(t, 1, event.Priority())
+	assert.Equal(t, testEvent{name: "Low2"}, event.Unwrap())
+
+	provider.Stop()
+	assert.True(t, high.stopped)
+	assert.True(t, low.stopped)
+}
+
+func TestPriorityProviderWaitsForAnySource(t *testing.T) {
+	high := newMockProvider()
+	low := newMockProvider()
+
+	provider, err := NewPriorityProvider(&PriorityConfig{Providers: []gomainevents.Provider{high, low}})
+	require.Nil(t, err)
+	defer provider.Stop()
+
+	events, _ := provider.Start()
+
+	low.events <- testEvent{name: "Low"}
+	assert.Equal(t, "Low", receive(t, events).Name())
+}
+
+func TestPriorityProviderRoutesOutcomes(t *testing.T) {
+	high := newMockProvider("High")
+	low := newMockProvider("Low")
+
+	provider, err := NewPriorityProvider(&PriorityConfig{Providers: []gomainevents.Provider{high, low}})
+	require.Nil(t, err)
+	defer provider.Stop()
+
+	events, _ := provider.Start()
+
+	first := receive(t, events)
+	second := receive(t, events)
+
+	provider.Delete(first)
+	assert.Nil(t, provider.Requeue(second))
+
+	assert.Equal(t, []string{"High"}, high.deleted)
+	assert.Equal(t, []string{"Low"}, low.requeued)
+	assert.Empty(t, high.requeued)
+	assert.Empty(t, low.deleted)
+}
+
+func TestPriorityProviderForwardsErrors(t *testing.T) {
+	low := newMockProvider()
+
+	provider, err := NewPriorityProvider(&PriorityConfig{Providers: []gomainevents.Provider{newMockProvider(), low}})
+	require.Nil(t, err)
+	defer provider.Stop()
+
+	_, errs := provider.Start()
+
+	low.errors <- errors.New("Oops")
+
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "Oops")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error")
+	}
+}
+
+func TestPriorityProviderStopsListenerWhenEverySourceCloses(t *testing.T) {
+	high := newMockProvider()
+	low := newMockProvider()
+
+	provider, err := NewPriorityProvider(&PriorityConfig{Providers: []gomainevents.Provider{high, low}})
+	require.Nil(t, err)
+	provider.debug = false
+
+	listener := gomainevents.NewListener(provider)
+	listener.RegisterHandler("Thing", func(gomainevents.Event) error { return nil })
+
+	result := make(chan error, 1)
+	go func() {
+		result <- listener.Listen()
+	}()
+
+	high.close()
+	low.close()
+
+	select {
+	case err := <-result:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the listener to stop")
+	}
+}
+
+func TestPriorityProviderReportsEverySourceClosing(t *testing.T) {
+	high := newMockProvider()
+	low := newMockProvider()
+
+	provider, err := NewPriorityProvider(&PriorityConfig{Providers: []gomainevents.Provider{high, low}})
+	require.Nil(t, err)
+	provider.debug = false
+	defer provider.Stop()
+
+	events, errs := provider.Start()
+
+	// Fill the errors channel, which used to leave no room for the error
+	low.errors <- errors.New("Oops")
+	assert.Eventually(t, func() bool { return len(errs) == 1 }, 5*time.Second, time.Millisecond)
+
+	high.close()
+	low.close()
+
+	assert.EqualError(t, <-errs, "Oops")
+
+	var providerErr *gomainevents.ProviderError
+	require.True(t, errors.As(<-errs, &providerErr))
+	assert.True(t, providerErr.Fatal)
+
+	_, ok := <-events
+	assert.False(t, ok)
+}
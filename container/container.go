@@ -0,0 +1,260 @@
+// Package container builds event handlers from constructors and registers
+// them with a Listener, so that services with many handlers don't have to
+// wire each one up by hand. Constructors are plain functions: their
+// parameters are the dependencies they need and their result is what they
+// provide, optionally followed by an error.
+//
+//	c := container.New()
+//	c.Supply(db, logger)
+//	c.Provide(NewOrderRepository)
+//	c.Handle("OrderPlaced", NewOrderPlacedHandler)
+//	c.Handler(NewInvoiceHandler)
+//
+//	if err := c.Register(listener); err != nil {
+//		log.Fatal(err)
+//	}
+package container
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Handler is implemented by handlers that declare the event they handle
+// themselves. See Container.Handler.
+type Handler interface {
+	EventName() string
+	Handle(event gomainevents.Event) error
+}
+
+var (
+	errorType        = reflect.TypeOf((*error)(nil)).Elem()
+	handlerType      = reflect.TypeOf((*Handler)(nil)).Elem()
+	eventHandlerType = reflect.TypeOf(gomainevents.EventHandler(nil))
+)
+
+// constructor is a function that provides a value.
+type constructor struct {
+	fn   reflect.Value
+	name string
+}
+
+// newConstructor checks that fn is a function returning a value and
+// optionally an error.
+func newConstructor(fn interface{}) (*constructor, error) {
+	value := reflect.ValueOf(fn)
+	if reflect.Func != value.Kind() {
+		return nil, fmt.Errorf("Constructor must be a function, not %T", fn)
+	}
+
+	c := &constructor{fn: value, name: runtime.FuncForPC(value.Pointer()).Name()}
+
+	kind := value.Type()
+	if kind.NumOut() < 1 || kind.NumOut() > 2 || (2 == kind.NumOut() && errorType != kind.Out(1)) {
+		return nil, fmt.Errorf("Constructor %s must return a value and optionally an error", c.name)
+	}
+
+	return c, nil
+}
+
+func (c *constructor) provides() reflect.Type {
+	return c.fn.Type().Out(0)
+}
+
+// handler is a handler that hasn't been built yet.
+type handler struct {
+	name        string
+	constructor *constructor
+}
+
+// Container holds the constructors of handlers and their dependencies. Every
+// dependency is built once, when the first handler that needs it is, and
+// shared between handlers.
+type Container struct {
+	constructors map[reflect.Type]*constructor
+	values       map[reflect.Type]reflect.Value
+	handlers     []handler
+}
+
+func New() *Container {
+	return &Container{
+		constructors: map[reflect.Type]*constructor{},
+		values:       map[reflect.Type]reflect.Value{},
+	}
+}
+
+// Supply adds values that are already built, by their type. To supply a value
+// as an interface, Provide a function returning it instead.
+func (c *Container) Supply(values ...interface{}) error {
+	for _, value := range values {
+		if nil == value {
+			return errors.New("Can't supply nil")
+		}
+
+		kind := reflect.TypeOf(value)
+		if err := c.checkUnprovided(kind); err != nil {
+			return err
+		}
+
+		c.values[kind] = reflect.ValueOf(value)
+	}
+
+	return nil
+}
+
+// Provide adds constructors for dependencies, by the type they return.
+func (c *Container) Provide(constructors ...interface{}) error {
+	for _, fn := range constructors {
+		constructor, err := newConstructor(fn)
+		if err != nil {
+			return err
+		}
+
+		if err := c.checkUnprovided(constructor.provides()); err != nil {
+			return err
+		}
+
+		c.constructors[constructor.provides()] = constructor
+	}
+
+	return nil
+}
+
+func (c *Container) checkUnprovided(kind reflect.Type) error {
+	_, supplied := c.values[kind]
+	_, provided := c.constructors[kind]
+	if supplied || provided {
+		return fmt.Errorf("%s is already provided", kind)
+	}
+
+	return nil
+}
+
+// Handle adds the constructor of a handler for the named event. It returns a
+// gomainevents.EventHandler or a func(gomainevents.Event) error.
+func (c *Container) Handle(name string, fn interface{}) error {
+	constructor, err := newConstructor(fn)
+	if err != nil {
+		return err
+	}
+
+	if !constructor.provides().ConvertibleTo(eventHandlerType) {
+		return fmt.Errorf("Constructor %s must return a gomainevents.EventHandler", constructor.name)
+	}
+
+	c.handlers = append(c.handlers, handler{name: name, constructor: constructor})
+
+	return nil
+}
+
+// Handler adds constructors of handlers that say which event they handle. They
+// return a Handler.
+func (c *Container) Handler(constructors ...interface{}) error {
+	for _, fn := range constructors {
+		constructor, err := newConstructor(fn)
+		if err != nil {
+			return err
+		}
+
+		if !constructor.provides().Implements(handlerType) {
+			return fmt.Errorf("Constructor %s must return a container.Handler", constructor.name)
+		}
+
+		c.handlers = append(c.handlers, handler{constructor: constructor})
+	}
+
+	return nil
+}
+
+// Register builds every handler, along with the dependencies they need, and
+// registers them with the Listener in the order they were added. Nothing is
+// registered if any of them can't be built.
+func (c *Container) Register(listener *gomainevents.Listener) error {
+	type built struct {
+		name string
+		fn   gomainevents.EventHandler
+	}
+
+	handlers := make([]built, 0, len(c.handlers))
+	for _, h := range c.handlers {
+		value, err := c.call(h.constructor, nil)
+		if err != nil {
+			return err
+		}
+
+		if "" != h.name {
+			handlers = append(handlers, built{name: h.name, fn: value.Convert(eventHandlerType).Interface().(gomainevents.EventHandler)})
+			continue
+		}
+
+		handler := value.Interface().(Handler)
+		handlers = append(handlers, built{name: handler.EventName(), fn: handler.Handle})
+	}
+
+	for _, h := range handlers {
+		listener.RegisterHandler(h.name, h.fn)
+	}
+
+	return nil
+}
+
+// resolve returns the value of a type, building it if needed. building holds
+// the constructors already being called, to catch cycles.
+func (c *Container) resolve(kind reflect.Type, building []*constructor) (reflect.Value, error) {
+	if value, ok := c.values[kind]; ok {
+		return value, nil
+	}
+
+	constructor, ok := c.constructors[kind]
+	if !ok {
+		return reflect.Value{}, &MissingDependencyError{Type: kind, Constructor: building[len(building)-1].name}
+	}
+
+	for i, b := range building {
+		if b == constructor {
+			names := []string{}
+			for _, b := range append(building[i:], constructor) {
+				names = append(names, b.name)
+			}
+
+			return reflect.Value{}, fmt.Errorf("Dependency cycle: %s", strings.Join(names, " -> "))
+		}
+	}
+
+	value, err := c.call(constructor, building)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	c.values[kind] = value
+
+	return value, nil
+}
+
+// call calls a constructor with its dependencies.
+func (c *Container) call(constructor *constructor, building []*constructor) (reflect.Value, error) {
+	building = append(building, constructor)
+
+	kind := constructor.fn.Type()
+	args := make([]reflect.Value, kind.NumIn())
+	for i := range args {
+		value, err := c.resolve(kind.In(i), building)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		args[i] = value
+	}
+
+	results := constructor.fn.Call(args)
+	if 2 == len(results) && !results[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("%s: %w", constructor.name, results[1].Interface().(error))
+	}
+
+	return results[0], nil
+}
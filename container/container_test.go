@@ -0,0 +1,151 @@
+package container
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+type config struct {
+	prefix string
+}
+
+type store struct {
+	config *config
+	saved  []string
+}
+
+func newStore(config *config) *store {
+	return &store{config: config}
+}
+
+// invoiceHandler declares the event it handles.
+type invoiceHandler struct {
+	store *store
+}
+
+func newInvoiceHandler(store *store) (*invoiceHandler, error) {
+	return &invoiceHandler{store: store}, nil
+}
+
+func (h *invoiceHandler) EventName() string {
+	return "InvoiceSent"
+}
+
+func (h *invoiceHandler) Handle(event gomainevents.Event) error {
+	h.store.saved = append(h.store.saved, h.store.config.prefix+event.Name())
+	return nil
+}
+
+func newOrderHandler(store *store) func(gomainevents.Event) error {
+	return func(event gomainevents.Event) error {
+		store.saved = append(store.saved, store.config.prefix+event.Name())
+		return nil
+	}
+}
+
+func newListener() *gomainevents.Listener {
+	return gomainevents.NewListener(memory.NewProvider(&memory.Config{}))
+}
+
+func TestContainerRegistersHandlers(t *testing.T) {
+	built := 0
+
+	c := New()
+	require.Nil(t, c.Supply(&config{prefix: "saved:"}))
+	require.Nil(t, c.Provide(func(config *config) *store {
+		built++
+		return newStore(config)
+	}))
+	require.Nil(t, c.Handle("OrderPlaced", newOrderHandler))
+	require.Nil(t, c.Handler(newInvoiceHandler))
+
+	listener := newListener()
+	require.Nil(t, c.Register(listener))
+
+	assert.Equal(t, []string{"InvoiceSent", "OrderPlaced"}, listener.RegisteredEvents())
+
+	require.Nil(t, listener.Handlers("OrderPlaced")[0](testEvent{name: "OrderPlaced"}))
+	require.Nil(t, listener.Handlers("InvoiceSent")[0](testEvent{name: "InvoiceSent"}))
+
+	// The handlers share the store
+	assert.Equal(t, 1, built)
+
+	s, err := c.resolve(reflect.TypeOf(&store{}), nil)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"saved:OrderPlaced", "saved:InvoiceSent"}, s.Interface().(*store).saved)
+}
+
+func TestContainerRejectsBadConstructors(t *testing.T) {
+	c := New()
+
+	assert.EqualError(t, c.Provide("store"), "Constructor must be a function, not string")
+	assert.Error(t, c.Provide(func() {}))
+	assert.Error(t, c.Provide(func() (*store, string) { return nil, "" }))
+	assert.EqualError(t, c.Supply(nil), "Can't supply nil")
+
+	require.Nil(t, c.Supply(&config{}))
+	assert.EqualError(t, c.Provide(func() *config { return nil }), "*container.config is already provided")
+
+	assert.Error(t, c.Handle("OrderPlaced", newStore))
+	assert.Error(t, c.Handler(newOrderHandler))
+}
+
+func TestContainerReportsMissingDependencies(t *testing.T) {
+	c := New()
+	require.Nil(t, c.Provide(newStore))
+	require.Nil(t, c.Handle("OrderPlaced", newOrderHandler))
+
+	listener := newListener()
+	err := c.Register(listener)
+
+	missing := &MissingDependencyError{}
+	require.True(t, errors.As(err, &missing))
+	assert.Equal(t, reflect.TypeOf(&config{}), missing.Type)
+	assert.Contains(t, missing.Constructor, "newStore")
+	assert.Empty(t, listener.RegisteredEvents())
+}
+
+type chicken struct{}
+type egg struct{}
+
+func TestContainerReportsCycles(t *testing.T) {
+	c := New()
+	require.Nil(t, c.Provide(
+		func(*egg) *chicken { return &chicken{} },
+		func(*chicken) *egg { return &egg{} },
+	))
+	require.Nil(t, c.Handle("Hatched", func(*chicken) gomainevents.EventHandler { return nil }))
+
+	err := c.Register(newListener())
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Dependency cycle: ")
+}
+
+func TestContainerReportsConstructorErrors(t *testing.T) {
+	c := New()
+	require.Nil(t, c.Provide(func() (*config, error) { return nil, errors.New("No config") }))
+	require.Nil(t, c.Provide(newStore))
+	require.Nil(t, c.Handler(newInvoiceHandler))
+
+	err := c.Register(newListener())
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "No config")
+}
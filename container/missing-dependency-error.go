@@ -0,0 +1,17 @@
+package container
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MissingDependencyError is returned by Register when a constructor needs a
+// type that nothing provides.
+type MissingDependencyError struct {
+	Type        reflect.Type
+	Constructor string
+}
+
+func (e *MissingDependencyError) Error() string {
+	return fmt.Sprintf("Nothing provides %s, needed by %s", e.Type, e.Constructor)
+}
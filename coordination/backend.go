@@ -0,0 +1,84 @@
+package coordination
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backend keeps leases on keys, shared by every instance. Acquiring a lease
+// has to be atomic, so that only one instance can hold it.
+type Backend interface {
+	// Acquire takes a lease on a key for an owner, or extends it if the
+	// owner already holds it. It returns false if someone else holds it.
+	Acquire(key string, owner string, ttl time.Duration) (bool, error)
+
+	// Release gives up an owner's lease on a key. Leases held by others
+	// are left alone.
+	Release(key string, owner string) error
+
+	// Holders returns the owners of the unexpired leases on keys starting
+	// with prefix, by key.
+	Holders(prefix string) (map[string]string, error)
+}
+
+// MemoryBackend keeps leases in memory. It only coordinates instances
+// within a single process, so it is mostly useful for tests.
+type MemoryBackend struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+
+	// Hook for tests
+	now func() time.Time
+}
+
+type memoryLease struct {
+	owner     string
+	expiresAt time.Time
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		leases: map[string]memoryLease{},
+		now:    time.Now,
+	}
+}
+
+func (b *MemoryBackend) Acquire(key string, owner string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if lease, ok := b.leases[key]; ok && owner != lease.owner && now.Before(lease.expiresAt) {
+		return false, nil
+	}
+
+	b.leases[key] = memoryLease{owner: owner, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (b *MemoryBackend) Release(key string, owner string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if lease, ok := b.leases[key]; ok && owner == lease.owner {
+		delete(b.leases, key)
+	}
+
+	return nil
+}
+
+func (b *MemoryBackend) Holders(prefix string) (map[string]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	holders := map[string]string{}
+	for key, lease := range b.leases {
+		if strings.HasPrefix(key, prefix) && now.Before(lease.expiresAt) {
+			holders[key] = lease.owner
+		}
+	}
+
+	return holders, nil
+}
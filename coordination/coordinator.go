@@ -0,0 +1,369 @@
+package coordination
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultName  = "gomainevents"
+	defaultLease = 30 * time.Second
+)
+
+// Coordinator divides shards, e.g. queues or partitions, between a fleet
+// of instances, so that each shard is consumed by exactly one of them:
+//
+//	coordinator, _ := coordination.NewCoordinator(&coordination.Config{
+//		Backend: backend,
+//		Shards:  queueURLs,
+//		OnAcquire: func(queueURL string) {
+//			// Start consuming the queue
+//		},
+//		OnRelease: func(queueURL string) {
+//			// Stop consuming the queue
+//		},
+//	})
+//	coordinator.Start()
+//	defer coordinator.Stop()
+//
+// Every instance holds a membership lease, and shards are assigned to the
+// live members by rendezvous hashing, so when an instance comes or goes
+// only the shards that have to move do. An instance only starts on a shard
+// once it holds the shard's lease, which the previous owner gives up after
+// OnRelease returns, or which runs out if the previous owner died. One
+// instance is elected leader, e.g. for scheduled jobs that should only run
+// once.
+type Coordinator struct {
+	backend  Backend
+	name     string
+	instance string
+	shards   []string
+	lease    time.Duration
+	interval time.Duration
+
+	onAcquire func(shard string)
+	onRelease func(shard string)
+	onError   gomainevents.ErrorHandler
+
+	mu      sync.Mutex
+	owned   map[string]bool
+	leader  bool
+	members []string
+
+	done     chan bool
+	stopOnce sync.Once
+	stopped  sync.WaitGroup
+}
+
+type Config struct {
+	// Backend leases are kept in. Required
+	Backend Backend
+
+	// Shards to divide between the instances. Every instance must be
+	// given the same ones. Required
+	Shards []string
+
+	// Prefixed to the keys leases are kept under, so that several fleets
+	// can share a backend. Defaults to "gomainevents".
+	Name string
+
+	// Identifies this instance. Defaults to the hostname and process ID.
+	Instance string
+
+	// How long leases last without being renewed. An instance that dies
+	// holds on to its shards this long. Defaults to 30 seconds.
+	Lease time.Duration
+
+	// How often leases are renewed and shards rebalanced. Must be shorter
+	// than Lease. Defaults to a third of Lease.
+	Interval time.Duration
+
+	// Called when this instance is assigned a shard, and when it has to
+	// give one up. OnRelease should stop consuming the shard before it
+	// returns, since another instance may start straight after.
+	OnAcquire func(shard string)
+	OnRelease func(shard string)
+
+	// Called with errors from the backend. Optional
+	OnError gomainevents.ErrorHandler
+}
+
+func NewCoordinator(config *Config) (*Coordinator, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Backend {
+		return nil, errors.New("Backend is required")
+	}
+
+	if len(config.Shards) == 0 {
+		return nil, errors.New("Shards are required")
+	}
+
+	name := config.Name
+	if "" == name {
+		name = defaultName
+	}
+
+	instance := config.Instance
+	if "" == instance {
+		hostname, _ := os.Hostname()
+		instance = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	lease := defaultLease
+	if config.Lease > 0 {
+		lease = config.Lease
+	}
+
+	interval := lease / 3
+	if config.Interval > 0 {
+		interval = config.Interval
+	}
+
+	if interval >= lease {
+		return nil, errors.New("Interval must be shorter than Lease")
+	}
+
+	return &Coordinator{
+		backend:   config.Backend,
+		name:      name,
+		instance:  instance,
+		shards:    append([]string{}, config.Shards...),
+		lease:     lease,
+		interval:  interval,
+		onAcquire: config.OnAcquire,
+		onRelease: config.OnRelease,
+		onError:   config.OnError,
+		owned:     map[string]bool{},
+		done:      make(chan bool),
+	}, nil
+}
+
+// Start joins the fleet and rebalances straight away and then every
+// Interval until Stop is called.
+func (c *Coordinator) Start() {
+	c.Rebalance()
+
+	c.stopped.Add(1)
+	go func() {
+		defer c.stopped.Done()
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+				c.Rebalance()
+			}
+		}
+	}()
+}
+
+// Stop leaves the fleet, releasing this instance's shards so that the
+// others can take them over without waiting for the leases to run out.
+func (c *Coordinator) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.done)
+		c.stopped.Wait()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		for _, shard := range c.ownedShards() {
+			c.release(shard)
+		}
+
+		if c.leader {
+			c.leader = false
+			c.reportError(c.backend.Release(c.leaderKey(), c.instance))
+		}
+
+		c.reportError(c.backend.Release(c.memberKey(c.instance), c.instance))
+	})
+}
+
+// Rebalance renews this instance's leases, then gives up the shards that
+// are now assigned to other instances and takes the ones assigned to it.
+func (c *Coordinator) Rebalance() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.backend.Acquire(c.memberKey(c.instance), c.instance, c.lease); err != nil {
+		c.reportError(err)
+		return
+	}
+
+	leader, err := c.backend.Acquire(c.leaderKey(), c.instance, c.lease)
+	c.reportError(err)
+	c.leader = leader && nil == err
+
+	members, err := c.liveMembers()
+	if err != nil {
+		c.reportError(err)
+		return
+	}
+	c.members = members
+
+	// Release first, so that shards move in a single round.
+	for _, shard := range c.ownedShards() {
+		if c.instance != assign(shard, members) {
+			c.release(shard)
+		}
+	}
+
+	for _, shard := range c.shards {
+		if c.instance != assign(shard, members) {
+			continue
+		}
+
+		acquired, err := c.backend.Acquire(c.shardKey(shard), c.instance, c.lease)
+		if err != nil {
+			// Keep consuming an owned shard, the lease may still be good.
+			c.reportError(err)
+			continue
+		}
+
+		switch {
+		case acquired && !c.owned[shard]:
+			c.owned[shard] = true
+			if nil != c.onAcquire {
+				c.onAcquire(shard)
+			}
+		case !acquired && c.owned[shard]:
+			// The lease ran out and someone else took it, e.g. while
+			// this instance was paused.
+			delete(c.owned, shard)
+			if nil != c.onRelease {
+				c.onRelease(shard)
+			}
+		}
+	}
+}
+
+// Owned returns the shards this instance holds, sorted.
+func (c *Coordinator) Owned() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ownedShards()
+}
+
+// Leader returns whether this instance was elected leader as of the last
+// rebalance.
+func (c *Coordinator) Leader() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.leader
+}
+
+// Members returns the instances that were live as of the last rebalance,
+// sorted.
+func (c *Coordinator) Members() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string{}, c.members...)
+}
+
+// Instance returns the name this instance goes by.
+func (c *Coordinator) Instance() string {
+	return c.instance
+}
+
+func (c *Coordinator) release(shard string) {
+	delete(c.owned, shard)
+	if nil != c.onRelease {
+		c.onRelease(shard)
+	}
+
+	c.reportError(c.backend.Release(c.shardKey(shard), c.instance))
+}
+
+func (c *Coordinator) liveMembers() ([]string, error) {
+	holders, err := c.backend.Holders(c.memberKey(""))
+	if err != nil {
+		return nil, err
+	}
+
+	members := []string{c.instance}
+	for _, owner := range holders {
+		if c.instance != owner {
+			members = append(members, owner)
+		}
+	}
+
+	sort.Strings(members)
+	return members, nil
+}
+
+func (c *Coordinator) ownedShards() []string {
+	shards := make([]string, 0, len(c.owned))
+	for shard := range c.owned {
+		shards = append(shards, shard)
+	}
+
+	sort.Strings(shards)
+	return shards
+}
+
+func (c *Coordinator) memberKey(instance string) string {
+	return c.name + "/members/" + instance
+}
+
+func (c *Coordinator) leaderKey() string {
+	return c.name + "/leader"
+}
+
+func (c *Coordinator) shardKey(shard string) string {
+	return c.name + "/shards/" + shard
+}
+
+func (c *Coordinator) reportError(err error) {
+	if err != nil && nil != c.onError {
+		c.onError(err)
+	}
+}
+
+// assign picks the member a shard belongs to by rendezvous hashing: the
+// member with the highest score for the shard. Members are sorted, so ties
+// go to the first.
+func assign(shard string, members []string) string {
+	best, bestScore := "", uint64(0)
+	for _, member := range members {
+		h := fnv.New64a()
+		h.Write([]byte(member))
+		h.Write([]byte{0})
+		h.Write([]byte(shard))
+
+		if score := mix(h.Sum64()); "" == best || score > bestScore {
+			best, bestScore = member, score
+		}
+	}
+
+	return best
+}
+
+// mix spreads the bits of a hash, since FNV alone leaves names that differ
+// only at the end, e.g. worker-1 and worker-2, scoring alike.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+
+	return h
+}
@@ -0,0 +1,217 @@
+package coordination
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testShards = []string{"queue-1", "queue-2", "queue-3", "queue-4", "queue-5", "queue-6"}
+
+// testInstance records the shards a coordinator was told to consume.
+type testInstance struct {
+	*Coordinator
+	consuming map[string]bool
+}
+
+func newTestInstance(t *testing.T, backend Backend, name string) *testInstance {
+	instance := &testInstance{consuming: map[string]bool{}}
+
+	coordinator, err := NewCoordinator(&Config{
+		Backend:  backend,
+		Shards:   testShards,
+		Instance: name,
+		OnAcquire: func(shard string) {
+			assert.False(t, instance.consuming[shard])
+			instance.consuming[shard] = true
+		},
+		OnRelease: func(shard string) {
+			assert.True(t, instance.consuming[shard])
+			delete(instance.consuming, shard)
+		},
+		OnError: func(err error) { t.Error(err) },
+	})
+	require.Nil(t, err)
+
+	instance.Coordinator = coordinator
+	return instance
+}
+
+func (i *testInstance) consumed() []string {
+	shards := []string{}
+	for shard := range i.consuming {
+		shards = append(shards, shard)
+	}
+
+	sort.Strings(shards)
+	return shards
+}
+
+func TestNewCoordinator(t *testing.T) {
+	coordinator, err := NewCoordinator(&Config{Backend: NewMemoryBackend(), Shards: testShards})
+	require.Nil(t, err)
+	assert.Equal(t, "gomainevents", coordinator.name)
+	assert.Equal(t, 30*time.Second, coordinator.lease)
+	assert.Equal(t, 10*time.Second, coordinator.interval)
+	assert.NotEqual(t, "", coordinator.Instance())
+
+	coordinator, err = NewCoordinator(&Config{Backend: NewMemoryBackend(), Shards: testShards, Lease: time.Second, Interval: time.Second})
+	assert.Nil(t, coordinator)
+	assert.NotNil(t, err)
+
+	coordinator, err = NewCoordinator(&Config{Backend: NewMemoryBackend()})
+	assert.Nil(t, coordinator)
+	assert.NotNil(t, err)
+
+	coordinator, err = NewCoordinator(&Config{Shards: testShards})
+	assert.Nil(t, coordinator)
+	assert.NotNil(t, err)
+
+	coordinator, err = NewCoordinator(nil)
+	assert.Nil(t, coordinator)
+	assert.NotNil(t, err)
+}
+
+func TestCoordinatorRebalances(t *testing.T) {
+	now := time.Now()
+	backend := NewMemoryBackend()
+	backend.now = func() time.Time { return now }
+
+	a := newTestInstance(t, backend, "worker-a")
+	b := newTestInstance(t, backend, "worker-b")
+
+	// Alone, a takes everything and leads.
+	a.Rebalance()
+	assert.Equal(t, testShards, a.Owned())
+	assert.Equal(t, testShards, a.consumed())
+	assert.True(t, a.Leader())
+
+	// b joins, but has to wait for a to let go of its shards.
+	b.Rebalance()
+	assert.Empty(t, b.Owned())
+	assert.False(t, b.Leader())
+	assert.Equal(t, []string{"worker-a", "worker-b"}, b.Members())
+
+	a.Rebalance()
+	b.Rebalance()
+	assert.NotEmpty(t, a.Owned())
+	assert.NotEmpty(t, b.Owned())
+	assert.Equal(t, a.Owned(), a.consumed())
+	assert.Equal(t, b.Owned(), b.consumed())
+
+	all := append(a.Owned(), b.Owned()...)
+	sort.Strings(all)
+	assert.Equal(t, testShards, all)
+
+	// Nothing moves while the members stay the same.
+	owned := a.Owned()
+	a.Rebalance()
+	b.Rebalance()
+	assert.Equal(t, owned, a.Owned())
+
+	// a leaves cleanly, so b takes over straight away.
+	a.Stop()
+	assert.Empty(t, a.Owned())
+	assert.Empty(t, a.consumed())
+	assert.False(t, a.Leader())
+
+	b.Rebalance()
+	assert.Equal(t, testShards, b.Owned())
+	assert.True(t, b.Leader())
+}
+
+func TestCoordinatorTakesOverFromDeadInstances(t *testing.T) {
+	now := time.Now()
+	backend := NewMemoryBackend()
+	backend.now = func() time.Time { return now }
+
+	a := newTestInstance(t, backend, "worker-a")
+	b := newTestInstance(t, backend, "worker-b")
+
+	a.Rebalance()
+	b.Rebalance()
+	a.Rebalance()
+	b.Rebalance()
+	require.NotEmpty(t, a.Owned())
+
+	// a dies without releasing anything.
+	now = now.Add(time.Minute)
+	b.Rebalance()
+	assert.Equal(t, []string{"worker-b"}, b.Members())
+	assert.Equal(t, testShards, b.Owned())
+	assert.True(t, b.Leader())
+
+	// a comes back, finds its leases gone, and rejoins.
+	a.Rebalance()
+	assert.Empty(t, a.Owned())
+	assert.Empty(t, a.consumed())
+	assert.False(t, a.Leader())
+}
+
+func TestCoordinatorStartAndStop(t *testing.T) {
+	backend := NewMemoryBackend()
+
+	coordinator, err := NewCoordinator(&Config{
+		Backend:  backend,
+		Shards:   testShards,
+		Lease:    time.Second,
+		Interval: 10 * time.Millisecond,
+	})
+	require.Nil(t, err)
+
+	coordinator.Start()
+	assert.Equal(t, testShards, coordinator.Owned())
+
+	coordinator.Stop()
+	coordinator.Stop()
+
+	holders, err := backend.Holders("")
+	require.Nil(t, err)
+	assert.Empty(t, holders)
+}
+
+type failingBackend struct {
+	Backend
+}
+
+func (b failingBackend) Acquire(key string, owner string, ttl time.Duration) (bool, error) {
+	return false, errors.New("Unavailable")
+}
+
+func TestCoordinatorReportsErrors(t *testing.T) {
+	reported := []error{}
+
+	coordinator, err := NewCoordinator(&Config{
+		Backend: failingBackend{NewMemoryBackend()},
+		Shards:  testShards,
+		OnError: func(err error) { reported = append(reported, err) },
+	})
+	require.Nil(t, err)
+
+	coordinator.Rebalance()
+	assert.Len(t, reported, 1)
+	assert.Empty(t, coordinator.Owned())
+}
+
+func TestAssign(t *testing.T) {
+	members := []string{"worker-a", "worker-b", "worker-c"}
+
+	counts := map[string]int{}
+	for i := 0; i < 300; i++ {
+		shard := "queue-" + string(rune('a'+i%26)) + string(rune('a'+i/26))
+		counts[assign(shard, members)]++
+
+		// Removing a member only moves its own shards.
+		if owner := assign(shard, members); "worker-c" != owner {
+			assert.Equal(t, owner, assign(shard, members[:2]))
+		}
+	}
+
+	for _, member := range members {
+		assert.True(t, counts[member] > 50, counts)
+	}
+}
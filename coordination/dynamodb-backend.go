@@ -0,0 +1,200 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const defaultRegion = "us-east-1"
+
+// Attributes of the lease table's items. The table's partition key must be
+// a string named "leaseKey". Turn on DynamoDB's TTL for "expiresAt" to have
+// expired leases deleted.
+const (
+	attributeKey       = "leaseKey"
+	attributeOwner     = "owner"
+	attributeExpiresAt = "expiresAt"
+
+	// Milliseconds, since leases are usually shorter than DynamoDB's TTL
+	// granularity.
+	attributeLeaseUntil = "leaseUntil"
+)
+
+// DynamoDBAPI is the subset of the DynamoDB client used by this package. It
+// is satisfied by *dynamodb.Client from aws-sdk-go-v2.
+type DynamoDBAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+// DynamoDBBackend keeps leases in a DynamoDB table, one item per key.
+// Leases are taken with a conditional put, which fails while someone else
+// holds an unexpired lease. Holders scans the table, which is fine for the
+// handful of items a fleet has.
+type DynamoDBBackend struct {
+	client DynamoDBAPI
+	table  string
+
+	// Hook for tests
+	now func() time.Time
+}
+
+type DynamoDBBackendConfig struct {
+	// Provide your own DynamoDB client. Default will use the
+	// default AWS config + shared credentials.
+	DynamoDBClient DynamoDBAPI
+
+	// Region used by the default client. Defaults to us-east-1. Ignored
+	// when DynamoDBClient is provided.
+	Region string
+
+	// Endpoint overrides the DynamoDB endpoint used by the default
+	// client. Ignored when DynamoDBClient is provided.
+	Endpoint string
+
+	// Table to keep leases in. Its partition key must be a string named
+	// "leaseKey". Required
+	Table string
+}
+
+func NewDynamoDBBackend(config *DynamoDBBackendConfig) (*DynamoDBBackend, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.Table {
+		return nil, errors.New("Table is required")
+	}
+
+	// Default to a new client using shared credentials
+	client := config.DynamoDBClient
+	if nil == client {
+		region := config.Region
+		if "" == region {
+			region = defaultRegion
+		}
+
+		awsConfig, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(region))
+		if err != nil {
+			return nil, err
+		}
+
+		client = dynamodb.NewFromConfig(awsConfig, func(o *dynamodb.Options) {
+			if "" != config.Endpoint {
+				o.BaseEndpoint = aws.String(config.Endpoint)
+			}
+		})
+	}
+
+	return &DynamoDBBackend{
+		client: client,
+		table:  config.Table,
+		now:    time.Now,
+	}, nil
+}
+
+func (b *DynamoDBBackend) Acquire(key string, owner string, ttl time.Duration) (bool, error) {
+	now := b.now()
+	until := now.Add(ttl)
+
+	_, err := b.client.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String(b.table),
+		Item: map[string]types.AttributeValue{
+			attributeKey:        &types.AttributeValueMemberS{Value: key},
+			attributeOwner:      &types.AttributeValueMemberS{Value: owner},
+			attributeLeaseUntil: number(until.UnixNano() / int64(time.Millisecond)),
+			attributeExpiresAt:  number(until.Unix() + 1),
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#key) OR #owner = :owner OR #leaseUntil <= :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":        attributeKey,
+			"#owner":      attributeOwner,
+			"#leaseUntil": attributeLeaseUntil,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: owner},
+			":now":   number(now.UnixNano() / int64(time.Millisecond)),
+		},
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+
+	return nil == err, err
+}
+
+func (b *DynamoDBBackend) Release(key string, owner string) error {
+	_, err := b.client.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+		TableName: aws.String(b.table),
+		Key: map[string]types.AttributeValue{
+			attributeKey: &types.AttributeValueMemberS{Value: key},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": attributeOwner,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: owner},
+		},
+	})
+
+	// Someone else holds it, or it's already gone.
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+
+	return err
+}
+
+func (b *DynamoDBBackend) Holders(prefix string) (map[string]string, error) {
+	holders := map[string]string{}
+
+	params := &dynamodb.ScanInput{
+		TableName:        aws.String(b.table),
+		FilterExpression: aws.String("begins_with(#key, :prefix) AND #leaseUntil > :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":        attributeKey,
+			"#leaseUntil": attributeLeaseUntil,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: prefix},
+			":now":    number(b.now().UnixNano() / int64(time.Millisecond)),
+		},
+	}
+
+	for {
+		resp, err := b.client.Scan(context.Background(), params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range resp.Items {
+			key, _ := item[attributeKey].(*types.AttributeValueMemberS)
+			owner, _ := item[attributeOwner].(*types.AttributeValueMemberS)
+			if nil != key && nil != owner {
+				holders[key.Value] = owner.Value
+			}
+		}
+
+		if len(resp.LastEvaluatedKey) == 0 {
+			return holders, nil
+		}
+
+		params.ExclusiveStartKey = resp.LastEvaluatedKey
+	}
+}
+
+func number(n int64) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(n, 10)}
+}
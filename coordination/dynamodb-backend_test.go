@@ -0,0 +1,180 @@
+package coordination
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockDynamoDB evaluates the conditions DynamoDBBackend uses.
+type mockDynamoDB struct {
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+}
+
+func (m *mockDynamoDB) PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := stringValue(in.Item[attributeKey])
+	if existing, ok := m.items[key]; ok {
+		owner := stringValue(in.ExpressionAttributeValues[":owner"])
+		now := numberValue(in.ExpressionAttributeValues[":now"])
+
+		if owner != stringValue(existing[attributeOwner]) && numberValue(existing[attributeLeaseUntil]) > now {
+			return nil, &types.ConditionalCheckFailedException{}
+		}
+	}
+
+	m.items[key] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m *mockDynamoDB) DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := stringValue(in.Key[attributeKey])
+	existing, ok := m.items[key]
+	if !ok || stringValue(in.ExpressionAttributeValues[":owner"]) != stringValue(existing[attributeOwner]) {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+
+	delete(m.items, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// Scan returns one item per page, to exercise paging.
+func (m *mockDynamoDB) Scan(ctx context.Context, in *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []string{}
+	for key := range m.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	start := ""
+	if nil != in.ExclusiveStartKey {
+		start = stringValue(in.ExclusiveStartKey[attributeKey])
+	}
+
+	prefix := stringValue(in.ExpressionAttributeValues[":prefix"])
+	now := numberValue(in.ExpressionAttributeValues[":now"])
+
+	for i, key := range keys {
+		if key <= start {
+			continue
+		}
+
+		out := &dynamodb.ScanOutput{}
+		item := m.items[key]
+		if strings.HasPrefix(key, prefix) && numberValue(item[attributeLeaseUntil]) > now {
+			out.Items = []map[string]types.AttributeValue{item}
+		}
+
+		if i < len(keys)-1 {
+			out.LastEvaluatedKey = map[string]types.AttributeValue{attributeKey: item[attributeKey]}
+		}
+
+		return out, nil
+	}
+
+	return &dynamodb.ScanOutput{}, nil
+}
+
+func stringValue(value types.AttributeValue) string {
+	if s, ok := value.(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+
+	return ""
+}
+
+func numberValue(value types.AttributeValue) int64 {
+	if n, ok := value.(*types.AttributeValueMemberN); ok {
+		i, _ := strconv.ParseInt(n.Value, 10, 64)
+		return i
+	}
+
+	return 0
+}
+
+func TestNewDynamoDBBackend(t *testing.T) {
+	backend, err := NewDynamoDBBackend(&DynamoDBBackendConfig{DynamoDBClient: &mockDynamoDB{}, Table: "leases"})
+	assert.NotNil(t, backend)
+	assert.Nil(t, err)
+
+	backend, err = NewDynamoDBBackend(&DynamoDBBackendConfig{DynamoDBClient: &mockDynamoDB{}})
+	assert.Nil(t, backend)
+	assert.NotNil(t, err)
+
+	backend, err = NewDynamoDBBackend(nil)
+	assert.Nil(t, backend)
+	assert.NotNil(t, err)
+}
+
+func TestDynamoDBBackend(t *testing.T) {
+	client := &mockDynamoDB{items: make(map[string]map[string]types.AttributeValue)}
+
+	backend, err := NewDynamoDBBackend(&DynamoDBBackendConfig{DynamoDBClient: client, Table: "leases"})
+	require.Nil(t, err)
+
+	now := time.Now()
+	backend.now = func() time.Time { return now }
+
+	acquired, err := backend.Acquire("fleet/shards/queue-1", "worker-a", time.Minute)
+	require.Nil(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = backend.Acquire("fleet/shards/queue-1", "worker-b", time.Minute)
+	require.Nil(t, err)
+	assert.False(t, acquired)
+
+	acquired, err = backend.Acquire("fleet/shards/queue-1", "worker-a", time.Minute)
+	require.Nil(t, err)
+	assert.True(t, acquired)
+
+	_, err = backend.Acquire("fleet/members/worker-a", "worker-a", time.Minute)
+	require.Nil(t, err)
+	_, err = backend.Acquire("other/members/worker-c", "worker-c", time.Minute)
+	require.Nil(t, err)
+
+	holders, err := backend.Holders("fleet/")
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"fleet/shards/queue-1":   "worker-a",
+		"fleet/members/worker-a": "worker-a",
+	}, holders)
+
+	// Only the owner can release a lease.
+	require.Nil(t, backend.Release("fleet/shards/queue-1", "worker-b"))
+	acquired, err = backend.Acquire("fleet/shards/queue-1", "worker-b", time.Minute)
+	require.Nil(t, err)
+	assert.False(t, acquired)
+
+	require.Nil(t, backend.Release("fleet/shards/queue-1", "worker-a"))
+	acquired, err = backend.Acquire("fleet/shards/queue-1", "worker-b", time.Minute)
+	require.Nil(t, err)
+	assert.True(t, acquired)
+
+	// Expired leases are free, and aren't held.
+	now = now.Add(2 * time.Minute)
+	holders, err = backend.Holders("fleet/")
+	require.Nil(t, err)
+	assert.Empty(t, holders)
+
+	acquired, err = backend.Acquire("fleet/shards/queue-1", "worker-a", time.Minute)
+	require.Nil(t, err)
+	assert.True(t, acquired)
+}
@@ -0,0 +1,149 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// acquireScript takes a lease if it's free, or extends it if the owner
+// already holds it.
+var acquireScript = redis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if owner == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+if owner then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+// releaseScript deletes a lease only if the owner holds it.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisBackend keeps leases in Redis as keys that expire. Holders scans for
+// keys, so with a cluster client only one node's keys are seen; give the
+// keys a hash tag, e.g. a Prefix of "{coordination}:", to keep them on one
+// node.
+type RedisBackend struct {
+	client  redis.UniversalClient
+	prefix  string
+	timeout time.Duration
+}
+
+type RedisBackendConfig struct {
+	// Address of the Redis server, e.g. localhost:6379. Either Addr or
+	// Client is required.
+	Addr string
+
+	// Provide your own client, e.g. a cluster client or one with TLS.
+	Client redis.UniversalClient
+
+	// Prefixed to the Redis keys. Defaults to "coordination:".
+	Prefix string
+
+	// How long to wait for Redis. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+func NewRedisBackend(config *RedisBackendConfig) (*RedisBackend, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	client := config.Client
+	if nil == client {
+		if "" == config.Addr {
+			return nil, errors.New("Addr or Client is required")
+		}
+
+		client = redis.NewClient(&redis.Options{Addr: config.Addr})
+	}
+
+	prefix := config.Prefix
+	if "" == prefix {
+		prefix = "coordination:"
+	}
+
+	timeout := defaultTimeout
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	return &RedisBackend{
+		client:  client,
+		prefix:  prefix,
+		timeout: timeout,
+	}, nil
+}
+
+func (b *RedisBackend) Acquire(key string, owner string, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	acquired, err := acquireScript.Run(ctx, b.client, []string{b.prefix + key}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return acquired == 1, nil
+}
+
+func (b *RedisBackend) Release(key string, owner string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	return releaseScript.Run(ctx, b.client, []string{b.prefix + key}, owner).Err()
+}
+
+func (b *RedisBackend) Holders(prefix string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	keys := []string{}
+	iter := b.client.Scan(ctx, 0, escapePattern(b.prefix+prefix)+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	holders := map[string]string{}
+	if len(keys) == 0 {
+		return holders, nil
+	}
+
+	owners, err := b.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, owner := range owners {
+		// Expired since the scan
+		if owner, ok := owner.(string); ok {
+			holders[strings.TrimPrefix(keys[i], b.prefix)] = owner
+		}
+	}
+
+	return holders, nil
+}
+
+// escapePattern escapes the characters SCAN treats as wildcards.
+func escapePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,75 @@
+package coordination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRedisBackend(t *testing.T) {
+	backend, err := NewRedisBackend(&RedisBackendConfig{Addr: "localhost:6379"})
+	assert.NotNil(t, backend)
+	assert.Nil(t, err)
+
+	backend, err = NewRedisBackend(&RedisBackendConfig{})
+	assert.Nil(t, backend)
+	assert.NotNil(t, err)
+
+	backend, err = NewRedisBackend(nil)
+	assert.Nil(t, backend)
+	assert.NotNil(t, err)
+}
+
+func TestRedisBackend(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	backend, err := NewRedisBackend(&RedisBackendConfig{Addr: server.Addr()})
+	require.Nil(t, err)
+
+	acquired, err := backend.Acquire("fleet/shards/queue-1", "worker-a", time.Minute)
+	require.Nil(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = backend.Acquire("fleet/shards/queue-1", "worker-b", time.Minute)
+	require.Nil(t, err)
+	assert.False(t, acquired)
+
+	// Renewing extends the lease.
+	server.FastForward(30 * time.Second)
+	acquired, err = backend.Acquire("fleet/shards/queue-1", "worker-a", time.Minute)
+	require.Nil(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, time.Minute, server.TTL("coordination:fleet/shards/queue-1"))
+
+	_, err = backend.Acquire("fleet/members/worker-a", "worker-a", time.Minute)
+	require.Nil(t, err)
+	_, err = backend.Acquire("fleet*/members/worker-c", "worker-c", time.Minute)
+	require.Nil(t, err)
+
+	holders, err := backend.Holders("fleet/")
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"fleet/shards/queue-1":   "worker-a",
+		"fleet/members/worker-a": "worker-a",
+	}, holders)
+
+	// Only the owner can release a lease.
+	require.Nil(t, backend.Release("fleet/shards/queue-1", "worker-b"))
+	acquired, err = backend.Acquire("fleet/shards/queue-1", "worker-b", time.Minute)
+	require.Nil(t, err)
+	assert.False(t, acquired)
+
+	require.Nil(t, backend.Release("fleet/shards/queue-1", "worker-a"))
+	acquired, err = backend.Acquire("fleet/shards/queue-1", "worker-b", time.Minute)
+	require.Nil(t, err)
+	assert.True(t, acquired)
+
+	// Expired leases are free.
+	server.FastForward(2 * time.Minute)
+	acquired, err = backend.Acquire("fleet/shards/queue-1", "worker-a", time.Minute)
+	require.Nil(t, err)
+	assert.True(t, acquired)
+}
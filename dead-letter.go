@@ -0,0 +1,231 @@
+package gomainevents
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// Most attempts kept in a dead letter, the most recent ones.
+	maxDeadLetterAttempts = 20
+
+	// How long attempts at an event are remembered after the last one.
+	deadLetterAttemptTTL = 24 * time.Hour
+)
+
+// DeadLetterReason is why an event was dead-lettered.
+type DeadLetterReason string
+
+const (
+	// ReasonRetriesExhausted is for events the provider won't retry again.
+	ReasonRetriesExhausted DeadLetterReason = "retries exhausted"
+
+	// ReasonPermanentError is for events a handler failed with a
+	// PermanentError.
+	ReasonPermanentError DeadLetterReason = "permanent error"
+)
+
+// RetriesExhaustedError is implemented by the errors providers' Requeue
+// returns when an event has used up its retries, e.g.
+// sqs.RetryAttemptsExceededError.
+type RetriesExhaustedError interface {
+	RequeuingEventFailedError
+	RetryAttemptsExceeded() bool
+}
+
+// DeadLetter describes an event that was given up on, with everything
+// needed to work out why without digging through logs.
+type DeadLetter struct {
+	EventName string                 `json:"eventName"`
+	Data      map[string]interface{} `json:"data"`
+
+	// ID the provider gave the event, if it has one. See EventID.
+	EventID string `json:"eventId,omitempty"`
+
+	Reason DeadLetterReason `json:"reason"`
+
+	// Message of the error the last attempt failed with, and the handler
+	// that returned it.
+	Error   string `json:"error"`
+	Handler string `json:"handler,omitempty"`
+
+	// Times the event was delivered before, if the provider keeps count.
+	RetryCount int `json:"retryCount"`
+
+	// Attempts this consumer saw, oldest first. Only the last 20 are kept,
+	// and attempts made by other instances are missing.
+	Attempts []Attempt `json:"attempts"`
+
+	Consumer       Consumer  `json:"consumer"`
+	DeadLetteredAt time.Time `json:"deadLetteredAt"`
+}
+
+// Attempt is one failed try at handling an event.
+type Attempt struct {
+	At      time.Time `json:"at"`
+	Handler string    `json:"handler,omitempty"`
+	Error   string    `json:"error"`
+}
+
+// Consumer identifies the process that dead-lettered an event.
+type Consumer struct {
+	Program string `json:"program"`
+	Host    string `json:"host"`
+	PID     int    `json:"pid"`
+}
+
+// DeadLetterSink stores dead letters, e.g. on a queue for triage. See
+// Listener.RegisterDeadLetterSink.
+type DeadLetterSink interface {
+	DeadLetter(DeadLetter) error
+}
+
+// DeadLetterSinkFunc lets a function be used as a DeadLetterSink.
+type DeadLetterSinkFunc func(DeadLetter) error
+
+func (fn DeadLetterSinkFunc) DeadLetter(deadLetter DeadLetter) error {
+	return fn(deadLetter)
+}
+
+// WriterDeadLetterSink writes dead letters as lines of JSON, e.g. to a file
+// or to stderr for the log collector to pick up.
+type WriterDeadLetterSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewWriterDeadLetterSink(w io.Writer) *WriterDeadLetterSink {
+	return &WriterDeadLetterSink{encoder: json.NewEncoder(w)}
+}
+
+func (s *WriterDeadLetterSink) DeadLetter(deadLetter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.encoder.Encode(deadLetter)
+}
+
+// RegisterDeadLetterSink sets where dead letters are stored. Events are
+// dead-lettered when the provider's Requeue says they've used up their
+// retries, which the provider handles as usual, e.g. leaving them for the
+// queue's redrive policy. Events a handler fails with a PermanentError are
+// dead-lettered straight away and deleted, rather than requeued, unless the
+// sink fails.
+func (l *Listener) RegisterDeadLetterSink(sink DeadLetterSink) {
+	l.deadLetterSink = sink
+}
+
+// SetClock sets the clock dead letters and their attempts are timestamped
+// on. Defaults to SystemClock.
+func (l *Listener) SetClock(clock Clock) {
+	l.clock = ClockOrDefault(clock)
+}
+
+// deadLetter stores a dead letter for an event, returning whether it was.
+func (l *Listener) deadLetter(event Event, reason DeadLetterReason, handler string, err error, attempts []Attempt) bool {
+	deadLetter := DeadLetter{
+		EventName:      event.Name(),
+		Data:           event.Data(),
+		EventID:        EventID(event),
+		Reason:         reason,
+		Error:          err.Error(),
+		Handler:        handler,
+		RetryCount:     retryCount(event),
+		Attempts:       attempts,
+		Consumer:       l.consumer,
+		DeadLetteredAt: l.clock.Now(),
+	}
+
+	if sinkErr := l.deadLetterSink.DeadLetter(deadLetter); sinkErr != nil {
+		l.debugPrint("Error: unable to dead-letter event: %s\n", sinkErr)
+		if nil != l.errorHandler {
+			l.errorHandler(sinkErr)
+		}
+
+		return false
+	}
+
+	l.debugPrint("Dead-lettered event: %s\n", reason)
+	return true
+}
+
+// attemptLog remembers the failed attempts at events, so that dead letters
+// can include them. Redeliveries of an event are recognized by its name and
+// data, which providers keep when requeuing, so identical events share a
+// history.
+type attemptLog struct {
+	mu       sync.Mutex
+	attempts map[string][]Attempt
+	lastSeen map[string]time.Time
+}
+
+func newAttemptLog() *attemptLog {
+	return &attemptLog{
+		attempts: map[string][]Attempt{},
+		lastSeen: map[string]time.Time{},
+	}
+}
+
+// record adds a failed attempt at an event, returning all the attempts at
+// it.
+func (a *attemptLog) record(event Event, handler string, err error, now time.Time) []Attempt {
+	key := attemptKey(event)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.prune(now)
+
+	attempts := append(a.attempts[key], Attempt{At: now, Handler: handler, Error: err.Error()})
+	if len(attempts) > maxDeadLetterAttempts {
+		attempts = attempts[len(attempts)-maxDeadLetterAttempts:]
+	}
+
+	a.attempts[key] = attempts
+	a.lastSeen[key] = now
+
+	return append([]Attempt{}, attempts...)
+}
+
+// forget drops the attempts at an event, e.g. once it's been handled.
+func (a *attemptLog) forget(event Event) {
+	key := attemptKey(event)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.attempts, key)
+	delete(a.lastSeen, key)
+}
+
+func (a *attemptLog) prune(now time.Time) {
+	for key, seen := range a.lastSeen {
+		if now.Sub(seen) > deadLetterAttemptTTL {
+			delete(a.attempts, key)
+			delete(a.lastSeen, key)
+		}
+	}
+}
+
+func attemptKey(event Event) string {
+	encoded, _ := json.Marshal(event.Data())
+	sum := sha256.Sum256(append([]byte(event.Name()+"\n"), encoded...))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func currentConsumer() Consumer {
+	host, _ := os.Hostname()
+
+	return Consumer{
+		Program: filepath.Base(os.Args[0]),
+		Host:    host,
+		PID:     os.Getpid(),
+	}
+}
@@ -0,0 +1,246 @@
+package gomainevents
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type exhaustedError struct{}
+
+func (exhaustedError) Error() string {
+	return "Event exceeded maximum retry count"
+}
+
+func (exhaustedError) RetryAttemptsExceeded() bool {
+	return true
+}
+
+// exhaustingProvider gives up on events after two requeues.
+type exhaustingProvider struct {
+	recordingProvider
+}
+
+func (p *exhaustingProvider) Requeue(event Event) RequeuingEventFailedError {
+	p.recordingProvider.Requeue(event)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.requeued) > 2 {
+		return exhaustedError{}
+	}
+
+	return nil
+}
+
+type deadLetterRecorder struct {
+	mu          sync.Mutex
+	deadLetters []DeadLetter
+	err         error
+}
+
+func (r *deadLetterRecorder) DeadLetter(deadLetter DeadLetter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if nil != r.err {
+		return r.err
+	}
+
+	r.deadLetters = append(r.deadLetters, deadLetter)
+	return nil
+}
+
+func (r *deadLetterRecorder) recorded() []DeadLetter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]DeadLetter{}, r.deadLetters...)
+}
+
+func failPayment(Event) error {
+	return errors.New("Card declined")
+}
+
+func TestListenerDeadLettersExhaustedEvents(t *testing.T) {
+	provider := &exhaustingProvider{recordingProvider{events: make(chan Event)}}
+	sink := &deadLetterRecorder{}
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("PaymentReceived", failPayment)
+	listener.RegisterDeadLetterSink(sink)
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	event := testEvent{name: "PaymentReceived", data: map[string]interface{}{"amount": 10}}
+	for i := 0; i < 3; i++ {
+		provider.events <- event
+	}
+
+	assert.Eventually(t, func() bool { return len(sink.recorded()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	deadLetter := sink.recorded()[0]
+	assert.Equal(t, "PaymentReceived", deadLetter.EventName)
+	assert.Equal(t, map[string]interface{}{"amount": 10}, deadLetter.Data)
+	assert.Equal(t, ReasonRetriesExhausted, deadLetter.Reason)
+	assert.Equal(t, "Card declined", deadLetter.Error)
+	assert.Equal(t, "github.com/researchsquare/gomainevents.failPayment", deadLetter.Handler)
+	assert.Equal(t, os.Getpid(), deadLetter.Consumer.PID)
+	assert.NotEmpty(t, deadLetter.Consumer.Program)
+
+	require.Len(t, deadLetter.Attempts, 3)
+	for _, attempt := range deadLetter.Attempts {
+		assert.Equal(t, "Card declined", attempt.Error)
+		assert.Equal(t, deadLetter.Handler, attempt.Handler)
+		assert.False(t, attempt.At.After(deadLetter.DeadLetteredAt))
+	}
+
+	// The history starts again after dead-lettering
+	assert.Eventually(t, func() bool {
+		listener.attempts.mu.Lock()
+		defer listener.attempts.mu.Unlock()
+
+		return len(listener.attempts.attempts) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestListenerDeadLettersPermanentErrors(t *testing.T) {
+	provider := &recordingProvider{events: make(chan Event)}
+	sink := &deadLetterRecorder{}
+
+	at := time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.SetClock(fixedClock{Clock: SystemClock, now: at})
+	listener.RegisterHandler("PaymentReceived", func(Event) error {
+		return Permanent(errors.New("Unknown currency"))
+	})
+	listener.RegisterDeadLetterSink(sink)
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	event := testEvent{name: "PaymentReceived"}
+	provider.events <- event
+
+	assert.Eventually(t, func() bool { return len(sink.recorded()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, ReasonPermanentError, sink.recorded()[0].Reason)
+	assert.Equal(t, "Unknown currency", sink.recorded()[0].Error)
+	assert.Equal(t, at, sink.recorded()[0].DeadLetteredAt)
+	assert.Equal(t, at, sink.recorded()[0].Attempts[0].At)
+
+	assert.Eventually(t, func() bool {
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+
+		return len(provider.deleted) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	provider.mu.Lock()
+	assert.Empty(t, provider.requeued)
+	provider.mu.Unlock()
+}
+
+func TestListenerRequeuesPermanentErrorsIfSinkFails(t *testing.T) {
+	provider := &recordingProvider{events: make(chan Event)}
+	sink := &deadLetterRecorder{err: errors.New("Sink unavailable")}
+
+	var mu sync.Mutex
+	var errs []error
+
+	at := time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.SetClock(fixedClock{Clock: SystemClock, now: at})
+	listener.RegisterHandler("PaymentReceived", func(Event) error {
+		return Permanent(errors.New("Unknown currency"))
+	})
+	listener.RegisterDeadLetterSink(sink)
+	listener.RegisterErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		errs = append(errs, err)
+	})
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	provider.events <- testEvent{name: "PaymentReceived"}
+
+	assert.Eventually(t, func() bool {
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+
+		return len(provider.requeued) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	assert.EqualError(t, errs[len(errs)-1], "Sink unavailable")
+	mu.Unlock()
+
+	provider.mu.Lock()
+	assert.Empty(t, provider.deleted)
+	provider.mu.Unlock()
+}
+
+func TestWriterDeadLetterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterDeadLetterSink(&buf)
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.NoError(t, sink.DeadLetter(DeadLetter{
+		EventName:      "PaymentReceived",
+		Data:           map[string]interface{}{"amount": 10},
+		Reason:         ReasonRetriesExhausted,
+		Error:          "Card declined",
+		Attempts:       []Attempt{{At: at, Error: "Card declined"}},
+		Consumer:       Consumer{Program: "payments", Host: "host-1", PID: 42},
+		DeadLetteredAt: at,
+	}))
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "retries exhausted", decoded["reason"])
+	assert.Equal(t, "payments", decoded["consumer"].(map[string]interface{})["program"])
+	assert.Equal(t, "2024-01-02T03:04:05Z", decoded["attempts"].([]interface{})[0].(map[string]interface{})["at"])
+	assert.NotContains(t, decoded, "eventId")
+}
+
+func TestAttemptLogKeepsRecentAttempts(t *testing.T) {
+	log := newAttemptLog()
+	event := testEvent{name: "PaymentReceived"}
+	now := time.Now()
+
+	var attempts []Attempt
+	for i := 0; i < maxDeadLetterAttempts+5; i++ {
+		attempts = log.record(event, "", errors.New("Failed"), now)
+	}
+	assert.Len(t, attempts, maxDeadLetterAttempts)
+
+	// Other events have their own history, and old histories are dropped
+	other := testEvent{name: "PaymentReceived", data: map[string]interface{}{"id": 2}}
+	attempts = log.record(other, "", errors.New("Failed"), now.Add(deadLetterAttemptTTL+time.Minute))
+	assert.Len(t, attempts, 1)
+	assert.Len(t, log.attempts, 1)
+}
+
+func TestIsPermanent(t *testing.T) {
+	err := Permanent(errors.New("Invalid"))
+	assert.True(t, IsPermanent(err))
+	assert.EqualError(t, err, "Invalid")
+	assert.True(t, IsPermanent(errors.Join(errors.New("Wrapped"), err)))
+	assert.False(t, IsPermanent(errors.New("Invalid")))
+}
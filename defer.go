@@ -0,0 +1,80 @@
+package gomainevents
+
+import (
+	"errors"
+	"time"
+)
+
+// disabledEventDelay is how long disabled events are put off for before
+// they're delivered again. See Listener.Disable.
+const disabledEventDelay = time.Minute
+
+// ErrDeferNotSupported is returned by Defer when the event can't be put off,
+// e.g. by composite providers whose source doesn't support it.
+var ErrDeferNotSupported = errors.New("Provider can't defer events")
+
+// DeferringProvider is implemented by providers that can put an event off
+// for a while without it counting as a retry, e.g. by changing its
+// visibility timeout on SQS. See Listener.Disable.
+type DeferringProvider interface {
+	Provider
+
+	// Defer has the event delivered again once delay has passed, with the
+	// same retry count.
+	Defer(event Event, delay time.Duration) error
+}
+
+// DeferredError is returned by handlers for events they won't handle yet,
+// e.g. because they're shedding load. The Listener defers them on providers
+// that implement DeferringProvider, so they don't use up their retries, and
+// requeues them like any other failure on the rest. See Deferred.
+type DeferredError struct {
+	Err   error
+	Delay time.Duration
+}
+
+// Deferred marks an error returned by a handler as a request to have the
+// event delivered again once delay has passed.
+func Deferred(err error, delay time.Duration) error {
+	return &DeferredError{Err: err, Delay: delay}
+}
+
+func (e *DeferredError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeferredError) Unwrap() error {
+	return e.Err
+}
+
+// asDeferred returns the *DeferredError an error is, or wraps, if any.
+func asDeferred(err error) (*DeferredError, bool) {
+	var deferred *DeferredError
+	return deferred, errors.As(err, &deferred)
+}
+
+// deferEvent puts an event off for delay, returning false if the provider
+// can't, or failed to.
+func (l *Listener) deferEvent(event Event, delay time.Duration) bool {
+	provider, ok := l.provider.(DeferringProvider)
+	if !ok {
+		return false
+	}
+
+	l.debugPrint("Deferring event for %s.\n", delay)
+
+	err := provider.Defer(event, delay)
+	if errors.Is(err, ErrDeferNotSupported) {
+		return false
+	}
+
+	if err != nil {
+		if nil != l.errorHandler {
+			l.errorHandler(err)
+		}
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,53 @@
+package gomainevents
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenerDefersDeferredEvents(t *testing.T) {
+	provider := &deferringProvider{recordingProvider: recordingProvider{events: make(chan Event)}}
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Viewed", func(Event) error {
+		return Deferred(errors.New("Busy"), time.Minute)
+	})
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	provider.events <- testEvent{name: "Viewed"}
+	assert.Eventually(t, func() bool { return listener.Stats().Deferred == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	provider.mu.Lock()
+	assert.Equal(t, []time.Duration{time.Minute}, provider.deferred)
+	assert.Empty(t, provider.deleted)
+	assert.Empty(t, provider.requeued)
+	provider.mu.Unlock()
+
+	assert.Equal(t, int64(0), listener.Stats().Failed)
+}
+
+func TestListenerRequeuesDeferredEventsItCantDefer(t *testing.T) {
+	provider := &recordingProvider{events: make(chan Event)}
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Viewed", func(Event) error {
+		return Deferred(errors.New("Busy"), time.Minute)
+	})
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	provider.events <- testEvent{name: "Viewed"}
+	assert.Eventually(t, func() bool { return listener.Stats().Failed == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	provider.mu.Lock()
+	assert.Len(t, provider.requeued, 1)
+	provider.mu.Unlock()
+}
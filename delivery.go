@@ -0,0 +1,40 @@
+package gomainevents
+
+// Delivery is how a provider shares events between the Listener instances
+// consuming them.
+type Delivery int
+
+const (
+	// DefaultDelivery leaves the choice to the provider.
+	DefaultDelivery Delivery = iota
+
+	// CompetingConsumers delivers each event to one of the instances, so
+	// that they share the work, e.g. instances polling the same SQS queue.
+	CompetingConsumers
+
+	// Broadcast delivers every event to every instance, e.g. to update
+	// in-memory caches or push events on to connected browsers.
+	Broadcast
+)
+
+func (d Delivery) String() string {
+	switch d {
+	case DefaultDelivery:
+		return "default"
+	case CompetingConsumers:
+		return "competing consumers"
+	case Broadcast:
+		return "broadcast"
+	}
+
+	return "unknown"
+}
+
+// DeliveryProvider is implemented by providers that say how they share
+// events between instances. See Delivery.
+type DeliveryProvider interface {
+	Provider
+
+	// Delivery returns how the provider was configured to share events.
+	Delivery() Delivery
+}
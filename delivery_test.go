@@ -0,0 +1,14 @@
+package gomainevents
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveryString(t *testing.T) {
+	assert.Equal(t, "default", DefaultDelivery.String())
+	assert.Equal(t, "competing consumers", CompetingConsumers.String())
+	assert.Equal(t, "broadcast", Broadcast.String())
+	assert.Equal(t, "unknown", Delivery(42).String())
+}
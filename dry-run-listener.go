@@ -0,0 +1,59 @@
+package gomainevents
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// NewDryRunListener returns a Listener that receives and decodes events and
+// logs which handlers would run, and with what, but doesn't run them. It is
+// meant for checking a new consumer against real traffic before it goes
+// live.
+//
+// Events are neither deleted nor requeued, so the provider delivers them
+// again once they time out, e.g. after the visibility timeout for SQS.
+// Until then they are held back from other consumers of the same queue.
+func NewDryRunListener(provider Provider) *Listener {
+	l := NewListener(provider)
+	l.dryRun = true
+
+	return l
+}
+
+// dryRunEvent logs what would happen to an event.
+func (l *Listener) dryRunEvent(event Event) {
+	encoded, err := json.Marshal(map[string]interface{}{
+		"name": event.Name(),
+		"data": event.Data(),
+	})
+	if err != nil {
+		log.Printf("[gomainevents] Dry run: unable to encode %s: %s\n", event.Name(), err)
+		return
+	}
+
+	handlers := l.handlers[event.Name()]
+	if len(handlers) == 0 {
+		log.Printf("[gomainevents] Dry run: no handlers for %s\n", encoded)
+		return
+	}
+
+	names := make([]string, len(handlers))
+	for i, fn := range handlers {
+		names[i] = handlerName(fn)
+	}
+
+	log.Printf("[gomainevents] Dry run: would run %s for %s\n", strings.Join(names, ", "), encoded)
+}
+
+// handlerName returns the name of a handler's function, e.g.
+// "main.sendReceipt". Closures are named after the function they're in.
+func handlerName(fn EventHandler) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); nil != f {
+		return f.Name()
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,105 @@
+package gomainevents
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingProvider struct {
+	events chan Event
+
+	mu       sync.Mutex
+	deleted  []Event
+	requeued []Event
+}
+
+func (p *recordingProvider) Start() (<-chan Event, <-chan error) {
+	return p.events, nil
+}
+
+func (p *recordingProvider) Delete(event Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.deleted = append(p.deleted, event)
+}
+
+func (p *recordingProvider) Requeue(event Event) RequeuingEventFailedError {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requeued = append(p.requeued, event)
+	return nil
+}
+
+func (p *recordingProvider) Stop() {}
+
+// logBuffer collects log output written from other goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func sendReceipt(Event) error {
+	return nil
+}
+
+func TestDryRunListener(t *testing.T) {
+	out := &logBuffer{}
+	log.SetOutput(out)
+	defer log.SetOutput(os.Stderr)
+
+	provider := &recordingProvider{events: make(chan Event)}
+	handled := false
+
+	listener := NewDryRunListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Created", sendReceipt)
+	listener.RegisterHandler("Created", func(Event) error {
+		handled = true
+		return nil
+	})
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	provider.events <- testEvent{name: "Created", data: map[string]interface{}{"userId": 12}}
+	provider.events <- testEvent{name: "Deleted", data: map[string]interface{}{}}
+
+	assert.Eventually(t, func() bool {
+		return strings.Count(out.String(), "Dry run") == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Contains(t, out.String(), `Dry run: would run github.com/researchsquare/gomainevents.sendReceipt, github.com/researchsquare/gomainevents.TestDryRunListener.func1 for {"data":{"userId":12},"name":"Created"}`)
+	assert.Contains(t, out.String(), `Dry run: no handlers for {"data":{},"name":"Deleted"}`)
+
+	// Nothing was touched
+	assert.False(t, handled)
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	assert.Empty(t, provider.deleted)
+	assert.Empty(t, provider.requeued)
+}
@@ -0,0 +1,32 @@
+package gomainevents
+
+import (
+	"os"
+)
+
+// dryRunPrefix marks the events a DryRunPublisher prints.
+const dryRunPrefix = "[gomainevents] Dry run: "
+
+// DryRunPublisher logs events instead of publishing them. It can stand in for
+// any Publisher so that staging environments and local development exercise
+// publish paths without touching real infrastructure. It is a LogPublisher
+// whose output is marked as a dry run.
+type DryRunPublisher struct {
+	*LogPublisher
+}
+
+// NewDryRunPublisher takes the same configuration as NewLogPublisher, with
+// Prefix defaulting to "[gomainevents] Dry run: ". Without a configuration,
+// events are printed on a single line to os.Stderr, like the log package.
+func NewDryRunPublisher(config *LogPublisherConfig) *DryRunPublisher {
+	if nil == config {
+		config = &LogPublisherConfig{Writer: os.Stderr, Compact: true}
+	}
+
+	copied := *config
+	if "" == copied.Prefix {
+		copied.Prefix = dryRunPrefix
+	}
+
+	return &DryRunPublisher{LogPublisher: NewLogPublisher(&copied)}
+}
@@ -0,0 +1,88 @@
+package gomainevents
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SentEvent is implemented by events whose provider knows when they were
+// sent, e.g. from SQS's SentTimestamp.
+type SentEvent interface {
+	Event
+
+	// SentAt returns when the event was sent, or zero if it isn't known.
+	SentAt() time.Time
+}
+
+// occurredOnLayouts are the formats occurredOn is parsed with, in order.
+var occurredOnLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.999999",
+}
+
+// OccurredAt returns when an event happened: its occurredOn field if it has
+// one, or when it was sent if its provider knows. Events that wrap another
+// event, with an Unwrap method, are looked through. Timestamps without a
+// time zone are taken to be UTC, and numbers to be Unix seconds.
+func OccurredAt(event Event) (time.Time, bool) {
+	if occurredOn, ok := parseOccurredOn(event.Data()["occurredOn"]); ok {
+		return occurredOn, true
+	}
+
+	for nil != event {
+		if sent, ok := event.(SentEvent); ok {
+			if sentAt := sent.SentAt(); !sentAt.IsZero() {
+				return sentAt, true
+			}
+		}
+
+		wrapper, ok := event.(interface{ Unwrap() Event })
+		if !ok {
+			break
+		}
+
+		event = wrapper.Unwrap()
+	}
+
+	return time.Time{}, false
+}
+
+// EventAge returns how long ago an event happened, as of now.
+func EventAge(event Event, now time.Time) (time.Duration, bool) {
+	occurredAt, ok := OccurredAt(event)
+	if !ok {
+		return 0, false
+	}
+
+	return now.Sub(occurredAt), true
+}
+
+func parseOccurredOn(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		for _, layout := range occurredOnLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	case float64:
+		return unixSeconds(v), true
+	case int64:
+		return time.Unix(v, 0), true
+	case int:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return unixSeconds(f), true
+		}
+	case time.Time:
+		return v, !v.IsZero()
+	}
+
+	return time.Time{}, false
+}
+
+func unixSeconds(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
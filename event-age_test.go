@@ -0,0 +1,71 @@
+package gomainevents
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sentEvent struct {
+	testEvent
+	sentAt time.Time
+}
+
+func (e sentEvent) SentAt() time.Time {
+	return e.sentAt
+}
+
+type wrappedEvent struct {
+	Event
+}
+
+func (e wrappedEvent) Unwrap() Event {
+	return e.Event
+}
+
+func TestOccurredAtFromOccurredOn(t *testing.T) {
+	want := time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+
+	for _, occurredOn := range []interface{}{
+		"2018-03-08T11:11:11Z",
+		"2018-03-08T06:11:11-05:00",
+		"2018-03-08 11:11:11",
+		float64(want.Unix()),
+		json.Number("1520507471"),
+		want,
+	} {
+		occurredAt, ok := OccurredAt(testEvent{data: map[string]interface{}{"occurredOn": occurredOn}})
+
+		assert.True(t, ok, "%v", occurredOn)
+		assert.True(t, want.Equal(occurredAt), "%v parsed as %s", occurredOn, occurredAt)
+	}
+}
+
+func TestOccurredAtFallsBackToSentAt(t *testing.T) {
+	sentAt := time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+	event := sentEvent{testEvent: testEvent{data: map[string]interface{}{"occurredOn": "yesterday-ish"}}, sentAt: sentAt}
+
+	occurredAt, ok := OccurredAt(event)
+	assert.True(t, ok)
+	assert.Equal(t, sentAt, occurredAt)
+
+	// Through wrappers too
+	occurredAt, ok = OccurredAt(wrappedEvent{Event: event})
+	assert.True(t, ok)
+	assert.Equal(t, sentAt, occurredAt)
+
+	// Unknown
+	_, ok = OccurredAt(wrappedEvent{Event: testEvent{}})
+	assert.False(t, ok)
+}
+
+func TestEventAge(t *testing.T) {
+	event := testEvent{data: map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}}
+
+	age, ok := EventAge(event, time.Date(2018, 3, 8, 11, 12, 11, 0, time.UTC))
+
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, age)
+}
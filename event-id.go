@@ -0,0 +1,53 @@
+package gomainevents
+
+import (
+	"strconv"
+)
+
+// EventID returns the ID the provider gave an event: its MessageID, ID or
+// SequenceNumber, whichever it has, or an empty string if it has none.
+// Events that wrap another event, with an Unwrap method, are looked through.
+func EventID(event Event) string {
+	for nil != event {
+		switch e := event.(type) {
+		case interface{ MessageID() string }:
+			return e.MessageID()
+		case interface{ ID() string }:
+			return e.ID()
+		case interface{ ID() int64 }:
+			return strconv.FormatInt(e.ID(), 10)
+		case interface{ ID() int }:
+			return strconv.Itoa(e.ID())
+		case interface{ SequenceNumber() string }:
+			return e.SequenceNumber()
+		}
+
+		wrapper, ok := event.(interface{ Unwrap() Event })
+		if !ok {
+			break
+		}
+
+		event = wrapper.Unwrap()
+	}
+
+	return ""
+}
+
+// retryCount returns how many times an event was delivered before, if its
+// provider keeps count.
+func retryCount(event Event) int {
+	for nil != event {
+		if counted, ok := event.(interface{ RetryCount() int }); ok {
+			return counted.RetryCount()
+		}
+
+		wrapper, ok := event.(interface{ Unwrap() Event })
+		if !ok {
+			break
+		}
+
+		event = wrapper.Unwrap()
+	}
+
+	return 0
+}
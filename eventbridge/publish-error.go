@@ -0,0 +1,44 @@
+package eventbridge
+
+import (
+	"fmt"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// EntryError is returned when EventBridge rejects an event, e.g. because the
+// bus doesn't exist or the entry is too large.
+type EntryError struct {
+	EventName string
+
+	// Code and Message are reported by EventBridge for the entry.
+	Code    string
+	Message string
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("Failed to publish event %s (%s): %s", e.EventName, e.Code, e.Message)
+}
+
+// BatchFailure describes a single event that could not be published as part
+// of a batch.
+type BatchFailure struct {
+	Event gomainevents.Event
+
+	// Code and Message are reported by EventBridge for entries it rejected.
+	Code    string
+	Message string
+
+	// Err is set instead of Code/Message when the whole request failed.
+	Err error
+}
+
+// BatchPublishError is returned by PublishBatch when one or more events
+// could not be published. Events not listed were published successfully.
+type BatchPublishError struct {
+	Failures []BatchFailure
+}
+
+func (e *BatchPublishError) Error() string {
+	return fmt.Sprintf("Failed to publish %d event(s) in batch", len(e.Failures))
+}
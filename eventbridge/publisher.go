@@ -0,0 +1,235 @@
+package eventbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awseventbridge "github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/researchsquare/gomainevents"
+)
+
+// defaultRegion is used for the default client when no Region is configured.
+const defaultRegion = "us-east-1"
+
+// defaultEventBusName is the bus every AWS account has.
+const defaultEventBusName = "default"
+
+const defaultTimeout = 10 * time.Second
+
+// maxBatchSize is the maximum number of entries EventBridge accepts in a
+// single PutEvents call.
+const maxBatchSize = 10
+
+// EventBridgeAPI is the subset of the EventBridge client used by this
+// package. It is satisfied by *eventbridge.Client from aws-sdk-go-v2.
+type EventBridgeAPI interface {
+	PutEvents(ctx context.Context, params *awseventbridge.PutEventsInput, optFns ...func(*awseventbridge.Options)) (*awseventbridge.PutEventsOutput, error)
+}
+
+// Publisher puts events on an EventBridge event bus. The event name becomes
+// the DetailType and its data the Detail, so rules can match on both, e.g.
+//
+//	{"source": ["com.example.orders"], "detail-type": ["OrderPlaced"]}
+type Publisher struct {
+	client       EventBridgeAPI
+	eventBusName string
+	source       string
+	timeout      time.Duration
+}
+
+type Config struct {
+	// Provide your own EventBridge client. Default will use the
+	// default AWS config + shared credentials.
+	EventBridgeClient EventBridgeAPI
+
+	// Region used by the default client. Defaults to us-east-1. Ignored
+	// when EventBridgeClient is provided.
+	Region string
+
+	// Endpoint overrides the EventBridge endpoint used by the default
+	// client, e.g. http://localhost:4566 for LocalStack. Ignored when
+	// EventBridgeClient is provided.
+	Endpoint string
+
+	// Name or ARN of the event bus. Defaults to the account's default bus.
+	EventBusName string
+
+	// Identifies the application publishing the events, e.g.
+	// com.example.orders. Required
+	Source string
+
+	// Maximum time allowed for a single publish. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+func NewPublisher(config *Config) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.Source {
+		return nil, errors.New("Source is required")
+	}
+
+	// Default to a new client using shared credentials
+	client := config.EventBridgeClient
+	if nil == client {
+		region := config.Region
+		if "" == region {
+			region = defaultRegion
+		}
+
+		awsConfig, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(region))
+		if err != nil {
+			return nil, err
+		}
+
+		client = awseventbridge.NewFromConfig(awsConfig, func(o *awseventbridge.Options) {
+			if "" != config.Endpoint {
+				o.BaseEndpoint = aws.String(config.Endpoint)
+			}
+		})
+	}
+
+	eventBusName := config.EventBusName
+	if "" == eventBusName {
+		eventBusName = defaultEventBusName
+	}
+
+	timeout := defaultTimeout
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	return &Publisher{
+		client:       client,
+		eventBusName: eventBusName,
+		source:       config.Source,
+		timeout:      timeout,
+	}, nil
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	entry, err := p.buildEntry(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	resp, err := p.client.PutEvents(ctx, &awseventbridge.PutEventsInput{
+		Entries: []types.PutEventsRequestEntry{entry},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, result := range resp.Entries {
+		if nil != result.ErrorCode {
+			return &EntryError{
+				EventName: event.Name(),
+				Code:      aws.ToString(result.ErrorCode),
+				Message:   aws.ToString(result.ErrorMessage),
+			}
+		}
+	}
+
+	return nil
+}
+
+// PublishBatch puts events on the bus using the PutEvents API, sending up to
+// 10 events per call. Every chunk is attempted; if any event fails, a
+// *BatchPublishError listing the failed events is returned.
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	failures := []BatchFailure{}
+
+	for start := 0; start < len(events); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(events) {
+			end = len(events)
+		}
+
+		failures = append(failures, p.publishChunk(events[start:end])...)
+	}
+
+	if len(failures) > 0 {
+		return &BatchPublishError{Failures: failures}
+	}
+
+	return nil
+}
+
+func (p *Publisher) publishChunk(events []gomainevents.Event) []BatchFailure {
+	failures := []BatchFailure{}
+	entries := []types.PutEventsRequestEntry{}
+	sent := []gomainevents.Event{}
+
+	for _, event := range events {
+		entry, err := p.buildEntry(event)
+		if err != nil {
+			failures = append(failures, BatchFailure{Event: event, Err: err})
+			continue
+		}
+
+		sent = append(sent, event)
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return failures
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	resp, err := p.client.PutEvents(ctx, &awseventbridge.PutEventsInput{Entries: entries})
+	if err != nil {
+		for _, event := range sent {
+			failures = append(failures, BatchFailure{Event: event, Err: err})
+		}
+
+		return failures
+	}
+
+	// Results are in the same order as the entries that were sent.
+	for i, result := range resp.Entries {
+		if nil == result.ErrorCode || i >= len(sent) {
+			continue
+		}
+
+		failures = append(failures, BatchFailure{
+			Event:   sent[i],
+			Code:    aws.ToString(result.ErrorCode),
+			Message: aws.ToString(result.ErrorMessage),
+		})
+	}
+
+	return failures
+}
+
+// buildEntry returns the PutEvents entry for an event.
+func (p *Publisher) buildEntry(event gomainevents.Event) (types.PutEventsRequestEntry, error) {
+	data := event.Data()
+	if nil == data {
+		// EventBridge requires the detail to be a JSON object.
+		data = map[string]interface{}{}
+	}
+
+	detail, err := json.Marshal(data)
+	if err != nil {
+		return types.PutEventsRequestEntry{}, err
+	}
+
+	return types.PutEventsRequestEntry{
+		EventBusName: aws.String(p.eventBusName),
+		Source:       aws.String(p.source),
+		DetailType:   aws.String(event.Name()),
+		Detail:       aws.String(string(detail)),
+	}, nil
+}
@@ -0,0 +1,127 @@
+package eventbridge
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awseventbridge "github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+type mockEventBridge struct {
+	puts         []*awseventbridge.PutEventsInput
+	failTypes    map[string]bool
+	requestError error
+}
+
+func (m *mockEventBridge) PutEvents(ctx context.Context, in *awseventbridge.PutEventsInput, optFns ...func(*awseventbridge.Options)) (*awseventbridge.PutEventsOutput, error) {
+	m.puts = append(m.puts, in)
+	if nil != m.requestError {
+		return nil, m.requestError
+	}
+
+	out := &awseventbridge.PutEventsOutput{}
+	for _, entry := range in.Entries {
+		if m.failTypes[aws.ToString(entry.DetailType)] {
+			out.FailedEntryCount++
+			out.Entries = append(out.Entries, types.PutEventsResultEntry{
+				ErrorCode:    aws.String("MalformedDetail"),
+				ErrorMessage: aws.String("Detail is malformed."),
+			})
+			continue
+		}
+
+		out.Entries = append(out.Entries, types.PutEventsResultEntry{EventId: aws.String("1")})
+	}
+
+	return out, nil
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(&Config{EventBridgeClient: &mockEventBridge{}, Source: "com.example.orders"})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+
+	publisher, err = NewPublisher(&Config{EventBridgeClient: &mockEventBridge{}})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestPublish(t *testing.T) {
+	client := &mockEventBridge{}
+	publisher, err := NewPublisher(&Config{EventBridgeClient: client, Source: "com.example.orders"})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.Publish(testEvent{name: "OrderPlaced"}))
+
+	require.Len(t, client.puts, 1)
+	require.Len(t, client.puts[0].Entries, 1)
+
+	entry := client.puts[0].Entries[0]
+	assert.Equal(t, "default", aws.ToString(entry.EventBusName))
+	assert.Equal(t, "com.example.orders", aws.ToString(entry.Source))
+	assert.Equal(t, "OrderPlaced", aws.ToString(entry.DetailType))
+	assert.JSONEq(t, `{"occurredOn":"2018-03-08 11:11:11"}`, aws.ToString(entry.Detail))
+}
+
+func TestPublishReportsRejectedEntries(t *testing.T) {
+	client := &mockEventBridge{failTypes: map[string]bool{"OrderPlaced": true}}
+	publisher, err := NewPublisher(&Config{EventBridgeClient: client, Source: "com.example.orders", EventBusName: "orders"})
+	require.Nil(t, err)
+
+	err = publisher.Publish(testEvent{name: "OrderPlaced"})
+	require.IsType(t, &EntryError{}, err)
+	assert.Equal(t, "MalformedDetail", err.(*EntryError).Code)
+	assert.Equal(t, "orders", aws.ToString(client.puts[0].Entries[0].EventBusName))
+}
+
+func TestPublishBatch(t *testing.T) {
+	client := &mockEventBridge{failTypes: map[string]bool{"Bad": true}}
+	publisher, err := NewPublisher(&Config{EventBridgeClient: client, Source: "com.example.orders"})
+	require.Nil(t, err)
+
+	events := []gomainevents.Event{}
+	for i := 0; i < 11; i++ {
+		events = append(events, testEvent{name: "OrderPlaced"})
+	}
+	events = append(events, testEvent{name: "Bad"})
+
+	err = publisher.PublishBatch(events)
+	require.IsType(t, &BatchPublishError{}, err)
+
+	failures := err.(*BatchPublishError).Failures
+	require.Len(t, failures, 1)
+	assert.Equal(t, "Bad", failures[0].Event.Name())
+	assert.Equal(t, "MalformedDetail", failures[0].Code)
+
+	require.Len(t, client.puts, 2)
+	assert.Len(t, client.puts[0].Entries, 10)
+	assert.Len(t, client.puts[1].Entries, 2)
+
+	// A failed request fails every event in it
+	client.requestError = errors.New("Oops")
+	err = publisher.PublishBatch(events[:2])
+	require.IsType(t, &BatchPublishError{}, err)
+	assert.Len(t, err.(*BatchPublishError).Failures, 2)
+}
@@ -51,7 +51,9 @@ func runSQSExample() {
 		return nil
 	})
 
-	listener.Listen()
+	if err := gomainevents.Run(listener); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // Websockets
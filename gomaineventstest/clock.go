@@ -0,0 +1,112 @@
+package gomaineventstest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+var _ gomainevents.Clock = (*Clock)(nil)
+
+// Clock is a fake clock that only moves when Advance is called, so that
+// delays and backoff can be tested without sleeping. It can be configured
+// wherever a gomainevents.Clock can.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*clockTimer
+}
+
+type clockTimer struct {
+	when time.Time
+	fn   func()
+}
+
+// NewClock returns a clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// AfterFunc calls fn once the clock has been advanced by d. It returns a
+// function that cancels the call, reporting whether it was still pending.
+func (c *Clock) AfterFunc(d time.Duration, fn func()) (cancel func() bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer := &clockTimer{when: c.now.Add(d), fn: fn}
+	c.timers = append(c.timers, timer)
+
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		for i, t := range c.timers {
+			if t == timer {
+				c.timers = append(c.timers[:i], c.timers[i+1:]...)
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// After returns a channel that receives the time once the clock has been
+// advanced by d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.AfterFunc(d, func() {
+		ch <- c.Now()
+	})
+
+	return ch
+}
+
+// Advance moves the clock forward by d, running everything that falls due
+// in the order it falls due. Callbacks run on the calling goroutine.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	until := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].when.Before(c.timers[j].when)
+		})
+
+		if len(c.timers) == 0 || c.timers[0].when.After(until) {
+			c.now = until
+			c.mu.Unlock()
+			return
+		}
+
+		timer := c.timers[0]
+		c.timers = c.timers[1:]
+		if timer.when.After(c.now) {
+			c.now = timer.when
+		}
+		c.mu.Unlock()
+
+		timer.fn()
+	}
+}
+
+// Pending returns the number of calls waiting for the clock to advance.
+func (c *Clock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
@@ -0,0 +1,31 @@
+package gomaineventstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+	clock := NewClock(start)
+
+	fired := []string{}
+	clock.AfterFunc(2*time.Second, func() { fired = append(fired, "second") })
+	clock.AfterFunc(time.Second, func() { fired = append(fired, "first") })
+	cancel := clock.AfterFunc(time.Second, func() { fired = append(fired, "cancelled") })
+	after := clock.After(3 * time.Second)
+
+	assert.True(t, cancel())
+	assert.False(t, cancel())
+
+	clock.Advance(1500 * time.Millisecond)
+	assert.Equal(t, []string{"first"}, fired)
+	assert.Equal(t, start.Add(1500*time.Millisecond), clock.Now())
+
+	clock.Advance(2 * time.Second)
+	assert.Equal(t, []string{"first", "second"}, fired)
+	assert.Equal(t, start.Add(3*time.Second), <-after)
+	assert.Equal(t, 0, clock.Pending())
+}
@@ -0,0 +1,370 @@
+package gomaineventstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// UpdateContractsEnv is the environment variable that lets RecordContract
+// overwrite a contract with breaking changes.
+const UpdateContractsEnv = "GOMAINEVENTS_UPDATE_CONTRACTS"
+
+// Contract is a sample of the events a producer publishes, recorded by the
+// producer's tests and checked in, so that consumers' tests can make sure
+// they still understand them.
+type Contract struct {
+	Producer string          `json:"producer"`
+	Events   []ContractEvent `json:"events"`
+}
+
+// ContractEvent is an event in a contract, with its data as it would be
+// received.
+type ContractEvent struct {
+	EventName string                 `json:"name"`
+	EventData map[string]interface{} `json:"data"`
+}
+
+func (e ContractEvent) Name() string {
+	return e.EventName
+}
+
+func (e ContractEvent) Data() map[string]interface{} {
+	return e.EventData
+}
+
+// LoadContract reads a contract written by RecordContract.
+func LoadContract(path string) (*Contract, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.UseNumber()
+
+	contract := &Contract{}
+	if err := decoder.Decode(contract); err != nil {
+		return nil, fmt.Errorf("Invalid contract %s: %s", path, err)
+	}
+
+	return contract, nil
+}
+
+// RecordContract writes the events a RecordingPublisher recorded to a
+// contract file for the producer, so that consumers can verify against it:
+//
+//	publisher := gomaineventstest.NewRecordingPublisher()
+//	// ... exercise the code that publishes ...
+//	gomaineventstest.RecordContract(t, "testdata/manuscripts.contract.json", "manuscripts", publisher)
+//
+// If the file already exists, the test fails instead when the new events
+// would break consumers of the old ones: an event that is no longer
+// published, a field that is gone, or a field whose type changed. Added
+// events and fields are fine. Set GOMAINEVENTS_UPDATE_CONTRACTS=1 to write
+// breaking changes anyway, once consumers are ready for them.
+func RecordContract(t testing.TB, path string, producer string, publisher *RecordingPublisher) {
+	t.Helper()
+
+	contract := &Contract{Producer: producer, Events: []ContractEvent{}}
+	for _, event := range publisher.Events() {
+		data, err := normalizeData(event.Data())
+		if err != nil {
+			t.Fatalf("Unable to record %s: %s", event.Name(), err)
+		}
+
+		contract.Events = append(contract.Events, ContractEvent{EventName: event.Name(), EventData: data})
+	}
+
+	if "" == os.Getenv(UpdateContractsEnv) {
+		if old, err := LoadContract(path); err == nil {
+			if breaks := breakingChanges(old, contract); len(breaks) > 0 {
+				t.Fatalf("Events published by %s would break consumers of %s:\n  %s\nSet %s=1 to update it anyway.",
+					producer, path, strings.Join(breaks, "\n  "), UpdateContractsEnv)
+			}
+		} else if !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+	}
+
+	encoded, err := json.MarshalIndent(contract, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, append(encoded, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ContractVerification is what a consumer expects of the events in a
+// contract.
+type ContractVerification struct {
+	// Listener the consumer registers its handlers with. Every event it
+	// has handlers for has to be in a contract, and its handlers have to
+	// succeed on each of them.
+	Listener *gomainevents.Listener
+
+	// Functions that decode events, by name, e.g. wrapping the Decode
+	// functions generated by gomainevents-gen. Every event listed has to be
+	// in a contract, and decode without an error.
+	Decoders map[string]func(gomainevents.Event) error
+}
+
+// VerifyContract fails the test if the consumer doesn't understand the
+// events in the producers' contracts: it handles or decodes events that
+// aren't published any more, or its handlers or decoders fail on the events
+// that are. Events the consumer doesn't handle are ignored.
+//
+// Handlers are run the way the Listener runs them, one after the other
+// until one fails, so they should be wired to fakes. It returns whether
+// verification passed.
+func VerifyContract(t testing.TB, verification *ContractVerification, paths ...string) bool {
+	t.Helper()
+
+	published := map[string][]ContractEvent{}
+	for _, path := range paths {
+		contract, err := LoadContract(path)
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+
+		for _, event := range contract.Events {
+			published[event.EventName] = append(published[event.EventName], event)
+		}
+	}
+
+	passed := true
+	fail := func(format string, values ...interface{}) {
+		t.Helper()
+		t.Errorf(format, values...)
+		passed = false
+	}
+
+	handled := []string{}
+	if nil != verification.Listener {
+		handled = verification.Listener.RegisteredEvents()
+	}
+
+	for _, name := range handled {
+		events, ok := published[name]
+		if !ok {
+			fail("Handlers are registered for %s, but no contract has it", name)
+			continue
+		}
+
+		for i, event := range events {
+			for _, fn := range verification.Listener.Handlers(name) {
+				if err := fn(WithRetryCount(event, 0)); err != nil {
+					fail("Handler failed on %s #%d %s: %s", name, i+1, encodeData(event.EventData), err)
+					break
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(verification.Decoders))
+	for name := range verification.Decoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		events, ok := published[name]
+		if !ok {
+			fail("A decoder is given for %s, but no contract has it", name)
+			continue
+		}
+
+		for i, event := range events {
+			if err := verification.Decoders[name](event); err != nil {
+				fail("Unable to decode %s #%d %s: %s", name, i+1, encodeData(event.EventData), err)
+			}
+		}
+	}
+
+	return passed
+}
+
+// breakingChanges lists what consumers of the old contract would miss in
+// the new one.
+func breakingChanges(previous *Contract, current *Contract) []string {
+	oldShapes, newShapes := shapes(previous), shapes(current)
+
+	names := make([]string, 0, len(oldShapes))
+	for name := range oldShapes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	breaks := []string{}
+	for _, name := range names {
+		newShape, ok := newShapes[name]
+		if !ok {
+			breaks = append(breaks, fmt.Sprintf("%s is no longer published", name))
+			continue
+		}
+
+		oldShape := oldShapes[name]
+		paths := make([]string, 0, len(oldShape))
+		for path := range oldShape {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			newTypes, ok := newShape[path]
+			if !ok {
+				// Only the outermost missing field is reported, and array
+				// items can't be missing when every array is empty.
+				parent := parentPath(path)
+				if _, parentPresent := newShape[parent]; "" == parent || (parentPresent && !strings.HasSuffix(path, "[]")) {
+					breaks = append(breaks, fmt.Sprintf("%s no longer has %s", name, path))
+				}
+				continue
+			}
+
+			if !overlaps(oldShape[path], newTypes) {
+				breaks = append(breaks, fmt.Sprintf("%s.%s changed from %s to %s", name, path, typeList(oldShape[path]), typeList(newTypes)))
+			}
+		}
+	}
+
+	return breaks
+}
+
+// shape is the JSON types seen at each path of an event's data, e.g.
+// "author.email" or "tags[]".
+type shape map[string]map[string]bool
+
+func shapes(contract *Contract) map[string]shape {
+	shapes := map[string]shape{}
+	for _, event := range contract.Events {
+		s, ok := shapes[event.EventName]
+		if !ok {
+			s = shape{}
+			shapes[event.EventName] = s
+		}
+
+		for key, value := range event.EventData {
+			s.add(key, value)
+		}
+	}
+
+	return shapes
+}
+
+func (s shape) add(path string, value interface{}) {
+	if nil == s[path] {
+		s[path] = map[string]bool{}
+	}
+	s[path][jsonType(value)] = true
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			s.add(path+"."+key, field)
+		}
+	case []interface{}:
+		for _, item := range v {
+			s.add(path+"[]", item)
+		}
+	}
+}
+
+// parentPath returns the path of the object or array a path is in, or an
+// empty string for top level fields.
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "[]") {
+		return strings.TrimSuffix(path, "[]")
+	}
+
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i]
+	}
+
+	return ""
+}
+
+// overlaps reports whether a field still has one of the types it had,
+// ignoring null, which consumers have to cope with anyway.
+func overlaps(previous map[string]bool, current map[string]bool) bool {
+	if len(previous) == 1 && previous["null"] {
+		return true
+	}
+
+	for t := range previous {
+		if "null" != t && current[t] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func typeList(types map[string]bool) string {
+	list := make([]string, 0, len(types))
+	for t := range types {
+		list = append(list, t)
+	}
+	sort.Strings(list)
+
+	return strings.Join(list, " or ")
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	}
+
+	return "object"
+}
+
+// normalizeData turns data into what consumers would receive, i.e. what
+// decoding it from JSON gives.
+func normalizeData(data map[string]interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+
+	normalized := map[string]interface{}{}
+	if err := decoder.Decode(&normalized); err != nil {
+		return nil, err
+	}
+
+	if nil == normalized {
+		normalized = map[string]interface{}{}
+	}
+
+	return normalized, nil
+}
+
+func encodeData(data map[string]interface{}) string {
+	encoded, _ := json.Marshal(data)
+	return string(encoded)
+}
@@ -0,0 +1,232 @@
+package gomaineventstest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failureRecorder stands in for the test passed to contract helpers, so
+// that their failures can be checked.
+type failureRecorder struct {
+	testing.TB
+	mu       sync.Mutex
+	failures []string
+}
+
+func (r *failureRecorder) Helper() {}
+
+func (r *failureRecorder) Errorf(format string, values ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures = append(r.failures, fmt.Sprintf(format, values...))
+}
+
+func (r *failureRecorder) Error(values ...interface{}) {
+	r.Errorf("%s", fmt.Sprint(values...))
+}
+
+func (r *failureRecorder) Fatalf(format string, values ...interface{}) {
+	r.Errorf(format, values...)
+	runtime.Goexit()
+}
+
+func (r *failureRecorder) Fatal(values ...interface{}) {
+	r.Error(values...)
+	runtime.Goexit()
+}
+
+// run calls fn in its own goroutine, so that Fatal can stop it, and returns
+// the failures.
+func (r *failureRecorder) run(fn func(t testing.TB)) []string {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fn(r)
+	}()
+	wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	failures := r.failures
+	r.failures = nil
+	return failures
+}
+
+func recordContract(t *testing.T, path string, events ...gomainevents.Event) []string {
+	publisher := NewRecordingPublisher()
+	require.Nil(t, publisher.PublishBatch(events))
+
+	recorder := &failureRecorder{TB: t}
+	return recorder.run(func(tb testing.TB) {
+		RecordContract(tb, path, "users", publisher)
+	})
+}
+
+func TestRecordContract(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contracts", "users.json")
+
+	failures := recordContract(t, path,
+		testEvent{name: "UserCreated", data: map[string]interface{}{"userId": 9007199254740993, "tags": []string{"a"}}},
+		testEvent{name: "UserDeleted", data: nil},
+	)
+	assert.Empty(t, failures)
+
+	contract, err := LoadContract(path)
+	require.Nil(t, err)
+	assert.Equal(t, "users", contract.Producer)
+	require.Len(t, contract.Events, 2)
+	assert.Equal(t, "UserCreated", contract.Events[0].Name())
+	assert.Equal(t, "9007199254740993", fmt.Sprint(contract.Events[0].Data()["userId"]))
+	assert.Equal(t, []interface{}{"a"}, contract.Events[0].Data()["tags"])
+	assert.Equal(t, map[string]interface{}{}, contract.Events[1].Data())
+
+	// Adding fields and events is fine.
+	failures = recordContract(t, path,
+		testEvent{name: "UserCreated", data: map[string]interface{}{"userId": 1, "tags": []string{}, "role": "admin"}},
+		testEvent{name: "UserDeleted", data: map[string]interface{}{}},
+		testEvent{name: "UserUpdated", data: map[string]interface{}{}},
+	)
+	assert.Empty(t, failures)
+
+	contract, _ = LoadContract(path)
+	assert.Len(t, contract.Events, 3)
+}
+
+func TestRecordContractBreakingChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+
+	assert.Empty(t, recordContract(t, path,
+		testEvent{name: "UserCreated", data: map[string]interface{}{
+			"userId":  1,
+			"email":   "a@example.com",
+			"manager": nil,
+			"address": map[string]interface{}{"city": "Durham"},
+		}},
+		testEvent{name: "UserDeleted", data: map[string]interface{}{"userId": 1}},
+	))
+
+	failures := recordContract(t, path,
+		testEvent{name: "UserCreated", data: map[string]interface{}{
+			"userId":  "1",
+			"manager": "someone",
+			"address": map[string]interface{}{},
+		}},
+	)
+
+	require.Len(t, failures, 1)
+	assert.Contains(t, failures[0], "UserCreated no longer has address.city")
+	assert.Contains(t, failures[0], "UserCreated no longer has email")
+	assert.Contains(t, failures[0], "UserCreated.userId changed from number to string")
+	assert.Contains(t, failures[0], "UserDeleted is no longer published")
+	assert.NotContains(t, failures[0], "manager")
+
+	// The old contract is kept.
+	contract, _ := LoadContract(path)
+	assert.Len(t, contract.Events, 2)
+
+	os.Setenv(UpdateContractsEnv, "1")
+	defer os.Unsetenv(UpdateContractsEnv)
+
+	assert.Empty(t, recordContract(t, path, testEvent{name: "UserCreated", data: map[string]interface{}{}}))
+
+	contract, _ = LoadContract(path)
+	assert.Len(t, contract.Events, 1)
+}
+
+func TestVerifyContract(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	require.Empty(t, recordContract(t, path,
+		testEvent{name: "UserCreated", data: map[string]interface{}{"userId": 1}},
+		testEvent{name: "UserCreated", data: map[string]interface{}{"userId": 2}},
+		testEvent{name: "UserDeleted", data: map[string]interface{}{"userId": 1}},
+	))
+
+	listener := gomainevents.NewListener(NewProvider(nil))
+
+	handled := []interface{}{}
+	listener.RegisterHandler("UserCreated", func(event gomainevents.Event) error {
+		handled = append(handled, event.Data()["userId"])
+		return nil
+	})
+
+	decoded := 0
+	verification := &ContractVerification{
+		Listener: listener,
+		Decoders: map[string]func(gomainevents.Event) error{
+			"UserDeleted": func(gomainevents.Event) error {
+				decoded++
+				return nil
+			},
+		},
+	}
+
+	recorder := &failureRecorder{TB: t}
+	failures := recorder.run(func(tb testing.TB) {
+		assert.True(t, VerifyContract(tb, verification, path))
+	})
+
+	assert.Empty(t, failures)
+	assert.Equal(t, "[1 2]", fmt.Sprint(handled))
+	assert.Equal(t, 1, decoded)
+}
+
+func TestVerifyContractFailures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	require.Empty(t, recordContract(t, path,
+		testEvent{name: "UserCreated", data: map[string]interface{}{"userId": "1"}},
+		testEvent{name: "UserDeleted", data: map[string]interface{}{}},
+	))
+
+	listener := gomainevents.NewListener(NewProvider(nil))
+	listener.RegisterHandler("UserCreated", func(event gomainevents.Event) error {
+		if _, ok := event.Data()["userId"].(string); ok {
+			return errors.New("userId must be a number")
+		}
+
+		return nil
+	})
+	listener.RegisterHandler("UserRenamed", func(gomainevents.Event) error {
+		return nil
+	})
+
+	verification := &ContractVerification{
+		Listener: listener,
+		Decoders: map[string]func(gomainevents.Event) error{
+			"UserDeleted": func(gomainevents.Event) error {
+				return errors.New("missing userId")
+			},
+			"UserSuspended": func(gomainevents.Event) error {
+				return nil
+			},
+		},
+	}
+
+	recorder := &failureRecorder{TB: t}
+	failures := recorder.run(func(tb testing.TB) {
+		assert.False(t, VerifyContract(tb, verification, path))
+	})
+
+	assert.Equal(t, []string{
+		`Handler failed on UserCreated #1 {"userId":"1"}: userId must be a number`,
+		"Handlers are registered for UserRenamed, but no contract has it",
+		`Unable to decode UserDeleted #1 {}: missing userId`,
+		"A decoder is given for UserSuspended, but no contract has it",
+	}, failures)
+
+	failures = recorder.run(func(tb testing.TB) {
+		assert.False(t, VerifyContract(tb, verification, filepath.Join(t.TempDir(), "missing.json")))
+	})
+	assert.Len(t, failures, 1)
+}
@@ -0,0 +1,49 @@
+package gomaineventstest
+
+import (
+	"github.com/researchsquare/gomainevents"
+)
+
+// Event wraps an event handed out by the fake Provider or RunHandler, with
+// the retry count real providers attach.
+type Event struct {
+	gomainevents.Event
+
+	// Assigned in the order events are pushed, starting from 1.
+	id int
+
+	retryCount int
+}
+
+// WithRetryCount returns the event as if it had already been delivered and
+// requeued retryCount times, for testing handlers that behave differently
+// on retries.
+func WithRetryCount(event gomainevents.Event, retryCount int) Event {
+	return Event{Event: unwrap(event), retryCount: retryCount}
+}
+
+// Unwrap returns the event as it was pushed.
+func (e Event) Unwrap() gomainevents.Event {
+	return e.Event
+}
+
+// ID returns the order in which the event was pushed to the fake Provider,
+// starting from 1. It is 0 for events built by WithRetryCount.
+func (e Event) ID() int {
+	return e.id
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
+
+// unwrap strips an Event back to the event inside it.
+func unwrap(event gomainevents.Event) gomainevents.Event {
+	if evt, ok := event.(Event); ok {
+		return evt.Event
+	}
+
+	return event
+}
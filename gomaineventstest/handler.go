@@ -0,0 +1,41 @@
+package gomaineventstest
+
+import (
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// RunHandler runs a handler against an event the way the Listener does: an
+// error means the event is requeued, otherwise it is deleted.
+func RunHandler(t testing.TB, handler gomainevents.EventHandler, event gomainevents.Event) Outcome {
+	t.Helper()
+
+	if err := handler(event); err != nil {
+		return Outcome{Event: event, Result: Requeued, Err: err}
+	}
+
+	return Outcome{Event: event, Result: Deleted}
+}
+
+// RunHandlerWithRetries runs a handler against an event, redelivering it
+// with an increasing retry count each time it fails, until it succeeds or
+// exceeds maximumRetryCount like a provider would. It returns the outcome
+// of every delivery.
+func RunHandlerWithRetries(t testing.TB, handler gomainevents.EventHandler, event gomainevents.Event, maximumRetryCount int) []Outcome {
+	t.Helper()
+
+	outcomes := []Outcome{}
+	for retryCount := 0; ; retryCount++ {
+		outcome := RunHandler(t, handler, WithRetryCount(event, retryCount))
+
+		if outcome.Result == Requeued && retryCount > maximumRetryCount {
+			outcome.Result = DeadLettered
+		}
+
+		outcomes = append(outcomes, outcome)
+		if outcome.Result != Requeued {
+			return outcomes
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package gomaineventstest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunHandler(t *testing.T) {
+	outcome := RunHandler(t, func(gomainevents.Event) error { return nil }, testEvent{name: "Thing"})
+	AssertDeleted(t, outcome)
+
+	outcome = RunHandler(t, func(gomainevents.Event) error { return errors.New("Oops") }, testEvent{name: "Thing"})
+	AssertRequeued(t, outcome)
+	assert.EqualError(t, outcome.Err, "Oops")
+}
+
+func TestRunHandlerWithRetries(t *testing.T) {
+	// Succeeds on the third attempt
+	handler := func(event gomainevents.Event) error {
+		if event.(Event).RetryCount() < 2 {
+			return errors.New("Not yet")
+		}
+
+		return nil
+	}
+
+	outcomes := RunHandlerWithRetries(t, handler, testEvent{name: "Thing"}, 5)
+	require.Len(t, outcomes, 3)
+	AssertRequeued(t, outcomes[0])
+	AssertRequeued(t, outcomes[1])
+	AssertDeleted(t, outcomes[2])
+
+	// Gives up after the first delivery and two retries
+	outcomes = RunHandlerWithRetries(t, func(gomainevents.Event) error { return errors.New("Never") }, testEvent{name: "Thing"}, 1)
+	require.Len(t, outcomes, 3)
+	AssertDeadLettered(t, outcomes[2])
+}
+
+func TestAssertResultFails(t *testing.T) {
+	inner := &testing.T{}
+	assert.False(t, AssertDeleted(inner, Outcome{Event: testEvent{name: "Thing"}, Result: Requeued, Err: errors.New("Oops")}))
+	assert.True(t, inner.Failed())
+}
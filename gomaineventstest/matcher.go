@@ -0,0 +1,69 @@
+package gomaineventstest
+
+import (
+	"reflect"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Matcher picks out events, e.g. for RecordingPublisher.Find.
+type Matcher func(gomainevents.Event) bool
+
+// Named matches events with the given name.
+func Named(name string) Matcher {
+	return func(event gomainevents.Event) bool {
+		return event.Name() == name
+	}
+}
+
+// WithData matches events whose data has the given value for a key.
+// Numbers match regardless of their type, so WithData("userId", 12) matches
+// an event whose userId is int64(12) or float64(12).
+func WithData(key string, value interface{}) Matcher {
+	return func(event gomainevents.Event) bool {
+		actual, ok := event.Data()[key]
+		return ok && equal(actual, value)
+	}
+}
+
+// WithDataSubset matches events whose data contains every key and value
+// in subset.
+func WithDataSubset(subset map[string]interface{}) Matcher {
+	return func(event gomainevents.Event) bool {
+		data := event.Data()
+		for key, value := range subset {
+			actual, ok := data[key]
+			if !ok || !equal(actual, value) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// equal compares values, treating numbers of any type as equal if they
+// have the same value.
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func number(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,74 @@
+package gomaineventstest
+
+import (
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Result is what happened to an event once it was handled.
+type Result string
+
+const (
+	// The handler succeeded and the event was deleted.
+	Deleted Result = "deleted"
+
+	// The handler failed and the event was requeued.
+	Requeued Result = "requeued"
+
+	// The handler failed too many times and the event was given up on.
+	DeadLettered Result = "dead-lettered"
+
+	// The event's handlers were disabled and it was put off without being
+	// handled. See gomainevents.Listener.Disable.
+	Deferred Result = "deferred"
+)
+
+// Outcome records how a delivery of an event ended.
+type Outcome struct {
+	Event  gomainevents.Event
+	Result Result
+
+	// The handler's error, when run through RunHandler.
+	Err error
+}
+
+// AssertDeleted fails the test unless the outcome is a deletion.
+func AssertDeleted(t testing.TB, outcome Outcome) bool {
+	t.Helper()
+	return assertResult(t, outcome, Deleted)
+}
+
+// AssertRequeued fails the test unless the outcome is a requeue.
+func AssertRequeued(t testing.TB, outcome Outcome) bool {
+	t.Helper()
+	return assertResult(t, outcome, Requeued)
+}
+
+// AssertDeferred fails the test unless the event was put off.
+func AssertDeferred(t testing.TB, outcome Outcome) bool {
+	t.Helper()
+	return assertResult(t, outcome, Deferred)
+}
+
+// AssertDeadLettered fails the test unless the event was given up on.
+func AssertDeadLettered(t testing.TB, outcome Outcome) bool {
+	t.Helper()
+	return assertResult(t, outcome, DeadLettered)
+}
+
+func assertResult(t testing.TB, outcome Outcome, expected Result) bool {
+	t.Helper()
+
+	if outcome.Result == expected {
+		return true
+	}
+
+	if nil != outcome.Err {
+		t.Errorf("Expected %s to be %s, but it was %s: %s", outcome.Event.Name(), expected, outcome.Result, outcome.Err)
+	} else {
+		t.Errorf("Expected %s to be %s, but it was %s", outcome.Event.Name(), expected, outcome.Result)
+	}
+
+	return false
+}
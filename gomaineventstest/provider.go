@@ -0,0 +1,334 @@
+package gomaineventstest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultMaximumRetryCount = 25
+	defaultWaitTimeout       = 5 * time.Second
+)
+
+// Provider is a fake provider for testing a Listener and its handlers.
+// Events and errors are scripted with Push and PushError and delivered in
+// order. Every Delete and Requeue is recorded as an Outcome. Requeued
+// events are redelivered once the Clock has been advanced past their delay,
+// so retries can be stepped through deterministically.
+type Provider struct {
+	clock             *Clock
+	redeliveryDelay   func(retryCount int) time.Duration
+	maximumRetryCount int
+
+	mu       sync.Mutex
+	nextID   int
+	queue    []Event
+	outcomes []Outcome
+	stopped  bool
+
+	// Signalled whenever the queue or the outcomes change.
+	changed chan bool
+	cond    *sync.Cond
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+}
+
+type ProviderConfig struct {
+	// Clock that redelivery delays are measured on. Defaults to a new
+	// fake clock.
+	Clock *Clock
+
+	// How long to wait before redelivering an event that has been
+	// requeued retryCount times. Defaults to the same backoff as the real
+	// providers: 2, 4, 8... seconds, up to 15 minutes.
+	RedeliveryDelay func(retryCount int) time.Duration
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+}
+
+// NewProvider returns a fake provider with the events queued up. A nil
+// config uses the defaults.
+func NewProvider(config *ProviderConfig, events ...gomainevents.Event) *Provider {
+	if nil == config {
+		config = &ProviderConfig{}
+	}
+
+	clock := config.Clock
+	if nil == clock {
+		clock = NewClock(time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC))
+	}
+
+	redeliveryDelay := config.RedeliveryDelay
+	if nil == redeliveryDelay {
+		redeliveryDelay = defaultRedeliveryDelay
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	p := &Provider{
+		clock:             clock,
+		redeliveryDelay:   redeliveryDelay,
+		maximumRetryCount: maximumRetryCount,
+		changed:           make(chan bool, 1),
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 100),
+		done:   make(chan bool),
+	}
+	p.cond = sync.NewCond(&p.mu)
+
+	p.Push(events...)
+
+	return p
+}
+
+// defaultRedeliveryDelay matches the backoff of the real providers.
+func defaultRedeliveryDelay(retryCount int) time.Duration {
+	return time.Duration(math.Min(
+		math.Pow(2, float64(retryCount+1)),
+		15*60, // Max is 15 minutes
+	)) * time.Second
+}
+
+// Clock returns the clock redelivery delays are measured on.
+func (p *Provider) Clock() *Clock {
+	return p.clock
+}
+
+// Push queues events to be delivered, after any already queued.
+func (p *Provider) Push(events ...gomainevents.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, event := range events {
+		p.nextID++
+		p.queue = append(p.queue, Event{Event: unwrap(event), id: p.nextID})
+	}
+
+	p.signal()
+}
+
+// PushError sends an error on the error channel, as a real provider does
+// when it fails to receive or decode events.
+func (p *Provider) PushError(err error) {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- err:
+	}
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	go func() {
+		for {
+			event, ok := p.next()
+			if !ok {
+				select {
+				case <-p.done:
+					return
+				case <-p.changed:
+				}
+				continue
+			}
+
+			if !p.deliver(event) {
+				return
+			}
+		}
+	}()
+
+	return p.events, p.errors
+}
+
+// next takes the first event off the queue.
+func (p *Provider) next() (Event, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.queue) == 0 {
+		return Event{}, false
+	}
+
+	event := p.queue[0]
+	p.queue = p.queue[1:]
+
+	return event, true
+}
+
+// Delete an event that we're done with
+func (p *Provider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to fake flavor
+
+	p.record(Outcome{Event: evt, Result: Deleted})
+}
+
+// Requeue an event for later. It is redelivered once the clock has been
+// advanced past its delay.
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to fake flavor
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		p.record(Outcome{Event: evt, Result: DeadLettered})
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	p.record(Outcome{Event: evt, Result: Requeued})
+
+	delay := p.redeliveryDelay(evt.retryCount)
+	evt.retryCount++
+	p.redeliver(evt, delay)
+
+	return nil
+}
+
+// Defer an event. It is redelivered with the same retry count once the
+// clock has been advanced past the delay.
+func (p *Provider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to fake flavor
+
+	p.record(Outcome{Event: evt, Result: Deferred})
+	p.redeliver(evt, delay)
+
+	return nil
+}
+
+// redeliver puts an event back on the queue once the clock has been
+// advanced past the delay.
+func (p *Provider) redeliver(evt Event, delay time.Duration) {
+	p.clock.AfterFunc(delay, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		p.queue = append(p.queue, evt)
+		p.signal()
+	})
+}
+
+func (p *Provider) record(outcome Outcome) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.outcomes = append(p.outcomes, outcome)
+	p.signal()
+}
+
+// signal wakes up the delivery loop and anyone waiting for outcomes. The
+// lock must be held.
+func (p *Provider) signal() {
+	select {
+	case p.changed <- true:
+	default:
+	}
+
+	p.cond.Broadcast()
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	p.mu.Lock()
+	p.stopped = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	close(p.done)
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// Outcomes returns the outcome of every delivery so far, in the order they
+// happened.
+func (p *Provider) Outcomes() []Outcome {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Outcome{}, p.outcomes...)
+}
+
+// WaitForOutcomes waits until there have been at least n outcomes and
+// returns them, failing the test if that takes more than 5 seconds.
+func (p *Provider) WaitForOutcomes(t testing.TB, n int) []Outcome {
+	t.Helper()
+
+	outcomes, err := p.waitForOutcomes(n, defaultWaitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return outcomes
+}
+
+func (p *Provider) waitForOutcomes(n int, timeout time.Duration) ([]Outcome, error) {
+	timer := time.AfterFunc(timeout, func() {
+		p.mu.Lock()
+		p.cond.Broadcast()
+		p.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	deadline := time.Now().Add(timeout)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.outcomes) < n {
+		if p.stopped {
+			return nil, errors.New("Provider stopped while waiting for outcomes")
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("Timed out waiting for %d outcomes, got %d", n, len(p.outcomes))
+		}
+
+		p.cond.Wait()
+	}
+
+	return append([]Outcome{}, p.outcomes...), nil
+}
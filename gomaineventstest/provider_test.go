@@ -0,0 +1,98 @@
+package gomaineventstest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, events <-chan gomainevents.Event) Event {
+	select {
+	case event := <-events:
+		return event.(Event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	return Event{}
+}
+
+func TestProviderRecordsOutcomes(t *testing.T) {
+	provider := NewProvider(nil, testEvent{name: "First"}, testEvent{name: "Second"})
+	defer provider.Stop()
+
+	events, _ := provider.Start()
+
+	first := receive(t, events)
+	second := receive(t, events)
+	assert.Equal(t, testEvent{name: "First"}, first.Unwrap())
+
+	provider.Delete(first)
+	assert.Nil(t, provider.Requeue(second))
+
+	outcomes := provider.WaitForOutcomes(t, 2)
+	AssertDeleted(t, outcomes[0])
+	AssertRequeued(t, outcomes[1])
+	assert.Equal(t, "Second", outcomes[1].Event.Name())
+}
+
+func TestProviderRedeliversOnTheClock(t *testing.T) {
+	provider := NewProvider(&ProviderConfig{MaximumRetryCount: 1}, testEvent{name: "Thing"})
+	defer provider.Stop()
+
+	events, _ := provider.Start()
+
+	assert.Nil(t, provider.Requeue(receive(t, events)))
+
+	// Not redelivered until its 2 second delay has passed
+	provider.Clock().Advance(time.Second)
+	select {
+	case <-events:
+		t.Fatal("Redelivered too soon")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	provider.Clock().Advance(time.Second)
+	event := receive(t, events)
+	assert.Equal(t, 1, event.RetryCount())
+
+	assert.Nil(t, provider.Requeue(event))
+	provider.Clock().Advance(4 * time.Second)
+
+	event = receive(t, events)
+	assert.Equal(t, 2, event.RetryCount())
+	assert.IsType(t, &RetryAttemptsExceededError{}, provider.Requeue(event))
+
+	outcomes := provider.WaitForOutcomes(t, 3)
+	AssertRequeued(t, outcomes[0])
+	AssertRequeued(t, outcomes[1])
+	AssertDeadLettered(t, outcomes[2])
+}
+
+func TestProviderPushError(t *testing.T) {
+	provider := NewProvider(nil)
+	defer provider.Stop()
+
+	_, errs := provider.Start()
+	provider.PushError(errors.New("Oops"))
+
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "Oops")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error")
+	}
+}
+
+func TestProviderWaitForOutcomesTimesOut(t *testing.T) {
+	provider := NewProvider(nil)
+	defer provider.Stop()
+
+	_, err := provider.waitForOutcomes(1, 10*time.Millisecond)
+	require.NotNil(t, err)
+	assert.Equal(t, "Timed out waiting for 1 outcomes, got 0", err.Error())
+}
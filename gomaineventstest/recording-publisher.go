@@ -0,0 +1,108 @@
+package gomaineventstest
+
+import (
+	"sync"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// RecordingPublisher keeps the events published to it in memory so that
+// tests can check what code under test published, without a hand-rolled
+// mock Publisher.
+type RecordingPublisher struct {
+	mu     sync.Mutex
+	events []gomainevents.Event
+	err    error
+}
+
+func NewRecordingPublisher() *RecordingPublisher {
+	return &RecordingPublisher{}
+}
+
+// Publish records the event, or returns the error set by FailWith.
+func (p *RecordingPublisher) Publish(event gomainevents.Event) error {
+	return p.PublishBatch([]gomainevents.Event{event})
+}
+
+// PublishBatch records the events, or returns the error set by FailWith.
+func (p *RecordingPublisher) PublishBatch(events []gomainevents.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if nil != p.err {
+		return p.err
+	}
+
+	p.events = append(p.events, events...)
+	return nil
+}
+
+// FailWith makes every publish fail with err, without recording anything,
+// until it's called again with nil.
+func (p *RecordingPublisher) FailWith(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.err = err
+}
+
+// Events returns every recorded event, in the order they were published.
+func (p *RecordingPublisher) Events() []gomainevents.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]gomainevents.Event{}, p.events...)
+}
+
+// ByName returns the recorded events with the given name.
+func (p *RecordingPublisher) ByName(name string) []gomainevents.Event {
+	return p.Find(Named(name))
+}
+
+// Find returns the recorded events that match all of the matchers.
+func (p *RecordingPublisher) Find(matchers ...Matcher) []gomainevents.Event {
+	found := []gomainevents.Event{}
+
+	for _, event := range p.Events() {
+		matched := true
+		for _, matches := range matchers {
+			if !matches(event) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			found = append(found, event)
+		}
+	}
+
+	return found
+}
+
+// Count returns the number of recorded events.
+func (p *RecordingPublisher) Count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.events)
+}
+
+// Names returns the names of the recorded events, in the order they were
+// published.
+func (p *RecordingPublisher) Names() []string {
+	names := []string{}
+	for _, event := range p.Events() {
+		names = append(names, event.Name())
+	}
+
+	return names
+}
+
+// Reset forgets every recorded event.
+func (p *RecordingPublisher) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = nil
+}
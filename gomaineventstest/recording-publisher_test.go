@@ -0,0 +1,59 @@
+package gomaineventstest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return e.data
+}
+
+func TestRecordingPublisher(t *testing.T) {
+	publisher := NewRecordingPublisher()
+
+	require.Nil(t, publisher.Publish(testEvent{name: "UserCreated", data: map[string]interface{}{"userId": 12, "role": "admin"}}))
+	require.Nil(t, publisher.PublishBatch([]gomainevents.Event{
+		testEvent{name: "UserCreated", data: map[string]interface{}{"userId": 13, "role": "author"}},
+		testEvent{name: "UserDeleted", data: map[string]interface{}{"userId": 12.0}},
+	}))
+
+	assert.Equal(t, 3, publisher.Count())
+	assert.Equal(t, []string{"UserCreated", "UserCreated", "UserDeleted"}, publisher.Names())
+	assert.Len(t, publisher.ByName("UserCreated"), 2)
+	assert.Empty(t, publisher.ByName("UserUpdated"))
+
+	// Numbers match whatever their type
+	assert.Len(t, publisher.Find(WithData("userId", 12)), 2)
+	assert.Len(t, publisher.Find(Named("UserDeleted"), WithData("userId", int64(12))), 1)
+	assert.Len(t, publisher.Find(WithDataSubset(map[string]interface{}{"userId": 13, "role": "author"})), 1)
+	assert.Empty(t, publisher.Find(WithDataSubset(map[string]interface{}{"userId": 13, "role": "admin"})))
+
+	publisher.Reset()
+	assert.Equal(t, 0, publisher.Count())
+}
+
+func TestRecordingPublisherFailWith(t *testing.T) {
+	publisher := NewRecordingPublisher()
+
+	publisher.FailWith(errors.New("Unavailable"))
+	assert.EqualError(t, publisher.Publish(testEvent{name: "Thing"}), "Unavailable")
+	assert.Equal(t, 0, publisher.Count())
+
+	publisher.FailWith(nil)
+	assert.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+	assert.Equal(t, 1, publisher.Count())
+}
@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/grpc/eventspb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Event implements the standard domain event interface for events received
+// over gRPC.
+type Event struct {
+	name string
+	data map[string]interface{}
+
+	// Streams have no broker to redeliver from, so the provider keeps
+	// track of how many times it has redelivered the event itself.
+	retryCount int
+}
+
+// encodeEvent converts a domain event to its protobuf form. The data is
+// converted through JSON, the same as for every other transport.
+func encodeEvent(event gomainevents.Event) (*eventspb.Event, error) {
+	msg := &eventspb.Event{Name: event.Name()}
+
+	if nil != event.Data() {
+		bytes, err := json.Marshal(event.Data())
+		if err != nil {
+			return nil, err
+		}
+
+		msg.Data = &structpb.Struct{}
+		if err := msg.Data.UnmarshalJSON(bytes); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+// decodeEvent builds an event from its protobuf form.
+func decodeEvent(msg *eventspb.Event) *Event {
+	return &Event{name: msg.GetName(), data: msg.GetData().AsMap()}
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
+
+// Delay returns how long to wait before redelivering this event.
+func (e Event) Delay() time.Duration {
+	return time.Duration(math.Min(
+		math.Pow(2, float64(e.retryCount+1)),
+		15*60, // Max is 15 minutes
+	)) * time.Second
+}
@@ -0,0 +1,5 @@
+// Package eventspb contains the generated protobuf and gRPC code for
+// events.proto.
+package eventspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative events.proto
@@ -0,0 +1,286 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/grpc/eventspb"
+	googlegrpc "google.golang.org/grpc"
+)
+
+const (
+	defaultMaximumRetryCount = 25
+	defaultReconnectDelay    = time.Second
+	defaultMaxReconnectDelay = time.Minute
+)
+
+// Provider streams events from a remote Server and hands them to the
+// Listener, resubscribing whenever the stream ends.
+type Provider struct {
+	client    eventspb.EventsClient
+	conn      *googlegrpc.ClientConn
+	ownConn   bool
+	subscribe []string
+
+	maximumRetryCount int
+	jitter            gomainevents.Jitter
+	clock             gomainevents.Clock
+	reconnectDelay    time.Duration
+	maxReconnectDelay time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+}
+
+type ProviderConfig struct {
+	// Address of the Server, e.g. localhost:8995 or dns:///events:8995.
+	// Either Target or Conn is required.
+	Target string
+
+	// Options used when connecting to Target. Transport credentials are
+	// required, e.g. insecure.NewCredentials() for plaintext.
+	DialOptions []googlegrpc.DialOption
+
+	// An existing connection to share with other clients. It is not
+	// closed by Stop.
+	Conn *googlegrpc.ClientConn
+
+	// Only receive these events. Defaults to every event.
+	Subscribe []string
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+
+	// Randomizes retry delays, so that events that failed together aren't
+	// retried together. Defaults to none.
+	Jitter gomainevents.Jitter
+
+	// Delay before the first resubscribe attempt. Doubles on every failed
+	// attempt up to MaxReconnectDelay. Defaults to 1 second and 1 minute.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+
+	// Clock reconnect and redelivery delays are measured on. Defaults to
+	// gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewProvider(config *ProviderConfig) (*Provider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	conn, ownConn, err := connect(config.Target, config.DialOptions, config.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	reconnectDelay := defaultReconnectDelay
+	if config.ReconnectDelay > 0 {
+		reconnectDelay = config.ReconnectDelay
+	}
+
+	maxReconnectDelay := defaultMaxReconnectDelay
+	if config.MaxReconnectDelay > 0 {
+		maxReconnectDelay = config.MaxReconnectDelay
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Provider{
+		client:            eventspb.NewEventsClient(conn),
+		conn:              conn,
+		ownConn:           ownConn,
+		subscribe:         config.Subscribe,
+		maximumRetryCount: maximumRetryCount,
+		jitter:            config.Jitter,
+		clock:             gomainevents.ClockOrDefault(config.Clock),
+		reconnectDelay:    reconnectDelay,
+		maxReconnectDelay: maxReconnectDelay,
+		ctx:               ctx,
+		cancel:            cancel,
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}, nil
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	go func() {
+		delay := p.reconnectDelay
+
+		for {
+			received, err := p.stream()
+
+			select {
+			case <-p.done:
+				return
+			default:
+			}
+
+			p.reportError(gomainevents.PhaseConnect, "", err)
+
+			// Only back off if the stream never got going.
+			if received {
+				delay = p.reconnectDelay
+			}
+
+			select {
+			case <-p.done:
+				return
+			case <-p.clock.After(delay):
+			}
+
+			delay *= 2
+			if delay > p.maxReconnectDelay {
+				delay = p.maxReconnectDelay
+			}
+
+			p.debugPrint("Stream ended, resubscribing...\n")
+		}
+	}()
+
+	return p.events, p.errors
+}
+
+// stream delivers events from a single Subscribe call until it fails. It
+// reports whether any events were received.
+func (p *Provider) stream() (bool, error) {
+	stream, err := p.client.Subscribe(p.ctx, &eventspb.SubscribeRequest{Events: p.subscribe})
+	if err != nil {
+		return false, err
+	}
+
+	received := false
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+
+		received = true
+		if !p.deliver(*decodeEvent(msg)) {
+			return received, nil
+		}
+	}
+}
+
+// Delete an event that we're done with. Streamed events aren't stored
+// anywhere, so there's nothing to do.
+func (p *Provider) Delete(event gomainevents.Event) {}
+
+// Requeue an event for later. The event is redelivered by the provider
+// itself after a delay.
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to gRPC flavor
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	delay := p.jitter.Apply(evt.Delay())
+	evt.retryCount++
+
+	p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", evt.RetryCount(), delay)
+	p.clock.AfterFunc(delay, func() {
+		p.deliver(evt)
+	})
+
+	return nil
+}
+
+// Defer an event, redelivering it after the delay with the same retry
+// count.
+func (p *Provider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to gRPC flavor
+
+	p.debugPrint("Deferring event. Delay: %s\n", delay)
+	p.clock.AfterFunc(delay, func() {
+		p.deliver(evt)
+	})
+
+	return nil
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	close(p.done)
+	p.cancel()
+
+	if p.ownConn {
+		p.conn.Close()
+	}
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, "", messageID, err):
+	default:
+	}
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-grpc] "+format, values...)
+	}
+}
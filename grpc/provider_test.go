@@ -0,0 +1,165 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var insecureOptions = []googlegrpc.DialOption{
+	googlegrpc.WithTransportCredentials(insecure.NewCredentials()),
+}
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11", "count": 3}
+}
+
+// newTestServer serves the Events service in memory and returns a
+// connection to it.
+func newTestServer(t *testing.T) (*Server, *googlegrpc.ClientConn) {
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := NewServer(nil)
+	grpcServer := googlegrpc.NewServer()
+	server.Register(grpcServer)
+	go grpcServer.Serve(listener)
+
+	conn, err := googlegrpc.NewClient("passthrough:///bufnet",
+		googlegrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		googlegrpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.Nil(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Close()
+		grpcServer.Stop()
+	})
+
+	return server, conn
+}
+
+func waitForSubscribers(t *testing.T, server *Server, count int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for server.Count() != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d subscribers, have %d", count, server.Count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{Target: "localhost:8995", DialOptions: insecureOptions})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+}
+
+func TestProviderReceivesPublishedEvents(t *testing.T) {
+	server, conn := newTestServer(t)
+
+	provider, err := NewProvider(&ProviderConfig{Conn: conn, Subscribe: []string{"Wanted"}})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+	waitForSubscribers(t, server, 1)
+
+	publisher, err := NewPublisher(&PublisherConfig{Conn: conn})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Unwanted"}))
+	require.Nil(t, publisher.Publish(testEvent{name: "Wanted"}))
+	require.Nil(t, server.Publish(testEvent{name: "Wanted"}))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-events:
+			assert.Equal(t, "Wanted", event.Name())
+			assert.Equal(t, map[string]interface{}{"occurredOn": "2018-03-08 11:11:11", "count": 3.0}, event.Data())
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for event")
+		}
+	}
+}
+
+func TestProviderResubscribes(t *testing.T) {
+	server, conn := newTestServer(t)
+
+	provider, err := NewProvider(&ProviderConfig{Conn: conn, ReconnectDelay: 10 * time.Millisecond})
+	require.Nil(t, err)
+
+	events, errs := provider.Start()
+	defer provider.Stop()
+	waitForSubscribers(t, server, 1)
+
+	// Ending the stream from the server side
+	server.mu.Lock()
+	for sub := range server.subscribers {
+		delete(server.subscribers, sub)
+		close(sub.send)
+	}
+	server.mu.Unlock()
+
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error")
+	}
+
+	waitForSubscribers(t, server, 1)
+	require.Nil(t, server.Publish(testEvent{name: "Thing"}))
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "Thing", event.Name())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+}
+
+func TestProviderRequeue(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{Target: "localhost:8995", DialOptions: insecureOptions, MaximumRetryCount: 1})
+	require.Nil(t, err)
+	defer provider.Stop()
+
+	assert.Nil(t, provider.Requeue(Event{name: "Thing", retryCount: 1}))
+
+	err = provider.Requeue(Event{name: "Thing", retryCount: 2})
+	assert.IsType(t, &RetryAttemptsExceededError{}, err)
+}
+
+func TestServerRejectsNamelessEvents(t *testing.T) {
+	_, conn := newTestServer(t)
+
+	publisher, err := NewPublisher(&PublisherConfig{Conn: conn})
+	require.Nil(t, err)
+
+	assert.NotNil(t, publisher.Publish(testEvent{}))
+}
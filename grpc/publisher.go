@@ -0,0 +1,102 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/grpc/eventspb"
+	googlegrpc "google.golang.org/grpc"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Publisher sends events to a remote Server.
+type Publisher struct {
+	client  eventspb.EventsClient
+	conn    *googlegrpc.ClientConn
+	ownConn bool
+	timeout time.Duration
+}
+
+type PublisherConfig struct {
+	// Address of the Server, e.g. localhost:8995 or dns:///events:8995.
+	// Either Target or Conn is required.
+	Target string
+
+	// Options used when connecting to Target. Transport credentials are
+	// required, e.g. insecure.NewCredentials() for plaintext.
+	DialOptions []googlegrpc.DialOption
+
+	// An existing connection to share with other clients. It is not
+	// closed by Close.
+	Conn *googlegrpc.ClientConn
+
+	// Maximum time allowed for a single publish. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+func NewPublisher(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	conn, ownConn, err := connect(config.Target, config.DialOptions, config.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultTimeout
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	return &Publisher{
+		client:  eventspb.NewEventsClient(conn),
+		conn:    conn,
+		ownConn: ownConn,
+		timeout: timeout,
+	}, nil
+}
+
+// connect returns the configured connection or creates one for the target.
+// The boolean reports whether the connection was created here.
+func connect(target string, options []googlegrpc.DialOption, conn *googlegrpc.ClientConn) (*googlegrpc.ClientConn, bool, error) {
+	if nil != conn {
+		return conn, false, nil
+	}
+
+	if "" == target {
+		return nil, false, errors.New("Target or Conn is required")
+	}
+
+	conn, err := googlegrpc.NewClient(target, options...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return conn, true, nil
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	msg, err := encodeEvent(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	_, err = p.client.Publish(ctx, msg)
+	return err
+}
+
+// Close closes the connection if the publisher created it.
+func (p *Publisher) Close() error {
+	if p.ownConn {
+		return p.conn.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package grpc
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/grpc/eventspb"
+	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultSendBufferSize = 256
+
+// Server implements the Events gRPC service. Events published to it, either
+// locally through Publish or by remote Publishers, are streamed to every
+// subscribed Provider.
+type Server struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]bool
+	closed      bool
+
+	sendBufferSize int
+
+	debug bool
+}
+
+type ServerConfig struct {
+	// Number of events queued per subscriber before it is considered too
+	// slow and its stream is ended. Defaults to 256.
+	SendBufferSize int
+}
+
+// subscriber is a single Subscribe stream.
+type subscriber struct {
+	// Event names the subscriber wants. Empty means everything.
+	events map[string]bool
+	send   chan *eventspb.Event
+}
+
+func (s *subscriber) wants(name string) bool {
+	return len(s.events) == 0 || s.events[name]
+}
+
+func NewServer(config *ServerConfig) *Server {
+	if nil == config {
+		config = &ServerConfig{}
+	}
+
+	sendBufferSize := defaultSendBufferSize
+	if config.SendBufferSize > 0 {
+		sendBufferSize = config.SendBufferSize
+	}
+
+	return &Server{
+		subscribers:    make(map[*subscriber]bool),
+		sendBufferSize: sendBufferSize,
+		debug:          true,
+	}
+}
+
+// Register adds the Events service to a gRPC server.
+func (s *Server) Register(registrar googlegrpc.ServiceRegistrar) {
+	eventspb.RegisterEventsServer(registrar, &service{server: s})
+}
+
+// Publish streams the event to every subscriber that wants it. Subscribers
+// that can't keep up are disconnected; that isn't considered a publish
+// failure.
+func (s *Server) Publish(event gomainevents.Event) error {
+	msg, err := encodeEvent(event)
+	if err != nil {
+		return err
+	}
+
+	s.broadcast(msg)
+	return nil
+}
+
+func (s *Server) broadcast(msg *eventspb.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscribers {
+		if !sub.wants(msg.GetName()) {
+			continue
+		}
+
+		select {
+		case sub.send <- msg:
+		default:
+			s.debugPrint("Subscriber is too slow, disconnecting\n")
+			delete(s.subscribers, sub)
+			close(sub.send)
+		}
+	}
+}
+
+// Count returns the number of subscribers.
+func (s *Server) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.subscribers)
+}
+
+// Close ends every stream and rejects new subscribers.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for sub := range s.subscribers {
+		delete(s.subscribers, sub)
+		close(sub.send)
+	}
+}
+
+func (s *Server) subscribe(sub *subscriber) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.subscribers[sub] = true
+	return true
+}
+
+func (s *Server) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscribers[sub] {
+		delete(s.subscribers, sub)
+		close(sub.send)
+	}
+}
+
+func (s *Server) debugPrint(format string, values ...interface{}) {
+	if s.debug {
+		log.Printf("[gomainevents-grpc] "+format, values...)
+	}
+}
+
+// service adapts the Server to the generated interface, whose Publish
+// method would otherwise clash with gomainevents.Publisher.
+type service struct {
+	eventspb.UnimplementedEventsServer
+
+	server *Server
+}
+
+func (svc *service) Publish(ctx context.Context, msg *eventspb.Event) (*eventspb.PublishResponse, error) {
+	if "" == msg.GetName() {
+		return nil, status.Error(codes.InvalidArgument, "Event name is required")
+	}
+
+	svc.server.broadcast(msg)
+	return &eventspb.PublishResponse{}, nil
+}
+
+func (svc *service) Subscribe(req *eventspb.SubscribeRequest, stream eventspb.Events_SubscribeServer) error {
+	sub := &subscriber{
+		events: make(map[string]bool),
+		send:   make(chan *eventspb.Event, svc.server.sendBufferSize),
+	}
+	for _, name := range req.GetEvents() {
+		sub.events[name] = true
+	}
+
+	if !svc.server.subscribe(sub) {
+		return status.Error(codes.Unavailable, "Server is shutting down")
+	}
+	defer svc.server.unsubscribe(sub)
+
+	for {
+		select {
+		case msg, ok := <-sub.send:
+			if !ok {
+				return status.Error(codes.Unavailable, "Stream closed by server")
+			}
+
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package gomainevents
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHeartbeatInterval  = time.Minute
+	defaultHeartbeatEventName = "GomaineventsHeartbeat"
+)
+
+// Heartbeat reports a Listener's stats, so that monitoring can tell a
+// consumer that is running but no longer receiving events from one that is
+// simply idle. It publishes a heartbeat event every Interval:
+//
+//	{"instance":"worker-1","startedAt":"...","received":120,"processed":118,
+//	 "failed":2,"lastReceivedAt":"...","lastProcessedAt":"...","occurredOn":"..."}
+//
+// and serves the same data as JSON over HTTP, e.g. for a health check.
+// Times are RFC 3339 in UTC, and empty until there is something to report.
+type Heartbeat struct {
+	listener  *Listener
+	publisher Publisher
+	interval  time.Duration
+	eventName string
+	instance  string
+	onError   ErrorHandler
+	clock     Clock
+
+	done     chan bool
+	stopOnce sync.Once
+}
+
+type HeartbeatConfig struct {
+	// Listener to report on. Required
+	Listener *Listener
+
+	// Where heartbeat events are published. Without one, heartbeats are
+	// only served over HTTP.
+	Publisher Publisher
+
+	// How often to publish. Defaults to a minute.
+	Interval time.Duration
+
+	// Name of the heartbeat events. Defaults to "GomaineventsHeartbeat".
+	EventName string
+
+	// Identifies this consumer. Defaults to the hostname.
+	Instance string
+
+	// Called when a heartbeat can't be published. Optional
+	OnError ErrorHandler
+
+	// Clock heartbeats are timed and stamped with. Defaults to
+	// SystemClock.
+	Clock Clock
+}
+
+func NewHeartbeat(config *HeartbeatConfig) (*Heartbeat, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Listener {
+		return nil, errors.New("Listener is required")
+	}
+
+	interval := defaultHeartbeatInterval
+	if config.Interval > 0 {
+		interval = config.Interval
+	}
+
+	eventName := defaultHeartbeatEventName
+	if "" != config.EventName {
+		eventName = config.EventName
+	}
+
+	instance := config.Instance
+	if "" == instance {
+		instance, _ = os.Hostname()
+	}
+
+	return &Heartbeat{
+		listener:  config.Listener,
+		publisher: config.Publisher,
+		interval:  interval,
+		eventName: eventName,
+		instance:  instance,
+		onError:   config.OnError,
+		clock:     ClockOrDefault(config.Clock),
+		done:      make(chan bool),
+	}, nil
+}
+
+// Start publishes a heartbeat straight away and then every Interval until
+// Stop is called. It does nothing without a Publisher.
+func (h *Heartbeat) Start() {
+	if nil == h.publisher {
+		return
+	}
+
+	go func() {
+		for {
+			h.publish()
+
+			select {
+			case <-h.done:
+				return
+			case <-h.clock.After(h.interval):
+			}
+		}
+	}()
+}
+
+// Stop stops publishing heartbeats.
+func (h *Heartbeat) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+// ServeHTTP responds with the current heartbeat as JSON.
+func (h *Heartbeat) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Event().Data())
+}
+
+// Event returns the current heartbeat.
+func (h *Heartbeat) Event() Event {
+	stats := h.listener.Stats()
+
+	return heartbeatEvent{
+		name: h.eventName,
+		data: map[string]interface{}{
+			"instance":        h.instance,
+			"startedAt":       formatHeartbeatTime(stats.StartedAt),
+			"received":        stats.Received,
+			"processed":       stats.Processed,
+			"failed":          stats.Failed,
+			"lastReceivedAt":  formatHeartbeatTime(stats.LastReceivedAt),
+			"lastProcessedAt": formatHeartbeatTime(stats.LastProcessedAt),
+			"occurredOn":      formatHeartbeatTime(h.clock.Now()),
+		},
+	}
+}
+
+func (h *Heartbeat) publish() {
+	if err := h.publisher.Publish(h.Event()); err != nil && nil != h.onError {
+		h.onError(err)
+	}
+}
+
+type heartbeatEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e heartbeatEvent) Name() string {
+	return e.name
+}
+
+func (e heartbeatEvent) Data() map[string]interface{} {
+	return e.data
+}
+
+func formatHeartbeatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,168 @@
+package gomainevents
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type heartbeatPublisher struct {
+	mu     sync.Mutex
+	events []Event
+	err    error
+}
+
+func (p *heartbeatPublisher) Publish(event Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, event)
+	return p.err
+}
+
+func (p *heartbeatPublisher) published() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Event{}, p.events...)
+}
+
+// fixedClock is the system clock, except that the time never changes.
+type fixedClock struct {
+	Clock
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestNewHeartbeat(t *testing.T) {
+	heartbeat, err := NewHeartbeat(nil)
+	assert.Nil(t, heartbeat)
+	assert.NotNil(t, err)
+
+	heartbeat, err = NewHeartbeat(&HeartbeatConfig{})
+	assert.Nil(t, heartbeat)
+	assert.NotNil(t, err)
+
+	heartbeat, err = NewHeartbeat(&HeartbeatConfig{Listener: NewListener(&recordingProvider{})})
+	require.Nil(t, err)
+	assert.Equal(t, time.Minute, heartbeat.interval)
+	assert.Equal(t, "GomaineventsHeartbeat", heartbeat.eventName)
+}
+
+func TestListenerStats(t *testing.T) {
+	provider := &recordingProvider{events: make(chan Event)}
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Created", func(Event) error { return nil })
+	listener.RegisterHandler("Deleted", func(Event) error { return errors.New("Nope") })
+
+	assert.Equal(t, ListenerStats{}, listener.Stats())
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	provider.events <- testEvent{name: "Created"}
+	provider.events <- testEvent{name: "Deleted"}
+	provider.events <- testEvent{name: "Created"}
+
+	assert.Eventually(t, func() bool {
+		stats := listener.Stats()
+		return stats.Processed == 2 && stats.Failed == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stats := listener.Stats()
+	assert.Equal(t, int64(3), stats.Received)
+	assert.False(t, stats.StartedAt.IsZero())
+	assert.False(t, stats.LastReceivedAt.Before(stats.StartedAt))
+	assert.False(t, stats.LastProcessedAt.Before(stats.StartedAt))
+}
+
+func TestHeartbeatPublishes(t *testing.T) {
+	publisher := &heartbeatPublisher{err: errors.New("Nope")}
+	reported := make(chan error, 10)
+
+	heartbeat, _ := NewHeartbeat(&HeartbeatConfig{
+		Listener:  NewListener(&recordingProvider{}),
+		Publisher: publisher,
+		Interval:  10 * time.Millisecond,
+		Instance:  "worker-1",
+		OnError: func(err error) {
+			select {
+			case reported <- err:
+			default:
+			}
+		},
+	})
+	heartbeat.clock = fixedClock{Clock: SystemClock, now: time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)}
+
+	heartbeat.Start()
+	assert.Eventually(t, func() bool { return len(publisher.published()) >= 2 }, 5*time.Second, time.Millisecond)
+	heartbeat.Stop()
+	heartbeat.Stop()
+
+	event := publisher.published()[0]
+	assert.Equal(t, "GomaineventsHeartbeat", event.Name())
+	assert.Equal(t, map[string]interface{}{
+		"instance":        "worker-1",
+		"startedAt":       "",
+		"received":        int64(0),
+		"processed":       int64(0),
+		"failed":          int64(0),
+		"lastReceivedAt":  "",
+		"lastProcessedAt": "",
+		"occurredOn":      "2018-03-08T11:11:11Z",
+	}, event.Data())
+	assert.EqualError(t, <-reported, "Nope")
+
+	// Nothing more once stopped
+	time.Sleep(20 * time.Millisecond)
+	count := len(publisher.published())
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, count, len(publisher.published()))
+}
+
+func TestHeartbeatServeHTTP(t *testing.T) {
+	listener := NewListener(&recordingProvider{})
+	listener.stats = ListenerStats{
+		StartedAt:       time.Date(2018, 3, 8, 11, 0, 0, 0, time.UTC),
+		Received:        3,
+		Processed:       2,
+		Failed:          1,
+		LastReceivedAt:  time.Date(2018, 3, 8, 11, 10, 0, 0, time.UTC),
+		LastProcessedAt: time.Date(2018, 3, 8, 11, 5, 0, 0, time.UTC),
+	}
+
+	heartbeat, _ := NewHeartbeat(&HeartbeatConfig{Listener: listener, Instance: "worker-1"})
+	heartbeat.clock = fixedClock{Clock: SystemClock, now: time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)}
+
+	// Without a publisher there's nothing to start
+	heartbeat.Start()
+
+	response := httptest.NewRecorder()
+	heartbeat.ServeHTTP(response, httptest.NewRequest("GET", "/heartbeat", nil))
+
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+	body := map[string]interface{}{}
+	require.Nil(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Equal(t, map[string]interface{}{
+		"instance":        "worker-1",
+		"startedAt":       "2018-03-08T11:00:00Z",
+		"received":        float64(3),
+		"processed":       float64(2),
+		"failed":          float64(1),
+		"lastReceivedAt":  "2018-03-08T11:10:00Z",
+		"lastProcessedAt": "2018-03-08T11:05:00Z",
+		"occurredOn":      "2018-03-08T11:11:11Z",
+	}, body)
+}
@@ -0,0 +1,208 @@
+package idempotency
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/audit"
+)
+
+const defaultWindow = time.Hour
+
+// Detector reports events that are delivered to a handler more than once
+// within a window, e.g. SQS redeliveries, to measure how often they happen
+// and which handlers need a Guard. Unlike a Guard, it never skips or fails
+// an event. Wrap handlers with it:
+//
+//	listener.RegisterHandler("PaymentReceived", detector.Handler("recordPayment", recordPayment))
+//
+// Each delivery claims the event's key in the Store for the window, and
+// completes it if the handler succeeds, so a duplicate's Previous status
+// says whether an earlier delivery was handled successfully. Sharing a
+// store between consumer instances catches redeliveries to other instances.
+type Detector struct {
+	store       Store
+	key         func(gomainevents.Event) string
+	namespace   string
+	window      time.Duration
+	onDuplicate func(Duplicate)
+	onError     func(error)
+	metrics     Counter
+
+	mu    sync.Mutex
+	stats DetectorStats
+}
+
+// Counter sends counter metrics. *statsd.Client is one.
+type Counter interface {
+	Incr(name string, tags ...string)
+}
+
+// Duplicate describes an event delivered to a handler again.
+type Duplicate struct {
+	Key       string
+	EventName string
+	Handler   string
+
+	// Completed if an earlier delivery was handled successfully, or
+	// InProgress if it failed or is still being handled.
+	Previous Status
+}
+
+// DetectorStats are counts of what a Detector has seen.
+type DetectorStats struct {
+	// Deliveries checked, and of those, the ones that were duplicates.
+	Checked    int64
+	Duplicates int64
+
+	// Duplicates by event name.
+	ByEvent map[string]int64
+}
+
+type DetectorConfig struct {
+	// Store deliveries are recorded in. Required
+	Store Store
+
+	// Returns the key identifying an event, or "" to not check it.
+	// Defaults to the message ID or sequence number the provider gave it,
+	// see audit.EventID.
+	Key func(gomainevents.Event) string
+
+	// Prefixed to keys. Use a different one from any Guard sharing the
+	// store.
+	Namespace string
+
+	// How long deliveries are remembered. Defaults to an hour.
+	Window time.Duration
+
+	// Called for each duplicate. Optional
+	OnDuplicate func(Duplicate)
+
+	// Counts duplicates as events.duplicates, tagged with event, handler
+	// and previous (completed or in_progress). Optional
+	Metrics Counter
+
+	// Called with errors from the store, which don't fail the event.
+	OnError func(error)
+}
+
+func NewDetector(config *DetectorConfig) (*Detector, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Store {
+		return nil, errors.New("Store is required")
+	}
+
+	key := config.Key
+	if nil == key {
+		key = audit.EventID
+	}
+
+	window := defaultWindow
+	if config.Window > 0 {
+		window = config.Window
+	}
+
+	return &Detector{
+		store:       config.Store,
+		key:         key,
+		namespace:   config.Namespace,
+		window:      window,
+		onDuplicate: config.OnDuplicate,
+		onError:     config.OnError,
+		metrics:     config.Metrics,
+		stats:       DetectorStats{ByEvent: map[string]int64{}},
+	}, nil
+}
+
+// Handler wraps an EventHandler, reporting events delivered to it more than
+// once under the given handler name.
+func (d *Detector) Handler(name string, fn gomainevents.EventHandler) gomainevents.EventHandler {
+	return func(event gomainevents.Event) error {
+		id := d.key(event)
+		if "" == id {
+			return fn(event)
+		}
+
+		key := name + "/" + event.Name() + "/" + id
+		if "" != d.namespace {
+			key = d.namespace + "/" + key
+		}
+
+		status, err := d.store.Claim(key, d.window)
+		if err != nil {
+			d.reportError(fmt.Errorf("Unable to claim %s: %s", key, err))
+			return fn(event)
+		}
+
+		d.count(event.Name(), Claimed != status)
+		if Claimed != status {
+			d.report(Duplicate{
+				Key:       key,
+				EventName: event.Name(),
+				Handler:   name,
+				Previous:  status,
+			})
+		}
+
+		err = fn(event)
+		if nil == err && Completed != status {
+			if completeErr := d.store.Complete(key, d.window); completeErr != nil {
+				d.reportError(fmt.Errorf("Unable to complete %s: %s", key, completeErr))
+			}
+		}
+
+		return err
+	}
+}
+
+// Stats returns what the Detector has seen so far.
+func (d *Detector) Stats() DetectorStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := d.stats
+	stats.ByEvent = map[string]int64{}
+	for name, count := range d.stats.ByEvent {
+		stats.ByEvent[name] = count
+	}
+
+	return stats
+}
+
+func (d *Detector) count(name string, duplicate bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stats.Checked++
+	if duplicate {
+		d.stats.Duplicates++
+		d.stats.ByEvent[name]++
+	}
+}
+
+func (d *Detector) report(duplicate Duplicate) {
+	if nil != d.metrics {
+		previous := "previous:in_progress"
+		if Completed == duplicate.Previous {
+			previous = "previous:completed"
+		}
+
+		d.metrics.Incr("events.duplicates", "event:"+duplicate.EventName, "handler:"+duplicate.Handler, previous)
+	}
+
+	if nil != d.onDuplicate {
+		d.onDuplicate(duplicate)
+	}
+}
+
+func (d *Detector) reportError(err error) {
+	if nil != d.onError {
+		d.onError(err)
+	}
+}
@@ -0,0 +1,137 @@
+package idempotency
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+)
+
+type unavailableStore struct{}
+
+func (unavailableStore) Claim(string, time.Duration) (Status, error) {
+	return Claimed, errors.New("Unavailable")
+}
+
+func (unavailableStore) Complete(string, time.Duration) error {
+	return errors.New("Unavailable")
+}
+
+func (unavailableStore) Release(string) error {
+	return errors.New("Unavailable")
+}
+
+type recordingCounter struct {
+	incrs []string
+}
+
+func (c *recordingCounter) Incr(name string, tags ...string) {
+	c.incrs = append(c.incrs, name+" "+strings.Join(tags, ","))
+}
+
+func TestNewDetector(t *testing.T) {
+	_, err := NewDetector(nil)
+	assert.EqualError(t, err, "Configuration is required")
+
+	_, err = NewDetector(&DetectorConfig{})
+	assert.EqualError(t, err, "Store is required")
+
+	detector, err := NewDetector(&DetectorConfig{Store: NewMemoryStore()})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, detector.window)
+}
+
+func TestDetectorReportsDuplicates(t *testing.T) {
+	var duplicates []Duplicate
+	counter := &recordingCounter{}
+
+	detector, _ := NewDetector(&DetectorConfig{
+		Store:     NewMemoryStore(),
+		Namespace: "billing",
+		Metrics:   counter,
+		OnDuplicate: func(duplicate Duplicate) {
+			duplicates = append(duplicates, duplicate)
+		},
+	})
+
+	calls := 0
+	fail := true
+	handler := detector.Handler("recordPayment", func(gomainevents.Event) error {
+		calls++
+		if fail {
+			return errors.New("Failed")
+		}
+
+		return nil
+	})
+
+	event := testEvent{name: "PaymentReceived", id: "1"}
+	assert.EqualError(t, handler(event), "Failed")
+
+	fail = false
+	assert.NoError(t, handler(event))
+	assert.NoError(t, handler(event))
+	assert.NoError(t, handler(testEvent{name: "PaymentReceived", id: "2"}))
+
+	// Events without a key aren't checked
+	assert.NoError(t, handler(testEvent{name: "PaymentReceived"}))
+
+	// Duplicates are always handled
+	assert.Equal(t, 5, calls)
+
+	key := "billing/recordPayment/PaymentReceived/1"
+	assert.Equal(t, []Duplicate{
+		{Key: key, EventName: "PaymentReceived", Handler: "recordPayment", Previous: InProgress},
+		{Key: key, EventName: "PaymentReceived", Handler: "recordPayment", Previous: Completed},
+	}, duplicates)
+
+	assert.Equal(t, []string{
+		"events.duplicates event:PaymentReceived,handler:recordPayment,previous:in_progress",
+		"events.duplicates event:PaymentReceived,handler:recordPayment,previous:completed",
+	}, counter.incrs)
+
+	assert.Equal(t, DetectorStats{
+		Checked:    4,
+		Duplicates: 2,
+		ByEvent:    map[string]int64{"PaymentReceived": 2},
+	}, detector.Stats())
+}
+
+func TestDetectorForgetsDeliveriesAfterWindow(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	detector, _ := NewDetector(&DetectorConfig{Store: store, Window: time.Minute})
+	handler := detector.Handler("recordPayment", func(gomainevents.Event) error { return nil })
+
+	event := testEvent{name: "PaymentReceived", id: "1"}
+	assert.NoError(t, handler(event))
+
+	now = now.Add(2 * time.Minute)
+	assert.NoError(t, handler(event))
+
+	assert.Equal(t, int64(0), detector.Stats().Duplicates)
+}
+
+func TestDetectorStoreErrorsDontFailEvents(t *testing.T) {
+	var errs []error
+	detector, _ := NewDetector(&DetectorConfig{
+		Store:   unavailableStore{},
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+
+	handled := false
+	handler := detector.Handler("recordPayment", func(gomainevents.Event) error {
+		handled = true
+		return nil
+	})
+
+	assert.NoError(t, handler(testEvent{name: "PaymentReceived", id: "1"}))
+	assert.True(t, handled)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, int64(0), detector.Stats().Checked)
+}
@@ -0,0 +1,181 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const defaultRegion = "us-east-1"
+
+// Attributes of the idempotency table's items. The table's partition key
+// must be a string named "idempotencyKey". Turn on DynamoDB's TTL for
+// "expiresAt" to have expired keys deleted.
+const (
+	attributeKey       = "idempotencyKey"
+	attributeStatus    = "status"
+	attributeExpiresAt = "expiresAt"
+)
+
+const (
+	statusInProgress = "in_progress"
+	statusCompleted  = "completed"
+)
+
+// DynamoDBAPI is the subset of the DynamoDB client used by this package. It
+// is satisfied by *dynamodb.Client from aws-sdk-go-v2.
+type DynamoDBAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// DynamoDBStore keeps keys in a DynamoDB table, one item per key. Keys are
+// claimed with a conditional put, which fails while an unexpired item
+// exists. DynamoDB deletes expired items lazily, up to a couple of days
+// late, so expiry is checked too.
+type DynamoDBStore struct {
+	client DynamoDBAPI
+	table  string
+
+	// Hook for tests
+	now func() time.Time
+}
+
+type DynamoDBStoreConfig struct {
+	// Provide your own DynamoDB client. Default will use the
+	// default AWS config + shared credentials.
+	DynamoDBClient DynamoDBAPI
+
+	// Region used by the default client. Defaults to us-east-1. Ignored
+	// when DynamoDBClient is provided.
+	Region string
+
+	// Endpoint overrides the DynamoDB endpoint used by the default
+	// client. Ignored when DynamoDBClient is provided.
+	Endpoint string
+
+	// Table to keep keys in. Its partition key must be a string named
+	// "idempotencyKey". Required
+	Table string
+}
+
+func NewDynamoDBStore(config *DynamoDBStoreConfig) (*DynamoDBStore, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.Table {
+		return nil, errors.New("Table is required")
+	}
+
+	// Default to a new client using shared credentials
+	client := config.DynamoDBClient
+	if nil == client {
+		region := config.Region
+		if "" == region {
+			region = defaultRegion
+		}
+
+		awsConfig, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(region))
+		if err != nil {
+			return nil, err
+		}
+
+		client = dynamodb.NewFromConfig(awsConfig, func(o *dynamodb.Options) {
+			if "" != config.Endpoint {
+				o.BaseEndpoint = aws.String(config.Endpoint)
+			}
+		})
+	}
+
+	return &DynamoDBStore{
+		client: client,
+		table:  config.Table,
+		now:    time.Now,
+	}, nil
+}
+
+func (s *DynamoDBStore) Claim(key string, lease time.Duration) (Status, error) {
+	now := s.now()
+
+	_, err := s.client.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName:           aws.String(s.table),
+		Item:                s.item(key, statusInProgress, now.Add(lease)),
+		ConditionExpression: aws.String("attribute_not_exists(#key) OR #expiresAt <= :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":       attributeKey,
+			"#expiresAt": attributeExpiresAt,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": epochSeconds(now),
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		if status, ok := conditionFailed.Item[attributeStatus].(*types.AttributeValueMemberS); ok && statusCompleted == status.Value {
+			return Completed, nil
+		}
+
+		return InProgress, nil
+	}
+
+	if err != nil {
+		return InProgress, err
+	}
+
+	return Claimed, nil
+}
+
+func (s *DynamoDBStore) Complete(key string, ttl time.Duration) error {
+	_, err := s.client.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      s.item(key, statusCompleted, s.now().Add(ttl)),
+	})
+
+	return err
+}
+
+func (s *DynamoDBStore) Release(key string) error {
+	_, err := s.client.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]types.AttributeValue{
+			attributeKey: &types.AttributeValueMemberS{Value: key},
+		},
+		ConditionExpression: aws.String("#status = :inProgress"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": attributeStatus,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":inProgress": &types.AttributeValueMemberS{Value: statusInProgress},
+		},
+	})
+
+	// It was completed, or already gone.
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *DynamoDBStore) item(key string, status string, expiresAt time.Time) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		attributeKey:       &types.AttributeValueMemberS{Value: key},
+		attributeStatus:    &types.AttributeValueMemberS{Value: status},
+		attributeExpiresAt: epochSeconds(expiresAt),
+	}
+}
+
+// epochSeconds is the format DynamoDB's TTL expects.
+func epochSeconds(t time.Time) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(t.Unix(), 10)}
+}
@@ -0,0 +1,111 @@
+package idempotency
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockDynamoDB evaluates the conditions DynamoDBStore uses.
+type mockDynamoDB struct {
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+}
+
+func (m *mockDynamoDB) PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := in.Item[attributeKey].(*types.AttributeValueMemberS).Value
+	existing, ok := m.items[key]
+
+	if nil != in.ConditionExpression && ok {
+		now := in.ExpressionAttributeValues[":now"].(*types.AttributeValueMemberN).Value
+		if epoch(existing[attributeExpiresAt]) > epoch(&types.AttributeValueMemberN{Value: now}) {
+			return nil, &types.ConditionalCheckFailedException{Item: existing}
+		}
+	}
+
+	m.items[key] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m *mockDynamoDB) DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := in.Key[attributeKey].(*types.AttributeValueMemberS).Value
+	existing, ok := m.items[key]
+	if !ok || statusInProgress != existing[attributeStatus].(*types.AttributeValueMemberS).Value {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+
+	delete(m.items, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func epoch(value types.AttributeValue) int64 {
+	n, _ := strconv.ParseInt(value.(*types.AttributeValueMemberN).Value, 10, 64)
+	return n
+}
+
+func TestNewDynamoDBStore(t *testing.T) {
+	store, err := NewDynamoDBStore(&DynamoDBStoreConfig{DynamoDBClient: &mockDynamoDB{}, Table: "idempotency"})
+	assert.NotNil(t, store)
+	assert.Nil(t, err)
+
+	store, err = NewDynamoDBStore(&DynamoDBStoreConfig{DynamoDBClient: &mockDynamoDB{}})
+	assert.Nil(t, store)
+	assert.NotNil(t, err)
+
+	store, err = NewDynamoDBStore(nil)
+	assert.Nil(t, store)
+	assert.NotNil(t, err)
+}
+
+func TestDynamoDBStore(t *testing.T) {
+	client := &mockDynamoDB{items: make(map[string]map[string]types.AttributeValue)}
+
+	store, err := NewDynamoDBStore(&DynamoDBStoreConfig{DynamoDBClient: client, Table: "idempotency"})
+	require.Nil(t, err)
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	status, err := store.Claim("key", time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, Claimed, status)
+
+	status, err = store.Claim("key", time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, InProgress, status)
+
+	// Released keys can be claimed again.
+	require.Nil(t, store.Release("key"))
+	status, err = store.Claim("key", time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, Claimed, status)
+
+	require.Nil(t, store.Complete("key", time.Hour))
+	assert.Equal(t, strconv.FormatInt(now.Add(time.Hour).Unix(), 10), client.items["key"][attributeExpiresAt].(*types.AttributeValueMemberN).Value)
+
+	// Completed keys aren't released.
+	require.Nil(t, store.Release("key"))
+	status, err = store.Claim("key", time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, Completed, status)
+
+	// Expired keys are claimed even if DynamoDB hasn't deleted them yet.
+	now = now.Add(2 * time.Hour)
+	status, err = store.Claim("key", time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, Claimed, status)
+	assert.Equal(t, statusInProgress, client.items["key"][attributeStatus].(*types.AttributeValueMemberS).Value)
+}
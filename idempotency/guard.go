@@ -0,0 +1,146 @@
+package idempotency
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/audit"
+)
+
+const (
+	defaultLease = 5 * time.Minute
+	defaultTTL   = 24 * time.Hour
+)
+
+// Guard makes sure each event is handled successfully at most once, even
+// when it is delivered more than once or to several consumer instances.
+// Wrap handlers with it:
+//
+//	listener.RegisterHandler("PaymentReceived", guard.Handler(handlePayment))
+//
+// Before an event is handled its key is claimed in the Store. Events whose
+// key has been completed are skipped, and ones another consumer is still
+// handling fail with an *InProgressError so that they are retried later.
+// A handler that succeeds completes the key; one that fails releases it so
+// that the event can be retried.
+type Guard struct {
+	store     Store
+	key       func(gomainevents.Event) string
+	namespace string
+	lease     time.Duration
+	ttl       time.Duration
+	onError   func(error)
+}
+
+type Config struct {
+	// Store keys are claimed in. Required
+	Store Store
+
+	// Returns the key identifying an event, or "" to handle it without
+	// protection. Defaults to the message ID or sequence number the
+	// provider gave it, see audit.EventID, which catches redeliveries but
+	// not the same event published twice. Use an ID from the event's data
+	// for that.
+	Key func(gomainevents.Event) string
+
+	// Prefixed to keys, so that several consumers can share a store
+	// without skipping each other's events. Usually the consumer's name.
+	Namespace string
+
+	// How long a claim lasts if the consumer dies before completing or
+	// releasing it. Should be longer than handling an event takes.
+	// Defaults to 5 minutes.
+	Lease time.Duration
+
+	// How long completed keys are remembered. Should be longer than
+	// duplicates can arrive apart. Defaults to 24 hours.
+	TTL time.Duration
+
+	// Called with errors completing or releasing keys, which don't fail
+	// the event.
+	OnError func(error)
+}
+
+func NewGuard(config *Config) (*Guard, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Store {
+		return nil, errors.New("Store is required")
+	}
+
+	key := config.Key
+	if nil == key {
+		key = audit.EventID
+	}
+
+	lease := defaultLease
+	if config.Lease > 0 {
+		lease = config.Lease
+	}
+
+	ttl := defaultTTL
+	if config.TTL > 0 {
+		ttl = config.TTL
+	}
+
+	return &Guard{
+		store:     config.Store,
+		key:       key,
+		namespace: config.Namespace,
+		lease:     lease,
+		ttl:       ttl,
+		onError:   config.OnError,
+	}, nil
+}
+
+// Handler wraps an EventHandler so that it is called at most once
+// successfully per event.
+func (g *Guard) Handler(fn gomainevents.EventHandler) gomainevents.EventHandler {
+	return func(event gomainevents.Event) error {
+		id := g.key(event)
+		if "" == id {
+			return fn(event)
+		}
+
+		key := event.Name() + "/" + id
+		if "" != g.namespace {
+			key = g.namespace + "/" + key
+		}
+
+		status, err := g.store.Claim(key, g.lease)
+		if err != nil {
+			return fmt.Errorf("Unable to claim %s: %s", key, err)
+		}
+
+		switch status {
+		case Completed:
+			return nil
+		case InProgress:
+			return &InProgressError{EventName: event.Name(), Key: key}
+		}
+
+		if err := fn(event); err != nil {
+			if releaseErr := g.store.Release(key); releaseErr != nil {
+				g.reportError(fmt.Errorf("Unable to release %s: %s", key, releaseErr))
+			}
+
+			return err
+		}
+
+		if err := g.store.Complete(key, g.ttl); err != nil {
+			g.reportError(fmt.Errorf("Unable to complete %s: %s", key, err))
+		}
+
+		return nil
+	}
+}
+
+func (g *Guard) reportError(err error) {
+	if nil != g.onError {
+		g.onError(err)
+	}
+}
@@ -0,0 +1,146 @@
+package idempotency
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+	id   string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return nil
+}
+
+func (e testEvent) MessageID() string {
+	return e.id
+}
+
+func TestNewGuard(t *testing.T) {
+	guard, err := NewGuard(&Config{Store: NewMemoryStore()})
+	assert.NotNil(t, guard)
+	assert.Nil(t, err)
+
+	guard, err = NewGuard(&Config{})
+	assert.Nil(t, guard)
+	assert.NotNil(t, err)
+
+	guard, err = NewGuard(nil)
+	assert.Nil(t, guard)
+	assert.NotNil(t, err)
+}
+
+func TestGuardSkipsCompletedEvents(t *testing.T) {
+	guard, err := NewGuard(&Config{Store: NewMemoryStore()})
+	require.Nil(t, err)
+
+	calls := 0
+	handler := guard.Handler(func(event gomainevents.Event) error {
+		calls++
+		return nil
+	})
+
+	event := testEvent{name: "ThingHappened", id: "message-1"}
+	assert.Nil(t, handler(event))
+	assert.Nil(t, handler(event))
+	assert.Equal(t, 1, calls)
+
+	assert.Nil(t, handler(testEvent{name: "ThingHappened", id: "message-2"}))
+	assert.Equal(t, 2, calls)
+}
+
+func TestGuardRetriesFailedEvents(t *testing.T) {
+	guard, err := NewGuard(&Config{Store: NewMemoryStore()})
+	require.Nil(t, err)
+
+	calls := 0
+	handler := guard.Handler(func(event gomainevents.Event) error {
+		calls++
+		if calls == 1 {
+			return errors.New("Failed")
+		}
+
+		return nil
+	})
+
+	event := testEvent{name: "ThingHappened", id: "message-1"}
+	assert.NotNil(t, handler(event))
+	assert.Nil(t, handler(event))
+	assert.Nil(t, handler(event))
+	assert.Equal(t, 2, calls)
+}
+
+func TestGuardRejectsEventsInProgress(t *testing.T) {
+	store := NewMemoryStore()
+	guard, err := NewGuard(&Config{Store: store, Namespace: "billing"})
+	require.Nil(t, err)
+
+	status, err := store.Claim("billing/ThingHappened/message-1", time.Minute)
+	require.Nil(t, err)
+	require.Equal(t, Claimed, status)
+
+	handler := guard.Handler(func(event gomainevents.Event) error {
+		t.Error("Handler should not be called")
+		return nil
+	})
+
+	err = handler(testEvent{name: "ThingHappened", id: "message-1"})
+	inProgress, ok := err.(*InProgressError)
+	require.True(t, ok)
+	assert.Equal(t, "ThingHappened", inProgress.EventName)
+	assert.Equal(t, "billing/ThingHappened/message-1", inProgress.Key)
+}
+
+func TestGuardHandlesEventsWithoutKeys(t *testing.T) {
+	guard, err := NewGuard(&Config{Store: NewMemoryStore()})
+	require.Nil(t, err)
+
+	calls := 0
+	handler := guard.Handler(func(event gomainevents.Event) error {
+		calls++
+		return nil
+	})
+
+	event := testEvent{name: "ThingHappened"}
+	assert.Nil(t, handler(event))
+	assert.Nil(t, handler(event))
+	assert.Equal(t, 2, calls)
+}
+
+func TestMemoryStoreExpiresKeys(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	status, _ := store.Claim("key", time.Minute)
+	assert.Equal(t, Claimed, status)
+
+	status, _ = store.Claim("key", time.Minute)
+	assert.Equal(t, InProgress, status)
+
+	// The lease ran out, e.g. because the consumer died.
+	now = now.Add(2 * time.Minute)
+	status, _ = store.Claim("key", time.Minute)
+	assert.Equal(t, Claimed, status)
+
+	require.Nil(t, store.Complete("key", time.Hour))
+	require.Nil(t, store.Release("key"))
+
+	status, _ = store.Claim("key", time.Minute)
+	assert.Equal(t, Completed, status)
+
+	now = now.Add(2 * time.Hour)
+	status, _ = store.Claim("key", time.Minute)
+	assert.Equal(t, Claimed, status)
+}
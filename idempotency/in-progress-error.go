@@ -0,0 +1,18 @@
+package idempotency
+
+import (
+	"fmt"
+)
+
+// InProgressError is returned for an event that another consumer is
+// handling, so that the Listener requeues it. If the other consumer
+// succeeds, the event is skipped when it is redelivered; if it fails, the
+// event is handled then.
+type InProgressError struct {
+	EventName string
+	Key       string
+}
+
+func (e *InProgressError) Error() string {
+	return fmt.Sprintf("Event is being handled by another consumer: %s (%s)", e.EventName, e.Key)
+}
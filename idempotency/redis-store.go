@@ -0,0 +1,120 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// releaseScript deletes a key only if it's still in progress, so that a
+// completed key isn't released.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisStore keeps keys in Redis. Keys are claimed with SET NX and expire
+// with their lease or TTL.
+type RedisStore struct {
+	client  redis.UniversalClient
+	prefix  string
+	timeout time.Duration
+}
+
+type RedisStoreConfig struct {
+	// Address of the Redis server, e.g. localhost:6379. Either Addr or
+	// Client is required.
+	Addr string
+
+	// Provide your own client, e.g. a cluster client or one with TLS.
+	Client redis.UniversalClient
+
+	// Prefixed to the Redis keys. Defaults to "idempotency:".
+	Prefix string
+
+	// How long to wait for Redis. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+func NewRedisStore(config *RedisStoreConfig) (*RedisStore, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	client := config.Client
+	if nil == client {
+		if "" == config.Addr {
+			return nil, errors.New("Addr or Client is required")
+		}
+
+		client = redis.NewClient(&redis.Options{Addr: config.Addr})
+	}
+
+	prefix := config.Prefix
+	if "" == prefix {
+		prefix = "idempotency:"
+	}
+
+	timeout := defaultTimeout
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	return &RedisStore{
+		client:  client,
+		prefix:  prefix,
+		timeout: timeout,
+	}, nil
+}
+
+func (s *RedisStore) Claim(key string, lease time.Duration) (Status, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	for {
+		claimed, err := s.client.SetNX(ctx, s.prefix+key, statusInProgress, lease).Result()
+		if err != nil {
+			return InProgress, err
+		}
+
+		if claimed {
+			return Claimed, nil
+		}
+
+		status, err := s.client.Get(ctx, s.prefix+key).Result()
+		if err == redis.Nil {
+			// It expired in between, so try again.
+			continue
+		}
+
+		if err != nil {
+			return InProgress, err
+		}
+
+		if statusCompleted == status {
+			return Completed, nil
+		}
+
+		return InProgress, nil
+	}
+}
+
+func (s *RedisStore) Complete(key string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	return s.client.Set(ctx, s.prefix+key, statusCompleted, ttl).Err()
+}
+
+func (s *RedisStore) Release(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	return releaseScript.Run(ctx, s.client, []string{s.prefix + key}, statusInProgress).Err()
+}
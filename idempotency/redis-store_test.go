@@ -0,0 +1,60 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRedisStore(t *testing.T) {
+	store, err := NewRedisStore(&RedisStoreConfig{Addr: "localhost:6379"})
+	assert.NotNil(t, store)
+	assert.Nil(t, err)
+
+	store, err = NewRedisStore(&RedisStoreConfig{})
+	assert.Nil(t, store)
+	assert.NotNil(t, err)
+
+	store, err = NewRedisStore(nil)
+	assert.Nil(t, store)
+	assert.NotNil(t, err)
+}
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	store, err := NewRedisStore(&RedisStoreConfig{Addr: server.Addr()})
+	require.Nil(t, err)
+
+	status, err := store.Claim("key", time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, Claimed, status)
+	assert.Equal(t, time.Minute, server.TTL("idempotency:key"))
+
+	status, err = store.Claim("key", time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, InProgress, status)
+
+	// Released keys can be claimed again.
+	require.Nil(t, store.Release("key"))
+	status, err = store.Claim("key", time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, Claimed, status)
+
+	require.Nil(t, store.Complete("key", time.Hour))
+	assert.Equal(t, time.Hour, server.TTL("idempotency:key"))
+
+	// Completed keys aren't released.
+	require.Nil(t, store.Release("key"))
+	status, err = store.Claim("key", time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, Completed, status)
+
+	server.FastForward(2 * time.Hour)
+	status, err = store.Claim("key", time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, Claimed, status)
+}
@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"sync"
+	"time"
+)
+
+// Status is where a key stands in a Store.
+type Status int
+
+const (
+	// Claimed means the key was free and is now claimed by the caller.
+	Claimed Status = iota
+
+	// InProgress means another consumer has claimed the key and its lease
+	// hasn't run out.
+	InProgress
+
+	// Completed means the key has already been processed.
+	Completed
+)
+
+func (s Status) String() string {
+	switch s {
+	case Claimed:
+		return "claimed"
+	case InProgress:
+		return "in progress"
+	case Completed:
+		return "completed"
+	}
+
+	return "unknown"
+}
+
+// Store records which keys are being or have been processed, shared by
+// every consumer instance. Claims have to be atomic, so that only one
+// instance can claim a key.
+type Store interface {
+	// Claim marks a key as being processed for the length of the lease,
+	// unless it already is or has been. It returns Claimed if the key is
+	// now the caller's, or else the key's status.
+	Claim(key string, lease time.Duration) (Status, error)
+
+	// Complete marks a claimed key as processed, remembering it for ttl.
+	Complete(key string, ttl time.Duration) error
+
+	// Release gives up a claim on a key, so that it can be claimed again.
+	// Completed keys are left alone.
+	Release(key string) error
+}
+
+// MemoryStore keeps keys in memory. It only protects against duplicates
+// within a single process, so it is mostly useful for tests.
+type MemoryStore struct {
+	mu   sync.Mutex
+	keys map[string]memoryEntry
+
+	// Hook for tests
+	now func() time.Time
+}
+
+type memoryEntry struct {
+	status    Status
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		keys: map[string]memoryEntry{},
+		now:  time.Now,
+	}
+}
+
+func (s *MemoryStore) Claim(key string, lease time.Duration) (Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if entry, ok := s.keys[key]; ok && now.Before(entry.expiresAt) {
+		return entry.status, nil
+	}
+
+	s.keys[key] = memoryEntry{status: InProgress, expiresAt: now.Add(lease)}
+	return Claimed, nil
+}
+
+func (s *MemoryStore) Complete(key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key] = memoryEntry{status: Completed, expiresAt: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.keys[key]; ok && InProgress == entry.status {
+		delete(s.keys, key)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package integrationtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Collector records the events its handlers handle, so that tests can wait
+// for them to make it through a transport.
+type Collector struct {
+	mu      sync.Mutex
+	changed chan struct{}
+	events  []gomainevents.Event
+}
+
+func NewCollector() *Collector {
+	return &Collector{changed: make(chan struct{}, 1)}
+}
+
+// Handler records the events fn handles without an error. A nil fn handles
+// every event.
+func (c *Collector) Handler(fn gomainevents.EventHandler) gomainevents.EventHandler {
+	return func(event gomainevents.Event) error {
+		if nil != fn {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+
+		c.mu.Lock()
+		c.events = append(c.events, event)
+		c.mu.Unlock()
+
+		select {
+		case c.changed <- struct{}{}:
+		default:
+		}
+
+		return nil
+	}
+}
+
+// Wait returns the first count events, failing the test if they don't all
+// arrive within the timeout.
+func (c *Collector) Wait(t testing.TB, count int, timeout time.Duration) []gomainevents.Event {
+	t.Helper()
+
+	deadline := time.After(timeout)
+	for {
+		c.mu.Lock()
+		if len(c.events) >= count {
+			events := append([]gomainevents.Event{}, c.events[:count]...)
+			c.mu.Unlock()
+			return events
+		}
+		received := len(c.events)
+		c.mu.Unlock()
+
+		select {
+		case <-c.changed:
+		case <-deadline:
+			t.Fatalf("Received %d of %d events within %s", received, count, timeout)
+		}
+	}
+}
+
+// Listen runs the listener until the test is done.
+func Listen(t testing.TB, listener *gomainevents.Listener) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		if err := listener.ListenContext(ctx, 5*time.Second); err != nil {
+			t.Logf("Listener stopped: %s", err)
+		}
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
@@ -0,0 +1,261 @@
+// Package integrationtest runs events through real transports rather than
+// hand-crafted fixtures. It targets LocalStack, which can be started with
+//
+//	docker run --rm -p 4566:4566 localstack/localstack
+//
+// and the tests that use it are built with the integration tag:
+//
+//	go test -tags integration ./integrationtest/
+//
+// Set GOMAINEVENTS_TEST_ENDPOINT to use another endpoint. Tests are skipped
+// when nothing is listening on it.
+package integrationtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	awssns "github.com/aws/aws-sdk-go-v2/service/sns"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3v1 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+const (
+	// EndpointVariable is the environment variable holding the endpoint to
+	// test against.
+	EndpointVariable = "GOMAINEVENTS_TEST_ENDPOINT"
+
+	defaultEndpoint = "http://localhost:4566"
+	defaultRegion   = "us-east-1"
+)
+
+// Environment holds clients for the endpoint being tested against, and
+// creates the topics, queues and buckets tests need, deleting them when the
+// test is done.
+type Environment struct {
+	Endpoint string
+	Region   string
+
+	// Clients for the endpoint, of the kinds the providers and publishers
+	// take.
+	SQSClient sqsiface.SQSAPI
+	SNSClient *awssns.Client
+	S3Client  s3iface.S3API
+
+	storage *s3.Client
+}
+
+// New connects to the endpoint, skipping the test if it can't be reached.
+// LocalStack accepts any credentials, so dummy ones are used when none are
+// set.
+func New(t testing.TB) *Environment {
+	t.Helper()
+
+	endpoint := os.Getenv(EndpointVariable)
+	if "" == endpoint {
+		endpoint = defaultEndpoint
+	}
+
+	address, err := url.Parse(endpoint)
+	if err != nil {
+		t.Fatalf("Invalid %s: %s", EndpointVariable, err)
+	}
+
+	conn, err := net.DialTimeout("tcp", address.Host, time.Second)
+	if err != nil {
+		t.Skipf("Nothing is listening on %s: %s", endpoint, err)
+	}
+	conn.Close()
+
+	if "" == os.Getenv("AWS_ACCESS_KEY_ID") {
+		t.Setenv("AWS_ACCESS_KEY_ID", "test")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	}
+
+	sess, err := session.NewSession(&awsv1.Config{
+		Region:           awsv1.String(defaultRegion),
+		Endpoint:         awsv1.String(endpoint),
+		S3ForcePathStyle: awsv1.Bool(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(defaultRegion))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Environment{
+		Endpoint:  endpoint,
+		Region:    defaultRegion,
+		SQSClient: awssqs.New(sess),
+		SNSClient: awssns.NewFromConfig(cfg, func(o *awssns.Options) {
+			o.BaseEndpoint = aws.String(endpoint)
+		}),
+		S3Client: awss3v1.New(sess),
+		storage: s3.NewFromConfig(cfg, func(o *s3.Options) {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}),
+	}
+}
+
+// UniqueName returns the prefix followed by a random suffix, so that tests
+// running at the same time don't share resources. Names ending in .fifo
+// keep the suffix.
+func UniqueName(prefix string) string {
+	suffix := ""
+	if strings.HasSuffix(prefix, ".fifo") {
+		prefix, suffix = strings.TrimSuffix(prefix, ".fifo"), ".fifo"
+	}
+
+	id := make([]byte, 4)
+	rand.Read(id)
+
+	return prefix + "-" + hex.EncodeToString(id) + suffix
+}
+
+// Queue is a queue created for a test.
+type Queue struct {
+	URL string
+	ARN string
+}
+
+// CreateQueue creates a queue named after the prefix. Names ending in .fifo
+// create FIFO queues with content based deduplication.
+func (e *Environment) CreateQueue(t testing.TB, prefix string) *Queue {
+	t.Helper()
+
+	name := UniqueName(prefix)
+	attributes := map[string]*string{}
+	if strings.HasSuffix(name, ".fifo") {
+		attributes[awssqs.QueueAttributeNameFifoQueue] = awsv1.String("true")
+		attributes[awssqs.QueueAttributeNameContentBasedDeduplication] = awsv1.String("true")
+	}
+
+	created, err := e.SQSClient.CreateQueue(&awssqs.CreateQueueInput{
+		QueueName:  awsv1.String(name),
+		Attributes: attributes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := e.SQSClient.DeleteQueue(&awssqs.DeleteQueueInput{QueueUrl: created.QueueUrl}); err != nil {
+			t.Logf("Unable to delete queue %s: %s", name, err)
+		}
+	})
+
+	resp, err := e.SQSClient.GetQueueAttributes(&awssqs.GetQueueAttributesInput{
+		QueueUrl:       created.QueueUrl,
+		AttributeNames: awsv1.StringSlice([]string{awssqs.QueueAttributeNameQueueArn}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Queue{
+		URL: awsv1.StringValue(created.QueueUrl),
+		ARN: awsv1.StringValue(resp.Attributes[awssqs.QueueAttributeNameQueueArn]),
+	}
+}
+
+// CreateTopic creates a topic named after the prefix, returning its ARN.
+// Names ending in .fifo create FIFO topics with content based deduplication.
+func (e *Environment) CreateTopic(t testing.TB, prefix string) string {
+	t.Helper()
+
+	name := UniqueName(prefix)
+	attributes := map[string]string{}
+	if strings.HasSuffix(name, ".fifo") {
+		attributes["FifoTopic"] = "true"
+		attributes["ContentBasedDeduplication"] = "true"
+	}
+
+	created, err := e.SNSClient.CreateTopic(context.Background(), &awssns.CreateTopicInput{
+		Name:       aws.String(name),
+		Attributes: attributes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := e.SNSClient.DeleteTopic(context.Background(), &awssns.DeleteTopicInput{TopicArn: created.TopicArn}); err != nil {
+			t.Logf("Unable to delete topic %s: %s", name, err)
+		}
+	})
+
+	return aws.ToString(created.TopicArn)
+}
+
+// Subscribe delivers the messages published to the topic to the queue,
+// wrapped in the SNS envelope the SQS provider expects. LocalStack doesn't
+// enforce queue policies, so none is set.
+func (e *Environment) Subscribe(t testing.TB, topicARN string, queue *Queue) {
+	t.Helper()
+
+	subscribed, err := e.SNSClient.Subscribe(context.Background(), &awssns.SubscribeInput{
+		TopicArn: aws.String(topicARN),
+		Protocol: aws.String("sqs"),
+		Endpoint: aws.String(queue.ARN),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		e.SNSClient.Unsubscribe(context.Background(), &awssns.UnsubscribeInput{SubscriptionArn: subscribed.SubscriptionArn})
+	})
+}
+
+// CreateBucket creates a bucket named after the prefix, returning its name.
+// Its objects are deleted along with it.
+func (e *Environment) CreateBucket(t testing.TB, prefix string) string {
+	t.Helper()
+
+	name := UniqueName(prefix)
+	if _, err := e.storage.CreateBucket(context.Background(), &s3.CreateBucketInput{Bucket: aws.String(name)}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ctx := context.Background()
+
+		objects, err := e.storage.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: aws.String(name)})
+		if err == nil && len(objects.Contents) > 0 {
+			ids := []s3types.ObjectIdentifier{}
+			for _, object := range objects.Contents {
+				ids = append(ids, s3types.ObjectIdentifier{Key: object.Key})
+			}
+
+			e.storage.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(name),
+				Delete: &s3types.Delete{Objects: ids},
+			})
+		}
+
+		if _, err := e.storage.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String(name)}); err != nil {
+			t.Logf("Unable to delete bucket %s: %s", name, err)
+		}
+	})
+
+	return name
+}
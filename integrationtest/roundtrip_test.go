@@ -0,0 +1,202 @@
+//go:build integration
+
+package integrationtest
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/sns"
+	"github.com/researchsquare/gomainevents/sqs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eventName = "ResearchSquare\\App\\Domain\\Model\\ThingHappened"
+
+type testEvent struct {
+	data map[string]interface{}
+}
+
+func (e testEvent) Name() string {
+	return eventName
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return e.data
+}
+
+// subscribedQueue creates a topic with a queue subscribed to it.
+func subscribedQueue(t *testing.T, env *Environment) (string, *Queue) {
+	topicARN := env.CreateTopic(t, "gomainevents-test")
+	queue := env.CreateQueue(t, "gomainevents-test")
+	env.Subscribe(t, topicARN, queue)
+
+	return topicARN, queue
+}
+
+func newProvider(t *testing.T, env *Environment, queue *Queue) *sqs.Provider {
+	provider, err := sqs.NewProvider(&sqs.Config{
+		SQSClient: env.SQSClient,
+		S3Client:  env.S3Client,
+		QueueURL:  queue.URL,
+	})
+	require.Nil(t, err)
+
+	return provider
+}
+
+// receive listens to the queue until count events have been handled.
+func receive(t *testing.T, env *Environment, queue *Queue, count int) []gomainevents.Event {
+	collector := NewCollector()
+
+	listener := gomainevents.NewListener(newProvider(t, env, queue))
+	listener.RegisterHandler(eventName, collector.Handler(nil))
+	Listen(t, listener)
+
+	return collector.Wait(t, count, 30*time.Second)
+}
+
+func TestSNSToSQS(t *testing.T) {
+	env := New(t)
+	topicARN, queue := subscribedQueue(t, env)
+
+	publisher, err := sns.NewPublisher(&sns.Config{SNSClient: env.SNSClient, TopicARN: topicARN})
+	require.Nil(t, err)
+
+	data := map[string]interface{}{"id": "1234", "tags": []interface{}{"a", "b"}}
+	require.Nil(t, publisher.Publish(testEvent{data: data}))
+	require.Nil(t, publisher.PublishBatch([]gomainevents.Event{testEvent{data: data}, testEvent{data: data}}))
+
+	for _, event := range receive(t, env, queue, 3) {
+		assert.Equal(t, eventName, event.Name())
+		assert.Equal(t, data, event.Data())
+	}
+}
+
+func TestSNSToSQSWithOffloading(t *testing.T) {
+	env := New(t)
+	topicARN, queue := subscribedQueue(t, env)
+
+	publisher, err := sns.NewPublisher(&sns.Config{
+		SNSClient: env.SNSClient,
+		TopicARN:  topicARN,
+		Region:    env.Region,
+		Endpoint:  env.Endpoint,
+		S3Bucket:  env.CreateBucket(t, "gomainevents-test"),
+	})
+	require.Nil(t, err)
+
+	blob := strings.Repeat("x", 300*1024)
+	require.Nil(t, publisher.Publish(testEvent{data: map[string]interface{}{"blob": blob}}))
+
+	events := receive(t, env, queue, 1)
+	assert.Equal(t, blob, events[0].Data()["blob"])
+}
+
+func TestSNSToSQSWithChunking(t *testing.T) {
+	env := New(t)
+	topicARN, queue := subscribedQueue(t, env)
+
+	publisher, err := sns.NewPublisher(&sns.Config{
+		SNSClient: env.SNSClient,
+		TopicARN:  topicARN,
+		Oversize:  sns.ChunkOversize,
+	})
+	require.Nil(t, err)
+
+	blob := strings.Repeat("x", 600*1024)
+	require.Nil(t, publisher.Publish(testEvent{data: map[string]interface{}{"blob": blob}}))
+
+	// Every chunk has to reach the same instance
+	provider, err := sqs.NewProvider(&sqs.Config{
+		SQSClient:      env.SQSClient,
+		QueueURL:       queue.URL,
+		SingleConsumer: true,
+	})
+	require.Nil(t, err)
+
+	collector := NewCollector()
+	listener := gomainevents.NewListener(provider)
+	listener.RegisterHandler(eventName, collector.Handler(nil))
+	Listen(t, listener)
+
+	events := collector.Wait(t, 1, 30*time.Second)
+	assert.Equal(t, blob, events[0].Data()["blob"])
+}
+
+func TestSQSPublisherToProvider(t *testing.T) {
+	env := New(t)
+	queue := env.CreateQueue(t, "gomainevents-test")
+
+	publisher, err := sqs.NewPublisher(&sqs.PublisherConfig{SQSClient: env.SQSClient, QueueURL: queue.URL})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.Publish(testEvent{data: map[string]interface{}{"id": "1234"}}))
+
+	events := receive(t, env, queue, 1)
+	assert.Equal(t, "1234", events[0].Data()["id"])
+}
+
+func TestRequeuedEventsAreRedelivered(t *testing.T) {
+	env := New(t)
+	queue := env.CreateQueue(t, "gomainevents-test")
+
+	publisher, err := sqs.NewPublisher(&sqs.PublisherConfig{SQSClient: env.SQSClient, QueueURL: queue.URL})
+	require.Nil(t, err)
+	require.Nil(t, publisher.Publish(testEvent{data: map[string]interface{}{"id": "1234"}}))
+
+	var attempts int32
+	collector := NewCollector()
+
+	listener := gomainevents.NewListener(newProvider(t, env, queue))
+	listener.RegisterHandler(eventName, collector.Handler(func(gomainevents.Event) error {
+		if 1 == atomic.AddInt32(&attempts, 1) {
+			return errors.New("Try again")
+		}
+
+		return nil
+	}))
+	Listen(t, listener)
+
+	events := collector.Wait(t, 1, 30*time.Second)
+	assert.Equal(t, 1, events[0].(sqs.Event).RetryCount())
+	assert.Equal(t, "1234", events[0].Data()["id"])
+}
+
+func TestBroadcastDelivery(t *testing.T) {
+	env := New(t)
+	topicARN := env.CreateTopic(t, "gomainevents-test")
+
+	collectors := []*Collector{}
+	for i := 0; i < 2; i++ {
+		provider, err := sqs.NewProvider(&sqs.Config{
+			SQSClient:       env.SQSClient,
+			SNSClient:       env.SNSClient,
+			Delivery:        gomainevents.Broadcast,
+			TopicARN:        topicARN,
+			QueueNamePrefix: UniqueName("gomainevents-test"),
+		})
+		require.Nil(t, err)
+
+		collector := NewCollector()
+		listener := gomainevents.NewListener(provider)
+		listener.RegisterHandler(eventName, collector.Handler(nil))
+		Listen(t, listener)
+
+		collectors = append(collectors, collector)
+	}
+
+	publisher, err := sns.NewPublisher(&sns.Config{SNSClient: env.SNSClient, TopicARN: topicARN})
+	require.Nil(t, err)
+	require.Nil(t, publisher.Publish(testEvent{data: map[string]interface{}{"id": "1234"}}))
+
+	for _, collector := range collectors {
+		events := collector.Wait(t, 1, 30*time.Second)
+		assert.Equal(t, "1234", events[0].Data()["id"])
+	}
+}
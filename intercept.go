@@ -0,0 +1,91 @@
+package gomainevents
+
+// PublishFunc publishes an event. See Interceptor.
+type PublishFunc func(Event) error
+
+// Interceptor runs around the publishing of each event, e.g. to validate
+// it, stamp metadata on it, time it or encrypt it. It calls next to carry
+// on publishing, possibly with a different event, or returns an error
+// instead to stop the event being published.
+type Interceptor func(event Event, next PublishFunc) error
+
+// Interceptors is a chain of interceptors, configured once and applied to
+// each Publisher with Wrap:
+//
+//	interceptors := gomainevents.Interceptors{validate, stampMetadata}
+//	snsPublisher := interceptors.Wrap(snsPublisher)
+//	sqsPublisher := interceptors.Wrap(sqsPublisher)
+type Interceptors []Interceptor
+
+// Wrap returns a Publisher that passes events through the interceptors, in
+// order, on the way to the given one.
+func (i Interceptors) Wrap(publisher Publisher) *InterceptingPublisher {
+	return NewInterceptingPublisher(publisher, i...)
+}
+
+// InterceptingPublisher passes events through a chain of interceptors
+// before publishing them with another Publisher.
+type InterceptingPublisher struct {
+	publisher    Publisher
+	interceptors []Interceptor
+}
+
+func NewInterceptingPublisher(publisher Publisher, interceptors ...Interceptor) *InterceptingPublisher {
+	return &InterceptingPublisher{
+		publisher:    publisher,
+		interceptors: append([]Interceptor{}, interceptors...),
+	}
+}
+
+func (p *InterceptingPublisher) Publish(event Event) error {
+	return p.intercept(event, p.publisher.Publish)
+}
+
+// PublishBatch passes each event through the interceptors, then publishes
+// the ones they let through in a single batch if the wrapped publisher
+// supports them. In a batch, next only adds the event to the batch, so
+// interceptors don't see whether it was published. Events are published one
+// at a time if the wrapped publisher doesn't support batches.
+func (p *InterceptingPublisher) PublishBatch(events []Event) error {
+	batchPublisher, ok := p.publisher.(BatchPublisher)
+	if !ok {
+		for _, event := range events {
+			if err := p.Publish(event); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	batch := make([]Event, 0, len(events))
+	add := func(event Event) error {
+		batch = append(batch, event)
+		return nil
+	}
+
+	for _, event := range events {
+		if err := p.intercept(event, add); err != nil {
+			return err
+		}
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	return batchPublisher.PublishBatch(batch)
+}
+
+// intercept runs the interceptors on an event, ending with publish.
+func (p *InterceptingPublisher) intercept(event Event, publish PublishFunc) error {
+	next := publish
+	for i := len(p.interceptors) - 1; i >= 0; i-- {
+		interceptor, rest := p.interceptors[i], next
+		next = func(event Event) error {
+			return interceptor(event, rest)
+		}
+	}
+
+	return next(event)
+}
@@ -0,0 +1,123 @@
+package gomainevents
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type batchRecorder struct {
+	published []Event
+	batches   [][]Event
+}
+
+func (p *batchRecorder) Publish(event Event) error {
+	p.published = append(p.published, event)
+	return nil
+}
+
+func (p *batchRecorder) PublishBatch(events []Event) error {
+	p.batches = append(p.batches, events)
+	return nil
+}
+
+type publishRecorder struct {
+	published []Event
+}
+
+func (p *publishRecorder) Publish(event Event) error {
+	p.published = append(p.published, event)
+	return nil
+}
+
+func stamp(key string) Interceptor {
+	return func(event Event, next PublishFunc) error {
+		data := map[string]interface{}{key: true}
+		for k, v := range event.Data() {
+			data[k] = v
+		}
+
+		return next(testEvent{name: event.Name(), data: data})
+	}
+}
+
+func rejectNamed(name string) Interceptor {
+	return func(event Event, next PublishFunc) error {
+		if event.Name() == name {
+			return errors.New("Invalid event: " + name)
+		}
+
+		return next(event)
+	}
+}
+
+func TestInterceptingPublisherPublish(t *testing.T) {
+	var order []string
+	trace := func(name string) Interceptor {
+		return func(event Event, next PublishFunc) error {
+			order = append(order, name+" before")
+			err := next(event)
+			order = append(order, name+" after")
+			return err
+		}
+	}
+
+	publisher := &publishRecorder{}
+	interceptors := Interceptors{trace("outer"), stamp("stamped"), trace("inner"), rejectNamed("Bad")}
+	intercepting := interceptors.Wrap(publisher)
+
+	assert.NoError(t, intercepting.Publish(testEvent{name: "Created", data: map[string]interface{}{"id": 1}}))
+	assert.Equal(t, []Event{testEvent{name: "Created", data: map[string]interface{}{"id": 1, "stamped": true}}}, publisher.published)
+	assert.Equal(t, []string{"outer before", "inner before", "inner after", "outer after"}, order)
+
+	assert.EqualError(t, intercepting.Publish(testEvent{name: "Bad"}), "Invalid event: Bad")
+	assert.Len(t, publisher.published, 1)
+}
+
+func TestInterceptingPublisherWithoutInterceptors(t *testing.T) {
+	publisher := &publishRecorder{}
+	event := testEvent{name: "Created"}
+
+	assert.NoError(t, NewInterceptingPublisher(publisher).Publish(event))
+	assert.Equal(t, []Event{event}, publisher.published)
+}
+
+func TestInterceptingPublisherPublishBatch(t *testing.T) {
+	skip := func(event Event, next PublishFunc) error {
+		if event.Name() == "Skipped" {
+			return nil
+		}
+
+		return next(event)
+	}
+
+	publisher := &batchRecorder{}
+	intercepting := NewInterceptingPublisher(publisher, skip, stamp("stamped"))
+
+	events := []Event{testEvent{name: "Created"}, testEvent{name: "Skipped"}, testEvent{name: "Deleted"}}
+	assert.NoError(t, intercepting.PublishBatch(events))
+	assert.Equal(t, [][]Event{{
+		testEvent{name: "Created", data: map[string]interface{}{"stamped": true}},
+		testEvent{name: "Deleted", data: map[string]interface{}{"stamped": true}},
+	}}, publisher.batches)
+	assert.Empty(t, publisher.published)
+
+	// Nothing is published if an interceptor rejects any of the events
+	intercepting = NewInterceptingPublisher(publisher, rejectNamed("Bad"))
+	assert.Error(t, intercepting.PublishBatch([]Event{testEvent{name: "Created"}, testEvent{name: "Bad"}}))
+	assert.Len(t, publisher.batches, 1)
+
+	// Or if they're all skipped
+	intercepting = NewInterceptingPublisher(publisher, skip)
+	assert.NoError(t, intercepting.PublishBatch([]Event{testEvent{name: "Skipped"}}))
+	assert.Len(t, publisher.batches, 1)
+}
+
+func TestInterceptingPublisherPublishBatchOneAtATime(t *testing.T) {
+	publisher := &publishRecorder{}
+	intercepting := NewInterceptingPublisher(publisher, stamp("stamped"))
+
+	assert.NoError(t, intercepting.PublishBatch([]Event{testEvent{name: "Created"}, testEvent{name: "Deleted"}}))
+	assert.Len(t, publisher.published, 2)
+}
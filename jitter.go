@@ -0,0 +1,57 @@
+package gomainevents
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Jitter randomizes retry delays, so that events that failed at the same
+// time, e.g. during an outage, aren't all retried at the same time too.
+// Providers take it as a Jitter option and apply it to their backoff.
+type Jitter int
+
+const (
+	// NoJitter leaves delays as they are: 2, 4, 8... seconds.
+	NoJitter Jitter = iota
+
+	// FullJitter picks a delay between zero and the backoff. It spreads
+	// retries out the most.
+	FullJitter
+
+	// EqualJitter picks a delay between half the backoff and the backoff,
+	// so that retries still wait at least half as long.
+	EqualJitter
+)
+
+// Hook for tests. Returns a number in [0, n).
+var randomInt63n = rand.Int63n
+
+// Apply returns a delay randomized according to the jitter.
+func (j Jitter) Apply(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return delay
+	}
+
+	switch j {
+	case FullJitter:
+		return time.Duration(randomInt63n(int64(delay) + 1))
+	case EqualJitter:
+		half := delay / 2
+		return delay - half + time.Duration(randomInt63n(int64(half)+1))
+	}
+
+	return delay
+}
+
+func (j Jitter) String() string {
+	switch j {
+	case NoJitter:
+		return "none"
+	case FullJitter:
+		return "full"
+	case EqualJitter:
+		return "equal"
+	}
+
+	return "unknown"
+}
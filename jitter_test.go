@@ -0,0 +1,38 @@
+package gomainevents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitterApply(t *testing.T) {
+	defer func(original func(int64) int64) { randomInt63n = original }(randomInt63n)
+
+	// Always the largest value
+	randomInt63n = func(n int64) int64 { return n - 1 }
+	assert.Equal(t, 8*time.Second, NoJitter.Apply(8*time.Second))
+	assert.Equal(t, 8*time.Second, FullJitter.Apply(8*time.Second))
+	assert.Equal(t, 8*time.Second, EqualJitter.Apply(8*time.Second))
+
+	// Always the smallest value
+	randomInt63n = func(n int64) int64 { return 0 }
+	assert.Equal(t, 8*time.Second, NoJitter.Apply(8*time.Second))
+	assert.Equal(t, time.Duration(0), FullJitter.Apply(8*time.Second))
+	assert.Equal(t, 4*time.Second, EqualJitter.Apply(8*time.Second))
+
+	assert.Equal(t, time.Duration(0), FullJitter.Apply(0))
+}
+
+func TestJitterSpreadsDelays(t *testing.T) {
+	delays := map[time.Duration]bool{}
+	for i := 0; i < 100; i++ {
+		delay := EqualJitter.Apply(8 * time.Second)
+		assert.True(t, delay >= 4*time.Second && delay <= 8*time.Second, delay)
+
+		delays[delay] = true
+	}
+
+	assert.True(t, len(delays) > 1)
+}
@@ -0,0 +1,85 @@
+package jsonl
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Event implements the standard domain event interface for events read from
+// a JSONL file.
+type Event struct {
+	name string
+	data map[string]interface{}
+
+	// Line of the file the event was read from, starting from 1.
+	line int
+
+	// Recorded for events archived from FIFO queues.
+	messageGroupID string
+	sequenceNumber string
+
+	// Events can be retried a set number of times before they're given
+	// up on.
+	retryCount int
+}
+
+// encodedEvent is a single line of the file.
+type encodedEvent struct {
+	Name string                 `json:"name"`
+	Data map[string]interface{} `json:"data"`
+
+	// Recorded for events from FIFO queues.
+	MessageGroupID string `json:"messageGroupId,omitempty"`
+	SequenceNumber string `json:"sequenceNumber,omitempty"`
+}
+
+func decodeEvent(raw []byte, line int) (*Event, error) {
+	e := &encodedEvent{}
+	if err := json.Unmarshal(raw, e); err != nil {
+		return nil, &LineError{Line: line, Err: err}
+	}
+
+	if "" == e.Name {
+		return nil, &LineError{Line: line, Err: errors.New("Event has no name")}
+	}
+
+	return &Event{
+		name:           e.Name,
+		data:           e.Data,
+		line:           line,
+		messageGroupID: e.MessageGroupID,
+		sequenceNumber: e.SequenceNumber,
+	}, nil
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// Line returns the line of the file the event was read from, starting
+// from 1.
+func (e Event) Line() int {
+	return e.line
+}
+
+// MessageGroupID returns the FIFO message group recorded for the event, if
+// any.
+func (e Event) MessageGroupID() string {
+	return e.messageGroupID
+}
+
+// SequenceNumber returns the FIFO sequence number recorded for the event, if
+// any.
+func (e Event) SequenceNumber() string {
+	return e.sequenceNumber
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
@@ -0,0 +1,20 @@
+package jsonl
+
+import (
+	"fmt"
+)
+
+// LineError is reported for lines of the file that aren't valid events.
+// They are skipped.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("Invalid event on line %d: %s", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,382 @@
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultMaximumRetryCount = 25
+	defaultFollowInterval    = 500 * time.Millisecond
+)
+
+// Provider replays events from a file with one JSON object per line:
+//
+//	{"name":"UserCreated","data":{"userId":12}}
+//
+// Blank lines are skipped, and invalid ones are reported as a *LineError,
+// wrapped in a *gomainevents.ProviderError with the line number as its
+// MessageID. Requeued events are redelivered after RedeliveryDelay. Nothing
+// is recorded in the file, so replaying it again delivers every event again.
+type Provider struct {
+	reader io.Reader
+	file   *os.File
+	path   string
+
+	follow            bool
+	followInterval    time.Duration
+	redeliveryDelay   time.Duration
+	maximumRetryCount int
+	clock             gomainevents.Clock
+
+	// Set to replay a single message group, whose events are held until
+	// the whole file is read.
+	messageGroupID string
+	group          []Event
+
+	// Progress, for Wait.
+	mu       sync.Mutex
+	reading  bool
+	inFlight int
+	changed  *sync.Cond
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+}
+
+type ProviderConfig struct {
+	// File to replay. Either Path or Reader is required.
+	Path string
+
+	// Provide your own reader instead, e.g. os.Stdin or a fixture.
+	Reader io.Reader
+
+	// Keep watching the file for new lines once the end is reached, like
+	// tail -f.
+	Follow bool
+
+	// How often to check for new lines when following. Defaults to 500
+	// milliseconds.
+	FollowInterval time.Duration
+
+	// How long to wait before redelivering a requeued event. Defaults to
+	// redelivering straight away.
+	RedeliveryDelay time.Duration
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+
+	// Only replay the events archived from this FIFO message group, in
+	// sequence order. The whole file is read before the first of them is
+	// delivered, so it can't be followed. Requeued events are redelivered
+	// after the ones behind them.
+	MessageGroupID string
+
+	// Clock redelivery delays and FollowInterval are measured on.
+	// Defaults to gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewProvider(config *ProviderConfig) (*Provider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	reader, file := config.Reader, (*os.File)(nil)
+	if nil == reader {
+		if "" == config.Path {
+			return nil, errors.New("Path or Reader is required")
+		}
+
+		var err error
+		if file, err = os.Open(config.Path); err != nil {
+			return nil, err
+		}
+
+		reader = file
+	}
+
+	if "" != config.MessageGroupID && config.Follow {
+		return nil, errors.New("Follow can't be used with MessageGroupID")
+	}
+
+	followInterval := defaultFollowInterval
+	if config.FollowInterval > 0 {
+		followInterval = config.FollowInterval
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	p := &Provider{
+		reader:            reader,
+		file:              file,
+		path:              config.Path,
+		follow:            config.Follow,
+		followInterval:    followInterval,
+		messageGroupID:    config.MessageGroupID,
+		redeliveryDelay:   config.RedeliveryDelay,
+		maximumRetryCount: maximumRetryCount,
+		clock:             gomainevents.ClockOrDefault(config.Clock),
+		reading:           true,
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}
+	p.changed = sync.NewCond(&p.mu)
+
+	return p, nil
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	go func() {
+		defer p.finishedReading()
+
+		reader := bufio.NewReader(p.reader)
+		partial := []byte{}
+		line := 0
+
+		for {
+			raw, err := reader.ReadBytes('\n')
+			partial = append(partial, raw...)
+
+			if err == io.EOF && p.follow {
+				// The rest of the line may not have been written yet.
+				select {
+				case <-p.done:
+					return
+				case <-p.clock.After(p.followInterval):
+				}
+				continue
+			}
+
+			if err != nil && err != io.EOF {
+				p.reportError(gomainevents.PhaseReceive, "", err)
+				return
+			}
+
+			if len(partial) > 0 {
+				line++
+				if !p.handle(partial, line) {
+					return
+				}
+				partial = []byte{}
+			}
+
+			if err == io.EOF {
+				p.deliverGroup()
+				return
+			}
+		}
+	}()
+
+	return p.events, p.errors
+}
+
+// handle decodes and delivers a line, returning false if the provider was
+// stopped.
+func (p *Provider) handle(raw []byte, line int) bool {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return true
+	}
+
+	event, err := decodeEvent(raw, line)
+	if err != nil {
+		p.reportError(gomainevents.PhaseDecode, strconv.Itoa(line), err)
+		return true
+	}
+
+	if "" != p.messageGroupID {
+		if p.messageGroupID == event.MessageGroupID() {
+			p.group = append(p.group, *event)
+		}
+
+		return true
+	}
+
+	p.mu.Lock()
+	p.inFlight++
+	p.mu.Unlock()
+
+	return p.deliver(*event)
+}
+
+// deliverGroup delivers the events of the message group being replayed in
+// sequence order, once the whole file has been read.
+func (p *Provider) deliverGroup() {
+	sort.SliceStable(p.group, func(i, j int) bool {
+		return gomainevents.CompareSequenceNumbers(p.group[i].SequenceNumber(), p.group[j].SequenceNumber()) < 0
+	})
+
+	for _, event := range p.group {
+		p.mu.Lock()
+		p.inFlight++
+		p.mu.Unlock()
+
+		if !p.deliver(event) {
+			return
+		}
+	}
+}
+
+func (p *Provider) finishedReading() {
+	p.mu.Lock()
+	p.reading = false
+	p.changed.Broadcast()
+	p.mu.Unlock()
+}
+
+// Delete an event that we're done with
+func (p *Provider) Delete(event gomainevents.Event) {
+	p.resolved()
+}
+
+// Requeue an event for later
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to JSONL flavor
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		p.resolved()
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	evt.retryCount++
+
+	p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", evt.RetryCount(), p.redeliveryDelay)
+	p.clock.AfterFunc(p.redeliveryDelay, func() {
+		p.deliver(evt)
+	})
+
+	return nil
+}
+
+// Defer an event, redelivering it after the delay with the same retry
+// count.
+func (p *Provider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to JSONL flavor
+
+	p.debugPrint("Deferring event. Delay: %s\n", delay)
+	p.clock.AfterFunc(delay, func() {
+		p.deliver(evt)
+	})
+
+	return nil
+}
+
+// resolved marks an event as deleted or given up on.
+func (p *Provider) resolved() {
+	p.mu.Lock()
+	p.inFlight--
+	p.changed.Broadcast()
+	p.mu.Unlock()
+}
+
+// Wait blocks until the whole file has been read and every event in it has
+// been deleted or given up on, or the timeout passes. It returns false on
+// timeout, and never returns true while following the file.
+func (p *Provider) Wait(timeout time.Duration) bool {
+	timer := time.AfterFunc(timeout, func() {
+		p.mu.Lock()
+		p.changed.Broadcast()
+		p.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	deadline := time.Now().Add(timeout)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.reading || p.inFlight > 0 {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+
+		p.changed.Wait()
+	}
+
+	return true
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	close(p.done)
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+
+	if nil != p.file {
+		p.file.Close()
+	}
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, p.path, messageID, err):
+	default:
+	}
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-jsonl] "+format, values...)
+	}
+}
@@ -0,0 +1,150 @@
+package jsonl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, events <-chan gomainevents.Event) Event {
+	select {
+	case event := <-events:
+		return event.(Event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	return Event{}
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{Reader: strings.NewReader("")})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Path: filepath.Join(t.TempDir(), "missing.jsonl")})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Reader: strings.NewReader(""), MessageGroupID: "order-1", Follow: true})
+	assert.Nil(t, provider)
+	assert.EqualError(t, err, "Follow can't be used with MessageGroupID")
+}
+
+func TestProviderReplaysGroupInSequenceOrder(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{
+		Reader: strings.NewReader(`{"name":"Shipped","data":{},"messageGroupId":"order-1","sequenceNumber":"18850000000000000900"}
+{"name":"Placed","data":{},"messageGroupId":"order-2","sequenceNumber":"18850000000000000100"}
+{"name":"Paid","data":{},"messageGroupId":"order-1","sequenceNumber":"9850000000000000800"}
+{"name":"Placed","data":{},"messageGroupId":"order-1","sequenceNumber":"9850000000000000100"}`),
+		MessageGroupID: "order-1",
+	})
+	require.Nil(t, err)
+	provider.debug = false
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	for _, name := range []string{"Placed", "Paid", "Shipped"} {
+		event := receive(t, events)
+		assert.Equal(t, name, event.Name())
+		assert.Equal(t, "order-1", event.MessageGroupID())
+		provider.Delete(event)
+	}
+
+	assert.True(t, provider.Wait(time.Second))
+}
+
+func TestProviderReplaysFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	require.Nil(t, os.WriteFile(path, []byte(`{"name":"First","data":{"userId":12}}
+
+not json
+{"name":"Second","data":{}}`), 0644))
+
+	provider, err := NewProvider(&ProviderConfig{Path: path})
+	require.Nil(t, err)
+
+	events, errs := provider.Start()
+	defer provider.Stop()
+
+	first := receive(t, events)
+	assert.Equal(t, "First", first.Name())
+	assert.Equal(t, map[string]interface{}{"userId": 12.0}, first.Data())
+	assert.Equal(t, 1, first.Line())
+	provider.Delete(first)
+
+	second := receive(t, events)
+	assert.Equal(t, "Second", second.Name())
+	assert.Equal(t, 4, second.Line())
+
+	// Not done until the last event is
+	assert.False(t, provider.Wait(10*time.Millisecond))
+	provider.Delete(second)
+	assert.True(t, provider.Wait(time.Second))
+
+	select {
+	case err := <-errs:
+		var lineErr *LineError
+		require.True(t, errors.As(err, &lineErr))
+		assert.Equal(t, 3, lineErr.Line)
+		assert.Equal(t, gomainevents.PhaseDecode, err.(*gomainevents.ProviderError).Phase)
+		assert.Equal(t, "3", err.(*gomainevents.ProviderError).MessageID)
+		assert.False(t, err.(*gomainevents.ProviderError).Retryable)
+	default:
+		t.Fatal("Expected an error")
+	}
+}
+
+func TestProviderRedeliversRequeuedEvents(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{
+		Reader:            strings.NewReader(`{"name":"Thing","data":{}}`),
+		MaximumRetryCount: 1,
+	})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	event := receive(t, events)
+	assert.Nil(t, provider.Requeue(event))
+
+	event = receive(t, events)
+	assert.Equal(t, 1, event.RetryCount())
+	assert.Nil(t, provider.Requeue(event))
+
+	event = receive(t, events)
+	assert.IsType(t, &RetryAttemptsExceededError{}, provider.Requeue(event))
+	assert.True(t, provider.Wait(time.Second))
+}
+
+func TestProviderFollowsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	publisher, err := NewPublisher(&PublisherConfig{Path: path})
+	require.Nil(t, err)
+	defer publisher.Close()
+
+	provider, err := NewProvider(&ProviderConfig{Path: path, Follow: true, FollowInterval: 10 * time.Millisecond})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Later"}))
+	assert.Equal(t, "Later", receive(t, events).Name())
+}
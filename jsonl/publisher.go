@@ -0,0 +1,88 @@
+package jsonl
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Publisher appends events to a file, one JSON object per line, in the
+// format the Provider reads.
+type Publisher struct {
+	mu     sync.Mutex
+	writer io.Writer
+	file   *os.File
+}
+
+type PublisherConfig struct {
+	// File to append to. It is created if it doesn't exist. Either Path or
+	// Writer is required.
+	Path string
+
+	// Provide your own writer instead, e.g. os.Stdout or a buffer.
+	Writer io.Writer
+}
+
+func NewPublisher(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil != config.Writer {
+		return &Publisher{writer: config.Writer}, nil
+	}
+
+	if "" == config.Path {
+		return nil, errors.New("Path or Writer is required")
+	}
+
+	file, err := os.OpenFile(config.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Publisher{writer: file, file: file}, nil
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	return p.PublishBatch([]gomainevents.Event{event})
+}
+
+// PublishBatch appends the events with a single write, so that they aren't
+// interleaved with events written by other processes.
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	lines := []byte{}
+	for _, event := range events {
+		encoded := &encodedEvent{
+			Name: event.Name(),
+			Data: event.Data(),
+		}
+		encoded.MessageGroupID, encoded.SequenceNumber, _ = gomainevents.Sequence(event)
+
+		line, err := json.Marshal(encoded)
+		if err != nil {
+			return err
+		}
+
+		lines = append(append(lines, line...), '\n')
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err := p.writer.Write(lines)
+	return err
+}
+
+// Close closes the file, if the publisher opened it.
+func (p *Publisher) Close() error {
+	if nil == p.file {
+		return nil
+	}
+
+	return p.file.Close()
+}
@@ -0,0 +1,58 @@
+package jsonl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(&PublisherConfig{Writer: &bytes.Buffer{}})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestPublisherAppendsToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	require.Nil(t, os.WriteFile(path, []byte(`{"name":"Existing","data":{}}`+"\n"), 0644))
+
+	publisher, err := NewPublisher(&PublisherConfig{Path: path})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.Publish(testEvent{name: "First"}))
+	require.Nil(t, publisher.PublishBatch([]gomainevents.Event{testEvent{name: "Second"}, testEvent{name: "Third"}}))
+	require.Nil(t, publisher.Close())
+
+	contents, err := os.ReadFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, `{"name":"Existing","data":{}}
+{"name":"First","data":{"occurredOn":"2018-03-08 11:11:11"}}
+{"name":"Second","data":{"occurredOn":"2018-03-08 11:11:11"}}
+{"name":"Third","data":{"occurredOn":"2018-03-08 11:11:11"}}
+`, string(contents))
+}
@@ -0,0 +1,89 @@
+package kafka
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// headerEventName carries the event name on every message, so consumers can
+// route on it without decoding the value.
+const headerEventName = "event-name"
+
+// Event implements the standard domain event interface for events read from
+// a Kafka topic.
+type Event struct {
+	name    string
+	data    map[string]interface{}
+	message kafkago.Message
+
+	// Kafka has no per-message redelivery, so the provider keeps track of
+	// how many times it has redelivered the event itself.
+	retryCount int
+}
+
+type encodedEvent struct {
+	Name string                 `json:"name"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// DecodeEvent builds an event from a Kafka message. The name header, when
+// present, takes precedence over the name in the value.
+func DecodeEvent(message kafkago.Message) (*Event, error) {
+	e := &encodedEvent{}
+	if err := json.Unmarshal(message.Value, e); err != nil {
+		return nil, err
+	}
+
+	name := e.Name
+	for _, header := range message.Headers {
+		if header.Key == headerEventName {
+			name = string(header.Value)
+		}
+	}
+
+	return &Event{
+		name:    name,
+		data:    e.Data,
+		message: message,
+	}, nil
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// Partition returns the partition the event was read from.
+func (e Event) Partition() int {
+	return e.message.Partition
+}
+
+// Offset returns the event's offset within its partition.
+func (e Event) Offset() int64 {
+	return e.message.Offset
+}
+
+// Key returns the message key the event was published with.
+func (e Event) Key() []byte {
+	return e.message.Key
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
+
+// Delay returns how long to wait before redelivering this event.
+func (e Event) Delay() time.Duration {
+	return time.Duration(math.Min(
+		math.Pow(2, float64(e.retryCount+1)),
+		15*60, // Max is 15 minutes
+	)) * time.Second
+}
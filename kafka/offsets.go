@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"sync"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// offsets works out how far each partition can be committed. Kafka keeps a
+// single committed offset per partition, so an event can only be committed
+// once every event before it in the partition is done as well. Otherwise an
+// event that is still being retried would be skipped after a restart.
+type offsets struct {
+	mu         sync.Mutex
+	partitions map[int]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	// Offsets handed out and not yet committed, in the order fetched.
+	pending []int64
+	done    map[int64]bool
+}
+
+func newOffsets() *offsets {
+	return &offsets{partitions: make(map[int]*partitionOffsets)}
+}
+
+// fetched records a message that is about to be handled.
+func (o *offsets) fetched(message kafkago.Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	partition, ok := o.partitions[message.Partition]
+	if !ok || (len(partition.pending) > 0 && message.Offset <= partition.pending[len(partition.pending)-1]) {
+		// New partition, or the reader went back after a rebalance.
+		partition = &partitionOffsets{done: make(map[int64]bool)}
+		o.partitions[message.Partition] = partition
+	}
+
+	partition.pending = append(partition.pending, message.Offset)
+}
+
+// done marks a message as handled. It returns the message to commit if the
+// partition's committed offset can move forward.
+func (o *offsets) done(message kafkago.Message) (kafkago.Message, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	partition, ok := o.partitions[message.Partition]
+	if !ok {
+		return kafkago.Message{}, false
+	}
+
+	partition.done[message.Offset] = true
+
+	committable := int64(-1)
+	for len(partition.pending) > 0 && partition.done[partition.pending[0]] {
+		committable = partition.pending[0]
+		delete(partition.done, committable)
+		partition.pending = partition.pending[1:]
+	}
+
+	if committable < 0 {
+		return kafkago.Message{}, false
+	}
+
+	return kafkago.Message{
+		Topic:     message.Topic,
+		Partition: message.Partition,
+		Offset:    committable,
+	}, true
+}
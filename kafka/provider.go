@@ -0,0 +1,329 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+const (
+	defaultMaximumRetryCount = 25
+	fetchErrorDelay          = time.Second
+)
+
+// headerRetryCount is added to messages sent to the dead letter topic.
+const headerRetryCount = "retry-count"
+
+// Reader is the part of kafka-go's Reader used by the Provider. It must be
+// configured with a consumer group.
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafkago.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafkago.Message) error
+	Close() error
+}
+
+// Provider consumes events from a Kafka topic as part of a consumer group.
+// Offsets are committed as events are deleted. Requeued events are
+// redelivered by the provider after a delay; until then, commits for their
+// partition pause at the requeued event, so it is read again if the
+// process restarts in the meantime.
+type Provider struct {
+	reader     Reader
+	deadLetter Writer
+	offsets    *offsets
+
+	maximumRetryCount int
+	jitter            gomainevents.Jitter
+	clock             gomainevents.Clock
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+}
+
+type ProviderConfig struct {
+	// Broker addresses, e.g. localhost:9092. Required unless a Reader is
+	// provided.
+	Brokers []string
+
+	// Topic to consume. Required unless a Reader is provided.
+	Topic string
+
+	// Consumer group to join. Required unless a Reader is provided.
+	GroupID string
+
+	// Provide your own reader, e.g. one with TLS or SASL configured.
+	Reader Reader
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+
+	// Randomizes retry delays, so that events that failed together aren't
+	// retried together. Defaults to none.
+	Jitter gomainevents.Jitter
+
+	// Topic that events exceeding MaximumRetryCount are moved to, using
+	// Brokers. Optional; without it those events are skipped.
+	DeadLetterTopic string
+
+	// Provide your own writer for dead letters instead of
+	// DeadLetterTopic.
+	DeadLetterWriter Writer
+
+	// Clock redelivery and fetch error delays are measured on. Defaults
+	// to gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewProvider(config *ProviderConfig) (*Provider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	reader := config.Reader
+	if nil == reader {
+		if len(config.Brokers) == 0 {
+			return nil, errors.New("Brokers are required")
+		}
+
+		if "" == config.Topic {
+			return nil, errors.New("Topic is required")
+		}
+
+		if "" == config.GroupID {
+			return nil, errors.New("GroupID is required")
+		}
+
+		reader = kafkago.NewReader(kafkago.ReaderConfig{
+			Brokers: config.Brokers,
+			Topic:   config.Topic,
+			GroupID: config.GroupID,
+		})
+	}
+
+	deadLetter := config.DeadLetterWriter
+	if nil == deadLetter && "" != config.DeadLetterTopic {
+		if len(config.Brokers) == 0 {
+			return nil, errors.New("Brokers are required for the dead letter topic")
+		}
+
+		deadLetter = &kafkago.Writer{
+			Addr:     kafkago.TCP(config.Brokers...),
+			Topic:    config.DeadLetterTopic,
+			Balancer: &kafkago.Hash{},
+		}
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Provider{
+		reader:            reader,
+		deadLetter:        deadLetter,
+		offsets:           newOffsets(),
+		maximumRetryCount: maximumRetryCount,
+		jitter:            config.Jitter,
+		clock:             gomainevents.ClockOrDefault(config.Clock),
+		ctx:               ctx,
+		cancel:            cancel,
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}, nil
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	go func() {
+		for {
+			message, err := p.reader.FetchMessage(p.ctx)
+			if err != nil {
+				select {
+				case <-p.done:
+					return
+				default:
+				}
+
+				p.reportError(gomainevents.PhaseReceive, "", err)
+
+				select {
+				case <-p.done:
+					return
+				case <-p.clock.After(fetchErrorDelay):
+				}
+
+				continue
+			}
+
+			p.offsets.fetched(message)
+
+			event, err := DecodeEvent(message)
+			if err != nil {
+				// It will never decode, so skip past it.
+				p.reportError(gomainevents.PhaseDecode, "", err)
+				p.commit(message)
+				continue
+			}
+
+			if !p.deliver(*event) {
+				return
+			}
+		}
+	}()
+
+	return p.events, p.errors
+}
+
+// Delete an event that we're done with by committing its offset, once
+// every earlier event in its partition is done too.
+func (p *Provider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to Kafka flavor
+
+	p.commit(evt.message)
+}
+
+// Requeue an event for later. Events that have been retried too many times
+// are moved to the dead letter topic, if there is one, and skipped.
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to Kafka flavor
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		if nil != p.deadLetter {
+			if err := p.sendToDeadLetter(evt); err != nil {
+				// Left uncommitted, so it is read again after a restart.
+				return err
+			}
+		}
+
+		p.commit(evt.message)
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	delay := p.jitter.Apply(evt.Delay())
+	evt.retryCount++
+
+	p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", evt.RetryCount(), delay)
+	p.clock.AfterFunc(delay, func() {
+		p.deliver(evt)
+	})
+
+	return nil
+}
+
+// Defer an event, redelivering it after the delay with the same retry
+// count.
+func (p *Provider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to Kafka flavor
+
+	p.debugPrint("Deferring event. Delay: %s\n", delay)
+	p.clock.AfterFunc(delay, func() {
+		p.deliver(evt)
+	})
+
+	return nil
+}
+
+func (p *Provider) sendToDeadLetter(event Event) error {
+	headers := append([]kafkago.Header{}, event.message.Headers...)
+	headers = append(headers, kafkago.Header{Key: headerRetryCount, Value: []byte(strconv.Itoa(event.RetryCount()))})
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	return p.deadLetter.WriteMessages(ctx, kafkago.Message{
+		Key:     event.message.Key,
+		Value:   event.message.Value,
+		Headers: headers,
+	})
+}
+
+// commit marks a message as done and commits as far as possible.
+func (p *Provider) commit(message kafkago.Message) {
+	committable, ok := p.offsets.done(message)
+	if !ok {
+		return
+	}
+
+	if err := p.reader.CommitMessages(p.ctx, committable); err != nil {
+		p.reportError(gomainevents.PhaseDelete, "", err)
+	}
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	close(p.done)
+	p.cancel()
+	p.reader.Close()
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, "", messageID, err):
+	default:
+	}
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-kafka] "+format, values...)
+	}
+}
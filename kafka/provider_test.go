@@ -0,0 +1,197 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockReader struct {
+	messages chan kafkago.Message
+
+	mu        sync.Mutex
+	committed []kafkago.Message
+}
+
+func newMockReader(messages ...kafkago.Message) *mockReader {
+	reader := &mockReader{messages: make(chan kafkago.Message, len(messages))}
+	for _, message := range messages {
+		reader.messages <- message
+	}
+
+	return reader
+}
+
+func (m *mockReader) FetchMessage(ctx context.Context) (kafkago.Message, error) {
+	select {
+	case message := <-m.messages:
+		return message, nil
+	case <-ctx.Done():
+		return kafkago.Message{}, ctx.Err()
+	}
+}
+
+func (m *mockReader) CommitMessages(ctx context.Context, msgs ...kafkago.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.committed = append(m.committed, msgs...)
+	return nil
+}
+
+func (m *mockReader) Close() error {
+	return nil
+}
+
+func (m *mockReader) committedOffsets() []int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	offsets := []int64{}
+	for _, message := range m.committed {
+		offsets = append(offsets, message.Offset)
+	}
+
+	return offsets
+}
+
+func message(partition int, offset int64, name string) kafkago.Message {
+	return kafkago.Message{
+		Topic:     "events",
+		Partition: partition,
+		Offset:    offset,
+		Value:     []byte(`{"name":"` + name + `","data":{"occurredOn":"2018-03-08 11:11:11"}}`),
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{Brokers: []string{"localhost:9092"}, Topic: "events", GroupID: "app"})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Reader: newMockReader()})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Brokers: []string{"localhost:9092"}, Topic: "events"})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Reader: newMockReader(), DeadLetterTopic: "events-dlq"})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+}
+
+func TestProviderCommitsInOrder(t *testing.T) {
+	reader := newMockReader(message(0, 10, "First"), message(0, 11, "Second"), message(0, 12, "Third"))
+	provider, err := NewProvider(&ProviderConfig{Reader: reader})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	received := []Event{}
+	for i := 0; i < 3; i++ {
+		select {
+		case event := <-events:
+			received = append(received, event.(Event))
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for event")
+		}
+	}
+	assert.Equal(t, "First", received[0].Name())
+	assert.Equal(t, int64(10), received[0].Offset())
+
+	// Nothing can be committed until the first event is done
+	provider.Delete(received[1])
+	provider.Delete(received[2])
+	assert.Empty(t, reader.committedOffsets())
+
+	provider.Delete(received[0])
+	assert.Equal(t, []int64{12}, reader.committedOffsets())
+}
+
+func TestProviderRequeue(t *testing.T) {
+	reader := newMockReader(message(0, 10, "First"))
+	deadLetter := &mockWriter{}
+	provider, err := NewProvider(&ProviderConfig{Reader: reader, DeadLetterWriter: deadLetter, MaximumRetryCount: 1})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	var event Event
+	select {
+	case e := <-events:
+		event = e.(Event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	event.retryCount = 1
+	assert.Nil(t, provider.Requeue(event))
+	assert.Empty(t, reader.committedOffsets())
+
+	event.retryCount = 2
+	err = provider.Requeue(event)
+	assert.IsType(t, &RetryAttemptsExceededError{}, err)
+
+	// Moved to the dead letter topic and skipped
+	dead := deadLetter.written()
+	require.Len(t, dead, 1)
+	assert.Equal(t, event.message.Value, dead[0].Value)
+	assert.Equal(t, []kafkago.Header{{Key: headerRetryCount, Value: []byte("2")}}, dead[0].Headers)
+	assert.Equal(t, []int64{10}, reader.committedOffsets())
+}
+
+func TestProviderSkipsUndecodableMessages(t *testing.T) {
+	reader := newMockReader(kafkago.Message{Partition: 0, Offset: 10, Value: []byte("nope")}, message(0, 11, "Thing"))
+	provider, err := NewProvider(&ProviderConfig{Reader: reader})
+	require.Nil(t, err)
+
+	events, errs := provider.Start()
+	defer provider.Stop()
+
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error")
+	}
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "Thing", event.Name())
+		provider.Delete(event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	assert.Equal(t, []int64{10, 11}, reader.committedOffsets())
+}
+
+func TestOffsetsAfterRebalance(t *testing.T) {
+	o := newOffsets()
+
+	o.fetched(message(1, 5, "First"))
+	o.fetched(message(1, 6, "Second"))
+
+	// The reader starts over from the last commit
+	o.fetched(message(1, 5, "First"))
+
+	committable, ok := o.done(message(1, 5, "First"))
+	assert.True(t, ok)
+	assert.Equal(t, int64(5), committable.Offset)
+
+	_, ok = o.done(message(2, 1, "Unknown"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,127 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Writer is the part of kafka-go's Writer used by the Publisher.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafkago.Message) error
+}
+
+// KeyFunc returns the message key for an event. Events with the same key
+// land on the same partition and are consumed in order.
+type KeyFunc func(gomainevents.Event) []byte
+
+// Publisher produces events to a Kafka topic. Each message carries the
+// event name in a header; the key defaults to the event name as well.
+type Publisher struct {
+	writer  Writer
+	key     KeyFunc
+	timeout time.Duration
+}
+
+type PublisherConfig struct {
+	// Broker addresses, e.g. localhost:9092. Required unless a Writer is
+	// provided.
+	Brokers []string
+
+	// Topic to produce to. Required unless a Writer is provided.
+	Topic string
+
+	// Provide your own writer, e.g. one with TLS or SASL configured.
+	Writer Writer
+
+	// Chooses the message key. Defaults to the event name.
+	Key KeyFunc
+
+	// Maximum time allowed for a single publish. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+func NewPublisher(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	writer := config.Writer
+	if nil == writer {
+		if len(config.Brokers) == 0 {
+			return nil, errors.New("Brokers are required")
+		}
+
+		if "" == config.Topic {
+			return nil, errors.New("Topic is required")
+		}
+
+		writer = &kafkago.Writer{
+			Addr:     kafkago.TCP(config.Brokers...),
+			Topic:    config.Topic,
+			Balancer: &kafkago.Hash{},
+		}
+	}
+
+	key := config.Key
+	if nil == key {
+		key = func(event gomainevents.Event) []byte {
+			return []byte(event.Name())
+		}
+	}
+
+	timeout := defaultTimeout
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	return &Publisher{
+		writer:  writer,
+		key:     key,
+		timeout: timeout,
+	}, nil
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	return p.PublishBatch([]gomainevents.Event{event})
+}
+
+// PublishBatch produces several events in a single request.
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	messages := make([]kafkago.Message, 0, len(events))
+	for _, event := range events {
+		message, err := p.message(event)
+		if err != nil {
+			return err
+		}
+
+		messages = append(messages, message)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	return p.writer.WriteMessages(ctx, messages...)
+}
+
+func (p *Publisher) message(event gomainevents.Event) (kafkago.Message, error) {
+	value, err := json.Marshal(&encodedEvent{
+		Name: event.Name(),
+		Data: event.Data(),
+	})
+	if err != nil {
+		return kafkago.Message{}, err
+	}
+
+	return kafkago.Message{
+		Key:     p.key(event),
+		Value:   value,
+		Headers: []kafkago.Header{{Key: headerEventName, Value: []byte(event.Name())}},
+	}, nil
+}
@@ -0,0 +1,134 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockWriter struct {
+	mu       sync.Mutex
+	messages []kafkago.Message
+	err      error
+}
+
+func (m *mockWriter) WriteMessages(ctx context.Context, msgs ...kafkago.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if nil != m.err {
+		return m.err
+	}
+
+	m.messages = append(m.messages, msgs...)
+	return nil
+}
+
+func (m *mockWriter) written() []kafkago.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]kafkago.Message{}, m.messages...)
+}
+
+type testEvent struct {
+	name string
+	id   string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11", "id": e.id}
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(&PublisherConfig{Brokers: []string{"localhost:9092"}, Topic: "events"})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Writer: &mockWriter{}})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Brokers: []string{"localhost:9092"}})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Topic: "events"})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestPublish(t *testing.T) {
+	writer := &mockWriter{}
+	publisher, err := NewPublisher(&PublisherConfig{Writer: writer})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing", id: "1"}))
+
+	messages := writer.written()
+	require.Len(t, messages, 1)
+	assert.Equal(t, []byte("Thing"), messages[0].Key)
+	assert.Equal(t, []kafkago.Header{{Key: headerEventName, Value: []byte("Thing")}}, messages[0].Headers)
+	assert.JSONEq(t, `{"name":"Thing","data":{"occurredOn":"2018-03-08 11:11:11","id":"1"}}`, string(messages[0].Value))
+
+	event, err := DecodeEvent(messages[0])
+	require.Nil(t, err)
+	assert.Equal(t, "Thing", event.Name())
+	assert.Equal(t, "1", event.Data()["id"])
+}
+
+func TestPublishBatchWithKey(t *testing.T) {
+	writer := &mockWriter{}
+	publisher, err := NewPublisher(&PublisherConfig{
+		Writer: writer,
+		Key: func(event gomainevents.Event) []byte {
+			return []byte(event.Data()["id"].(string))
+		},
+	})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.PublishBatch([]gomainevents.Event{
+		testEvent{name: "Created", id: "1"},
+		testEvent{name: "Updated", id: "1"},
+	}))
+
+	messages := writer.written()
+	require.Len(t, messages, 2)
+	for _, message := range messages {
+		assert.Equal(t, []byte("1"), message.Key)
+	}
+
+	writer.err = errors.New("Broker unavailable")
+	assert.Equal(t, writer.err, publisher.Publish(testEvent{name: "Thing", id: "2"}))
+}
+
+func TestPublishUnencodableEvent(t *testing.T) {
+	publisher, err := NewPublisher(&PublisherConfig{Writer: &mockWriter{}})
+	require.Nil(t, err)
+
+	assert.NotNil(t, publisher.Publish(unencodableEvent{}))
+}
+
+type unencodableEvent struct{}
+
+func (unencodableEvent) Name() string {
+	return "Unencodable"
+}
+
+func (unencodableEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"fn": func() {}}
+}
@@ -0,0 +1,162 @@
+package gomainevents
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultKillSwitchInterval = 10 * time.Second
+
+// KillSwitch disables events on a Listener according to a file, so that a
+// misbehaving handler can be turned off without redeploying, e.g. by
+// editing a mounted ConfigMap. The file lists the names of the events to
+// disable, one per line:
+//
+//	# Double charging, see INC-123
+//	PaymentReceived
+//
+// Blank lines and lines starting with # are ignored. A missing file
+// disables nothing. See Listener.Disable.
+type KillSwitch struct {
+	listener *Listener
+	path     string
+	interval time.Duration
+	onError  ErrorHandler
+
+	// Events the file disabled, which are enabled again when they're
+	// removed from it. Other disabled events are left alone.
+	mu       sync.Mutex
+	disabled map[string]bool
+
+	done     chan bool
+	stopOnce sync.Once
+}
+
+type KillSwitchConfig struct {
+	// Listener to disable events on. Required
+	Listener *Listener
+
+	// File listing the events to disable. Required
+	Path string
+
+	// How often the file is read. Defaults to 10 seconds.
+	Interval time.Duration
+
+	// Called when the file can't be read. The events disabled stay as
+	// they were. Optional
+	OnError ErrorHandler
+}
+
+func NewKillSwitch(config *KillSwitchConfig) (*KillSwitch, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Listener {
+		return nil, errors.New("Listener is required")
+	}
+
+	if "" == config.Path {
+		return nil, errors.New("Path is required")
+	}
+
+	interval := defaultKillSwitchInterval
+	if config.Interval > 0 {
+		interval = config.Interval
+	}
+
+	return &KillSwitch{
+		listener: config.Listener,
+		path:     config.Path,
+		interval: interval,
+		onError:  config.OnError,
+		disabled: make(map[string]bool),
+		done:     make(chan bool),
+	}, nil
+}
+
+// Start reads the file straight away and then every Interval until Stop is
+// called.
+func (k *KillSwitch) Start() {
+	k.Reload()
+
+	go func() {
+		ticker := time.NewTicker(k.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-k.done:
+				return
+			case <-ticker.C:
+				k.Reload()
+			}
+		}
+	}()
+}
+
+// Stop stops reading the file. Events stay disabled.
+func (k *KillSwitch) Stop() {
+	k.stopOnce.Do(func() {
+		close(k.done)
+	})
+}
+
+// Reload reads the file and disables and enables events to match it.
+func (k *KillSwitch) Reload() {
+	names, err := k.read()
+	if err != nil {
+		if nil != k.onError {
+			k.onError(err)
+		}
+
+		return
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for name := range k.disabled {
+		if !names[name] {
+			k.listener.Enable(name)
+			delete(k.disabled, name)
+		}
+	}
+
+	for name := range names {
+		if !k.disabled[name] {
+			k.listener.Disable(name)
+			k.disabled[name] = true
+		}
+	}
+}
+
+func (k *KillSwitch) read() (map[string]bool, error) {
+	names := make(map[string]bool)
+
+	contents, err := os.ReadFile(k.path)
+	if os.IsNotExist(err) {
+		return names, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if "" == line || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		names[line] = true
+	}
+
+	return names, scanner.Err()
+}
@@ -0,0 +1,161 @@
+package gomainevents
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerDisable(t *testing.T) {
+	provider := &recordingProvider{events: make(chan Event)}
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Created", func(Event) error { return nil })
+	listener.RegisterHandler("Deleted", func(Event) error { return errors.New("Nope") })
+
+	listener.Disable("Deleted")
+	assert.True(t, listener.Disabled("Deleted"))
+	assert.False(t, listener.Disabled("Created"))
+	assert.Equal(t, []string{"Deleted"}, listener.DisabledEvents())
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	provider.events <- testEvent{name: "Deleted"}
+	provider.events <- testEvent{name: "Created"}
+
+	assert.Eventually(t, func() bool {
+		stats := listener.Stats()
+		return stats.Processed == 1 && stats.Disabled == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stats := listener.Stats()
+	assert.Equal(t, int64(0), stats.Failed)
+
+	provider.mu.Lock()
+	assert.Len(t, provider.deleted, 1)
+	assert.Len(t, provider.requeued, 0)
+	provider.mu.Unlock()
+
+	listener.Enable("Deleted")
+	assert.Empty(t, listener.DisabledEvents())
+
+	provider.events <- testEvent{name: "Deleted"}
+	assert.Eventually(t, func() bool { return listener.Stats().Failed == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	provider.mu.Lock()
+	assert.Len(t, provider.requeued, 1)
+	provider.mu.Unlock()
+}
+
+// deferringProvider records the events it's asked to defer.
+type deferringProvider struct {
+	recordingProvider
+
+	deferred []time.Duration
+}
+
+func (p *deferringProvider) Defer(event Event, delay time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.deferred = append(p.deferred, delay)
+	return nil
+}
+
+func TestListenerDefersDisabledEvents(t *testing.T) {
+	provider := &deferringProvider{recordingProvider: recordingProvider{events: make(chan Event)}}
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Deleted", func(Event) error { return nil })
+	listener.Disable("Deleted")
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	provider.events <- testEvent{name: "Deleted"}
+	assert.Eventually(t, func() bool { return listener.Stats().Disabled == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+
+		return len(provider.deferred) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	provider.mu.Lock()
+	assert.Equal(t, []time.Duration{disabledEventDelay}, provider.deferred)
+	assert.Empty(t, provider.deleted)
+	assert.Empty(t, provider.requeued)
+	provider.mu.Unlock()
+}
+
+func TestNewKillSwitch(t *testing.T) {
+	killSwitch, err := NewKillSwitch(nil)
+	assert.Nil(t, killSwitch)
+	assert.NotNil(t, err)
+
+	killSwitch, err = NewKillSwitch(&KillSwitchConfig{Path: "disabled-events"})
+	assert.Nil(t, killSwitch)
+	assert.NotNil(t, err)
+
+	killSwitch, err = NewKillSwitch(&KillSwitchConfig{Listener: NewListener(&recordingProvider{})})
+	assert.Nil(t, killSwitch)
+	assert.NotNil(t, err)
+
+	killSwitch, err = NewKillSwitch(&KillSwitchConfig{Listener: NewListener(&recordingProvider{}), Path: "disabled-events"})
+	require.Nil(t, err)
+	assert.Equal(t, 10*time.Second, killSwitch.interval)
+}
+
+func TestKillSwitchReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disabled-events")
+
+	listener := NewListener(&recordingProvider{})
+	listener.Disable("Archived")
+
+	killSwitch, err := NewKillSwitch(&KillSwitchConfig{Listener: listener, Path: path})
+	require.Nil(t, err)
+
+	// Missing files disable nothing
+	killSwitch.Reload()
+	assert.Equal(t, []string{"Archived"}, listener.DisabledEvents())
+
+	require.Nil(t, os.WriteFile(path, []byte("# Double charging\nPaymentReceived\n\n  Deleted  \n"), 0644))
+	killSwitch.Reload()
+	assert.Equal(t, []string{"Archived", "Deleted", "PaymentReceived"}, listener.DisabledEvents())
+
+	require.Nil(t, os.WriteFile(path, []byte("Deleted\n"), 0644))
+	killSwitch.Reload()
+	assert.Equal(t, []string{"Archived", "Deleted"}, listener.DisabledEvents())
+
+	require.Nil(t, os.Remove(path))
+	killSwitch.Reload()
+	assert.Equal(t, []string{"Archived"}, listener.DisabledEvents())
+}
+
+func TestKillSwitchReportsErrors(t *testing.T) {
+	var reported error
+
+	listener := NewListener(&recordingProvider{})
+	listener.Disable("Deleted")
+
+	// A directory can't be read as a file
+	killSwitch, err := NewKillSwitch(&KillSwitchConfig{
+		Listener: listener,
+		Path:     t.TempDir(),
+		OnError:  func(err error) { reported = err },
+	})
+	require.Nil(t, err)
+
+	killSwitch.Reload()
+	assert.NotNil(t, reported)
+	assert.Equal(t, []string{"Deleted"}, listener.DisabledEvents())
+}
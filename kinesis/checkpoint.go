@@ -0,0 +1,144 @@
+package kinesis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ShardEnd is checkpointed once every record of a closed shard (one that
+// was split or merged) has been processed.
+const ShardEnd = "SHARD_END"
+
+// Attributes of the checkpoint table's items. The table's partition key
+// must be a string named "shardKey".
+const (
+	attributeShardKey       = "shardKey"
+	attributeSequenceNumber = "sequenceNumber"
+	attributeUpdatedAt      = "updatedAt"
+)
+
+// Checkpointer records how far each shard has been processed, so that a
+// restarted provider resumes where it left off.
+type Checkpointer interface {
+	// Checkpoint returns the sequence number last processed for the
+	// shard, ShardEnd, or "" if the shard hasn't been checkpointed.
+	Checkpoint(shardID string) (string, error)
+
+	// SetCheckpoint records the sequence number last processed for the
+	// shard.
+	SetCheckpoint(shardID, sequenceNumber string) error
+}
+
+// DynamoDBAPI is the subset of the DynamoDB client used by this package. It
+// is satisfied by *dynamodb.Client from aws-sdk-go-v2.
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+// DynamoDBCheckpointer keeps checkpoints in a DynamoDB table, one item per
+// shard.
+type DynamoDBCheckpointer struct {
+	client    DynamoDBAPI
+	table     string
+	namespace string
+}
+
+type CheckpointConfig struct {
+	// Provide your own DynamoDB client. Default will use the
+	// default AWS config + shared credentials.
+	DynamoDBClient DynamoDBAPI
+
+	// Region used by the default client. Defaults to us-east-1. Ignored
+	// when DynamoDBClient is provided.
+	Region string
+
+	// Endpoint overrides the DynamoDB endpoint used by the default
+	// client. Ignored when DynamoDBClient is provided.
+	Endpoint string
+
+	// Table to keep checkpoints in. Its partition key must be a string
+	// named "shardKey". Required
+	Table string
+
+	// Prefixed to shard IDs, so that several applications or streams can
+	// share a table. Usually the application and stream name.
+	Namespace string
+}
+
+func NewDynamoDBCheckpointer(config *CheckpointConfig) (*DynamoDBCheckpointer, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.Table {
+		return nil, errors.New("Table is required")
+	}
+
+	// Default to a new client using shared credentials
+	client := config.DynamoDBClient
+	if nil == client {
+		awsConfig, err := loadAWSConfig(config.Region)
+		if err != nil {
+			return nil, err
+		}
+
+		client = dynamodb.NewFromConfig(awsConfig, func(o *dynamodb.Options) {
+			if "" != config.Endpoint {
+				o.BaseEndpoint = aws.String(config.Endpoint)
+			}
+		})
+	}
+
+	return &DynamoDBCheckpointer{
+		client:    client,
+		table:     config.Table,
+		namespace: config.Namespace,
+	}, nil
+}
+
+func (c *DynamoDBCheckpointer) Checkpoint(shardID string) (string, error) {
+	resp, err := c.client.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName:      aws.String(c.table),
+		Key:            c.key(shardID),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if value, ok := resp.Item[attributeSequenceNumber].(*types.AttributeValueMemberS); ok {
+		return value.Value, nil
+	}
+
+	return "", nil
+}
+
+func (c *DynamoDBCheckpointer) SetCheckpoint(shardID, sequenceNumber string) error {
+	item := c.key(shardID)
+	item[attributeSequenceNumber] = &types.AttributeValueMemberS{Value: sequenceNumber}
+	item[attributeUpdatedAt] = &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)}
+
+	_, err := c.client.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String(c.table),
+		Item:      item,
+	})
+
+	return err
+}
+
+func (c *DynamoDBCheckpointer) key(shardID string) map[string]types.AttributeValue {
+	key := shardID
+	if "" != c.namespace {
+		key = c.namespace + "/" + shardID
+	}
+
+	return map[string]types.AttributeValue{
+		attributeShardKey: &types.AttributeValueMemberS{Value: key},
+	}
+}
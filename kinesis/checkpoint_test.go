@@ -0,0 +1,89 @@
+package kinesis
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDynamoDB struct {
+	DynamoDBAPI
+
+	items map[string]map[string]types.AttributeValue
+}
+
+func (m *mockDynamoDB) GetItem(ctx context.Context, in *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	key := in.Key[attributeShardKey].(*types.AttributeValueMemberS).Value
+	return &dynamodb.GetItemOutput{Item: m.items[aws.ToString(in.TableName)+"|"+key]}, nil
+}
+
+func (m *mockDynamoDB) PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	key := in.Item[attributeShardKey].(*types.AttributeValueMemberS).Value
+	m.items[aws.ToString(in.TableName)+"|"+key] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// memoryCheckpointer keeps checkpoints in memory for provider tests.
+type memoryCheckpointer struct {
+	mu          sync.Mutex
+	checkpoints map[string]string
+}
+
+func newMemoryCheckpointer() *memoryCheckpointer {
+	return &memoryCheckpointer{checkpoints: make(map[string]string)}
+}
+
+func (c *memoryCheckpointer) Checkpoint(shardID string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.checkpoints[shardID], nil
+}
+
+func (c *memoryCheckpointer) SetCheckpoint(shardID, sequenceNumber string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checkpoints[shardID] = sequenceNumber
+	return nil
+}
+
+func TestNewDynamoDBCheckpointer(t *testing.T) {
+	checkpointer, err := NewDynamoDBCheckpointer(&CheckpointConfig{DynamoDBClient: &mockDynamoDB{}, Table: "checkpoints"})
+	assert.NotNil(t, checkpointer)
+	assert.Nil(t, err)
+
+	checkpointer, err = NewDynamoDBCheckpointer(&CheckpointConfig{DynamoDBClient: &mockDynamoDB{}})
+	assert.Nil(t, checkpointer)
+	assert.NotNil(t, err)
+
+	checkpointer, err = NewDynamoDBCheckpointer(nil)
+	assert.Nil(t, checkpointer)
+	assert.NotNil(t, err)
+}
+
+func TestDynamoDBCheckpointer(t *testing.T) {
+	client := &mockDynamoDB{items: make(map[string]map[string]types.AttributeValue)}
+
+	checkpointer, err := NewDynamoDBCheckpointer(&CheckpointConfig{DynamoDBClient: client, Table: "checkpoints", Namespace: "app/events"})
+	require.Nil(t, err)
+
+	checkpoint, err := checkpointer.Checkpoint("shard-0")
+	require.Nil(t, err)
+	assert.Equal(t, "", checkpoint)
+
+	require.Nil(t, checkpointer.SetCheckpoint("shard-0", "42"))
+
+	checkpoint, err = checkpointer.Checkpoint("shard-0")
+	require.Nil(t, err)
+	assert.Equal(t, "42", checkpoint)
+
+	// Namespaced, so other applications can share the table
+	assert.Contains(t, client.items, "checkpoints|app/events/shard-0")
+}
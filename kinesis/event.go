@@ -0,0 +1,107 @@
+package kinesis
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+// Event implements the standard domain event interface for events read from
+// a Kinesis stream.
+type Event struct {
+	name string
+	data map[string]interface{}
+
+	shardID        string
+	sequenceNumber string
+	partitionKey   string
+
+	// Kinesis has no per-record redelivery, so the provider keeps track of
+	// how many times it has redelivered the event itself.
+	retryCount int
+
+	// Tells the shard's worker what became of the event. Buffered, so
+	// resolving it never blocks.
+	outcome chan outcome
+}
+
+type encodedEvent struct {
+	Name string                 `json:"name"`
+	Data map[string]interface{} `json:"data"`
+}
+
+func encodeEvent(name string, data map[string]interface{}) ([]byte, error) {
+	return json.Marshal(&encodedEvent{
+		Name: name,
+		Data: data,
+	})
+}
+
+// DecodeEvent builds an event from a record read from the given shard.
+func DecodeEvent(shardID string, record types.Record) (*Event, error) {
+	e := &encodedEvent{}
+	if err := json.Unmarshal(record.Data, e); err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		name:           e.Name,
+		data:           e.Data,
+		shardID:        shardID,
+		sequenceNumber: aws.ToString(record.SequenceNumber),
+		partitionKey:   aws.ToString(record.PartitionKey),
+	}, nil
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// ShardID returns the shard the event was read from.
+func (e Event) ShardID() string {
+	return e.shardID
+}
+
+// SequenceNumber returns the event's sequence number within its shard.
+func (e Event) SequenceNumber() string {
+	return e.sequenceNumber
+}
+
+// PartitionKey returns the partition key the event was published with.
+func (e Event) PartitionKey() string {
+	return e.partitionKey
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
+
+// Delay returns how long to wait before redelivering this event.
+func (e Event) Delay() time.Duration {
+	return time.Duration(math.Min(
+		math.Pow(2, float64(e.retryCount+1)),
+		15*60, // Max is 15 minutes
+	)) * time.Second
+}
+
+// resolve tells the shard's worker what became of the event. Only the first
+// outcome counts.
+func (e Event) resolve(o outcome) {
+	if nil == e.outcome {
+		return
+	}
+
+	select {
+	case e.outcome <- o:
+	default:
+	}
+}
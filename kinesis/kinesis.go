@@ -0,0 +1,47 @@
+package kinesis
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awskinesis "github.com/aws/aws-sdk-go-v2/service/kinesis"
+)
+
+// defaultRegion is used for the default client when no Region is configured.
+const defaultRegion = "us-east-1"
+
+// KinesisAPI is the subset of the Kinesis client used by this package. It is
+// satisfied by *kinesis.Client from aws-sdk-go-v2.
+type KinesisAPI interface {
+	PutRecord(ctx context.Context, params *awskinesis.PutRecordInput, optFns ...func(*awskinesis.Options)) (*awskinesis.PutRecordOutput, error)
+	PutRecords(ctx context.Context, params *awskinesis.PutRecordsInput, optFns ...func(*awskinesis.Options)) (*awskinesis.PutRecordsOutput, error)
+	ListShards(ctx context.Context, params *awskinesis.ListShardsInput, optFns ...func(*awskinesis.Options)) (*awskinesis.ListShardsOutput, error)
+	GetShardIterator(ctx context.Context, params *awskinesis.GetShardIteratorInput, optFns ...func(*awskinesis.Options)) (*awskinesis.GetShardIteratorOutput, error)
+	GetRecords(ctx context.Context, params *awskinesis.GetRecordsInput, optFns ...func(*awskinesis.Options)) (*awskinesis.GetRecordsOutput, error)
+}
+
+// loadAWSConfig loads the default AWS config for the region, falling back to
+// us-east-1.
+func loadAWSConfig(region string) (aws.Config, error) {
+	if "" == region {
+		region = defaultRegion
+	}
+
+	return awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(region))
+}
+
+// newKinesisClient returns a client using the default AWS config and shared
+// credentials.
+func newKinesisClient(region, endpoint string) (KinesisAPI, error) {
+	awsConfig, err := loadAWSConfig(region)
+	if err != nil {
+		return nil, err
+	}
+
+	return awskinesis.NewFromConfig(awsConfig, func(o *awskinesis.Options) {
+		if "" != endpoint {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	}), nil
+}
@@ -0,0 +1,472 @@
+package kinesis
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskinesis "github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultMaximumRetryCount = 25
+	defaultBatchSize         = 100
+	defaultPollInterval      = time.Second
+	defaultShardSyncInterval = time.Minute
+)
+
+// outcome is what became of a delivered event.
+type outcome struct {
+	retry bool
+	delay time.Duration
+
+	// Deferred events are redelivered without counting as a retry.
+	deferred bool
+}
+
+// Provider reads events from every shard of a Kinesis stream. Each shard has
+// its own worker that delivers one event at a time and waits for it to be
+// deleted or requeued before moving on, so events with the same partition
+// key are handled in the order they were published. A requeued event holds
+// up the rest of its shard until it is redelivered after a delay.
+//
+// Progress is checkpointed after each batch of records. Shards are not
+// leased, so only one provider should read a stream per Checkpointer
+// namespace.
+type Provider struct {
+	client       KinesisAPI
+	streamName   string
+	checkpointer Checkpointer
+
+	initialPosition   types.ShardIteratorType
+	batchSize         int32
+	pollInterval      time.Duration
+	shardSyncInterval time.Duration
+	maximumRetryCount int
+	jitter            gomainevents.Jitter
+	clock             gomainevents.Clock
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Shards that have a worker, and shards that have been read to the end.
+	mu       sync.Mutex
+	running  map[string]bool
+	finished map[string]bool
+	workers  sync.WaitGroup
+	resync   chan bool
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+
+	polledMu sync.Mutex
+	polledAt time.Time
+}
+
+type ProviderConfig struct {
+	// Provide your own Kinesis client. Default will use the
+	// default AWS config + shared credentials.
+	KinesisClient KinesisAPI
+
+	// Region used by the default clients. Defaults to us-east-1. Ignored
+	// when the clients are provided.
+	Region string
+
+	// Endpoint overrides the Kinesis endpoint used by the default client.
+	// Ignored when KinesisClient is provided.
+	Endpoint string
+
+	// Stream to read from. Required
+	StreamName string
+
+	// Where progress is recorded. Either Checkpointer or CheckpointTable
+	// is required.
+	Checkpointer Checkpointer
+
+	// DynamoDB table to checkpoint to, using the default client. See
+	// CheckpointConfig.
+	CheckpointTable string
+
+	// Distinguishes this application's checkpoints from others in the
+	// same table. Defaults to the stream name.
+	ApplicationName string
+
+	// Where to start reading shards that haven't been checkpointed.
+	// Defaults to TRIM_HORIZON, the oldest record available.
+	InitialPosition types.ShardIteratorType
+
+	// Maximum number of records read from a shard at once. Defaults to 100.
+	BatchSize int32
+
+	// How long to wait before reading a shard again once it has caught up.
+	// Defaults to 1 second.
+	PollInterval time.Duration
+
+	// How often to look for new shards after the stream is resharded.
+	// Defaults to 1 minute.
+	ShardSyncInterval time.Duration
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+
+	// Randomizes retry delays, so that events that failed together aren't
+	// retried together. Defaults to none.
+	Jitter gomainevents.Jitter
+
+	// Clock polls, shard syncs and retry delays are measured on.
+	// Defaults to gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewProvider(config *ProviderConfig) (*Provider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.StreamName {
+		return nil, errors.New("StreamName is required")
+	}
+
+	checkpointer := config.Checkpointer
+	if nil == checkpointer {
+		if "" == config.CheckpointTable {
+			return nil, errors.New("Checkpointer or CheckpointTable is required")
+		}
+
+		namespace := config.ApplicationName
+		if "" == namespace {
+			namespace = config.StreamName
+		}
+
+		var err error
+		checkpointer, err = NewDynamoDBCheckpointer(&CheckpointConfig{
+			Region:    config.Region,
+			Table:     config.CheckpointTable,
+			Namespace: namespace,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Default to a new client using shared credentials
+	client := config.KinesisClient
+	if nil == client {
+		var err error
+		if client, err = newKinesisClient(config.Region, config.Endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	initialPosition := config.InitialPosition
+	if "" == initialPosition {
+		initialPosition = types.ShardIteratorTypeTrimHorizon
+	}
+
+	batchSize := int32(defaultBatchSize)
+	if config.BatchSize > 0 {
+		batchSize = config.BatchSize
+	}
+
+	pollInterval := defaultPollInterval
+	if config.PollInterval > 0 {
+		pollInterval = config.PollInterval
+	}
+
+	shardSyncInterval := defaultShardSyncInterval
+	if config.ShardSyncInterval > 0 {
+		shardSyncInterval = config.ShardSyncInterval
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Provider{
+		client:            client,
+		streamName:        config.StreamName,
+		checkpointer:      checkpointer,
+		initialPosition:   initialPosition,
+		batchSize:         batchSize,
+		pollInterval:      pollInterval,
+		shardSyncInterval: shardSyncInterval,
+		maximumRetryCount: maximumRetryCount,
+		jitter:            config.Jitter,
+		clock:             gomainevents.ClockOrDefault(config.Clock),
+		ctx:               ctx,
+		cancel:            cancel,
+		running:           make(map[string]bool),
+		finished:          make(map[string]bool),
+		resync:            make(chan bool, 1),
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}, nil
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	p.debugPrint("Listening for events from %s\n", p.streamName)
+
+	go func() {
+		for {
+			if err := p.syncShards(); err != nil {
+				p.reportError(gomainevents.PhaseReceive, "", err)
+			}
+
+			select {
+			case <-p.done:
+				return
+			case <-p.resync:
+			case <-p.clock.After(p.shardSyncInterval):
+			}
+		}
+	}()
+
+	return p.events, p.errors
+}
+
+// syncShards starts a worker for every shard that is ready to be read. A
+// shard created by resharding only becomes ready once its parents have been
+// read to the end, so that events stay in order across the reshard.
+func (p *Provider) syncShards() error {
+	shards, err := p.listShards()
+	if err != nil {
+		return err
+	}
+
+	listed := make(map[string]bool, len(shards))
+	for _, shard := range shards {
+		listed[aws.ToString(shard.ShardId)] = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, shard := range shards {
+		id := aws.ToString(shard.ShardId)
+		if p.running[id] || p.finished[id] {
+			continue
+		}
+
+		if !p.parentFinished(shard.ParentShardId, listed) || !p.parentFinished(shard.AdjacentParentShardId, listed) {
+			continue
+		}
+
+		select {
+		case <-p.done:
+			return nil
+		default:
+		}
+
+		p.running[id] = true
+		p.workers.Add(1)
+		go p.consume(id)
+	}
+
+	return nil
+}
+
+// parentFinished reports whether a shard's parent has been read to the end.
+// Parents that have expired from the stream no longer hold any records.
+func (p *Provider) parentFinished(parentID *string, listed map[string]bool) bool {
+	id := aws.ToString(parentID)
+
+	return "" == id || !listed[id] || p.finished[id]
+}
+
+func (p *Provider) listShards() ([]types.Shard, error) {
+	shards := []types.Shard{}
+	params := &awskinesis.ListShardsInput{StreamName: aws.String(p.streamName)}
+
+	for {
+		resp, err := p.client.ListShards(p.ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		shards = append(shards, resp.Shards...)
+		if nil == resp.NextToken {
+			return shards, nil
+		}
+
+		// The stream name can't be given along with a token.
+		params = &awskinesis.ListShardsInput{NextToken: resp.NextToken}
+	}
+}
+
+// stopped marks a shard's worker as stopped. Finished shards are never read
+// again; others are picked up by the next sync.
+func (p *Provider) stopped(shardID string, finished bool) {
+	p.mu.Lock()
+	delete(p.running, shardID)
+	if finished {
+		p.finished[shardID] = true
+	}
+	p.mu.Unlock()
+
+	if finished {
+		// Its children may be ready now.
+		select {
+		case p.resync <- true:
+		default:
+		}
+	}
+}
+
+// process delivers an event and waits until it has been deleted, or has
+// been requeued and redelivered until it is. It returns false if the
+// provider was stopped first.
+func (p *Provider) process(event Event) bool {
+	for {
+		event.outcome = make(chan outcome, 1)
+		if !p.deliver(event) {
+			return false
+		}
+
+		var o outcome
+		select {
+		case o = <-event.outcome:
+		case <-p.done:
+			return false
+		}
+
+		if !o.retry {
+			return true
+		}
+
+		p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", event.RetryCount(), o.delay)
+
+		select {
+		case <-p.clock.After(o.delay):
+		case <-p.done:
+			return false
+		}
+
+		if !o.deferred {
+			event.retryCount++
+		}
+	}
+}
+
+// Delete an event that we're done with
+func (p *Provider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to Kinesis flavor
+
+	evt.resolve(outcome{})
+}
+
+// Requeue an event for later. The rest of its shard waits for it.
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to Kinesis flavor
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		// Skip it so the shard can move on.
+		evt.resolve(outcome{})
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	evt.resolve(outcome{retry: true, delay: p.jitter.Apply(evt.Delay())})
+	return nil
+}
+
+// Defer redelivers an event once delay has passed, without counting it as a
+// retry. The rest of its shard waits for it.
+func (p *Provider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to Kinesis flavor
+
+	evt.resolve(outcome{retry: true, delay: delay, deferred: true})
+	return nil
+}
+
+// Stop the channel. Workers checkpoint whatever they had processed before
+// it returns.
+func (p *Provider) Stop() {
+	close(p.done)
+	p.cancel()
+	p.workers.Wait()
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+// LastPolledAt returns when a shard last answered a poll, or zero if it
+// hasn't yet. See gomainevents.Probes.
+func (p *Provider) LastPolledAt() time.Time {
+	p.polledMu.Lock()
+	defer p.polledMu.Unlock()
+
+	return p.polledAt
+}
+
+func (p *Provider) polled() {
+	p.polledMu.Lock()
+	defer p.polledMu.Unlock()
+
+	p.polledAt = time.Now()
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, p.streamName, messageID, err):
+	default:
+	}
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-kinesis] "+format, values...)
+	}
+}
@@ -0,0 +1,273 @@
+package kinesis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskinesis "github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockStream is an in-memory stream. Iterators are "<shard>:<index>".
+type mockStream struct {
+	KinesisAPI
+
+	mu      sync.Mutex
+	shards  []types.Shard
+	records map[string][]types.Record
+	closed  map[string]bool
+}
+
+func newMockStream() *mockStream {
+	return &mockStream{
+		records: make(map[string][]types.Record),
+		closed:  make(map[string]bool),
+	}
+}
+
+func (m *mockStream) addShard(id, parent string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	shard := types.Shard{ShardId: aws.String(id)}
+	if "" != parent {
+		shard.ParentShardId = aws.String(parent)
+	}
+
+	m.shards = append(m.shards, shard)
+}
+
+func (m *mockStream) add(shardID string, names ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range names {
+		data, _ := json.Marshal(map[string]interface{}{"name": name, "data": map[string]interface{}{}})
+		sequence := strconv.Itoa(len(m.records[shardID]) + 1)
+		m.records[shardID] = append(m.records[shardID], types.Record{
+			Data:           data,
+			SequenceNumber: aws.String(sequence),
+			PartitionKey:   aws.String(name),
+		})
+	}
+}
+
+func (m *mockStream) ListShards(ctx context.Context, in *awskinesis.ListShardsInput, optFns ...func(*awskinesis.Options)) (*awskinesis.ListShardsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return &awskinesis.ListShardsOutput{Shards: append([]types.Shard{}, m.shards...)}, nil
+}
+
+func (m *mockStream) GetShardIterator(ctx context.Context, in *awskinesis.GetShardIteratorInput, optFns ...func(*awskinesis.Options)) (*awskinesis.GetShardIteratorOutput, error) {
+	index := 0
+	if in.ShardIteratorType == types.ShardIteratorTypeAfterSequenceNumber {
+		index, _ = strconv.Atoi(aws.ToString(in.StartingSequenceNumber))
+	}
+
+	return &awskinesis.GetShardIteratorOutput{
+		ShardIterator: aws.String(aws.ToString(in.ShardId) + ":" + strconv.Itoa(index)),
+	}, nil
+}
+
+func (m *mockStream) GetRecords(ctx context.Context, in *awskinesis.GetRecordsInput, optFns ...func(*awskinesis.Options)) (*awskinesis.GetRecordsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	parts := strings.SplitN(aws.ToString(in.ShardIterator), ":", 2)
+	shardID := parts[0]
+	index, _ := strconv.Atoi(parts[1])
+
+	records := m.records[shardID][index:]
+	next := aws.String(shardID + ":" + strconv.Itoa(index+len(records)))
+	if m.closed[shardID] {
+		next = nil
+	}
+
+	return &awskinesis.GetRecordsOutput{Records: records, NextShardIterator: next}, nil
+}
+
+func newTestProvider(t *testing.T, stream *mockStream, checkpointer Checkpointer) *Provider {
+	provider, err := NewProvider(&ProviderConfig{
+		KinesisClient:     stream,
+		StreamName:        "events",
+		Checkpointer:      checkpointer,
+		PollInterval:      10 * time.Millisecond,
+		ShardSyncInterval: 50 * time.Millisecond,
+	})
+	require.Nil(t, err)
+
+	return provider
+}
+
+func receive(t *testing.T, events <-chan gomainevents.Event) Event {
+	select {
+	case event := <-events:
+		return event.(Event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	return Event{}
+}
+
+func assertNothingDelivered(t *testing.T, events <-chan gomainevents.Event) {
+	select {
+	case event := <-events:
+		t.Fatalf("Unexpected event: %s", event.Name())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{KinesisClient: newMockStream(), StreamName: "events", Checkpointer: newMemoryCheckpointer()})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{KinesisClient: newMockStream(), StreamName: "events"})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{KinesisClient: newMockStream(), Checkpointer: newMemoryCheckpointer()})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+}
+
+func TestProviderDeliversEachShardInOrder(t *testing.T) {
+	stream := newMockStream()
+	stream.addShard("shard-0", "")
+	stream.add("shard-0", "First", "Second")
+
+	checkpointer := newMemoryCheckpointer()
+	provider := newTestProvider(t, stream, checkpointer)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	first := receive(t, events)
+	assert.Equal(t, "First", first.Name())
+	assert.Equal(t, "shard-0", first.ShardID())
+
+	// The next event waits for the first to be handled
+	assertNothingDelivered(t, events)
+	provider.Delete(first)
+
+	second := receive(t, events)
+	assert.Equal(t, "Second", second.Name())
+	provider.Delete(second)
+
+	assert.Eventually(t, func() bool {
+		checkpoint, _ := checkpointer.Checkpoint("shard-0")
+		return "2" == checkpoint
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestProviderResumesFromCheckpoint(t *testing.T) {
+	stream := newMockStream()
+	stream.addShard("shard-0", "")
+	stream.add("shard-0", "First", "Second")
+
+	checkpointer := newMemoryCheckpointer()
+	checkpointer.SetCheckpoint("shard-0", "1")
+
+	provider := newTestProvider(t, stream, checkpointer)
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	assert.Equal(t, "Second", receive(t, events).Name())
+}
+
+func TestProviderRedeliversRequeuedEvents(t *testing.T) {
+	stream := newMockStream()
+	stream.addShard("shard-0", "")
+	stream.add("shard-0", "First", "Second")
+
+	provider := newTestProvider(t, stream, newMemoryCheckpointer())
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	first := receive(t, events)
+	assert.Nil(t, provider.Requeue(first))
+
+	// Nothing else in the shard overtakes it
+	redelivered := receive(t, events)
+	assert.Equal(t, "First", redelivered.Name())
+	assert.Equal(t, 1, redelivered.RetryCount())
+	provider.Delete(redelivered)
+
+	assert.Equal(t, "Second", receive(t, events).Name())
+}
+
+func TestProviderRedeliversDeferredEvents(t *testing.T) {
+	stream := newMockStream()
+	stream.addShard("shard-0", "")
+	stream.add("shard-0", "First", "Second")
+
+	provider := newTestProvider(t, stream, newMemoryCheckpointer())
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	first := receive(t, events)
+	assert.Nil(t, provider.Defer(first, 10*time.Millisecond))
+
+	// Redelivered ahead of the rest of the shard, without using up a retry
+	redelivered := receive(t, events)
+	assert.Equal(t, "First", redelivered.Name())
+	assert.Equal(t, 0, redelivered.RetryCount())
+	provider.Delete(redelivered)
+
+	assert.Equal(t, "Second", receive(t, events).Name())
+}
+
+func TestProviderSkipsEventsExceedingRetries(t *testing.T) {
+	provider := newTestProvider(t, newMockStream(), newMemoryCheckpointer())
+	event := Event{name: "Thing", retryCount: 26, outcome: make(chan outcome, 1)}
+
+	err := provider.Requeue(event)
+	assert.IsType(t, &RetryAttemptsExceededError{}, err)
+	assert.Equal(t, outcome{}, <-event.outcome)
+}
+
+func TestProviderReadsChildShardsAfterParents(t *testing.T) {
+	stream := newMockStream()
+	stream.addShard("parent", "")
+	stream.addShard("child", "parent")
+	stream.add("parent", "Before")
+	stream.add("child", "After")
+
+	checkpointer := newMemoryCheckpointer()
+	provider := newTestProvider(t, stream, checkpointer)
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	before := receive(t, events)
+	assert.Equal(t, "Before", before.Name())
+	provider.Delete(before)
+
+	// The parent is still open, so the child waits
+	assertNothingDelivered(t, events)
+
+	stream.mu.Lock()
+	stream.closed["parent"] = true
+	stream.mu.Unlock()
+
+	after := receive(t, events)
+	assert.Equal(t, "After", after.Name())
+	assert.Equal(t, "child", after.ShardID())
+
+	checkpoint, _ := checkpointer.Checkpoint("parent")
+	assert.Equal(t, ShardEnd, checkpoint)
+}
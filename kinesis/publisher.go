@@ -0,0 +1,215 @@
+package kinesis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskinesis "github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/researchsquare/gomainevents"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// maxBatchSize is the maximum number of records Kinesis accepts in a single
+// PutRecords call.
+const maxBatchSize = 500
+
+// PartitionKeyFunc returns the partition key for an event. Events with the
+// same key go to the same shard, and are read back in the order they were
+// published.
+type PartitionKeyFunc func(event gomainevents.Event) string
+
+// DataField returns a PartitionKeyFunc that uses the given field of the
+// event's data, e.g. "userId" to keep each user's events in order. Events
+// without the field are keyed by their name.
+func DataField(field string) PartitionKeyFunc {
+	return func(event gomainevents.Event) string {
+		value, ok := event.Data()[field]
+		if !ok || nil == value {
+			return event.Name()
+		}
+
+		return fmt.Sprint(value)
+	}
+}
+
+// eventName is the default PartitionKeyFunc.
+func eventName(event gomainevents.Event) string {
+	return event.Name()
+}
+
+// Publisher puts events on a Kinesis stream.
+type Publisher struct {
+	client       KinesisAPI
+	streamName   string
+	partitionKey PartitionKeyFunc
+	timeout      time.Duration
+}
+
+type PublisherConfig struct {
+	// Provide your own Kinesis client. Default will use the
+	// default AWS config + shared credentials.
+	KinesisClient KinesisAPI
+
+	// Region used by the default client. Defaults to us-east-1. Ignored
+	// when KinesisClient is provided.
+	Region string
+
+	// Endpoint overrides the Kinesis endpoint used by the default client,
+	// e.g. http://localhost:4566 for LocalStack. Ignored when KinesisClient
+	// is provided.
+	Endpoint string
+
+	// Stream to publish to. Required
+	StreamName string
+
+	// Chooses the partition key of each event. Defaults to the event
+	// name, which keeps events of the same kind in order.
+	PartitionKey PartitionKeyFunc
+
+	// Maximum time allowed for a single publish. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+func NewPublisher(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.StreamName {
+		return nil, errors.New("StreamName is required")
+	}
+
+	// Default to a new client using shared credentials
+	client := config.KinesisClient
+	if nil == client {
+		var err error
+		if client, err = newKinesisClient(config.Region, config.Endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	partitionKey := config.PartitionKey
+	if nil == partitionKey {
+		partitionKey = eventName
+	}
+
+	timeout := defaultTimeout
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	return &Publisher{
+		client:       client,
+		streamName:   config.StreamName,
+		partitionKey: partitionKey,
+		timeout:      timeout,
+	}, nil
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	data, err := encodeEvent(event.Name(), event.Data())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	_, err = p.client.PutRecord(ctx, &awskinesis.PutRecordInput{
+		StreamName:   aws.String(p.streamName),
+		PartitionKey: aws.String(p.key(event)),
+		Data:         data,
+	})
+
+	return err
+}
+
+// PublishBatch puts events on the stream using the PutRecords API, sending
+// up to 500 events per call. Every chunk is attempted; if any event fails, a
+// *BatchPublishError listing the failed events is returned.
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	failures := []BatchFailure{}
+
+	for start := 0; start < len(events); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(events) {
+			end = len(events)
+		}
+
+		failures = append(failures, p.publishChunk(events[start:end])...)
+	}
+
+	if len(failures) > 0 {
+		return &BatchPublishError{Failures: failures}
+	}
+
+	return nil
+}
+
+func (p *Publisher) publishChunk(events []gomainevents.Event) []BatchFailure {
+	failures := []BatchFailure{}
+	entries := []types.PutRecordsRequestEntry{}
+	sent := []gomainevents.Event{}
+
+	for _, event := range events {
+		data, err := encodeEvent(event.Name(), event.Data())
+		if err != nil {
+			failures = append(failures, BatchFailure{Event: event, Err: err})
+			continue
+		}
+
+		sent = append(sent, event)
+		entries = append(entries, types.PutRecordsRequestEntry{
+			PartitionKey: aws.String(p.key(event)),
+			Data:         data,
+		})
+	}
+
+	if len(entries) == 0 {
+		return failures
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	resp, err := p.client.PutRecords(ctx, &awskinesis.PutRecordsInput{
+		StreamName: aws.String(p.streamName),
+		Records:    entries,
+	})
+	if err != nil {
+		for _, event := range sent {
+			failures = append(failures, BatchFailure{Event: event, Err: err})
+		}
+
+		return failures
+	}
+
+	// Results are in the same order as the records that were sent.
+	for i, result := range resp.Records {
+		if nil == result.ErrorCode || i >= len(sent) {
+			continue
+		}
+
+		failures = append(failures, BatchFailure{
+			Event:   sent[i],
+			Code:    aws.ToString(result.ErrorCode),
+			Message: aws.ToString(result.ErrorMessage),
+		})
+	}
+
+	return failures
+}
+
+// key returns the partition key for an event. Kinesis rejects empty keys.
+func (p *Publisher) key(event gomainevents.Event) string {
+	if key := p.partitionKey(event); "" != key {
+		return key
+	}
+
+	return event.Name()
+}
@@ -0,0 +1,157 @@
+package kinesis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskinesis "github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	if nil == e.data {
+		return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+	}
+
+	return e.data
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(&PublisherConfig{KinesisClient: newMockKinesis(), StreamName: "events"})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{KinesisClient: newMockKinesis()})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestPublish(t *testing.T) {
+	client := newMockKinesis()
+	publisher, err := NewPublisher(&PublisherConfig{KinesisClient: client, StreamName: "events", PartitionKey: DataField("userId")})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing", data: map[string]interface{}{"userId": 12}}))
+	require.Nil(t, publisher.Publish(testEvent{name: "Other"}))
+
+	require.Len(t, client.put, 2)
+	assert.Equal(t, "events", aws.ToString(client.put[0].StreamName))
+	assert.Equal(t, "12", aws.ToString(client.put[0].PartitionKey))
+	assert.JSONEq(t, `{"name":"Thing","data":{"userId":12}}`, string(client.put[0].Data))
+
+	// Events without the field fall back to their name
+	assert.Equal(t, "Other", aws.ToString(client.put[1].PartitionKey))
+}
+
+func TestPublishBatch(t *testing.T) {
+	client := newMockKinesis()
+	client.failKeys = map[string]bool{"Bad": true}
+
+	publisher, err := NewPublisher(&PublisherConfig{KinesisClient: client, StreamName: "events"})
+	require.Nil(t, err)
+
+	events := []gomainevents.Event{}
+	for i := 0; i < 501; i++ {
+		events = append(events, testEvent{name: "Thing"})
+	}
+	events = append(events, testEvent{name: "Bad"})
+
+	err = publisher.PublishBatch(events)
+	require.IsType(t, &BatchPublishError{}, err)
+
+	failures := err.(*BatchPublishError).Failures
+	require.Len(t, failures, 1)
+	assert.Equal(t, "Bad", failures[0].Event.Name())
+	assert.Equal(t, "ProvisionedThroughputExceededException", failures[0].Code)
+
+	require.Len(t, client.batches, 2)
+	assert.Len(t, client.batches[0].Records, 500)
+	assert.Len(t, client.batches[1].Records, 2)
+
+	// A failed request fails every event in it
+	client.requestError = errors.New("Oops")
+	err = publisher.PublishBatch(events[:2])
+	require.IsType(t, &BatchPublishError{}, err)
+	assert.Len(t, err.(*BatchPublishError).Failures, 2)
+}
+
+func TestDecodeEvent(t *testing.T) {
+	data, err := json.Marshal(map[string]interface{}{"name": "Thing", "data": map[string]interface{}{"userId": 12.0}})
+	require.Nil(t, err)
+
+	event, err := DecodeEvent("shard-0", types.Record{
+		Data:           data,
+		SequenceNumber: aws.String("42"),
+		PartitionKey:   aws.String("12"),
+	})
+	require.Nil(t, err)
+
+	assert.Equal(t, "Thing", event.Name())
+	assert.Equal(t, map[string]interface{}{"userId": 12.0}, event.Data())
+	assert.Equal(t, "shard-0", event.ShardID())
+	assert.Equal(t, "42", event.SequenceNumber())
+	assert.Equal(t, "12", event.PartitionKey())
+
+	_, err = DecodeEvent("shard-0", types.Record{Data: []byte("nope")})
+	assert.NotNil(t, err)
+}
+
+// mockKinesis records what is published to it.
+type mockKinesis struct {
+	KinesisAPI
+
+	put          []*awskinesis.PutRecordInput
+	batches      []*awskinesis.PutRecordsInput
+	failKeys     map[string]bool
+	requestError error
+}
+
+func newMockKinesis() *mockKinesis {
+	return &mockKinesis{}
+}
+
+func (m *mockKinesis) PutRecord(ctx context.Context, in *awskinesis.PutRecordInput, optFns ...func(*awskinesis.Options)) (*awskinesis.PutRecordOutput, error) {
+	m.put = append(m.put, in)
+	return &awskinesis.PutRecordOutput{ShardId: aws.String("shard-0"), SequenceNumber: aws.String("1")}, nil
+}
+
+func (m *mockKinesis) PutRecords(ctx context.Context, in *awskinesis.PutRecordsInput, optFns ...func(*awskinesis.Options)) (*awskinesis.PutRecordsOutput, error) {
+	m.batches = append(m.batches, in)
+	if nil != m.requestError {
+		return nil, m.requestError
+	}
+
+	out := &awskinesis.PutRecordsOutput{}
+	for _, record := range in.Records {
+		if m.failKeys[aws.ToString(record.PartitionKey)] {
+			out.Records = append(out.Records, types.PutRecordsResultEntry{
+				ErrorCode:    aws.String("ProvisionedThroughputExceededException"),
+				ErrorMessage: aws.String("Slow down"),
+			})
+			continue
+		}
+
+		out.Records = append(out.Records, types.PutRecordsResultEntry{SequenceNumber: aws.String("1")})
+	}
+
+	return out, nil
+}
@@ -0,0 +1,168 @@
+package kinesis
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskinesis "github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/researchsquare/gomainevents"
+)
+
+// shardWorker reads a single shard in order.
+type shardWorker struct {
+	provider *Provider
+	id       string
+	iterator string
+
+	// Sequence number of the last record processed, and of the last one
+	// checkpointed.
+	processed    string
+	checkpointed string
+}
+
+// consume reads the shard until it ends or the provider is stopped.
+func (p *Provider) consume(shardID string) {
+	defer p.workers.Done()
+
+	w := &shardWorker{provider: p, id: shardID}
+	p.stopped(shardID, w.run())
+}
+
+// run returns true once the shard has been read to the end.
+func (w *shardWorker) run() bool {
+	p := w.provider
+
+	checkpoint, err := p.checkpointer.Checkpoint(w.id)
+	if err != nil {
+		p.reportError(gomainevents.PhaseConnect, "", err)
+		return false
+	}
+
+	if ShardEnd == checkpoint {
+		return true
+	}
+
+	w.processed, w.checkpointed = checkpoint, checkpoint
+	defer w.checkpoint()
+
+	if err := w.refreshIterator(); err != nil {
+		p.reportError(gomainevents.PhaseConnect, "", err)
+		return false
+	}
+
+	for {
+		resp, err := p.client.GetRecords(p.ctx, &awskinesis.GetRecordsInput{
+			ShardIterator: aws.String(w.iterator),
+			Limit:         aws.Int32(p.batchSize),
+		})
+
+		if err != nil {
+			if nil != p.ctx.Err() {
+				return false
+			}
+
+			p.reportError(gomainevents.PhaseReceive, "", err)
+
+			// Iterators expire after five minutes, e.g. while an event
+			// is waiting to be retried.
+			var expired *types.ExpiredIteratorException
+			if errors.As(err, &expired) {
+				if err := w.refreshIterator(); err != nil {
+					p.reportError(gomainevents.PhaseReceive, "", err)
+				}
+			}
+
+			if !w.wait() {
+				return false
+			}
+			continue
+		}
+
+		p.polled()
+
+		for _, record := range resp.Records {
+			event, err := DecodeEvent(w.id, record)
+			if err != nil {
+				// It will never decode, so skip it.
+				p.reportError(gomainevents.PhaseDecode, aws.ToString(record.SequenceNumber), err)
+				w.processed = aws.ToString(record.SequenceNumber)
+				continue
+			}
+
+			if !p.process(*event) {
+				return false
+			}
+
+			w.processed = event.SequenceNumber()
+		}
+
+		w.checkpoint()
+
+		// The shard was closed by a reshard and has no more records.
+		if nil == resp.NextShardIterator {
+			if err := p.checkpointer.SetCheckpoint(w.id, ShardEnd); err != nil {
+				p.reportError(gomainevents.PhaseDelete, "", err)
+			}
+
+			w.checkpointed = ShardEnd
+			return true
+		}
+
+		w.iterator = aws.ToString(resp.NextShardIterator)
+
+		if len(resp.Records) == 0 && !w.wait() {
+			return false
+		}
+	}
+}
+
+// refreshIterator gets an iterator that starts after the last processed
+// record.
+func (w *shardWorker) refreshIterator() error {
+	p := w.provider
+
+	params := &awskinesis.GetShardIteratorInput{
+		StreamName:        aws.String(p.streamName),
+		ShardId:           aws.String(w.id),
+		ShardIteratorType: p.initialPosition,
+	}
+
+	if "" != w.processed {
+		params.ShardIteratorType = types.ShardIteratorTypeAfterSequenceNumber
+		params.StartingSequenceNumber = aws.String(w.processed)
+	}
+
+	resp, err := p.client.GetShardIterator(p.ctx, params)
+	if err != nil {
+		return err
+	}
+
+	w.iterator = aws.ToString(resp.ShardIterator)
+	return nil
+}
+
+// checkpoint records progress, if there is any since the last time.
+func (w *shardWorker) checkpoint() {
+	if w.processed == w.checkpointed || ShardEnd == w.checkpointed {
+		return
+	}
+
+	if err := w.provider.checkpointer.SetCheckpoint(w.id, w.processed); err != nil {
+		w.provider.reportError(gomainevents.PhaseDelete, w.processed, err)
+		return
+	}
+
+	w.checkpointed = w.processed
+}
+
+// wait pauses before reading again, returning false if the provider was
+// stopped.
+func (w *shardWorker) wait() bool {
+	select {
+	case <-w.provider.done:
+		return false
+	case <-w.provider.clock.After(w.provider.pollInterval):
+		return true
+	}
+}
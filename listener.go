@@ -1,7 +1,12 @@
 package gomainevents
 
 import (
+	"context"
+	"errors"
 	"log"
+	"sort"
+	"sync"
+	"time"
 )
 
 // EventHandler is a function responsible for processing an event.
@@ -16,7 +21,9 @@ type ErrorHandler func(error)
 // accumulates events and emits them via a channel for the Listener.
 // The channel should be held open for as long as Listener is listening.
 type Provider interface {
-	// Return a channel that can be used to retrieve events
+	// Return a channel that can be used to retrieve events, and one for
+	// errors, which should be *ProviderError. The Listener passes errors on
+	// to its ErrorHandler.
 	Start() (<-chan Event, <-chan error)
 
 	// Delete an event that we're done with
@@ -34,6 +41,13 @@ type RequeuingEventFailedError interface {
 	Error() string
 }
 
+// ErrProviderClosed is returned by Listen when the provider closes its
+// events channel without being stopped.
+var ErrProviderClosed = errors.New("Provider stopped delivering events")
+
+// ErrNoHandlers is returned by Listen when no handlers are registered.
+var ErrNoHandlers = errors.New("No handlers are registered")
+
 // Listener receives events and passes them to the registered event
 // handlers. The events are provided by a Provider via a channel.
 type Listener struct {
@@ -42,13 +56,79 @@ type Listener struct {
 	done         chan bool
 	debug        bool
 	errorHandler ErrorHandler
+
+	// Run on events before they're handled. See RegisterTransformer.
+	transformers []Transformer
+
+	// Where events that are given up on are described. See
+	// RegisterDeadLetterSink.
+	deadLetterSink DeadLetterSink
+	attempts       *attemptLog
+	consumer       Consumer
+	clock          Clock
+
+	// Workers handling events, and a channel closed once they've all
+	// finished after the Listener stopped.
+	workers  sync.WaitGroup
+	stopped  chan bool
+	stopOnce sync.Once
+
+	// Receives the first fatal error from the provider.
+	fatal chan error
+
+	// Log events instead of handling them. See NewDryRunListener.
+	dryRun bool
+
+	// Events whose handlers are turned off. See Disable.
+	disabledMu sync.RWMutex
+	disabled   map[string]bool
+
+	statsMu sync.Mutex
+	stats   ListenerStats
+
+	// When the events being handled were received, by handling ID. See
+	// Probes.
+	handlingMu sync.Mutex
+	handling   map[uint64]time.Time
+	nextID     uint64
+}
+
+// ListenerStats are counts of what a Listener has done since it started
+// listening.
+type ListenerStats struct {
+	StartedAt time.Time
+
+	// Events received from the provider, and of those, the ones handled
+	// and the ones whose handlers failed.
+	Received  int64
+	Processed int64
+	Failed    int64
+
+	// Events deferred, or left for the provider to redeliver, because
+	// they're disabled. See Disable.
+	Disabled int64
+
+	// Events handlers deferred, e.g. because load was being shed. See
+	// DeferredError.
+	Deferred int64
+
+	// Zero until the first event.
+	LastReceivedAt  time.Time
+	LastProcessedAt time.Time
 }
 
 func NewListener(provider Provider) *Listener {
 	return &Listener{
 		provider: provider,
 		handlers: make(map[string][]EventHandler),
+		disabled: make(map[string]bool),
+		handling: make(map[uint64]time.Time),
 		done:     make(chan bool, 1),
+		stopped:  make(chan bool),
+		fatal:    make(chan error, 1),
+		attempts: newAttemptLog(),
+		consumer: currentConsumer(),
+		clock:    SystemClock,
 		debug:    true,
 	}
 }
@@ -57,104 +137,403 @@ func (l *Listener) RegisterHandler(name string, fn EventHandler) {
 	l.handlers[name] = append(l.handlers[name], fn)
 }
 
+// RegisteredEvents returns the names of the events handlers are registered
+// for, sorted.
+func (l *Listener) RegisteredEvents() []string {
+	names := make([]string, 0, len(l.handlers))
+	for name := range l.handlers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Handlers returns the handlers registered for an event, in the order they
+// run.
+func (l *Listener) Handlers(name string) []EventHandler {
+	return append([]EventHandler{}, l.handlers[name]...)
+}
+
+// Disable turns off the handlers for an event until Enable is called, e.g.
+// because they're misbehaving. Events of that type are still received, but
+// aren't handled. Providers that implement DeferringProvider deliver them
+// again a minute later without using up their retries. Others, and those
+// whose Defer fails, get neither Delete nor Requeue: providers that
+// redeliver unfinished events, e.g. once a lease runs out, deliver them
+// again, but the rest never do, and those that deliver in order hold up
+// everything behind them for good. Other events are unaffected.
+func (l *Listener) Disable(name string) {
+	l.disabledMu.Lock()
+	defer l.disabledMu.Unlock()
+
+	l.disabled[name] = true
+}
+
+// Enable turns the handlers for an event back on.
+func (l *Listener) Enable(name string) {
+	l.disabledMu.Lock()
+	defer l.disabledMu.Unlock()
+
+	delete(l.disabled, name)
+}
+
+// Disabled returns whether the handlers for an event are turned off.
+func (l *Listener) Disabled(name string) bool {
+	l.disabledMu.RLock()
+	defer l.disabledMu.RUnlock()
+
+	return l.disabled[name]
+}
+
+// DisabledEvents returns the names of the events that are disabled, sorted.
+func (l *Listener) DisabledEvents() []string {
+	l.disabledMu.RLock()
+	defer l.disabledMu.RUnlock()
+
+	names := make([]string, 0, len(l.disabled))
+	for name := range l.disabled {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// RegisterErrorHandler sets the function errors are passed to: errors
+// returned by event handlers, and errors from the provider, which are
+// always *ProviderError.
 func (l *Listener) RegisterErrorHandler(fn ErrorHandler) {
 	l.errorHandler = fn
 }
 
-func (l *Listener) Listen() {
+// Stats returns what the Listener has done so far.
+func (l *Listener) Stats() ListenerStats {
+	l.statsMu.Lock()
+	defer l.statsMu.Unlock()
+
+	return l.stats
+}
+
+// Listen receives events and passes them to their handlers until the
+// Listener is shut down, and returns nil once the events already received
+// have been handled. See Shutdown. It returns an error if the provider
+// fails: the *ProviderError if it reports a fatal one, or
+// ErrProviderClosed if it stops delivering events on its own. It returns
+// ErrNoHandlers straight away if no handlers are registered.
+func (l *Listener) Listen() error {
+	if len(l.handlers) == 0 {
+		return ErrNoHandlers
+	}
+
+	l.statsMu.Lock()
+	l.stats.StartedAt = time.Now()
+	l.statsMu.Unlock()
+
 	// Initialize our provider
 	events, errors := l.provider.Start()
-	workers, max := 0, len(l.handlers)*4
-
-	// Channel for notifying parent listener that a worker is done and needs
-	// to be restarted.
-	workerDone := make(chan bool, max)
+	max := len(l.handlers) * 4
 
 	l.debugPrint("Domain events processed using %d handlers\n", max)
 
 	// Start our workers
 	for i := 0; i < max; i++ {
-		go func() {
-			defer func() { workers-- }()
-
-			workers++
-			l.worker(events, errors, workerDone)
-			l.debugPrint("Worker closed\n")
-		}()
+		l.startWorker(events, errors)
 	}
 
+	finished := make(chan bool)
+	go func() {
+		l.workers.Wait()
+		close(finished)
+	}()
+
 	// Start listening!
-	for {
-		select {
-		case <-l.done:
-			l.debugPrint("Halting...")
-			l.provider.Stop()
-			return
-		case <-workerDone:
-			if workers < max {
-				l.debugPrint("Restarting worker...\n")
-				go func() {
-					defer func() { workers-- }()
-
-					workers++
-					l.worker(events, errors, workerDone)
-				}()
-			}
-		}
+	var err error
+	select {
+	case <-l.done:
+		l.debugPrint("Halting...\n")
+		l.provider.Stop()
+	case err = <-l.fatal:
+		l.debugPrint("Halting after fatal error: %s\n", err)
+		l.provider.Stop()
+	case <-finished:
+		l.debugPrint("Event provider closed.\n")
+		err = ErrProviderClosed
 	}
 
+	// Stopping closes the events channel, so the workers finish once
+	// they've handled the events already received.
+	<-finished
+	close(l.stopped)
 	l.debugPrint("finished\n")
+
+	return err
+}
+
+// ListenContext is Listen, but shuts the Listener down when ctx is done,
+// waiting up to drainTimeout for the events already received to be
+// handled. It returns a *ShutdownTimeoutError if they weren't. It suits
+// errgroup and other supervisors:
+//
+//	g, ctx := errgroup.WithContext(ctx)
+//	g.Go(func() error {
+//		return listener.ListenContext(ctx, 30*time.Second)
+//	})
+func (l *Listener) ListenContext(ctx context.Context, drainTimeout time.Duration) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- l.Listen()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if err := l.Shutdown(drainCtx); err != nil {
+		return &ShutdownTimeoutError{Timeout: drainTimeout}
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-drainCtx.Done():
+		return &ShutdownTimeoutError{Timeout: drainTimeout}
+	}
+}
+
+// Shutdown stops the provider and waits for the events already received to
+// be handled, or for ctx to be done, in which case it returns ctx's error
+// and the remaining handlers are left running. Events that are neither
+// deleted nor requeued are redelivered by providers that support it, e.g.
+// once the visibility timeout runs out for SQS. If Listen hasn't been
+// called yet, it returns as soon as it is.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	l.stopOnce.Do(func() {
+		l.done <- true
+	})
+
+	if l.Stats().StartedAt.IsZero() {
+		return nil
+	}
+
+	select {
+	case <-l.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-func (l *Listener) worker(events <-chan Event, errors <-chan error, workerDone chan bool) {
+// startWorker starts a worker, which is restarted whenever a handler fails.
+func (l *Listener) startWorker(events <-chan Event, errors <-chan error) {
+	l.workers.Add(1)
+	go func() {
+		defer l.workers.Done()
+
+		for l.worker(events, errors) {
+			l.debugPrint("Restarting worker...\n")
+		}
+
+		l.debugPrint("Worker closed\n")
+	}()
+}
+
+// worker handles events until the provider closes its events channel. It
+// returns true if it stopped because a handler failed.
+func (l *Listener) worker(events <-chan Event, errors <-chan error) bool {
 	for {
 		select {
+		case err, ok := <-errors:
+			if !ok {
+				// Keep going until the events stop
+				errors = nil
+				continue
+			}
+
+			l.handleProviderError(err)
 		case event, ok := <-events:
 			if !ok {
-				l.debugPrint("Event provider closed.\n")
-				return
+				return false
 			}
 
-			l.debugPrint("Received event: %s %+v\n", event.Name(), event.Data())
+			// Data can be expensive to build, so only if it's logged.
+			if l.debug {
+				l.debugPrint("Received event: %s %+v\n", event.Name(), event.Data())
+			}
+			l.count(func(stats *ListenerStats) {
+				stats.Received++
+				stats.LastReceivedAt = time.Now()
+			})
+
+			if l.dryRun {
+				l.dryRunEvent(event)
+				continue
+			}
+
+			if l.Disabled(event.Name()) {
+				l.count(func(stats *ListenerStats) { stats.Disabled++ })
+				if !l.deferEvent(event, disabledEventDelay) {
+					l.debugPrint("Event disabled, leaving it to be redelivered.\n")
+				}
+				continue
+			}
 
 			// Pass the event to a handler
-			if err := l.handleEvent(event); err != nil {
+			id := l.startHandling()
+			handler, err := l.handleEvent(event)
+			l.finishHandling(id)
+
+			if deferred, ok := asDeferred(err); ok && l.deferEvent(event, deferred.Delay) {
+				l.count(func(stats *ListenerStats) { stats.Deferred++ })
+				continue
+			}
+
+			if err != nil {
 				l.debugPrint("Error: %s\n", err)
+				l.count(func(stats *ListenerStats) { stats.Failed++ })
 				if l.errorHandler != nil {
 					l.errorHandler(err)
 				}
 
-				err := l.provider.Requeue(event)
-				if err != nil && l.errorHandler != nil {
-					l.errorHandler(err)
-				}
-
-				workerDone <- true
-
-				return
+				l.failEvent(event, handler, err)
+				return true
 			}
 
 			// If there were no errors, we're done with event. We can delete it.
+			if nil != l.deadLetterSink {
+				l.attempts.forget(event)
+			}
 			l.provider.Delete(event)
 			l.debugPrint("Successfully processed.\n")
+			l.count(func(stats *ListenerStats) {
+				stats.Processed++
+				stats.LastProcessedAt = time.Now()
+			})
 		}
 	}
 }
 
-func (l *Listener) handleEvent(event Event) error {
+// handleProviderError passes an error from the provider on to the error
+// handler, filling in what it can for providers that send plain errors.
+func (l *Listener) handleProviderError(err error) {
+	providerErr := NewProviderError(PhaseUnknown, "", "", err)
+
+	if nil != l.errorHandler {
+		l.errorHandler(providerErr)
+	}
+
+	if providerErr.Fatal {
+		select {
+		case l.fatal <- providerErr:
+		default:
+		}
+	}
+}
+
+// failEvent requeues an event a handler failed, or dead-letters it if it
+// can't be retried.
+func (l *Listener) failEvent(event Event, handler string, err error) {
+	var attempts []Attempt
+	if nil != l.deadLetterSink {
+		attempts = l.attempts.record(event, handler, err, l.clock.Now())
+
+		if IsPermanent(err) && l.deadLetter(event, ReasonPermanentError, handler, err, attempts) {
+			l.attempts.forget(event)
+			l.provider.Delete(event)
+			return
+		}
+	}
+
+	requeueErr := l.provider.Requeue(event)
+	if requeueErr == nil {
+		return
+	}
+
+	if l.errorHandler != nil {
+		l.errorHandler(requeueErr)
+	}
+
+	if exhausted, ok := requeueErr.(RetriesExhaustedError); ok && exhausted.RetryAttemptsExceeded() && nil != l.deadLetterSink {
+		if l.deadLetter(event, ReasonRetriesExhausted, handler, err, attempts) {
+			l.attempts.forget(event)
+		}
+	}
+}
+
+// handleEvent passes an event to its handlers, returning the name of the
+// one that failed, if one did.
+func (l *Listener) handleEvent(event Event) (string, error) {
+	if _, ok := l.handlers[event.Name()]; !ok {
+		l.debugPrint("No handler registered for event.\n")
+		return "", nil
+	}
+
+	event, err := l.transform(event)
+	if err != nil {
+		return "", err
+	}
+
 	handlers, ok := l.handlers[event.Name()]
 	if !ok {
-		l.debugPrint("No handler registered for event.\n")
-		return nil
+		l.debugPrint("No handler registered for transformed event: %s\n", event.Name())
+		return "", nil
 	}
 
 	for _, fn := range handlers {
 		if err := fn(event); err != nil {
-			return err
+			return handlerName(fn), err
 		}
 	}
 
-	return nil
+	return "", nil
+}
+
+// startHandling records that an event is being handled, returning an ID to
+// pass to finishHandling.
+func (l *Listener) startHandling() uint64 {
+	l.handlingMu.Lock()
+	defer l.handlingMu.Unlock()
+
+	l.nextID++
+	l.handling[l.nextID] = time.Now()
+
+	return l.nextID
+}
+
+func (l *Listener) finishHandling(id uint64) {
+	l.handlingMu.Lock()
+	defer l.handlingMu.Unlock()
+
+	delete(l.handling, id)
+}
+
+// handlingSince returns when the longest running handler started, or zero
+// if none are running.
+func (l *Listener) handlingSince() time.Time {
+	l.handlingMu.Lock()
+	defer l.handlingMu.Unlock()
+
+	oldest := time.Time{}
+	for _, started := range l.handling {
+		if oldest.IsZero() || started.Before(oldest) {
+			oldest = started
+		}
+	}
+
+	return oldest
+}
+
+// count updates the stats.
+func (l *Listener) count(fn func(*ListenerStats)) {
+	l.statsMu.Lock()
+	defer l.statsMu.Unlock()
+
+	fn(&l.stats)
 }
 
 func (l *Listener) debugPrint(format string, values ...interface{}) {
@@ -0,0 +1,16 @@
+package loadshed
+
+import (
+	"fmt"
+)
+
+// ShedError is returned, wrapped in a gomainevents.DeferredError, for an
+// event that was shed rather than handled. Error handlers only see it when
+// the event couldn't be deferred, and can leave it out of error reports.
+type ShedError struct {
+	EventName string
+}
+
+func (e *ShedError) Error() string {
+	return fmt.Sprintf("Shed event during overload: %s", e.EventName)
+}
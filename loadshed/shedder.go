@@ -0,0 +1,156 @@
+package loadshed
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultDeferFor = 30 * time.Second
+
+	// How long the age of the latest event handled counts for. Without it
+	// shedding would never stop if only low-priority events were left.
+	ageLifetime = 10 * time.Second
+)
+
+// Shedder keeps critical events flowing during an overload by not handling
+// low-priority ones. Wrap every handler with it, not only the low-priority
+// ones, so that it sees all the load:
+//
+//	listener.RegisterHandler("PaymentReceived", shedder.Handler(handlePayment))
+//	listener.RegisterHandler("ProfileViewed", shedder.Handler(handleView))
+//
+// It is overloaded while MaxInFlight handlers are running, or while the
+// events being handled are older than MaxAge, i.e. consumers are behind.
+// An event's age counts for 10 seconds after it is handled, so shedding
+// stops when nothing else is being handled.
+// Low-priority events received then aren't handled. They fail with a
+// *ShedError marked as gomainevents.Deferred, so the Listener defers them
+// without using up their retries, or requeues them on providers that can't
+// defer events.
+type Shedder struct {
+	maxInFlight int
+	maxAge      time.Duration
+	lowPriority map[string]bool
+	deferFor    time.Duration
+	onShed      func(gomainevents.Event)
+
+	mu       sync.Mutex
+	inFlight int
+
+	// Age of the latest event handled, and when it was handled.
+	age   time.Duration
+	ageAt time.Time
+
+	// Hook for tests
+	now func() time.Time
+}
+
+type Config struct {
+	// Number of events being handled at once at which low-priority events
+	// are shed. Zero leaves it unchecked.
+	MaxInFlight int
+
+	// Age of the latest event handled at which low-priority events are
+	// shed. See gomainevents.OccurredAt for how an event's age is worked
+	// out. Zero leaves it unchecked.
+	MaxAge time.Duration
+
+	// Names of the events that are shed. Required
+	LowPriority []string
+
+	// How long shed events are deferred for before they're delivered
+	// again. Defaults to 30 seconds.
+	Defer time.Duration
+
+	// Called for each event that is shed.
+	OnShed func(gomainevents.Event)
+}
+
+func NewShedder(config *Config) (*Shedder, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if len(config.LowPriority) == 0 {
+		return nil, errors.New("LowPriority is required")
+	}
+
+	if config.MaxInFlight <= 0 && config.MaxAge <= 0 {
+		return nil, errors.New("MaxInFlight or MaxAge is required")
+	}
+
+	lowPriority := map[string]bool{}
+	for _, name := range config.LowPriority {
+		lowPriority[name] = true
+	}
+
+	deferFor := defaultDeferFor
+	if config.Defer > 0 {
+		deferFor = config.Defer
+	}
+
+	return &Shedder{
+		maxInFlight: config.MaxInFlight,
+		maxAge:      config.MaxAge,
+		lowPriority: lowPriority,
+		deferFor:    deferFor,
+		onShed:      config.OnShed,
+		now:         time.Now,
+	}, nil
+}
+
+// Handler wraps an EventHandler, shedding low-priority events while the
+// Shedder is overloaded.
+func (s *Shedder) Handler(fn gomainevents.EventHandler) gomainevents.EventHandler {
+	return func(event gomainevents.Event) error {
+		if s.lowPriority[event.Name()] && s.Overloaded() {
+			if nil != s.onShed {
+				s.onShed(event)
+			}
+
+			return gomainevents.Deferred(&ShedError{EventName: event.Name()}, s.deferFor)
+		}
+
+		s.observe(event)
+
+		s.mu.Lock()
+		s.inFlight++
+		s.mu.Unlock()
+
+		defer func() {
+			s.mu.Lock()
+			s.inFlight--
+			s.mu.Unlock()
+		}()
+
+		return fn(event)
+	}
+}
+
+// Overloaded reports whether low-priority events are being shed.
+func (s *Shedder) Overloaded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return (s.maxInFlight > 0 && s.inFlight >= s.maxInFlight) ||
+		(s.maxAge > 0 && s.age >= s.maxAge && s.now().Sub(s.ageAt) < ageLifetime)
+}
+
+// observe records the age of an event that is being handled.
+func (s *Shedder) observe(event gomainevents.Event) {
+	now := s.now()
+
+	age, ok := gomainevents.EventAge(event, now)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.age, s.ageAt = age, now
+}
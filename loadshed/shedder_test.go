@@ -0,0 +1,152 @@
+package loadshed
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return e.data
+}
+
+func TestNewShedder(t *testing.T) {
+	_, err := NewShedder(nil)
+	assert.EqualError(t, err, "Configuration is required")
+
+	_, err = NewShedder(&Config{MaxInFlight: 1})
+	assert.EqualError(t, err, "LowPriority is required")
+
+	_, err = NewShedder(&Config{LowPriority: []string{"ProfileViewed"}})
+	assert.EqualError(t, err, "MaxInFlight or MaxAge is required")
+}
+
+func TestShedderMaxInFlight(t *testing.T) {
+	shed := []string{}
+	shedder, err := NewShedder(&Config{
+		MaxInFlight: 1,
+		LowPriority: []string{"ProfileViewed"},
+		OnShed:      func(event gomainevents.Event) { shed = append(shed, event.Name()) },
+	})
+	assert.NoError(t, err)
+
+	started, release := make(chan bool), make(chan bool)
+	critical := shedder.Handler(func(gomainevents.Event) error {
+		started <- true
+		<-release
+		return nil
+	})
+
+	handled := 0
+	lowPriority := shedder.Handler(func(gomainevents.Event) error {
+		handled++
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, critical(testEvent{name: "PaymentReceived"}))
+	}()
+	<-started
+
+	assert.True(t, shedder.Overloaded())
+
+	err = lowPriority(testEvent{name: "ProfileViewed"})
+	var shedErr *ShedError
+	assert.True(t, errors.As(err, &shedErr))
+	assert.EqualError(t, err, "Shed event during overload: ProfileViewed")
+	assert.Equal(t, []string{"ProfileViewed"}, shed)
+	assert.Equal(t, 0, handled)
+
+	// Events that aren't low-priority are always handled.
+	assert.NoError(t, lowPriority(testEvent{name: "PaymentRefunded"}))
+	assert.Equal(t, 1, handled)
+
+	close(release)
+	wg.Wait()
+
+	assert.False(t, shedder.Overloaded())
+	assert.NoError(t, lowPriority(testEvent{name: "ProfileViewed"}))
+	assert.Equal(t, 2, handled)
+}
+
+func TestShedderDefer(t *testing.T) {
+	shedder, _ := NewShedder(&Config{
+		MaxInFlight: 1,
+		LowPriority: []string{"ProfileViewed"},
+	})
+	assert.Equal(t, defaultDeferFor, shedder.deferFor)
+
+	shedder, _ = NewShedder(&Config{
+		MaxInFlight: 1,
+		LowPriority: []string{"ProfileViewed"},
+		Defer:       time.Minute,
+	})
+
+	started, release := make(chan bool), make(chan bool)
+	go shedder.Handler(func(gomainevents.Event) error {
+		started <- true
+		<-release
+		return nil
+	})(testEvent{name: "PaymentReceived"})
+	<-started
+	defer close(release)
+
+	// Shed events are deferred rather than waited on
+	handled := false
+	err := shedder.Handler(func(gomainevents.Event) error {
+		handled = true
+		return nil
+	})(testEvent{name: "ProfileViewed"})
+
+	var deferred *gomainevents.DeferredError
+	assert.True(t, errors.As(err, &deferred))
+	assert.Equal(t, time.Minute, deferred.Delay)
+	assert.False(t, handled)
+}
+
+func TestShedderMaxAge(t *testing.T) {
+	now := time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+
+	shedder, _ := NewShedder(&Config{
+		MaxAge:      time.Minute,
+		LowPriority: []string{"ProfileViewed"},
+	})
+	shedder.now = func() time.Time { return now }
+
+	handler := shedder.Handler(func(gomainevents.Event) error { return nil })
+	occurredOn := func(ago time.Duration) map[string]interface{} {
+		return map[string]interface{}{"occurredOn": now.Add(-ago).Format(time.RFC3339)}
+	}
+
+	assert.NoError(t, handler(testEvent{name: "ProfileViewed", data: occurredOn(2 * time.Minute)}))
+	assert.True(t, shedder.Overloaded())
+
+	assert.Error(t, handler(testEvent{name: "ProfileViewed", data: occurredOn(time.Second)}))
+
+	// Handling a fresh event ends it.
+	assert.NoError(t, handler(testEvent{name: "PaymentReceived", data: occurredOn(time.Second)}))
+	assert.False(t, shedder.Overloaded())
+
+	// So does time passing without anything being handled.
+	assert.NoError(t, handler(testEvent{name: "PaymentReceived", data: occurredOn(5 * time.Minute)}))
+	assert.True(t, shedder.Overloaded())
+
+	now = now.Add(ageLifetime)
+	assert.False(t, shedder.Overloaded())
+}
@@ -0,0 +1,173 @@
+package gomainevents
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redacted replaces the values of redacted fields.
+const redacted = "[REDACTED]"
+
+// ANSI escape codes used when color is enabled.
+const (
+	colorReset = "\x1b[0m"
+	colorDim   = "\x1b[2m"
+	colorName  = "\x1b[1;36m"
+)
+
+// LogPublisher pretty-prints events to a writer. It is meant for local
+// development, on its own or alongside a real Publisher.
+type LogPublisher struct {
+	mu      sync.Mutex
+	writer  io.Writer
+	color   bool
+	compact bool
+	prefix  string
+	redact  map[string]bool
+
+	// Returns the current time. Replaced in tests.
+	now func() time.Time
+}
+
+type LogPublisherConfig struct {
+	// Where events are printed. Defaults to os.Stdout.
+	Writer io.Writer
+
+	// Highlight event names and timestamps with ANSI colors.
+	Color bool
+
+	// Print each event on a single line instead of indenting its data.
+	Compact bool
+
+	// Data fields whose values are replaced with [REDACTED], e.g.
+	// "password" or "email". Matched case-insensitively at any depth.
+	Redact []string
+
+	// Printed before every event, e.g. to tell them apart from other
+	// output.
+	Prefix string
+}
+
+func NewLogPublisher(config *LogPublisherConfig) *LogPublisher {
+	if nil == config {
+		config = &LogPublisherConfig{}
+	}
+
+	writer := config.Writer
+	if nil == writer {
+		writer = os.Stdout
+	}
+
+	redact := make(map[string]bool, len(config.Redact))
+	for _, field := range config.Redact {
+		redact[strings.ToLower(field)] = true
+	}
+
+	return &LogPublisher{
+		writer:  writer,
+		color:   config.Color,
+		compact: config.Compact,
+		prefix:  config.Prefix,
+		redact:  redact,
+		now:     time.Now,
+	}
+}
+
+// Publish prints the event
+func (p *LogPublisher) Publish(event Event) error {
+	data, err := p.format(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err = p.writer.Write(data)
+	return err
+}
+
+// PublishBatch prints each of the events
+func (p *LogPublisher) PublishBatch(events []Event) error {
+	for _, event := range events {
+		if err := p.Publish(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *LogPublisher) format(event Event) ([]byte, error) {
+	var data []byte
+	var err error
+
+	if p.compact {
+		data, err = json.Marshal(p.redacted(event.Data()))
+	} else {
+		data, err = json.MarshalIndent(p.redacted(event.Data()), "", "  ")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := p.now().UTC().Format(time.RFC3339)
+	name := event.Name()
+	if p.color {
+		timestamp = colorDim + timestamp + colorReset
+		name = colorName + name + colorReset
+	}
+
+	separator := "\n"
+	if p.compact {
+		separator = " "
+	}
+
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(p.prefix + timestamp + " " + name + separator)
+	buffer.Write(data)
+	buffer.WriteString("\n")
+
+	return buffer.Bytes(), nil
+}
+
+// redacted returns a copy of the data with redacted fields replaced. The
+// event's own data is left alone.
+func (p *LogPublisher) redacted(data map[string]interface{}) map[string]interface{} {
+	if len(p.redact) == 0 || nil == data {
+		return data
+	}
+
+	copied := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if p.redact[strings.ToLower(key)] {
+			copied[key] = redacted
+			continue
+		}
+
+		copied[key] = p.redactedValue(value)
+	}
+
+	return copied
+}
+
+func (p *LogPublisher) redactedValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return p.redacted(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = p.redactedValue(item)
+		}
+
+		return copied
+	default:
+		return value
+	}
+}
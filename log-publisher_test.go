@@ -0,0 +1,90 @@
+package gomainevents
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return e.data
+}
+
+func newTestLogPublisher(config *LogPublisherConfig) (*LogPublisher, *bytes.Buffer) {
+	buffer := &bytes.Buffer{}
+	config.Writer = buffer
+
+	publisher := NewLogPublisher(config)
+	publisher.now = func() time.Time {
+		return time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+	}
+
+	return publisher, buffer
+}
+
+func TestLogPublisherPrettyPrints(t *testing.T) {
+	publisher, buffer := newTestLogPublisher(&LogPublisherConfig{})
+
+	require.Nil(t, publisher.Publish(testEvent{name: "UserCreated", data: map[string]interface{}{"userId": 12}}))
+	assert.Equal(t, "2018-03-08T11:11:11Z UserCreated\n{\n  \"userId\": 12\n}\n", buffer.String())
+}
+
+func TestLogPublisherCompactWithColor(t *testing.T) {
+	publisher, buffer := newTestLogPublisher(&LogPublisherConfig{Compact: true, Color: true})
+
+	require.Nil(t, publisher.PublishBatch([]Event{
+		testEvent{name: "First", data: map[string]interface{}{}},
+		testEvent{name: "Second", data: map[string]interface{}{}},
+	}))
+
+	assert.Equal(t,
+		"\x1b[2m2018-03-08T11:11:11Z\x1b[0m \x1b[1;36mFirst\x1b[0m {}\n"+
+			"\x1b[2m2018-03-08T11:11:11Z\x1b[0m \x1b[1;36mSecond\x1b[0m {}\n",
+		buffer.String())
+}
+
+func TestLogPublisherRedacts(t *testing.T) {
+	publisher, buffer := newTestLogPublisher(&LogPublisherConfig{Compact: true, Redact: []string{"Password", "email"}})
+
+	data := map[string]interface{}{
+		"password": "hunter2",
+		"user": map[string]interface{}{
+			"Email": "someone@example.com",
+			"name":  "Someone",
+		},
+		"contacts": []interface{}{
+			map[string]interface{}{"email": "other@example.com"},
+		},
+	}
+
+	require.Nil(t, publisher.Publish(testEvent{name: "UserCreated", data: data}))
+	assert.Equal(t,
+		`2018-03-08T11:11:11Z UserCreated {"contacts":[{"email":"[REDACTED]"}],"password":"[REDACTED]","user":{"Email":"[REDACTED]","name":"Someone"}}`+"\n",
+		buffer.String())
+
+	// The event itself is left alone
+	assert.Equal(t, "hunter2", data["password"])
+}
+
+func TestDryRunPublisher(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	publisher := NewDryRunPublisher(&LogPublisherConfig{Writer: buffer, Compact: true, Redact: []string{"password"}})
+	publisher.now = func() time.Time {
+		return time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+	}
+
+	require.Nil(t, publisher.Publish(testEvent{name: "UserCreated", data: map[string]interface{}{"password": "hunter2"}}))
+	assert.Equal(t, `[gomainevents] Dry run: 2018-03-08T11:11:11Z UserCreated {"password":"[REDACTED]"}`+"\n", buffer.String())
+}
@@ -0,0 +1,25 @@
+package logadapters
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Logrus returns a Writer that logs to a logrus logger or entry, with the
+// component as a field.
+func Logrus(logger logrus.FieldLogger) *Writer {
+	return NewWriter(func(entry Entry) {
+		l := logger
+		if "" != entry.Component {
+			l = logger.WithField("component", entry.Component)
+		}
+
+		switch entry.Level {
+		case LevelDebug:
+			l.Debug(entry.Message)
+		case LevelError:
+			l.Error(entry.Message)
+		default:
+			l.Info(entry.Message)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package logadapters
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSampledMessages are the debug lines written for every event.
+var defaultSampledMessages = []string{
+	"Received event",
+	"Successfully processed",
+	"No handler registered",
+	"Requeuing event",
+}
+
+// maxErrors is how many distinct errors are remembered between clean ups.
+const maxErrors = 1000
+
+// Sampler cuts down the library's log output at high volume, writing only
+// some of the lines logged for every event and holding back repeats of the
+// same error. Other lines, including everything logged from outside the
+// library, are passed on untouched. Install it with log.SetOutput:
+//
+//	sampler, _ := logadapters.NewSampler(&logadapters.SamplerConfig{
+//		Writer:        os.Stderr,
+//		SampleEvery:   100,
+//		ErrorInterval: time.Minute,
+//	})
+//	log.SetOutput(sampler)
+type Sampler struct {
+	writer        io.Writer
+	every         int
+	messages      []string
+	errorInterval time.Duration
+
+	// Lines seen, by sampled message
+	counts map[string]int
+	errors map[string]*errorCount
+	mu     sync.Mutex
+
+	// Hook for tests
+	now func() time.Time
+}
+
+type errorCount struct {
+	written    time.Time
+	suppressed int
+}
+
+type SamplerConfig struct {
+	// Where lines that get through are written, e.g. os.Stderr or one of
+	// the adapters in this package. Required
+	Writer io.Writer
+
+	// Write 1 in every N of each of the sampled lines. Defaults to 1,
+	// writing all of them.
+	SampleEvery int
+
+	// Beginnings of the debug lines to sample. Defaults to the lines written
+	// for every event: "Received event", "Successfully processed", "No
+	// handler registered" and "Requeuing event".
+	SampledMessages []string
+
+	// Write each distinct error at most once per interval. The next one
+	// written says how many were held back. Defaults to 0, writing every
+	// error.
+	ErrorInterval time.Duration
+}
+
+func NewSampler(config *SamplerConfig) (*Sampler, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Writer {
+		return nil, errors.New("Writer is required")
+	}
+
+	if config.SampleEvery < 0 {
+		return nil, errors.New("SampleEvery must not be negative")
+	}
+
+	every := config.SampleEvery
+	if every == 0 {
+		every = 1
+	}
+
+	messages := defaultSampledMessages
+	if nil != config.SampledMessages {
+		messages = config.SampledMessages
+	}
+
+	return &Sampler{
+		writer:        config.Writer,
+		every:         every,
+		messages:      messages,
+		errorInterval: config.ErrorInterval,
+		counts:        map[string]int{},
+		errors:        map[string]*errorCount{},
+		now:           time.Now,
+	}, nil
+}
+
+// Write passes the line on if it gets through. Lines held back are reported
+// as written, so that the standard logger carries on as normal.
+func (s *Sampler) Write(p []byte) (int, error) {
+	line, ok := s.filter(string(p))
+	if !ok {
+		return len(p), nil
+	}
+
+	if _, err := io.WriteString(s.writer, line); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// filter decides whether a line gets through, and returns it with a note of
+// any repeats held back since it was last written.
+func (s *Sampler) filter(line string) (string, bool) {
+	entry := parse(line)
+
+	switch entry.Level {
+	case LevelDebug:
+		return line, s.sample(entry.Message)
+	case LevelError:
+		return s.limit(entry, line)
+	}
+
+	return line, true
+}
+
+func (s *Sampler) sample(message string) bool {
+	if s.every == 1 {
+		return true
+	}
+
+	for _, sampled := range s.messages {
+		if !strings.HasPrefix(message, sampled) {
+			continue
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		count := s.counts[sampled]
+		s.counts[sampled] = count + 1
+
+		return count%s.every == 0
+	}
+
+	return true
+}
+
+func (s *Sampler) limit(entry Entry, line string) (string, bool) {
+	if s.errorInterval <= 0 {
+		return line, true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	key := entry.Component + "\x00" + entry.Message
+
+	count, ok := s.errors[key]
+	if !ok {
+		s.forget(now)
+
+		s.errors[key] = &errorCount{written: now}
+
+		return line, true
+	}
+
+	if now.Sub(count.written) < s.errorInterval {
+		count.suppressed++
+
+		return "", false
+	}
+
+	if count.suppressed > 0 {
+		line = fmt.Sprintf("%s (%d identical errors suppressed)\n", strings.TrimRight(line, "\n"), count.suppressed)
+	}
+
+	count.written, count.suppressed = now, 0
+
+	return line, true
+}
+
+// forget drops errors that haven't been seen for an interval once there are
+// too many to keep track of. Repeats held back for them go unreported.
+func (s *Sampler) forget(now time.Time) {
+	if len(s.errors) < maxErrors {
+		return
+	}
+
+	for key, count := range s.errors {
+		if now.Sub(count.written) >= s.errorInterval {
+			delete(s.errors, key)
+		}
+	}
+}
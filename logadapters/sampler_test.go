@@ -0,0 +1,113 @@
+package logadapters
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSampler(t *testing.T) {
+	sampler, err := NewSampler(nil)
+	assert.Nil(t, sampler)
+	assert.NotNil(t, err)
+
+	sampler, err = NewSampler(&SamplerConfig{})
+	assert.Nil(t, sampler)
+	assert.NotNil(t, err)
+
+	sampler, err = NewSampler(&SamplerConfig{Writer: &bytes.Buffer{}, SampleEvery: -1})
+	assert.Nil(t, sampler)
+	assert.NotNil(t, err)
+
+	sampler, err = NewSampler(&SamplerConfig{Writer: &bytes.Buffer{}})
+	assert.NotNil(t, sampler)
+	assert.Nil(t, err)
+}
+
+func TestSamplerSamplesEventLines(t *testing.T) {
+	out := &bytes.Buffer{}
+	sampler, _ := NewSampler(&SamplerConfig{Writer: out, SampleEvery: 3})
+	logger := log.New(sampler, "", log.LstdFlags)
+
+	for i := 0; i < 7; i++ {
+		logger.Printf("[gomainevents] Received event: Domain\\Event map[id:%d]\n", i)
+		logger.Printf("[gomainevents] Successfully processed.\n")
+	}
+	logger.Printf("[gomainevents-sqs] Listening for events from queue\n")
+	logger.Printf("Listening on :8080\n")
+
+	output := out.String()
+	assert.Equal(t, 3, strings.Count(output, "Received event"))
+	assert.Contains(t, output, "map[id:0]")
+	assert.Contains(t, output, "map[id:3]")
+	assert.Contains(t, output, "map[id:6]")
+	assert.Equal(t, 3, strings.Count(output, "Successfully processed."))
+
+	// Everything else gets through
+	assert.Contains(t, output, "Listening for events from queue")
+	assert.Contains(t, output, "Listening on :8080")
+}
+
+func TestSamplerSampledMessages(t *testing.T) {
+	out := &bytes.Buffer{}
+	sampler, _ := NewSampler(&SamplerConfig{
+		Writer:          out,
+		SampleEvery:     2,
+		SampledMessages: []string{"Stored"},
+	})
+
+	for i := 0; i < 4; i++ {
+		sampler.Write([]byte("[gomainevents-storeforward] Stored 1 events. Pending: 1\n"))
+		sampler.Write([]byte("[gomainevents] Received event: Domain\\Event map[]\n"))
+	}
+
+	assert.Equal(t, 2, strings.Count(out.String(), "Stored"))
+	assert.Equal(t, 4, strings.Count(out.String(), "Received event"))
+}
+
+func TestSamplerLimitsErrors(t *testing.T) {
+	out := &bytes.Buffer{}
+	sampler, _ := NewSampler(&SamplerConfig{Writer: out, ErrorInterval: time.Minute})
+
+	now := time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+	sampler.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		n, err := sampler.Write([]byte("[gomainevents-sqs] Error: Connection refused\n"))
+		require.Nil(t, err)
+		assert.Equal(t, 45, n)
+	}
+	sampler.Write([]byte("[gomainevents] Error: Connection refused\n"))
+	sampler.Write([]byte("[gomainevents-sqs] Error: Access denied\n"))
+
+	assert.Equal(t, "[gomainevents-sqs] Error: Connection refused\n"+
+		"[gomainevents] Error: Connection refused\n"+
+		"[gomainevents-sqs] Error: Access denied\n", out.String())
+
+	// Once the interval is up, the next one says what was held back
+	out.Reset()
+	now = now.Add(time.Minute)
+	sampler.Write([]byte("[gomainevents-sqs] Error: Connection refused\n"))
+	sampler.Write([]byte("[gomainevents-sqs] Error: Access denied\n"))
+
+	assert.Equal(t, "[gomainevents-sqs] Error: Connection refused (4 identical errors suppressed)\n"+
+		"[gomainevents-sqs] Error: Access denied\n", out.String())
+}
+
+func TestSamplerDefaultsPassEverything(t *testing.T) {
+	out := &bytes.Buffer{}
+	sampler, _ := NewSampler(&SamplerConfig{Writer: out})
+
+	for i := 0; i < 3; i++ {
+		sampler.Write([]byte("[gomainevents] Received event: Domain\\Event map[]\n"))
+		sampler.Write([]byte("[gomainevents] Error: Nope\n"))
+	}
+
+	assert.Equal(t, 3, strings.Count(out.String(), "Received event"))
+	assert.Equal(t, 3, strings.Count(out.String(), "Error: Nope"))
+}
@@ -0,0 +1,27 @@
+package logadapters
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Slog returns a Writer that logs to a slog.Logger, with the component as
+// an attribute.
+func Slog(logger *slog.Logger) *Writer {
+	return NewWriter(func(entry Entry) {
+		level := slog.LevelInfo
+		switch entry.Level {
+		case LevelDebug:
+			level = slog.LevelDebug
+		case LevelError:
+			level = slog.LevelError
+		}
+
+		if "" == entry.Component {
+			logger.Log(context.Background(), level, entry.Message)
+			return
+		}
+
+		logger.Log(context.Background(), level, entry.Message, slog.String("component", entry.Component))
+	})
+}
@@ -0,0 +1,39 @@
+package logadapters
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSlogger logs everything, without times, so output is predictable.
+func newTestSlogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if slog.TimeKey == attr.Key && len(groups) == 0 {
+				return slog.Attr{}
+			}
+
+			return attr
+		},
+	}))
+}
+
+func TestSlog(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger := log.New(Slog(newTestSlogger(out)), "", log.LstdFlags)
+
+	logger.Printf("[gomainevents-sns] Publishing 3 events to the secondary topic\n")
+	logger.Printf("[gomainevents-sns] Error: %s\n", "Throttled")
+	logger.Printf("Unrelated\n")
+
+	assert.Equal(t, ""+
+		"level=DEBUG msg=\"Publishing 3 events to the secondary topic\" component=sns\n"+
+		"level=ERROR msg=Throttled component=sns\n"+
+		"level=INFO msg=Unrelated\n", out.String())
+}
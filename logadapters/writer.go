@@ -0,0 +1,91 @@
+package logadapters
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Level is how serious a line of log output is.
+type Level int
+
+const (
+	// LevelDebug is the library's running commentary: events received,
+	// processed, requeued and so on.
+	LevelDebug Level = iota
+
+	// LevelInfo is log output from outside the library.
+	LevelInfo
+
+	// LevelError is an error reported by the library.
+	LevelError
+)
+
+// Entry is a line of log output, split into its parts.
+type Entry struct {
+	Level Level
+
+	// Part of the library that logged the line, e.g. "sqs" for the SQS
+	// provider or "gomainevents" for the Listener. Empty for log output
+	// from outside the library.
+	Component string
+
+	Message string
+}
+
+// Writer turns lines written to the standard logger into calls to another
+// logger. Install it with log.SetOutput:
+//
+//	log.SetOutput(logadapters.Slog(slog.Default()))
+//
+// Everything logged through the standard logger goes through the Writer,
+// not only the library's output.
+type Writer struct {
+	log func(Entry)
+}
+
+// NewWriter returns a Writer that passes each line to fn, for loggers
+// without an adapter.
+func NewWriter(fn func(Entry)) *Writer {
+	return &Writer{log: fn}
+}
+
+// Write parses a line from the standard logger. It never fails, so that
+// logging can't break whatever is doing the logging.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.log(parse(string(p)))
+
+	return len(p), nil
+}
+
+// timestamp matches the date and time the standard logger adds by default.
+var timestamp = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} )?\d{2}:\d{2}:\d{2}(\.\d+)? `)
+
+// component matches the prefix of the library's log output, e.g.
+// "[gomainevents-sqs] ".
+var component = regexp.MustCompile(`^\[gomainevents(?:-([\w-]+))?\] `)
+
+func parse(line string) Entry {
+	line = strings.TrimRight(line, "\n")
+	line = timestamp.ReplaceAllString(line, "")
+
+	match := component.FindStringSubmatch(line)
+	if nil == match {
+		return Entry{Level: LevelInfo, Message: line}
+	}
+
+	entry := Entry{
+		Level:     LevelDebug,
+		Component: match[1],
+		Message:   line[len(match[0]):],
+	}
+	if "" == entry.Component {
+		entry.Component = "gomainevents"
+	}
+
+	if strings.HasPrefix(entry.Message, "Error: ") {
+		entry.Level = LevelError
+		entry.Message = strings.TrimPrefix(entry.Message, "Error: ")
+	}
+
+	return entry
+}
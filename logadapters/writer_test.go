@@ -0,0 +1,44 @@
+package logadapters
+
+import (
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	assert.Equal(t, Entry{
+		Level:     LevelDebug,
+		Component: "sqs",
+		Message:   "Requeuing event. Retries: 2, Delay: 4",
+	}, parse("2018/03/08 11:11:11 [gomainevents-sqs] Requeuing event. Retries: 2, Delay: 4\n"))
+
+	assert.Equal(t, Entry{
+		Level:     LevelError,
+		Component: "storeforward",
+		Message:   "Connection refused",
+	}, parse("11:11:11.123456 [gomainevents-storeforward] Error: Connection refused\n"))
+
+	assert.Equal(t, Entry{
+		Level:     LevelDebug,
+		Component: "gomainevents",
+		Message:   "Successfully processed.",
+	}, parse("[gomainevents] Successfully processed.\n"))
+
+	assert.Equal(t, Entry{
+		Level:   LevelInfo,
+		Message: "Listening on :8080",
+	}, parse("2018/03/08 11:11:11 Listening on :8080\n"))
+}
+
+func TestWriterWithStandardLogger(t *testing.T) {
+	entries := []Entry{}
+	logger := log.New(NewWriter(func(entry Entry) {
+		entries = append(entries, entry)
+	}), "", log.LstdFlags|log.Lmicroseconds)
+
+	logger.Printf("[gomainevents-kafka] "+"Error: %s\n", "Broker not available")
+
+	assert.Equal(t, []Entry{{Level: LevelError, Component: "kafka", Message: "Broker not available"}}, entries)
+}
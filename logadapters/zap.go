@@ -0,0 +1,25 @@
+package logadapters
+
+import (
+	"go.uber.org/zap"
+)
+
+// Zap returns a Writer that logs to a zap.Logger, with the component as a
+// field.
+func Zap(logger *zap.Logger) *Writer {
+	return NewWriter(func(entry Entry) {
+		fields := []zap.Field{}
+		if "" != entry.Component {
+			fields = append(fields, zap.String("component", entry.Component))
+		}
+
+		switch entry.Level {
+		case LevelDebug:
+			logger.Debug(entry.Message, fields...)
+		case LevelError:
+			logger.Error(entry.Message, fields...)
+		default:
+			logger.Info(entry.Message, fields...)
+		}
+	})
+}
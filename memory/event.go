@@ -0,0 +1,34 @@
+package memory
+
+// Event implements the standard domain event interface for events published
+// to a memory Provider.
+type Event struct {
+	name string
+	data map[string]interface{}
+
+	// Assigned in the order events are published, starting from 1.
+	id int64
+
+	// Events can be retried a set number of times before they're given
+	// up on.
+	retryCount int
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// ID returns the order in which the event was published, starting from 1.
+func (e Event) ID() int64 {
+	return e.id
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
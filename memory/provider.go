@@ -0,0 +1,348 @@
+package memory
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const defaultMaximumRetryCount = 25
+
+// ErrStopped is returned when publishing to a provider that has been
+// stopped.
+var ErrStopped = errors.New("Provider is stopped")
+
+// RedeliveryDelayFunc returns how long to wait before redelivering an event
+// that has been requeued retryCount times.
+type RedeliveryDelayFunc func(retryCount int) time.Duration
+
+// Provider is an in-memory queue of events, for tests and local development.
+// Events published to it, directly or through a Publisher, are delivered to
+// the Listener in order. Requeued events are redelivered after the
+// configured delay, and the provider keeps track of which events were
+// deleted or given up on so that tests can check the outcome.
+type Provider struct {
+	redeliveryDelay   RedeliveryDelayFunc
+	maximumRetryCount int
+	clock             gomainevents.Clock
+
+	mu          sync.Mutex
+	nextID      int64
+	queue       []Event
+	inFlight    map[int64]Event
+	delayed     int
+	deleted     []Event
+	deadLetters []Event
+	stopped     bool
+
+	// Signalled whenever the queue or the in-flight events change.
+	changed chan bool
+	idle    *sync.Cond
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+}
+
+type Config struct {
+	// How long to wait before redelivering a requeued event. Defaults to
+	// redelivering straight away, which keeps tests fast.
+	RedeliveryDelay RedeliveryDelayFunc
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+
+	// Clock redelivery delays are measured on, e.g. a
+	// gomaineventstest.Clock to step through them. Defaults to
+	// gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewProvider(config *Config) *Provider {
+	if nil == config {
+		config = &Config{}
+	}
+
+	redeliveryDelay := config.RedeliveryDelay
+	if nil == redeliveryDelay {
+		redeliveryDelay = func(int) time.Duration { return 0 }
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	clock := gomainevents.ClockOrDefault(config.Clock)
+
+	p := &Provider{
+		redeliveryDelay:   redeliveryDelay,
+		maximumRetryCount: maximumRetryCount,
+		clock:             clock,
+		inFlight:          make(map[int64]Event),
+		changed:           make(chan bool, 1),
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}
+	p.idle = sync.NewCond(&p.mu)
+
+	return p
+}
+
+// Publish adds an event to the queue. Events can be published before the
+// provider is started.
+func (p *Provider) Publish(event gomainevents.Event) error {
+	return p.PublishBatch([]gomainevents.Event{event})
+}
+
+// PublishBatch adds events to the queue, in order.
+func (p *Provider) PublishBatch(events []gomainevents.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return ErrStopped
+	}
+
+	for _, event := range events {
+		p.nextID++
+		p.queue = append(p.queue, Event{
+			name: event.Name(),
+			data: event.Data(),
+			id:   p.nextID,
+		})
+	}
+
+	p.signal()
+	return nil
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	go func() {
+		for {
+			event, ok := p.next()
+			if !ok {
+				select {
+				case <-p.done:
+					return
+				case <-p.changed:
+				}
+				continue
+			}
+
+			if !p.deliver(event) {
+				return
+			}
+		}
+	}()
+
+	return p.events, p.errors
+}
+
+// next takes the first event off the queue, marking it in flight.
+func (p *Provider) next() (Event, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.queue) == 0 {
+		return Event{}, false
+	}
+
+	event := p.queue[0]
+	p.queue = p.queue[1:]
+	p.inFlight[event.id] = event
+
+	return event, true
+}
+
+// Delete an event that we're done with
+func (p *Provider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to memory flavor
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.inFlight, evt.id)
+	p.deleted = append(p.deleted, evt)
+	p.signal()
+}
+
+// Requeue an event for later
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to memory flavor
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.inFlight, evt.id)
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		p.deadLetters = append(p.deadLetters, evt)
+		p.signal()
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	delay := p.redeliveryDelay(evt.retryCount)
+	evt.retryCount++
+
+	p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", evt.RetryCount(), delay)
+	p.redeliver(evt, delay)
+
+	return nil
+}
+
+// Defer an event, redelivering it after the delay with the same retry
+// count.
+func (p *Provider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to memory flavor
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.inFlight, evt.id)
+
+	p.debugPrint("Deferring event. Delay: %s\n", delay)
+	p.redeliver(evt, delay)
+
+	return nil
+}
+
+// redeliver puts an event back on the queue once the delay has passed. The
+// caller holds the lock.
+func (p *Provider) redeliver(evt Event, delay time.Duration) {
+	if delay <= 0 {
+		p.queue = append(p.queue, evt)
+		p.signal()
+		return
+	}
+
+	p.delayed++
+	p.clock.AfterFunc(delay, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		p.delayed--
+		p.queue = append(p.queue, evt)
+		p.signal()
+	})
+}
+
+// signal wakes up the delivery loop and anyone waiting for the provider to
+// be idle. The lock must be held.
+func (p *Provider) signal() {
+	select {
+	case p.changed <- true:
+	default:
+	}
+
+	p.idle.Broadcast()
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	p.mu.Lock()
+	p.stopped = true
+	p.idle.Broadcast()
+	p.mu.Unlock()
+
+	close(p.done)
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// Wait blocks until every published event has been deleted or given up
+// on, or the timeout passes. It returns false on timeout.
+func (p *Provider) Wait(timeout time.Duration) bool {
+	timer := time.AfterFunc(timeout, func() {
+		p.mu.Lock()
+		p.idle.Broadcast()
+		p.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	deadline := time.Now().Add(timeout)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for !p.isIdle() {
+		if p.stopped || !time.Now().Before(deadline) {
+			return false
+		}
+
+		p.idle.Wait()
+	}
+
+	return true
+}
+
+func (p *Provider) isIdle() bool {
+	return len(p.queue) == 0 && len(p.inFlight) == 0 && p.delayed == 0
+}
+
+// Pending returns the number of events that are queued, in flight, or
+// waiting to be redelivered.
+func (p *Provider) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.queue) + len(p.inFlight) + p.delayed
+}
+
+// Deleted returns the events that were processed, in the order they were
+// deleted.
+func (p *Provider) Deleted() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Event{}, p.deleted...)
+}
+
+// DeadLetters returns the events that exceeded the maximum retry count.
+func (p *Provider) DeadLetters() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Event{}, p.deadLetters...)
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-memory] "+format, values...)
+	}
+}
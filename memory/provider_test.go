@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/gomaineventstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func receive(t *testing.T, events <-chan gomainevents.Event) Event {
+	select {
+	case event := <-events:
+		return event.(Event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	return Event{}
+}
+
+func TestProviderDeliversInOrder(t *testing.T) {
+	provider := NewProvider(nil)
+	publisher := NewPublisher(provider)
+
+	// Publishing before starting is fine
+	require.Nil(t, publisher.Publish(testEvent{name: "First"}))
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	require.Nil(t, publisher.PublishBatch([]gomainevents.Event{testEvent{name: "Second"}, testEvent{name: "Third"}}))
+
+	for i, name := range []string{"First", "Second", "Third"} {
+		event := receive(t, events)
+		assert.Equal(t, name, event.Name())
+		assert.Equal(t, int64(i+1), event.ID())
+		assert.Equal(t, map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}, event.Data())
+
+		provider.Delete(event)
+	}
+
+	assert.True(t, provider.Wait(time.Second))
+	assert.Equal(t, 0, provider.Pending())
+	assert.Len(t, provider.Deleted(), 3)
+}
+
+func TestProviderRedeliversRequeuedEvents(t *testing.T) {
+	delays := []int{}
+	provider := NewProvider(&Config{
+		MaximumRetryCount: 1,
+		RedeliveryDelay: func(retryCount int) time.Duration {
+			delays = append(delays, retryCount)
+			return 10 * time.Millisecond
+		},
+	})
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	require.Nil(t, provider.Publish(testEvent{name: "Thing"}))
+
+	event := receive(t, events)
+	assert.Nil(t, provider.Requeue(event))
+	assert.Equal(t, 1, provider.Pending())
+
+	event = receive(t, events)
+	assert.Equal(t, 1, event.RetryCount())
+	assert.Nil(t, provider.Requeue(event))
+
+	event = receive(t, events)
+	assert.Equal(t, 2, event.RetryCount())
+	assert.IsType(t, &RetryAttemptsExceededError{}, provider.Requeue(event))
+
+	assert.Equal(t, []int{0, 1}, delays)
+	assert.True(t, provider.Wait(time.Second))
+	require.Len(t, provider.DeadLetters(), 1)
+	assert.Equal(t, "Thing", provider.DeadLetters()[0].Name())
+}
+
+func TestProviderRedeliversOnClock(t *testing.T) {
+	clock := gomaineventstest.NewClock(time.Now())
+	provider := NewProvider(&Config{
+		Clock: clock,
+		RedeliveryDelay: func(retryCount int) time.Duration {
+			return time.Hour
+		},
+	})
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	require.Nil(t, provider.Publish(testEvent{name: "Thing"}))
+	assert.Nil(t, provider.Requeue(receive(t, events)))
+
+	clock.Advance(59 * time.Minute)
+	select {
+	case <-events:
+		t.Fatal("Event was redelivered early")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, 1, receive(t, events).RetryCount())
+}
+
+func TestProviderDefersWithoutRetrying(t *testing.T) {
+	clock := gomaineventstest.NewClock(time.Now())
+	provider := NewProvider(&Config{Clock: clock})
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	require.Nil(t, provider.Publish(testEvent{name: "Thing"}))
+	assert.Nil(t, provider.Defer(receive(t, events), time.Minute))
+	assert.Equal(t, 1, provider.Pending())
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, 0, receive(t, events).RetryCount())
+}
+
+func TestProviderWaitTimesOut(t *testing.T) {
+	provider := NewProvider(nil)
+	require.Nil(t, provider.Publish(testEvent{name: "Thing"}))
+
+	assert.False(t, provider.Wait(10*time.Millisecond))
+	assert.Equal(t, 1, provider.Pending())
+
+	provider.Stop()
+	assert.Equal(t, ErrStopped, provider.Publish(testEvent{name: "Thing"}))
+}
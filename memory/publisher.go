@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"github.com/researchsquare/gomainevents"
+)
+
+// Publisher publishes events to a memory Provider, so that code written
+// against a Publisher can feed a Listener in the same process.
+type Publisher struct {
+	provider *Provider
+}
+
+func NewPublisher(provider *Provider) *Publisher {
+	return &Publisher{provider: provider}
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	return p.provider.Publish(event)
+}
+
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	return p.provider.PublishBatch(events)
+}
@@ -0,0 +1,121 @@
+package metadata
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Enricher stamps the events a service publishes with Metadata, so that
+// consumers and audit tooling can tell where they came from. Add its
+// Interceptor to the service's publishers:
+//
+//	interceptors := gomainevents.Interceptors{enricher.Interceptor()}
+//	publisher := interceptors.Wrap(snsPublisher)
+//
+// Consumers read the metadata back with From. Events that already have
+// metadata, e.g. ones being forwarded, keep it.
+type Enricher struct {
+	service     string
+	version     string
+	environment string
+	host        string
+
+	// Hooks for tests
+	now   func() time.Time
+	newID func() (string, error)
+}
+
+type Config struct {
+	// Name of the service publishing events. Required
+	Service string
+
+	// Version of the service, e.g. a release tag or commit. Optional
+	Version string
+
+	// Environment the service runs in, e.g. production. Optional
+	Environment string
+
+	// Host the service runs on. Defaults to the hostname.
+	Host string
+}
+
+func NewEnricher(config *Config) (*Enricher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.Service {
+		return nil, errors.New("Service is required")
+	}
+
+	host := config.Host
+	if "" == host {
+		host, _ = os.Hostname()
+	}
+
+	return &Enricher{
+		service:     config.Service,
+		version:     config.Version,
+		environment: config.Environment,
+		host:        host,
+		now:         time.Now,
+		newID:       newID,
+	}, nil
+}
+
+// Enrich returns the event with metadata added to its data, or the event
+// itself if it already has some. Its data is copied, not modified.
+func (e *Enricher) Enrich(event gomainevents.Event) (gomainevents.Event, error) {
+	if _, ok := From(event); ok {
+		return event, nil
+	}
+
+	id, err := e.newID()
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := Metadata{
+		ID:          id,
+		PublishedAt: e.now(),
+		Service:     e.service,
+		Version:     e.version,
+		Environment: e.environment,
+		Host:        e.host,
+	}
+
+	data := map[string]interface{}{}
+	for key, value := range event.Data() {
+		data[key] = value
+	}
+	data[DataField] = metadata.fields()
+
+	return gomainevents.WithData(event, data), nil
+}
+
+// Interceptor returns an interceptor that enriches each event before it's
+// published.
+func (e *Enricher) Interceptor() gomainevents.Interceptor {
+	return func(event gomainevents.Event, next gomainevents.PublishFunc) error {
+		enriched, err := e.Enrich(event)
+		if err != nil {
+			return err
+		}
+
+		return next(enriched)
+	}
+}
+
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,123 @@
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return e.data
+}
+
+func newTestEnricher() *Enricher {
+	enricher, _ := NewEnricher(&Config{
+		Service:     "billing",
+		Version:     "1.2.3",
+		Environment: "production",
+		Host:        "billing-1",
+	})
+
+	enricher.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	enricher.newID = func() (string, error) { return "abc123", nil }
+
+	return enricher
+}
+
+func TestNewEnricher(t *testing.T) {
+	_, err := NewEnricher(nil)
+	assert.EqualError(t, err, "Configuration is required")
+
+	_, err = NewEnricher(&Config{})
+	assert.EqualError(t, err, "Service is required")
+
+	enricher, err := NewEnricher(&Config{Service: "billing"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, enricher.host)
+}
+
+func TestEnrich(t *testing.T) {
+	event := testEvent{name: "InvoicePaid", data: map[string]interface{}{"invoiceId": 1}}
+
+	enriched, err := newTestEnricher().Enrich(event)
+	assert.NoError(t, err)
+	assert.Equal(t, "InvoicePaid", enriched.Name())
+	assert.Equal(t, 1, enriched.Data()["invoiceId"])
+	assert.NotContains(t, event.Data(), DataField)
+
+	expected := Metadata{
+		ID:          "abc123",
+		PublishedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Service:     "billing",
+		Version:     "1.2.3",
+		Environment: "production",
+		Host:        "billing-1",
+	}
+
+	metadata, ok := From(enriched)
+	assert.True(t, ok)
+	assert.Equal(t, expected, metadata)
+
+	// As a consumer would receive it
+	encoded, _ := json.Marshal(enriched.Data())
+	var data map[string]interface{}
+	json.Unmarshal(encoded, &data)
+
+	metadata, ok = From(testEvent{name: "InvoicePaid", data: data})
+	assert.True(t, ok)
+	assert.Equal(t, expected, metadata)
+}
+
+func TestEnrichKeepsExistingMetadata(t *testing.T) {
+	enricher := newTestEnricher()
+	enriched, _ := enricher.Enrich(testEvent{name: "InvoicePaid"})
+
+	enricher.service = "forwarder"
+	enricher.newID = func() (string, error) { return "def456", nil }
+
+	forwarded, err := enricher.Enrich(enriched)
+	assert.NoError(t, err)
+	assert.Equal(t, enriched, forwarded)
+}
+
+func TestFromWithoutMetadata(t *testing.T) {
+	_, ok := From(testEvent{name: "InvoicePaid"})
+	assert.False(t, ok)
+
+	_, ok = From(testEvent{name: "InvoicePaid", data: map[string]interface{}{DataField: "nope"}})
+	assert.False(t, ok)
+}
+
+func TestInterceptor(t *testing.T) {
+	publisher := &mocks.Publisher{}
+	interceptors := gomainevents.Interceptors{newTestEnricher().Interceptor()}
+
+	assert.NoError(t, interceptors.Wrap(publisher).Publish(testEvent{name: "InvoicePaid"}))
+
+	calls := publisher.PublishCalls()
+	assert.Len(t, calls, 1)
+
+	metadata, _ := From(calls[0])
+	assert.Equal(t, "abc123", metadata.ID)
+
+	enricher := newTestEnricher()
+	enricher.newID = func() (string, error) { return "", errors.New("No entropy") }
+	interceptors = gomainevents.Interceptors{enricher.Interceptor()}
+
+	assert.EqualError(t, interceptors.Wrap(publisher).Publish(testEvent{name: "InvoicePaid"}), "No entropy")
+	assert.Len(t, publisher.PublishCalls(), 1)
+}
@@ -0,0 +1,71 @@
+package metadata
+
+import (
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// DataField is the field of an event's data its metadata is kept in. Every
+// provider carries an event's data in its envelope, so the metadata reaches
+// consumers whichever one it's published with.
+const DataField = "_metadata"
+
+// Metadata says where an event came from.
+type Metadata struct {
+	// Generated when the event is first published, and kept if it's
+	// published again, e.g. when it's forwarded.
+	ID          string
+	PublishedAt time.Time
+
+	Service     string
+	Version     string
+	Environment string
+	Host        string
+}
+
+// From returns the metadata an Enricher stamped on an event, and whether
+// there was any.
+func From(event gomainevents.Event) (Metadata, bool) {
+	fields, ok := event.Data()[DataField].(map[string]interface{})
+	if !ok {
+		return Metadata{}, false
+	}
+
+	str := func(key string) string {
+		value, _ := fields[key].(string)
+		return value
+	}
+
+	metadata := Metadata{
+		ID:          str("id"),
+		Service:     str("service"),
+		Version:     str("version"),
+		Environment: str("environment"),
+		Host:        str("host"),
+	}
+
+	metadata.PublishedAt, _ = time.Parse(time.RFC3339Nano, str("publishedAt"))
+
+	return metadata, "" != metadata.ID
+}
+
+func (m Metadata) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"id":          m.ID,
+		"publishedAt": m.PublishedAt.UTC().Format(time.RFC3339Nano),
+		"service":     m.Service,
+	}
+
+	for key, value := range map[string]string{
+		"version":     m.Version,
+		"environment": m.Environment,
+		"host":        m.Host,
+	} {
+		if "" != value {
+			fields[key] = value
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,70 @@
+package mocks
+
+import (
+	"encoding/json"
+	"sync"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/researchsquare/gomainevents/websocket"
+)
+
+// Codec is a test double for websocket.Codec. Each method calls the
+// matching func field if it is set, falling back to JSON text messages,
+// and records the call either way.
+type Codec struct {
+	EncodeFunc      func(*websocket.Frame) ([]byte, error)
+	DecodeFunc      func([]byte, *websocket.Frame) error
+	MessageTypeFunc func() int
+
+	mu          sync.Mutex
+	encodeCalls []*websocket.Frame
+	decodeCalls [][]byte
+}
+
+func (c *Codec) Encode(frame *websocket.Frame) ([]byte, error) {
+	c.mu.Lock()
+	c.encodeCalls = append(c.encodeCalls, frame)
+	c.mu.Unlock()
+
+	if nil != c.EncodeFunc {
+		return c.EncodeFunc(frame)
+	}
+
+	return json.Marshal(frame)
+}
+
+func (c *Codec) Decode(message []byte, frame *websocket.Frame) error {
+	c.mu.Lock()
+	c.decodeCalls = append(c.decodeCalls, message)
+	c.mu.Unlock()
+
+	if nil != c.DecodeFunc {
+		return c.DecodeFunc(message, frame)
+	}
+
+	return json.Unmarshal(message, frame)
+}
+
+func (c *Codec) MessageType() int {
+	if nil != c.MessageTypeFunc {
+		return c.MessageTypeFunc()
+	}
+
+	return gorilla.TextMessage
+}
+
+// EncodeCalls returns the frames passed to Encode, in order.
+func (c *Codec) EncodeCalls() []*websocket.Frame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*websocket.Frame{}, c.encodeCalls...)
+}
+
+// DecodeCalls returns the messages passed to Decode, in order.
+func (c *Codec) DecodeCalls() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([][]byte{}, c.decodeCalls...)
+}
@@ -0,0 +1,83 @@
+package mocks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+// Fails to compile if the doubles drift from the interfaces.
+var (
+	_ gomainevents.Provider       = &Provider{}
+	_ gomainevents.BatchPublisher = &Publisher{}
+	_ websocket.Codec             = &Codec{}
+)
+
+func TestProvider(t *testing.T) {
+	provider := NewProvider()
+	provider.RequeueFunc = func(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+		return errors.New("Full")
+	}
+
+	provider.Events <- testEvent{name: "Thing"}
+	events, _ := provider.Start()
+	event := <-events
+
+	provider.Delete(event)
+	assert.EqualError(t, provider.Requeue(event), "Full")
+	provider.Stop()
+	provider.Stop()
+
+	assert.Equal(t, 1, provider.StartCalls())
+	assert.Equal(t, []gomainevents.Event{event}, provider.DeleteCalls())
+	assert.Equal(t, []gomainevents.Event{event}, provider.RequeueCalls())
+	assert.Equal(t, 2, provider.StopCalls())
+
+	_, open := <-events
+	assert.False(t, open)
+}
+
+func TestPublisher(t *testing.T) {
+	publisher := &Publisher{
+		PublishFunc: func(gomainevents.Event) error {
+			return errors.New("Unavailable")
+		},
+	}
+
+	assert.EqualError(t, publisher.Publish(testEvent{name: "First"}), "Unavailable")
+	require.Nil(t, publisher.PublishBatch([]gomainevents.Event{testEvent{name: "Second"}, testEvent{name: "Third"}}))
+
+	assert.Equal(t, []gomainevents.Event{testEvent{name: "First"}}, publisher.PublishCalls())
+	require.Len(t, publisher.PublishBatchCalls(), 1)
+	assert.Len(t, publisher.PublishBatchCalls()[0], 2)
+}
+
+func TestCodec(t *testing.T) {
+	codec := &Codec{}
+
+	message, err := codec.Encode(&websocket.Frame{Name: "Thing"})
+	require.Nil(t, err)
+
+	frame := &websocket.Frame{}
+	require.Nil(t, codec.Decode(message, frame))
+	assert.Equal(t, "Thing", frame.Name)
+
+	assert.Len(t, codec.EncodeCalls(), 1)
+	assert.Equal(t, [][]byte{message}, codec.DecodeCalls())
+}
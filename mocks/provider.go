@@ -0,0 +1,120 @@
+package mocks
+
+import (
+	"sync"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Provider is a test double for gomainevents.Provider. Each method calls the
+// matching func field if it is set, and records the call either way.
+// Without a StartFunc, Start returns the Events and Errors channels, which
+// Stop closes.
+type Provider struct {
+	StartFunc   func() (<-chan gomainevents.Event, <-chan error)
+	DeleteFunc  func(gomainevents.Event)
+	RequeueFunc func(gomainevents.Event) gomainevents.RequeuingEventFailedError
+	StopFunc    func()
+
+	// Returned by Start when there is no StartFunc.
+	Events chan gomainevents.Event
+	Errors chan error
+
+	mu           sync.Mutex
+	startCalls   int
+	deleteCalls  []gomainevents.Event
+	requeueCalls []gomainevents.Event
+	stopCalls    int
+}
+
+// NewProvider returns a Provider whose channels hold up to 100 events and
+// errors.
+func NewProvider() *Provider {
+	return &Provider{
+		Events: make(chan gomainevents.Event, 100),
+		Errors: make(chan error, 100),
+	}
+}
+
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	p.mu.Lock()
+	p.startCalls++
+	p.mu.Unlock()
+
+	if nil != p.StartFunc {
+		return p.StartFunc()
+	}
+
+	return p.Events, p.Errors
+}
+
+func (p *Provider) Delete(event gomainevents.Event) {
+	p.mu.Lock()
+	p.deleteCalls = append(p.deleteCalls, event)
+	p.mu.Unlock()
+
+	if nil != p.DeleteFunc {
+		p.DeleteFunc(event)
+	}
+}
+
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	p.mu.Lock()
+	p.requeueCalls = append(p.requeueCalls, event)
+	p.mu.Unlock()
+
+	if nil != p.RequeueFunc {
+		return p.RequeueFunc(event)
+	}
+
+	return nil
+}
+
+func (p *Provider) Stop() {
+	p.mu.Lock()
+	p.stopCalls++
+	first := p.stopCalls == 1
+	p.mu.Unlock()
+
+	if nil != p.StopFunc {
+		p.StopFunc()
+		return
+	}
+
+	if first && nil != p.Events {
+		close(p.Events)
+		close(p.Errors)
+	}
+}
+
+// StartCalls returns the number of times Start was called.
+func (p *Provider) StartCalls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.startCalls
+}
+
+// DeleteCalls returns the events passed to Delete, in order.
+func (p *Provider) DeleteCalls() []gomainevents.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]gomainevents.Event{}, p.deleteCalls...)
+}
+
+// RequeueCalls returns the events passed to Requeue, in order.
+func (p *Provider) RequeueCalls() []gomainevents.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]gomainevents.Event{}, p.requeueCalls...)
+}
+
+// StopCalls returns the number of times Stop was called.
+func (p *Provider) StopCalls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.stopCalls
+}
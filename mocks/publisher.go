@@ -0,0 +1,59 @@
+package mocks
+
+import (
+	"sync"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Publisher is a test double for gomainevents.Publisher and BatchPublisher.
+// Each method calls the matching func field if it is set, and records the
+// call either way.
+type Publisher struct {
+	PublishFunc      func(gomainevents.Event) error
+	PublishBatchFunc func([]gomainevents.Event) error
+
+	mu                sync.Mutex
+	publishCalls      []gomainevents.Event
+	publishBatchCalls [][]gomainevents.Event
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	p.mu.Lock()
+	p.publishCalls = append(p.publishCalls, event)
+	p.mu.Unlock()
+
+	if nil != p.PublishFunc {
+		return p.PublishFunc(event)
+	}
+
+	return nil
+}
+
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	p.mu.Lock()
+	p.publishBatchCalls = append(p.publishBatchCalls, append([]gomainevents.Event{}, events...))
+	p.mu.Unlock()
+
+	if nil != p.PublishBatchFunc {
+		return p.PublishBatchFunc(events)
+	}
+
+	return nil
+}
+
+// PublishCalls returns the events passed to Publish, in order.
+func (p *Publisher) PublishCalls() []gomainevents.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]gomainevents.Event{}, p.publishCalls...)
+}
+
+// PublishBatchCalls returns the batches passed to PublishBatch, in order.
+func (p *Publisher) PublishBatchCalls() [][]gomainevents.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([][]gomainevents.Event{}, p.publishBatchCalls...)
+}
@@ -0,0 +1,81 @@
+package nats
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// headerEventName carries the event name on every message, since subjects
+// can't hold every character an event name might use.
+const headerEventName = "Event-Name"
+
+// Event implements the standard domain event interface for events consumed
+// from a JetStream stream.
+type Event struct {
+	name string
+	data map[string]interface{}
+	msg  jetstream.Msg
+
+	// Number of previous deliveries, as tracked by the server.
+	retryCount int
+}
+
+type encodedEvent struct {
+	Name string                 `json:"name"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// DecodeEvent builds an event from a JetStream message.
+func DecodeEvent(msg jetstream.Msg) (*Event, error) {
+	e := &encodedEvent{}
+	if err := json.Unmarshal(msg.Data(), e); err != nil {
+		return nil, err
+	}
+
+	name := e.Name
+	if header := msg.Headers().Get(headerEventName); "" != header {
+		name = header
+	}
+
+	retryCount := 0
+	if metadata, err := msg.Metadata(); err == nil && metadata.NumDelivered > 0 {
+		retryCount = int(metadata.NumDelivered) - 1
+	}
+
+	return &Event{
+		name:       name,
+		data:       e.Data,
+		msg:        msg,
+		retryCount: retryCount,
+	}, nil
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// Subject returns the subject the event was published on.
+func (e Event) Subject() string {
+	return e.msg.Subject()
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
+
+// Delay returns how long to wait before redelivering this event.
+func (e Event) Delay() time.Duration {
+	return time.Duration(math.Min(
+		math.Pow(2, float64(e.retryCount+1)),
+		15*60, // Max is 15 minutes
+	)) * time.Second
+}
@@ -0,0 +1,323 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultMaximumRetryCount = 25
+	defaultAckWait           = 30 * time.Second
+	nextErrorDelay           = time.Second
+)
+
+// headerRetryCount is added to messages sent to the dead letter subject.
+const headerRetryCount = "Retry-Count"
+
+// Provider consumes events from a JetStream durable consumer. Delete acks,
+// Requeue naks with a backoff delay, and events that run out of retries are
+// terminated, after being copied to the dead letter subject if there is one.
+type Provider struct {
+	js       jetstream.JetStream
+	conn     *natsgo.Conn
+	consumer jetstream.Consumer
+	messages jetstream.MessagesContext
+
+	maximumRetryCount int
+	jitter            gomainevents.Jitter
+	clock             gomainevents.Clock
+	deadLetterSubject string
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+}
+
+type ProviderConfig struct {
+	// Server to connect to, e.g. nats://localhost:4222. One of URL, Conn
+	// or JetStream is required unless a Consumer is provided.
+	URL string
+
+	// An existing connection. It is not closed by Stop.
+	Conn *natsgo.Conn
+
+	// An existing JetStream context.
+	JetStream jetstream.JetStream
+
+	// Stream to consume from. Required unless a Consumer is provided.
+	Stream string
+
+	// Name of the durable consumer, which is created or updated on
+	// start. Required unless a Consumer is provided.
+	Durable string
+
+	// Only consume these subjects. Defaults to every subject in the
+	// stream.
+	Subjects []string
+
+	// How long the server waits for a Delete or Requeue before
+	// redelivering. Defaults to 30 seconds.
+	AckWait time.Duration
+
+	// Provide your own consumer instead of Stream and Durable.
+	Consumer jetstream.Consumer
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+
+	// Randomizes retry delays, so that events that failed together aren't
+	// retried together. Defaults to none.
+	Jitter gomainevents.Jitter
+
+	// Subject that events exceeding MaximumRetryCount are published to
+	// before being terminated. Optional.
+	DeadLetterSubject string
+
+	// Clock fetch error delays are measured on. Defaults to
+	// gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewProvider(config *ProviderConfig) (*Provider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Consumer {
+		if "" == config.Stream {
+			return nil, errors.New("Stream is required")
+		}
+
+		if "" == config.Durable {
+			return nil, errors.New("Durable is required")
+		}
+	}
+
+	var (
+		js   = config.JetStream
+		conn *natsgo.Conn
+	)
+	if nil == config.Consumer || "" != config.DeadLetterSubject {
+		var err error
+		if js, conn, err = connect(config.URL, config.Conn, config.JetStream); err != nil {
+			return nil, err
+		}
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	consumer := config.Consumer
+	if nil == consumer {
+		ackWait := defaultAckWait
+		if config.AckWait > 0 {
+			ackWait = config.AckWait
+		}
+
+		var err error
+		consumer, err = js.CreateOrUpdateConsumer(context.Background(), config.Stream, jetstream.ConsumerConfig{
+			Durable:        config.Durable,
+			FilterSubjects: config.Subjects,
+			AckPolicy:      jetstream.AckExplicitPolicy,
+			AckWait:        ackWait,
+		})
+		if err != nil {
+			if nil != conn {
+				conn.Close()
+			}
+			return nil, err
+		}
+	}
+
+	return &Provider{
+		js:                js,
+		conn:              conn,
+		consumer:          consumer,
+		maximumRetryCount: maximumRetryCount,
+		jitter:            config.Jitter,
+		clock:             gomainevents.ClockOrDefault(config.Clock),
+		deadLetterSubject: config.DeadLetterSubject,
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}, nil
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	messages, err := p.consumer.Messages()
+	if err != nil {
+		p.reportError(gomainevents.PhaseConnect, "", err)
+		return p.events, p.errors
+	}
+
+	p.closeMu.Lock()
+	p.messages = messages
+	p.closeMu.Unlock()
+
+	go func() {
+		for {
+			msg, err := messages.Next()
+			if err != nil {
+				if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+					return
+				}
+
+				p.reportError(gomainevents.PhaseReceive, "", err)
+
+				select {
+				case <-p.done:
+					return
+				case <-p.clock.After(nextErrorDelay):
+				}
+
+				continue
+			}
+
+			event, err := DecodeEvent(msg)
+			if err != nil {
+				// It will never decode, so don't redeliver it.
+				p.reportError(gomainevents.PhaseDecode, "", err)
+				msg.TermWithReason("undecodable")
+				continue
+			}
+
+			if !p.deliver(*event) {
+				return
+			}
+		}
+	}()
+
+	return p.events, p.errors
+}
+
+// Delete an event that we're done with
+func (p *Provider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to NATS flavor
+
+	if err := evt.msg.Ack(); err != nil {
+		p.reportError(gomainevents.PhaseDelete, "", err)
+	}
+}
+
+// Requeue an event for later. The server redelivers it after a delay.
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to NATS flavor
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		if "" != p.deadLetterSubject {
+			if err := p.sendToDeadLetter(evt); err != nil {
+				// Left unacked, so the server redelivers it.
+				return err
+			}
+		}
+
+		evt.msg.TermWithReason("retries exhausted")
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	delay := p.jitter.Apply(evt.Delay())
+	p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", evt.RetryCount(), delay)
+
+	if err := evt.msg.NakWithDelay(delay); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *Provider) sendToDeadLetter(event Event) error {
+	msg := natsgo.NewMsg(p.deadLetterSubject)
+	msg.Data = event.msg.Data()
+	for key, values := range event.msg.Headers() {
+		msg.Header[key] = values
+	}
+	msg.Header.Set(headerRetryCount, strconv.Itoa(event.RetryCount()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := p.js.PublishMsg(ctx, msg)
+	return err
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	close(p.done)
+
+	p.closeMu.Lock()
+	if nil != p.messages {
+		p.messages.Stop()
+	}
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+
+	if nil != p.conn {
+		p.conn.Close()
+	}
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, "", messageID, err):
+	default:
+	}
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-nats] "+format, values...)
+	}
+}
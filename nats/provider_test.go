@@ -0,0 +1,238 @@
+package nats
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockMsg struct {
+	jetstream.Msg
+
+	subject      string
+	data         []byte
+	header       natsgo.Header
+	numDelivered uint64
+
+	mu       sync.Mutex
+	acked    bool
+	nakDelay time.Duration
+	termed   string
+}
+
+func newMockMsg(name string, numDelivered uint64) *mockMsg {
+	return &mockMsg{
+		subject:      Subject("events", name),
+		data:         []byte(`{"name":"` + name + `","data":{"occurredOn":"2018-03-08 11:11:11"}}`),
+		header:       natsgo.Header{},
+		numDelivered: numDelivered,
+	}
+}
+
+func (m *mockMsg) Data() []byte {
+	return m.data
+}
+
+func (m *mockMsg) Subject() string {
+	return m.subject
+}
+
+func (m *mockMsg) Headers() natsgo.Header {
+	return m.header
+}
+
+func (m *mockMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{NumDelivered: m.numDelivered}, nil
+}
+
+func (m *mockMsg) Ack() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.acked = true
+	return nil
+}
+
+func (m *mockMsg) NakWithDelay(delay time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nakDelay = delay
+	return nil
+}
+
+func (m *mockMsg) TermWithReason(reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.termed = reason
+	return nil
+}
+
+type mockMessages struct {
+	jetstream.MessagesContext
+
+	msgs      chan jetstream.Msg
+	stop      chan bool
+	closeOnce sync.Once
+}
+
+func (m *mockMessages) Next(opts ...jetstream.NextOpt) (jetstream.Msg, error) {
+	select {
+	case msg := <-m.msgs:
+		return msg, nil
+	case <-m.stop:
+		return nil, jetstream.ErrMsgIteratorClosed
+	}
+}
+
+func (m *mockMessages) Stop() {
+	m.closeOnce.Do(func() { close(m.stop) })
+}
+
+type mockConsumer struct {
+	jetstream.Consumer
+
+	messages *mockMessages
+}
+
+func newMockConsumer(msgs ...jetstream.Msg) *mockConsumer {
+	messages := &mockMessages{msgs: make(chan jetstream.Msg, len(msgs)), stop: make(chan bool)}
+	for _, msg := range msgs {
+		messages.msgs <- msg
+	}
+
+	return &mockConsumer{messages: messages}
+}
+
+func (m *mockConsumer) Messages(opts ...jetstream.PullMessagesOpt) (jetstream.MessagesContext, error) {
+	return m.messages, nil
+}
+
+type mockJetStream struct {
+	jetstream.JetStream
+
+	mu        sync.Mutex
+	published []*natsgo.Msg
+}
+
+func (m *mockJetStream) PublishMsg(ctx context.Context, msg *natsgo.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.published = append(m.published, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{Consumer: newMockConsumer()})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Durable: "app", JetStream: &mockJetStream{}})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Stream: "EVENTS", JetStream: &mockJetStream{}})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Consumer: newMockConsumer(), DeadLetterSubject: "events.dead"})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+}
+
+func TestProviderAcksAndNaks(t *testing.T) {
+	first, second := newMockMsg("First", 1), newMockMsg("Second", 3)
+	provider, err := NewProvider(&ProviderConfig{Consumer: newMockConsumer(first, second)})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	received := []Event{}
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-events:
+			received = append(received, event.(Event))
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for event")
+		}
+	}
+
+	assert.Equal(t, "First", received[0].Name())
+	assert.Equal(t, "events.First", received[0].Subject())
+	assert.Equal(t, 0, received[0].RetryCount())
+	assert.Equal(t, 2, received[1].RetryCount())
+
+	provider.Delete(received[0])
+	assert.True(t, first.acked)
+
+	assert.Nil(t, provider.Requeue(received[1]))
+	assert.Equal(t, 8*time.Second, second.nakDelay)
+	assert.False(t, second.acked)
+}
+
+func TestProviderDeadLetters(t *testing.T) {
+	msg := newMockMsg("Thing", 3)
+	msg.header.Set(headerEventName, "Thing")
+	js := &mockJetStream{}
+
+	provider, err := NewProvider(&ProviderConfig{
+		Consumer:          newMockConsumer(msg),
+		JetStream:         js,
+		MaximumRetryCount: 1,
+		DeadLetterSubject: "events.dead",
+	})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	select {
+	case event := <-events:
+		err := provider.Requeue(event)
+		assert.IsType(t, &RetryAttemptsExceededError{}, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	assert.Equal(t, "retries exhausted", msg.termed)
+	require.Len(t, js.published, 1)
+	assert.Equal(t, "events.dead", js.published[0].Subject)
+	assert.Equal(t, msg.data, js.published[0].Data)
+	assert.Equal(t, "Thing", js.published[0].Header.Get(headerEventName))
+	assert.Equal(t, "2", js.published[0].Header.Get(headerRetryCount))
+}
+
+func TestProviderTerminatesUndecodableMessages(t *testing.T) {
+	msg := newMockMsg("Thing", 1)
+	msg.data = []byte("nope")
+
+	provider, err := NewProvider(&ProviderConfig{Consumer: newMockConsumer(msg)})
+	require.Nil(t, err)
+
+	_, errs := provider.Start()
+	defer provider.Stop()
+
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error")
+	}
+
+	msg.mu.Lock()
+	defer msg.mu.Unlock()
+	assert.Equal(t, "undecodable", msg.termed)
+}
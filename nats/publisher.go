@@ -0,0 +1,139 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/researchsquare/gomainevents"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Publisher publishes events to JetStream, on a subject derived from the
+// event name. A stream must already capture the subjects.
+type Publisher struct {
+	js      jetstream.JetStream
+	conn    *natsgo.Conn
+	subject SubjectFunc
+	timeout time.Duration
+}
+
+type PublisherConfig struct {
+	// Server to connect to, e.g. nats://localhost:4222. One of URL, Conn
+	// or JetStream is required.
+	URL string
+
+	// An existing connection. It is not closed by Close.
+	Conn *natsgo.Conn
+
+	// An existing JetStream context.
+	JetStream jetstream.JetStream
+
+	// Prefix for derived subjects. Defaults to "events".
+	SubjectPrefix string
+
+	// Chooses the subject for each event, instead of deriving it from
+	// the event name.
+	Subject SubjectFunc
+
+	// Maximum time to wait for the server to acknowledge a publish.
+	// Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+func NewPublisher(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	js, conn, err := connect(config.URL, config.Conn, config.JetStream)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := config.Subject
+	if nil == subject {
+		prefix := defaultSubjectPrefix
+		if "" != config.SubjectPrefix {
+			prefix = config.SubjectPrefix
+		}
+
+		subject = func(event gomainevents.Event) string {
+			return Subject(prefix, event.Name())
+		}
+	}
+
+	timeout := defaultTimeout
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	return &Publisher{
+		js:      js,
+		conn:    conn,
+		subject: subject,
+		timeout: timeout,
+	}, nil
+}
+
+// connect returns a JetStream context from whichever of the options was
+// configured. The connection is returned only if it was opened here.
+func connect(url string, conn *natsgo.Conn, js jetstream.JetStream) (jetstream.JetStream, *natsgo.Conn, error) {
+	if nil != js {
+		return js, nil, nil
+	}
+
+	var owned *natsgo.Conn
+	if nil == conn {
+		if "" == url {
+			return nil, nil, errors.New("URL, Conn or JetStream is required")
+		}
+
+		var err error
+		if conn, err = natsgo.Connect(url); err != nil {
+			return nil, nil, err
+		}
+		owned = conn
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		if nil != owned {
+			owned.Close()
+		}
+		return nil, nil, err
+	}
+
+	return js, owned, nil
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	data, err := json.Marshal(&encodedEvent{
+		Name: event.Name(),
+		Data: event.Data(),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg := natsgo.NewMsg(p.subject(event))
+	msg.Data = data
+	msg.Header.Set(headerEventName, event.Name())
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	_, err = p.js.PublishMsg(ctx, msg)
+	return err
+}
+
+// Close closes the connection if the publisher opened it.
+func (p *Publisher) Close() {
+	if nil != p.conn {
+		p.conn.Close()
+	}
+}
@@ -0,0 +1,63 @@
+package nats
+
+import (
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(&PublisherConfig{JetStream: &mockJetStream{}})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestPublish(t *testing.T) {
+	js := &mockJetStream{}
+	publisher, err := NewPublisher(&PublisherConfig{JetStream: js, SubjectPrefix: "domain"})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.Publish(testEvent{name: `App\ThingHappened`}))
+
+	require.Len(t, js.published, 1)
+	msg := js.published[0]
+	assert.Equal(t, "domain.App.ThingHappened", msg.Subject)
+	assert.Equal(t, `App\ThingHappened`, msg.Header.Get(headerEventName))
+	assert.JSONEq(t, `{"name":"App\\ThingHappened","data":{"occurredOn":"2018-03-08 11:11:11"}}`, string(msg.Data))
+}
+
+func TestPublishWithSubjectFunc(t *testing.T) {
+	js := &mockJetStream{}
+	publisher, err := NewPublisher(&PublisherConfig{
+		JetStream: js,
+		Subject: func(event gomainevents.Event) string {
+			return "custom." + event.Name()
+		},
+	})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+	assert.Equal(t, "custom.Thing", js.published[0].Subject)
+}
@@ -0,0 +1,40 @@
+package nats
+
+import (
+	"strings"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const defaultSubjectPrefix = "events"
+
+// SubjectFunc returns the subject to publish an event on.
+type SubjectFunc func(gomainevents.Event) string
+
+// Subject derives a subject from an event name under the prefix. Namespace
+// separators become subject tokens, so
+// ResearchSquare\App\Domain\Model\ThingHappened is published on
+// events.ResearchSquare.App.Domain.Model.ThingHappened and can be matched
+// with wildcards such as events.ResearchSquare.App.>.
+func Subject(prefix string, name string) string {
+	tokens := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '\\' || r == '/' || r == '.'
+	})
+
+	for i, token := range tokens {
+		// Whitespace and wildcards aren't allowed in subjects.
+		tokens[i] = strings.Map(func(r rune) rune {
+			switch r {
+			case ' ', '\t', '\r', '\n', '*', '>':
+				return '_'
+			}
+			return r
+		}, token)
+	}
+
+	if "" != prefix {
+		tokens = append([]string{prefix}, tokens...)
+	}
+
+	return strings.Join(tokens, ".")
+}
@@ -0,0 +1,14 @@
+package nats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubject(t *testing.T) {
+	assert.Equal(t, "events.ResearchSquare.App.Domain.Model.ThingHappened", Subject("events", `ResearchSquare\App\Domain\Model\ThingHappened`))
+	assert.Equal(t, "events.Thing_Happened", Subject("events", "Thing Happened"))
+	assert.Equal(t, "events.a._", Subject("events", "a.*"))
+	assert.Equal(t, "ThingHappened", Subject("", "ThingHappened"))
+}
@@ -0,0 +1,49 @@
+package outbox
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect is the flavor of SQL spoken by the database. Both need to support
+// SELECT ... FOR UPDATE SKIP LOCKED: PostgreSQL 9.5 or MySQL 8 and later.
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	MySQL
+)
+
+// placeholder returns the nth (1-based) query parameter.
+func (d Dialect) placeholder(n int) string {
+	if MySQL == d {
+		return "?"
+	}
+
+	return "$" + strconv.Itoa(n)
+}
+
+// placeholders returns count parameters, starting from the nth.
+func (d Dialect) placeholders(n, count int) string {
+	params := make([]string, count)
+	for i := range params {
+		params[i] = d.placeholder(n + i)
+	}
+
+	return strings.Join(params, ", ")
+}
+
+// quote quotes an optionally schema qualified identifier.
+func (d Dialect) quote(name string) string {
+	quote := `"`
+	if MySQL == d {
+		quote = "`"
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.Replace(part, quote, quote+quote, -1) + quote
+	}
+
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,47 @@
+package outbox
+
+import (
+	"math"
+	"time"
+)
+
+// Event implements the standard domain event interface for events read from
+// the outbox table.
+type Event struct {
+	name string
+	data map[string]interface{}
+
+	// ID of the event's row.
+	id int64
+
+	// Number of times the event has been requeued, kept in the row's
+	// attempts column.
+	retryCount int
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// ID returns the ID of the event's row.
+func (e Event) ID() int64 {
+	return e.id
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
+
+// Delay returns how long to wait before redelivering this event.
+func (e Event) Delay() time.Duration {
+	return time.Duration(math.Min(
+		math.Pow(2, float64(e.retryCount+1)),
+		15*60, // Max is 15 minutes
+	)) * time.Second
+}
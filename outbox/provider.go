@@ -0,0 +1,425 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultMaximumRetryCount = 25
+	defaultTable             = "outbox"
+	defaultBatchSize         = 10
+	defaultPollInterval      = time.Second
+	defaultLease             = 30 * time.Second
+)
+
+// Provider polls an outbox table for events that producers recorded in the
+// same transaction as the changes they describe. The table needs these
+// columns, shown for PostgreSQL:
+//
+//	CREATE TABLE outbox (
+//	    id           BIGSERIAL PRIMARY KEY,
+//	    name         TEXT NOT NULL,
+//	    data         JSONB NOT NULL,
+//	    attempts     INT NOT NULL DEFAULT 0,
+//	    available_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC'),
+//	    processed_at TIMESTAMP
+//	);
+//
+// Rows are claimed with SELECT ... FOR UPDATE SKIP LOCKED and leased by
+// pushing available_at forward, so any number of providers can poll the
+// same table. If an event isn't deleted or requeued before its lease runs
+// out, e.g. because the process died, it is delivered again.
+//
+// Deleting an event sets processed_at, or deletes the row when
+// DeleteProcessed is set. Requeuing increments attempts and delays the row.
+// Rows that exceed MaximumRetryCount are marked processed and left in the
+// table, recognizable by their attempts.
+type Provider struct {
+	db      *sql.DB
+	dialect Dialect
+	table   string
+
+	batchSize       int
+	pollInterval    time.Duration
+	lease           time.Duration
+	deleteProcessed bool
+
+	maximumRetryCount int
+	jitter            gomainevents.Jitter
+	clock             gomainevents.Clock
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+
+	polledMu sync.Mutex
+	polledAt time.Time
+}
+
+type Config struct {
+	// Database to poll. Required
+	DB *sql.DB
+
+	// SQL flavor of the database. Defaults to Postgres.
+	Dialect Dialect
+
+	// Outbox table, optionally schema qualified. Defaults to "outbox".
+	Table string
+
+	// Maximum number of rows claimed at once. Defaults to 10.
+	BatchSize int
+
+	// How long to wait before polling again once the table has been
+	// drained. Defaults to 1 second.
+	PollInterval time.Duration
+
+	// How long a claimed row is kept from other pollers. Should be longer
+	// than handling an event takes. Defaults to 30 seconds.
+	Lease time.Duration
+
+	// Delete rows once they're processed instead of setting processed_at.
+	DeleteProcessed bool
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+
+	// Randomizes retry delays, so that events that failed together aren't
+	// retried together. Defaults to none.
+	Jitter gomainevents.Jitter
+
+	// Clock PollInterval, leases and requeue delays are measured on.
+	// Defaults to gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewProvider(config *Config) (*Provider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.DB {
+		return nil, errors.New("DB is required")
+	}
+
+	table := config.Table
+	if "" == table {
+		table = defaultTable
+	}
+
+	batchSize := defaultBatchSize
+	if config.BatchSize > 0 {
+		batchSize = config.BatchSize
+	}
+
+	pollInterval := defaultPollInterval
+	if config.PollInterval > 0 {
+		pollInterval = config.PollInterval
+	}
+
+	lease := defaultLease
+	if config.Lease > 0 {
+		lease = config.Lease
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Provider{
+		db:                config.DB,
+		dialect:           config.Dialect,
+		table:             config.Dialect.quote(table),
+		batchSize:         batchSize,
+		pollInterval:      pollInterval,
+		lease:             lease,
+		deleteProcessed:   config.DeleteProcessed,
+		maximumRetryCount: maximumRetryCount,
+		jitter:            config.Jitter,
+		clock:             gomainevents.ClockOrDefault(config.Clock),
+		ctx:               ctx,
+		cancel:            cancel,
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}, nil
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	p.debugPrint("Polling for events from %s\n", p.table)
+
+	go func() {
+		for {
+			events, err := p.claim()
+			if err != nil && nil == p.ctx.Err() {
+				p.reportError(gomainevents.PhaseReceive, "", err)
+			} else if nil == err {
+				p.polled()
+			}
+
+			for _, event := range events {
+				if !p.deliver(event) {
+					return
+				}
+			}
+
+			// A full batch means there may be more waiting.
+			if len(events) == p.batchSize {
+				continue
+			}
+
+			select {
+			case <-p.done:
+				return
+			case <-p.clock.After(p.pollInterval):
+			}
+		}
+	}()
+
+	return p.events, p.errors
+}
+
+// claim leases a batch of rows that are due.
+func (p *Provider) claim() ([]Event, error) {
+	tx, err := p.db.BeginTx(p.ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := p.clock.Now().UTC()
+
+	rows, err := tx.QueryContext(p.ctx, fmt.Sprintf(
+		"SELECT id, name, data, attempts FROM %s WHERE processed_at IS NULL AND available_at <= %s ORDER BY id LIMIT %d FOR UPDATE SKIP LOCKED",
+		p.table, p.dialect.placeholder(1), p.batchSize,
+	), now)
+	if err != nil {
+		return nil, err
+	}
+
+	events, undecodable, err := p.scan(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// They will never decode, so don't claim them again. This has to happen
+	// within the transaction, which holds their locks.
+	for _, id := range undecodable {
+		if _, err := tx.ExecContext(p.ctx, p.markProcessedQuery(), now, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(events) == 0 {
+		return nil, tx.Commit()
+	}
+
+	args := []interface{}{now.Add(p.lease)}
+	for _, event := range events {
+		args = append(args, event.id)
+	}
+
+	_, err = tx.ExecContext(p.ctx, fmt.Sprintf(
+		"UPDATE %s SET available_at = %s WHERE id IN (%s)",
+		p.table, p.dialect.placeholder(1), p.dialect.placeholders(2, len(events)),
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// scan reads claimed rows. Rows that can't be decoded are returned
+// separately.
+func (p *Provider) scan(rows *sql.Rows) ([]Event, []int64, error) {
+	defer rows.Close()
+
+	events := []Event{}
+	undecodable := []int64{}
+	for rows.Next() {
+		var (
+			event Event
+			data  []byte
+		)
+
+		if err := rows.Scan(&event.id, &event.name, &data, &event.retryCount); err != nil {
+			return nil, nil, err
+		}
+
+		if err := json.Unmarshal(data, &event.data); err != nil {
+			p.reportError(gomainevents.PhaseDecode, strconv.FormatInt(event.id, 10), fmt.Errorf("Unable to decode event %d: %s", event.id, err))
+			undecodable = append(undecodable, event.id)
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return events, undecodable, rows.Err()
+}
+
+// Delete an event that we're done with
+func (p *Provider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to outbox flavor
+
+	if !p.deleteProcessed {
+		p.markProcessed(evt.id)
+		return
+	}
+
+	_, err := p.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = %s", p.table, p.dialect.placeholder(1)), evt.id)
+	if err != nil {
+		p.reportError(gomainevents.PhaseDelete, strconv.FormatInt(evt.id, 10), err)
+	}
+}
+
+func (p *Provider) markProcessed(id int64) {
+	if _, err := p.db.Exec(p.markProcessedQuery(), p.clock.Now().UTC(), id); err != nil {
+		p.reportError(gomainevents.PhaseDelete, strconv.FormatInt(id, 10), err)
+	}
+}
+
+func (p *Provider) markProcessedQuery() string {
+	return fmt.Sprintf(
+		"UPDATE %s SET processed_at = %s WHERE id = %s",
+		p.table, p.dialect.placeholder(1), p.dialect.placeholder(2),
+	)
+}
+
+// Requeue an event for later.
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to outbox flavor
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		p.markProcessed(evt.id)
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	delay := p.jitter.Apply(evt.Delay())
+	p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", evt.RetryCount(), delay)
+
+	_, err := p.db.Exec(fmt.Sprintf(
+		"UPDATE %s SET attempts = attempts + 1, available_at = %s WHERE id = %s",
+		p.table, p.dialect.placeholder(1), p.dialect.placeholder(2),
+	), p.clock.Now().UTC().Add(delay), evt.id)
+
+	// Otherwise it is claimed again once its lease runs out.
+	return err
+}
+
+// Defer an event by making it available again after the delay, without
+// counting an attempt.
+func (p *Provider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to outbox flavor
+
+	p.debugPrint("Deferring event. Delay: %s\n", delay)
+
+	_, err := p.db.Exec(fmt.Sprintf(
+		"UPDATE %s SET available_at = %s WHERE id = %s",
+		p.table, p.dialect.placeholder(1), p.dialect.placeholder(2),
+	), p.clock.Now().UTC().Add(delay), evt.id)
+
+	return err
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	close(p.done)
+	p.cancel()
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+// LastPolledAt returns when the database last answered a poll, or zero if it
+// hasn't yet. See gomainevents.Probes.
+func (p *Provider) LastPolledAt() time.Time {
+	p.polledMu.Lock()
+	defer p.polledMu.Unlock()
+
+	return p.polledAt
+}
+
+func (p *Provider) polled() {
+	p.polledMu.Lock()
+	defer p.polledMu.Unlock()
+
+	p.polledAt = time.Now()
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, p.table, messageID, err):
+	default:
+	}
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-outbox] "+format, values...)
+	}
+}
@@ -0,0 +1,151 @@
+package outbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProvider(t *testing.T, config *Config) (*Provider, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	config.DB = db
+	if 0 == config.PollInterval {
+		config.PollInterval = time.Minute
+	}
+
+	provider, err := NewProvider(config)
+	require.Nil(t, err)
+
+	return provider, mock
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, _ := newTestProvider(t, &Config{})
+	assert.NotNil(t, provider)
+
+	provider, err := NewProvider(&Config{})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+}
+
+func TestDialect(t *testing.T) {
+	assert.Equal(t, "$2, $3", Postgres.placeholders(2, 2))
+	assert.Equal(t, "?, ?", MySQL.placeholders(2, 2))
+
+	assert.Equal(t, `"app"."out""box"`, Postgres.quote(`app.out"box`))
+	assert.Equal(t, "`app`.`outbox`", MySQL.quote("app.outbox"))
+}
+
+func TestProviderClaimsAndDeliversRows(t *testing.T) {
+	provider, mock := newTestProvider(t, &Config{})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, name, data, attempts FROM "outbox" WHERE processed_at IS NULL AND available_at <= $1 ORDER BY id LIMIT 10 FOR UPDATE SKIP LOCKED`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "data", "attempts"}).
+			AddRow(1, "Thing", `{"userId":12}`, 2).
+			AddRow(2, "Broken", `not json`, 0))
+	mock.ExpectExec(`UPDATE "outbox" SET processed_at = $1 WHERE id = $2`).
+		WithArgs(sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "outbox" SET available_at = $1 WHERE id IN ($2)`).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`UPDATE "outbox" SET processed_at = $1 WHERE id = $2`).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	events, errs := provider.Start()
+	defer provider.Stop()
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "Thing", event.Name())
+		assert.Equal(t, map[string]interface{}{"userId": 12.0}, event.Data())
+		assert.Equal(t, int64(1), event.(Event).ID())
+		assert.Equal(t, 2, event.(Event).RetryCount())
+
+		provider.Delete(event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	// The row that didn't decode was reported
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "Unable to decode event 2")
+	default:
+		t.Fatal("Expected an error")
+	}
+
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestProviderRequeue(t *testing.T) {
+	provider, mock := newTestProvider(t, &Config{MaximumRetryCount: 3})
+
+	mock.ExpectExec(`UPDATE "outbox" SET attempts = attempts + 1, available_at = $1 WHERE id = $2`).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(t, provider.Requeue(Event{id: 1, name: "Thing", retryCount: 3}))
+
+	// Given up on, but left in the table
+	mock.ExpectExec(`UPDATE "outbox" SET processed_at = $1 WHERE id = $2`).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	err := provider.Requeue(Event{id: 1, name: "Thing", retryCount: 4})
+	assert.IsType(t, &RetryAttemptsExceededError{}, err)
+
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestProviderMySQLDeleteProcessed(t *testing.T) {
+	provider, mock := newTestProvider(t, &Config{Dialect: MySQL, Table: "events_outbox", DeleteProcessed: true, BatchSize: 1})
+
+	// Deleting races with the next poll
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, name, data, attempts FROM `events_outbox` WHERE processed_at IS NULL AND available_at <= ? ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "data", "attempts"}).AddRow(7, "Thing", `{}`, 0))
+	mock.ExpectExec("UPDATE `events_outbox` SET available_at = ? WHERE id IN (?)").
+		WithArgs(sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// A full batch is followed straight away by another poll
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, name, data, attempts FROM `events_outbox` WHERE processed_at IS NULL AND available_at <= ? ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "data", "attempts"}))
+	mock.ExpectCommit()
+	mock.ExpectExec("DELETE FROM `events_outbox` WHERE id = ?").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	select {
+	case event := <-events:
+		provider.Delete(event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	assert.Eventually(t, func() bool {
+		return nil == mock.ExpectationsWereMet()
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,33 @@
+package gomainevents
+
+import (
+	"errors"
+)
+
+// PermanentError is returned by handlers for events that will never be
+// handled, however often they're retried, e.g. because they're invalid.
+// With a DeadLetterSink registered, the Listener dead-letters them straight
+// away instead of requeuing them. See Permanent.
+type PermanentError struct {
+	Err error
+}
+
+// Permanent marks an error returned by a handler as permanent.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanent returns whether an error, or one it wraps, is a
+// *PermanentError.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
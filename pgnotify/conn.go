@@ -0,0 +1,29 @@
+package pgnotify
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Conn is the part of a pgx connection used by the Provider. It is
+// satisfied by *pgx.Conn. Pooled connections won't do, since notifications
+// are only delivered to the connection that is listening.
+type Conn interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Close(ctx context.Context) error
+}
+
+// ConnectFunc opens a new connection. It is called again whenever the
+// connection is lost.
+type ConnectFunc func(ctx context.Context) (Conn, error)
+
+// connectTo returns a ConnectFunc for a connection string.
+func connectTo(connString string) ConnectFunc {
+	return func(ctx context.Context) (Conn, error) {
+		return pgx.Connect(ctx, connString)
+	}
+}
@@ -0,0 +1,72 @@
+package pgnotify
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+// Event implements the standard domain event interface for events received
+// as notifications or read from the backing table.
+type Event struct {
+	name string
+	data map[string]interface{}
+
+	// ID of the event's row in the backing table, if it has one.
+	id int64
+
+	// NOTIFY has no redelivery, so the provider keeps track of how many
+	// times it has redelivered the event itself.
+	retryCount int
+}
+
+// encodedEvent is the notification payload. Payloads are limited to 8000
+// bytes, so large events can be sent as just an ID and read from the
+// backing table instead.
+type encodedEvent struct {
+	ID   int64                  `json:"id,omitempty"`
+	Name string                 `json:"name,omitempty"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+func decodePayload(payload string) (*encodedEvent, error) {
+	e := &encodedEvent{}
+	if err := json.Unmarshal([]byte(payload), e); err != nil {
+		return nil, err
+	}
+
+	if "" == e.Name && 0 == e.ID {
+		return nil, errors.New("Notification has neither a name nor an ID")
+	}
+
+	return e, nil
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// ID returns the ID of the event's row in the backing table, or 0 if it
+// didn't come with one.
+func (e Event) ID() int64 {
+	return e.id
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
+
+// Delay returns how long to wait before redelivering this event.
+func (e Event) Delay() time.Duration {
+	return time.Duration(math.Min(
+		math.Pow(2, float64(e.retryCount+1)),
+		15*60, // Max is 15 minutes
+	)) * time.Second
+}
@@ -0,0 +1,319 @@
+package pgnotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockDatabase hands out connections that share a table and a channel of
+// notifications.
+type mockDatabase struct {
+	mu            sync.Mutex
+	rows          []Event
+	notifications chan *pgconn.Notification
+	listened      []string
+	connects      int
+	failConnects  int
+}
+
+func newMockDatabase() *mockDatabase {
+	return &mockDatabase{notifications: make(chan *pgconn.Notification, 10)}
+}
+
+func (d *mockDatabase) connect(ctx context.Context) (Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.connects++
+	if d.connects <= d.failConnects {
+		return nil, errors.New("Connection refused")
+	}
+
+	return &mockConn{db: d}, nil
+}
+
+func (d *mockDatabase) insert(id int64, name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.rows = append(d.rows, Event{id: id, name: name, data: map[string]interface{}{"id": float64(id)}})
+}
+
+func (d *mockDatabase) notify(payload string) {
+	d.notifications <- &pgconn.Notification{Channel: "events", Payload: payload}
+}
+
+type mockConn struct {
+	db *mockDatabase
+}
+
+func (c *mockConn) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.db.listened = append(c.db.listened, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *mockConn) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if strings.Contains(sql, "MAX(id)") {
+		var max int64
+		for _, row := range c.db.rows {
+			if row.id > max {
+				max = row.id
+			}
+		}
+
+		return &mockRows{values: [][]any{{max}}}, nil
+	}
+
+	id := args[0].(int64)
+	rows := &mockRows{}
+	for _, row := range c.db.rows {
+		if (strings.Contains(sql, "id > $1") && row.id > id) || (strings.Contains(sql, "id = $1") && row.id == id) {
+			data, _ := json.Marshal(row.data)
+			rows.values = append(rows.values, []any{row.id, row.name, data})
+		}
+	}
+
+	return rows, nil
+}
+
+func (c *mockConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	select {
+	case notification := <-c.db.notifications:
+		if nil == notification {
+			return nil, errors.New("Connection lost")
+		}
+
+		return notification, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *mockConn) Close(ctx context.Context) error {
+	return nil
+}
+
+type mockRows struct {
+	pgx.Rows
+
+	values  [][]any
+	current []any
+}
+
+func (r *mockRows) Next() bool {
+	if len(r.values) == 0 {
+		return false
+	}
+
+	r.current, r.values = r.values[0], r.values[1:]
+	return true
+}
+
+func (r *mockRows) Scan(dest ...any) error {
+	for i, value := range r.current {
+		switch d := dest[i].(type) {
+		case *int64:
+			*d = value.(int64)
+		case *string:
+			*d = value.(string)
+		case *[]byte:
+			*d = value.([]byte)
+		}
+	}
+
+	return nil
+}
+
+func (r *mockRows) Close() {}
+
+func (r *mockRows) Err() error {
+	return nil
+}
+
+func newTestProvider(t *testing.T, db *mockDatabase, table string, startID int64) *Provider {
+	provider, err := NewProvider(&Config{
+		Connect:        db.connect,
+		Channel:        "events",
+		Table:          table,
+		StartID:        startID,
+		ReconnectDelay: 10 * time.Millisecond,
+	})
+	require.Nil(t, err)
+
+	return provider
+}
+
+func receive(t *testing.T, events <-chan gomainevents.Event) Event {
+	select {
+	case event := <-events:
+		return event.(Event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	return Event{}
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&Config{ConnString: "postgres://localhost/app", Channel: "events"})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&Config{ConnString: "postgres://localhost/app"})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&Config{Channel: "events"})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+}
+
+func TestProviderDeliversNotifications(t *testing.T) {
+	db := newMockDatabase()
+	provider := newTestProvider(t, db, "", 0)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	db.notify(`{"name":"Thing","data":{"userId":12}}`)
+
+	event := receive(t, events)
+	assert.Equal(t, "Thing", event.Name())
+	assert.Equal(t, map[string]interface{}{"userId": 12.0}, event.Data())
+
+	db.mu.Lock()
+	assert.Equal(t, []string{`LISTEN "events"`}, db.listened)
+	db.mu.Unlock()
+}
+
+func TestProviderReportsBadPayloads(t *testing.T) {
+	db := newMockDatabase()
+	provider := newTestProvider(t, db, "", 0)
+
+	events, errs := provider.Start()
+	defer provider.Stop()
+
+	db.notify(`not json`)
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error")
+	}
+
+	// Only an ID, with nowhere to read the event from
+	db.notify(`{"id":1}`)
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error")
+	}
+
+	db.notify(`{"name":"Thing"}`)
+	assert.Equal(t, "Thing", receive(t, events).Name())
+}
+
+func TestProviderCatchesUpFromTable(t *testing.T) {
+	db := newMockDatabase()
+	db.insert(1, "Old")
+	db.insert(2, "Missed")
+
+	provider := newTestProvider(t, db, "public.events", 1)
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	missed := receive(t, events)
+	assert.Equal(t, "Missed", missed.Name())
+	assert.Equal(t, int64(2), missed.ID())
+
+	// Notifications for rows already caught up on are skipped
+	db.notify(`{"id":2,"name":"Missed"}`)
+
+	// Large events are read from the table
+	db.insert(3, "Large")
+	db.notify(`{"id":3}`)
+
+	large := receive(t, events)
+	assert.Equal(t, "Large", large.Name())
+	assert.Equal(t, map[string]interface{}{"id": 3.0}, large.Data())
+}
+
+func TestProviderStartsFromNewestRow(t *testing.T) {
+	db := newMockDatabase()
+	db.insert(1, "Old")
+
+	provider := newTestProvider(t, db, "events", 0)
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	db.insert(2, "New")
+	db.notify(`{"id":2,"name":"New"}`)
+
+	assert.Equal(t, "New", receive(t, events).Name())
+}
+
+func TestProviderReconnectsAndCatchesUp(t *testing.T) {
+	db := newMockDatabase()
+	db.failConnects = 1
+
+	provider := newTestProvider(t, db, "events", 0)
+	events, errs := provider.Start()
+	defer provider.Stop()
+
+	// The first connection fails
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error")
+	}
+
+	db.insert(1, "First")
+	db.notify(`{"id":1,"name":"First"}`)
+	assert.Equal(t, "First", receive(t, events).Name())
+
+	// Lose the connection, and a notification with it
+	db.notifications <- nil
+	db.insert(2, "WhileDisconnected")
+
+	assert.Equal(t, "WhileDisconnected", receive(t, events).Name())
+}
+
+func TestProviderRequeue(t *testing.T) {
+	provider := newTestProvider(t, newMockDatabase(), "", 0)
+
+	err := provider.Requeue(Event{name: "Thing", retryCount: 26})
+	assert.IsType(t, &RetryAttemptsExceededError{}, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	// The first retry is after 2 seconds
+	assert.Nil(t, provider.Requeue(Event{name: "Thing"}))
+
+	event := receive(t, events)
+	assert.Equal(t, "Thing", event.Name())
+	assert.Equal(t, 1, event.RetryCount())
+}
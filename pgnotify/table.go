@@ -0,0 +1,83 @@
+package pgnotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// catchUpBatchSize is the number of rows read from the backing table at once.
+const catchUpBatchSize = 100
+
+// table reads events from the backing table, which needs id (an increasing
+// integer), name (text) and data (json or jsonb) columns.
+type table struct {
+	name string
+}
+
+func newTable(name string) *table {
+	return &table{
+		name: pgx.Identifier(strings.Split(name, ".")).Sanitize(),
+	}
+}
+
+// maxID returns the ID of the newest row.
+func (t *table) maxID(ctx context.Context, conn Conn) (int64, error) {
+	rows, err := conn.Query(ctx, fmt.Sprintf("SELECT COALESCE(MAX(id), 0) FROM %s", t.name))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var id int64
+	if rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, rows.Err()
+}
+
+// after returns up to catchUpBatchSize rows with an ID greater than id.
+func (t *table) after(ctx context.Context, conn Conn, id int64) ([]Event, error) {
+	return t.query(ctx, conn, fmt.Sprintf("SELECT id, name, data FROM %s WHERE id > $1 ORDER BY id LIMIT %d", t.name, catchUpBatchSize), id)
+}
+
+// get returns the row with the given ID, if it exists.
+func (t *table) get(ctx context.Context, conn Conn, id int64) ([]Event, error) {
+	return t.query(ctx, conn, fmt.Sprintf("SELECT id, name, data FROM %s WHERE id = $1", t.name), id)
+}
+
+func (t *table) query(ctx context.Context, conn Conn, sql string, args ...any) ([]Event, error) {
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var (
+			event Event
+			data  []byte
+		)
+
+		if err := rows.Scan(&event.id, &event.name, &data); err != nil {
+			return nil, err
+		}
+
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &event.data); err != nil {
+				return nil, err
+			}
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
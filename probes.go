@@ -0,0 +1,146 @@
+package gomainevents
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultMaxPollAge = time.Minute
+
+// PollingProvider is implemented by providers that poll for events, e.g.
+// SQS, so that Probes can tell whether polling is still working.
+type PollingProvider interface {
+	Provider
+
+	// LastPolledAt returns when a poll last succeeded, or zero if none
+	// has yet. Polls that return no events count.
+	LastPolledAt() time.Time
+}
+
+// Probes report whether a Listener is ready and alive, for Kubernetes
+// readiness and liveness probes:
+//
+//	http.Handle("/readyz", probes.ReadinessHandler())
+//	http.Handle("/livez", probes.LivenessHandler())
+//
+// A Listener is ready once it is listening and, if its provider is a
+// PollingProvider, a poll has succeeded. It is alive unless polling has
+// been failing for MaxPollAge, or a handler has been running for
+// MaxHandleTime, since both usually need a restart to fix. A Listener that
+// hasn't started listening is alive, so that a slow start isn't mistaken
+// for a hung one.
+type Probes struct {
+	listener      *Listener
+	maxPollAge    time.Duration
+	maxHandleTime time.Duration
+
+	// Hook for tests
+	now func() time.Time
+}
+
+type ProbesConfig struct {
+	// Listener to report on. Required
+	Listener *Listener
+
+	// How long polls can fail before the Listener isn't alive. Should be
+	// longer than a poll takes, e.g. SQS's 20 second long polls. Defaults
+	// to a minute.
+	MaxPollAge time.Duration
+
+	// How long a handler can run before the Listener isn't alive. Defaults
+	// to no limit.
+	MaxHandleTime time.Duration
+}
+
+func NewProbes(config *ProbesConfig) (*Probes, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Listener {
+		return nil, errors.New("Listener is required")
+	}
+
+	maxPollAge := defaultMaxPollAge
+	if config.MaxPollAge > 0 {
+		maxPollAge = config.MaxPollAge
+	}
+
+	return &Probes{
+		listener:      config.Listener,
+		maxPollAge:    maxPollAge,
+		maxHandleTime: config.MaxHandleTime,
+		now:           time.Now,
+	}, nil
+}
+
+// Ready returns why the Listener isn't ready, or nil if it is.
+func (p *Probes) Ready() error {
+	if p.listener.Stats().StartedAt.IsZero() {
+		return errors.New("Listener hasn't started listening")
+	}
+
+	if poller, ok := p.listener.provider.(PollingProvider); ok && poller.LastPolledAt().IsZero() {
+		return errors.New("Provider hasn't polled successfully yet")
+	}
+
+	return nil
+}
+
+// Live returns why the Listener isn't alive, or nil if it is.
+func (p *Probes) Live() error {
+	startedAt := p.listener.Stats().StartedAt
+	if startedAt.IsZero() {
+		return nil
+	}
+
+	now := p.now()
+
+	if poller, ok := p.listener.provider.(PollingProvider); ok {
+		// Give the first poll as long as the rest.
+		polledAt := poller.LastPolledAt()
+		if polledAt.IsZero() {
+			polledAt = startedAt
+		}
+
+		if age := now.Sub(polledAt); age > p.maxPollAge {
+			return fmt.Errorf("Provider hasn't polled successfully for %s", age.Round(time.Second))
+		}
+	}
+
+	if p.maxHandleTime > 0 {
+		if since := p.listener.handlingSince(); !since.IsZero() && now.Sub(since) > p.maxHandleTime {
+			return fmt.Errorf("A handler has been running for %s", now.Sub(since).Round(time.Second))
+		}
+	}
+
+	return nil
+}
+
+// ReadinessHandler responds 200 OK when the Listener is ready, and 503
+// Service Unavailable with the reason when it isn't.
+func (p *Probes) ReadinessHandler() http.Handler {
+	return probeHandler(p.Ready)
+}
+
+// LivenessHandler responds 200 OK when the Listener is alive, and 503
+// Service Unavailable with the reason when it isn't.
+func (p *Probes) LivenessHandler() http.Handler {
+	return probeHandler(p.Live)
+}
+
+func probeHandler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		if err := check(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, err)
+			return
+		}
+
+		fmt.Fprintln(w, "ok")
+	})
+}
@@ -0,0 +1,136 @@
+package gomainevents
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pollingProvider struct {
+	recordingProvider
+
+	polledMu sync.Mutex
+	polledAt time.Time
+}
+
+func (p *pollingProvider) LastPolledAt() time.Time {
+	p.polledMu.Lock()
+	defer p.polledMu.Unlock()
+
+	return p.polledAt
+}
+
+func (p *pollingProvider) poll(at time.Time) {
+	p.polledMu.Lock()
+	defer p.polledMu.Unlock()
+
+	p.polledAt = at
+}
+
+func TestNewProbes(t *testing.T) {
+	probes, err := NewProbes(nil)
+	assert.Nil(t, probes)
+	assert.NotNil(t, err)
+
+	probes, err = NewProbes(&ProbesConfig{})
+	assert.Nil(t, probes)
+	assert.NotNil(t, err)
+
+	probes, err = NewProbes(&ProbesConfig{Listener: NewListener(&recordingProvider{})})
+	require.Nil(t, err)
+	assert.Equal(t, time.Minute, probes.maxPollAge)
+}
+
+func TestProbesPolling(t *testing.T) {
+	provider := &pollingProvider{recordingProvider: recordingProvider{events: make(chan Event)}}
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Created", func(Event) error { return nil })
+
+	probes, err := NewProbes(&ProbesConfig{Listener: listener, MaxPollAge: 30 * time.Second})
+	require.Nil(t, err)
+
+	now := time.Now()
+	probes.now = func() time.Time { return now }
+
+	// Not started
+	assert.NotNil(t, probes.Ready())
+	assert.Nil(t, probes.Live())
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	assert.Eventually(t, func() bool { return !listener.Stats().StartedAt.IsZero() }, 5*time.Second, time.Millisecond)
+
+	// Started, but hasn't polled
+	assert.NotNil(t, probes.Ready())
+	assert.Nil(t, probes.Live())
+
+	provider.poll(now)
+	assert.Nil(t, probes.Ready())
+	assert.Nil(t, probes.Live())
+
+	// Polls started failing
+	now = now.Add(time.Minute)
+	assert.Nil(t, probes.Ready())
+	assert.NotNil(t, probes.Live())
+
+	provider.poll(now)
+	assert.Nil(t, probes.Live())
+}
+
+func TestProbesHandleTime(t *testing.T) {
+	provider := &recordingProvider{events: make(chan Event)}
+	release := make(chan bool)
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Created", func(Event) error {
+		<-release
+		return nil
+	})
+
+	probes, err := NewProbes(&ProbesConfig{Listener: listener, MaxHandleTime: time.Minute})
+	require.Nil(t, err)
+
+	now := time.Now()
+	probes.now = func() time.Time { return now }
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	provider.events <- testEvent{name: "Created"}
+	assert.Eventually(t, func() bool { return !listener.handlingSince().IsZero() }, 5*time.Second, time.Millisecond)
+
+	// Providers that don't poll are ready once listening.
+	assert.Nil(t, probes.Ready())
+	assert.Nil(t, probes.Live())
+
+	now = now.Add(2 * time.Minute)
+	assert.NotNil(t, probes.Live())
+
+	release <- true
+	assert.Eventually(t, func() bool { return listener.Stats().Processed == 1 }, 5*time.Second, time.Millisecond)
+	assert.Nil(t, probes.Live())
+}
+
+func TestProbesHandlers(t *testing.T) {
+	probes, err := NewProbes(&ProbesConfig{Listener: NewListener(&recordingProvider{})})
+	require.Nil(t, err)
+
+	recorder := httptest.NewRecorder()
+	probes.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "Listener hasn't started listening\n", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	probes.LivenessHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/livez", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ok\n", recorder.Body.String())
+}
@@ -0,0 +1,74 @@
+package gomainevents
+
+// ErrorPhase is what a provider was doing when an error happened.
+type ErrorPhase string
+
+const (
+	// PhaseUnknown is for errors from providers that don't say.
+	PhaseUnknown ErrorPhase = ""
+
+	// PhaseConnect covers connecting, subscribing and setting up.
+	PhaseConnect ErrorPhase = "connect"
+
+	// PhaseReceive covers fetching events.
+	PhaseReceive ErrorPhase = "receive"
+
+	// PhaseDecode covers turning a message into an event. Decoding the same
+	// message again won't help.
+	PhaseDecode ErrorPhase = "decode"
+
+	// PhaseDelete covers deleting, acknowledging or checkpointing events
+	// that were handled.
+	PhaseDelete ErrorPhase = "delete"
+
+	// PhaseRequeue covers putting events back for another try.
+	PhaseRequeue ErrorPhase = "requeue"
+)
+
+// ProviderError is an error sent on a provider's error channel, saying what
+// went wrong and where. Its message is the underlying error's, so that
+// logging it reads the same as logging the underlying error.
+type ProviderError struct {
+	Phase ErrorPhase
+
+	// Queue, topic, stream or whatever else the provider reads from.
+	Source string
+
+	// ID of the message the error is about, if it's about one.
+	MessageID string
+
+	// Whether trying again might succeed. Decode errors never do.
+	Retryable bool
+
+	// Whether the provider has given up and won't deliver any more
+	// events, e.g. because its queue was deleted. The Listener shuts down
+	// and Listen returns the error.
+	Fatal bool
+
+	Err error
+}
+
+// NewProviderError wraps err with what a provider was doing. Errors that
+// already are ProviderErrors, e.g. from a provider wrapping another one, are
+// returned as they are.
+func NewProviderError(phase ErrorPhase, source string, messageID string, err error) *ProviderError {
+	if providerErr, ok := err.(*ProviderError); ok {
+		return providerErr
+	}
+
+	return &ProviderError{
+		Phase:     phase,
+		Source:    source,
+		MessageID: messageID,
+		Retryable: phase != PhaseDecode,
+		Err:       err,
+	}
+}
+
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
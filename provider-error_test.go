@@ -0,0 +1,72 @@
+package gomainevents
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProviderError(t *testing.T) {
+	err := NewProviderError(PhaseDelete, "queue", "1234", errors.New("Nope"))
+	assert.Equal(t, PhaseDelete, err.Phase)
+	assert.Equal(t, "queue", err.Source)
+	assert.Equal(t, "1234", err.MessageID)
+	assert.True(t, err.Retryable)
+	assert.EqualError(t, err, "Nope")
+	assert.EqualError(t, errors.Unwrap(err), "Nope")
+
+	// Decoding again won't help
+	assert.False(t, NewProviderError(PhaseDecode, "queue", "1234", errors.New("Nope")).Retryable)
+
+	// Errors passed on from another provider keep their details
+	assert.Equal(t, err, NewProviderError(PhaseUnknown, "", "", err))
+}
+
+type errorProvider struct {
+	events chan Event
+	errors chan error
+}
+
+func (p *errorProvider) Start() (<-chan Event, <-chan error) {
+	return p.events, p.errors
+}
+
+func (p *errorProvider) Delete(Event) {}
+
+func (p *errorProvider) Requeue(Event) RequeuingEventFailedError {
+	return nil
+}
+
+func (p *errorProvider) Stop() {}
+
+func TestListenerRoutesProviderErrors(t *testing.T) {
+	provider := &errorProvider{events: make(chan Event), errors: make(chan error, 2)}
+	provider.errors <- errors.New("Connection refused")
+	provider.errors <- NewProviderError(PhaseDelete, "queue", "1234", errors.New("Access denied"))
+
+	received := make(chan error, 2)
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Thing", func(Event) error { return nil })
+	listener.RegisterErrorHandler(func(err error) { received <- err })
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	for _, expected := range []*ProviderError{
+		{Phase: PhaseUnknown, Retryable: true, Err: errors.New("Connection refused")},
+		{Phase: PhaseDelete, Source: "queue", MessageID: "1234", Retryable: true, Err: errors.New("Access denied")},
+	} {
+		select {
+		case err := <-received:
+			var providerErr *ProviderError
+			require.True(t, errors.As(err, &providerErr))
+			assert.Equal(t, expected, providerErr)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for error")
+		}
+	}
+}
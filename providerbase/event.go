@@ -0,0 +1,44 @@
+package providerbase
+
+// Message is an event as a Transport fetched it.
+type Message struct {
+	// ID of the message, used in errors. Optional.
+	ID string
+
+	Name string
+	Data map[string]interface{}
+
+	// Number of times the message has been delivered, but not processed.
+	// Requeue increments it before passing the message to Nack, for
+	// transports that resend messages rather than redeliver them.
+	RetryCount int
+
+	// Whatever the Transport needs to Ack or Nack the message, e.g. a
+	// receipt handle or delivery tag.
+	Handle interface{}
+}
+
+// Event implements the standard domain event interface for messages fetched
+// by a Transport.
+type Event struct {
+	message Message
+}
+
+func (e Event) Name() string {
+	return e.message.Name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.message.Data
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.message.RetryCount
+}
+
+// Message returns the message the event was fetched as.
+func (e Event) Message() Message {
+	return e.message
+}
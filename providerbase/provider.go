@@ -0,0 +1,340 @@
+// Package providerbase implements the parts of a provider every transport
+// needs: delivering events to the Listener, counting retries, backing off,
+// reporting errors and stopping cleanly. Custom providers implement a
+// Transport that fetches, acks and nacks messages, and leave the rest to
+// Provider.
+package providerbase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultMaximumRetryCount = 25
+	defaultBufferSize        = 100
+	defaultErrorDelay        = time.Second
+)
+
+// Transport fetches messages from a broker and settles them. Fetch is called
+// in a loop of its own, while Ack and Nack are called by the Listener's
+// workers, one at a time.
+type Transport interface {
+	// Fetch waits for messages until some arrive or the context is done.
+	// Returning a *gomainevents.ProviderError that is Fatal stops the
+	// provider fetching.
+	Fetch(ctx context.Context) ([]Message, error)
+
+	// Ack removes a message that was handled.
+	Ack(message Message) error
+
+	// Nack hands a message back to be redelivered after the delay.
+	Nack(message Message, delay time.Duration) error
+}
+
+// Provider delivers the messages a Transport fetches to the Listener.
+type Provider struct {
+	transport Transport
+	name      string
+
+	maximumRetryCount int
+	backoff           func(retryCount int) time.Duration
+	jitter            gomainevents.Jitter
+	errorDelay        time.Duration
+	clock             gomainevents.Clock
+
+	// Serializes calls to Ack and Nack.
+	transportMu sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+}
+
+type Config struct {
+	// Fetches and settles the messages. Required
+	Transport Transport
+
+	// Name of the transport, used in logs and as the source of errors.
+	// Defaults to providerbase.
+	Name string
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+
+	// How long to wait before redelivering an event that has been retried
+	// the given number of times. Defaults to 2, 4, 8... seconds, up to 15
+	// minutes.
+	Backoff func(retryCount int) time.Duration
+
+	// Randomizes retry delays, so that events that failed together aren't
+	// retried together. Defaults to none.
+	Jitter gomainevents.Jitter
+
+	// How long to wait before fetching again after an error. Defaults to a
+	// second.
+	ErrorDelay time.Duration
+
+	// Most events fetched but not yet taken by the Listener. Defaults to
+	// 100.
+	BufferSize int
+
+	// Clock error delays are measured on. Defaults to
+	// gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func New(config *Config) (*Provider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Transport {
+		return nil, errors.New("Transport is required")
+	}
+
+	name := config.Name
+	if "" == name {
+		name = "providerbase"
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	backoff := config.Backoff
+	if nil == backoff {
+		backoff = defaultBackoff
+	}
+
+	errorDelay := defaultErrorDelay
+	if config.ErrorDelay > 0 {
+		errorDelay = config.ErrorDelay
+	}
+
+	bufferSize := defaultBufferSize
+	if config.BufferSize > 0 {
+		bufferSize = config.BufferSize
+	}
+
+	clock := gomainevents.ClockOrDefault(config.Clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Provider{
+		transport:         config.Transport,
+		name:              name,
+		maximumRetryCount: maximumRetryCount,
+		backoff:           backoff,
+		jitter:            config.Jitter,
+		errorDelay:        errorDelay,
+		clock:             clock,
+		ctx:               ctx,
+		cancel:            cancel,
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, bufferSize),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}, nil
+}
+
+// defaultBackoff doubles the delay with every retry, from 2 seconds up to 15
+// minutes.
+func defaultBackoff(retryCount int) time.Duration {
+	return time.Duration(math.Min(
+		math.Pow(2, float64(retryCount+1)),
+		15*60, // Max is 15 minutes
+	)) * time.Second
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	p.debugPrint("Listening for events\n")
+
+	go p.fetch()
+
+	return p.events, p.errors
+}
+
+// fetch passes on messages from the transport until the provider is stopped.
+func (p *Provider) fetch() {
+	for {
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+
+		messages, err := p.transport.Fetch(p.ctx)
+		if err != nil {
+			// Fetches cut short by Stop aren't errors.
+			select {
+			case <-p.done:
+				return
+			default:
+			}
+
+			providerErr := gomainevents.NewProviderError(gomainevents.PhaseReceive, p.name, "", err)
+			if providerErr.Fatal {
+				p.reportFatal(providerErr)
+				return
+			}
+
+			p.reportError(providerErr)
+
+			select {
+			case <-p.done:
+				return
+			case <-p.clock.After(p.errorDelay):
+			}
+
+			continue
+		}
+
+		for _, message := range messages {
+			if !p.deliver(Event{message: message}) {
+				return
+			}
+		}
+	}
+}
+
+// Delete an event that we're done with
+func (p *Provider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to our flavor
+
+	p.transportMu.Lock()
+	err := p.transport.Ack(evt.message)
+	p.transportMu.Unlock()
+
+	if err != nil {
+		p.reportError(gomainevents.NewProviderError(gomainevents.PhaseDelete, p.name, evt.message.ID, err))
+	}
+}
+
+// Requeue an event for later. The transport redelivers it after a delay.
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to our flavor
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	delay := p.jitter.Apply(p.backoff(evt.RetryCount()))
+	p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", evt.RetryCount()+1, delay)
+
+	message := evt.message
+	message.RetryCount++
+
+	p.transportMu.Lock()
+	defer p.transportMu.Unlock()
+
+	return p.transport.Nack(message, delay)
+}
+
+// Defer an event. The transport redelivers it after the delay with the same
+// retry count.
+func (p *Provider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to our flavor
+
+	p.debugPrint("Deferring event. Delay: %s\n", delay)
+
+	p.transportMu.Lock()
+	defer p.transportMu.Unlock()
+
+	return p.transport.Nack(evt.message, delay)
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	close(p.done)
+	p.cancel()
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(err *gomainevents.ProviderError) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- err:
+	default:
+	}
+}
+
+// reportFatal passes on an error the provider can't recover from, waiting
+// for it to be read, since the Listener has to see it to shut down.
+func (p *Provider) reportFatal(err *gomainevents.ProviderError) {
+	p.debugPrint("Fatal error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- err:
+	}
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-"+p.name+"] "+format, values...)
+	}
+}
@@ -0,0 +1,158 @@
+package providerbase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/gomaineventstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTransport fetches the messages and errors sent to it and records how
+// they were settled.
+type fakeTransport struct {
+	fetched chan []Message
+	errors  chan error
+
+	mu     sync.Mutex
+	acked  []Message
+	nacked []Message
+	delays []time.Duration
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{fetched: make(chan []Message), errors: make(chan error)}
+}
+
+func (f *fakeTransport) Fetch(ctx context.Context) ([]Message, error) {
+	select {
+	case messages := <-f.fetched:
+		return messages, nil
+	case err := <-f.errors:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *fakeTransport) Ack(message Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.acked = append(f.acked, message)
+	return nil
+}
+
+func (f *fakeTransport) Nack(message Message, delay time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nacked = append(f.nacked, message)
+	f.delays = append(f.delays, delay)
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	provider, err := New(nil)
+	assert.Nil(t, provider)
+	assert.EqualError(t, err, "Configuration is required")
+
+	provider, err = New(&Config{})
+	assert.Nil(t, provider)
+	assert.EqualError(t, err, "Transport is required")
+
+	provider, err = New(&Config{Transport: newFakeTransport()})
+	require.Nil(t, err)
+	assert.Equal(t, "providerbase", provider.name)
+	assert.Equal(t, 25, provider.maximumRetryCount)
+	assert.Equal(t, 100, cap(provider.events))
+}
+
+func TestProviderDeliversAndSettlesEvents(t *testing.T) {
+	transport := newFakeTransport()
+	provider, err := New(&Config{Transport: transport, MaximumRetryCount: 2})
+	require.Nil(t, err)
+	provider.debug = false
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	transport.fetched <- []Message{
+		{ID: "1", Name: "Created", Data: map[string]interface{}{"id": 1}, Handle: "receipt-1"},
+		{ID: "2", Name: "Deleted", RetryCount: 1},
+	}
+
+	created := (<-events).(Event)
+	assert.Equal(t, "Created", created.Name())
+	assert.Equal(t, 1, created.Data()["id"])
+	assert.Equal(t, "receipt-1", created.Message().Handle)
+
+	deleted := (<-events).(Event)
+	assert.Equal(t, 1, deleted.RetryCount())
+
+	provider.Delete(created)
+	assert.Nil(t, provider.Requeue(deleted))
+
+	transport.mu.Lock()
+	require.Len(t, transport.acked, 1)
+	assert.Equal(t, "1", transport.acked[0].ID)
+	require.Len(t, transport.nacked, 1)
+	assert.Equal(t, 2, transport.nacked[0].RetryCount)
+	assert.Equal(t, []time.Duration{4 * time.Second}, transport.delays)
+	transport.mu.Unlock()
+
+	err = provider.Requeue(Event{message: Message{Name: "Deleted", RetryCount: 3}})
+	assert.IsType(t, &RetryAttemptsExceededError{}, err)
+}
+
+func TestProviderRetriesFetchErrors(t *testing.T) {
+	clock := gomaineventstest.NewClock(time.Now())
+	transport := newFakeTransport()
+	provider, err := New(&Config{Transport: transport, Name: "custom", Clock: clock})
+	require.Nil(t, err)
+	provider.debug = false
+
+	events, errs := provider.Start()
+	defer provider.Stop()
+
+	transport.errors <- errors.New("Broker unavailable")
+
+	providerErr := (<-errs).(*gomainevents.ProviderError)
+	assert.Equal(t, gomainevents.PhaseReceive, providerErr.Phase)
+	assert.Equal(t, "custom", providerErr.Source)
+
+	// Fetching waits out the error delay
+	assert.Eventually(t, func() bool { return clock.Pending() > 0 }, 5*time.Second, time.Millisecond)
+	clock.Advance(time.Second)
+
+	transport.fetched <- []Message{{Name: "Created"}}
+	assert.Equal(t, "Created", (<-events).Name())
+}
+
+func TestProviderStopsOnFatalErrors(t *testing.T) {
+	transport := newFakeTransport()
+	provider, err := New(&Config{Transport: transport})
+	require.Nil(t, err)
+	provider.debug = false
+
+	_, errs := provider.Start()
+	defer provider.Stop()
+
+	fatal := gomainevents.NewProviderError(gomainevents.PhaseReceive, "", "", errors.New("Queue deleted"))
+	fatal.Fatal = true
+	transport.errors <- fatal
+
+	assert.Equal(t, fatal, <-errs)
+
+	// Nothing is fetching any more
+	select {
+	case transport.errors <- errors.New("Unreachable"):
+		t.Fatal("Fetched after a fatal error")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
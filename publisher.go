@@ -5,3 +5,12 @@ type Publisher interface {
 	// Publish pushes an individual event to a store
 	Publish(Event) error
 }
+
+// BatchPublisher is a Publisher that can also push several events to a
+// store in as few requests as possible.
+type BatchPublisher interface {
+	Publisher
+
+	// PublishBatch pushes a group of events to a store
+	PublishBatch([]Event) error
+}
@@ -0,0 +1,45 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Channel is the part of amqp091-go's Channel used by the Publisher and
+// Provider.
+type Channel interface {
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Cancel(consumer string, noWait bool) error
+	Close() error
+}
+
+// open returns the configured channel, or dials the URL and opens one. The
+// connection is returned only if it was opened here.
+func open(url string, channel Channel) (Channel, *amqp.Connection, error) {
+	if nil != channel {
+		return channel, nil, nil
+	}
+
+	if "" == url {
+		return nil, nil, errors.New("URL or Channel is required")
+	}
+
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return ch, conn, nil
+}
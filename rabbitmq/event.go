@@ -0,0 +1,92 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// headerRetryCount counts how many times the provider has requeued a
+// message.
+const headerRetryCount = "x-retry-count"
+
+// Event implements the standard domain event interface for events consumed
+// from a RabbitMQ queue.
+type Event struct {
+	name     string
+	data     map[string]interface{}
+	delivery amqp.Delivery
+
+	retryCount int
+}
+
+type encodedEvent struct {
+	Name string                 `json:"name"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// DecodeEvent builds an event from a delivery.
+func DecodeEvent(delivery amqp.Delivery) (*Event, error) {
+	e := &encodedEvent{}
+	if err := json.Unmarshal(delivery.Body, e); err != nil {
+		return nil, err
+	}
+
+	name := e.Name
+	if "" != delivery.Type {
+		name = delivery.Type
+	}
+
+	return &Event{
+		name:       name,
+		data:       e.Data,
+		delivery:   delivery,
+		retryCount: retryCount(delivery.Headers),
+	}, nil
+}
+
+// retryCount reads the retry header, which may come back as any integer
+// type depending on who set it.
+func retryCount(headers amqp.Table) int {
+	switch count := headers[headerRetryCount].(type) {
+	case int:
+		return count
+	case int16:
+		return int(count)
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	default:
+		return 0
+	}
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// RoutingKey returns the key the event was published with.
+func (e Event) RoutingKey() string {
+	return e.delivery.RoutingKey
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
+
+// Delay returns how long to wait before redelivering this event.
+func (e Event) Delay() time.Duration {
+	return time.Duration(math.Min(
+		math.Pow(2, float64(e.retryCount+1)),
+		15*60, // Max is 15 minutes
+	)) * time.Second
+}
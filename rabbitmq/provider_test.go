@@ -0,0 +1,246 @@
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type published struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+type mockChannel struct {
+	mu         sync.Mutex
+	published  []published
+	declared   amqp.Table
+	bindings   []string
+	prefetch   int
+	cancelled  string
+	deliveries chan amqp.Delivery
+}
+
+func newMockChannel(deliveries ...amqp.Delivery) *mockChannel {
+	ch := &mockChannel{deliveries: make(chan amqp.Delivery, len(deliveries))}
+	for _, delivery := range deliveries {
+		ch.deliveries <- delivery
+	}
+
+	return ch
+}
+
+func (m *mockChannel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.published = append(m.published, published{exchange: exchange, key: key, msg: msg})
+	return nil
+}
+
+func (m *mockChannel) publishedMessages() []published {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]published{}, m.published...)
+}
+
+func (m *mockChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	m.prefetch = prefetchCount
+	return nil
+}
+
+func (m *mockChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	m.declared = args
+	return amqp.Queue{Name: name}, nil
+}
+
+func (m *mockChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	m.bindings = append(m.bindings, exchange+":"+key)
+	return nil
+}
+
+func (m *mockChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	return m.deliveries, nil
+}
+
+func (m *mockChannel) Cancel(consumer string, noWait bool) error {
+	m.cancelled = consumer
+	return nil
+}
+
+func (m *mockChannel) Close() error {
+	return nil
+}
+
+// mockAcknowledger records what happened to each delivery tag.
+type mockAcknowledger struct {
+	mu      sync.Mutex
+	results map[uint64]string
+}
+
+func (m *mockAcknowledger) record(tag uint64, result string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.results[tag] = result
+	return nil
+}
+
+func (m *mockAcknowledger) result(tag uint64) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.results[tag]
+}
+
+func (m *mockAcknowledger) Ack(tag uint64, multiple bool) error {
+	return m.record(tag, "ack")
+}
+
+func (m *mockAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	if requeue {
+		return m.record(tag, "nack-requeue")
+	}
+	return m.record(tag, "nack")
+}
+
+func (m *mockAcknowledger) Reject(tag uint64, requeue bool) error {
+	if requeue {
+		return m.record(tag, "reject-requeue")
+	}
+	return m.record(tag, "reject")
+}
+
+func delivery(acknowledger amqp.Acknowledger, tag uint64, name string, retries int32) amqp.Delivery {
+	return amqp.Delivery{
+		Acknowledger: acknowledger,
+		DeliveryTag:  tag,
+		Type:         name,
+		RoutingKey:   name,
+		ContentType:  "application/json",
+		Headers:      amqp.Table{headerRetryCount: retries},
+		Body:         []byte(`{"name":"` + name + `","data":{"occurredOn":"2018-03-08 11:11:11"}}`),
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{Channel: newMockChannel(), Queue: "events"})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Channel: newMockChannel()})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Queue: "events"})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+}
+
+func TestProviderDeclaresQueue(t *testing.T) {
+	channel := newMockChannel()
+	_, err := NewProvider(&ProviderConfig{
+		Channel:            channel,
+		Queue:              "app-events",
+		Prefetch:           5,
+		Exchange:           "domain-events",
+		Bindings:           []string{"Created", "Updated"},
+		DeadLetterExchange: "domain-events-dlx",
+	})
+	require.Nil(t, err)
+
+	assert.Equal(t, 5, channel.prefetch)
+	assert.Equal(t, amqp.Table{"x-dead-letter-exchange": "domain-events-dlx"}, channel.declared)
+	assert.Equal(t, []string{"domain-events:Created", "domain-events:Updated"}, channel.bindings)
+}
+
+func TestProviderAcksAndRequeues(t *testing.T) {
+	acks := &mockAcknowledger{results: map[uint64]string{}}
+	channel := newMockChannel(delivery(acks, 1, "First", 0), delivery(acks, 2, "Second", 2))
+
+	provider, err := NewProvider(&ProviderConfig{Channel: channel, Queue: "events", MaximumRetryCount: 5})
+	require.Nil(t, err)
+	provider.requeueDelay = func(Event) time.Duration { return 0 }
+
+	events, _ := provider.Start()
+
+	received := []Event{}
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-events:
+			received = append(received, event.(Event))
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for event")
+		}
+	}
+
+	assert.Equal(t, "First", received[0].Name())
+	assert.Equal(t, 2, received[1].RetryCount())
+
+	provider.Delete(received[0])
+	assert.Equal(t, "ack", acks.result(1))
+
+	assert.Nil(t, provider.Requeue(received[1]))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for acks.result(2) == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "ack", acks.result(2))
+
+	// A copy goes back on the queue with the retry count bumped
+	republished := channel.publishedMessages()
+	require.Len(t, republished, 1)
+	assert.Equal(t, "", republished[0].exchange)
+	assert.Equal(t, "events", republished[0].key)
+	assert.Equal(t, "Second", republished[0].msg.Type)
+	assert.Equal(t, int32(3), republished[0].msg.Headers[headerRetryCount])
+
+	provider.Stop()
+	assert.Equal(t, provider.consumer, channel.cancelled)
+}
+
+func TestProviderRejectsExhaustedEvents(t *testing.T) {
+	acks := &mockAcknowledger{results: map[uint64]string{}}
+	provider, err := NewProvider(&ProviderConfig{Channel: newMockChannel(), Queue: "events", MaximumRetryCount: 1})
+	require.Nil(t, err)
+	defer provider.Stop()
+
+	event, err := DecodeEvent(delivery(acks, 1, "Thing", 2))
+	require.Nil(t, err)
+
+	assert.IsType(t, &RetryAttemptsExceededError{}, provider.Requeue(*event))
+	assert.Equal(t, "reject", acks.result(1))
+}
+
+func TestProviderRejectsUndecodableMessages(t *testing.T) {
+	acks := &mockAcknowledger{results: map[uint64]string{}}
+	bad := delivery(acks, 1, "Thing", 0)
+	bad.Body = []byte("nope")
+
+	provider, err := NewProvider(&ProviderConfig{Channel: newMockChannel(bad), Queue: "events"})
+	require.Nil(t, err)
+
+	_, errs := provider.Start()
+	defer provider.Stop()
+
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error")
+	}
+
+	assert.Equal(t, "reject", acks.result(1))
+}
@@ -0,0 +1,60 @@
+package rabbitmq
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(&PublisherConfig{Channel: newMockChannel(), Exchange: "domain-events"})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Channel: newMockChannel()})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Exchange: "domain-events"})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestPublish(t *testing.T) {
+	channel := newMockChannel()
+	publisher, err := NewPublisher(&PublisherConfig{Channel: channel, Exchange: "domain-events"})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+
+	messages := channel.publishedMessages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "domain-events", messages[0].exchange)
+	assert.Equal(t, "Thing", messages[0].key)
+	assert.Equal(t, "Thing", messages[0].msg.Type)
+	assert.Equal(t, amqp.Persistent, messages[0].msg.DeliveryMode)
+	assert.JSONEq(t, `{"name":"Thing","data":{"occurredOn":"2018-03-08 11:11:11"}}`, string(messages[0].msg.Body))
+
+	event, err := DecodeEvent(amqp.Delivery{Type: messages[0].msg.Type, Body: messages[0].msg.Body})
+	require.Nil(t, err)
+	assert.Equal(t, "Thing", event.Name())
+	assert.Equal(t, 0, event.RetryCount())
+}
@@ -0,0 +1,35 @@
+package gomainevents
+
+import (
+	"encoding/json"
+)
+
+// RawEvent is implemented by events whose provider kept the JSON their data
+// was received as, so that it can be decoded straight into a struct without
+// building a map first.
+type RawEvent interface {
+	Event
+
+	// RawData returns the JSON object the event's data was received as, or
+	// nil if there isn't one. It must not be modified.
+	RawData() []byte
+}
+
+// DecodeData decodes an event's data into v, which is usually a pointer to
+// a struct, the way encoding/json would. Events that implement RawEvent are
+// decoded from their JSON directly, which is much cheaper than going
+// through Data. Other events' data is encoded as JSON first.
+func DecodeData(event Event, v interface{}) error {
+	if raw, ok := event.(RawEvent); ok {
+		if data := raw.RawData(); nil != data {
+			return json.Unmarshal(data, v)
+		}
+	}
+
+	encoded, err := json.Marshal(event.Data())
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(encoded, v)
+}
@@ -0,0 +1,40 @@
+package gomainevents
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rawTestEvent struct {
+	testEvent
+	raw []byte
+}
+
+func (e rawTestEvent) RawData() []byte {
+	return e.raw
+}
+
+func TestDecodeData(t *testing.T) {
+	var decoded struct {
+		UserID int64  `json:"userId"`
+		Role   string `json:"role"`
+	}
+
+	event := testEvent{name: "UserCreated", data: map[string]interface{}{"userId": 12, "role": "admin"}}
+	assert.NoError(t, DecodeData(event, &decoded))
+	assert.Equal(t, int64(12), decoded.UserID)
+	assert.Equal(t, "admin", decoded.Role)
+
+	// The raw data is used rather than the map.
+	raw := rawTestEvent{testEvent: testEvent{name: "UserCreated"}, raw: []byte(`{"userId": 9007199254740993}`)}
+	assert.NoError(t, DecodeData(raw, &decoded))
+	assert.Equal(t, int64(9007199254740993), decoded.UserID)
+
+	// Falling back to the map if there isn't any.
+	raw = rawTestEvent{testEvent: testEvent{name: "UserCreated", data: map[string]interface{}{"role": "author"}}}
+	assert.NoError(t, DecodeData(raw, &decoded))
+	assert.Equal(t, "author", decoded.Role)
+
+	assert.Error(t, DecodeData(rawTestEvent{raw: []byte(`{"userId": "12"}`)}, &decoded))
+}
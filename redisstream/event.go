@@ -0,0 +1,74 @@
+package redisstream
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Stream entry fields.
+const (
+	fieldName = "name"
+	fieldData = "data"
+)
+
+// Event implements the standard domain event interface for events read from
+// a Redis stream.
+type Event struct {
+	name string
+	data map[string]interface{}
+	id   string
+
+	// Number of previous deliveries, from the group's pending entries.
+	retryCount int
+}
+
+// encodeEvent returns the stream entry fields for an event.
+func encodeEvent(name string, data map[string]interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		fieldName: name,
+		fieldData: string(encoded),
+	}, nil
+}
+
+// DecodeEvent builds an event from a stream entry.
+func DecodeEvent(message redis.XMessage) (*Event, error) {
+	name, _ := message.Values[fieldName].(string)
+	if "" == name {
+		return nil, errors.New("Stream entry has no event name")
+	}
+
+	data := map[string]interface{}{}
+	if encoded, ok := message.Values[fieldData].(string); ok {
+		if err := json.Unmarshal([]byte(encoded), &data); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Event{name: name, data: data, id: message.ID}, nil
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// ID returns the stream entry ID.
+func (e Event) ID() string {
+	return e.id
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
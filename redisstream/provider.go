@@ -0,0 +1,425 @@
+package redisstream
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultMaximumRetryCount = 25
+	defaultBatchSize         = 10
+	defaultBlockTimeout      = 5 * time.Second
+	defaultClaimIdle         = 30 * time.Second
+	readErrorDelay           = time.Second
+)
+
+// Provider reads events from a Redis stream as a member of a consumer group.
+// Delete acknowledges the entry with XACK. Requeue leaves it pending: once
+// it has been idle for ClaimIdle it is claimed again with XCLAIM, by this or
+// any other consumer in the group, which is also how entries held by crashed
+// consumers are recovered. Retries are counted by the group's pending
+// entries list.
+type Provider struct {
+	client   redis.UniversalClient
+	stream   string
+	group    string
+	consumer string
+
+	batchSize    int64
+	blockTimeout time.Duration
+	claimIdle    time.Duration
+
+	maximumRetryCount int
+	clock             gomainevents.Clock
+	deadLetterStream  string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+}
+
+type ProviderConfig struct {
+	// Address of the Redis server, e.g. localhost:6379. Either Addr or
+	// Client is required.
+	Addr string
+
+	// Provide your own client, e.g. a cluster client or one with TLS.
+	Client redis.UniversalClient
+
+	// Stream to read from. Required
+	Stream string
+
+	// Consumer group to read as. Created if it doesn't exist. Required
+	Group string
+
+	// Name of this consumer within the group. Must be unique per
+	// process. Defaults to the hostname plus a random suffix.
+	Consumer string
+
+	// Maximum number of entries read at once. Defaults to 10.
+	BatchSize int64
+
+	// How long a read waits for new entries. Defaults to 5 seconds.
+	BlockTimeout time.Duration
+
+	// How long an entry has to be pending before it is claimed for
+	// another attempt. Defaults to 30 seconds.
+	ClaimIdle time.Duration
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+
+	// Stream that events exceeding MaximumRetryCount are copied to.
+	// Optional; without it those events are dropped.
+	DeadLetterStream string
+
+	// Clock read error delays are measured on. Defaults to
+	// gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewProvider(config *ProviderConfig) (*Provider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.Stream {
+		return nil, errors.New("Stream is required")
+	}
+
+	if "" == config.Group {
+		return nil, errors.New("Group is required")
+	}
+
+	client, err := newClient(config.Addr, config.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := config.Consumer
+	if "" == consumer {
+		consumer = newConsumerName()
+	}
+
+	batchSize := int64(defaultBatchSize)
+	if config.BatchSize > 0 {
+		batchSize = config.BatchSize
+	}
+
+	blockTimeout := defaultBlockTimeout
+	if config.BlockTimeout > 0 {
+		blockTimeout = config.BlockTimeout
+	}
+
+	claimIdle := defaultClaimIdle
+	if config.ClaimIdle > 0 {
+		claimIdle = config.ClaimIdle
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Provider{
+		client:            client,
+		stream:            config.Stream,
+		group:             config.Group,
+		consumer:          consumer,
+		batchSize:         batchSize,
+		blockTimeout:      blockTimeout,
+		claimIdle:         claimIdle,
+		maximumRetryCount: maximumRetryCount,
+		clock:             gomainevents.ClockOrDefault(config.Clock),
+		deadLetterStream:  config.DeadLetterStream,
+		ctx:               ctx,
+		cancel:            cancel,
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}, nil
+}
+
+// newConsumerName returns a name that is unique to this process.
+func newConsumerName() string {
+	hostname, _ := os.Hostname()
+	bytes := make([]byte, 4)
+	rand.Read(bytes)
+
+	return hostname + "-" + hex.EncodeToString(bytes)
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	go func() {
+		if err := p.createGroup(); err != nil {
+			p.reportError(gomainevents.PhaseConnect, "", err)
+		}
+
+		var lastClaim time.Time
+		for {
+			// Claiming every read would be wasteful on a busy stream.
+			if time.Since(lastClaim) >= p.claimIdle/2 {
+				lastClaim = time.Now()
+				if !p.claim() {
+					return
+				}
+			}
+
+			if !p.read() {
+				return
+			}
+		}
+	}()
+
+	return p.events, p.errors
+}
+
+// createGroup creates the consumer group, and the stream if necessary,
+// starting from new entries.
+func (p *Provider) createGroup() error {
+	err := p.client.XGroupCreateMkStream(p.ctx, p.stream, p.group, "$").Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+
+	return err
+}
+
+// read delivers new entries, returning false once the provider is stopped.
+func (p *Provider) read() bool {
+	streams, err := p.client.XReadGroup(p.ctx, &redis.XReadGroupArgs{
+		Group:    p.group,
+		Consumer: p.consumer,
+		Streams:  []string{p.stream, ">"},
+		Count:    p.batchSize,
+		Block:    p.blockTimeout,
+	}).Result()
+
+	if err == redis.Nil {
+		return true
+	}
+
+	if err != nil {
+		return p.failed(err)
+	}
+
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			if !p.handle(message, 0) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// claim takes over entries that have been pending for too long and delivers
+// them again, returning false once the provider is stopped.
+func (p *Provider) claim() bool {
+	pending, err := p.client.XPendingExt(p.ctx, &redis.XPendingExtArgs{
+		Stream: p.stream,
+		Group:  p.group,
+		Idle:   p.claimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  p.batchSize,
+	}).Result()
+	if err != nil {
+		return p.failed(err)
+	}
+
+	if len(pending) == 0 {
+		return true
+	}
+
+	ids := make([]string, 0, len(pending))
+	deliveries := make(map[string]int64, len(pending))
+	for _, entry := range pending {
+		ids = append(ids, entry.ID)
+		deliveries[entry.ID] = entry.RetryCount
+	}
+
+	messages, err := p.client.XClaim(p.ctx, &redis.XClaimArgs{
+		Stream:   p.stream,
+		Group:    p.group,
+		Consumer: p.consumer,
+		MinIdle:  p.claimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return p.failed(err)
+	}
+
+	for _, message := range messages {
+		// Every previous delivery was a failed attempt.
+		if !p.handle(message, int(deliveries[message.ID])) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// handle decodes and delivers an entry.
+func (p *Provider) handle(message redis.XMessage, retryCount int) bool {
+	event, err := DecodeEvent(message)
+	if err != nil {
+		// It will never decode, so don't leave it pending forever.
+		p.reportError(gomainevents.PhaseDecode, message.ID, err)
+		p.ack(message.ID)
+		return true
+	}
+
+	event.retryCount = retryCount
+	return p.deliver(*event)
+}
+
+// failed reports an error and waits a moment before carrying on. It returns
+// false if the provider was stopped.
+func (p *Provider) failed(err error) bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	p.reportError(gomainevents.PhaseReceive, "", err)
+
+	select {
+	case <-p.done:
+		return false
+	case <-p.clock.After(readErrorDelay):
+		return true
+	}
+}
+
+// Delete an event that we're done with
+func (p *Provider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to Redis flavor
+
+	p.ack(evt.id)
+}
+
+func (p *Provider) ack(id string) {
+	if err := p.client.XAck(context.Background(), p.stream, p.group, id).Err(); err != nil {
+		p.reportError(gomainevents.PhaseDelete, id, err)
+	}
+}
+
+// Requeue an event for later. It stays pending and is claimed again after
+// ClaimIdle.
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to Redis flavor
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		if "" != p.deadLetterStream {
+			if err := p.sendToDeadLetter(evt); err != nil {
+				// Still pending, so it will be tried again.
+				return err
+			}
+		}
+
+		p.ack(evt.id)
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", evt.RetryCount(), p.claimIdle)
+	return nil
+}
+
+func (p *Provider) sendToDeadLetter(event Event) error {
+	values, err := encodeEvent(event.Name(), event.Data())
+	if err != nil {
+		return err
+	}
+
+	values["id"] = event.id
+	values["retryCount"] = event.RetryCount()
+
+	return p.client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: p.deadLetterStream,
+		Values: values,
+	}).Err()
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	close(p.done)
+	p.cancel()
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, p.stream, messageID, err):
+	default:
+	}
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-redisstream] "+format, values...)
+	}
+}
@@ -0,0 +1,143 @@
+package redisstream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{Addr: "localhost:6379", Stream: "events", Group: "app"})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Addr: "localhost:6379", Stream: "events"})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Addr: "localhost:6379", Group: "app"})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Stream: "events", Group: "app"})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+}
+
+func TestPublishAndConsume(t *testing.T) {
+	_, client := newTestClient(t)
+	ctx := context.Background()
+
+	// Create the group first so the events aren't missed
+	require.Nil(t, client.XGroupCreateMkStream(ctx, "events", "app", "$").Err())
+
+	publisher, err := NewPublisher(&PublisherConfig{Client: client, Stream: "events", MaxLen: 100})
+	require.Nil(t, err)
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+
+	provider, err := NewProvider(&ProviderConfig{Client: client, Stream: "events", Group: "app", BlockTimeout: 50 * time.Millisecond})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "Thing", event.Name())
+		assert.Equal(t, map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}, event.Data())
+		assert.Equal(t, 0, event.(Event).RetryCount())
+
+		pending, err := client.XPending(ctx, "events", "app").Result()
+		require.Nil(t, err)
+		assert.Equal(t, int64(1), pending.Count)
+
+		provider.Delete(event)
+
+		pending, err = client.XPending(ctx, "events", "app").Result()
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), pending.Count)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+}
+
+func TestProviderClaimsStuckEntries(t *testing.T) {
+	_, client := newTestClient(t)
+	ctx := context.Background()
+
+	require.Nil(t, client.XGroupCreateMkStream(ctx, "events", "app", "$").Err())
+	values, err := encodeEvent("Thing", nil)
+	require.Nil(t, err)
+	require.Nil(t, client.XAdd(ctx, &redis.XAddArgs{Stream: "events", Values: values}).Err())
+
+	// Another consumer read it and died
+	require.Nil(t, client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "app", Consumer: "crashed", Streams: []string{"events", ">"},
+	}).Err())
+
+	provider, err := NewProvider(&ProviderConfig{
+		Client:       client,
+		Stream:       "events",
+		Group:        "app",
+		BlockTimeout: 20 * time.Millisecond,
+		ClaimIdle:    50 * time.Millisecond,
+	})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "Thing", event.Name())
+		assert.Equal(t, 1, event.(Event).RetryCount())
+
+		// Requeuing leaves it pending, so it is claimed once more
+		assert.Nil(t, provider.Requeue(event))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	select {
+	case event := <-events:
+		assert.Equal(t, 2, event.(Event).RetryCount())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for redelivery")
+	}
+}
+
+func TestProviderDeadLetters(t *testing.T) {
+	_, client := newTestClient(t)
+	ctx := context.Background()
+
+	provider, err := NewProvider(&ProviderConfig{
+		Client:            client,
+		Stream:            "events",
+		Group:             "app",
+		MaximumRetryCount: 1,
+		DeadLetterStream:  "events-dead",
+	})
+	require.Nil(t, err)
+	require.Nil(t, provider.createGroup())
+
+	// Creating it twice is fine
+	require.Nil(t, provider.createGroup())
+
+	err = provider.Requeue(Event{name: "Thing", id: "1-0", data: map[string]interface{}{}, retryCount: 2})
+	assert.IsType(t, &RetryAttemptsExceededError{}, err)
+
+	dead, err := client.XRange(ctx, "events-dead", "-", "+").Result()
+	require.Nil(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "Thing", dead[0].Values[fieldName])
+	assert.Equal(t, "1-0", dead[0].Values["id"])
+	assert.Equal(t, "2", dead[0].Values["retryCount"])
+}
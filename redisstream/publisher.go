@@ -0,0 +1,96 @@
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/researchsquare/gomainevents"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Publisher appends events to a Redis stream with XADD.
+type Publisher struct {
+	client  redis.UniversalClient
+	stream  string
+	maxLen  int64
+	timeout time.Duration
+}
+
+type PublisherConfig struct {
+	// Address of the Redis server, e.g. localhost:6379. Either Addr or
+	// Client is required.
+	Addr string
+
+	// Provide your own client, e.g. a cluster client or one with TLS.
+	Client redis.UniversalClient
+
+	// Stream to append to. Required
+	Stream string
+
+	// Trim the stream to roughly this many entries on every publish.
+	// Optional; the stream grows unbounded without it.
+	MaxLen int64
+
+	// Maximum time allowed for a single publish. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+func NewPublisher(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.Stream {
+		return nil, errors.New("Stream is required")
+	}
+
+	client, err := newClient(config.Addr, config.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultTimeout
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	return &Publisher{
+		client:  client,
+		stream:  config.Stream,
+		maxLen:  config.MaxLen,
+		timeout: timeout,
+	}, nil
+}
+
+// newClient returns the configured client or creates one for the address.
+func newClient(addr string, client redis.UniversalClient) (redis.UniversalClient, error) {
+	if nil != client {
+		return client, nil
+	}
+
+	if "" == addr {
+		return nil, errors.New("Addr or Client is required")
+	}
+
+	return redis.NewClient(&redis.Options{Addr: addr}), nil
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	values, err := encodeEvent(event.Name(), event.Data())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.maxLen,
+		Approx: p.maxLen > 0,
+		Values: values,
+	}).Err()
+}
@@ -0,0 +1,72 @@
+package redisstream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func newTestClient(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return server, client
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(&PublisherConfig{Addr: "localhost:6379", Stream: "events"})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Addr: "localhost:6379"})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Stream: "events"})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestPublish(t *testing.T) {
+	_, client := newTestClient(t)
+
+	publisher, err := NewPublisher(&PublisherConfig{Client: client, Stream: "events"})
+	require.Nil(t, err)
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+
+	entries, err := client.XRange(context.Background(), "events", "-", "+").Result()
+	require.Nil(t, err)
+	require.Len(t, entries, 1)
+
+	event, err := DecodeEvent(entries[0])
+	require.Nil(t, err)
+	assert.Equal(t, "Thing", event.Name())
+	assert.Equal(t, entries[0].ID, event.ID())
+	assert.Equal(t, map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}, event.Data())
+}
+
+func TestDecodeEventRequiresName(t *testing.T) {
+	_, err := DecodeEvent(redis.XMessage{ID: "1-0", Values: map[string]interface{}{fieldData: "{}"}})
+	assert.NotNil(t, err)
+}
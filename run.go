@@ -0,0 +1,58 @@
+package gomainevents
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultDrainTimeout is how long Run waits for the events already
+// received to be handled once it has been told to stop.
+const DefaultDrainTimeout = 30 * time.Second
+
+// Run listens until the process receives SIGINT or SIGTERM, then shuts the
+// Listener down, waiting up to DefaultDrainTimeout for the events already
+// received to be handled. It returns an error if they weren't, or if the
+// Listener failed, so that main can exit non-zero:
+//
+//	if err := gomainevents.Run(listener); err != nil {
+//		log.Fatal(err)
+//	}
+func Run(listener *Listener) error {
+	return RunContext(context.Background(), listener, DefaultDrainTimeout)
+}
+
+// RunContext is Run, but also stops when ctx is done, and waits up to
+// drainTimeout for events to be handled. A second signal while draining
+// gives up straight away.
+func RunContext(ctx context.Context, listener *Listener, drainTimeout time.Duration) error {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- listener.ListenContext(ctx, drainTimeout)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case sig := <-signals:
+		listener.debugPrint("Received %s, shutting down\n", sig)
+		cancel()
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case sig := <-signals:
+		return errors.New("Received " + sig.String() + " while waiting for events to be handled")
+	}
+}
@@ -0,0 +1,208 @@
+package gomainevents
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closingProvider closes its events channel when stopped, like the real
+// providers.
+type closingProvider struct {
+	recordingProvider
+}
+
+func (p *closingProvider) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	close(p.events)
+}
+
+// push queues an event. The channel has to have room for it.
+func (p *closingProvider) push(event Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events <- event
+}
+
+func TestShutdownDrains(t *testing.T) {
+	provider := &closingProvider{recordingProvider{events: make(chan Event, 10)}}
+	release := make(chan bool)
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Created", func(Event) error {
+		<-release
+		return nil
+	})
+
+	go listener.Listen()
+
+	provider.push(testEvent{name: "Created"})
+	provider.push(testEvent{name: "Created"})
+	assert.Eventually(t, func() bool { return !listener.handlingSince().IsZero() }, 5*time.Second, time.Millisecond)
+
+	// Handlers are still running
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, listener.Shutdown(ctx))
+
+	close(release)
+	require.Nil(t, listener.Shutdown(context.Background()))
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	assert.Len(t, provider.deleted, 2)
+}
+
+func TestRunContext(t *testing.T) {
+	provider := &closingProvider{recordingProvider{events: make(chan Event, 10)}}
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Created", func(Event) error { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- RunContext(ctx, listener, time.Second)
+	}()
+
+	provider.push(testEvent{name: "Created"})
+	assert.Eventually(t, func() bool { return listener.Stats().Processed == 1 }, 5*time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext didn't return")
+	}
+}
+
+func TestRunStopsOnSignal(t *testing.T) {
+	provider := &closingProvider{recordingProvider{events: make(chan Event, 10)}}
+	release := make(chan bool)
+	defer close(release)
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Created", func(Event) error {
+		<-release
+		return nil
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- RunContext(context.Background(), listener, 10*time.Millisecond)
+	}()
+
+	provider.push(testEvent{name: "Created"})
+	assert.Eventually(t, func() bool { return !listener.handlingSince().IsZero() }, 5*time.Second, time.Millisecond)
+
+	require.Nil(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	// The handler never finishes, so draining times out.
+	select {
+	case err := <-done:
+		assert.EqualError(t, err, "Timed out after 10ms waiting for events to be handled")
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext didn't return")
+	}
+}
+
+func TestListenReturnsErrors(t *testing.T) {
+	// No handlers
+	listener := NewListener(&closingProvider{recordingProvider{events: make(chan Event)}})
+	listener.debug = false
+	assert.Equal(t, ErrNoHandlers, listener.Listen())
+
+	// Provider closed its channel
+	provider := &recordingProvider{events: make(chan Event)}
+	listener = NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Created", func(Event) error { return nil })
+
+	close(provider.events)
+	assert.Equal(t, ErrProviderClosed, listener.Listen())
+}
+
+// fatalProvider reports a fatal error once started.
+type fatalProvider struct {
+	closingProvider
+	errors chan error
+}
+
+func (p *fatalProvider) Start() (<-chan Event, <-chan error) {
+	err := NewProviderError(PhaseReceive, "queue", "", errors.New("Queue does not exist"))
+	err.Fatal = true
+	p.errors <- err
+
+	return p.events, p.errors
+}
+
+func TestListenReturnsFatalErrors(t *testing.T) {
+	provider := &fatalProvider{
+		closingProvider: closingProvider{recordingProvider{events: make(chan Event)}},
+		errors:          make(chan error, 1),
+	}
+
+	reported := make(chan error, 1)
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Created", func(Event) error { return nil })
+	listener.RegisterErrorHandler(func(err error) { reported <- err })
+
+	err := listener.Listen()
+	require.IsType(t, &ProviderError{}, err)
+	assert.True(t, err.(*ProviderError).Fatal)
+	assert.EqualError(t, err, "Queue does not exist")
+	assert.Equal(t, err, <-reported)
+}
+
+func TestShutdownBeforeListen(t *testing.T) {
+	listener := NewListener(&closingProvider{recordingProvider{events: make(chan Event)}})
+	listener.debug = false
+	listener.RegisterHandler("Created", func(Event) error { return nil })
+
+	assert.Nil(t, listener.Shutdown(context.Background()))
+	assert.Nil(t, listener.Listen())
+}
+
+func TestListenContextTimesOut(t *testing.T) {
+	provider := &closingProvider{recordingProvider{events: make(chan Event, 10)}}
+	release := make(chan bool)
+	defer close(release)
+
+	listener := NewListener(provider)
+	listener.debug = false
+	listener.RegisterHandler("Created", func(Event) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- listener.ListenContext(ctx, 10*time.Millisecond)
+	}()
+
+	provider.push(testEvent{name: "Created"})
+	assert.Eventually(t, func() bool { return !listener.handlingSince().IsZero() }, 5*time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.Equal(t, &ShutdownTimeoutError{Timeout: 10 * time.Millisecond}, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenContext didn't return")
+	}
+}
@@ -0,0 +1,102 @@
+package s3archive
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Format decides how events are written to objects. JSONL is built in;
+// columnar formats such as Parquet can be added by implementing it.
+type Format interface {
+	// Extension of objects in this format, e.g. ".jsonl".
+	Extension() string
+
+	// ContentType of objects in this format.
+	ContentType() string
+
+	// NewEncoder starts a new object. Its output may be split into
+	// multipart upload parts at any point.
+	NewEncoder(w io.Writer) Encoder
+}
+
+// Encoder writes events to a single object.
+type Encoder interface {
+	Encode(event gomainevents.Event, publishedAt time.Time) error
+
+	// Close writes anything buffered, e.g. a footer. No events are
+	// encoded after it.
+	Close() error
+}
+
+// JSONL writes one JSON object per line, optionally gzipped:
+//
+//	{"name":"UserCreated","data":{"userId":12},"publishedAt":"2018-03-08T11:11:11Z"}
+//
+// Events from FIFO queues also record their messageGroupId and
+// sequenceNumber, so that a group can be replayed in order with the jsonl
+// provider.
+type JSONL struct {
+	Gzip bool
+}
+
+func (f JSONL) Extension() string {
+	if f.Gzip {
+		return ".jsonl.gz"
+	}
+
+	return ".jsonl"
+}
+
+func (f JSONL) ContentType() string {
+	if f.Gzip {
+		return "application/gzip"
+	}
+
+	return "application/x-ndjson"
+}
+
+func (f JSONL) NewEncoder(w io.Writer) Encoder {
+	if !f.Gzip {
+		return &jsonlEncoder{encoder: json.NewEncoder(w)}
+	}
+
+	gz := gzip.NewWriter(w)
+	return &jsonlEncoder{encoder: json.NewEncoder(gz), closer: gz}
+}
+
+type jsonlEncoder struct {
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+type encodedEvent struct {
+	Name        string                 `json:"name"`
+	Data        map[string]interface{} `json:"data"`
+	PublishedAt time.Time              `json:"publishedAt"`
+
+	MessageGroupID string `json:"messageGroupId,omitempty"`
+	SequenceNumber string `json:"sequenceNumber,omitempty"`
+}
+
+func (e *jsonlEncoder) Encode(event gomainevents.Event, publishedAt time.Time) error {
+	encoded := &encodedEvent{
+		Name:        event.Name(),
+		Data:        event.Data(),
+		PublishedAt: publishedAt,
+	}
+	encoded.MessageGroupID, encoded.SequenceNumber, _ = gomainevents.Sequence(event)
+
+	return e.encoder.Encode(encoded)
+}
+
+func (e *jsonlEncoder) Close() error {
+	if nil == e.closer {
+		return nil
+	}
+
+	return e.closer.Close()
+}
@@ -0,0 +1,136 @@
+package s3archive
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/researchsquare/gomainevents"
+)
+
+// object is an archive object being built. Once its buffer grows past the
+// part size it becomes a multipart upload, and parts are uploaded as they
+// fill up.
+type object struct {
+	key      string
+	openedAt time.Time
+
+	buffer  bytes.Buffer
+	encoder Encoder
+	closed  bool
+
+	// Set once the multipart upload has started.
+	uploadID string
+	parts    []types.CompletedPart
+	uploaded int64
+}
+
+func newObject(key string, format Format, openedAt time.Time) *object {
+	o := &object{key: key, openedAt: openedAt}
+	o.encoder = format.NewEncoder(&o.buffer)
+
+	return o
+}
+
+func (o *object) encode(event gomainevents.Event, publishedAt time.Time) error {
+	return o.encoder.Encode(event, publishedAt)
+}
+
+// size returns the number of bytes written so far.
+func (o *object) size() int64 {
+	return o.uploaded + int64(o.buffer.Len())
+}
+
+// uploadPart uploads the buffer as the next part, starting the multipart
+// upload if need be. The buffer is only emptied once the part is uploaded,
+// so a failed part is tried again next time.
+func (o *object) uploadPart(ctx context.Context, p *Publisher) error {
+	if "" == o.uploadID {
+		resp, err := p.s3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:      aws.String(p.bucket),
+			Key:         aws.String(o.key),
+			ContentType: aws.String(p.format.ContentType()),
+		})
+		if err != nil {
+			return err
+		}
+
+		o.uploadID = aws.ToString(resp.UploadId)
+	}
+
+	number := int32(len(o.parts) + 1)
+	resp, err := p.s3Client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(p.bucket),
+		Key:        aws.String(o.key),
+		UploadId:   aws.String(o.uploadID),
+		PartNumber: aws.Int32(number),
+		Body:       bytes.NewReader(o.buffer.Bytes()),
+	})
+	if err != nil {
+		return err
+	}
+
+	o.parts = append(o.parts, types.CompletedPart{ETag: resp.ETag, PartNumber: aws.Int32(number)})
+	o.uploaded += int64(o.buffer.Len())
+	o.buffer.Reset()
+
+	return nil
+}
+
+// finish writes whatever is left and completes the object. It can be called
+// again if it fails.
+func (o *object) finish(ctx context.Context, p *Publisher) error {
+	if !o.closed {
+		if err := o.encoder.Close(); err != nil {
+			return err
+		}
+
+		o.closed = true
+	}
+
+	// Small objects are uploaded in one go.
+	if "" == o.uploadID {
+		_, err := p.s3Client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(p.bucket),
+			Key:         aws.String(o.key),
+			ContentType: aws.String(p.format.ContentType()),
+			Body:        bytes.NewReader(o.buffer.Bytes()),
+		})
+		return err
+	}
+
+	// The last part may be smaller than the minimum part size.
+	if o.buffer.Len() > 0 {
+		if err := o.uploadPart(ctx, p); err != nil {
+			return err
+		}
+	}
+
+	_, err := p.s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(p.bucket),
+		Key:             aws.String(o.key),
+		UploadId:        aws.String(o.uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: o.parts},
+	})
+
+	return err
+}
+
+// abort gives up on the object, aborting its multipart upload if it was
+// started.
+func (o *object) abort(ctx context.Context, p *Publisher) error {
+	if "" == o.uploadID {
+		return nil
+	}
+
+	_, err := p.s3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(p.bucket),
+		Key:      aws.String(o.key),
+		UploadId: aws.String(o.uploadID),
+	})
+
+	return err
+}
@@ -0,0 +1,367 @@
+package s3archive
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	// defaultRegion is used for the default client when no Region is
+	// configured.
+	defaultRegion = "us-east-1"
+
+	// minPartSize is the smallest part S3 accepts, other than the last.
+	minPartSize = 5 * 1024 * 1024
+
+	defaultPartSize      = 8 * 1024 * 1024
+	defaultMaxObjectSize = 256 * 1024 * 1024
+	defaultMaxBatchAge   = 5 * time.Minute
+	defaultTimeout       = time.Minute
+)
+
+// S3API is the subset of the S3 client used by this package. It is
+// satisfied by *s3.Client from aws-sdk-go-v2.
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// Publisher archives events to S3, partitioned by date and event name so
+// that the bucket can be queried with Athena or similar:
+//
+//	<prefix>date=2018-03-08/name=UserCreated/20180308T111111Z-1a2b3c4d.jsonl
+//
+// Events are buffered per partition and written out when an object reaches
+// MaxObjectSize or MaxBatchAge, or on Flush and Close. Large objects are
+// streamed to S3 as a multipart upload while they're being built.
+//
+// Uploads that fail are retried on the next flush rather than failing
+// Publish, and reported to OnError. Events still buffered are lost if the
+// process exits without calling Close, so it is meant to run alongside
+// real-time delivery rather than replace it.
+type Publisher struct {
+	s3Client S3API
+	bucket   string
+	prefix   string
+	format   Format
+
+	partSize      int64
+	maxObjectSize int64
+	maxBatchAge   time.Duration
+	onError       gomainevents.ErrorHandler
+
+	mu      sync.Mutex
+	objects map[string]*object
+
+	// Objects that failed to upload and are retried on each flush.
+	failed []*object
+
+	clock gomainevents.Clock
+
+	done      chan bool
+	closeOnce sync.Once
+}
+
+type Config struct {
+	// Provide your own S3 client. Default will use the
+	// default AWS config + shared credentials.
+	S3Client S3API
+
+	// Region used by the default client. Defaults to us-east-1. Ignored
+	// when S3Client is provided.
+	Region string
+
+	// Endpoint overrides the S3 endpoint used by the default client, e.g.
+	// http://localhost:4566 for LocalStack. Ignored when S3Client is
+	// provided.
+	Endpoint string
+
+	// Bucket to archive to. Required
+	Bucket string
+
+	// Prefix for every key, e.g. "events/".
+	Prefix string
+
+	// How events are written. Defaults to JSONL.
+	Format Format
+
+	// Size of multipart upload parts. Defaults to 8MB; S3 requires at
+	// least 5MB.
+	PartSize int64
+
+	// Objects are written out once they reach this size. Defaults to 256MB.
+	MaxObjectSize int64
+
+	// Objects are written out once they have been open this long.
+	// Defaults to 5 minutes.
+	MaxBatchAge time.Duration
+
+	// Called with errors from uploads. Optional
+	OnError gomainevents.ErrorHandler
+
+	// Clock objects are timed and stamped with. Defaults to
+	// gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewPublisher(config *Config) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.Bucket {
+		return nil, errors.New("Bucket is required")
+	}
+
+	// Default to a new client using shared credentials
+	s3Client := config.S3Client
+	if nil == s3Client {
+		region := config.Region
+		if "" == region {
+			region = defaultRegion
+		}
+
+		awsConfig, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(region))
+		if err != nil {
+			return nil, err
+		}
+
+		s3Client = s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+			if "" != config.Endpoint {
+				o.BaseEndpoint = aws.String(config.Endpoint)
+				o.UsePathStyle = true
+			}
+		})
+	}
+
+	format := config.Format
+	if nil == format {
+		format = JSONL{}
+	}
+
+	partSize := int64(defaultPartSize)
+	if config.PartSize > 0 {
+		partSize = config.PartSize
+	}
+
+	if partSize < minPartSize {
+		return nil, errors.New("PartSize must be at least 5MB")
+	}
+
+	maxObjectSize := int64(defaultMaxObjectSize)
+	if config.MaxObjectSize > 0 {
+		maxObjectSize = config.MaxObjectSize
+	}
+
+	maxBatchAge := defaultMaxBatchAge
+	if config.MaxBatchAge > 0 {
+		maxBatchAge = config.MaxBatchAge
+	}
+
+	clock := gomainevents.ClockOrDefault(config.Clock)
+
+	p := &Publisher{
+		s3Client:      s3Client,
+		bucket:        config.Bucket,
+		prefix:        config.Prefix,
+		format:        format,
+		partSize:      partSize,
+		maxObjectSize: maxObjectSize,
+		maxBatchAge:   maxBatchAge,
+		onError:       config.OnError,
+		objects:       make(map[string]*object),
+		clock:         clock,
+		done:          make(chan bool),
+	}
+
+	go p.flushPeriodically()
+
+	return p, nil
+}
+
+// Publish buffers an event in its partition's object.
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return errors.New("Publisher is closed")
+	default:
+	}
+
+	now := p.clock.Now().UTC()
+	partition := p.partition(event.Name(), now)
+
+	o, ok := p.objects[partition]
+	if !ok {
+		o = newObject(p.key(partition, now), p.format, now)
+		p.objects[partition] = o
+	}
+
+	if err := o.encode(event, now); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if o.size() >= p.maxObjectSize {
+		p.finish(ctx, partition, o)
+		return nil
+	}
+
+	if int64(o.buffer.Len()) >= p.partSize {
+		if err := o.uploadPart(ctx, p); err != nil {
+			p.reportError(err)
+		}
+	}
+
+	return nil
+}
+
+// PublishBatch buffers each of the events.
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	for _, event := range events {
+		if err := p.Publish(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush writes out every buffered object, and retries any that failed
+// before. It returns the first error.
+func (p *Publisher) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.flush(func(*object) bool { return true })
+}
+
+// Close stops the publisher and flushes what is buffered. Objects that
+// still can't be written are given up on, and their multipart uploads are
+// aborted so the parts aren't left in the bucket.
+func (p *Publisher) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.flush(func(*object) bool { return true })
+	p.abort()
+
+	return err
+}
+
+func (p *Publisher) flushPeriodically() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-p.clock.After(p.maxBatchAge / 4):
+		}
+
+		p.mu.Lock()
+		cutoff := p.clock.Now().UTC().Add(-p.maxBatchAge)
+		p.flush(func(o *object) bool { return !o.openedAt.After(cutoff) })
+		p.mu.Unlock()
+	}
+}
+
+// flush writes out the objects selected, and retries failed ones. The lock
+// must be held.
+func (p *Publisher) flush(selected func(*object) bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var first error
+
+	failed := p.failed
+	p.failed = nil
+	for _, o := range failed {
+		if err := o.finish(ctx, p); err != nil {
+			p.failed = append(p.failed, o)
+			p.reportError(err)
+			if nil == first {
+				first = err
+			}
+		}
+	}
+
+	for partition, o := range p.objects {
+		if !selected(o) {
+			continue
+		}
+
+		if err := p.finish(ctx, partition, o); err != nil && nil == first {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// finish writes out an object. Its partition starts a new object either
+// way. The lock must be held.
+func (p *Publisher) finish(ctx context.Context, partition string, o *object) error {
+	delete(p.objects, partition)
+
+	if err := o.finish(ctx, p); err != nil {
+		p.failed = append(p.failed, o)
+		p.reportError(err)
+		return err
+	}
+
+	return nil
+}
+
+// abort gives up on the objects that failed to upload. The lock must be
+// held.
+func (p *Publisher) abort() {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	for _, o := range p.failed {
+		if err := o.abort(ctx, p); err != nil {
+			p.reportError(err)
+		}
+	}
+
+	p.failed = nil
+}
+
+// partition returns the path of the partition an event belongs in.
+func (p *Publisher) partition(name string, now time.Time) string {
+	return "date=" + now.Format("2006-01-02") + "/name=" + url.PathEscape(name) + "/"
+}
+
+// key returns a unique key for a new object in the partition.
+func (p *Publisher) key(partition string, now time.Time) string {
+	bytes := make([]byte, 4)
+	rand.Read(bytes)
+
+	return p.prefix + partition + now.Format("20060102T150405Z") + "-" + hex.EncodeToString(bytes) + p.format.Extension()
+}
+
+func (p *Publisher) reportError(err error) {
+	if nil != p.onError {
+		p.onError(err)
+	}
+}
@@ -0,0 +1,313 @@
+package s3archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/researchsquare/gomainevents/gomaineventstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	if nil == e.data {
+		return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+	}
+
+	return e.data
+}
+
+type mockS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	types    map[string]string
+	uploads  map[string][][]byte
+	parts    int
+	failPuts int
+
+	failCompletes int
+	aborted       []string
+}
+
+func newMockS3() *mockS3 {
+	return &mockS3{
+		objects: make(map[string][]byte),
+		types:   make(map[string]string),
+		uploads: make(map[string][][]byte),
+	}
+}
+
+func (m *mockS3) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.failPuts > 0 {
+		m.failPuts--
+		return nil, errors.New("Service unavailable")
+	}
+
+	body, _ := io.ReadAll(in.Body)
+	m.objects[aws.ToString(in.Key)] = body
+	m.types[aws.ToString(in.Key)] = aws.ToString(in.ContentType)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *mockS3) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-" + aws.ToString(in.Key))}, nil
+}
+
+func (m *mockS3) UploadPart(ctx context.Context, in *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	body, _ := io.ReadAll(in.Body)
+	m.uploads[aws.ToString(in.UploadId)] = append(m.uploads[aws.ToString(in.UploadId)], body)
+	m.parts++
+	return &s3.UploadPartOutput{ETag: aws.String("etag")}, nil
+}
+
+func (m *mockS3) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.failCompletes > 0 {
+		m.failCompletes--
+		return nil, errors.New("Service unavailable")
+	}
+
+	parts := m.uploads[aws.ToString(in.UploadId)]
+	if len(parts) != len(in.MultipartUpload.Parts) {
+		return nil, errors.New("Part mismatch")
+	}
+
+	m.objects[aws.ToString(in.Key)] = bytes.Join(parts, nil)
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (m *mockS3) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.uploads, aws.ToString(in.UploadId))
+	m.aborted = append(m.aborted, aws.ToString(in.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (m *mockS3) keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []string{}
+	for key := range m.objects {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func newTestPublisher(t *testing.T, client *mockS3, config *Config) *Publisher {
+	config.S3Client = client
+	config.Bucket = "archive"
+	if nil == config.Clock {
+		config.Clock = gomaineventstest.NewClock(time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC))
+	}
+
+	publisher, err := NewPublisher(config)
+	require.Nil(t, err)
+	t.Cleanup(func() { publisher.Close() })
+
+	return publisher
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(&Config{S3Client: newMockS3(), Bucket: "archive"})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+	publisher.Close()
+
+	publisher, err = NewPublisher(&Config{S3Client: newMockS3(), Bucket: "archive", PartSize: 1024})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(&Config{S3Client: newMockS3()})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestPublisherPartitionsByDateAndName(t *testing.T) {
+	client := newMockS3()
+	publisher := newTestPublisher(t, client, &Config{Prefix: "events/"})
+
+	require.Nil(t, publisher.Publish(testEvent{name: "UserCreated"}))
+	require.Nil(t, publisher.Publish(testEvent{name: "UserCreated"}))
+	require.Nil(t, publisher.Publish(testEvent{name: `App\Events\UserDeleted`}))
+
+	// Nothing is written until flushed
+	assert.Len(t, client.keys(), 0)
+	require.Nil(t, publisher.Flush())
+
+	keys := client.keys()
+	require.Len(t, keys, 2)
+
+	var created string
+	for _, key := range keys {
+		if strings.HasPrefix(key, "events/date=2018-03-08/name=UserCreated/20180308T111111Z-") {
+			created = key
+		} else {
+			assert.True(t, strings.HasPrefix(key, `events/date=2018-03-08/name=App%5CEvents%5CUserDeleted/`), key)
+		}
+
+		assert.True(t, strings.HasSuffix(key, ".jsonl"), key)
+	}
+
+	require.NotEqual(t, "", created)
+	line := `{"name":"UserCreated","data":{"occurredOn":"2018-03-08 11:11:11"},"publishedAt":"2018-03-08T11:11:11Z"}` + "\n"
+	assert.Equal(t, line+line, string(client.objects[created]))
+	assert.Equal(t, "application/x-ndjson", client.types[created])
+}
+
+func TestPublisherFlushesOldObjects(t *testing.T) {
+	clock := gomaineventstest.NewClock(time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC))
+	client := newMockS3()
+	publisher := newTestPublisher(t, client, &Config{Clock: clock, MaxBatchAge: 4 * time.Minute})
+
+	require.Nil(t, publisher.Publish(testEvent{name: "UserCreated"}))
+
+	for i := 0; i < 3; i++ {
+		assert.Eventually(t, func() bool { return clock.Pending() == 1 }, 5*time.Second, time.Millisecond)
+		clock.Advance(time.Minute)
+	}
+
+	assert.Eventually(t, func() bool { return clock.Pending() == 1 }, 5*time.Second, time.Millisecond)
+	assert.Len(t, client.keys(), 0)
+
+	clock.Advance(time.Minute)
+	assert.Eventually(t, func() bool { return len(client.keys()) == 1 }, 5*time.Second, time.Millisecond)
+}
+
+func TestPublisherUploadsLargeObjectsInParts(t *testing.T) {
+	client := newMockS3()
+	publisher := newTestPublisher(t, client, &Config{PartSize: minPartSize})
+
+	large := strings.Repeat("a", 1024*1024)
+	for i := 0; i < 8; i++ {
+		require.Nil(t, publisher.Publish(testEvent{name: "Large", data: map[string]interface{}{"blob": large, "i": i}}))
+	}
+	require.Nil(t, publisher.Close())
+
+	keys := client.keys()
+	require.Len(t, keys, 1)
+
+	// One full part, then the rest on close
+	assert.Equal(t, 2, client.parts)
+	assert.Equal(t, 8, strings.Count(string(client.objects[keys[0]]), "\n"))
+}
+
+func TestPublisherGzip(t *testing.T) {
+	client := newMockS3()
+	publisher := newTestPublisher(t, client, &Config{Format: JSONL{Gzip: true}})
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+	require.Nil(t, publisher.Flush())
+
+	keys := client.keys()
+	require.Len(t, keys, 1)
+	assert.True(t, strings.HasSuffix(keys[0], ".jsonl.gz"))
+	assert.Equal(t, "application/gzip", client.types[keys[0]])
+
+	reader, err := gzip.NewReader(bytes.NewReader(client.objects[keys[0]]))
+	require.Nil(t, err)
+
+	contents, err := io.ReadAll(reader)
+	require.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(contents), "\n"))
+}
+
+func TestPublisherRetriesFailedUploads(t *testing.T) {
+	client := newMockS3()
+	client.failPuts = 1
+
+	reported := []error{}
+	publisher := newTestPublisher(t, client, &Config{OnError: func(err error) { reported = append(reported, err) }})
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+	assert.NotNil(t, publisher.Flush())
+	assert.Len(t, reported, 1)
+	assert.Len(t, client.keys(), 0)
+
+	require.Nil(t, publisher.Flush())
+	assert.Len(t, client.keys(), 1)
+
+	// Closed publishers don't take any more events
+	require.Nil(t, publisher.Close())
+	assert.NotNil(t, publisher.Publish(testEvent{name: "Thing"}))
+}
+
+func TestPublisherAbortsUploadsGivenUpOnWhenClosed(t *testing.T) {
+	client := newMockS3()
+	client.failCompletes = 2
+
+	publisher := newTestPublisher(t, client, &Config{PartSize: minPartSize})
+
+	large := strings.Repeat("a", 1024*1024)
+	for i := 0; i < 6; i++ {
+		require.Nil(t, publisher.Publish(testEvent{name: "Large", data: map[string]interface{}{"blob": large, "i": i}}))
+	}
+	assert.NotNil(t, publisher.Flush())
+
+	// Still failing when closed, so the upload is aborted
+	assert.NotNil(t, publisher.Close())
+	assert.Len(t, client.keys(), 0)
+	assert.Len(t, client.aborted, 1)
+	assert.Len(t, client.uploads, 0)
+}
+
+// fifoEvent was received from a FIFO queue.
+type fifoEvent struct {
+	testEvent
+}
+
+func (e fifoEvent) MessageGroupID() string {
+	return "order-1"
+}
+
+func (e fifoEvent) SequenceNumber() string {
+	return "18850000000000000100"
+}
+
+func TestJSONLRecordsSequence(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	encoder := JSONL{}.NewEncoder(buffer)
+
+	publishedAt := time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC)
+	require.Nil(t, encoder.Encode(fifoEvent{testEvent{name: "Placed"}}, publishedAt))
+	require.Nil(t, encoder.Encode(testEvent{name: "Created"}, publishedAt))
+	require.Nil(t, encoder.Close())
+
+	assert.Equal(t, `{"name":"Placed","data":{"occurredOn":"2018-03-08 11:11:11"},"publishedAt":"2018-03-08T11:11:11Z","messageGroupId":"order-1","sequenceNumber":"18850000000000000100"}
+{"name":"Created","data":{"occurredOn":"2018-03-08 11:11:11"},"publishedAt":"2018-03-08T11:11:11Z"}
+`, buffer.String())
+}
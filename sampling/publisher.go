@@ -0,0 +1,117 @@
+package sampling
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Publisher wraps another Publisher, passing on only a sample of the events
+// with certain names, e.g. chatty telemetry events that are worth
+// observing but not worth processing in full downstream. Events with other
+// names are always passed on. Events left out of the sample are dropped as
+// if they were published.
+type Publisher struct {
+	publisher gomainevents.Publisher
+	rates     map[string]float64
+	onDropped func(gomainevents.Event)
+
+	// Hook for tests
+	random func() float64
+}
+
+type PublisherConfig struct {
+	// Publisher the sampled events are passed on to. Required
+	Publisher gomainevents.Publisher
+
+	// Fraction of the events with each name to pass on, from 0 for none to
+	// 1 for all, e.g. 0.05 for 5%. Required
+	Rates map[string]float64
+
+	// Called for each event that's dropped. Optional
+	OnDropped func(gomainevents.Event)
+}
+
+func NewPublisher(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Publisher {
+		return nil, errors.New("Publisher is required")
+	}
+
+	if len(config.Rates) == 0 {
+		return nil, errors.New("Rates are required")
+	}
+
+	rates := map[string]float64{}
+	for name, rate := range config.Rates {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("Rate for %s must be between 0 and 1", name)
+		}
+
+		rates[name] = rate
+	}
+
+	return &Publisher{
+		publisher: config.Publisher,
+		rates:     rates,
+		onDropped: config.OnDropped,
+		random:    rand.Float64,
+	}, nil
+}
+
+// Publish passes the event on if it's in the sample.
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	if !p.sample(event) {
+		return nil
+	}
+
+	return p.publisher.Publish(event)
+}
+
+// PublishBatch passes the events in the sample on in a single batch if the
+// wrapped publisher supports them, or one at a time if it doesn't.
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	sampled := make([]gomainevents.Event, 0, len(events))
+	for _, event := range events {
+		if p.sample(event) {
+			sampled = append(sampled, event)
+		}
+	}
+
+	if len(sampled) == 0 {
+		return nil
+	}
+
+	batchPublisher, ok := p.publisher.(gomainevents.BatchPublisher)
+	if !ok {
+		for _, event := range sampled {
+			if err := p.publisher.Publish(event); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return batchPublisher.PublishBatch(sampled)
+}
+
+// sample returns whether an event should be passed on, calling OnDropped if
+// it shouldn't.
+func (p *Publisher) sample(event gomainevents.Event) bool {
+	rate, ok := p.rates[event.Name()]
+	if !ok || p.random() < rate {
+		return true
+	}
+
+	if nil != p.onDropped {
+		p.onDropped(event)
+	}
+
+	return false
+}
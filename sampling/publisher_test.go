@@ -0,0 +1,111 @@
+package sampling
+
+import (
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return nil
+}
+
+type singlePublisher struct {
+	published []gomainevents.Event
+}
+
+func (p *singlePublisher) Publish(event gomainevents.Event) error {
+	p.published = append(p.published, event)
+	return nil
+}
+
+// sequence returns a random func that returns the given values in turn.
+func sequence(values ...float64) func() float64 {
+	return func() float64 {
+		value := values[0]
+		values = values[1:]
+		return value
+	}
+}
+
+func TestNewPublisher(t *testing.T) {
+	_, err := NewPublisher(nil)
+	assert.EqualError(t, err, "Configuration is required")
+
+	_, err = NewPublisher(&PublisherConfig{})
+	assert.EqualError(t, err, "Publisher is required")
+
+	_, err = NewPublisher(&PublisherConfig{Publisher: &mocks.Publisher{}})
+	assert.EqualError(t, err, "Rates are required")
+
+	_, err = NewPublisher(&PublisherConfig{Publisher: &mocks.Publisher{}, Rates: map[string]float64{"Tick": 1.5}})
+	assert.EqualError(t, err, "Rate for Tick must be between 0 and 1")
+
+	publisher, err := NewPublisher(&PublisherConfig{Publisher: &mocks.Publisher{}, Rates: map[string]float64{"Tick": 0.1}})
+	assert.NoError(t, err)
+	assert.NotNil(t, publisher)
+}
+
+func TestPublish(t *testing.T) {
+	wrapped := &mocks.Publisher{}
+	var dropped []gomainevents.Event
+
+	publisher, _ := NewPublisher(&PublisherConfig{
+		Publisher: wrapped,
+		Rates:     map[string]float64{"Tick": 0.1, "Muted": 0},
+		OnDropped: func(event gomainevents.Event) {
+			dropped = append(dropped, event)
+		},
+	})
+	publisher.random = sequence(0.05, 0.5, 0)
+
+	assert.NoError(t, publisher.Publish(testEvent{name: "Tick"}))
+	assert.NoError(t, publisher.Publish(testEvent{name: "Tick"}))
+	assert.NoError(t, publisher.Publish(testEvent{name: "Muted"}))
+	assert.NoError(t, publisher.Publish(testEvent{name: "Created"}))
+
+	assert.Equal(t, []gomainevents.Event{testEvent{name: "Tick"}, testEvent{name: "Created"}}, wrapped.PublishCalls())
+	assert.Equal(t, []gomainevents.Event{testEvent{name: "Tick"}, testEvent{name: "Muted"}}, dropped)
+}
+
+func TestPublishBatch(t *testing.T) {
+	wrapped := &mocks.Publisher{}
+
+	publisher, _ := NewPublisher(&PublisherConfig{
+		Publisher: wrapped,
+		Rates:     map[string]float64{"Tick": 0.5},
+	})
+	publisher.random = sequence(0.9, 0.1, 0.9)
+
+	events := []gomainevents.Event{testEvent{name: "Tick"}, testEvent{name: "Created"}, testEvent{name: "Tick"}}
+	assert.NoError(t, publisher.PublishBatch(events))
+
+	assert.NoError(t, publisher.PublishBatch([]gomainevents.Event{testEvent{name: "Tick"}}))
+
+	assert.Equal(t, [][]gomainevents.Event{{testEvent{name: "Created"}, testEvent{name: "Tick"}}}, wrapped.PublishBatchCalls())
+}
+
+func TestPublishBatchOneAtATime(t *testing.T) {
+	wrapped := &singlePublisher{}
+
+	publisher, _ := NewPublisher(&PublisherConfig{
+		Publisher: wrapped,
+		Rates:     map[string]float64{"Tick": 0.5},
+	})
+	publisher.random = sequence(0.9, 0.1)
+
+	events := []gomainevents.Event{testEvent{name: "Tick"}, testEvent{name: "Created"}, testEvent{name: "Tick"}}
+	assert.NoError(t, publisher.PublishBatch(events))
+
+	assert.Equal(t, []gomainevents.Event{testEvent{name: "Created"}, testEvent{name: "Tick"}}, wrapped.published)
+}
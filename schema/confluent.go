@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const confluentContentType = "application/vnd.schemaregistry.v1+json"
+
+// ConfluentRegistry fetches schemas from a Confluent Schema Registry. The
+// schemas have to be registered with the JSON schema type, under a subject
+// named after the event unless Subject says otherwise.
+type ConfluentRegistry struct {
+	url      string
+	client   *http.Client
+	username string
+	password string
+	subject  func(eventName string) string
+	cache    *cache
+}
+
+type ConfluentConfig struct {
+	// Base URL of the registry, e.g. "http://localhost:8081". Required
+	URL string
+
+	// Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+
+	// Credentials for registries behind basic auth, e.g. Confluent Cloud's
+	// API key and secret.
+	Username string
+	Password string
+
+	// Returns the subject an event's schemas are registered under. Defaults
+	// to the event name.
+	Subject func(eventName string) string
+
+	// How long to use the latest version of a schema before checking for a
+	// newer one. Defaults to 5 minutes.
+	CacheTTL time.Duration
+}
+
+func NewConfluentRegistry(config *ConfluentConfig) (*ConfluentRegistry, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.URL {
+		return nil, errors.New("URL is required")
+	}
+
+	client := config.HTTPClient
+	if nil == client {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	subject := config.Subject
+	if nil == subject {
+		subject = func(eventName string) string { return eventName }
+	}
+
+	return &ConfluentRegistry{
+		url:      strings.TrimRight(config.URL, "/"),
+		client:   client,
+		username: config.Username,
+		password: config.Password,
+		subject:  subject,
+		cache:    newCache(config.CacheTTL),
+	}, nil
+}
+
+type confluentSchema struct {
+	Subject    string `json:"subject"`
+	ID         int    `json:"id"`
+	Version    int    `json:"version"`
+	SchemaType string `json:"schemaType"`
+	Schema     string `json:"schema"`
+}
+
+func (r *ConfluentRegistry) Schema(eventName string, version int) (*Schema, error) {
+	return r.cache.get(eventName, version, func() (*Schema, error) {
+		return r.fetch(eventName, version)
+	})
+}
+
+func (r *ConfluentRegistry) fetch(eventName string, version int) (*Schema, error) {
+	versionPath := "latest"
+	if Latest != version {
+		versionPath = strconv.Itoa(version)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(
+		"%s/subjects/%s/versions/%s",
+		r.url, url.PathEscape(r.subject(eventName)), versionPath,
+	), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", confluentContentType)
+	if "" != r.username {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if http.StatusNotFound == resp.StatusCode {
+		return nil, &NotFoundError{EventName: eventName, Version: version}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Schema registry responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	registered := &confluentSchema{}
+	if err := json.Unmarshal(body, registered); err != nil {
+		return nil, err
+	}
+
+	// Avro is the default, and is left out of responses.
+	if "JSON" != registered.SchemaType {
+		return nil, fmt.Errorf("Schema for %s version %d is not a JSON schema", eventName, registered.Version)
+	}
+
+	return NewSchema(eventName, registered.Version, strconv.Itoa(registered.ID), registered.Schema)
+}
@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestConfluentRegistry(t *testing.T, handler http.HandlerFunc) (*ConfluentRegistry, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	registry, err := NewConfluentRegistry(&ConfluentConfig{URL: server.URL + "/"})
+	assert.NoError(t, err)
+
+	return registry, &requests
+}
+
+func confluentResponse(w http.ResponseWriter, version int, schemaType string) {
+	w.Header().Set("Content-Type", confluentContentType)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"subject":    "ThingHappened",
+		"id":         100 + version,
+		"version":    version,
+		"schemaType": schemaType,
+		"schema":     thingHappenedSchema,
+	})
+}
+
+func TestNewConfluentRegistry(t *testing.T) {
+	_, err := NewConfluentRegistry(nil)
+	assert.EqualError(t, err, "Configuration is required")
+
+	_, err = NewConfluentRegistry(&ConfluentConfig{})
+	assert.EqualError(t, err, "URL is required")
+}
+
+func TestConfluentRegistrySchema(t *testing.T) {
+	registry, requests := newTestConfluentRegistry(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, confluentContentType, r.Header.Get("Accept"))
+
+		switch r.URL.Path {
+		case "/subjects/ThingHappened/versions/latest":
+			confluentResponse(w, 3, "JSON")
+		case "/subjects/ThingHappened/versions/2":
+			confluentResponse(w, 2, "JSON")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40401,"message":"Subject not found"}`))
+		}
+	})
+
+	schema, err := registry.Schema("ThingHappened", Latest)
+	assert.NoError(t, err)
+	assert.Equal(t, "ThingHappened", schema.EventName)
+	assert.Equal(t, 3, schema.Version)
+	assert.Equal(t, "103", schema.ID)
+
+	schema, err = registry.Schema("ThingHappened", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, schema.Version)
+
+	// Both are cached, and so is version 3.
+	_, err = registry.Schema("ThingHappened", Latest)
+	assert.NoError(t, err)
+	_, err = registry.Schema("ThingHappened", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+
+	_, err = registry.Schema("OtherThingHappened", Latest)
+	assert.Equal(t, &NotFoundError{EventName: "OtherThingHappened"}, err)
+	assert.EqualError(t, err, "No schema registered for OtherThingHappened")
+
+	_, err = registry.Schema("ThingHappened", 7)
+	assert.EqualError(t, err, "No schema registered for ThingHappened version 7")
+}
+
+func TestConfluentRegistryRefreshesLatest(t *testing.T) {
+	version := int32(1)
+	registry, requests := newTestConfluentRegistry(t, func(w http.ResponseWriter, r *http.Request) {
+		confluentResponse(w, int(atomic.LoadInt32(&version)), "JSON")
+	})
+
+	now := time.Now()
+	registry.cache.now = func() time.Time { return now }
+
+	schema, _ := registry.Schema("ThingHappened", Latest)
+	assert.Equal(t, 1, schema.Version)
+
+	atomic.StoreInt32(&version, 2)
+	schema, _ = registry.Schema("ThingHappened", Latest)
+	assert.Equal(t, 1, schema.Version)
+
+	now = now.Add(defaultCacheTTL)
+	schema, _ = registry.Schema("ThingHappened", Latest)
+	assert.Equal(t, 2, schema.Version)
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+}
+
+func TestConfluentRegistryConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		assert.Equal(t, "key", username)
+		assert.Equal(t, "secret", password)
+		assert.Equal(t, "/subjects/events%2FThingHappened-value/versions/latest", r.URL.EscapedPath())
+
+		confluentResponse(w, 1, "JSON")
+	}))
+	defer server.Close()
+
+	registry, err := NewConfluentRegistry(&ConfluentConfig{
+		URL:      server.URL,
+		Username: "key",
+		Password: "secret",
+		Subject: func(eventName string) string {
+			return "events/" + eventName + "-value"
+		},
+	})
+	assert.NoError(t, err)
+
+	_, err = registry.Schema("ThingHappened", Latest)
+	assert.NoError(t, err)
+}
+
+func TestConfluentRegistryErrors(t *testing.T) {
+	registry, _ := newTestConfluentRegistry(t, func(w http.ResponseWriter, r *http.Request) {
+		if "/subjects/Avro/versions/latest" == r.URL.Path {
+			confluentResponse(w, 1, "")
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Boom\n"))
+	})
+
+	_, err := registry.Schema("Avro", Latest)
+	assert.EqualError(t, err, "Schema for Avro version 1 is not a JSON schema")
+
+	_, err = registry.Schema("ThingHappened", Latest)
+	assert.EqualError(t, err, "Schema registry responded with 500 Internal Server Error: Boom")
+}
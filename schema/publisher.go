@@ -0,0 +1,155 @@
+package schema
+
+import (
+	"errors"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Publisher wraps another Publisher, checking events against their
+// registered schemas before they are published. Events that don't match
+// aren't published, and fail with a *ValidationError.
+//
+// Events are published with the version of the schema they were checked
+// against in their data, under VersionField, so that consumers can check
+// them against the same version with a Validator even once newer ones are
+// registered. Schemas needn't describe the field; it is left out when
+// checking.
+type Publisher struct {
+	publisher         gomainevents.Publisher
+	registry          Registry
+	versions          map[string]int
+	versionField      string
+	allowUnregistered bool
+}
+
+type PublisherConfig struct {
+	// Publisher to pass events on to. Required
+	Publisher gomainevents.Publisher
+
+	// Registry schemas are fetched from. Required
+	Registry Registry
+
+	// Schema version to publish each event with, by name. Events that
+	// aren't listed use the latest version.
+	Versions map[string]int
+
+	// Field of the event data the schema version is recorded in. Defaults
+	// to "schemaVersion".
+	VersionField string
+
+	// Publish events that have no schema registered at all as they are,
+	// instead of failing them with a *NotFoundError. Events whose version
+	// in Versions isn't registered still fail.
+	AllowUnregistered bool
+}
+
+func NewPublisher(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Publisher {
+		return nil, errors.New("Publisher is required")
+	}
+
+	if nil == config.Registry {
+		return nil, errors.New("Registry is required")
+	}
+
+	versionField := config.VersionField
+	if "" == versionField {
+		versionField = defaultVersionField
+	}
+
+	return &Publisher{
+		publisher:         config.Publisher,
+		registry:          config.Registry,
+		versions:          config.Versions,
+		versionField:      versionField,
+		allowUnregistered: config.AllowUnregistered,
+	}, nil
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	validated, err := p.validate(event)
+	if err != nil {
+		return err
+	}
+
+	return p.publisher.Publish(validated)
+}
+
+// PublishBatch checks every event before publishing any of them, so that a
+// batch with an invalid event isn't partly published. The events are passed
+// on in a single batch if the wrapped publisher supports them, or one at a
+// time if it doesn't.
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	validated := make([]gomainevents.Event, 0, len(events))
+	for _, event := range events {
+		v, err := p.validate(event)
+		if err != nil {
+			return err
+		}
+
+		validated = append(validated, v)
+	}
+
+	batchPublisher, ok := p.publisher.(gomainevents.BatchPublisher)
+	if !ok {
+		for _, event := range validated {
+			if err := p.publisher.Publish(event); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return batchPublisher.PublishBatch(validated)
+}
+
+// validate checks an event against its schema and returns it with the
+// schema version added.
+func (p *Publisher) validate(event gomainevents.Event) (gomainevents.Event, error) {
+	schema, err := p.registry.Schema(event.Name(), p.versions[event.Name()])
+
+	var notFound *NotFoundError
+	if errors.As(err, &notFound) && Latest == notFound.Version && p.allowUnregistered {
+		return event, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := schema.Validate(withoutField(event.Data(), p.versionField)); err != nil {
+		return nil, &ValidationError{EventName: event.Name(), Version: schema.Version, Err: err}
+	}
+
+	return versionedEvent{Event: event, field: p.versionField, version: schema.Version}, nil
+}
+
+// versionedEvent is an event with the version of its schema added to its
+// data.
+type versionedEvent struct {
+	gomainevents.Event
+	field   string
+	version int
+}
+
+// Data returns a copy of the original event's data, leaving it unchanged.
+func (e versionedEvent) Data() map[string]interface{} {
+	data := make(map[string]interface{}, len(e.Event.Data())+1)
+	for key, value := range e.Event.Data() {
+		data[key] = value
+	}
+
+	data[e.field] = e.version
+	return data
+}
+
+// Unwrap returns the original event.
+func (e versionedEvent) Unwrap() gomainevents.Event {
+	return e.Event
+}
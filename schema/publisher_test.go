@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/gomaineventstest"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPublisher struct {
+	events []gomainevents.Event
+}
+
+func (p *testPublisher) Publish(event gomainevents.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestNewPublisher(t *testing.T) {
+	_, err := NewPublisher(nil)
+	assert.EqualError(t, err, "Configuration is required")
+
+	_, err = NewPublisher(&PublisherConfig{Registry: &testRegistry{t: t}})
+	assert.EqualError(t, err, "Publisher is required")
+
+	_, err = NewPublisher(&PublisherConfig{Publisher: &testPublisher{}})
+	assert.EqualError(t, err, "Registry is required")
+}
+
+func TestPublisherPublish(t *testing.T) {
+	recorder := gomaineventstest.NewRecordingPublisher()
+	publisher, err := NewPublisher(&PublisherConfig{
+		Publisher: recorder,
+		Registry:  &testRegistry{t: t},
+	})
+	assert.NoError(t, err)
+
+	event := testEvent{"ThingHappened", map[string]interface{}{"thingId": 1, "occurredOn": "2020-01-01"}}
+	assert.NoError(t, publisher.Publish(event))
+
+	published := recorder.Events()
+	assert.Len(t, published, 1)
+	assert.Equal(t, map[string]interface{}{"thingId": 1, "occurredOn": "2020-01-01", "schemaVersion": 2}, published[0].Data())
+	assert.Equal(t, event, published[0].(interface{ Unwrap() gomainevents.Event }).Unwrap())
+
+	// The original is left alone.
+	assert.NotContains(t, event.Data(), "schemaVersion")
+
+	err = publisher.Publish(testEvent{"ThingHappened", map[string]interface{}{"thingId": "1"}})
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 1, recorder.Count())
+
+	err = publisher.Publish(testEvent{"OtherThingHappened", map[string]interface{}{}})
+	assert.EqualError(t, err, "No schema registered for OtherThingHappened")
+}
+
+func TestPublisherVersions(t *testing.T) {
+	recorder := gomaineventstest.NewRecordingPublisher()
+	publisher, _ := NewPublisher(&PublisherConfig{
+		Publisher:         recorder,
+		Registry:          &testRegistry{t: t},
+		Versions:          map[string]int{"ThingHappened": 1},
+		VersionField:      "v",
+		AllowUnregistered: true,
+	})
+
+	assert.NoError(t, publisher.Publish(testEvent{"ThingHappened", map[string]interface{}{"thingId": "1"}}))
+	assert.NoError(t, publisher.Publish(testEvent{"OtherThingHappened", map[string]interface{}{"a": 1}}))
+
+	published := recorder.Events()
+	assert.Equal(t, map[string]interface{}{"thingId": "1", "v": 1}, published[0].Data())
+	assert.Equal(t, map[string]interface{}{"a": 1}, published[1].Data())
+
+	// Published events pass the consumer's validation.
+	validator, _ := NewValidator(&ValidatorConfig{Registry: &testRegistry{t: t}, VersionField: "v"})
+	assert.NoError(t, validator.Validate(published[0]))
+}
+
+func TestPublisherPublishBatch(t *testing.T) {
+	valid := testEvent{"ThingHappened", map[string]interface{}{"thingId": 1, "occurredOn": "2020-01-01"}}
+	invalid := testEvent{"ThingHappened", map[string]interface{}{}}
+
+	recorder := gomaineventstest.NewRecordingPublisher()
+	publisher, _ := NewPublisher(&PublisherConfig{Publisher: recorder, Registry: &testRegistry{t: t}})
+
+	assert.Error(t, publisher.PublishBatch([]gomainevents.Event{valid, invalid}))
+	assert.Equal(t, 0, recorder.Count())
+
+	assert.NoError(t, publisher.PublishBatch([]gomainevents.Event{valid, valid}))
+	assert.Equal(t, 2, recorder.Count())
+
+	// One at a time for publishers without batches.
+	single := &testPublisher{}
+	publisher, _ = NewPublisher(&PublisherConfig{Publisher: single, Registry: &testRegistry{t: t}})
+
+	assert.NoError(t, publisher.PublishBatch([]gomainevents.Event{valid, valid}))
+	assert.Len(t, single.events, 2)
+}
@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Latest asks a Registry for the newest version of a schema.
+const Latest = 0
+
+const defaultCacheTTL = 5 * time.Minute
+
+// Registry looks up the schemas registered for events.
+type Registry interface {
+	// Schema returns a version of the schema registered for an event, or the
+	// newest if version is Latest. It returns a *NotFoundError if there
+	// isn't one.
+	Schema(eventName string, version int) (*Schema, error)
+}
+
+// NotFoundError is returned when no schema is registered for an event, or
+// not in the version asked for.
+type NotFoundError struct {
+	EventName string
+	Version   int
+}
+
+func (e *NotFoundError) Error() string {
+	if Latest == e.Version {
+		return fmt.Sprintf("No schema registered for %s", e.EventName)
+	}
+
+	return fmt.Sprintf("No schema registered for %s version %d", e.EventName, e.Version)
+}
+
+// cache holds schemas fetched from a registry. Versions never change once
+// registered, so they're kept for good; which version is the latest is
+// looked up again after the TTL.
+type cache struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	versions map[cacheKey]*Schema
+	latest   map[string]cachedLatest
+
+	// Hook for tests
+	now func() time.Time
+}
+
+type cacheKey struct {
+	eventName string
+	version   int
+}
+
+type cachedLatest struct {
+	schema  *Schema
+	fetched time.Time
+}
+
+func newCache(ttl time.Duration) *cache {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+
+	return &cache{
+		ttl:      ttl,
+		versions: map[cacheKey]*Schema{},
+		latest:   map[string]cachedLatest{},
+		now:      time.Now,
+	}
+}
+
+// get returns a cached schema, or fetches it.
+func (c *cache) get(eventName string, version int, fetch func() (*Schema, error)) (*Schema, error) {
+	c.mu.Lock()
+	if Latest == version {
+		if cached, ok := c.latest[eventName]; ok && c.now().Sub(cached.fetched) < c.ttl {
+			c.mu.Unlock()
+			return cached.schema, nil
+		}
+	} else if cached, ok := c.versions[cacheKey{eventName, version}]; ok {
+		c.mu.Unlock()
+		return cached, nil
+	}
+	c.mu.Unlock()
+
+	schema, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.versions[cacheKey{eventName, schema.Version}] = schema
+	if Latest == version {
+		c.latest[eventName] = cachedLatest{schema: schema, fetched: c.now()}
+	}
+
+	return schema, nil
+}
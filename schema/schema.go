@@ -0,0 +1,69 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Schema is a registered JSON Schema for the data of an event.
+type Schema struct {
+	EventName string
+	Version   int
+
+	// The registry's own ID for this version, e.g. Confluent's schema ID or
+	// Glue's schema version ID.
+	ID string
+
+	// JSON Schema document the event data must match.
+	Definition string
+
+	root interface{}
+}
+
+// NewSchema parses a JSON Schema definition.
+func NewSchema(eventName string, version int, id string, definition string) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal([]byte(definition), &root); err != nil {
+		return nil, err
+	}
+
+	switch root.(type) {
+	case map[string]interface{}, bool:
+	default:
+		return nil, errors.New("Schema must be an object or a boolean")
+	}
+
+	return &Schema{
+		EventName:  eventName,
+		Version:    version,
+		ID:         id,
+		Definition: definition,
+		root:       root,
+	}, nil
+}
+
+// Validate checks event data against the schema. The data is compared as it
+// would be published, i.e. after encoding it as JSON.
+func (s *Schema) Validate(data map[string]interface{}) error {
+	value, err := normalize(data)
+	if err != nil {
+		return err
+	}
+
+	return (&validator{root: s.root}).validate(s.root, value, "")
+}
+
+// normalize turns data into what decoding it from JSON would give.
+func normalize(data map[string]interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,120 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const thingHappenedSchema = `{
+	"type": "object",
+	"required": ["thingId", "occurredOn"],
+	"additionalProperties": false,
+	"properties": {
+		"thingId": {"type": "integer", "minimum": 1},
+		"occurredOn": {"type": "string", "pattern": "^\\d{4}-"},
+		"status": {"enum": ["new", "done"]},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+		"owner": {"$ref": "#/definitions/owner"}
+	},
+	"definitions": {
+		"owner": {
+			"type": "object",
+			"required": ["email"],
+			"properties": {"email": {"type": "string", "minLength": 3}}
+		}
+	}
+}`
+
+func newTestSchema(t *testing.T, version int) *Schema {
+	schema, err := NewSchema("ThingHappened", version, "1", thingHappenedSchema)
+	assert.NoError(t, err)
+
+	return schema
+}
+
+func TestNewSchema(t *testing.T) {
+	_, err := NewSchema("ThingHappened", 1, "1", "{")
+	assert.Error(t, err)
+
+	_, err = NewSchema("ThingHappened", 1, "1", `"string"`)
+	assert.EqualError(t, err, "Schema must be an object or a boolean")
+
+	schema, err := NewSchema("ThingHappened", 1, "1", "true")
+	assert.NoError(t, err)
+	assert.NoError(t, schema.Validate(map[string]interface{}{"anything": 1}))
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := newTestSchema(t, 1)
+
+	valid := map[string]interface{}{
+		"thingId":    42,
+		"occurredOn": "2020-01-02 03:04:05",
+		"status":     "done",
+		"tags":       []string{"a", "b"},
+		"owner":      map[string]interface{}{"email": "a@b.c"},
+	}
+	assert.NoError(t, schema.Validate(valid))
+
+	tests := []struct {
+		field string
+		value interface{}
+		err   string
+	}{
+		{"thingId", "42", `thingId: must be integer, not string`},
+		{"thingId", 4.2, `thingId: must be integer, not number`},
+		{"thingId", 0, `thingId: must be at least 1`},
+		{"occurredOn", "yesterday", `occurredOn: must match "^\\d{4}-"`},
+		{"status", "lost", `status: must be one of ["new","done"]`},
+		{"tags", []interface{}{"a", 1}, `tags[1]: must be string, not number`},
+		{"tags", []string{"a", "b", "c"}, `tags: must have at most 2 items`},
+		{"owner", map[string]interface{}{}, `owner: missing required field "email"`},
+		{"owner", map[string]interface{}{"email": "a"}, `owner.email: must be at least 3 characters`},
+		{"unexpected", true, `unexpected: not allowed`},
+	}
+
+	for _, test := range tests {
+		data := map[string]interface{}{}
+		for key, value := range valid {
+			data[key] = value
+		}
+		data[test.field] = test.value
+
+		assert.EqualError(t, schema.Validate(data), test.err, test.field)
+	}
+
+	assert.EqualError(t, schema.Validate(map[string]interface{}{"thingId": 1}), `Event data: missing required field "occurredOn"`)
+}
+
+func TestSchemaValidateCombinations(t *testing.T) {
+	schema, err := NewSchema("ThingHappened", 1, "1", `{
+		"properties": {
+			"id": {"anyOf": [{"type": "string"}, {"type": "integer"}]},
+			"size": {"oneOf": [{"multipleOf": 2}, {"multipleOf": 3}]},
+			"name": {"allOf": [{"minLength": 2}, {"not": {"const": "no"}}]}
+		}
+	}`)
+	assert.NoError(t, err)
+
+	assert.NoError(t, schema.Validate(map[string]interface{}{"id": "a", "size": 4, "name": "yes"}))
+	assert.NoError(t, schema.Validate(map[string]interface{}{"id": 1, "size": 9}))
+
+	assert.EqualError(t, schema.Validate(map[string]interface{}{"id": true}), "id: must match at least one schema in anyOf")
+	assert.EqualError(t, schema.Validate(map[string]interface{}{"size": 6}), "size: must match exactly one schema in oneOf")
+	assert.EqualError(t, schema.Validate(map[string]interface{}{"size": 5}), "size: must match exactly one schema in oneOf")
+	assert.EqualError(t, schema.Validate(map[string]interface{}{"name": "no"}), "name: must not match the schema in not")
+	assert.EqualError(t, schema.Validate(map[string]interface{}{"name": "x"}), "name: must be at least 2 characters")
+}
+
+func TestSchemaValidateUnresolvableRef(t *testing.T) {
+	schema, err := NewSchema("ThingHappened", 1, "1", `{"properties": {"a": {"$ref": "#/definitions/missing"}}}`)
+	assert.NoError(t, err)
+
+	assert.EqualError(t, schema.Validate(map[string]interface{}{"a": 1}), `Unable to resolve $ref "#/definitions/missing"`)
+
+	schema, err = NewSchema("ThingHappened", 1, "1", `{"$ref": "other.json"}`)
+	assert.NoError(t, err)
+
+	assert.Error(t, schema.Validate(map[string]interface{}{}))
+}
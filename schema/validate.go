@@ -0,0 +1,358 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// validator checks values against a JSON Schema. It understands the
+// keywords that describe the shape of event data: type, enum, const,
+// properties, required, additionalProperties, items, minItems, maxItems,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf,
+// minLength, maxLength, pattern, allOf, anyOf, oneOf, not, and $ref to
+// definitions in the same document. Other keywords, such as format, are
+// ignored.
+type validator struct {
+	root interface{}
+}
+
+func (v *validator) validate(schema interface{}, value interface{}, path string) error {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return fmt.Errorf("%s: not allowed", describe(path))
+		}
+
+		return nil
+	case map[string]interface{}:
+		return v.validateObject(s, value, path)
+	}
+
+	return fmt.Errorf("%s: invalid schema", describe(path))
+}
+
+func (v *validator) validateObject(s map[string]interface{}, value interface{}, path string) error {
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := v.resolve(ref)
+		if err != nil {
+			return err
+		}
+
+		if err := v.validate(target, value, path); err != nil {
+			return err
+		}
+	}
+
+	if t, ok := s["type"]; ok {
+		if err := checkType(t, value, path); err != nil {
+			return err
+		}
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || reflect.DeepEqual(allowed, value)
+		}
+
+		if !found {
+			return fmt.Errorf("%s: must be one of %s", describe(path), encode(enum))
+		}
+	}
+
+	if constant, ok := s["const"]; ok && !reflect.DeepEqual(constant, value) {
+		return fmt.Errorf("%s: must be %s", describe(path), encode(constant))
+	}
+
+	for _, check := range []func(map[string]interface{}, interface{}, string) error{
+		v.checkObject,
+		v.checkArray,
+		checkNumber,
+		checkString,
+		v.checkCombinations,
+	} {
+		if err := check(s, value, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *validator) checkObject(s map[string]interface{}, value interface{}, path string) error {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, field := range required {
+			if name, ok := field.(string); ok {
+				if _, present := object[name]; !present {
+					return fmt.Errorf("%s: missing required field %q", describe(path), name)
+				}
+			}
+		}
+	}
+
+	properties, _ := s["properties"].(map[string]interface{})
+	for name, fieldValue := range object {
+		if fieldSchema, ok := properties[name]; ok {
+			if err := v.validate(fieldSchema, fieldValue, join(path, name)); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if additional, ok := s["additionalProperties"]; ok {
+			if err := v.validate(additional, fieldValue, join(path, name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (v *validator) checkArray(s map[string]interface{}, value interface{}, path string) error {
+	array, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	if min, ok := number(s["minItems"]); ok && float64(len(array)) < min {
+		return fmt.Errorf("%s: must have at least %v items", describe(path), min)
+	}
+
+	if max, ok := number(s["maxItems"]); ok && float64(len(array)) > max {
+		return fmt.Errorf("%s: must have at most %v items", describe(path), max)
+	}
+
+	if items, ok := s["items"]; ok {
+		for i, item := range array {
+			if err := v.validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkNumber(s map[string]interface{}, value interface{}, path string) error {
+	n, ok := number(value)
+	if !ok {
+		return nil
+	}
+
+	if min, ok := number(s["minimum"]); ok && n < min {
+		return fmt.Errorf("%s: must be at least %v", describe(path), min)
+	}
+
+	if max, ok := number(s["maximum"]); ok && n > max {
+		return fmt.Errorf("%s: must be at most %v", describe(path), max)
+	}
+
+	if min, ok := number(s["exclusiveMinimum"]); ok && n <= min {
+		return fmt.Errorf("%s: must be more than %v", describe(path), min)
+	}
+
+	if max, ok := number(s["exclusiveMaximum"]); ok && n >= max {
+		return fmt.Errorf("%s: must be less than %v", describe(path), max)
+	}
+
+	if divisor, ok := number(s["multipleOf"]); ok && divisor > 0 {
+		if quotient := n / divisor; quotient != math.Trunc(quotient) {
+			return fmt.Errorf("%s: must be a multiple of %v", describe(path), divisor)
+		}
+	}
+
+	return nil
+}
+
+func checkString(s map[string]interface{}, value interface{}, path string) error {
+	str, ok := value.(string)
+	if !ok {
+		return nil
+	}
+
+	length := float64(utf8.RuneCountInString(str))
+
+	if min, ok := number(s["minLength"]); ok && length < min {
+		return fmt.Errorf("%s: must be at least %v characters", describe(path), min)
+	}
+
+	if max, ok := number(s["maxLength"]); ok && length > max {
+		return fmt.Errorf("%s: must be at most %v characters", describe(path), max)
+	}
+
+	if pattern, ok := s["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern %q in schema", describe(path), pattern)
+		}
+
+		if !re.MatchString(str) {
+			return fmt.Errorf("%s: must match %q", describe(path), pattern)
+		}
+	}
+
+	return nil
+}
+
+func (v *validator) checkCombinations(s map[string]interface{}, value interface{}, path string) error {
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if err := v.validate(sub, value, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if anyOf, ok := s["anyOf"].([]interface{}); ok && v.matches(anyOf, value, path) == 0 {
+		return fmt.Errorf("%s: must match at least one schema in anyOf", describe(path))
+	}
+
+	if oneOf, ok := s["oneOf"].([]interface{}); ok && v.matches(oneOf, value, path) != 1 {
+		return fmt.Errorf("%s: must match exactly one schema in oneOf", describe(path))
+	}
+
+	if not, ok := s["not"]; ok && nil == v.validate(not, value, path) {
+		return fmt.Errorf("%s: must not match the schema in not", describe(path))
+	}
+
+	return nil
+}
+
+// matches counts the schemas a value matches.
+func (v *validator) matches(schemas []interface{}, value interface{}, path string) int {
+	count := 0
+	for _, sub := range schemas {
+		if nil == v.validate(sub, value, path) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// resolve finds the schema a $ref points to. Only references within the
+// same document, e.g. "#/definitions/address", are supported.
+func (v *validator) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("Unsupported $ref %q: only references within the schema are supported", ref)
+	}
+
+	target := v.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if "" == part {
+			continue
+		}
+
+		part = strings.Replace(strings.Replace(part, "~1", "/", -1), "~0", "~", -1)
+
+		object, ok := target.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Unable to resolve $ref %q", ref)
+		}
+
+		if target, ok = object[part]; !ok {
+			return nil, fmt.Errorf("Unable to resolve $ref %q", ref)
+		}
+	}
+
+	return target, nil
+}
+
+func checkType(t interface{}, value interface{}, path string) error {
+	types := []string{}
+	switch t := t.(type) {
+	case string:
+		types = append(types, t)
+	case []interface{}:
+		for _, name := range t {
+			if name, ok := name.(string); ok {
+				types = append(types, name)
+			}
+		}
+	}
+
+	for _, name := range types {
+		if isType(name, value) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: must be %s, not %s", describe(path), strings.Join(types, " or "), typeOf(value))
+}
+
+func isType(name string, value interface{}) bool {
+	switch name {
+	case "integer":
+		n, ok := number(value)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := number(value)
+		return ok
+	}
+
+	return name == typeOf(value)
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+func join(path string, field string) string {
+	if "" == path {
+		return field
+	}
+
+	return path + "." + field
+}
+
+func describe(path string) string {
+	if "" == path {
+		return "Event data"
+	}
+
+	return path
+}
+
+func encode(value interface{}) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
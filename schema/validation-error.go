@@ -0,0 +1,18 @@
+package schema
+
+import "fmt"
+
+// ValidationError is returned for event data that doesn't match its schema.
+type ValidationError struct {
+	EventName string
+	Version   int
+	Err       error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s does not match schema version %d: %s", e.EventName, e.Version, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const defaultVersionField = "schemaVersion"
+
+// Validator checks received events against the schema version they were
+// published with, which the Publisher records in their data. Events without
+// a version are checked against the latest one.
+type Validator struct {
+	registry          Registry
+	versionField      string
+	allowUnregistered bool
+}
+
+type ValidatorConfig struct {
+	// Registry schemas are fetched from. Required
+	Registry Registry
+
+	// Field of the event data holding the schema version. Defaults to
+	// "schemaVersion".
+	VersionField string
+
+	// Let events through that have no schema registered at all, instead of
+	// failing them with a *NotFoundError. Events asking for a version that
+	// isn't registered still fail.
+	AllowUnregistered bool
+}
+
+func NewValidator(config *ValidatorConfig) (*Validator, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Registry {
+		return nil, errors.New("Registry is required")
+	}
+
+	versionField := config.VersionField
+	if "" == versionField {
+		versionField = defaultVersionField
+	}
+
+	return &Validator{
+		registry:          config.Registry,
+		versionField:      versionField,
+		allowUnregistered: config.AllowUnregistered,
+	}, nil
+}
+
+// Schema returns the schema an event was published with. It is nil, without
+// an error, for unregistered events when AllowUnregistered is set.
+func (v *Validator) Schema(event gomainevents.Event) (*Schema, error) {
+	version, err := versionOf(event.Data(), v.versionField)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := v.registry.Schema(event.Name(), version)
+
+	var notFound *NotFoundError
+	if errors.As(err, &notFound) && Latest == notFound.Version && v.allowUnregistered {
+		return nil, nil
+	}
+
+	return schema, err
+}
+
+// Validate checks an event against the schema it was published with,
+// returning a *ValidationError if it doesn't match.
+func (v *Validator) Validate(event gomainevents.Event) error {
+	schema, err := v.Schema(event)
+	if err != nil || nil == schema {
+		return err
+	}
+
+	if err := schema.Validate(withoutField(event.Data(), v.versionField)); err != nil {
+		return &ValidationError{EventName: event.Name(), Version: schema.Version, Err: err}
+	}
+
+	return nil
+}
+
+// Handler wraps an EventHandler so that it is only called with events that
+// match their schema. Other events fail with the validation error, without
+// calling the handler.
+//
+//	listener.RegisterHandler("ThingHappened", validator.Handler(handleThing))
+func (v *Validator) Handler(fn gomainevents.EventHandler) gomainevents.EventHandler {
+	return func(event gomainevents.Event) error {
+		if err := v.Validate(event); err != nil {
+			return err
+		}
+
+		return fn(event)
+	}
+}
+
+// withoutField returns a copy of data without the version field, which
+// schemas don't describe.
+func withoutField(data map[string]interface{}, field string) map[string]interface{} {
+	if _, ok := data[field]; !ok {
+		return data
+	}
+
+	copied := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if key != field {
+			copied[key] = value
+		}
+	}
+
+	return copied
+}
+
+// versionOf reads the schema version from event data, or Latest if it
+// doesn't have one.
+func versionOf(data map[string]interface{}, field string) (int, error) {
+	value, ok := data[field]
+	if !ok || nil == value {
+		return Latest, nil
+	}
+
+	var version float64
+	switch v := value.(type) {
+	case float64:
+		version = v
+	case int:
+		version = float64(v)
+	case int64:
+		version = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("Invalid schema version %q in %s", v, field)
+		}
+		version = f
+	default:
+		return 0, fmt.Errorf("Invalid schema version %v in %s", value, field)
+	}
+
+	if version < 1 || version != math.Trunc(version) {
+		return 0, fmt.Errorf("Invalid schema version %v in %s", value, field)
+	}
+
+	return int(version), nil
+}
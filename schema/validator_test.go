@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return e.data
+}
+
+// testRegistry has ThingHappened versions 1 and 2. Version 1 requires
+// thingId to be a string.
+type testRegistry struct {
+	t        *testing.T
+	requests []int
+}
+
+func (r *testRegistry) Schema(eventName string, version int) (*Schema, error) {
+	r.requests = append(r.requests, version)
+
+	if "ThingHappened" != eventName || version > 2 {
+		return nil, &NotFoundError{EventName: eventName, Version: version}
+	}
+
+	if 1 == version {
+		return NewSchema(eventName, 1, "1", `{"properties": {"thingId": {"type": "string"}}, "additionalProperties": false}`)
+	}
+
+	return newTestSchema(r.t, 2), nil
+}
+
+func TestNewValidator(t *testing.T) {
+	_, err := NewValidator(nil)
+	assert.EqualError(t, err, "Configuration is required")
+
+	_, err = NewValidator(&ValidatorConfig{})
+	assert.EqualError(t, err, "Registry is required")
+}
+
+func TestValidatorValidate(t *testing.T) {
+	registry := &testRegistry{t: t}
+	validator, err := NewValidator(&ValidatorConfig{Registry: registry})
+	assert.NoError(t, err)
+
+	// Checked against the latest version.
+	assert.NoError(t, validator.Validate(testEvent{"ThingHappened", map[string]interface{}{"thingId": 1, "occurredOn": "2020-01-01"}}))
+
+	// Checked against the version it was published with, which doesn't
+	// need to describe the version field.
+	assert.NoError(t, validator.Validate(testEvent{"ThingHappened", map[string]interface{}{"thingId": "1", "schemaVersion": float64(1)}}))
+
+	err = validator.Validate(testEvent{"ThingHappened", map[string]interface{}{"thingId": 1, "schemaVersion": float64(1)}})
+	assert.EqualError(t, err, "ThingHappened does not match schema version 1: thingId: must be string, not number")
+
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 1, validationErr.Version)
+
+	assert.Equal(t, []int{Latest, 1, 1}, registry.requests)
+
+	err = validator.Validate(testEvent{"ThingHappened", map[string]interface{}{"schemaVersion": "one"}})
+	assert.EqualError(t, err, "Invalid schema version one in schemaVersion")
+
+	err = validator.Validate(testEvent{"ThingHappened", map[string]interface{}{"schemaVersion": 1.5}})
+	assert.EqualError(t, err, "Invalid schema version 1.5 in schemaVersion")
+
+	err = validator.Validate(testEvent{"OtherThingHappened", map[string]interface{}{}})
+	assert.EqualError(t, err, "No schema registered for OtherThingHappened")
+}
+
+func TestValidatorAllowUnregistered(t *testing.T) {
+	validator, err := NewValidator(&ValidatorConfig{
+		Registry:          &testRegistry{t: t},
+		VersionField:      "v",
+		AllowUnregistered: true,
+	})
+	assert.NoError(t, err)
+
+	schema, err := validator.Schema(testEvent{"OtherThingHappened", map[string]interface{}{}})
+	assert.NoError(t, err)
+	assert.Nil(t, schema)
+	assert.NoError(t, validator.Validate(testEvent{"OtherThingHappened", map[string]interface{}{}}))
+
+	schema, err = validator.Schema(testEvent{"ThingHappened", map[string]interface{}{"v": float64(1)}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, schema.Version)
+
+	// Unknown versions of registered events still fail.
+	_, err = validator.Schema(testEvent{"ThingHappened", map[string]interface{}{"v": float64(9)}})
+	assert.EqualError(t, err, "No schema registered for ThingHappened version 9")
+}
+
+func TestValidatorHandler(t *testing.T) {
+	validator, _ := NewValidator(&ValidatorConfig{Registry: &testRegistry{t: t}})
+
+	handled := 0
+	handler := validator.Handler(func(gomainevents.Event) error {
+		handled++
+		return nil
+	})
+
+	assert.NoError(t, handler(testEvent{"ThingHappened", map[string]interface{}{"thingId": "1", "schemaVersion": float64(1)}}))
+	assert.Error(t, handler(testEvent{"ThingHappened", map[string]interface{}{"thingId": 1, "schemaVersion": float64(1)}}))
+	assert.Equal(t, 1, handled)
+}
@@ -0,0 +1,113 @@
+package gomainevents
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Sequence returns the message group and sequence number of an event received
+// from a FIFO queue or topic, or false if it has none. Events that wrap
+// another event, with an Unwrap method, are looked through.
+func Sequence(event Event) (group string, sequenceNumber string, ok bool) {
+	for nil != event {
+		if sequenced, ok := event.(interface {
+			MessageGroupID() string
+			SequenceNumber() string
+		}); ok && "" != sequenced.SequenceNumber() {
+			return sequenced.MessageGroupID(), sequenced.SequenceNumber(), true
+		}
+
+		wrapper, ok := event.(interface{ Unwrap() Event })
+		if !ok {
+			break
+		}
+
+		event = wrapper.Unwrap()
+	}
+
+	return "", "", false
+}
+
+// CompareSequenceNumbers compares two sequence numbers, returning -1, 0 or 1.
+// They're decimal strings too large for an int64, so they're compared by
+// length first.
+func CompareSequenceNumbers(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+// OrderChecker reports events that are handled out of sequence order within
+// their message group, e.g. because they were redriven from a dead letter
+// queue or replayed from an archive after later events, so that consumers
+// rebuilding state from ordered streams can tell when to recover. Events
+// without a sequence number are ignored.
+type OrderChecker struct {
+	onOutOfOrder func(event Event, previous string)
+
+	mu   sync.Mutex
+	last map[string]string
+}
+
+type OrderCheckerConfig struct {
+	// Called with events handled after one later in their group, and the
+	// sequence number of that one. Required
+	OnOutOfOrder func(event Event, previous string)
+}
+
+func NewOrderChecker(config *OrderCheckerConfig) (*OrderChecker, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.OnOutOfOrder {
+		return nil, errors.New("OnOutOfOrder is required")
+	}
+
+	return &OrderChecker{
+		onOutOfOrder: config.OnOutOfOrder,
+		last:         map[string]string{},
+	}, nil
+}
+
+// Handler checks the order of the events fn handles successfully.
+func (c *OrderChecker) Handler(fn EventHandler) EventHandler {
+	return func(event Event) error {
+		if err := fn(event); err != nil {
+			return err
+		}
+
+		c.Check(event)
+
+		return nil
+	}
+}
+
+// Check records that an event was handled, reporting it if it's out of
+// order.
+func (c *OrderChecker) Check(event Event) {
+	group, sequenceNumber, ok := Sequence(event)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	previous, seen := c.last[group]
+	outOfOrder := seen && CompareSequenceNumbers(sequenceNumber, previous) < 0
+	if !outOfOrder {
+		c.last[group] = sequenceNumber
+	}
+	c.mu.Unlock()
+
+	if outOfOrder {
+		c.onOutOfOrder(event, previous)
+	}
+}
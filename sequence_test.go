@@ -0,0 +1,75 @@
+package gomainevents
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sequencedEvent struct {
+	testEvent
+	group          string
+	sequenceNumber string
+}
+
+func (e sequencedEvent) MessageGroupID() string {
+	return e.group
+}
+
+func (e sequencedEvent) SequenceNumber() string {
+	return e.sequenceNumber
+}
+
+func TestSequence(t *testing.T) {
+	event := sequencedEvent{testEvent: testEvent{name: "Placed"}, group: "order-1", sequenceNumber: "100"}
+
+	group, sequenceNumber, ok := Sequence(WithData(event, map[string]interface{}{}))
+	assert.True(t, ok)
+	assert.Equal(t, "order-1", group)
+	assert.Equal(t, "100", sequenceNumber)
+
+	_, _, ok = Sequence(testEvent{name: "Placed"})
+	assert.False(t, ok)
+
+	_, _, ok = Sequence(sequencedEvent{testEvent: testEvent{name: "Placed"}})
+	assert.False(t, ok)
+}
+
+func TestCompareSequenceNumbers(t *testing.T) {
+	assert.Equal(t, -1, CompareSequenceNumbers("9850000000000000100", "18850000000000000100"))
+	assert.Equal(t, 1, CompareSequenceNumbers("18850000000000000200", "18850000000000000100"))
+	assert.Equal(t, 0, CompareSequenceNumbers("0100", "100"))
+}
+
+func TestOrderChecker(t *testing.T) {
+	checker, err := NewOrderChecker(nil)
+	assert.Nil(t, checker)
+	assert.EqualError(t, err, "Configuration is required")
+
+	checker, err = NewOrderChecker(&OrderCheckerConfig{})
+	assert.Nil(t, checker)
+	assert.EqualError(t, err, "OnOutOfOrder is required")
+
+	reported := []string{}
+	checker, err = NewOrderChecker(&OrderCheckerConfig{
+		OnOutOfOrder: func(event Event, previous string) {
+			_, sequenceNumber, _ := Sequence(event)
+			reported = append(reported, sequenceNumber+" after "+previous)
+		},
+	})
+	require.Nil(t, err)
+
+	handler := checker.Handler(func(Event) error { return nil })
+	for _, event := range []sequencedEvent{
+		{group: "order-1", sequenceNumber: "100"},
+		{group: "order-2", sequenceNumber: "50"},
+		{group: "order-1", sequenceNumber: "300"},
+		{group: "order-1", sequenceNumber: "200"},
+		{group: "order-1", sequenceNumber: "400"},
+	} {
+		require.Nil(t, handler(event))
+	}
+
+	assert.Equal(t, []string{"200 after 300"}, reported)
+}
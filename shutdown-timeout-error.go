@@ -0,0 +1,16 @@
+package gomainevents
+
+import (
+	"fmt"
+	"time"
+)
+
+// ShutdownTimeoutError is returned when a Listener is shut down but its
+// handlers don't finish the events already received in time.
+type ShutdownTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *ShutdownTimeoutError) Error() string {
+	return fmt.Sprintf("Timed out after %s waiting for events to be handled", e.Timeout)
+}
@@ -0,0 +1,130 @@
+package slo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Kind is which objective was missed.
+type Kind string
+
+const (
+	// KindHandleTime is a handler running for longer than HandleTime.
+	KindHandleTime Kind = "handle_time"
+
+	// KindAge is an event being handled longer than Age after it happened.
+	KindAge Kind = "age"
+)
+
+// Breach describes a missed objective.
+type Breach struct {
+	Kind  Kind
+	Event gomainevents.Event
+
+	// How long the handler had been running, or how old the event was.
+	Duration  time.Duration
+	Threshold time.Duration
+}
+
+// Thresholds are the objectives for handling an event. Zero leaves an
+// objective unchecked.
+type Thresholds struct {
+	// Longest a handler may run.
+	HandleTime time.Duration
+
+	// Longest between an event happening and its handler finishing. See
+	// gomainevents.OccurredAt for how an event's age is worked out.
+	Age time.Duration
+}
+
+// Monitor calls OnBreach when handling an event misses its objectives, so
+// that breaches can page someone rather than wait to be noticed on a
+// dashboard. Wrap each handler with it:
+//
+//	listener.RegisterHandler("ThingHappened", monitor.Handler(handleThing))
+//
+// Handle time breaches are reported as soon as the threshold passes, while
+// the handler is still running, and age breaches when the handler finishes.
+// Each is reported at most once per handler call. OnBreach may be called
+// from several goroutines at once.
+type Monitor struct {
+	thresholds      Thresholds
+	eventThresholds map[string]Thresholds
+	onBreach        func(Breach)
+
+	// Hook for tests
+	now func() time.Time
+}
+
+type Config struct {
+	// Objectives for every event.
+	Thresholds Thresholds
+
+	// Objectives for particular events, by name, replacing Thresholds.
+	EventThresholds map[string]Thresholds
+
+	// Called for each breach. Required
+	OnBreach func(Breach)
+}
+
+func NewMonitor(config *Config) (*Monitor, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.OnBreach {
+		return nil, errors.New("OnBreach is required")
+	}
+
+	return &Monitor{
+		thresholds:      config.Thresholds,
+		eventThresholds: config.EventThresholds,
+		onBreach:        config.OnBreach,
+		now:             time.Now,
+	}, nil
+}
+
+// Handler wraps an EventHandler, checking each call against the event's
+// objectives.
+func (m *Monitor) Handler(fn gomainevents.EventHandler) gomainevents.EventHandler {
+	return func(event gomainevents.Event) error {
+		thresholds := m.thresholdsFor(event.Name())
+
+		if thresholds.HandleTime > 0 {
+			timer := time.AfterFunc(thresholds.HandleTime, func() {
+				m.onBreach(Breach{
+					Kind:      KindHandleTime,
+					Event:     event,
+					Duration:  thresholds.HandleTime,
+					Threshold: thresholds.HandleTime,
+				})
+			})
+			defer timer.Stop()
+		}
+
+		err := fn(event)
+
+		if thresholds.Age > 0 {
+			if age, ok := gomainevents.EventAge(event, m.now()); ok && age > thresholds.Age {
+				m.onBreach(Breach{
+					Kind:      KindAge,
+					Event:     event,
+					Duration:  age,
+					Threshold: thresholds.Age,
+				})
+			}
+		}
+
+		return err
+	}
+}
+
+func (m *Monitor) thresholdsFor(name string) Thresholds {
+	if thresholds, ok := m.eventThresholds[name]; ok {
+		return thresholds
+	}
+
+	return m.thresholds
+}
@@ -0,0 +1,116 @@
+package slo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08T11:11:11Z"}
+}
+
+type breaches struct {
+	mu   sync.Mutex
+	list []Breach
+}
+
+func (b *breaches) record(breach Breach) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.list = append(b.list, breach)
+}
+
+func (b *breaches) all() []Breach {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Breach{}, b.list...)
+}
+
+func newTestMonitor(config *Config, b *breaches) *Monitor {
+	config.OnBreach = b.record
+	monitor, _ := NewMonitor(config)
+	monitor.now = func() time.Time {
+		return time.Date(2018, 3, 8, 11, 16, 11, 0, time.UTC)
+	}
+
+	return monitor
+}
+
+func TestNewMonitor(t *testing.T) {
+	monitor, err := NewMonitor(nil)
+	assert.Nil(t, monitor)
+	assert.NotNil(t, err)
+
+	monitor, err = NewMonitor(&Config{})
+	assert.Nil(t, monitor)
+	assert.NotNil(t, err)
+
+	monitor, err = NewMonitor(&Config{OnBreach: func(Breach) {}})
+	assert.NotNil(t, monitor)
+	assert.Nil(t, err)
+}
+
+func TestMonitorReportsSlowHandlersWhileRunning(t *testing.T) {
+	b := &breaches{}
+	monitor := newTestMonitor(&Config{Thresholds: Thresholds{HandleTime: 10 * time.Millisecond}}, b)
+
+	event := testEvent{name: "Created"}
+	handler := monitor.Handler(func(gomainevents.Event) error {
+		// Reported before the handler finishes
+		assert.Eventually(t, func() bool { return len(b.all()) == 1 }, 5*time.Second, time.Millisecond)
+		return errors.New("Nope")
+	})
+
+	assert.EqualError(t, handler(event), "Nope")
+	assert.Equal(t, []Breach{{
+		Kind:      KindHandleTime,
+		Event:     event,
+		Duration:  10 * time.Millisecond,
+		Threshold: 10 * time.Millisecond,
+	}}, b.all())
+}
+
+func TestMonitorIgnoresQuickHandlers(t *testing.T) {
+	b := &breaches{}
+	monitor := newTestMonitor(&Config{Thresholds: Thresholds{HandleTime: 20 * time.Millisecond, Age: time.Hour}}, b)
+
+	require.Nil(t, monitor.Handler(func(gomainevents.Event) error { return nil })(testEvent{name: "Created"}))
+
+	time.Sleep(40 * time.Millisecond)
+	assert.Empty(t, b.all())
+}
+
+func TestMonitorReportsOldEvents(t *testing.T) {
+	b := &breaches{}
+	monitor := newTestMonitor(&Config{
+		Thresholds:      Thresholds{Age: time.Minute},
+		EventThresholds: map[string]Thresholds{"Reported": {Age: 10 * time.Minute}},
+	}, b)
+
+	handler := monitor.Handler(func(gomainevents.Event) error { return nil })
+	require.Nil(t, handler(testEvent{name: "Created"}))
+	require.Nil(t, handler(testEvent{name: "Reported"}))
+
+	assert.Equal(t, []Breach{{
+		Kind:      KindAge,
+		Event:     testEvent{name: "Created"},
+		Duration:  5 * time.Minute,
+		Threshold: time.Minute,
+	}}, b.all())
+}
@@ -0,0 +1,34 @@
+package sns
+
+import (
+	"fmt"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// BatchFailure describes a single event that could not be published as part
+// of a batch.
+type BatchFailure struct {
+	Event gomainevents.Event
+
+	// Code and Message are reported by SNS for entries it rejected.
+	Code    string
+	Message string
+
+	// SenderFault is true when SNS reports that the entry itself was bad
+	// and retrying it unchanged will not help.
+	SenderFault bool
+
+	// Err is set instead of Code/Message when the whole request failed.
+	Err error
+}
+
+// BatchPublishError is returned by PublishBatch when one or more events
+// could not be published. Events not listed were published successfully.
+type BatchPublishError struct {
+	Failures []BatchFailure
+}
+
+func (e *BatchPublishError) Error() string {
+	return fmt.Sprintf("Failed to publish %d event(s) in batch", len(e.Failures))
+}
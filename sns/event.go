@@ -0,0 +1,39 @@
+package sns
+
+// Event implements the standard domain event interface for notifications
+// delivered by SNS to an HTTP(S) subscription.
+type Event struct {
+	name string
+	data map[string]interface{}
+
+	// SNS assigns every notification a unique ID. It is used to match
+	// the event back up with the request that delivered it.
+	messageID string
+
+	topicARN string
+	subject  string
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// MessageID returns the unique identifier SNS assigned to the notification
+// this event was created from.
+func (e Event) MessageID() string {
+	return e.messageID
+}
+
+// TopicARN returns the topic the notification was published to.
+func (e Event) TopicARN() string {
+	return e.topicARN
+}
+
+// Subject returns the subject of the notification, if one was set.
+func (e Event) Subject() string {
+	return e.subject
+}
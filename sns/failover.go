@@ -0,0 +1,303 @@
+package sns
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awssns "github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultFailureThreshold  = 3
+	defaultRecoveryThreshold = 3
+	defaultProbeInterval     = 30 * time.Second
+	probeTimeout             = 10 * time.Second
+)
+
+// FailoverPublisher publishes to a primary topic, falling back to a
+// secondary topic, typically in another region, when the primary can't be
+// reached. Each event that fails on the primary is published to the
+// secondary instead. After FailureThreshold failures in a row, publishing
+// switches to the secondary altogether and the primary topic is probed
+// until it has answered RecoveryThreshold times in a row, at which point
+// publishing fails back.
+//
+// Failures caused by the event itself, such as validation errors, are
+// returned as they are and don't count towards failing over.
+type FailoverPublisher struct {
+	primary   *Publisher
+	secondary *Publisher
+
+	failureThreshold  int
+	recoveryThreshold int
+	probeInterval     time.Duration
+	onFailover        func(failedOver bool)
+
+	mu         sync.Mutex
+	failedOver bool
+	failures   int
+	recoveries int
+
+	done      chan bool
+	stopped   chan bool
+	closeOnce sync.Once
+	debug     bool
+}
+
+type FailoverConfig struct {
+	// Publisher for the primary topic. Required
+	Primary *Publisher
+
+	// Publisher for the secondary topic. Required
+	Secondary *Publisher
+
+	// Number of failed publishes in a row before switching to the
+	// secondary. Defaults to 3.
+	FailureThreshold int
+
+	// Number of successful probes in a row before switching back to the
+	// primary. Defaults to 3.
+	RecoveryThreshold int
+
+	// How often the primary topic is probed while failed over. Defaults to
+	// 30 seconds.
+	ProbeInterval time.Duration
+
+	// Called whenever publishing switches topics, with true on failing
+	// over and false on failing back. Optional
+	OnFailover func(failedOver bool)
+}
+
+func NewFailoverPublisher(config *FailoverConfig) (*FailoverPublisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Primary {
+		return nil, errors.New("Primary is required")
+	}
+
+	if nil == config.Secondary {
+		return nil, errors.New("Secondary is required")
+	}
+
+	failureThreshold := defaultFailureThreshold
+	if config.FailureThreshold > 0 {
+		failureThreshold = config.FailureThreshold
+	}
+
+	recoveryThreshold := defaultRecoveryThreshold
+	if config.RecoveryThreshold > 0 {
+		recoveryThreshold = config.RecoveryThreshold
+	}
+
+	probeInterval := defaultProbeInterval
+	if config.ProbeInterval > 0 {
+		probeInterval = config.ProbeInterval
+	}
+
+	p := &FailoverPublisher{
+		primary:           config.Primary,
+		secondary:         config.Secondary,
+		failureThreshold:  failureThreshold,
+		recoveryThreshold: recoveryThreshold,
+		probeInterval:     probeInterval,
+		onFailover:        config.OnFailover,
+		done:              make(chan bool),
+		stopped:           make(chan bool),
+		debug:             true,
+	}
+
+	go p.run()
+
+	return p, nil
+}
+
+// FailedOver reports whether events are currently published to the
+// secondary topic.
+func (p *FailoverPublisher) FailedOver() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.failedOver
+}
+
+func (p *FailoverPublisher) Publish(event gomainevents.Event) error {
+	if p.FailedOver() {
+		return p.secondary.Publish(event)
+	}
+
+	err := p.primary.Publish(event)
+	if err == nil || isEventFault(err) {
+		p.recordPrimary(true)
+		return err
+	}
+
+	p.recordPrimary(false)
+	p.debugPrint("Publishing %s to the secondary topic: %s\n", event.Name(), err)
+
+	return p.secondary.Publish(event)
+}
+
+// PublishBatch publishes the events to the active topic. Events that fail
+// on the primary are published to the secondary.
+func (p *FailoverPublisher) PublishBatch(events []gomainevents.Event) error {
+	if p.FailedOver() {
+		return p.secondary.PublishBatch(events)
+	}
+
+	err := p.primary.PublishBatch(events)
+	if err == nil {
+		p.recordPrimary(true)
+		return nil
+	}
+
+	var batchErr *BatchPublishError
+	if !errors.As(err, &batchErr) {
+		p.recordPrimary(false)
+		return p.secondary.PublishBatch(events)
+	}
+
+	// Failures caused by the events themselves will fail on the secondary
+	// too, so only the rest are retried there.
+	failures := []BatchFailure{}
+	retry := []gomainevents.Event{}
+	for _, failure := range batchErr.Failures {
+		if failure.SenderFault || isEventFault(failure.Err) {
+			failures = append(failures, failure)
+			continue
+		}
+
+		retry = append(retry, failure.Event)
+	}
+
+	if len(retry) == 0 {
+		p.recordPrimary(true)
+		return err
+	}
+
+	p.recordPrimary(false)
+	p.debugPrint("Publishing %d events to the secondary topic\n", len(retry))
+
+	if err := p.secondary.PublishBatch(retry); err != nil {
+		if !errors.As(err, &batchErr) {
+			return err
+		}
+
+		failures = append(failures, batchErr.Failures...)
+	}
+
+	if len(failures) > 0 {
+		return &BatchPublishError{Failures: failures}
+	}
+
+	return nil
+}
+
+// Close stops probing the primary topic.
+func (p *FailoverPublisher) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		<-p.stopped
+	})
+}
+
+// recordPrimary counts a publish to the primary, failing over once enough
+// have failed in a row.
+func (p *FailoverPublisher) recordPrimary(ok bool) {
+	p.mu.Lock()
+
+	if ok {
+		p.failures = 0
+		p.mu.Unlock()
+		return
+	}
+
+	p.failures++
+	if p.failedOver || p.failures < p.failureThreshold {
+		p.mu.Unlock()
+		return
+	}
+
+	p.failedOver = true
+	p.recoveries = 0
+	p.mu.Unlock()
+
+	p.debugPrint("Failing over to the secondary topic\n")
+	if nil != p.onFailover {
+		p.onFailover(true)
+	}
+}
+
+func (p *FailoverPublisher) run() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(p.probeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			if p.FailedOver() {
+				p.probe()
+			}
+		}
+	}
+}
+
+// probe checks that the primary topic is reachable, failing back once it
+// has been enough times in a row.
+func (p *FailoverPublisher) probe() {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	_, err := p.primary.snsClient.GetTopicAttributes(ctx, &awssns.GetTopicAttributesInput{
+		TopicArn: aws.String(p.primary.topicARN),
+	})
+
+	p.mu.Lock()
+
+	if err != nil {
+		p.recoveries = 0
+		p.mu.Unlock()
+		p.debugPrint("Primary topic is still unavailable: %s\n", err)
+		return
+	}
+
+	p.recoveries++
+	if !p.failedOver || p.recoveries < p.recoveryThreshold {
+		p.mu.Unlock()
+		return
+	}
+
+	p.failedOver = false
+	p.failures = 0
+	p.mu.Unlock()
+
+	p.debugPrint("Failing back to the primary topic\n")
+	if nil != p.onFailover {
+		p.onFailover(false)
+	}
+}
+
+// isEventFault reports whether a publish failed because of the event
+// itself, so that publishing it anywhere else would fail the same way.
+func isEventFault(err error) bool {
+	var validationErr *ValidationError
+	var tooLargeErr *MessageTooLargeError
+
+	return errors.As(err, &validationErr) || errors.As(err, &tooLargeErr)
+}
+
+func (p *FailoverPublisher) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-sns] "+format, values...)
+	}
+}
@@ -0,0 +1,137 @@
+package sns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFailoverPublisher(t *testing.T, primary, secondary *mockSNS, config *FailoverConfig) *FailoverPublisher {
+	primaryPublisher, err := NewPublisher(&Config{SNSClient: primary, TopicARN: "arn:aws:sns:us-east-1:1234:events", MaximumRetryCount: 1})
+	require.Nil(t, err)
+	primaryPublisher.retryDelay = noDelay
+
+	secondaryPublisher, err := NewPublisher(&Config{SNSClient: secondary, TopicARN: "arn:aws:sns:us-west-2:1234:events", MaximumRetryCount: 1})
+	require.Nil(t, err)
+	secondaryPublisher.retryDelay = noDelay
+
+	config.Primary = primaryPublisher
+	config.Secondary = secondaryPublisher
+
+	// Tests probe by hand
+	config.ProbeInterval = time.Hour
+
+	publisher, err := NewFailoverPublisher(config)
+	require.Nil(t, err)
+	publisher.debug = false
+
+	return publisher
+}
+
+func TestNewFailoverPublisher(t *testing.T) {
+	primary, _ := NewPublisher(&Config{SNSClient: &mockSNS{}, TopicARN: "arn"})
+
+	publisher, err := NewFailoverPublisher(&FailoverConfig{Primary: primary})
+	assert.Nil(t, publisher)
+	assert.EqualError(t, err, "Secondary is required")
+
+	publisher, err = NewFailoverPublisher(&FailoverConfig{Secondary: primary})
+	assert.Nil(t, publisher)
+	assert.EqualError(t, err, "Primary is required")
+
+	publisher, err = NewFailoverPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestFailoverPublisherFailsOverAndBack(t *testing.T) {
+	down := &types.InternalErrorException{Message: aws.String("Oops")}
+	primary := &mockSNS{publishErrors: []error{down, down, down, down}}
+	secondary := &mockSNS{}
+
+	switches := []bool{}
+	publisher := newTestFailoverPublisher(t, primary, secondary, &FailoverConfig{
+		FailureThreshold:  2,
+		RecoveryThreshold: 2,
+		OnFailover: func(failedOver bool) {
+			switches = append(switches, failedOver)
+		},
+	})
+	defer publisher.Close()
+
+	// Each failed event still goes out through the secondary
+	require.Nil(t, publisher.Publish(testEvent{name: "First"}))
+	assert.False(t, publisher.FailedOver())
+	require.Nil(t, publisher.Publish(testEvent{name: "Second"}))
+	assert.True(t, publisher.FailedOver())
+	assert.Len(t, secondary.published, 2)
+	assert.Len(t, primary.published, 4)
+
+	// Once failed over, the primary is left alone
+	require.Nil(t, publisher.Publish(testEvent{name: "Third"}))
+	assert.Len(t, secondary.published, 3)
+	assert.Len(t, primary.published, 4)
+
+	// Fails back once the primary has answered enough probes in a row
+	publisher.probe()
+	assert.True(t, publisher.FailedOver())
+
+	primary.topicExists = true
+	publisher.probe()
+	assert.True(t, publisher.FailedOver())
+	publisher.probe()
+	assert.False(t, publisher.FailedOver())
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Fourth"}))
+	assert.Len(t, primary.published, 5)
+	assert.Equal(t, []bool{true, false}, switches)
+}
+
+func TestFailoverPublisherRecoversBeforeThreshold(t *testing.T) {
+	down := &types.InternalErrorException{Message: aws.String("Oops")}
+	primary := &mockSNS{publishErrors: []error{down, down}}
+	publisher := newTestFailoverPublisher(t, primary, &mockSNS{}, &FailoverConfig{FailureThreshold: 2})
+	defer publisher.Close()
+
+	require.Nil(t, publisher.Publish(testEvent{name: "First"}))
+	require.Nil(t, publisher.Publish(testEvent{name: "Second"}))
+	require.Nil(t, publisher.Publish(testEvent{name: "Third"}))
+
+	assert.False(t, publisher.FailedOver())
+}
+
+func TestFailoverPublisherIgnoresEventFaults(t *testing.T) {
+	primary := &mockSNS{}
+	secondary := &mockSNS{}
+	publisher := newTestFailoverPublisher(t, primary, secondary, &FailoverConfig{FailureThreshold: 1})
+	defer publisher.Close()
+
+	publisher.primary.validators = map[string]Validator{
+		"Thing": func(map[string]interface{}) error {
+			return assert.AnError
+		},
+	}
+
+	err := publisher.Publish(testEvent{name: "Thing"})
+	assert.IsType(t, &ValidationError{}, err)
+	assert.False(t, publisher.FailedOver())
+	assert.Empty(t, secondary.published)
+}
+
+func TestFailoverPublisherBatchRetriesFailedEntries(t *testing.T) {
+	primary := &mockSNS{failIDs: map[string]bool{"1": true}}
+	secondary := &mockSNS{}
+	publisher := newTestFailoverPublisher(t, primary, secondary, &FailoverConfig{})
+	defer publisher.Close()
+
+	require.Nil(t, publisher.PublishBatch(makeEvents(3)))
+
+	require.Len(t, secondary.batches, 1)
+	require.Len(t, secondary.batches[0].PublishBatchRequestEntries, 1)
+	assert.Contains(t, *secondary.batches[0].PublishBatchRequestEntries[0].Message, "Event1")
+	assert.False(t, publisher.FailedOver())
+}
@@ -0,0 +1,40 @@
+package sns
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/researchsquare/gomainevents"
+)
+
+// IDMapper derives an ID from an event, e.g. the message group of events
+// published to a FIFO topic.
+type IDMapper func(gomainevents.Event) string
+
+// isFIFO reports whether the topic is a FIFO topic, which have names ending
+// in .fifo.
+func isFIFO(topicARN string) bool {
+	return strings.HasSuffix(topicName(topicARN), ".fifo")
+}
+
+// fifoIDs returns the message group and deduplication IDs a message of an
+// event is published with, which are nil for standard topics. Each of the
+// count messages an event was split into gets its own deduplication ID.
+func (p *Publisher) fifoIDs(event gomainevents.Event, index int, count int) (*string, *string) {
+	if nil == p.messageGroupID {
+		return nil, nil
+	}
+
+	groupID := aws.String(p.messageGroupID(event))
+	if nil == p.deduplicationID {
+		return groupID, nil
+	}
+
+	deduplicationID := p.deduplicationID(event)
+	if count > 1 {
+		deduplicationID += "-" + strconv.Itoa(index)
+	}
+
+	return groupID, aws.String(deduplicationID)
+}
@@ -0,0 +1,309 @@
+package sns
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// defaultResponseTimeout matches the time SNS waits for an HTTP endpoint to
+// respond before treating the delivery as failed.
+const defaultResponseTimeout = 15 * time.Second
+
+// HTTPProvider is a Provider for SNS HTTP(S) subscriptions. Mount it as the
+// http.Handler for the subscription endpoint: it confirms subscriptions,
+// verifies message signatures and passes notifications to the Listener.
+//
+// Each request is held open until the event is deleted or requeued. Deleted
+// events are acknowledged with a 200, requeued events with a 500 so that SNS
+// redelivers them according to the topic's delivery policy.
+type HTTPProvider struct {
+	topicARN                  string
+	httpClient                *http.Client
+	verifier                  *signatureVerifier
+	skipSignatureVerification bool
+	responseTimeout           time.Duration
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while requests are delivering events.
+	closeMu sync.RWMutex
+
+	// Requests waiting for their event to be deleted or requeued, by
+	// message ID.
+	mu      sync.Mutex
+	pending map[string]chan bool
+}
+
+type HTTPProviderConfig struct {
+	// Only accept messages published to this topic. Optional, but
+	// strongly recommended.
+	TopicARN string
+
+	// Client used to confirm subscriptions and fetch signing
+	// certificates. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Disables message signature verification. Only intended for tests
+	// and local development.
+	SkipSignatureVerification bool
+
+	// How long to wait for handlers before asking SNS to redeliver.
+	// Defaults to 15 seconds.
+	ResponseTimeout time.Duration
+}
+
+func NewHTTPProvider(config *HTTPProviderConfig) (*HTTPProvider, error) {
+	if nil == config {
+		return nil, fmt.Errorf("Configuration is required")
+	}
+
+	httpClient := config.HTTPClient
+	if nil == httpClient {
+		httpClient = http.DefaultClient
+	}
+
+	responseTimeout := defaultResponseTimeout
+	if config.ResponseTimeout > 0 {
+		responseTimeout = config.ResponseTimeout
+	}
+
+	return &HTTPProvider{
+		topicARN:                  config.TopicARN,
+		httpClient:                httpClient,
+		verifier:                  newSignatureVerifier(httpClient),
+		skipSignatureVerification: config.SkipSignatureVerification,
+		responseTimeout:           responseTimeout,
+		events:                    make(chan gomainevents.Event, 100),
+		errors:                    make(chan error, 1),
+		done:                      make(chan bool),
+		debug:                     true,
+		pending:                   make(map[string]chan bool),
+	}, nil
+}
+
+// Return a channel that can be used to retrieve events
+func (p *HTTPProvider) Start() (<-chan gomainevents.Event, <-chan error) {
+	return p.events, p.errors
+}
+
+// Delete an event that we're done with
+func (p *HTTPProvider) Delete(event gomainevents.Event) {
+	p.resolve(event.(Event), true)
+}
+
+// Requeue an event for later. SNS decides when to redeliver it.
+func (p *HTTPProvider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	p.resolve(event.(Event), false)
+
+	return nil
+}
+
+// Stop the channel
+func (p *HTTPProvider) Stop() {
+	close(p.done)
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+func (p *HTTPProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	msg := &httpMessage{}
+	if err := json.NewDecoder(r.Body).Decode(msg); err != nil {
+		p.reportError(gomainevents.PhaseDecode, "", err)
+		http.Error(w, "Invalid message", http.StatusBadRequest)
+		return
+	}
+
+	if "" != p.topicARN && msg.TopicArn != p.topicARN {
+		p.reportError(gomainevents.PhaseDecode, msg.MessageId, fmt.Errorf("Message from unexpected topic: %s", msg.TopicArn))
+		http.Error(w, "Unexpected topic", http.StatusForbidden)
+		return
+	}
+
+	if !p.skipSignatureVerification {
+		if err := p.verifier.verify(msg); err != nil {
+			p.reportError(gomainevents.PhaseDecode, msg.MessageId, fmt.Errorf("Invalid message signature: %w", err))
+			http.Error(w, "Invalid signature", http.StatusForbidden)
+			return
+		}
+	}
+
+	switch msg.Type {
+	case messageTypeSubscriptionConfirmation:
+		if err := p.confirmSubscription(msg); err != nil {
+			p.reportError(gomainevents.PhaseConnect, msg.MessageId, err)
+			http.Error(w, "Unable to confirm subscription", http.StatusInternalServerError)
+			return
+		}
+	case messageTypeUnsubscribeConfirmation:
+		p.debugPrint("Unsubscribed from %s\n", msg.TopicArn)
+	case messageTypeNotification:
+		p.handleNotification(w, msg)
+	default:
+		http.Error(w, "Unknown message type", http.StatusBadRequest)
+	}
+}
+
+func (p *HTTPProvider) confirmSubscription(msg *httpMessage) error {
+	p.debugPrint("Confirming subscription to %s\n", msg.TopicArn)
+
+	resp, err := p.httpClient.Get(msg.SubscribeURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unable to confirm subscription: %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (p *HTTPProvider) handleNotification(w http.ResponseWriter, msg *httpMessage) {
+	evt := &encodedEvent{}
+	if err := json.Unmarshal([]byte(msg.Message), evt); err != nil {
+		// Redelivering won't make the message decodable.
+		p.reportError(gomainevents.PhaseDecode, msg.MessageId, err)
+		http.Error(w, "Invalid event", http.StatusBadRequest)
+		return
+	}
+
+	event := Event{
+		name:      evt.Name,
+		data:      evt.Data,
+		messageID: msg.MessageId,
+		topicARN:  msg.TopicArn,
+		subject:   msg.Subject,
+	}
+
+	result, ok := p.register(event.messageID)
+	if !ok {
+		http.Error(w, "Message is already being processed", http.StatusServiceUnavailable)
+		return
+	}
+	defer p.unregister(event.messageID)
+
+	timeout := time.NewTimer(p.responseTimeout)
+	defer timeout.Stop()
+
+	if !p.deliver(event, timeout.C) {
+		http.Error(w, "Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	select {
+	case deleted := <-result:
+		if !deleted {
+			http.Error(w, "Requeued", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	case <-timeout.C:
+		http.Error(w, "Timed out", http.StatusGatewayTimeout)
+	case <-p.done:
+		http.Error(w, "Unavailable", http.StatusServiceUnavailable)
+	}
+}
+
+// deliver passes the event to the Listener, returning false if the provider
+// stopped or the timeout fired first.
+func (p *HTTPProvider) deliver(event Event, timeout <-chan time.Time) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-timeout:
+		return false
+	case <-p.done:
+		return false
+	}
+}
+
+func (p *HTTPProvider) register(messageID string) (chan bool, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.pending[messageID]; ok {
+		return nil, false
+	}
+
+	result := make(chan bool, 1)
+	p.pending[messageID] = result
+
+	return result, true
+}
+
+func (p *HTTPProvider) unregister(messageID string) {
+	p.mu.Lock()
+	delete(p.pending, messageID)
+	p.mu.Unlock()
+}
+
+func (p *HTTPProvider) resolve(event Event, deleted bool) {
+	p.mu.Lock()
+	result, ok := p.pending[event.messageID]
+	p.mu.Unlock()
+
+	if !ok {
+		p.debugPrint("No request waiting for message %s\n", event.messageID)
+		return
+	}
+
+	select {
+	case result <- deleted:
+	default:
+	}
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking the request.
+func (p *HTTPProvider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, p.topicARN, messageID, err):
+	default:
+	}
+}
+
+func (p *HTTPProvider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-sns] "+format, values...)
+	}
+}
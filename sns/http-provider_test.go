@@ -0,0 +1,170 @@
+package sns
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type signingServer struct {
+	*httptest.Server
+	key        *rsa.PrivateKey
+	subscribed bool
+}
+
+func newSigningServer(t *testing.T) *signingServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.us-east-1.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	s := &signingServer{key: key}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cert.pem":
+			w.Write(certPEM)
+		case "/subscribe":
+			s.subscribed = true
+		}
+	}))
+
+	return s
+}
+
+func (s *signingServer) sign(t *testing.T, msg *httpMessage) []byte {
+	msg.SignatureVersion = "2"
+	msg.SigningCertURL = s.URL + "/cert.pem"
+
+	digest := sha256.Sum256([]byte(msg.stringToSign()))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	require.Nil(t, err)
+	msg.Signature = base64.StdEncoding.EncodeToString(signature)
+
+	body, err := json.Marshal(msg)
+	require.Nil(t, err)
+
+	return body
+}
+
+func newTestHTTPProvider(t *testing.T, s *signingServer) *HTTPProvider {
+	provider, err := NewHTTPProvider(&HTTPProviderConfig{
+		TopicARN:        "arn:aws:sns:us-east-1:1234:events",
+		HTTPClient:      s.Client(),
+		ResponseTimeout: time.Second,
+	})
+	require.Nil(t, err)
+	provider.verifier.isTrustedCertURL = func(*url.URL) bool { return true }
+
+	return provider
+}
+
+func notification(id string) *httpMessage {
+	return &httpMessage{
+		Type:      messageTypeNotification,
+		MessageId: id,
+		TopicArn:  "arn:aws:sns:us-east-1:1234:events",
+		Message:   `{"name":"Domain\\Event","data":{"occurredOn":"2018-03-08 11:11:11"}}`,
+		Timestamp: "2018-03-08T11:11:11.000Z",
+	}
+}
+
+func post(provider *HTTPProvider, body []byte) int {
+	recorder := httptest.NewRecorder()
+	provider.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+
+	return recorder.Code
+}
+
+func TestHTTPProviderDeliversNotifications(t *testing.T) {
+	server := newSigningServer(t)
+	defer server.Close()
+
+	provider := newTestHTTPProvider(t, server)
+	events, _ := provider.Start()
+
+	go func() {
+		event := <-events
+		assert.Equal(t, "Domain\\Event", event.Name())
+		assert.Equal(t, "1", event.(Event).MessageID())
+		provider.Delete(event)
+
+		event = <-events
+		provider.Requeue(event)
+	}()
+
+	assert.Equal(t, http.StatusOK, post(provider, server.sign(t, notification("1"))))
+	assert.Equal(t, http.StatusInternalServerError, post(provider, server.sign(t, notification("2"))))
+}
+
+func TestHTTPProviderRejectsInvalidMessages(t *testing.T) {
+	server := newSigningServer(t)
+	defer server.Close()
+
+	provider := newTestHTTPProvider(t, server)
+
+	// Tampered message
+	msg := notification("1")
+	body := server.sign(t, msg)
+	body = bytes.Replace(body, []byte("11:11:11\\\""), []byte("12:12:12\\\""), 1)
+	assert.Equal(t, http.StatusForbidden, post(provider, body))
+
+	// Wrong topic
+	msg = notification("1")
+	msg.TopicArn = "arn:aws:sns:us-east-1:1234:other"
+	assert.Equal(t, http.StatusForbidden, post(provider, server.sign(t, msg)))
+}
+
+func TestHTTPProviderConfirmsSubscriptions(t *testing.T) {
+	server := newSigningServer(t)
+	defer server.Close()
+
+	provider := newTestHTTPProvider(t, server)
+
+	msg := &httpMessage{
+		Type:         messageTypeSubscriptionConfirmation,
+		MessageId:    "1",
+		Token:        "token",
+		TopicArn:     "arn:aws:sns:us-east-1:1234:events",
+		Message:      "Confirm",
+		SubscribeURL: server.URL + "/subscribe",
+		Timestamp:    "2018-03-08T11:11:11.000Z",
+	}
+
+	assert.Equal(t, http.StatusOK, post(provider, server.sign(t, msg)))
+	assert.True(t, server.subscribed)
+}
+
+func TestIsSNSCertURL(t *testing.T) {
+	valid, _ := url.Parse("https://sns.us-east-1.amazonaws.com/SimpleNotificationService-1234.pem")
+	assert.True(t, isSNSCertURL(valid))
+
+	insecure, _ := url.Parse("http://sns.us-east-1.amazonaws.com/SimpleNotificationService-1234.pem")
+	assert.False(t, isSNSCertURL(insecure))
+
+	spoofed, _ := url.Parse("https://sns.us-east-1.amazonaws.com.evil.example/cert.pem")
+	assert.False(t, isSNSCertURL(spoofed))
+}
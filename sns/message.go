@@ -0,0 +1,190 @@
+package sns
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Message types sent by SNS to HTTP(S) subscriptions.
+const (
+	messageTypeNotification             = "Notification"
+	messageTypeSubscriptionConfirmation = "SubscriptionConfirmation"
+	messageTypeUnsubscribeConfirmation  = "UnsubscribeConfirmation"
+)
+
+// certHostPattern matches the hosts SNS serves its signing certificates from.
+var certHostPattern = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// httpMessage is the JSON document SNS POSTs to HTTP(S) subscriptions.
+type httpMessage struct {
+	Type             string
+	MessageId        string
+	Token            string
+	TopicArn         string
+	Subject          string
+	Message          string
+	Timestamp        string
+	SignatureVersion string
+	Signature        string
+	SigningCertURL   string
+	SubscribeURL     string
+	UnsubscribeURL   string
+}
+
+// stringToSign builds the canonical string SNS signs for this message type.
+func (m *httpMessage) stringToSign() string {
+	var fields [][2]string
+
+	switch m.Type {
+	case messageTypeNotification:
+		fields = [][2]string{{"Message", m.Message}, {"MessageId", m.MessageId}}
+		if "" != m.Subject {
+			fields = append(fields, [2]string{"Subject", m.Subject})
+		}
+		fields = append(fields,
+			[2]string{"Timestamp", m.Timestamp},
+			[2]string{"TopicArn", m.TopicArn},
+			[2]string{"Type", m.Type},
+		)
+	default:
+		fields = [][2]string{
+			{"Message", m.Message},
+			{"MessageId", m.MessageId},
+			{"SubscribeURL", m.SubscribeURL},
+			{"Timestamp", m.Timestamp},
+			{"Token", m.Token},
+			{"TopicArn", m.TopicArn},
+			{"Type", m.Type},
+		}
+	}
+
+	var b strings.Builder
+	for _, field := range fields {
+		b.WriteString(field[0])
+		b.WriteString("\n")
+		b.WriteString(field[1])
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// signatureVerifier checks SNS message signatures, caching the signing
+// certificates it downloads.
+type signatureVerifier struct {
+	httpClient *http.Client
+
+	// isTrustedCertURL decides whether a SigningCertURL may be fetched.
+	isTrustedCertURL func(*url.URL) bool
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+func newSignatureVerifier(httpClient *http.Client) *signatureVerifier {
+	return &signatureVerifier{
+		httpClient:       httpClient,
+		isTrustedCertURL: isSNSCertURL,
+		certs:            make(map[string]*x509.Certificate),
+	}
+}
+
+// isSNSCertURL only allows certificates served over HTTPS by SNS itself.
+func isSNSCertURL(u *url.URL) bool {
+	return u.Scheme == "https" && certHostPattern.MatchString(u.Hostname())
+}
+
+func (v *signatureVerifier) verify(m *httpMessage) error {
+	var hash crypto.Hash
+	var digest []byte
+
+	switch m.SignatureVersion {
+	case "1":
+		sum := sha1.Sum([]byte(m.stringToSign()))
+		hash, digest = crypto.SHA1, sum[:]
+	case "2":
+		sum := sha256.Sum256([]byte(m.stringToSign()))
+		hash, digest = crypto.SHA256, sum[:]
+	default:
+		return fmt.Errorf("Unsupported signature version: %s", m.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return err
+	}
+
+	cert, err := v.certificate(m.SigningCertURL)
+	if err != nil {
+		return err
+	}
+
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("Signing certificate does not contain an RSA key")
+	}
+
+	return rsa.VerifyPKCS1v15(publicKey, hash, digest, signature)
+}
+
+func (v *signatureVerifier) certificate(certURL string) (*x509.Certificate, error) {
+	v.mu.Lock()
+	cert, ok := v.certs[certURL]
+	v.mu.Unlock()
+	if ok {
+		return cert, nil
+	}
+
+	u, err := url.Parse(certURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !v.isTrustedCertURL(u) {
+		return nil, fmt.Errorf("Untrusted signing certificate URL: %s", certURL)
+	}
+
+	resp, err := v.httpClient.Get(certURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to fetch signing certificate: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(body)
+	if nil == block {
+		return nil, errors.New("Signing certificate is not PEM encoded")
+	}
+
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.Lock()
+	v.certs[certURL] = cert
+	v.mu.Unlock()
+
+	return cert, nil
+}
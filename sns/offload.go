@@ -0,0 +1,87 @@
+package sns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/researchsquare/gomainevents"
+)
+
+// maxMessageSize is the largest message SNS accepts, in bytes.
+const maxMessageSize = 256 * 1024
+
+// S3API is the subset of the S3 client used to offload oversized messages.
+// It is satisfied by *s3.Client from aws-sdk-go-v2.
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// s3Pointer locates an event body that was uploaded to S3 because it was too
+// large to publish. The SQS provider follows it when decoding.
+type s3Pointer struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+}
+
+// MessageTooLargeError is returned when an encoded event exceeds the SNS
+// message size limit and is rejected rather than offloaded or chunked. See
+// Oversize.
+type MessageTooLargeError struct {
+	EventName string
+	Size      int
+}
+
+func (e *MessageTooLargeError) Error() string {
+	return fmt.Sprintf("Event %s is %d bytes, exceeding the %d byte SNS limit", e.EventName, e.Size, maxMessageSize)
+}
+
+// offload uploads the encoded event to S3 and returns the pointer message to
+// publish in its place.
+func (p *Publisher) offload(event gomainevents.Event, encoded string) (string, error) {
+	if nil == p.s3Client {
+		return "", &MessageTooLargeError{EventName: event.Name(), Size: len(encoded)}
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+	key := p.s3KeyPrefix + id + ".json"
+
+	_, err = p.s3Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(p.s3Bucket),
+		Key:         aws.String(key),
+		Body:        strings.NewReader(encoded),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	bytes, err := json.Marshal(&encodedEvent{
+		Name:      event.Name(),
+		S3Pointer: &s3Pointer{Bucket: p.s3Bucket, Key: key},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+// randomID returns a random hex identifier for offloaded objects and chunked
+// events.
+func randomID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
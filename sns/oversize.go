@@ -0,0 +1,103 @@
+package sns
+
+import (
+	"encoding/json"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Oversize is what the publisher does with events too large to publish.
+type Oversize int
+
+const (
+	// DefaultOversize offloads events to S3 when there is an S3Bucket and
+	// rejects them otherwise.
+	DefaultOversize Oversize = iota
+
+	// RejectOversize fails oversized events with a *MessageTooLargeError.
+	RejectOversize
+
+	// OffloadOversize uploads oversized events to S3Bucket and publishes a
+	// pointer to them instead, which the SQS provider follows.
+	OffloadOversize
+
+	// ChunkOversize splits oversized events into several messages, which
+	// the SQS provider puts back together before passing the event on.
+	// Every chunk of an event has to reach the same instance, so the SQS
+	// provider only accepts chunks with broadcast delivery or
+	// sqs.Config.SingleConsumer. Prefer OffloadOversize otherwise.
+	ChunkOversize
+)
+
+func (o Oversize) String() string {
+	switch o {
+	case DefaultOversize:
+		return "default"
+	case RejectOversize:
+		return "reject"
+	case OffloadOversize:
+		return "offload"
+	case ChunkOversize:
+		return "chunk"
+	}
+
+	return "unknown"
+}
+
+// chunk is one part of an encoded event that was split up because it was too
+// large to publish. Data is base64 encoded by encoding/json, which bounds the
+// size of a message regardless of what the event contains.
+type chunk struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+	Count int    `json:"count"`
+	Data  []byte `json:"data"`
+}
+
+// split breaks the encoded event up into messages of at most limit bytes. It
+// returns nil if not even the smallest chunk fits.
+func (p *Publisher) split(event gomainevents.Event, encoded string, limit int) ([]string, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	// Measure the message around the data, allowing for indexes and counts
+	// of any size.
+	envelope, err := json.Marshal(&encodedEvent{Name: event.Name(), Chunk: &chunk{ID: id}})
+	if err != nil {
+		return nil, err
+	}
+
+	size := (limit - len(envelope) - 32) / 4 * 3
+	if size <= 0 {
+		return nil, nil
+	}
+
+	count := (len(encoded) + size - 1) / size
+
+	messages := make([]string, 0, count)
+	for index := 0; index < count; index++ {
+		end := (index + 1) * size
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+
+		bytes, err := json.Marshal(&encodedEvent{
+			Name: event.Name(),
+			Chunk: &chunk{
+				ID:    id,
+				Index: index,
+				Count: count,
+				Data:  []byte(encoded[index*size : end]),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, string(bytes))
+	}
+
+	return messages, nil
+}
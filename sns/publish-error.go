@@ -0,0 +1,119 @@
+package sns
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// PermanentPublishError is returned when SNS rejects a publish for a reason
+// that retrying will not fix, such as a missing topic or denied access.
+type PermanentPublishError struct {
+	EventName string
+
+	// Code is the error code reported by SNS, e.g. NotFound or
+	// AuthorizationError.
+	Code string
+
+	Err error
+}
+
+func (e *PermanentPublishError) Error() string {
+	return fmt.Sprintf("Failed to publish event %s (%s): %s", e.EventName, e.Code, e.Err)
+}
+
+func (e *PermanentPublishError) Unwrap() error {
+	return e.Err
+}
+
+// TopicNotFound reports whether the topic being published to doesn't exist.
+func (e *PermanentPublishError) TopicNotFound() bool {
+	return e.Code == awssnsNotFound
+}
+
+// AccessDenied reports whether the caller isn't allowed to publish to the
+// topic or use its encryption key.
+func (e *PermanentPublishError) AccessDenied() bool {
+	return e.Code == awssnsAuthorizationError || e.Code == awssnsKMSAccessDenied
+}
+
+// RetriesExhaustedError is returned when a publish kept failing with
+// retryable errors until the configured number of attempts ran out.
+type RetriesExhaustedError struct {
+	EventName string
+	Attempts  int
+	Err       error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("Failed to publish event %s after %d attempts: %s", e.EventName, e.Attempts, e.Err)
+}
+
+func (e *RetriesExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// Error codes returned by SNS that we treat specially.
+const (
+	awssnsNotFound           = "NotFound"
+	awssnsAuthorizationError = "AuthorizationError"
+	awssnsKMSAccessDenied    = "KMSAccessDenied"
+)
+
+// retryableCodes are SNS and SDK error codes that indicate a transient
+// problem on the AWS side.
+var retryableCodes = map[string]bool{
+	"Throttled":               true,
+	"Throttling":              true,
+	"ThrottlingException":     true,
+	"KMSThrottling":           true,
+	"InternalError":           true,
+	"InternalFailure":         true,
+	"ServiceUnavailable":      true,
+	"RequestError":            true,
+	"RequestTimeout":          true,
+	"RequestTimeoutException": true,
+	"ConcurrentAccess":        true,
+}
+
+// isRetryableCode reports whether an SNS error code is worth retrying.
+func isRetryableCode(code string) bool {
+	return retryableCodes[code]
+}
+
+// isRetryable reports whether an error returned by the SNS client is
+// transient. Errors that didn't come from AWS (e.g. encoding errors) are
+// never retried.
+func isRetryable(err error) bool {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		status := respErr.HTTPStatusCode()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			return true
+		}
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return isRetryableCode(apiErr.ErrorCode())
+	}
+
+	// Connection resets, timeouts and the like
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// classifyError wraps a non-retryable AWS error in a PermanentPublishError.
+// Other errors are returned unchanged.
+func classifyError(eventName string, err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && !isRetryable(err) {
+		return &PermanentPublishError{EventName: eventName, Code: apiErr.ErrorCode(), Err: err}
+	}
+
+	return err
+}
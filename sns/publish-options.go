@@ -0,0 +1,81 @@
+package sns
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/researchsquare/gomainevents"
+)
+
+// PublishOptions are per-message SNS settings that aren't part of the event
+// itself.
+type PublishOptions struct {
+	// Subject is shown by subscribers that support it, such as email.
+	Subject string
+
+	// MessageAttributes are sent as String message attributes and can be
+	// used by subscription filter policies.
+	MessageAttributes map[string]string
+}
+
+// OptionsMapper derives publish options from an event, e.g. to build a
+// Subject out of the event name or to copy data fields into attributes.
+type OptionsMapper func(gomainevents.Event) *PublishOptions
+
+// merge returns the options with overrides applied on top. Attributes are
+// merged key by key.
+func (o *PublishOptions) merge(overrides *PublishOptions) *PublishOptions {
+	merged := &PublishOptions{MessageAttributes: map[string]string{}}
+
+	for _, options := range []*PublishOptions{o, overrides} {
+		if nil == options {
+			continue
+		}
+
+		if "" != options.Subject {
+			merged.Subject = options.Subject
+		}
+
+		for key, value := range options.MessageAttributes {
+			merged.MessageAttributes[key] = value
+		}
+	}
+
+	return merged
+}
+
+// subject returns the Subject parameter, which must be omitted when empty.
+func (o *PublishOptions) subject() *string {
+	if "" == o.Subject {
+		return nil
+	}
+
+	return aws.String(o.Subject)
+}
+
+// messageAttributes converts the attributes to SNS message attributes.
+func (o *PublishOptions) messageAttributes() map[string]types.MessageAttributeValue {
+	if len(o.MessageAttributes) == 0 {
+		return nil
+	}
+
+	attributes := make(map[string]types.MessageAttributeValue, len(o.MessageAttributes))
+	for key, value := range o.MessageAttributes {
+		attributes[key] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+
+	return attributes
+}
+
+// size returns how much the options add to the size of a message, which
+// SNS counts towards its limit along with the body.
+func (o *PublishOptions) size() int {
+	size := len(o.Subject)
+	for key, value := range o.MessageAttributes {
+		size += len(key) + len("String") + len(value)
+	}
+
+	return size
+}
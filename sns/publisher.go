@@ -1,28 +1,160 @@
 package sns
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	awssns "github.com/aws/aws-sdk-go/service/sns"
-	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	awssns "github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
 	"github.com/researchsquare/gomainevents"
 )
 
+// defaultRegion is used for the default client when no Region is configured.
+const defaultRegion = "us-east-1"
+
+// defaultMaximumRetryCount is the number of times a publish failing with a
+// retryable error is retried when no MaximumRetryCount is configured.
+const defaultMaximumRetryCount = 3
+
+// maxBatchSize is the maximum number of entries SNS accepts in a single
+// PublishBatch call.
+const maxBatchSize = 10
+
+// maxBatchRequestSize is the most SNS accepts for the messages of a single
+// PublishBatch call put together, in bytes.
+const maxBatchRequestSize = 256 * 1024
+
+// messageStructureJSON tells SNS that the message is a JSON object with a
+// separate payload per protocol.
+const messageStructureJSON = "json"
+
+// ProtocolMessagesFunc derives protocol-specific payloads (keyed by protocol,
+// e.g. "sqs", "http", "email") from an event and its standard encoding.
+type ProtocolMessagesFunc func(event gomainevents.Event, encoded string) (map[string]string, error)
+
+// SNSAPI is the subset of the SNS client used by this package. It is
+// satisfied by *sns.Client from aws-sdk-go-v2.
+type SNSAPI interface {
+	Publish(ctx context.Context, params *awssns.PublishInput, optFns ...func(*awssns.Options)) (*awssns.PublishOutput, error)
+	PublishBatch(ctx context.Context, params *awssns.PublishBatchInput, optFns ...func(*awssns.Options)) (*awssns.PublishBatchOutput, error)
+	GetTopicAttributes(ctx context.Context, params *awssns.GetTopicAttributesInput, optFns ...func(*awssns.Options)) (*awssns.GetTopicAttributesOutput, error)
+	CreateTopic(ctx context.Context, params *awssns.CreateTopicInput, optFns ...func(*awssns.Options)) (*awssns.CreateTopicOutput, error)
+}
+
 type Publisher struct {
-	snsClient snsiface.SNSAPI
-	topicARN  string
+	snsClient        SNSAPI
+	topicARN         string
+	protocolMessages ProtocolMessagesFunc
+	optionsMapper    OptionsMapper
+	validators       map[string]Validator
+
+	messageGroupID  IDMapper
+	deduplicationID IDMapper
+
+	s3Client    S3API
+	s3Bucket    string
+	s3KeyPrefix string
+	oversize    Oversize
+
+	maximumRetryCount int
+	retryDelay        func(attempt int) time.Duration
+	clock             gomainevents.Clock
+
+	dryRun bool
 }
 
 type Config struct {
 	// Provide your own SNS client. Default will use the
-	// default AWS session + shared credentials.
-	SNSClient snsiface.SNSAPI
+	// default AWS config + shared credentials, without the SDK's retries
+	// since the publisher retries itself. Clients that are provided should
+	// use aws.NopRetryer too, or every retry is retried again.
+	SNSClient SNSAPI
+
+	// Region used by the default client. Defaults to us-east-1. Ignored
+	// when SNSClient is provided.
+	Region string
+
+	// Endpoint overrides the SNS endpoint used by the default client, e.g.
+	// http://localhost:4566 for LocalStack. Ignored when SNSClient is provided.
+	Endpoint string
 
 	// Specify the Queue URL. Required
 	TopicARN string
+
+	// MessageGroupID returns the message group of each event published to
+	// a FIFO topic, whose name ends in .fifo. Subscribers receive the
+	// events of a group in the order they were published. Required for
+	// FIFO topics, and not allowed for others.
+	MessageGroupID IDMapper
+
+	// DeduplicationID returns the deduplication ID of each event published
+	// to a FIFO topic. SNS drops events published again with the same ID
+	// within five minutes. Defaults to the topic's content based
+	// deduplication, which CreateIfMissing turns on.
+	DeduplicationID IDMapper
+
+	// ProtocolMessages, when set, publishes every event with
+	// MessageStructure=json using the payloads it returns. The "default"
+	// payload falls back to the standard encoding when not supplied.
+	// Events too large to publish with them are handled as Oversize says,
+	// and offloaded or chunked events are published without them.
+	ProtocolMessages ProtocolMessagesFunc
+
+	// OptionsMapper derives the Subject and message attributes for each
+	// published event. Optional.
+	OptionsMapper OptionsMapper
+
+	// Validators check the data of events before they are published, by
+	// event name. Events without a validator are published as-is.
+	Validators map[string]Validator
+
+	// This specifies the maximum number of times a publish failing with a
+	// retryable error (throttling, 5xx) is retried. Defaults to 3.
+	MaximumRetryCount int
+
+	// DisableRetries publishes each message once, leaving retries to the
+	// caller, e.g. a storeforward.Publisher. MaximumRetryCount is ignored.
+	DisableRetries bool
+
+	// VerifyTopic checks that the topic exists when the publisher is
+	// created, so a misconfigured ARN fails at startup.
+	VerifyTopic bool
+
+	// CreateIfMissing creates the topic when it doesn't exist, in the
+	// client's region, which has to match the ARN's. Implies VerifyTopic.
+	CreateIfMissing bool
+
+	// Events too large to publish are uploaded to this bucket and replaced
+	// by a pointer the SQS provider knows how to follow. Without a bucket,
+	// oversized events fail with a *MessageTooLargeError.
+	S3Bucket string
+
+	// What to do with events too large to publish: reject, offload to
+	// S3Bucket or split them into chunks. Defaults to offloading when
+	// there's an S3Bucket and rejecting otherwise.
+	Oversize Oversize
+
+	// Prefix for the keys of offloaded events, e.g. "events/".
+	S3KeyPrefix string
+
+	// Provide your own S3 client for offloading. Defaults to a client for
+	// the configured Region and Endpoint.
+	S3Client S3API
+
+	// DryRun logs the fully encoded request instead of sending it to SNS.
+	// Useful for exercising publish paths in local development.
+	DryRun bool
+
+	// Clock retries are delayed on. Defaults to gomainevents.SystemClock.
+	Clock gomainevents.Clock
 }
 
 func NewPublisher(config *Config) (*Publisher, error) {
@@ -33,39 +165,460 @@ func NewPublisher(config *Config) (*Publisher, error) {
 	// Default to a new client using shared credentials
 	snsClient := config.SNSClient
 	if nil == snsClient {
-		sess := session.Must(session.NewSession())
-		snsClient = awssns.New(sess, &aws.Config{Region: aws.String("us-east-1")})
+		awsConfig, err := loadAWSConfig(config.Region)
+		if err != nil {
+			return nil, err
+		}
+
+		snsClient = awssns.NewFromConfig(awsConfig, func(o *awssns.Options) {
+			if "" != config.Endpoint {
+				o.BaseEndpoint = aws.String(config.Endpoint)
+			}
+
+			// The publisher retries on its own, see MaximumRetryCount.
+			o.Retryer = aws.NopRetryer{}
+		})
+	}
+
+	s3Client := config.S3Client
+	if nil == s3Client && "" != config.S3Bucket {
+		awsConfig, err := loadAWSConfig(config.Region)
+		if err != nil {
+			return nil, err
+		}
+
+		s3Client = s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+			if "" != config.Endpoint {
+				o.BaseEndpoint = aws.String(config.Endpoint)
+				o.UsePathStyle = true
+			}
+		})
 	}
 
 	if "" == config.TopicARN {
 		return nil, errors.New("TopicARN is required")
 	}
 
+	if isFIFO(config.TopicARN) && nil == config.MessageGroupID {
+		return nil, errors.New("MessageGroupID is required for FIFO topics")
+	}
+
+	if !isFIFO(config.TopicARN) && (nil != config.MessageGroupID || nil != config.DeduplicationID) {
+		return nil, errors.New("MessageGroupID and DeduplicationID are only for FIFO topics")
+	}
+
+	oversize := config.Oversize
+	switch oversize {
+	case DefaultOversize:
+		oversize = RejectOversize
+		if "" != config.S3Bucket {
+			oversize = OffloadOversize
+		}
+	case OffloadOversize:
+		if "" == config.S3Bucket {
+			return nil, errors.New("S3Bucket is required to offload oversized events")
+		}
+	case RejectOversize, ChunkOversize:
+	default:
+		return nil, fmt.Errorf("Oversize isn't supported: %s", oversize)
+	}
+
+	topicARN := config.TopicARN
+	if !config.DryRun && (config.VerifyTopic || config.CreateIfMissing) {
+		var err error
+		if topicARN, err = verifyTopic(snsClient, topicARN, config.CreateIfMissing, nil == config.DeduplicationID); err != nil {
+			return nil, err
+		}
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.DisableRetries {
+		maximumRetryCount = 0
+	} else if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	clock := gomainevents.ClockOrDefault(config.Clock)
+
 	return &Publisher{
-		snsClient: snsClient,
-		topicARN:  config.TopicARN,
+		snsClient:         snsClient,
+		topicARN:          topicARN,
+		protocolMessages:  config.ProtocolMessages,
+		optionsMapper:     config.OptionsMapper,
+		validators:        config.Validators,
+		messageGroupID:    config.MessageGroupID,
+		deduplicationID:   config.DeduplicationID,
+		s3Client:          s3Client,
+		s3Bucket:          config.S3Bucket,
+		s3KeyPrefix:       config.S3KeyPrefix,
+		oversize:          oversize,
+		maximumRetryCount: maximumRetryCount,
+		retryDelay:        defaultRetryDelay,
+		clock:             clock,
+		dryRun:            config.DryRun,
 	}, nil
 }
 
+// loadAWSConfig loads the default AWS config for the region, falling back to
+// us-east-1.
+func loadAWSConfig(region string) (aws.Config, error) {
+	if "" == region {
+		region = defaultRegion
+	}
+
+	return awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(region))
+}
+
 func (p *Publisher) Publish(event gomainevents.Event) error {
-	encoded, err := p.encodeEvent(event)
+	return p.PublishWithOptions(event, nil)
+}
+
+// PublishWithOptions publishes an event with a Subject and message
+// attributes. They take precedence over any derived by the OptionsMapper.
+func (p *Publisher) PublishWithOptions(event gomainevents.Event, options *PublishOptions) error {
+	options = p.options(event).merge(options)
+
+	messages, structure, err := p.buildMessage(event, options)
 	if err != nil {
 		return err
 	}
 
-	params := &awssns.PublishInput{
-		TopicArn: aws.String(p.topicARN),
-		Message:  aws.String(encoded),
+	return p.publishMessages(event, messages, structure, options)
+}
+
+// publishMessages publishes the messages an event was encoded as one at a
+// time, retrying each as needed.
+func (p *Publisher) publishMessages(event gomainevents.Event, messages []string, structure *string, options *PublishOptions) error {
+	for i, message := range messages {
+		groupID, deduplicationID := p.fifoIDs(event, i, len(messages))
+
+		params := &awssns.PublishInput{
+			TopicArn:               aws.String(p.topicARN),
+			Message:                aws.String(message),
+			MessageStructure:       structure,
+			Subject:                options.subject(),
+			MessageAttributes:      options.messageAttributes(),
+			MessageGroupId:         groupID,
+			MessageDeduplicationId: deduplicationID,
+		}
+
+		if p.dryRun {
+			p.logDryRun(params)
+			continue
+		}
+
+		if err := p.publish(event, params); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publish sends a message, retrying while SNS fails with retryable errors.
+func (p *Publisher) publish(event gomainevents.Event, params *awssns.PublishInput) error {
+	var lastErr error
+	for attempt := 0; attempt <= p.maximumRetryCount; attempt++ {
+		if attempt > 0 {
+			<-p.clock.After(p.retryDelay(attempt))
+		}
+
+		_, err := p.snsClient.Publish(context.Background(), params)
+		if err == nil {
+			return nil
+		}
+
+		if !isRetryable(err) {
+			return classifyError(event.Name(), err)
+		}
+
+		lastErr = err
+	}
+
+	return &RetriesExhaustedError{EventName: event.Name(), Attempts: p.maximumRetryCount + 1, Err: lastErr}
+}
+
+// PublishBatch pushes events to the topic using the PublishBatch API, sending
+// up to 10 events, and 256 KB of messages, per call. Every request is
+// attempted; if any event fails, a *BatchPublishError listing the failed
+// events is returned.
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	failures := []BatchFailure{}
+
+	request := []batchEntry{}
+	requestSize := 0
+	send := func() {
+		failures = append(failures, p.publishEntries(request)...)
+		request, requestSize = []batchEntry{}, 0
+	}
+
+	for _, event := range events {
+		options := p.options(event)
+
+		messages, structure, err := p.buildMessage(event, options)
+		if err != nil {
+			failures = append(failures, BatchFailure{Event: event, Err: err})
+			continue
+		}
+
+		// Chunks fill a request on their own, so they're published
+		// separately, after the events before them.
+		if len(messages) > 1 {
+			send()
+			if err := p.publishMessages(event, messages, structure, options); err != nil {
+				failures = append(failures, BatchFailure{Event: event, Err: err})
+			}
+			continue
+		}
+
+		size := len(messages[0]) + options.size()
+		if len(request) == maxBatchSize || requestSize+size > maxBatchRequestSize {
+			send()
+		}
+
+		groupID, deduplicationID := p.fifoIDs(event, 0, 1)
+		request = append(request, batchEntry{
+			event: event,
+			entry: types.PublishBatchRequestEntry{
+				Message:                aws.String(messages[0]),
+				MessageStructure:       structure,
+				Subject:                options.subject(),
+				MessageAttributes:      options.messageAttributes(),
+				MessageGroupId:         groupID,
+				MessageDeduplicationId: deduplicationID,
+			},
+		})
+		requestSize += size
+	}
+
+	send()
+
+	if len(failures) > 0 {
+		return &BatchPublishError{Failures: failures}
+	}
+
+	return nil
+}
+
+// batchEntry is an event ready to be sent in a PublishBatch request.
+type batchEntry struct {
+	event gomainevents.Event
+	entry types.PublishBatchRequestEntry
+}
+
+// publishEntries sends a PublishBatch request, returning the entries that
+// failed.
+func (p *Publisher) publishEntries(request []batchEntry) []BatchFailure {
+	failures := []BatchFailure{}
+	entries := make([]types.PublishBatchRequestEntry, 0, len(request))
+
+	// Entry IDs only need to be unique within a request, so the index of
+	// the event within the request is enough to map results back.
+	byID := make(map[string]gomainevents.Event, len(request))
+	for i, batched := range request {
+		id := strconv.Itoa(i)
+		byID[id] = batched.event
+
+		entry := batched.entry
+		entry.Id = aws.String(id)
+		entries = append(entries, entry)
+	}
+
+	// Failed entries are retried on their own as long as SNS says the
+	// failure was on its side.
+	pending := entries
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			<-p.clock.After(p.retryDelay(attempt))
+		}
+
+		exhausted := attempt >= p.maximumRetryCount
+		retry := []types.PublishBatchRequestEntry{}
+
+		params := &awssns.PublishBatchInput{
+			TopicArn:                   aws.String(p.topicARN),
+			PublishBatchRequestEntries: pending,
+		}
+
+		if p.dryRun {
+			p.logDryRun(params)
+			return failures
+		}
+
+		resp, err := p.snsClient.PublishBatch(context.Background(), params)
+		if err != nil {
+			if isRetryable(err) && !exhausted {
+				continue
+			}
+
+			for _, entry := range pending {
+				event := byID[*entry.Id]
+				failures = append(failures, BatchFailure{Event: event, Err: p.finalError(event.Name(), err, attempt)})
+			}
+
+			return failures
+		}
+
+		byEntryID := make(map[string]types.PublishBatchRequestEntry, len(pending))
+		for _, entry := range pending {
+			byEntryID[*entry.Id] = entry
+		}
+
+		for _, failed := range resp.Failed {
+			id := aws.ToString(failed.Id)
+			code := aws.ToString(failed.Code)
+			senderFault := failed.SenderFault
+
+			if !senderFault && isRetryableCode(code) && !exhausted {
+				retry = append(retry, byEntryID[id])
+				continue
+			}
+
+			failures = append(failures, BatchFailure{
+				Event:       byID[id],
+				Code:        code,
+				Message:     aws.ToString(failed.Message),
+				SenderFault: senderFault,
+			})
+		}
+
+		pending = retry
+	}
+
+	return failures
+}
+
+// finalError converts an error that won't be retried any further into the
+// error reported to the caller.
+func (p *Publisher) finalError(eventName string, err error, attempt int) error {
+	if isRetryable(err) {
+		return &RetriesExhaustedError{EventName: eventName, Attempts: attempt + 1, Err: err}
+	}
+
+	return classifyError(eventName, err)
+}
+
+// logDryRun prints the request that would have been sent to SNS.
+func (p *Publisher) logDryRun(params interface{}) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		log.Printf("[gomainevents-sns] Dry run: unable to encode request: %s\n", err)
+		return
+	}
+
+	log.Printf("[gomainevents-sns] Dry run: %s\n", encoded)
+}
+
+// defaultRetryDelay backs off exponentially from 100ms, up to 5 seconds.
+func defaultRetryDelay(attempt int) time.Duration {
+	delay := 100 * time.Millisecond << uint(attempt-1)
+	if delay > 5*time.Second || delay <= 0 {
+		return 5 * time.Second
 	}
 
-	_, err = p.snsClient.Publish(params)
+	return delay
+}
+
+// options returns the publish options derived from the event, if any.
+func (p *Publisher) options(event gomainevents.Event) *PublishOptions {
+	if nil == p.optionsMapper {
+		return &PublishOptions{}
+	}
 
-	return err
+	return (&PublishOptions{}).merge(p.optionsMapper(event))
+}
+
+// buildMessage returns the SNS message bodies for an event along with the
+// MessageStructure to publish them with, which is nil for plain messages.
+// There is one body unless the event was split into chunks. SNS counts the
+// Subject and message attributes towards the size of a message, so bodies
+// leave room for the options they're published with.
+func (p *Publisher) buildMessage(event gomainevents.Event, options *PublishOptions) ([]string, *string, error) {
+	if validate, ok := p.validators[event.Name()]; ok {
+		if err := validate(event.Data()); err != nil {
+			return nil, nil, &ValidationError{EventName: event.Name(), Err: err}
+		}
+	}
+
+	encoded, err := p.encodeEvent(event)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limit := maxMessageSize - options.size()
+	if len(encoded) > limit {
+		return p.oversized(event, encoded, len(encoded)+options.size(), limit)
+	}
+
+	if nil == p.protocolMessages {
+		return []string{encoded}, nil, nil
+	}
+
+	messages, err := p.protocolMessages(event, encoded)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// SNS rejects json structured messages without a default payload.
+	payloads := map[string]string{"default": encoded}
+	for protocol, message := range messages {
+		payloads[protocol] = message
+	}
+
+	bytes, err := json.Marshal(payloads)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(bytes) > limit {
+		return p.oversized(event, encoded, len(bytes)+options.size(), limit)
+	}
+
+	return []string{string(bytes)}, aws.String(messageStructureJSON), nil
+}
+
+// oversized handles an event whose message, of the given size, is too large
+// to publish, leaving each message limit bytes for its body. Offloaded and
+// chunked events are published without the protocol messages, since only
+// the SQS provider can follow pointers and put chunks back together.
+func (p *Publisher) oversized(event gomainevents.Event, encoded string, size int, limit int) ([]string, *string, error) {
+	switch p.oversize {
+	case OffloadOversize:
+		pointer, err := p.offload(event, encoded)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(pointer) > limit {
+			return nil, nil, &MessageTooLargeError{EventName: event.Name(), Size: size}
+		}
+
+		return []string{pointer}, nil, nil
+	case ChunkOversize:
+		chunks, err := p.split(event, encoded, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if nil == chunks {
+			return nil, nil, &MessageTooLargeError{EventName: event.Name(), Size: size}
+		}
+
+		return chunks, nil, nil
+	}
+
+	return nil, nil, &MessageTooLargeError{EventName: event.Name(), Size: size}
 }
 
 type encodedEvent struct {
 	Name string                 `json:"name"`
 	Data map[string]interface{} `json:"data"`
+
+	// Set instead of Data when the event was offloaded to S3.
+	S3Pointer *s3Pointer `json:"s3Pointer,omitempty"`
+
+	// Set instead of Data when the event was split into chunks.
+	Chunk *chunk `json:"chunk,omitempty"`
 }
 
 func (p *Publisher) encodeEvent(event gomainevents.Event) (string, error) {
@@ -0,0 +1,694 @@
+package sns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	awssns "github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/gomaineventstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSNS struct {
+	batches      []*awssns.PublishBatchInput
+	failIDs      map[string]bool
+	requestError error
+
+	publishErrors []error
+	published     []*awssns.PublishInput
+
+	topicExists  bool
+	createdTopic *awssns.CreateTopicInput
+}
+
+func (m *mockSNS) GetTopicAttributes(ctx context.Context, in *awssns.GetTopicAttributesInput, optFns ...func(*awssns.Options)) (*awssns.GetTopicAttributesOutput, error) {
+	if !m.topicExists {
+		return nil, &types.NotFoundException{Message: aws.String("Topic does not exist")}
+	}
+
+	return &awssns.GetTopicAttributesOutput{}, nil
+}
+
+func (m *mockSNS) CreateTopic(ctx context.Context, in *awssns.CreateTopicInput, optFns ...func(*awssns.Options)) (*awssns.CreateTopicOutput, error) {
+	m.createdTopic = in
+	return &awssns.CreateTopicOutput{
+		TopicArn: aws.String("arn:aws:sns:us-east-1:1234:" + *in.Name),
+	}, nil
+}
+
+func (m *mockSNS) Publish(ctx context.Context, in *awssns.PublishInput, optFns ...func(*awssns.Options)) (*awssns.PublishOutput, error) {
+	m.published = append(m.published, in)
+	if len(m.publishErrors) > 0 {
+		err := m.publishErrors[0]
+		m.publishErrors = m.publishErrors[1:]
+		return nil, err
+	}
+
+	return &awssns.PublishOutput{MessageId: aws.String("1")}, nil
+}
+
+func (m *mockSNS) PublishBatch(ctx context.Context, in *awssns.PublishBatchInput, optFns ...func(*awssns.Options)) (*awssns.PublishBatchOutput, error) {
+	m.batches = append(m.batches, in)
+	if m.requestError != nil {
+		return nil, m.requestError
+	}
+
+	out := &awssns.PublishBatchOutput{}
+	for _, entry := range in.PublishBatchRequestEntries {
+		if m.failIDs[*entry.Id] {
+			out.Failed = append(out.Failed, types.BatchResultErrorEntry{
+				Id:          entry.Id,
+				Code:        aws.String("InternalError"),
+				Message:     aws.String("Oops"),
+				SenderFault: false,
+			})
+			continue
+		}
+
+		out.Successful = append(out.Successful, types.PublishBatchResultEntry{Id: entry.Id})
+	}
+
+	return out, nil
+}
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func makeEvents(n int) []gomainevents.Event {
+	events := []gomainevents.Event{}
+	for i := 0; i < n; i++ {
+		events = append(events, testEvent{name: "Event" + strconv.Itoa(i)})
+	}
+
+	return events
+}
+
+func TestNewPublisher(t *testing.T) {
+	// Success case - default client with a custom region and endpoint
+	publisher, err := NewPublisher(&Config{
+		TopicARN: "arn",
+		Region:   "eu-west-1",
+		Endpoint: "http://localhost:4566",
+	})
+	require.Nil(t, err)
+
+	options := publisher.snsClient.(*awssns.Client).Options()
+	assert.Equal(t, "eu-west-1", options.Region)
+	assert.Equal(t, "http://localhost:4566", *options.BaseEndpoint)
+
+	// Retries are left to the publisher
+	assert.Equal(t, 1, options.Retryer.MaxAttempts())
+
+	// Failure case - no topic provided
+	publisher, err = NewPublisher(&Config{})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestNewPublisherVerifyTopic(t *testing.T) {
+	arn := "arn:aws:sns:us-east-1:1234:events.fifo"
+
+	// Existing topic
+	publisher, err := NewPublisher(&Config{
+		SNSClient:      &mockSNS{topicExists: true},
+		TopicARN:       arn,
+		MessageGroupID: orderID,
+		VerifyTopic:    true,
+	})
+	require.Nil(t, err)
+	assert.Equal(t, arn, publisher.topicARN)
+
+	// Missing topic
+	publisher, err = NewPublisher(&Config{
+		SNSClient:      &mockSNS{},
+		TopicARN:       arn,
+		MessageGroupID: orderID,
+		VerifyTopic:    true,
+	})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	// Missing topic gets created
+	mockClient := &mockSNS{}
+	publisher, err = NewPublisher(&Config{
+		SNSClient:       mockClient,
+		TopicARN:        "arn:aws:sns:us-east-1:1234:events",
+		CreateIfMissing: true,
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "arn:aws:sns:us-east-1:1234:events", publisher.topicARN)
+	assert.Equal(t, "events", *mockClient.createdTopic.Name)
+
+	// FIFO topics deduplicate by content unless there are deduplication IDs
+	mockClient = &mockSNS{}
+	publisher, err = NewPublisher(&Config{
+		SNSClient:       mockClient,
+		TopicARN:        arn,
+		MessageGroupID:  orderID,
+		CreateIfMissing: true,
+	})
+	require.Nil(t, err)
+	assert.Equal(t, arn, publisher.topicARN)
+	assert.Equal(t, "events.fifo", *mockClient.createdTopic.Name)
+	assert.Equal(t, map[string]string{"FifoTopic": "true", "ContentBasedDeduplication": "true"}, mockClient.createdTopic.Attributes)
+
+	mockClient = &mockSNS{}
+	_, err = NewPublisher(&Config{
+		SNSClient:       mockClient,
+		TopicARN:        arn,
+		MessageGroupID:  orderID,
+		DeduplicationID: eventID,
+		CreateIfMissing: true,
+	})
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"FifoTopic": "true"}, mockClient.createdTopic.Attributes)
+}
+
+func TestCreateTopicChecksRegion(t *testing.T) {
+	client := awssns.New(awssns.Options{Region: "eu-west-1"})
+
+	_, err := createTopic(client, "arn:aws:sns:us-east-1:1234:events", false)
+	assert.EqualError(t, err, "Unable to create topic events: its region us-east-1 isn't the client's region eu-west-1")
+}
+
+func orderID(event gomainevents.Event) string {
+	return "order-1234"
+}
+
+func eventID(event gomainevents.Event) string {
+	return event.Name() + "-1"
+}
+
+func TestNewPublisherFIFO(t *testing.T) {
+	_, err := NewPublisher(&Config{SNSClient: &mockSNS{}, TopicARN: "arn:aws:sns:us-east-1:1234:events.fifo"})
+	assert.EqualError(t, err, "MessageGroupID is required for FIFO topics")
+
+	_, err = NewPublisher(&Config{SNSClient: &mockSNS{}, TopicARN: "arn:aws:sns:us-east-1:1234:events", MessageGroupID: orderID})
+	assert.EqualError(t, err, "MessageGroupID and DeduplicationID are only for FIFO topics")
+}
+
+func TestPublishFIFO(t *testing.T) {
+	mockClient := &mockSNS{}
+	publisher, err := NewPublisher(&Config{
+		SNSClient:       mockClient,
+		TopicARN:        "arn:aws:sns:us-east-1:1234:events.fifo",
+		MessageGroupID:  orderID,
+		DeduplicationID: eventID,
+		Oversize:        ChunkOversize,
+	})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Placed"}))
+	require.Len(t, mockClient.published, 1)
+	assert.Equal(t, "order-1234", *mockClient.published[0].MessageGroupId)
+	assert.Equal(t, "Placed-1", *mockClient.published[0].MessageDeduplicationId)
+
+	require.Nil(t, publisher.PublishBatch(makeEvents(2)))
+	require.Len(t, mockClient.batches, 1)
+	for i, entry := range mockClient.batches[0].PublishBatchRequestEntries {
+		assert.Equal(t, "order-1234", *entry.MessageGroupId)
+		assert.Equal(t, "Event"+strconv.Itoa(i)+"-1", *entry.MessageDeduplicationId)
+	}
+
+	// Each chunk of an event is deduplicated on its own
+	require.Nil(t, publisher.Publish(largeEvent{}))
+	require.Len(t, mockClient.published, 3)
+	assert.Equal(t, "Large-1-0", *mockClient.published[1].MessageDeduplicationId)
+	assert.Equal(t, "Large-1-1", *mockClient.published[2].MessageDeduplicationId)
+}
+
+func TestPublishBatchChunks(t *testing.T) {
+	mockClient := &mockSNS{}
+	publisher, err := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn"})
+	require.Nil(t, err)
+
+	err = publisher.PublishBatch(makeEvents(23))
+
+	assert.Nil(t, err)
+	require.Len(t, mockClient.batches, 3)
+	assert.Len(t, mockClient.batches[0].PublishBatchRequestEntries, 10)
+	assert.Len(t, mockClient.batches[1].PublishBatchRequestEntries, 10)
+	assert.Len(t, mockClient.batches[2].PublishBatchRequestEntries, 3)
+	assert.Equal(t, "arn", *mockClient.batches[0].TopicArn)
+}
+
+// sizedEvent has a blob of the given size.
+type sizedEvent struct {
+	size int
+}
+
+func (e sizedEvent) Name() string {
+	return "Sized"
+}
+
+func (e sizedEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"blob": strings.Repeat("x", e.size)}
+}
+
+func TestPublishBatchSplitsBySize(t *testing.T) {
+	mockClient := &mockSNS{}
+	publisher, err := NewPublisher(&Config{
+		SNSClient: mockClient,
+		TopicARN:  "arn",
+		OptionsMapper: func(gomainevents.Event) *PublishOptions {
+			return &PublishOptions{Subject: "Sized", MessageAttributes: map[string]string{"source": "test"}}
+		},
+	})
+	require.Nil(t, err)
+
+	events := []gomainevents.Event{}
+	for i := 0; i < 10; i++ {
+		events = append(events, sizedEvent{size: 30 * 1024})
+	}
+	require.Nil(t, publisher.PublishBatch(events))
+
+	// Eight fit in the first request, and the rest go in another
+	require.Len(t, mockClient.batches, 2)
+	assert.Len(t, mockClient.batches[0].PublishBatchRequestEntries, 8)
+	assert.Len(t, mockClient.batches[1].PublishBatchRequestEntries, 2)
+
+	for _, batch := range mockClient.batches {
+		size := 0
+		for _, entry := range batch.PublishBatchRequestEntries {
+			size += len(*entry.Message) + len(*entry.Subject)
+		}
+		assert.LessOrEqual(t, size, maxBatchRequestSize)
+	}
+}
+
+func TestPublishBatchEntryFailures(t *testing.T) {
+	mockClient := &mockSNS{failIDs: map[string]bool{"1": true}}
+	publisher, _ := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn"})
+	publisher.retryDelay = noDelay
+
+	err := publisher.PublishBatch(makeEvents(12))
+
+	batchErr, ok := err.(*BatchPublishError)
+	require.True(t, ok)
+	require.Len(t, batchErr.Failures, 2)
+	assert.Equal(t, "Event1", batchErr.Failures[0].Event.Name())
+	assert.Equal(t, "Event11", batchErr.Failures[1].Event.Name())
+	assert.Equal(t, "InternalError", batchErr.Failures[0].Code)
+
+	// Two chunks, each retried three times with only the failed entry
+	require.Len(t, mockClient.batches, 8)
+	assert.Len(t, mockClient.batches[1].PublishBatchRequestEntries, 1)
+}
+
+func TestPublishBatchRetriesWaitOnClock(t *testing.T) {
+	clock := gomaineventstest.NewClock(time.Now())
+	mockClient := &mockSNS{failIDs: map[string]bool{"1": true}}
+	publisher, _ := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn", Clock: clock})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- publisher.PublishBatch(makeEvents(2))
+	}()
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		assert.Eventually(t, func() bool { return clock.Pending() == 1 }, 5*time.Second, time.Millisecond)
+		assert.Len(t, mockClient.batches, attempt)
+
+		clock.Advance(time.Minute)
+	}
+
+	assert.IsType(t, &BatchPublishError{}, <-done)
+	assert.Len(t, mockClient.batches, 4)
+}
+
+func TestPublishBatchRequestFailure(t *testing.T) {
+	mockClient := &mockSNS{requestError: errors.New("boom")}
+	publisher, _ := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn"})
+
+	err := publisher.PublishBatch(makeEvents(3))
+
+	batchErr, ok := err.(*BatchPublishError)
+	require.True(t, ok)
+	assert.Len(t, batchErr.Failures, 3)
+	assert.EqualError(t, batchErr.Failures[0].Err, "boom")
+}
+
+func TestBuildMessageWithProtocolMessages(t *testing.T) {
+	publisher, _ := NewPublisher(&Config{
+		SNSClient: &mockSNS{},
+		TopicARN:  "arn",
+		ProtocolMessages: func(event gomainevents.Event, encoded string) (map[string]string, error) {
+			return map[string]string{"http": event.Name()}, nil
+		},
+	})
+
+	messages, structure, err := publisher.buildMessage(testEvent{name: "Thing"}, &PublishOptions{})
+
+	require.Nil(t, err)
+	assert.Equal(t, "json", *structure)
+	require.Len(t, messages, 1)
+	assert.JSONEq(
+		t,
+		`{"default":"{\"name\":\"Thing\",\"data\":{\"occurredOn\":\"2018-03-08 11:11:11\"}}","http":"Thing"}`,
+		messages[0],
+	)
+
+	// Plain publishers don't set a structure
+	publisher, _ = NewPublisher(&Config{SNSClient: &mockSNS{}, TopicARN: "arn"})
+	_, structure, err = publisher.buildMessage(testEvent{name: "Thing"}, &PublishOptions{})
+	require.Nil(t, err)
+	assert.Nil(t, structure)
+}
+
+func noDelay(int) time.Duration {
+	return 0
+}
+
+func TestPublishRetriesTransientErrors(t *testing.T) {
+	mockClient := &mockSNS{publishErrors: []error{
+		&types.ThrottledException{Message: aws.String("Slow down")},
+		&types.InternalErrorException{Message: aws.String("Oops")},
+	}}
+	publisher, _ := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn"})
+	publisher.retryDelay = noDelay
+
+	err := publisher.Publish(testEvent{name: "Thing"})
+
+	assert.Nil(t, err)
+	assert.Len(t, mockClient.published, 3)
+}
+
+func TestPublishGivesUpAfterMaximumRetries(t *testing.T) {
+	throttled := &types.ThrottledException{Message: aws.String("Slow down")}
+	mockClient := &mockSNS{publishErrors: []error{throttled, throttled, throttled}}
+	publisher, _ := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn", MaximumRetryCount: 2})
+	publisher.retryDelay = noDelay
+
+	err := publisher.Publish(testEvent{name: "Thing"})
+
+	exhausted, ok := err.(*RetriesExhaustedError)
+	require.True(t, ok)
+	assert.Equal(t, 3, exhausted.Attempts)
+	assert.Len(t, mockClient.published, 3)
+}
+
+func TestPublishWithRetriesDisabled(t *testing.T) {
+	throttled := &types.ThrottledException{Message: aws.String("Slow down")}
+	mockClient := &mockSNS{publishErrors: []error{throttled}}
+	publisher, _ := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn", MaximumRetryCount: 2, DisableRetries: true})
+
+	err := publisher.Publish(testEvent{name: "Thing"})
+
+	exhausted, ok := err.(*RetriesExhaustedError)
+	require.True(t, ok)
+	assert.Equal(t, 1, exhausted.Attempts)
+	assert.Len(t, mockClient.published, 1)
+
+	mockClient.failIDs = map[string]bool{"0": true}
+	assert.IsType(t, &BatchPublishError{}, publisher.PublishBatch(makeEvents(2)))
+	assert.Len(t, mockClient.batches, 1)
+}
+
+func TestPublishPermanentErrors(t *testing.T) {
+	mockClient := &mockSNS{publishErrors: []error{
+		&types.NotFoundException{Message: aws.String("Topic does not exist")},
+	}}
+	publisher, _ := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn"})
+	publisher.retryDelay = noDelay
+
+	err := publisher.Publish(testEvent{name: "Thing"})
+
+	permanent, ok := err.(*PermanentPublishError)
+	require.True(t, ok)
+	assert.True(t, permanent.TopicNotFound())
+	assert.False(t, permanent.AccessDenied())
+	assert.Len(t, mockClient.published, 1)
+}
+
+func TestPublishWithOptions(t *testing.T) {
+	mockClient := &mockSNS{}
+	publisher, _ := NewPublisher(&Config{
+		SNSClient: mockClient,
+		TopicARN:  "arn",
+		OptionsMapper: func(event gomainevents.Event) *PublishOptions {
+			return &PublishOptions{
+				Subject:           event.Name(),
+				MessageAttributes: map[string]string{"eventName": event.Name(), "source": "mapper"},
+			}
+		},
+	})
+
+	err := publisher.PublishWithOptions(testEvent{name: "Thing"}, &PublishOptions{
+		MessageAttributes: map[string]string{"source": "caller"},
+	})
+
+	require.Nil(t, err)
+	require.Len(t, mockClient.published, 1)
+	params := mockClient.published[0]
+	assert.Equal(t, "Thing", *params.Subject)
+	assert.Equal(t, "Thing", *params.MessageAttributes["eventName"].StringValue)
+	assert.Equal(t, "caller", *params.MessageAttributes["source"].StringValue)
+	assert.Equal(t, "String", *params.MessageAttributes["source"].DataType)
+
+	// Without options nothing extra is sent
+	publisher, _ = NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn"})
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+	assert.Nil(t, mockClient.published[1].Subject)
+	assert.Nil(t, mockClient.published[1].MessageAttributes)
+}
+
+func TestPublishDryRun(t *testing.T) {
+	mockClient := &mockSNS{}
+	publisher, err := NewPublisher(&Config{
+		SNSClient:   mockClient,
+		TopicARN:    "arn",
+		VerifyTopic: true,
+		DryRun:      true,
+	})
+	require.Nil(t, err)
+
+	assert.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+	assert.Nil(t, publisher.PublishBatch(makeEvents(3)))
+	assert.Empty(t, mockClient.published)
+	assert.Empty(t, mockClient.batches)
+}
+
+func TestPublishValidation(t *testing.T) {
+	mockClient := &mockSNS{}
+	publisher, _ := NewPublisher(&Config{
+		SNSClient: mockClient,
+		TopicARN:  "arn",
+		Validators: map[string]Validator{
+			"Valid":   RequireFields("occurredOn"),
+			"Invalid": RequireFields("occurredOn", "userId"),
+		},
+	})
+
+	assert.Nil(t, publisher.Publish(testEvent{name: "Valid"}))
+	assert.Nil(t, publisher.Publish(testEvent{name: "Unvalidated"}))
+
+	err := publisher.Publish(testEvent{name: "Invalid"})
+	validationErr, ok := err.(*ValidationError)
+	require.True(t, ok)
+	assert.Equal(t, "Invalid", validationErr.EventName)
+	assert.Len(t, mockClient.published, 2)
+
+	// Invalid events in a batch are reported without being sent
+	err = publisher.PublishBatch([]gomainevents.Event{testEvent{name: "Valid"}, testEvent{name: "Invalid"}})
+	batchErr, ok := err.(*BatchPublishError)
+	require.True(t, ok)
+	require.Len(t, batchErr.Failures, 1)
+	assert.IsType(t, &ValidationError{}, batchErr.Failures[0].Err)
+	assert.Len(t, mockClient.batches[0].PublishBatchRequestEntries, 1)
+}
+
+type mockS3 struct {
+	objects map[string]string
+}
+
+func (m *mockS3) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, _ := io.ReadAll(in.Body)
+	m.objects[*in.Bucket+"/"+*in.Key] = string(body)
+
+	return &s3.PutObjectOutput{}, nil
+}
+
+type largeEvent struct{}
+
+func (e largeEvent) Name() string {
+	return "Large"
+}
+
+func (e largeEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"blob": strings.Repeat("x", maxMessageSize)}
+}
+
+func TestPublishOffloadsLargeEvents(t *testing.T) {
+	mockClient := &mockSNS{}
+	mockStorage := &mockS3{objects: map[string]string{}}
+	publisher, _ := NewPublisher(&Config{
+		SNSClient:   mockClient,
+		TopicARN:    "arn",
+		S3Client:    mockStorage,
+		S3Bucket:    "bucket",
+		S3KeyPrefix: "events/",
+	})
+
+	require.Nil(t, publisher.Publish(largeEvent{}))
+	require.Len(t, mockStorage.objects, 1)
+
+	pointer := &encodedEvent{}
+	require.Nil(t, json.Unmarshal([]byte(*mockClient.published[0].Message), pointer))
+	assert.Equal(t, "Large", pointer.Name)
+	assert.Equal(t, "bucket", pointer.S3Pointer.Bucket)
+	assert.True(t, strings.HasPrefix(pointer.S3Pointer.Key, "events/"))
+
+	stored := &encodedEvent{}
+	require.Nil(t, json.Unmarshal([]byte(mockStorage.objects["bucket/"+pointer.S3Pointer.Key]), stored))
+	assert.Equal(t, "Large", stored.Name)
+	assert.Len(t, stored.Data["blob"], maxMessageSize)
+}
+
+func TestPublishRejectsLargeEventsWithoutBucket(t *testing.T) {
+	mockClient := &mockSNS{}
+	publisher, _ := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn"})
+
+	err := publisher.Publish(largeEvent{})
+
+	assert.IsType(t, &MessageTooLargeError{}, err)
+	assert.Empty(t, mockClient.published)
+}
+
+func TestNewPublisherOversize(t *testing.T) {
+	publisher, err := NewPublisher(&Config{SNSClient: &mockSNS{}, TopicARN: "arn", S3Client: &mockS3{}, S3Bucket: "bucket"})
+	require.Nil(t, err)
+	assert.Equal(t, OffloadOversize, publisher.oversize)
+
+	publisher, err = NewPublisher(&Config{SNSClient: &mockSNS{}, TopicARN: "arn"})
+	require.Nil(t, err)
+	assert.Equal(t, RejectOversize, publisher.oversize)
+
+	publisher, err = NewPublisher(&Config{SNSClient: &mockSNS{}, TopicARN: "arn", Oversize: OffloadOversize})
+	assert.Nil(t, publisher)
+	assert.EqualError(t, err, "S3Bucket is required to offload oversized events")
+
+	publisher, err = NewPublisher(&Config{SNSClient: &mockSNS{}, TopicARN: "arn", Oversize: Oversize(10)})
+	assert.Nil(t, publisher)
+	assert.EqualError(t, err, "Oversize isn't supported: unknown")
+}
+
+func TestPublishRejectsLargeEventsWithBucket(t *testing.T) {
+	mockClient := &mockSNS{}
+	mockStorage := &mockS3{objects: map[string]string{}}
+	publisher, _ := NewPublisher(&Config{
+		SNSClient: mockClient,
+		TopicARN:  "arn",
+		S3Client:  mockStorage,
+		S3Bucket:  "bucket",
+		Oversize:  RejectOversize,
+	})
+
+	err := publisher.Publish(largeEvent{})
+
+	assert.IsType(t, &MessageTooLargeError{}, err)
+	assert.Empty(t, mockStorage.objects)
+	assert.Empty(t, mockClient.published)
+}
+
+func TestPublishChunksLargeEvents(t *testing.T) {
+	mockClient := &mockSNS{}
+	publisher, _ := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn", Oversize: ChunkOversize})
+
+	require.Nil(t, publisher.PublishBatch([]gomainevents.Event{largeEvent{}, testEvent{name: "Small"}}))
+	require.Len(t, mockClient.batches, 1)
+	assert.Len(t, mockClient.batches[0].PublishBatchRequestEntries, 1)
+
+	// The base64 encoding takes two chunks to fit the event
+	require.Len(t, mockClient.published, 2)
+
+	encoded := []byte{}
+	ids := map[string]bool{}
+	for i, published := range mockClient.published {
+		assert.LessOrEqual(t, len(*published.Message), maxMessageSize)
+
+		part := &encodedEvent{}
+		require.Nil(t, json.Unmarshal([]byte(*published.Message), part))
+		assert.Equal(t, "Large", part.Name)
+		assert.Nil(t, part.Data)
+		assert.Equal(t, i, part.Chunk.Index)
+		assert.Equal(t, 2, part.Chunk.Count)
+		ids[part.Chunk.ID] = true
+
+		encoded = append(encoded, part.Chunk.Data...)
+	}
+	assert.Len(t, ids, 1)
+
+	event := &encodedEvent{}
+	require.Nil(t, json.Unmarshal(encoded, event))
+	assert.Equal(t, "Large", event.Name)
+	assert.Len(t, event.Data["blob"], maxMessageSize)
+}
+
+func TestPublishCountsOptionsTowardsTheSizeLimit(t *testing.T) {
+	options := &PublishOptions{Subject: "Sized", MessageAttributes: map[string]string{"note": strings.Repeat("n", 2000)}}
+	event := sizedEvent{size: maxMessageSize - 1000}
+
+	// The body fits on its own, but not with the attributes
+	mockClient := &mockSNS{}
+	publisher, _ := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn"})
+	assert.IsType(t, &MessageTooLargeError{}, publisher.PublishWithOptions(event, options))
+	assert.Empty(t, mockClient.published)
+
+	mockClient = &mockSNS{}
+	publisher, _ = NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn", Oversize: ChunkOversize})
+	require.Nil(t, publisher.PublishWithOptions(event, options))
+	require.Len(t, mockClient.published, 2)
+	for _, published := range mockClient.published {
+		assert.LessOrEqual(t, len(*published.Message)+options.size(), maxMessageSize)
+	}
+}
+
+func TestPublishHandlesOversizedProtocolMessages(t *testing.T) {
+	// Small enough on its own, but not once every protocol gets a copy
+	event := sizedEvent{size: maxMessageSize / 2}
+	protocolMessages := func(event gomainevents.Event, encoded string) (map[string]string, error) {
+		return map[string]string{"http": encoded}, nil
+	}
+
+	mockClient := &mockSNS{}
+	publisher, _ := NewPublisher(&Config{SNSClient: mockClient, TopicARN: "arn", ProtocolMessages: protocolMessages})
+	assert.IsType(t, &MessageTooLargeError{}, publisher.Publish(event))
+
+	mockClient = &mockSNS{}
+	mockStorage := &mockS3{objects: map[string]string{}}
+	publisher, _ = NewPublisher(&Config{
+		SNSClient:        mockClient,
+		TopicARN:         "arn",
+		ProtocolMessages: protocolMessages,
+		S3Client:         mockStorage,
+		S3Bucket:         "bucket",
+	})
+	require.Nil(t, publisher.Publish(event))
+	require.Len(t, mockStorage.objects, 1)
+	require.Len(t, mockClient.published, 1)
+	assert.Nil(t, mockClient.published[0].MessageStructure)
+
+	pointer := &encodedEvent{}
+	require.Nil(t, json.Unmarshal([]byte(*mockClient.published[0].Message), pointer))
+	assert.Equal(t, "bucket", pointer.S3Pointer.Bucket)
+}
@@ -0,0 +1,90 @@
+package sns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awssns "github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/smithy-go"
+)
+
+// verifyTopic checks that the topic exists, optionally creating it when it
+// doesn't. It returns the ARN that should be published to.
+func verifyTopic(snsClient SNSAPI, topicARN string, createIfMissing bool, contentBasedDeduplication bool) (string, error) {
+	_, err := snsClient.GetTopicAttributes(context.Background(), &awssns.GetTopicAttributesInput{
+		TopicArn: aws.String(topicARN),
+	})
+	if err == nil {
+		return topicARN, nil
+	}
+
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != awssnsNotFound {
+		return "", fmt.Errorf("Unable to verify topic %s: %w", topicARN, err)
+	}
+
+	if !createIfMissing {
+		return "", fmt.Errorf("Topic %s does not exist", topicARN)
+	}
+
+	return createTopic(snsClient, topicARN, contentBasedDeduplication)
+}
+
+// createTopic creates the topic named by the last segment of the ARN, in the
+// client's region, which has to be the one in the ARN. Topics ending in .fifo
+// are created as FIFO topics, deduplicating by content if asked to.
+func createTopic(snsClient SNSAPI, topicARN string, contentBasedDeduplication bool) (string, error) {
+	name := topicName(topicARN)
+	if "" == name {
+		return "", fmt.Errorf("Unable to determine topic name from %s", topicARN)
+	}
+
+	if client, ok := snsClient.(interface{ Options() awssns.Options }); ok {
+		if region := topicRegion(topicARN); region != client.Options().Region {
+			return "", fmt.Errorf("Unable to create topic %s: its region %s isn't the client's region %s", name, region, client.Options().Region)
+		}
+	}
+
+	params := &awssns.CreateTopicInput{
+		Name: aws.String(name),
+	}
+
+	if isFIFO(topicARN) {
+		params.Attributes = map[string]string{"FifoTopic": "true"}
+		if contentBasedDeduplication {
+			params.Attributes["ContentBasedDeduplication"] = "true"
+		}
+	}
+
+	resp, err := snsClient.CreateTopic(context.Background(), params)
+	if err != nil {
+		return "", fmt.Errorf("Unable to create topic %s: %w", name, err)
+	}
+
+	return aws.ToString(resp.TopicArn), nil
+}
+
+// topicName extracts the topic name from an ARN such as
+// arn:aws:sns:us-east-1:1234:my-topic.
+func topicName(topicARN string) string {
+	parts := strings.Split(topicARN, ":")
+	if len(parts) < 6 {
+		return ""
+	}
+
+	return parts[len(parts)-1]
+}
+
+// topicRegion extracts the region from an ARN such as
+// arn:aws:sns:us-east-1:1234:my-topic.
+func topicRegion(topicARN string) string {
+	parts := strings.Split(topicARN, ":")
+	if len(parts) < 6 {
+		return ""
+	}
+
+	return parts[3]
+}
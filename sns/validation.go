@@ -0,0 +1,38 @@
+package sns
+
+import (
+	"fmt"
+)
+
+// Validator checks the data of an event before it is published and returns
+// an error describing what is wrong with it.
+type Validator func(data map[string]interface{}) error
+
+// ValidationError is returned when a Validator rejects an event. Nothing is
+// sent to SNS for the event.
+type ValidationError struct {
+	EventName string
+	Err       error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Event %s failed validation: %s", e.EventName, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// RequireFields returns a Validator that checks the given fields are present
+// and not nil.
+func RequireFields(fields ...string) Validator {
+	return func(data map[string]interface{}) error {
+		for _, field := range fields {
+			if value, ok := data[field]; !ok || nil == value {
+				return fmt.Errorf("Missing required field %q", field)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,156 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	awssns "github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go/aws"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+const (
+	defaultQueueNamePrefix = "gomainevents"
+
+	// Longest queue name SQS allows.
+	maxQueueNameLength = 80
+
+	// How long messages are kept in an instance's queue if the instance
+	// dies without deleting it: an hour.
+	broadcastRetentionPeriod = "3600"
+)
+
+var invalidQueueNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// SNSAPI is the subset of the SNS client used to subscribe queues to a
+// topic for broadcast delivery. It is satisfied by *sns.Client from
+// aws-sdk-go-v2.
+type SNSAPI interface {
+	Subscribe(ctx context.Context, params *awssns.SubscribeInput, optFns ...func(*awssns.Options)) (*awssns.SubscribeOutput, error)
+	Unsubscribe(ctx context.Context, params *awssns.UnsubscribeInput, optFns ...func(*awssns.Options)) (*awssns.UnsubscribeOutput, error)
+}
+
+// broadcastQueue is a queue created for one instance and subscribed to a
+// topic, so that the instance receives every event published to it.
+type broadcastQueue struct {
+	queueURL        string
+	subscriptionARN string
+}
+
+// createBroadcastQueue creates a queue, lets a topic send to it and
+// subscribes it to the topic. The queue is deleted again if any step fails.
+func createBroadcastQueue(sqsClient sqsiface.SQSAPI, snsClient SNSAPI, topicARN string, name string) (*broadcastQueue, error) {
+	created, err := sqsClient.CreateQueue(&awssqs.CreateQueueInput{
+		QueueName: aws.String(name),
+		Attributes: map[string]*string{
+			awssqs.QueueAttributeNameMessageRetentionPeriod: aws.String(broadcastRetentionPeriod),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create queue %s: %s", name, err)
+	}
+
+	queue := &broadcastQueue{queueURL: aws.StringValue(created.QueueUrl)}
+
+	if err := queue.subscribe(sqsClient, snsClient, topicARN); err != nil {
+		sqsClient.DeleteQueue(&awssqs.DeleteQueueInput{QueueUrl: aws.String(queue.queueURL)})
+		return nil, err
+	}
+
+	return queue, nil
+}
+
+func (q *broadcastQueue) subscribe(sqsClient sqsiface.SQSAPI, snsClient SNSAPI, topicARN string) error {
+	attributes, err := sqsClient.GetQueueAttributes(&awssqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(q.queueURL),
+		AttributeNames: aws.StringSlice([]string{awssqs.QueueAttributeNameQueueArn}),
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to get the ARN of %s: %s", q.queueURL, err)
+	}
+
+	queueARN := aws.StringValue(attributes.Attributes[awssqs.QueueAttributeNameQueueArn])
+
+	policy, _ := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{"Service": "sns.amazonaws.com"},
+			"Action":    "sqs:SendMessage",
+			"Resource":  queueARN,
+			"Condition": map[string]interface{}{
+				"ArnEquals": map[string]string{"aws:SourceArn": topicARN},
+			},
+		}},
+	})
+
+	_, err = sqsClient.SetQueueAttributes(&awssqs.SetQueueAttributesInput{
+		QueueUrl: aws.String(q.queueURL),
+		Attributes: map[string]*string{
+			awssqs.QueueAttributeNamePolicy: aws.String(string(policy)),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to let %s send to %s: %s", topicARN, q.queueURL, err)
+	}
+
+	subscribed, err := snsClient.Subscribe(context.Background(), &awssns.SubscribeInput{
+		TopicArn: aws.String(topicARN),
+		Protocol: aws.String("sqs"),
+		Endpoint: aws.String(queueARN),
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to subscribe %s to %s: %s", q.queueURL, topicARN, err)
+	}
+
+	q.subscriptionARN = aws.StringValue(subscribed.SubscriptionArn)
+
+	return nil
+}
+
+// remove unsubscribes the queue from the topic and deletes it.
+func (q *broadcastQueue) remove(sqsClient sqsiface.SQSAPI, snsClient SNSAPI) error {
+	_, unsubscribeErr := snsClient.Unsubscribe(context.Background(), &awssns.UnsubscribeInput{
+		SubscriptionArn: aws.String(q.subscriptionARN),
+	})
+
+	_, deleteErr := sqsClient.DeleteQueue(&awssqs.DeleteQueueInput{QueueUrl: aws.String(q.queueURL)})
+
+	if unsubscribeErr != nil {
+		return fmt.Errorf("Unable to unsubscribe %s: %s", q.queueURL, unsubscribeErr)
+	}
+
+	if deleteErr != nil {
+		return fmt.Errorf("Unable to delete %s: %s", q.queueURL, deleteErr)
+	}
+
+	return nil
+}
+
+// instanceQueueName returns a queue name unique to this instance.
+func instanceQueueName(prefix string) string {
+	host, _ := os.Hostname()
+
+	name := invalidQueueNameChars.ReplaceAllString(fmt.Sprintf("%s-%s-%d", prefix, host, os.Getpid()), "-")
+	if len(name) > maxQueueNameLength {
+		name = name[len(name)-maxQueueNameLength:]
+	}
+
+	return name
+}
+
+// topicRegion extracts the region from a topic ARN such as
+// arn:aws:sns:us-east-1:1234:events, or returns "".
+func topicRegion(topicARN string) string {
+	parts := strings.Split(topicARN, ":")
+	if len(parts) < 6 || "arn" != parts[0] {
+		return ""
+	}
+
+	return parts[3]
+}
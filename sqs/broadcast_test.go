@@ -0,0 +1,146 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	awssns "github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go/aws"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockQueueManager struct {
+	sqsiface.SQSAPI
+	created    []*awssqs.CreateQueueInput
+	policies   []string
+	deleted    []string
+	attributes error
+}
+
+func (m *mockQueueManager) CreateQueue(in *awssqs.CreateQueueInput) (*awssqs.CreateQueueOutput, error) {
+	m.created = append(m.created, in)
+	return &awssqs.CreateQueueOutput{QueueUrl: aws.String("https://sqs/" + aws.StringValue(in.QueueName))}, nil
+}
+
+func (m *mockQueueManager) GetQueueAttributes(in *awssqs.GetQueueAttributesInput) (*awssqs.GetQueueAttributesOutput, error) {
+	if nil != m.attributes {
+		return nil, m.attributes
+	}
+
+	return &awssqs.GetQueueAttributesOutput{Attributes: map[string]*string{
+		awssqs.QueueAttributeNameQueueArn: aws.String("arn:aws:sqs:us-east-1:123:instance"),
+	}}, nil
+}
+
+func (m *mockQueueManager) SetQueueAttributes(in *awssqs.SetQueueAttributesInput) (*awssqs.SetQueueAttributesOutput, error) {
+	m.policies = append(m.policies, aws.StringValue(in.Attributes[awssqs.QueueAttributeNamePolicy]))
+	return &awssqs.SetQueueAttributesOutput{}, nil
+}
+
+func (m *mockQueueManager) DeleteQueue(in *awssqs.DeleteQueueInput) (*awssqs.DeleteQueueOutput, error) {
+	m.deleted = append(m.deleted, aws.StringValue(in.QueueUrl))
+	return &awssqs.DeleteQueueOutput{}, nil
+}
+
+type mockSubscriber struct {
+	subscribed   []*awssns.SubscribeInput
+	unsubscribed []string
+}
+
+func (m *mockSubscriber) Subscribe(ctx context.Context, in *awssns.SubscribeInput, optFns ...func(*awssns.Options)) (*awssns.SubscribeOutput, error) {
+	m.subscribed = append(m.subscribed, in)
+	return &awssns.SubscribeOutput{SubscriptionArn: aws.String("arn:aws:sns:us-east-1:123:events:sub")}, nil
+}
+
+func (m *mockSubscriber) Unsubscribe(ctx context.Context, in *awssns.UnsubscribeInput, optFns ...func(*awssns.Options)) (*awssns.UnsubscribeOutput, error) {
+	m.unsubscribed = append(m.unsubscribed, aws.StringValue(in.SubscriptionArn))
+	return &awssns.UnsubscribeOutput{}, nil
+}
+
+func TestNewProviderDelivery(t *testing.T) {
+	provider, err := NewProvider(&Config{SQSClient: &mockSQS{}, QueueURL: "queue"})
+	require.Nil(t, err)
+	assert.Equal(t, gomainevents.CompetingConsumers, provider.Delivery())
+
+	_, err = NewProvider(&Config{SQSClient: &mockSQS{}, Delivery: gomainevents.CompetingConsumers})
+	assert.EqualError(t, err, "QueueURL is required")
+
+	_, err = NewProvider(&Config{SQSClient: &mockSQS{}, Delivery: gomainevents.Broadcast})
+	assert.EqualError(t, err, "TopicARN is required for broadcast delivery")
+
+	_, err = NewProvider(&Config{SQSClient: &mockSQS{}, Delivery: gomainevents.Broadcast, TopicARN: "topic", QueueURL: "queue"})
+	assert.EqualError(t, err, "QueueURL can't be used with broadcast delivery")
+
+	_, err = NewProvider(&Config{SQSClient: &mockSQS{}, Delivery: gomainevents.Delivery(42), QueueURL: "queue"})
+	assert.EqualError(t, err, "Delivery isn't supported: unknown")
+
+	// The default SNS client is for the topic's region
+	_, err = NewProvider(&Config{SQSClient: &mockSQS{}, Delivery: gomainevents.Broadcast, TopicARN: "topic"})
+	assert.EqualError(t, err, "Unable to determine the region of topic topic")
+	assert.Equal(t, "eu-west-1", topicRegion("arn:aws:sns:eu-west-1:123456789012:events"))
+}
+
+func TestBroadcastDelivery(t *testing.T) {
+	sqsClient := &mockQueueManager{}
+	snsClient := &mockSubscriber{}
+
+	provider, err := NewProvider(&Config{
+		SQSClient:       sqsClient,
+		SNSClient:       snsClient,
+		Delivery:        gomainevents.Broadcast,
+		TopicARN:        "arn:aws:sns:us-east-1:123:events",
+		QueueNamePrefix: "cache-invalidator",
+	})
+	require.Nil(t, err)
+	assert.Equal(t, gomainevents.Broadcast, provider.Delivery())
+
+	require.Len(t, sqsClient.created, 1)
+	name := aws.StringValue(sqsClient.created[0].QueueName)
+	assert.True(t, strings.HasPrefix(name, "cache-invalidator-"))
+	assert.Equal(t, "https://sqs/"+name, provider.queueURL)
+
+	require.Len(t, sqsClient.policies, 1)
+	assert.Contains(t, sqsClient.policies[0], `"aws:SourceArn":"arn:aws:sns:us-east-1:123:events"`)
+	assert.Contains(t, sqsClient.policies[0], `"Resource":"arn:aws:sqs:us-east-1:123:instance"`)
+
+	require.Len(t, snsClient.subscribed, 1)
+	assert.Equal(t, "sqs", aws.StringValue(snsClient.subscribed[0].Protocol))
+	assert.Equal(t, "arn:aws:sqs:us-east-1:123:instance", aws.StringValue(snsClient.subscribed[0].Endpoint))
+
+	provider.debug = false
+	provider.Stop()
+
+	assert.Equal(t, []string{"arn:aws:sns:us-east-1:123:events:sub"}, snsClient.unsubscribed)
+	assert.Equal(t, []string{"https://sqs/" + name}, sqsClient.deleted)
+}
+
+func TestBroadcastDeliveryDeletesQueueIfSubscribingFails(t *testing.T) {
+	sqsClient := &mockQueueManager{attributes: errors.New("Access denied")}
+	snsClient := &mockSubscriber{}
+
+	_, err := NewProvider(&Config{
+		SQSClient: sqsClient,
+		SNSClient: snsClient,
+		Delivery:  gomainevents.Broadcast,
+		TopicARN:  "arn:aws:sns:us-east-1:123:events",
+	})
+	assert.Error(t, err)
+
+	require.Len(t, sqsClient.created, 1)
+	assert.Len(t, sqsClient.deleted, 1)
+	assert.Empty(t, snsClient.subscribed)
+}
+
+func TestInstanceQueueName(t *testing.T) {
+	name := instanceQueueName("gomainevents")
+	assert.Regexp(t, `^gomainevents-[A-Za-z0-9_-]+-[0-9]+$`, name)
+
+	name = instanceQueueName(strings.Repeat("a", 100))
+	assert.Len(t, name, maxQueueNameLength)
+}
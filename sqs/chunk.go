@@ -0,0 +1,128 @@
+package sqs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// chunkTimeout is how long the chunks of an event are held waiting for the
+// rest of them, hidden from other receives. Chunks that are given up on
+// aren't deleted, so they go to the queue's dead letter queue like any other
+// message that isn't handled.
+const chunkTimeout = 5 * time.Minute
+
+// chunk is one part of an event the SNS publisher split up because it was
+// too large to publish.
+type chunk struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+	Count int    `json:"count"`
+	Data  []byte `json:"data"`
+}
+
+// chunkSet holds the chunks of an event that have arrived so far.
+type chunkSet struct {
+	parts    []*Event
+	received int
+	started  time.Time
+}
+
+// chunkAssembler puts chunked events back together as their chunks arrive,
+// from any of the provider's pollers.
+type chunkAssembler struct {
+	mu      sync.Mutex
+	pending map[string]*chunkSet
+
+	now func() time.Time
+}
+
+func newChunkAssembler() *chunkAssembler {
+	return &chunkAssembler{
+		pending: map[string]*chunkSet{},
+		now:     time.Now,
+	}
+}
+
+// add holds on to a chunk, returning the whole event once every chunk of it
+// has arrived, and nil until then. Chunks that are received again replace
+// the earlier copy, whose receipt handle is no longer valid.
+func (a *chunkAssembler) add(event Event) (*Event, error) {
+	part := event.chunk
+	if part.Count < 1 || part.Index < 0 || part.Index >= part.Count {
+		return nil, fmt.Errorf("Chunk %d of %d is out of range", part.Index, part.Count)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	for id, set := range a.pending {
+		if now.Sub(set.started) > chunkTimeout {
+			delete(a.pending, id)
+		}
+	}
+
+	set, ok := a.pending[part.ID]
+	if !ok {
+		set = &chunkSet{parts: make([]*Event, part.Count), started: now}
+		a.pending[part.ID] = set
+	}
+
+	if len(set.parts) != part.Count {
+		return nil, errors.New("Chunks of the same event disagree on how many there are")
+	}
+
+	if nil == set.parts[part.Index] {
+		set.received++
+	}
+	set.parts[part.Index] = &event
+
+	if set.received < part.Count {
+		return nil, nil
+	}
+
+	delete(a.pending, part.ID)
+
+	return assemble(set.parts)
+}
+
+// assemble decodes the event that was split into the given chunks.
+func assemble(parts []*Event) (*Event, error) {
+	encoded := &bytes.Buffer{}
+	for _, part := range parts {
+		encoded.Write(part.chunk.Data)
+	}
+
+	evt := &receivedEvent{}
+	if err := unmarshalString(encoded.String(), evt); err != nil {
+		return nil, err
+	}
+
+	// The event is handled as though it was the first chunk, but deleting
+	// or requeueing it applies to all of them.
+	first := *parts[0]
+	event := &Event{
+		name:          evt.Name,
+		provider:      first.provider,
+		receiptHandle: first.receiptHandle,
+		messageID:     first.messageID,
+		retryCount:    first.retryCount,
+		sentAt:        first.sentAt,
+		traceHeader:   first.traceHeader,
+
+		messageGroupID: first.messageGroupID,
+		sequenceNumber: first.sequenceNumber,
+	}
+	for _, part := range parts {
+		event.parts = append(event.parts, *part)
+	}
+
+	if err := event.setData(evt.Data); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
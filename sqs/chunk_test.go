@@ -0,0 +1,212 @@
+package sqs
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chunkMessages splits an event the way the SNS publisher does, into
+// messages of at most size bytes of the encoded event each.
+func chunkMessages(t *testing.T, id string, encoded string, size int) []*awssqs.Message {
+	count := (len(encoded) + size - 1) / size
+
+	messages := []*awssqs.Message{}
+	for index := 0; index < count; index++ {
+		end := (index + 1) * size
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+
+		part, err := json.Marshal(map[string]interface{}{
+			"name": "Domain\\Event",
+			"data": nil,
+			"chunk": &chunk{
+				ID:    id,
+				Index: index,
+				Count: count,
+				Data:  []byte(encoded[index*size : end]),
+			},
+		})
+		require.Nil(t, err)
+
+		body, err := json.Marshal(&encodedMessage{Message: string(part)})
+		require.Nil(t, err)
+
+		messages = append(messages, &awssqs.Message{
+			ReceiptHandle: aws.String("handle-" + strconv.Itoa(index)),
+			MessageId:     aws.String("message-" + strconv.Itoa(index)),
+			Body:          aws.String(string(body)),
+		})
+	}
+
+	return messages
+}
+
+func decodeChunks(t *testing.T, provider *Provider, messages []*awssqs.Message) []Event {
+	events := []Event{}
+	for _, message := range messages {
+		event, err := DecodeEvent(provider, message)
+		require.Nil(t, err)
+		require.NotNil(t, event.chunk)
+
+		events = append(events, *event)
+	}
+
+	return events
+}
+
+const chunkedEvent = `{"name":"Domain\\Event","data":{"occurredOn":"2018-03-08 11:11:11"}}`
+
+func TestChunkAssemblerAssemblesEvents(t *testing.T) {
+	provider := &Provider{}
+	chunks := decodeChunks(t, provider, chunkMessages(t, "abc", chunkedEvent, 20))
+	require.Len(t, chunks, 4)
+
+	assembler := newChunkAssembler()
+
+	// Chunks can arrive in any order, and more than once
+	for _, i := range []int{2, 0, 3, 2} {
+		event, err := assembler.add(chunks[i])
+		require.Nil(t, err)
+		assert.Nil(t, event)
+	}
+
+	event, err := assembler.add(chunks[1])
+	require.Nil(t, err)
+	require.NotNil(t, event)
+
+	assert.Equal(t, "Domain\\Event", event.Name())
+	assert.Equal(t, "2018-03-08 11:11:11", event.Data()["occurredOn"])
+	assert.Equal(t, "message-0", event.MessageID())
+	assert.Len(t, event.messages(), 4)
+	assert.Empty(t, assembler.pending)
+}
+
+func TestChunkAssemblerRejectsBadChunks(t *testing.T) {
+	provider := &Provider{}
+	chunks := decodeChunks(t, provider, chunkMessages(t, "abc", chunkedEvent, 20))
+	assembler := newChunkAssembler()
+
+	outOfRange := chunks[0]
+	outOfRange.chunk = &chunk{ID: "abc", Index: 4, Count: 4}
+	_, err := assembler.add(outOfRange)
+	assert.EqualError(t, err, "Chunk 4 of 4 is out of range")
+
+	_, err = assembler.add(chunks[0])
+	require.Nil(t, err)
+
+	disagreeing := chunks[1]
+	disagreeing.chunk = &chunk{ID: "abc", Index: 1, Count: 5}
+	_, err = assembler.add(disagreeing)
+	assert.EqualError(t, err, "Chunks of the same event disagree on how many there are")
+}
+
+func TestChunkAssemblerGivesUpOnIncompleteEvents(t *testing.T) {
+	provider := &Provider{}
+	first := decodeChunks(t, provider, chunkMessages(t, "first", chunkedEvent, 20))
+	second := decodeChunks(t, provider, chunkMessages(t, "second", chunkedEvent, 20))
+
+	now := time.Now()
+	assembler := newChunkAssembler()
+	assembler.now = func() time.Time { return now }
+
+	assembler.add(first[0])
+	assert.Len(t, assembler.pending, 1)
+
+	now = now.Add(chunkTimeout + time.Second)
+	assembler.add(second[0])
+
+	assert.Len(t, assembler.pending, 1)
+	assert.Contains(t, assembler.pending, "second")
+}
+
+// chunkSQS records the messages that are deleted and sent.
+type chunkSQS struct {
+	mockSender
+	deleted []string
+}
+
+func (m *chunkSQS) DeleteMessage(in *awssqs.DeleteMessageInput) (*awssqs.DeleteMessageOutput, error) {
+	m.deleted = append(m.deleted, aws.StringValue(in.ReceiptHandle))
+	return &awssqs.DeleteMessageOutput{}, nil
+}
+
+func TestProviderDeletesAndRequeuesChunks(t *testing.T) {
+	client := &chunkSQS{}
+	provider, err := NewProvider(&Config{SQSClient: client, QueueURL: "queueueueueueue"})
+	require.Nil(t, err)
+	provider.debug = false
+
+	messages := chunkMessages(t, "abc", chunkedEvent, 40)
+	chunks := decodeChunks(t, provider, messages)
+
+	var event *Event
+	for _, part := range chunks {
+		event, err = provider.chunks.add(part)
+		require.Nil(t, err)
+	}
+	require.NotNil(t, event)
+
+	provider.Delete(*event)
+	assert.Equal(t, []string{"handle-0", "handle-1"}, client.deleted)
+
+	client.deleted = nil
+	require.Nil(t, provider.Requeue(*event))
+	assert.Equal(t, []string{"handle-0", "handle-1"}, client.deleted)
+
+	// The chunks are sent again as they were received
+	require.Len(t, client.sent, 2)
+	for i, sent := range client.sent {
+		assert.Equal(t, aws.StringValue(messages[i].Body), aws.StringValue(sent.MessageBody))
+		assert.Equal(t, "1", aws.StringValue(sent.MessageAttributes["RetryCount"].StringValue))
+	}
+
+	requeued := decodeChunks(t, provider, []*awssqs.Message{{
+		ReceiptHandle:     aws.String("requeued"),
+		Body:              client.sent[0].MessageBody,
+		MessageAttributes: client.sent[0].MessageAttributes,
+	}})
+	assert.Equal(t, 1, requeued[0].RetryCount())
+}
+
+func TestProviderHoldsChunksOutOfSight(t *testing.T) {
+	client := &fifoSQS{}
+	provider, err := NewProvider(&Config{SQSClient: client, QueueURL: "queueueueueueue", SingleConsumer: true})
+	require.Nil(t, err)
+	provider.debug = false
+
+	chunks := decodeChunks(t, provider, chunkMessages(t, "abc", chunkedEvent, 40))
+	require.Len(t, chunks, 2)
+
+	event, err := provider.assemble(chunks[0])
+	require.Nil(t, err)
+	assert.Nil(t, event)
+
+	require.Len(t, client.visibility, 1)
+	assert.Equal(t, "handle-0", aws.StringValue(client.visibility[0].ReceiptHandle))
+	assert.Equal(t, int64(chunkTimeout/time.Second), aws.Int64Value(client.visibility[0].VisibilityTimeout))
+
+	event, err = provider.assemble(chunks[1])
+	require.Nil(t, err)
+	require.NotNil(t, event)
+	assert.Len(t, client.visibility, 1)
+}
+
+func TestProviderRejectsChunksWithCompetingConsumers(t *testing.T) {
+	provider, err := NewProvider(&Config{SQSClient: &fifoSQS{}, QueueURL: "queueueueueueue"})
+	require.Nil(t, err)
+	provider.debug = false
+
+	chunks := decodeChunks(t, provider, chunkMessages(t, "abc", chunkedEvent, 40))
+
+	_, err = provider.assemble(chunks[0])
+	assert.EqualError(t, err, "Chunked events need broadcast delivery or a single consumer")
+	assert.Empty(t, provider.chunks.pending)
+}
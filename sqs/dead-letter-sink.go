@@ -0,0 +1,80 @@
+package sqs
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/researchsquare/gomainevents"
+)
+
+// DeadLetterSink sends dead letters to a queue as JSON, with EventName and
+// Reason message attributes to filter them by, so that failed events can be
+// triaged from the queue alone. Register it with the Listener:
+//
+//	listener.RegisterDeadLetterSink(sink)
+//
+// Use a different queue from the one the redrive policy moves messages
+// to: that one holds the original messages, for redriving.
+type DeadLetterSink struct {
+	sqsClient sqsiface.SQSAPI
+	queueURL  string
+}
+
+type DeadLetterSinkConfig struct {
+	// Provide your own SQS client. Default will use the
+	// default AWS session + shared credentials.
+	SQSClient sqsiface.SQSAPI
+
+	// Specify the Queue URL. Required
+	QueueURL string
+}
+
+func NewDeadLetterSink(config *DeadLetterSinkConfig) (*DeadLetterSink, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.QueueURL {
+		return nil, errors.New("QueueURL is required")
+	}
+
+	// Default to a new client using shared credentials
+	sqsClient := config.SQSClient
+	if nil == sqsClient {
+		sess := session.Must(session.NewSession())
+		sqsClient = awssqs.New(sess, &aws.Config{Region: aws.String("us-east-1")})
+	}
+
+	return &DeadLetterSink{
+		sqsClient: sqsClient,
+		queueURL:  config.QueueURL,
+	}, nil
+}
+
+func (s *DeadLetterSink) DeadLetter(deadLetter gomainevents.DeadLetter) error {
+	body, err := json.Marshal(deadLetter)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sqsClient.SendMessage(&awssqs.SendMessageInput{
+		QueueUrl:    aws.String(s.queueURL),
+		MessageBody: aws.String(string(body)),
+		MessageAttributes: map[string]*awssqs.MessageAttributeValue{
+			"EventName": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(deadLetter.EventName),
+			},
+			"Reason": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(string(deadLetter.Reason)),
+			},
+		},
+	})
+
+	return err
+}
@@ -0,0 +1,53 @@
+package sqs
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDeadLetterSink(t *testing.T) {
+	_, err := NewDeadLetterSink(nil)
+	assert.EqualError(t, err, "Configuration is required")
+
+	_, err = NewDeadLetterSink(&DeadLetterSinkConfig{SQSClient: &mockSender{}})
+	assert.EqualError(t, err, "QueueURL is required")
+
+	sink, err := NewDeadLetterSink(&DeadLetterSinkConfig{SQSClient: &mockSender{}, QueueURL: "triage"})
+	assert.NoError(t, err)
+	assert.NotNil(t, sink)
+}
+
+func TestDeadLetterSinkSendsDeadLetters(t *testing.T) {
+	client := &mockSender{}
+	sink, _ := NewDeadLetterSink(&DeadLetterSinkConfig{SQSClient: client, QueueURL: "triage"})
+
+	deadLetter := gomainevents.DeadLetter{
+		EventName:      "PaymentReceived",
+		Data:           map[string]interface{}{"amount": 10.0},
+		EventID:        "message-1",
+		Reason:         gomainevents.ReasonRetriesExhausted,
+		Error:          "Card declined",
+		Handler:        "main.recordPayment",
+		RetryCount:     25,
+		Attempts:       []gomainevents.Attempt{{At: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Error: "Card declined"}},
+		Consumer:       gomainevents.Consumer{Program: "payments", Host: "host-1", PID: 42},
+		DeadLetteredAt: time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC),
+	}
+	assert.NoError(t, sink.DeadLetter(deadLetter))
+
+	require.Len(t, client.sent, 1)
+	sent := client.sent[0]
+	assert.Equal(t, "triage", aws.StringValue(sent.QueueUrl))
+	assert.Equal(t, "PaymentReceived", aws.StringValue(sent.MessageAttributes["EventName"].StringValue))
+	assert.Equal(t, "retries exhausted", aws.StringValue(sent.MessageAttributes["Reason"].StringValue))
+
+	var decoded gomainevents.DeadLetter
+	require.NoError(t, json.Unmarshal([]byte(aws.StringValue(sent.MessageBody)), &decoded))
+	assert.Equal(t, deadLetter, decoded)
+}
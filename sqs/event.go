@@ -1,9 +1,13 @@
 package sqs
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"math"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	awssqs "github.com/aws/aws-sdk-go/service/sqs"
@@ -15,6 +19,10 @@ type Event struct {
 	name string
 	data map[string]interface{}
 
+	// Received events keep their data as JSON until it's asked for, so that
+	// handlers that decode it into structs never build the map.
+	raw *rawData
+
 	// We want a reference to the provider that retrieved the message
 	// for this event from SQS. This allows the EventHandler to operate
 	// on the message semi-directly, if necessary.
@@ -24,22 +32,113 @@ type Event struct {
 	// received. This is necessary for deleting and requeueing.
 	receiptHandle string
 
+	// ID SQS gave the message, which stays the same between receives.
+	messageID string
+
 	// FIFO queues require a deduplication ID if ContentBasedDeduplication
 	// isn't being used.
 	deduplicationID *string
 
+	// FIFO queues order messages within a group by their sequence number.
+	messageGroupID string
+	sequenceNumber string
+
 	// Messages can be retried a set number of times before they
 	// go to a deadletter queue.
 	retryCount int
+
+	// Events too large for SNS/SQS are stored in S3. We keep the pointer
+	// so that requeueing doesn't re-inline the body.
+	s3Pointer *s3Pointer
+
+	// When SQS received the message, if it said.
+	sentAt time.Time
+
+	// X-Ray trace header the event was published with, if any.
+	traceHeader string
+
+	// Set on the chunks of events that were too large to publish whole,
+	// along with the body they were received with, so they can be
+	// requeued as they were.
+	chunk *chunk
+	body  string
+
+	// The chunks an event was put back together from.
+	parts []Event
 }
 
+// traceHeaderAttribute is the attribute X-Ray trace headers are passed in.
+const traceHeaderAttribute = "AWSTraceHeader"
+
+// Attributes FIFO queues give messages.
+const (
+	messageGroupIDAttribute  = "MessageGroupId"
+	sequenceNumberAttribute  = "SequenceNumber"
+	deduplicationIDAttribute = "MessageDeduplicationId"
+	receiveCountAttribute    = "ApproximateReceiveCount"
+)
+
 type encodedEvent struct {
 	Name string                 `json:"name"`
 	Data map[string]interface{} `json:"data"`
+
+	// Set instead of Data when the event was offloaded to S3.
+	S3Pointer *s3Pointer `json:"s3Pointer,omitempty"`
+}
+
+// receivedEvent is an encodedEvent as it is decoded, leaving the data as
+// JSON.
+type receivedEvent struct {
+	Name      string          `json:"name"`
+	Data      json.RawMessage `json:"data"`
+	S3Pointer *s3Pointer      `json:"s3Pointer,omitempty"`
+	Chunk     *chunk          `json:"chunk,omitempty"`
+}
+
+// rawData is event data that is decoded into a map the first time it is
+// needed. It is shared by copies of an event.
+type rawData struct {
+	json []byte
+	once sync.Once
+	data map[string]interface{}
+}
+
+func (r *rawData) decode() map[string]interface{} {
+	r.once.Do(func() {
+		// The data was checked to be an object when it was received.
+		json.Unmarshal(r.json, &r.data)
+	})
+
+	return r.data
+}
+
+// buffers are reused for the bodies of received messages, which otherwise
+// have to be copied to be decoded.
+var buffers = sync.Pool{
+	New: func() interface{} { return &bytes.Buffer{} },
+}
+
+// unmarshalString decodes JSON held in a string.
+func unmarshalString(s string, v interface{}) error {
+	buf := buffers.Get().(*bytes.Buffer)
+	defer buffers.Put(buf)
+
+	buf.Reset()
+	buf.WriteString(s)
+
+	return json.Unmarshal(buf.Bytes(), v)
 }
 
 type encodedMessage struct {
 	Message string
+
+	// Attributes the event was published to SNS with.
+	MessageAttributes map[string]snsMessageAttribute `json:",omitempty"`
+}
+
+type snsMessageAttribute struct {
+	Type  string
+	Value string
 }
 
 // DecodeEvent will take an SQS message and extract all the information
@@ -51,7 +150,24 @@ func DecodeEvent(provider *Provider, message *awssqs.Message) (*Event, error) {
 	event := &Event{
 		provider:        provider,
 		receiptHandle:   *message.ReceiptHandle,
-		deduplicationID: message.Attributes["DeduplicationID"],
+		messageID:       aws.StringValue(message.MessageId),
+		deduplicationID: message.Attributes[deduplicationIDAttribute],
+		messageGroupID:  aws.StringValue(message.Attributes[messageGroupIDAttribute]),
+		sequenceNumber:  aws.StringValue(message.Attributes[sequenceNumberAttribute]),
+	}
+
+	if sentTimestamp, ok := message.Attributes["SentTimestamp"]; ok {
+		if millis, err := strconv.ParseInt(aws.StringValue(sentTimestamp), 10, 64); err == nil {
+			event.sentAt = time.Unix(0, millis*int64(time.Millisecond))
+		}
+	}
+
+	// SQS passes trace headers on as a system attribute, but publishers
+	// without X-Ray integration send them as message attributes.
+	if traceHeader, ok := message.Attributes[traceHeaderAttribute]; ok {
+		event.traceHeader = aws.StringValue(traceHeader)
+	} else if traceHeader, ok := message.MessageAttributes[traceHeaderAttribute]; ok {
+		event.traceHeader = aws.StringValue(traceHeader.StringValue)
 	}
 
 	// Determine if we have a retry count and default to 0 if this is the first time we've seen it.
@@ -67,31 +183,101 @@ func DecodeEvent(provider *Provider, message *awssqs.Message) (*Event, error) {
 		event.retryCount = retryCount
 	}
 
+	// FIFO events are delayed in place rather than resent, so SQS counts
+	// their retries.
+	if receiveCount, ok := message.Attributes[receiveCountAttribute]; ok && "" != event.messageGroupID {
+		if count, err := strconv.Atoi(aws.StringValue(receiveCount)); err == nil && count > 1 {
+			event.retryCount = count - 1
+		}
+	}
+
 	// And now fill in the actual event!
 	// We have to double-decode because the body is json and the message
 	// inside the body is also json.
-	body := []byte(aws.StringValue(message.Body))
 	msg := &encodedMessage{}
-	if err := json.Unmarshal(body, msg); err != nil {
+	if err := unmarshalString(aws.StringValue(message.Body), msg); err != nil {
 		return nil, err
 	}
 
-	evt := &encodedEvent{}
-	if err := json.Unmarshal([]byte(msg.Message), evt); err != nil {
+	evt := &receivedEvent{}
+	if err := unmarshalString(msg.Message, evt); err != nil {
 		return nil, err
 	}
 
+	// Events that came through SNS carry its message attributes in the body.
+	if attribute, ok := msg.MessageAttributes[traceHeaderAttribute]; ok && "" == event.traceHeader {
+		event.traceHeader = attribute.Value
+	}
+
+	// Chunks are put back together by the provider once they've all
+	// arrived.
+	if nil != evt.Chunk {
+		event.name = evt.Name
+		event.chunk = evt.Chunk
+		event.body = aws.StringValue(message.Body)
+		return event, nil
+	}
+
+	// Large events only carry a pointer to the body in S3.
+	if nil != evt.S3Pointer {
+		event.s3Pointer = evt.S3Pointer
+
+		var err error
+		if evt, err = provider.fetchOffloadedEvent(evt.S3Pointer); err != nil {
+			return nil, err
+		}
+	}
+
 	event.name = evt.Name
-	event.data = evt.Data
+
+	if err := event.setData(evt.Data); err != nil {
+		return nil, err
+	}
 
 	return event, nil
 }
 
+// setData keeps the JSON data an event was received with.
+func (e *Event) setData(data json.RawMessage) error {
+	switch data := bytes.TrimSpace(data); {
+	case len(data) == 0 || bytes.Equal(data, []byte("null")):
+	case '{' == data[0]:
+		e.raw = &rawData{json: data}
+	default:
+		return errors.New("Event data is not an object")
+	}
+
+	return nil
+}
+
+// messages returns the events of the SQS messages the event was received as:
+// its chunks, or the event itself.
+func (e Event) messages() []Event {
+	if len(e.parts) > 0 {
+		return e.parts
+	}
+
+	return []Event{e}
+}
+
+// messageBody returns the body to requeue the event's message with.
+func (e *Event) messageBody() string {
+	if "" != e.body {
+		return e.body
+	}
+
+	return e.EncodeEvent()
+}
+
 func (e *Event) EncodeEvent() string {
 	evt := &encodedEvent{
 		Name: e.Name(),
 		Data: e.Data(),
 	}
+	if nil != e.s3Pointer {
+		evt.Data = nil
+		evt.S3Pointer = e.s3Pointer
+	}
 	bytes, _ := json.Marshal(evt)
 
 	msg := &encodedMessage{
@@ -108,20 +294,54 @@ func (e Event) Name() string {
 }
 
 func (e Event) Data() map[string]interface{} {
+	if nil == e.data && nil != e.raw {
+		return e.raw.decode()
+	}
+
 	return e.data
 }
 
+// RawData returns the JSON the event's data was received as, or nil for
+// events that weren't received. See gomainevents.DecodeData.
+func (e Event) RawData() []byte {
+	if nil == e.raw {
+		return nil
+	}
+
+	return e.raw.json
+}
+
 // ReceiptHandle returns the unique identifier for the message that this event
 // was created from.
 func (e *Event) ReceiptHandle() string {
 	return e.receiptHandle
 }
 
+// MessageID returns the ID SQS gave the message this event was created
+// from.
+func (e Event) MessageID() string {
+	return e.messageID
+}
+
 // DeduplicationID returns the deduplication ID for FIFO queues, if set.
 func (e *Event) DeduplicationID() *string {
 	return e.deduplicationID
 }
 
+// MessageGroupID returns the message group the event was sent to a FIFO
+// queue in, or an empty string for standard queues.
+func (e Event) MessageGroupID() string {
+	return e.messageGroupID
+}
+
+// SequenceNumber returns the number a FIFO queue orders the event by within
+// its message group, or an empty string for standard queues. Requeued events
+// keep theirs, as they're delayed in place. See
+// gomainevents.CompareSequenceNumbers.
+func (e Event) SequenceNumber() string {
+	return e.sequenceNumber
+}
+
 // DelaySeconds returns the number of seconds to delay before this
 // message becomes available.
 func (e *Event) DelaySeconds() int64 {
@@ -133,13 +353,32 @@ func (e *Event) DelaySeconds() int64 {
 
 // RetryCount returns the number of times this event has been delivered, but
 // not processed.
-func (e *Event) RetryCount() int {
+func (e Event) RetryCount() int {
 	return e.retryCount
 }
 
+// SentAt returns when SQS received the message this event was created from,
+// or zero if it isn't known. Requeued events were sent when they were
+// requeued.
+func (e Event) SentAt() time.Time {
+	return e.sentAt
+}
+
+// TraceHeader returns the X-Ray trace header the event was published with,
+// or an empty string if there wasn't one.
+func (e Event) TraceHeader() string {
+	return e.traceHeader
+}
+
 // UpdateVisibilityTimeout changes the timeout for this message only. It is up
 // to the provider to check if the timeout is different from the default for the
 // queue and to update it accordingly.
 func (e *Event) UpdateVisibilityTimeout(newTimeout int64) error {
-	return e.provider.updateVisibilityTimeout(e.receiptHandle, newTimeout)
+	for _, message := range e.messages() {
+		if err := e.provider.updateVisibilityTimeout(message.receiptHandle, newTimeout); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -1,11 +1,18 @@
 package sqs
 
 import (
+	"io/ioutil"
 	"math"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/researchsquare/gomainevents"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -16,7 +23,8 @@ func TestEventDecode(t *testing.T) {
 	msg := &awssqs.Message{
 		ReceiptHandle: aws.String("Hello!"),
 		Attributes: aws.StringMap(map[string]string{
-			"DeduplicationID": "1234",
+			"MessageDeduplicationId": "1234",
+			"SentTimestamp":          "1520507471000",
 		}),
 		MessageAttributes: map[string]*awssqs.MessageAttributeValue{
 			"RetryCount": &awssqs.MessageAttributeValue{
@@ -36,6 +44,69 @@ func TestEventDecode(t *testing.T) {
 	assert.Equal(t, int64(math.Pow(2, 6)), event.DelaySeconds())
 	assert.Equal(t, "Domain\\Event", event.Name())
 	assert.Equal(t, "2018-03-08 11:11:11", event.Data()["occurredOn"].(string))
+	assert.Equal(t, time.Date(2018, 3, 8, 11, 11, 11, 0, time.UTC), event.SentAt().UTC())
+}
+
+func TestEventDecodeData(t *testing.T) {
+	message := func(body string) *awssqs.Message {
+		return &awssqs.Message{ReceiptHandle: aws.String("Hello!"), Body: aws.String(body)}
+	}
+
+	event, err := DecodeEvent(&Provider{}, message(`{"Message":"{\"name\":\"UserCreated\",\"data\":{\"userId\":9007199254740993}}"}`))
+	require.Nil(t, err)
+	assert.Equal(t, `{"userId":9007199254740993}`, string(event.RawData()))
+
+	var decoded struct {
+		UserID int64 `json:"userId"`
+	}
+	require.Nil(t, gomainevents.DecodeData(*event, &decoded))
+	assert.Equal(t, int64(9007199254740993), decoded.UserID)
+
+	// The map is built once and shared by copies of the event.
+	copied := *event
+	assert.Equal(t, map[string]interface{}{"userId": 9007199254740993.0}, copied.Data())
+	copied.Data()["role"] = "admin"
+	assert.Equal(t, "admin", event.Data()["role"])
+
+	event, err = DecodeEvent(&Provider{}, message(`{"Message":"{\"name\":\"UserCreated\"}"}`))
+	require.Nil(t, err)
+	assert.Nil(t, event.Data())
+	assert.Nil(t, event.RawData())
+
+	_, err = DecodeEvent(&Provider{}, message(`{"Message":"{\"name\":\"UserCreated\",\"data\":[1]}"}`))
+	assert.EqualError(t, err, "Event data is not an object")
+}
+
+func BenchmarkDecodeEvent(b *testing.B) {
+	message := &awssqs.Message{
+		ReceiptHandle: aws.String("Hello!"),
+		Attributes: aws.StringMap(map[string]string{
+			"SentTimestamp": "1520507471000",
+		}),
+		Body: aws.String(`{"Type":"Notification","MessageId":"c0a4b1e6","Message":"{\"name\":\"Domain\\\\Event\",\"data\":{\"occurredOn\":\"2018-03-08 11:11:11\",\"userId\":12,\"roles\":[\"admin\",\"author\"],\"profile\":{\"name\":\"Ada\",\"email\":\"ada@example.com\"}}}"}`),
+	}
+
+	b.Run("Data", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			event, _ := DecodeEvent(nil, message)
+			event.Data()
+		}
+	})
+
+	b.Run("DecodeData", func(b *testing.B) {
+		var decoded struct {
+			OccurredOn string   `json:"occurredOn"`
+			UserID     int64    `json:"userId"`
+			Roles      []string `json:"roles"`
+		}
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			event, _ := DecodeEvent(nil, message)
+			gomainevents.DecodeData(*event, &decoded)
+		}
+	})
 }
 
 func TestEventEncode(t *testing.T) {
@@ -52,3 +123,116 @@ func TestEventEncode(t *testing.T) {
 		event.EncodeEvent(),
 	)
 }
+
+func TestEventDecodeTraceHeader(t *testing.T) {
+	provider := &Provider{}
+	body := "{\"Message\":\"{\\\"name\\\":\\\"Domain\\\\\\\\Event\\\",\\\"data\\\":{}}\"}"
+
+	// Passed on by SQS
+	event, err := DecodeEvent(provider, &awssqs.Message{
+		ReceiptHandle: aws.String("Hello!"),
+		Attributes: aws.StringMap(map[string]string{
+			"AWSTraceHeader": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+		}),
+		Body: aws.String(body),
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1", event.TraceHeader())
+
+	// Sent as a message attribute
+	event, err = DecodeEvent(provider, &awssqs.Message{
+		ReceiptHandle: aws.String("Hello!"),
+		MessageAttributes: map[string]*awssqs.MessageAttributeValue{
+			"AWSTraceHeader": &awssqs.MessageAttributeValue{
+				StringValue: aws.String("Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=0"),
+				DataType:    aws.String("String"),
+			},
+		},
+		Body: aws.String(body),
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=0", event.TraceHeader())
+
+	// Published to SNS as a message attribute
+	event, err = DecodeEvent(provider, &awssqs.Message{
+		ReceiptHandle: aws.String("Hello!"),
+		Body:          aws.String(strings.Replace(body, "}\"}", "}\",\"MessageAttributes\":{\"AWSTraceHeader\":{\"Type\":\"String\",\"Value\":\"Root=1-5759e988-bd862e3fe1be46a994272793\"}}}", 1)),
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793", event.TraceHeader())
+
+	// Not traced
+	event, err = DecodeEvent(provider, &awssqs.Message{
+		ReceiptHandle: aws.String("Hello!"),
+		Body:          aws.String(body),
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, "", event.TraceHeader())
+}
+
+type mockS3 struct {
+	s3iface.S3API
+	objects map[string]string
+}
+
+func (m mockS3) GetObject(in *awss3.GetObjectInput) (*awss3.GetObjectOutput, error) {
+	return &awss3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader(m.objects[*in.Bucket+"/"+*in.Key])),
+	}, nil
+}
+
+func TestEventDecodeOffloaded(t *testing.T) {
+	provider := &Provider{
+		s3Client: mockS3{objects: map[string]string{
+			"bucket/events/1.json": "{\"name\":\"Domain\\\\Event\",\"data\":{\"occurredOn\":\"2018-03-08 11:11:11\"}}",
+		}},
+	}
+
+	msg := &awssqs.Message{
+		ReceiptHandle: aws.String("Hello!"),
+		Body:          aws.String("{\"Message\":\"{\\\"name\\\":\\\"Domain\\\\\\\\Event\\\",\\\"s3Pointer\\\":{\\\"bucket\\\":\\\"bucket\\\",\\\"key\\\":\\\"events/1.json\\\"}}\"}"),
+	}
+
+	event, err := DecodeEvent(provider, msg)
+
+	require.Nil(t, err)
+	assert.Equal(t, "Domain\\Event", event.Name())
+	assert.Equal(t, "2018-03-08 11:11:11", event.Data()["occurredOn"].(string))
+
+	// Requeued events keep pointing at S3 instead of inlining the body
+	assert.Equal(
+		t,
+		"{\"Message\":\"{\\\"name\\\":\\\"Domain\\\\\\\\Event\\\",\\\"data\\\":null,\\\"s3Pointer\\\":{\\\"bucket\\\":\\\"bucket\\\",\\\"key\\\":\\\"events/1.json\\\"}}\"}",
+		event.EncodeEvent(),
+	)
+}
+
+func TestProviderCreatesS3ClientWhenNeeded(t *testing.T) {
+	provider, err := NewProvider(&Config{SQSClient: &mockSQS{}, QueueURL: "https://sqs.eu-west-1.amazonaws.com/1234/events"})
+	require.Nil(t, err)
+	assert.Nil(t, provider.s3Client)
+
+	// In the queue's region, for lack of a real SQS client
+	assert.Equal(t, "eu-west-1", provider.region())
+
+	s3Client, err := provider.s3()
+	require.Nil(t, err)
+	assert.NotNil(t, s3Client)
+	assert.Equal(t, s3Client, provider.s3Client)
+
+	// Otherwise in the SQS client's region
+	sess, err := session.NewSession()
+	require.Nil(t, err)
+	provider, err = NewProvider(&Config{
+		SQSClient: awssqs.New(sess, &aws.Config{Region: aws.String("ap-southeast-2")}),
+		QueueURL:  "https://sqs.eu-west-1.amazonaws.com/1234/events",
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "ap-southeast-2", provider.region())
+
+	assert.Equal(t, "", queueRegion("queue"))
+}
@@ -0,0 +1,95 @@
+package sqs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// s3Pointer locates an event body that the SNS publisher uploaded to S3
+// because it was too large to publish.
+type s3Pointer struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+}
+
+// fetchOffloadedEvent downloads the full event that a pointer refers to.
+func (p *Provider) fetchOffloadedEvent(pointer *s3Pointer) (*receivedEvent, error) {
+	if nil == p {
+		return nil, errors.New("Event was offloaded to S3 but no S3 client is configured")
+	}
+
+	s3Client, err := p.s3()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s3Client.GetObject(&awss3.GetObjectInput{
+		Bucket: aws.String(pointer.Bucket),
+		Key:    aws.String(pointer.Key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	evt := &receivedEvent{}
+	if err := json.NewDecoder(resp.Body).Decode(evt); err != nil {
+		return nil, err
+	}
+
+	return evt, nil
+}
+
+// s3 returns the client offloaded events are fetched with, creating one in
+// the SQS client's region the first time it's needed.
+func (p *Provider) s3() (s3iface.S3API, error) {
+	p.s3Mu.Lock()
+	defer p.s3Mu.Unlock()
+
+	if nil != p.s3Client {
+		return p.s3Client, nil
+	}
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(p.region())})
+	if err != nil {
+		return nil, err
+	}
+
+	p.s3Client = awss3.New(sess)
+
+	return p.s3Client, nil
+}
+
+// region returns the region of the SQS client, or failing that the one in
+// the queue URL.
+func (p *Provider) region() string {
+	if client, ok := p.sqsClient.(*awssqs.SQS); ok && "" != aws.StringValue(client.Config.Region) {
+		return aws.StringValue(client.Config.Region)
+	}
+
+	return queueRegion(p.queueURL)
+}
+
+// queueRegion extracts the region from a queue URL such as
+// https://sqs.us-east-1.amazonaws.com/1234/events, or returns "".
+func queueRegion(queueURL string) string {
+	parsed, err := url.Parse(queueURL)
+	if err != nil {
+		return ""
+	}
+
+	parts := strings.Split(parsed.Hostname(), ".")
+	if len(parts) < 3 || "sqs" != parts[0] {
+		return ""
+	}
+
+	return parts[1]
+}
@@ -0,0 +1,103 @@
+package sqs
+
+import (
+	"sync"
+)
+
+const (
+	defaultMaxMessages = 10
+	defaultMaxPollers  = 4
+
+	// SQS returns at most 10 messages per receive.
+	maxMessagesPerReceive = 10
+)
+
+// pollTuner adapts how many messages are asked for per receive, and how many
+// pollers receive at once, to the queue and the Listener. Full receives
+// while the events channel has room mean there's a backlog, so it asks for
+// more messages, then adds pollers. Empty receives mean the queue is idle,
+// and a filling channel means the Listener can't keep up, so it backs off,
+// keeping messages on the queue rather than letting their visibility
+// timeout run out while they wait in the channel.
+type pollTuner struct {
+	maxMessages int
+	maxPollers  int
+
+	mu       sync.Mutex
+	messages int
+	pollers  int
+	running  int
+}
+
+func newPollTuner(maxMessages int, maxPollers int) *pollTuner {
+	return &pollTuner{
+		maxMessages: maxMessages,
+		maxPollers:  maxPollers,
+		messages:    1,
+		pollers:     1,
+	}
+}
+
+// batchSize returns how many messages to ask for.
+func (t *pollTuner) batchSize() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.messages
+}
+
+// observe adjusts to a receive that asked for requested messages and got
+// received, with the events channel filled to occupancy, from 0 to 1.
+func (t *pollTuner) observe(requested int, received int, occupancy float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case occupancy >= 0.5 || 0 == received:
+		t.messages = clamp(t.messages/2, 1, t.maxMessages)
+		t.pollers = clamp(t.pollers-1, 1, t.maxPollers)
+	case received >= requested && t.messages < t.maxMessages:
+		t.messages = clamp(t.messages*2, 1, t.maxMessages)
+	case received >= requested:
+		t.pollers = clamp(t.pollers+1, 1, t.maxPollers)
+	}
+}
+
+// start returns true if another poller should be started, counting it as
+// running.
+func (t *pollTuner) start() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.running >= t.pollers {
+		return false
+	}
+
+	t.running++
+	return true
+}
+
+// stop returns true if a poller should stop, counting it as stopped.
+func (t *pollTuner) stop() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.running <= t.pollers {
+		return false
+	}
+
+	t.running--
+	return true
+}
+
+func clamp(value int, lower int, upper int) int {
+	if value < lower {
+		return lower
+	}
+
+	if value > upper {
+		return upper
+	}
+
+	return value
+}
@@ -0,0 +1,59 @@
+package sqs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPollTunerBacklog(t *testing.T) {
+	tuner := newPollTuner(10, 3)
+	assert.True(t, tuner.start())
+	assert.False(t, tuner.start())
+
+	// Full receives ask for more messages, up to the maximum...
+	sizes := []int{}
+	for i := 0; i < 5; i++ {
+		size := tuner.batchSize()
+		sizes = append(sizes, size)
+		tuner.observe(size, size, 0)
+	}
+	assert.Equal(t, []int{1, 2, 4, 8, 10}, sizes)
+
+	// ...then add pollers, up to the maximum.
+	assert.True(t, tuner.start())
+	assert.False(t, tuner.start())
+
+	tuner.observe(10, 10, 0)
+	tuner.observe(10, 10, 0)
+	assert.True(t, tuner.start())
+	assert.False(t, tuner.start())
+	assert.False(t, tuner.stop())
+}
+
+func TestPollTunerBacksOff(t *testing.T) {
+	tuner := newPollTuner(10, 2)
+	tuner.start()
+	for i := 0; i < 5; i++ {
+		tuner.observe(tuner.batchSize(), tuner.batchSize(), 0)
+	}
+	tuner.start()
+	assert.Equal(t, 10, tuner.batchSize())
+
+	// A filling channel means the Listener can't keep up.
+	tuner.observe(10, 10, 0.6)
+	assert.Equal(t, 5, tuner.batchSize())
+	assert.True(t, tuner.stop())
+	assert.False(t, tuner.stop())
+
+	// Partial receives leave things as they are.
+	tuner.observe(5, 3, 0)
+	assert.Equal(t, 5, tuner.batchSize())
+
+	// Empty receives back off down to one poller asking for one message.
+	for i := 0; i < 5; i++ {
+		tuner.observe(tuner.batchSize(), 0, 0)
+	}
+	assert.Equal(t, 1, tuner.batchSize())
+	assert.False(t, tuner.stop())
+}
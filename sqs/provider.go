@@ -1,12 +1,20 @@
 package sqs
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
 	"strconv"
+	"sync"
+	"time"
 
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awssns "github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	awssqs "github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	"github.com/researchsquare/gomainevents"
@@ -22,6 +30,29 @@ type Provider struct {
 	done              chan bool
 	debug             bool
 	maximumRetryCount int
+	jitter            gomainevents.Jitter
+	tuner             *pollTuner
+
+	// Puts chunked events back together, if every chunk reaches this
+	// instance. See Config.SingleConsumer.
+	chunks   *chunkAssembler
+	chunking bool
+
+	// Set for broadcast delivery. See Config.Delivery.
+	delivery  gomainevents.Delivery
+	snsClient SNSAPI
+	broadcast *broadcastQueue
+
+	// Created the first time an offloaded event is fetched, unless one
+	// was provided. See s3.
+	s3Mu     sync.Mutex
+	s3Client s3iface.S3API
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+
+	polledMu sync.Mutex
+	polledAt time.Time
 }
 
 type Config struct {
@@ -29,11 +60,56 @@ type Config struct {
 	// default AWS session + shared credentials.
 	SQSClient sqsiface.SQSAPI
 
-	// Specify the Queue URL. Required
+	// Specify the Queue URL. Required for competing consumers delivery
 	QueueURL string
 
+	// How events are shared between instances. Defaults to competing
+	// consumers: the instances poll QueueURL, and each event goes to one
+	// of them. With broadcast, each instance creates its own queue,
+	// subscribed to TopicARN, so that every instance gets every event.
+	// Stop unsubscribes and deletes the queue; the queues of instances
+	// that die without stopping have to be cleaned up separately, though
+	// they only keep messages for an hour.
+	Delivery gomainevents.Delivery
+
+	// Topic each instance's queue is subscribed to. Required for
+	// broadcast delivery
+	TopicARN string
+
+	// Provide your own SNS client for subscribing to TopicARN. Default
+	// will use the default AWS config, in the topic's region.
+	SNSClient SNSAPI
+
+	// Start of the names of the queues created for broadcast delivery,
+	// followed by the hostname and process ID. Defaults to gomainevents.
+	QueueNamePrefix string
+
 	// This specifies the maximum number of times an event should be retried
 	MaximumRetryCount int
+
+	// Randomizes retry delays, so that events that failed together aren't
+	// retried together. Defaults to none.
+	Jitter gomainevents.Jitter
+
+	// Provide your own S3 client for fetching events that were offloaded
+	// to S3 by the publisher. Default will use the default AWS session, in
+	// the SQS client's region, once an offloaded event is received.
+	S3Client s3iface.S3API
+
+	// Most messages asked for in one receive, up to 10. The provider starts
+	// at 1 and asks for more while there's a backlog. Defaults to 10.
+	MaxMessages int
+
+	// Most pollers receiving at once. The provider starts with one and adds
+	// more while receives come back full. Defaults to 4.
+	MaxPollers int
+
+	// Set when this is the only instance receiving from QueueURL. Events
+	// split into chunks by the SNS publisher, see sns.ChunkOversize, are
+	// only put back together with broadcast delivery or a single consumer,
+	// since every chunk of an event has to reach the same instance. Their
+	// chunks are otherwise left to go to the dead letter queue.
+	SingleConsumer bool
 }
 
 func NewProvider(config *Config) (*Provider, error) {
@@ -44,12 +120,66 @@ func NewProvider(config *Config) (*Provider, error) {
 	// Default to a new client using shared credentials
 	sqsClient := config.SQSClient
 	if nil == sqsClient {
-		sess := session.Must(session.NewSession())
+		sess, err := session.NewSession()
+		if err != nil {
+			return nil, err
+		}
+
 		sqsClient = awssqs.New(sess, &aws.Config{Region: aws.String("us-east-1")})
 	}
 
-	if "" == config.QueueURL {
-		return nil, errors.New("QueueURL is required")
+	delivery := config.Delivery
+	if gomainevents.DefaultDelivery == delivery {
+		delivery = gomainevents.CompetingConsumers
+	}
+
+	queueURL := config.QueueURL
+	var snsClient SNSAPI
+	var broadcast *broadcastQueue
+
+	switch delivery {
+	case gomainevents.CompetingConsumers:
+		if "" == queueURL {
+			return nil, errors.New("QueueURL is required")
+		}
+	case gomainevents.Broadcast:
+		if "" == config.TopicARN {
+			return nil, errors.New("TopicARN is required for broadcast delivery")
+		}
+
+		if "" != queueURL {
+			return nil, errors.New("QueueURL can't be used with broadcast delivery")
+		}
+
+		snsClient = config.SNSClient
+		if nil == snsClient {
+			region := topicRegion(config.TopicARN)
+			if "" == region {
+				return nil, fmt.Errorf("Unable to determine the region of topic %s", config.TopicARN)
+			}
+
+			cfg, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(region))
+			if err != nil {
+				return nil, err
+			}
+
+			snsClient = awssns.NewFromConfig(cfg)
+		}
+
+		prefix := config.QueueNamePrefix
+		if "" == prefix {
+			prefix = defaultQueueNamePrefix
+		}
+
+		queue, err := createBroadcastQueue(sqsClient, snsClient, config.TopicARN, instanceQueueName(prefix))
+		if err != nil {
+			return nil, err
+		}
+
+		queueURL = queue.queueURL
+		broadcast = queue
+	default:
+		return nil, fmt.Errorf("Delivery isn't supported: %s", delivery)
 	}
 
 	maximumRetryCount := defaultMaximumRetryCount
@@ -57,71 +187,182 @@ func NewProvider(config *Config) (*Provider, error) {
 		maximumRetryCount = config.MaximumRetryCount
 	}
 
+	maxMessages := defaultMaxMessages
+	if config.MaxMessages > 0 {
+		maxMessages = clamp(config.MaxMessages, 1, maxMessagesPerReceive)
+	}
+
+	maxPollers := defaultMaxPollers
+	if config.MaxPollers > 0 {
+		maxPollers = config.MaxPollers
+	}
+
 	return &Provider{
 		sqsClient: sqsClient,
-		queueURL:  config.QueueURL,
+		s3Client:  config.S3Client,
+		queueURL:  queueURL,
+		delivery:  delivery,
+		snsClient: snsClient,
+		broadcast: broadcast,
 
 		// Buffered channel makes it so that the listener will block while the channel is empty.
 		events:            make(chan gomainevents.Event, 100),
 		errors:            make(chan error, 1),
-		done:              make(chan bool, 1),
+		done:              make(chan bool),
 		debug:             true,
 		maximumRetryCount: maximumRetryCount,
+		jitter:            config.Jitter,
+		tuner:             newPollTuner(maxMessages, maxPollers),
+		chunks:            newChunkAssembler(),
+		chunking:          gomainevents.Broadcast == delivery || config.SingleConsumer,
 	}, nil
 }
 
-// Return a channel that can be used to retrieve events
+// Return a channel that can be used to retrieve events. Pollers are added
+// and removed as the queue and the Listener's pace change; see Config.
 func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
-	params := &awssqs.ReceiveMessageInput{
-		QueueUrl:              aws.String(p.queueURL),
-		WaitTimeSeconds:       aws.Int64(20),
-		MessageAttributeNames: aws.StringSlice([]string{"All"}),
-	}
-
 	p.debugPrint("Listening for events from %s\n", p.queueURL)
 
-	// This goroutine is non-blocking due to the bufferred events channel
-	go func() {
-		for {
-			select {
-			case <-p.done:
+	p.tuner.start()
+	go p.poll()
+
+	return p.events, p.errors
+}
+
+// poll receives messages until the provider is stopped or the tuner has no
+// more need for this poller, starting more pollers when it asks for them.
+func (p *Provider) poll() {
+	for {
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+
+		batchSize := p.tuner.batchSize()
+		resp, err := p.sqsClient.ReceiveMessage(&awssqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(p.queueURL),
+			WaitTimeSeconds:       aws.Int64(20),
+			MaxNumberOfMessages:   aws.Int64(int64(batchSize)),
+			AttributeNames:        aws.StringSlice([]string{"SentTimestamp", traceHeaderAttribute, messageGroupIDAttribute, sequenceNumberAttribute, deduplicationIDAttribute, receiveCountAttribute}),
+			MessageAttributeNames: aws.StringSlice([]string{"All"}),
+		})
+		if err != nil {
+			// Polling a queue that was deleted will never succeed.
+			if aerr, ok := err.(awserr.Error); ok && awssqs.ErrCodeQueueDoesNotExist == aerr.Code() {
+				p.reportFatal(err)
 				return
-			case err := <-p.errors:
-				p.debugPrint("Error: %s\n", err)
-			default:
-				resp, err := p.sqsClient.ReceiveMessage(params)
-				if err != nil {
-					p.errors <- err
-					continue
-				}
-
-				for _, msg := range resp.Messages {
-					event, err := DecodeEvent(p, msg)
-					if err != nil {
-						p.errors <- err
-						continue
-					}
-
-					p.events <- *event
-				}
 			}
+
+			p.reportError(gomainevents.PhaseReceive, "", err)
+			continue
 		}
-	}()
 
-	return p.events, p.errors
+		p.polled()
+
+		for _, msg := range resp.Messages {
+			event, err := DecodeEvent(p, msg)
+			if err == nil && nil != event.chunk {
+				event, err = p.assemble(*event)
+			}
+			if err != nil {
+				p.reportError(gomainevents.PhaseDecode, aws.StringValue(msg.MessageId), err)
+				continue
+			}
+
+			// Still waiting for the rest of the chunks
+			if nil == event {
+				continue
+			}
+
+			if !p.deliver(*event) {
+				return
+			}
+		}
+
+		p.tuner.observe(batchSize, len(resp.Messages), float64(len(p.events))/float64(cap(p.events)))
+
+		if p.tuner.stop() {
+			p.debugPrint("Stopping a poller\n")
+			return
+		}
+
+		for p.tuner.start() {
+			p.debugPrint("Starting another poller\n")
+			go p.poll()
+		}
+	}
+}
+
+// assemble holds on to a chunk until the rest of its event arrives,
+// returning the whole event once it has. Held chunks are kept from being
+// received again meanwhile.
+func (p *Provider) assemble(part Event) (*Event, error) {
+	if !p.chunking {
+		return nil, errors.New("Chunked events need broadcast delivery or a single consumer")
+	}
+
+	event, err := p.chunks.add(part)
+	if err != nil || nil != event {
+		return event, err
+	}
+
+	if err := p.updateVisibilityTimeout(part.receiptHandle, int64(chunkTimeout/time.Second)); err != nil {
+		p.reportError(gomainevents.PhaseReceive, part.MessageID(), err)
+	}
+
+	return nil, nil
+}
+
+// LastPolledAt returns when SQS last answered a poll, or zero if it
+// hasn't yet. See gomainevents.Probes.
+func (p *Provider) LastPolledAt() time.Time {
+	p.polledMu.Lock()
+	defer p.polledMu.Unlock()
+
+	return p.polledAt
+}
+
+func (p *Provider) polled() {
+	p.polledMu.Lock()
+	defer p.polledMu.Unlock()
+
+	p.polledAt = time.Now()
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
 }
 
 // Delete an event that we're done with
 func (p *Provider) Delete(event gomainevents.Event) {
 	evt := event.(Event) // Cast to SQS flavor
 
-	params := &awssqs.DeleteMessageInput{
-		QueueUrl:      aws.String(p.queueURL),
-		ReceiptHandle: aws.String(evt.ReceiptHandle()),
-	}
+	for _, message := range evt.messages() {
+		params := &awssqs.DeleteMessageInput{
+			QueueUrl:      aws.String(p.queueURL),
+			ReceiptHandle: aws.String(message.ReceiptHandle()),
+		}
 
-	if _, err := p.sqsClient.DeleteMessage(params); err != nil {
-		p.errors <- err
+		if _, err := p.sqsClient.DeleteMessage(params); err != nil {
+			p.reportError(gomainevents.PhaseDelete, message.MessageID(), err)
+		}
 	}
 }
 
@@ -133,36 +374,88 @@ func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEvent
 		return &RetryAttemptsExceededError{EventName: evt.Name()}
 	}
 
+	delay := p.jitter.Apply(time.Duration(evt.DelaySeconds()) * time.Second)
+
+	// Resending to a FIFO queue would put the event behind the rest of its
+	// group, or be dropped as a duplicate, so it's left where it is until
+	// the delay is up. The group is held back until then.
+	if "" != evt.MessageGroupID() {
+		p.debugPrint("Delaying event. Retries: %d, Delay: %s\n", evt.RetryCount()+1, delay)
+
+		if err := evt.UpdateVisibilityTimeout(int64(delay / time.Second)); err != nil {
+			p.reportError(gomainevents.PhaseRequeue, evt.MessageID(), err)
+		}
+
+		return nil
+	}
+
 	p.Delete(event)
 
 	retryCount := &awssqs.MessageAttributeValue{}
 	retryCount.SetStringValue(strconv.Itoa(evt.RetryCount() + 1))
 	retryCount.SetDataType("Number")
 
-	params := &awssqs.SendMessageInput{
-		QueueUrl:          aws.String(p.queueURL),
-		DelaySeconds:      aws.Int64(evt.DelaySeconds()),
-		MessageAttributes: map[string]*awssqs.MessageAttributeValue{"RetryCount": retryCount},
-		MessageBody:       aws.String(evt.EncodeEvent()),
-	}
+	attributes := map[string]*awssqs.MessageAttributeValue{"RetryCount": retryCount}
 
-	if nil != evt.DeduplicationID() {
-		params.MessageDeduplicationId = evt.DeduplicationID()
+	// Keep the retry in the same trace
+	if "" != evt.TraceHeader() {
+		traceHeader := &awssqs.MessageAttributeValue{}
+		traceHeader.SetStringValue(evt.TraceHeader())
+		traceHeader.SetDataType("String")
+		attributes[traceHeaderAttribute] = traceHeader
 	}
 
-	p.debugPrint("Requeuing event. Retries: %d, Delay: %d\n", evt.RetryCount()+1, evt.DelaySeconds())
-	if _, err := p.sqsClient.SendMessage(params); err != nil {
-		p.errors <- err
+	p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", evt.RetryCount()+1, delay)
+
+	// Chunked events are requeued as the chunks they were received as.
+	for _, message := range evt.messages() {
+		params := &awssqs.SendMessageInput{
+			QueueUrl:          aws.String(p.queueURL),
+			DelaySeconds:      aws.Int64(int64(delay / time.Second)),
+			MessageAttributes: attributes,
+			MessageBody:       aws.String(message.messageBody()),
+		}
+
+		if _, err := p.sqsClient.SendMessage(params); err != nil {
+			p.reportError(gomainevents.PhaseRequeue, message.MessageID(), err)
+		}
 	}
 
 	return nil
 }
 
+// Defer an event by extending its visibility timeout, so that it is
+// received again once delay has passed with the same retry count. Receiving
+// it again still counts towards the queue's redrive policy, and for FIFO
+// queues towards its retry count, which comes from the receive count.
+func (p *Provider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to SQS flavor
+
+	p.debugPrint("Deferring event. Delay: %s\n", delay)
+
+	return evt.UpdateVisibilityTimeout(int64(delay / time.Second))
+}
+
 // Stop the channel
 func (p *Provider) Stop() {
+	close(p.done)
+
+	p.closeMu.Lock()
 	close(p.events)
 	close(p.errors)
-	p.done <- true
+	p.closeMu.Unlock()
+
+	if nil != p.broadcast {
+		if err := p.broadcast.remove(p.sqsClient, p.snsClient); err != nil {
+			p.debugPrint("Error: %s\n", err)
+		}
+	}
+}
+
+// Delivery returns how events are shared between instances. See
+// Config.Delivery.
+func (p *Provider) Delivery() gomainevents.Delivery {
+	return p.delivery
 }
 
 func (p *Provider) updateVisibilityTimeout(receiptHandle string, newTimeout int64) error {
@@ -177,6 +470,51 @@ func (p *Provider) updateVisibilityTimeout(receiptHandle string, newTimeout int6
 	return err
 }
 
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, p.queueURL, messageID, err):
+	default:
+	}
+}
+
+// reportFatal passes on an error the provider can't recover from, waiting
+// for it to be read, since the Listener has to see it to shut down.
+func (p *Provider) reportFatal(err error) {
+	p.debugPrint("Fatal error: %s\n", err)
+
+	providerErr := gomainevents.NewProviderError(gomainevents.PhaseReceive, p.queueURL, "", err)
+	providerErr.Retryable = false
+	providerErr.Fatal = true
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- providerErr:
+	}
+}
+
 func (p *Provider) debugPrint(format string, values ...interface{}) {
 	if p.debug {
 		log.Printf("[gomainevents-sqs] "+format, values...)
@@ -1,13 +1,18 @@
 package sqs
 
 import (
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	awssqs "github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/researchsquare/gomainevents"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockSQS struct {
@@ -61,7 +66,7 @@ func TestStart(t *testing.T) {
 			&awssqs.Message{
 				ReceiptHandle: aws.String("Hello!"),
 				Attributes: aws.StringMap(map[string]string{
-					"DeduplicationID": "1234",
+					"MessageDeduplicationId": "1234",
 				}),
 				MessageAttributes: map[string]*awssqs.MessageAttributeValue{
 					"RetryCount": &awssqs.MessageAttributeValue{
@@ -74,7 +79,7 @@ func TestStart(t *testing.T) {
 			&awssqs.Message{
 				ReceiptHandle: aws.String("Goodbye!"),
 				Attributes: aws.StringMap(map[string]string{
-					"DeduplicationID": "4321",
+					"MessageDeduplicationId": "4321",
 				}),
 				MessageAttributes: map[string]*awssqs.MessageAttributeValue{
 					"RetryCount": &awssqs.MessageAttributeValue{
@@ -107,3 +112,235 @@ func TestStart(t *testing.T) {
 		provider.Stop()
 	}()
 }
+
+// backlogSQS has an endless backlog, returning as many messages as are asked
+// for, and records the receives.
+type backlogSQS struct {
+	sqsiface.SQSAPI
+
+	mu         sync.Mutex
+	batchSizes []int64
+	inFlight   int
+	concurrent int
+}
+
+func (m *backlogSQS) ReceiveMessage(in *awssqs.ReceiveMessageInput) (*awssqs.ReceiveMessageOutput, error) {
+	m.mu.Lock()
+	m.batchSizes = append(m.batchSizes, aws.Int64Value(in.MaxNumberOfMessages))
+	m.inFlight++
+	if m.inFlight > m.concurrent {
+		m.concurrent = m.inFlight
+	}
+	m.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	m.mu.Lock()
+	m.inFlight--
+	m.mu.Unlock()
+
+	out := &awssqs.ReceiveMessageOutput{}
+	for i := int64(0); i < aws.Int64Value(in.MaxNumberOfMessages); i++ {
+		out.Messages = append(out.Messages, &awssqs.Message{
+			ReceiptHandle: aws.String("handle"),
+			Body:          aws.String(`{"Message":"{\"name\":\"Domain\\\\Event\",\"data\":{}}"}`),
+		})
+	}
+
+	return out, nil
+}
+
+func TestStartAdaptsToBacklog(t *testing.T) {
+	client := &backlogSQS{}
+	provider, _ := NewProvider(&Config{
+		SQSClient:   client,
+		QueueURL:    "queueueueueueue",
+		MaxMessages: 4,
+		MaxPollers:  3,
+	})
+	provider.debug = false
+	assert.True(t, provider.LastPolledAt().IsZero())
+
+	events, _ := provider.Start()
+
+	// Keep up with the events until every poller has started.
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		<-events
+
+		client.mu.Lock()
+		concurrent := client.concurrent
+		client.mu.Unlock()
+
+		if concurrent == 3 {
+			break
+		}
+	}
+
+	provider.Stop()
+	assert.False(t, provider.LastPolledAt().IsZero())
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	assert.Equal(t, []int64{1, 2, 4}, client.batchSizes[:3])
+	for _, size := range client.batchSizes {
+		assert.True(t, size <= 4)
+	}
+	assert.Equal(t, 3, client.concurrent)
+}
+
+// mockRequeuer records the messages events are requeued with.
+type mockRequeuer struct {
+	mockSender
+}
+
+func (m *mockRequeuer) DeleteMessage(in *awssqs.DeleteMessageInput) (*awssqs.DeleteMessageOutput, error) {
+	return &awssqs.DeleteMessageOutput{}, nil
+}
+
+func TestRequeueWithJitter(t *testing.T) {
+	client := &mockRequeuer{}
+	provider, err := NewProvider(&Config{
+		SQSClient: client,
+		QueueURL:  "queueueueueueue",
+		Jitter:    gomainevents.EqualJitter,
+	})
+	require.Nil(t, err)
+	provider.debug = false
+
+	// Backs off 32 seconds without jitter
+	event := Event{name: "Domain\\Event", provider: provider, receiptHandle: "handle", retryCount: 4}
+
+	for i := 0; i < 20; i++ {
+		assert.Nil(t, provider.Requeue(event))
+	}
+
+	require.Len(t, client.sent, 20)
+	for _, sent := range client.sent {
+		delay := aws.Int64Value(sent.DelaySeconds)
+		assert.True(t, delay >= 16 && delay <= 32, delay)
+	}
+}
+
+// fifoSQS records what's done with the messages of a FIFO queue.
+type fifoSQS struct {
+	chunkSQS
+	visibility []*awssqs.ChangeMessageVisibilityInput
+}
+
+func (m *fifoSQS) ChangeMessageVisibility(in *awssqs.ChangeMessageVisibilityInput) (*awssqs.ChangeMessageVisibilityOutput, error) {
+	m.visibility = append(m.visibility, in)
+	return &awssqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func TestRequeueDelaysFIFOEventsInPlace(t *testing.T) {
+	client := &fifoSQS{}
+	provider, err := NewProvider(&Config{SQSClient: client, QueueURL: "queueueueueueue.fifo", MaximumRetryCount: 3})
+	require.Nil(t, err)
+	provider.debug = false
+
+	message := &awssqs.Message{
+		ReceiptHandle: aws.String("handle"),
+		Attributes: aws.StringMap(map[string]string{
+			"MessageGroupId":          "order-1",
+			"SequenceNumber":          "18850000000000000100",
+			"MessageDeduplicationId":  "order-1-placed",
+			"ApproximateReceiveCount": "3",
+		}),
+		Body: aws.String(`{"Message":"{\"name\":\"Domain\\\\Event\",\"data\":{}}"}`),
+	}
+
+	event, err := DecodeEvent(provider, message)
+	require.Nil(t, err)
+	assert.Equal(t, "order-1", event.MessageGroupID())
+	assert.Equal(t, "18850000000000000100", event.SequenceNumber())
+	assert.Equal(t, "order-1-placed", aws.StringValue(event.DeduplicationID()))
+
+	// SQS counts the retries, since the message is never resent
+	assert.Equal(t, 2, event.RetryCount())
+
+	group, sequenceNumber, ok := gomainevents.Sequence(*event)
+	assert.True(t, ok)
+	assert.Equal(t, "order-1", group)
+	assert.Equal(t, "18850000000000000100", sequenceNumber)
+
+	require.Nil(t, provider.Requeue(*event))
+
+	// The message stays put, keeping its place and sequence number
+	assert.Empty(t, client.deleted)
+	assert.Empty(t, client.sent)
+	require.Len(t, client.visibility, 1)
+	assert.Equal(t, "handle", aws.StringValue(client.visibility[0].ReceiptHandle))
+	assert.Equal(t, int64(8), aws.Int64Value(client.visibility[0].VisibilityTimeout))
+
+	message.Attributes["ApproximateReceiveCount"] = aws.String("5")
+	event, err = DecodeEvent(provider, message)
+	require.Nil(t, err)
+	assert.IsType(t, &RetryAttemptsExceededError{}, provider.Requeue(*event))
+}
+
+func TestDeferExtendsVisibility(t *testing.T) {
+	client := &fifoSQS{}
+	provider, err := NewProvider(&Config{SQSClient: client, QueueURL: "queueueueueueue"})
+	require.Nil(t, err)
+	provider.debug = false
+
+	event, err := DecodeEvent(provider, &awssqs.Message{
+		ReceiptHandle:     aws.String("handle"),
+		Body:              aws.String(`{"Message":"{\"name\":\"Domain\\\\Event\",\"data\":{}}"}`),
+		MessageAttributes: map[string]*awssqs.MessageAttributeValue{"RetryCount": {DataType: aws.String("Number"), StringValue: aws.String("2")}},
+	})
+	require.Nil(t, err)
+
+	var deferring gomainevents.DeferringProvider = provider
+	require.Nil(t, deferring.Defer(*event, time.Minute))
+
+	assert.Empty(t, client.deleted)
+	assert.Empty(t, client.sent)
+	require.Len(t, client.visibility, 1)
+	assert.Equal(t, "handle", aws.StringValue(client.visibility[0].ReceiptHandle))
+	assert.Equal(t, int64(60), aws.Int64Value(client.visibility[0].VisibilityTimeout))
+	assert.Equal(t, 2, event.RetryCount())
+}
+
+// missingQueueSQS fails every receive because the queue doesn't exist.
+type missingQueueSQS struct {
+	sqsiface.SQSAPI
+}
+
+func (m missingQueueSQS) ReceiveMessage(in *awssqs.ReceiveMessageInput) (*awssqs.ReceiveMessageOutput, error) {
+	return nil, awserr.New(awssqs.ErrCodeQueueDoesNotExist, "The specified queue does not exist", nil)
+}
+
+func TestStartReportsMissingQueueAsFatal(t *testing.T) {
+	provider, _ := NewProvider(&Config{
+		SQSClient: missingQueueSQS{},
+		QueueURL:  "queueueueueueue",
+	})
+	provider.debug = false
+
+	listener := gomainevents.NewListener(provider)
+	listener.RegisterHandler("Domain\\Event", func(gomainevents.Event) error { return nil })
+
+	err := listener.Listen()
+	require.IsType(t, &gomainevents.ProviderError{}, err)
+	assert.True(t, err.(*gomainevents.ProviderError).Fatal)
+	assert.Equal(t, gomainevents.PhaseReceive, err.(*gomainevents.ProviderError).Phase)
+}
+
+func TestReportErrorAfterStop(t *testing.T) {
+	provider, _ := NewProvider(&Config{
+		SQSClient: &mockSQS{},
+		QueueURL:  "queueueueueueue",
+	})
+	provider.debug = false
+	provider.Stop()
+
+	assert.NotPanics(t, func() {
+		for i := 0; i < 100; i++ {
+			provider.reportError(gomainevents.PhaseDelete, "", errors.New("Failed"))
+		}
+		provider.reportFatal(errors.New("Failed"))
+	})
+}
@@ -0,0 +1,95 @@
+package sqs
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/researchsquare/gomainevents"
+)
+
+// Publisher sends events straight to a queue, encoded the same way as
+// events that arrive through an SNS subscription, so that the Provider
+// reads them either way.
+type Publisher struct {
+	sqsClient      sqsiface.SQSAPI
+	queueURL       string
+	messageGroupID string
+	attributes     func(gomainevents.Event) map[string]string
+}
+
+type PublisherConfig struct {
+	// Provide your own SQS client. Default will use the
+	// default AWS session + shared credentials.
+	SQSClient sqsiface.SQSAPI
+
+	// Specify the Queue URL. Required
+	QueueURL string
+
+	// Message group for FIFO queues. Required for FIFO queues, which
+	// should also have ContentBasedDeduplication enabled.
+	MessageGroupID string
+
+	// Derives String message attributes from each event, e.g. to pass on
+	// a trace header. Optional
+	MessageAttributes func(gomainevents.Event) map[string]string
+}
+
+func NewPublisher(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.QueueURL {
+		return nil, errors.New("QueueURL is required")
+	}
+
+	// Default to a new client using shared credentials
+	sqsClient := config.SQSClient
+	if nil == sqsClient {
+		sess := session.Must(session.NewSession())
+		sqsClient = awssqs.New(sess, &aws.Config{Region: aws.String("us-east-1")})
+	}
+
+	return &Publisher{
+		sqsClient:      sqsClient,
+		queueURL:       config.QueueURL,
+		messageGroupID: config.MessageGroupID,
+		attributes:     config.MessageAttributes,
+	}, nil
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	evt := &Event{
+		name: event.Name(),
+		data: event.Data(),
+	}
+
+	params := &awssqs.SendMessageInput{
+		QueueUrl:    aws.String(p.queueURL),
+		MessageBody: aws.String(evt.EncodeEvent()),
+	}
+
+	if "" != p.messageGroupID {
+		params.MessageGroupId = aws.String(p.messageGroupID)
+	}
+
+	if nil != p.attributes {
+		for key, value := range p.attributes(event) {
+			if nil == params.MessageAttributes {
+				params.MessageAttributes = map[string]*awssqs.MessageAttributeValue{}
+			}
+
+			params.MessageAttributes[key] = &awssqs.MessageAttributeValue{
+				DataType:    aws.String("String"),
+				StringValue: aws.String(value),
+			}
+		}
+	}
+
+	_, err := p.sqsClient.SendMessage(params)
+
+	return err
+}
@@ -0,0 +1,95 @@
+package sqs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSender struct {
+	sqsiface.SQSAPI
+	sent []*awssqs.SendMessageInput
+}
+
+func (m *mockSender) SendMessage(in *awssqs.SendMessageInput) (*awssqs.SendMessageOutput, error) {
+	m.sent = append(m.sent, in)
+	return &awssqs.SendMessageOutput{}, nil
+}
+
+type testEvent struct{}
+
+func (e testEvent) Name() string {
+	return "Domain\\Event"
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{SQSClient: &mockSender{}})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{SQSClient: &mockSender{}, QueueURL: "queueueueueueue"})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+}
+
+func TestPublisherPublish(t *testing.T) {
+	client := &mockSender{}
+	publisher, _ := NewPublisher(&PublisherConfig{
+		SQSClient:      client,
+		QueueURL:       "queueueueueueue",
+		MessageGroupID: "group",
+	})
+
+	require.Nil(t, publisher.Publish(testEvent{}))
+	require.Len(t, client.sent, 1)
+	assert.Equal(t, "queueueueueueue", aws.StringValue(client.sent[0].QueueUrl))
+	assert.Equal(t, "group", aws.StringValue(client.sent[0].MessageGroupId))
+
+	// The provider reads what the publisher sends
+	event, err := DecodeEvent(&Provider{}, &awssqs.Message{
+		ReceiptHandle: aws.String("Hello!"),
+		Body:          client.sent[0].MessageBody,
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, "Domain\\Event", event.Name())
+	assert.Equal(t, "2018-03-08 11:11:11", event.Data()["occurredOn"])
+}
+
+func TestPublisherPublishMessageAttributes(t *testing.T) {
+	client := &mockSender{}
+	publisher, _ := NewPublisher(&PublisherConfig{
+		SQSClient: client,
+		QueueURL:  "queueueueueueue",
+		MessageAttributes: func(event gomainevents.Event) map[string]string {
+			return map[string]string{"AWSTraceHeader": "Root=1-5759e988-bd862e3fe1be46a994272793"}
+		},
+	})
+
+	require.Nil(t, publisher.Publish(testEvent{}))
+	require.Len(t, client.sent, 1)
+	assert.Nil(t, client.sent[0].MessageGroupId)
+
+	// The provider picks the attribute back up
+	event, err := DecodeEvent(&Provider{}, &awssqs.Message{
+		ReceiptHandle:     aws.String("Hello!"),
+		MessageAttributes: client.sent[0].MessageAttributes,
+		Body:              client.sent[0].MessageBody,
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793", event.TraceHeader())
+}
@@ -0,0 +1,384 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+const (
+	defaultRedriveVisibilityTimeout = 5 * time.Minute
+	redriveWaitTimeSeconds          = 2
+
+	// maxBatchSize is the most messages SQS hands out per receive, and the
+	// most entries it accepts per batch call.
+	maxBatchSize = 10
+)
+
+// Redriver moves messages from a dead letter queue back to the queue they
+// came from, once whatever made them fail has been fixed. Moved messages
+// start over with no retries. Messages that are left behind, because they
+// were filtered out or because it's a dry run, are hidden while the redrive
+// runs and made visible again when it's done.
+type Redriver struct {
+	sqsClient  sqsiface.SQSAPI
+	provider   *Provider
+	deadLetter string
+	queueURL   string
+
+	eventNames        map[string]bool
+	interval          time.Duration
+	maxMessages       int
+	visibilityTimeout time.Duration
+	dryRun            bool
+	onMessage         func(RedriveMessage)
+
+	sleep func(context.Context, time.Duration) error
+}
+
+type RedriveConfig struct {
+	// Provide your own SQS client. Default will use the
+	// default AWS session + shared credentials.
+	SQSClient sqsiface.SQSAPI
+
+	// Provide your own S3 client for reading events that were offloaded to
+	// S3. Default will use the default AWS session, in the SQS client's
+	// region.
+	S3Client s3iface.S3API
+
+	// Queue to move messages from. Required
+	DeadLetterQueueURL string
+
+	// Queue to move messages to. Required
+	QueueURL string
+
+	// Only move events with these names. Defaults to every message,
+	// including ones that can't be decoded.
+	EventNames []string
+
+	// Most messages moved per second. Defaults to no limit.
+	RatePerSecond float64
+
+	// Stop after moving this many messages. Defaults to no limit.
+	MaxMessages int
+
+	// How long messages stay hidden from other consumers of the dead letter
+	// queue while the redrive runs. Defaults to 5 minutes.
+	VisibilityTimeout time.Duration
+
+	// Report what would be moved without moving anything.
+	DryRun bool
+
+	// Called for each message looked at. Optional
+	OnMessage func(RedriveMessage)
+}
+
+// RedriveMessage describes a message looked at by a redrive.
+type RedriveMessage struct {
+	MessageID string
+
+	// Name of the event, or empty if the message couldn't be decoded.
+	EventName string
+
+	// Why the message couldn't be decoded, if it couldn't.
+	Err error
+
+	// Whether the message was moved, or would have been in a dry run.
+	Moved bool
+}
+
+// RedriveResult counts the messages looked at by a redrive.
+type RedriveResult struct {
+	// Messages moved, or that would have been in a dry run.
+	Moved int
+
+	// Messages left on the dead letter queue.
+	Skipped int
+}
+
+func NewRedriver(config *RedriveConfig) (*Redriver, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.DeadLetterQueueURL {
+		return nil, errors.New("DeadLetterQueueURL is required")
+	}
+
+	if "" == config.QueueURL {
+		return nil, errors.New("QueueURL is required")
+	}
+
+	if config.RatePerSecond < 0 {
+		return nil, errors.New("RatePerSecond can't be negative")
+	}
+
+	// Default to a new client using shared credentials
+	sqsClient := config.SQSClient
+	if nil == sqsClient {
+		sess, err := session.NewSession()
+		if err != nil {
+			return nil, err
+		}
+
+		sqsClient = awssqs.New(sess, &aws.Config{Region: aws.String("us-east-1")})
+	}
+
+	// Decoding goes through a provider so that offloaded events are followed.
+	provider, err := NewProvider(&Config{
+		SQSClient: sqsClient,
+		S3Client:  config.S3Client,
+		QueueURL:  config.DeadLetterQueueURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	eventNames := map[string]bool{}
+	for _, name := range config.EventNames {
+		eventNames[name] = true
+	}
+
+	var interval time.Duration
+	if config.RatePerSecond > 0 {
+		interval = time.Duration(float64(time.Second) / config.RatePerSecond)
+	}
+
+	visibilityTimeout := defaultRedriveVisibilityTimeout
+	if config.VisibilityTimeout > 0 {
+		visibilityTimeout = config.VisibilityTimeout
+	}
+
+	return &Redriver{
+		sqsClient:         sqsClient,
+		provider:          provider,
+		deadLetter:        config.DeadLetterQueueURL,
+		queueURL:          config.QueueURL,
+		eventNames:        eventNames,
+		interval:          interval,
+		maxMessages:       config.MaxMessages,
+		visibilityTimeout: visibilityTimeout,
+		dryRun:            config.DryRun,
+		onMessage:         config.OnMessage,
+		sleep:             sleep,
+	}, nil
+}
+
+// Run moves messages until the dead letter queue has nothing more to give,
+// MaxMessages have been moved or the context is cancelled. The result
+// counts what was done even if an error stops the redrive part way.
+func (r *Redriver) Run(ctx context.Context) (RedriveResult, error) {
+	result := RedriveResult{}
+
+	// Receipt handles of the messages left behind, by message ID.
+	held := map[string]string{}
+
+	err := r.run(ctx, &result, held)
+
+	if releaseErr := r.release(held); err == nil {
+		err = releaseErr
+	}
+
+	return result, err
+}
+
+func (r *Redriver) run(ctx context.Context, result *RedriveResult, held map[string]string) error {
+	var last time.Time
+
+	for {
+		if ctx.Err() != nil || r.done(result) {
+			return nil
+		}
+
+		resp, err := r.sqsClient.ReceiveMessage(&awssqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(r.deadLetter),
+			MaxNumberOfMessages:   aws.Int64(maxBatchSize),
+			VisibilityTimeout:     aws.Int64(int64(r.visibilityTimeout / time.Second)),
+			WaitTimeSeconds:       aws.Int64(redriveWaitTimeSeconds),
+			AttributeNames:        aws.StringSlice([]string{"All"}),
+			MessageAttributeNames: aws.StringSlice([]string{"All"}),
+		})
+		if err != nil {
+			return err
+		}
+
+		fresh := 0
+		for i, message := range resp.Messages {
+			id := aws.StringValue(message.MessageId)
+			if _, seen := held[id]; seen {
+				// Keep the newest receipt handle, the only one that can
+				// release the message.
+				held[id] = aws.StringValue(message.ReceiptHandle)
+				continue
+			}
+
+			fresh++
+
+			if r.done(result) || !r.wanted(message) {
+				held[id] = aws.StringValue(message.ReceiptHandle)
+				result.Skipped++
+				continue
+			}
+
+			if r.dryRun {
+				held[id] = aws.StringValue(message.ReceiptHandle)
+				result.Moved++
+				continue
+			}
+
+			if r.interval > 0 && !last.IsZero() {
+				if err := r.sleep(ctx, r.interval-time.Since(last)); err != nil {
+					hold(held, resp.Messages[i:])
+					return nil
+				}
+			}
+			last = time.Now()
+
+			if err := r.move(message); err != nil {
+				hold(held, resp.Messages[i:])
+				return err
+			}
+
+			result.Moved++
+		}
+
+		if fresh == 0 {
+			return nil
+		}
+	}
+}
+
+// hold keeps track of messages to release at the end.
+func hold(held map[string]string, messages []*awssqs.Message) {
+	for _, message := range messages {
+		held[aws.StringValue(message.MessageId)] = aws.StringValue(message.ReceiptHandle)
+	}
+}
+
+// done reports whether enough messages have been moved.
+func (r *Redriver) done(result *RedriveResult) bool {
+	return r.maxMessages > 0 && result.Moved >= r.maxMessages
+}
+
+// wanted reports whether a message passes the event name filter, and
+// reports it either way.
+func (r *Redriver) wanted(message *awssqs.Message) bool {
+	report := RedriveMessage{MessageID: aws.StringValue(message.MessageId)}
+
+	event, err := DecodeEvent(r.provider, message)
+	if err != nil {
+		report.Err = err
+	} else {
+		report.EventName = event.Name()
+	}
+
+	report.Moved = len(r.eventNames) == 0 || (nil == err && r.eventNames[event.Name()])
+
+	if nil != r.onMessage {
+		r.onMessage(report)
+	}
+
+	return report.Moved
+}
+
+// move sends a copy of a message to the queue, without its retry count,
+// then deletes it from the dead letter queue.
+func (r *Redriver) move(message *awssqs.Message) error {
+	attributes := map[string]*awssqs.MessageAttributeValue{}
+	for name, value := range message.MessageAttributes {
+		if "RetryCount" != name {
+			attributes[name] = value
+		}
+	}
+
+	params := &awssqs.SendMessageInput{
+		QueueUrl:    aws.String(r.queueURL),
+		MessageBody: message.Body,
+	}
+	if len(attributes) > 0 {
+		params.MessageAttributes = attributes
+	}
+
+	// FIFO queues need the message group, and a deduplication ID that
+	// doesn't match the message's earlier trip through the queue.
+	if groupID, ok := message.Attributes["MessageGroupId"]; ok {
+		params.MessageGroupId = groupID
+		params.MessageDeduplicationId = message.MessageId
+	}
+
+	if _, err := r.sqsClient.SendMessage(params); err != nil {
+		return err
+	}
+
+	_, err := r.sqsClient.DeleteMessage(&awssqs.DeleteMessageInput{
+		QueueUrl:      aws.String(r.deadLetter),
+		ReceiptHandle: message.ReceiptHandle,
+	})
+	if err != nil {
+		return fmt.Errorf("Message %s was moved but not deleted from the dead letter queue: %s", aws.StringValue(message.MessageId), err)
+	}
+
+	return nil
+}
+
+// release makes the messages left behind visible again.
+func (r *Redriver) release(held map[string]string) error {
+	entries := []*awssqs.ChangeMessageVisibilityBatchRequestEntry{}
+	for _, receiptHandle := range held {
+		entries = append(entries, &awssqs.ChangeMessageVisibilityBatchRequestEntry{
+			Id:                aws.String(strconv.Itoa(len(entries))),
+			ReceiptHandle:     aws.String(receiptHandle),
+			VisibilityTimeout: aws.Int64(0),
+		})
+	}
+
+	failed := 0
+	for start := 0; start < len(entries); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		resp, err := r.sqsClient.ChangeMessageVisibilityBatch(&awssqs.ChangeMessageVisibilityBatchInput{
+			QueueUrl: aws.String(r.deadLetter),
+			Entries:  entries[start:end],
+		})
+		if err != nil {
+			return err
+		}
+
+		failed += len(resp.Failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Could not release %d messages. They will be visible again after the visibility timeout", failed)
+	}
+
+	return nil
+}
+
+// sleep waits for d, returning early with the context's error if it is
+// cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
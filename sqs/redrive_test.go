@@ -0,0 +1,240 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockDeadLetterQueue hands out its messages in order, as a queue does while
+// received messages stay invisible, and records what is done with them.
+type mockDeadLetterQueue struct {
+	sqsiface.SQSAPI
+	messages []*awssqs.Message
+	next     int
+	sent     []*awssqs.SendMessageInput
+	deleted  []string
+	released []string
+	sendErr  error
+}
+
+func newMockDeadLetterQueue(names ...string) *mockDeadLetterQueue {
+	q := &mockDeadLetterQueue{}
+	for i, name := range names {
+		id := fmt.Sprintf("message-%d", i)
+		retryCount := &awssqs.MessageAttributeValue{}
+		retryCount.SetStringValue("26")
+		retryCount.SetDataType("Number")
+
+		q.messages = append(q.messages, &awssqs.Message{
+			MessageId:         aws.String(id),
+			ReceiptHandle:     aws.String("receipt-" + id),
+			Body:              aws.String(fmt.Sprintf(`{"Message":"{\"name\":\"%s\",\"data\":{}}"}`, name)),
+			MessageAttributes: map[string]*awssqs.MessageAttributeValue{"RetryCount": retryCount},
+		})
+	}
+
+	return q
+}
+
+func (q *mockDeadLetterQueue) ReceiveMessage(in *awssqs.ReceiveMessageInput) (*awssqs.ReceiveMessageOutput, error) {
+	end := q.next + int(aws.Int64Value(in.MaxNumberOfMessages))
+	if end > len(q.messages) {
+		end = len(q.messages)
+	}
+
+	messages := q.messages[q.next:end]
+	q.next = end
+
+	return &awssqs.ReceiveMessageOutput{Messages: messages}, nil
+}
+
+func (q *mockDeadLetterQueue) SendMessage(in *awssqs.SendMessageInput) (*awssqs.SendMessageOutput, error) {
+	if nil != q.sendErr {
+		return nil, q.sendErr
+	}
+
+	q.sent = append(q.sent, in)
+	return &awssqs.SendMessageOutput{}, nil
+}
+
+func (q *mockDeadLetterQueue) DeleteMessage(in *awssqs.DeleteMessageInput) (*awssqs.DeleteMessageOutput, error) {
+	q.deleted = append(q.deleted, aws.StringValue(in.ReceiptHandle))
+	return &awssqs.DeleteMessageOutput{}, nil
+}
+
+func (q *mockDeadLetterQueue) ChangeMessageVisibilityBatch(in *awssqs.ChangeMessageVisibilityBatchInput) (*awssqs.ChangeMessageVisibilityBatchOutput, error) {
+	for _, entry := range in.Entries {
+		q.released = append(q.released, aws.StringValue(entry.ReceiptHandle))
+	}
+
+	return &awssqs.ChangeMessageVisibilityBatchOutput{}, nil
+}
+
+func TestNewRedriver(t *testing.T) {
+	redriver, err := NewRedriver(nil)
+	assert.Nil(t, redriver)
+	assert.NotNil(t, err)
+
+	redriver, err = NewRedriver(&RedriveConfig{SQSClient: &mockDeadLetterQueue{}, QueueURL: "queue"})
+	assert.Nil(t, redriver)
+	assert.NotNil(t, err)
+
+	redriver, err = NewRedriver(&RedriveConfig{SQSClient: &mockDeadLetterQueue{}, DeadLetterQueueURL: "dlq"})
+	assert.Nil(t, redriver)
+	assert.NotNil(t, err)
+
+	redriver, err = NewRedriver(&RedriveConfig{SQSClient: &mockDeadLetterQueue{}, DeadLetterQueueURL: "dlq", QueueURL: "queue", RatePerSecond: -1})
+	assert.Nil(t, redriver)
+	assert.NotNil(t, err)
+
+	redriver, err = NewRedriver(&RedriveConfig{SQSClient: &mockDeadLetterQueue{}, DeadLetterQueueURL: "dlq", QueueURL: "queue"})
+	assert.NotNil(t, redriver)
+	assert.Nil(t, err)
+}
+
+func TestRedriveMovesEverything(t *testing.T) {
+	names := []string{}
+	for i := 0; i < 12; i++ {
+		names = append(names, "Domain\\Event")
+	}
+
+	queue := newMockDeadLetterQueue(names...)
+	redriver, _ := NewRedriver(&RedriveConfig{
+		SQSClient:          queue,
+		DeadLetterQueueURL: "dlq",
+		QueueURL:           "queue",
+	})
+
+	result, err := redriver.Run(context.Background())
+
+	require.Nil(t, err)
+	assert.Equal(t, RedriveResult{Moved: 12}, result)
+	assert.Len(t, queue.sent, 12)
+	assert.Len(t, queue.deleted, 12)
+	assert.Empty(t, queue.released)
+
+	// Moved events start over with no retries
+	assert.Equal(t, "queue", aws.StringValue(queue.sent[0].QueueUrl))
+	assert.Equal(t, queue.messages[0].Body, queue.sent[0].MessageBody)
+	assert.NotContains(t, queue.sent[0].MessageAttributes, "RetryCount")
+}
+
+func TestRedriveFiltersByName(t *testing.T) {
+	queue := newMockDeadLetterQueue("Created", "Updated", "Created")
+	queue.messages = append(queue.messages, &awssqs.Message{
+		MessageId:     aws.String("garbage"),
+		ReceiptHandle: aws.String("receipt-garbage"),
+		Body:          aws.String("not json"),
+	})
+
+	reports := []RedriveMessage{}
+	redriver, _ := NewRedriver(&RedriveConfig{
+		SQSClient:          queue,
+		DeadLetterQueueURL: "dlq",
+		QueueURL:           "queue",
+		EventNames:         []string{"Created"},
+		OnMessage: func(message RedriveMessage) {
+			reports = append(reports, message)
+		},
+	})
+
+	result, err := redriver.Run(context.Background())
+
+	require.Nil(t, err)
+	assert.Equal(t, RedriveResult{Moved: 2, Skipped: 2}, result)
+	assert.Equal(t, []string{"receipt-message-0", "receipt-message-2"}, queue.deleted)
+
+	sort.Strings(queue.released)
+	assert.Equal(t, []string{"receipt-garbage", "receipt-message-1"}, queue.released)
+
+	require.Len(t, reports, 4)
+	assert.Equal(t, RedriveMessage{MessageID: "message-1", EventName: "Updated"}, reports[1])
+	assert.NotNil(t, reports[3].Err)
+	assert.False(t, reports[3].Moved)
+}
+
+func TestRedriveDryRun(t *testing.T) {
+	queue := newMockDeadLetterQueue("Created", "Updated")
+	redriver, _ := NewRedriver(&RedriveConfig{
+		SQSClient:          queue,
+		DeadLetterQueueURL: "dlq",
+		QueueURL:           "queue",
+		DryRun:             true,
+	})
+
+	result, err := redriver.Run(context.Background())
+
+	require.Nil(t, err)
+	assert.Equal(t, RedriveResult{Moved: 2}, result)
+	assert.Empty(t, queue.sent)
+	assert.Empty(t, queue.deleted)
+	assert.Len(t, queue.released, 2)
+}
+
+func TestRedriveStopsAtMax(t *testing.T) {
+	queue := newMockDeadLetterQueue("A", "B", "C")
+	redriver, _ := NewRedriver(&RedriveConfig{
+		SQSClient:          queue,
+		DeadLetterQueueURL: "dlq",
+		QueueURL:           "queue",
+		MaxMessages:        2,
+	})
+
+	result, err := redriver.Run(context.Background())
+
+	require.Nil(t, err)
+	assert.Equal(t, RedriveResult{Moved: 2, Skipped: 1}, result)
+	assert.Equal(t, []string{"receipt-message-2"}, queue.released)
+}
+
+func TestRedriveRateLimits(t *testing.T) {
+	queue := newMockDeadLetterQueue("A", "B", "C")
+	redriver, _ := NewRedriver(&RedriveConfig{
+		SQSClient:          queue,
+		DeadLetterQueueURL: "dlq",
+		QueueURL:           "queue",
+		RatePerSecond:      4,
+	})
+
+	waits := []time.Duration{}
+	redriver.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	_, err := redriver.Run(context.Background())
+
+	require.Nil(t, err)
+	require.Len(t, waits, 2)
+	assert.InDelta(t, float64(250*time.Millisecond), float64(waits[0]), float64(50*time.Millisecond))
+}
+
+func TestRedriveStopsOnSendError(t *testing.T) {
+	queue := newMockDeadLetterQueue("A", "B")
+	queue.sendErr = fmt.Errorf("Access denied")
+
+	redriver, _ := NewRedriver(&RedriveConfig{
+		SQSClient:          queue,
+		DeadLetterQueueURL: "dlq",
+		QueueURL:           "queue",
+	})
+
+	result, err := redriver.Run(context.Background())
+
+	assert.NotNil(t, err)
+	assert.Equal(t, RedriveResult{}, result)
+	assert.Empty(t, queue.deleted)
+
+	// Both messages were received together and go back to the queue
+	sort.Strings(queue.released)
+	assert.Equal(t, []string{"receipt-message-0", "receipt-message-1"}, queue.released)
+}
@@ -13,3 +13,9 @@ type RetryAttemptsExceededError struct {
 func (e *RetryAttemptsExceededError) Error() string {
 	return fmt.Sprintf("Event exceeded maximum retry count: %s", e.EventName)
 }
+
+// RetryAttemptsExceeded marks the error as a
+// gomainevents.RetriesExhaustedError.
+func (e *RetryAttemptsExceededError) RetryAttemptsExceeded() bool {
+	return true
+}
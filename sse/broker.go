@@ -0,0 +1,291 @@
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultHistorySize       = 100
+	defaultSendBufferSize    = 256
+	defaultKeepaliveInterval = 30 * time.Second
+)
+
+// Broker streams published events to any number of clients as Server-Sent
+// Events. It is a Publisher, and an http.Handler that clients connect to,
+// e.g. with a browser's EventSource.
+//
+// Each event is sent with its name as the SSE event type and its data as
+// JSON. Clients can limit what they receive with an events query parameter,
+// e.g. /events?events=ThingHappened,OtherThingHappened. Reconnecting clients
+// are sent the events they missed, as long as those are still in the
+// broker's history.
+type Broker struct {
+	mu      sync.RWMutex
+	clients map[*client]bool
+	closed  bool
+
+	// The most recent events, for clients resuming with Last-Event-ID.
+	history     []*message
+	historySize int
+	lastID      uint64
+
+	sendBufferSize    int
+	keepaliveInterval time.Duration
+
+	debug bool
+}
+
+type BrokerConfig struct {
+	// Number of recent events kept for clients that reconnect. Defaults
+	// to 100.
+	HistorySize int
+
+	// Number of events queued per client before it is considered too
+	// slow and disconnected. Defaults to 256.
+	SendBufferSize int
+
+	// How often a comment is sent to idle clients so that proxies don't
+	// close the connection. Defaults to 30 seconds.
+	KeepaliveInterval time.Duration
+}
+
+// message is an event ready to be written to clients.
+type message struct {
+	id      uint64
+	name    string
+	payload []byte
+}
+
+// client is a single connected stream.
+type client struct {
+	// Event names the client wants. Empty means everything.
+	events map[string]bool
+	send   chan *message
+}
+
+func (c *client) wants(msg *message) bool {
+	return len(c.events) == 0 || c.events[msg.name]
+}
+
+func NewBroker(config *BrokerConfig) *Broker {
+	if nil == config {
+		config = &BrokerConfig{}
+	}
+
+	historySize := defaultHistorySize
+	if config.HistorySize > 0 {
+		historySize = config.HistorySize
+	}
+
+	sendBufferSize := defaultSendBufferSize
+	if config.SendBufferSize > 0 {
+		sendBufferSize = config.SendBufferSize
+	}
+
+	keepaliveInterval := defaultKeepaliveInterval
+	if config.KeepaliveInterval > 0 {
+		keepaliveInterval = config.KeepaliveInterval
+	}
+
+	return &Broker{
+		clients:           make(map[*client]bool),
+		historySize:       historySize,
+		sendBufferSize:    sendBufferSize,
+		keepaliveInterval: keepaliveInterval,
+		debug:             true,
+	}
+}
+
+// ServeHTTP streams events to the client until it disconnects or the broker
+// is closed.
+func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	c := &client{
+		events: parseEvents(r.URL.Query().Get("events")),
+		send:   make(chan *message, b.sendBufferSize),
+	}
+
+	if !b.register(c, lastEventID(r)) {
+		http.Error(w, "Shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer b.unregister(c)
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	b.debugPrint("Client connected from %s\n", r.RemoteAddr)
+
+	ticker := time.NewTicker(b.keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			if !ok {
+				return
+			}
+
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.id, msg.name, msg.payload); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			b.debugPrint("Client disconnected from %s\n", r.RemoteAddr)
+			return
+		}
+	}
+}
+
+// register adds a client, first queueing any events it missed since
+// lastID. It returns false if the broker is closed.
+func (b *Broker) register(c *client, lastID uint64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return false
+	}
+
+	if lastID > 0 {
+		for _, msg := range b.history {
+			if msg.id <= lastID || !c.wants(msg) {
+				continue
+			}
+
+			select {
+			case c.send <- msg:
+			default:
+				// More to replay than fits the buffer; the rest is lost.
+			}
+		}
+	}
+
+	b.clients[c] = true
+	return true
+}
+
+func (b *Broker) unregister(c *client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.clients[c] {
+		delete(b.clients, c)
+		close(c.send)
+	}
+}
+
+// Count returns the number of connected clients.
+func (b *Broker) Count() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.clients)
+}
+
+// Publish sends the event to every connected client that wants it. Clients
+// that can't keep up are disconnected; that isn't considered a publish
+// failure.
+func (b *Broker) Publish(event gomainevents.Event) error {
+	payload, err := json.Marshal(event.Data())
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+
+	b.lastID++
+	msg := &message{id: b.lastID, name: event.Name(), payload: payload}
+
+	b.history = append(b.history, msg)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for c := range b.clients {
+		if !c.wants(msg) {
+			continue
+		}
+
+		select {
+		case c.send <- msg:
+		default:
+			b.debugPrint("Client is too slow, disconnecting\n")
+			delete(b.clients, c)
+			close(c.send)
+		}
+	}
+
+	return nil
+}
+
+// Close disconnects every client and rejects new ones.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for c := range b.clients {
+		delete(b.clients, c)
+		close(c.send)
+	}
+}
+
+// parseEvents reads a comma separated list of event names.
+func parseEvents(value string) map[string]bool {
+	events := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); "" != name {
+			events[name] = true
+		}
+	}
+
+	return events
+}
+
+// lastEventID returns the ID of the last event the client saw. Browsers send
+// it as a header when reconnecting; the query parameter allows resuming a
+// new EventSource.
+func lastEventID(r *http.Request) uint64 {
+	value := r.Header.Get("Last-Event-ID")
+	if "" == value {
+		value = r.URL.Query().Get("lastEventId")
+	}
+
+	id, _ := strconv.ParseUint(value, 10, 64)
+	return id
+}
+
+func (b *Broker) debugPrint(format string, values ...interface{}) {
+	if b.debug {
+		log.Printf("[gomainevents-sse] "+format, values...)
+	}
+}
@@ -0,0 +1,161 @@
+package sse
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+// connect opens a stream and returns a function reading the next event's
+// lines, skipping comments.
+func connect(t *testing.T, url string, header http.Header) (func() []string, func()) {
+	req, err := http.NewRequest("GET", url, nil)
+	require.Nil(t, err)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	next := func() []string {
+		lines := []string{}
+		for {
+			line, err := reader.ReadString('\n')
+			require.Nil(t, err)
+
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case "" == line && len(lines) > 0:
+				return lines
+			case "" == line, strings.HasPrefix(line, ":"):
+			default:
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return next, func() { resp.Body.Close() }
+}
+
+func waitForClients(t *testing.T, broker *Broker, count int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for broker.Count() != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d clients, have %d", count, broker.Count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBrokerStreamsEvents(t *testing.T) {
+	broker := NewBroker(nil)
+	server := httptest.NewServer(broker)
+	defer server.Close()
+	defer broker.Close()
+
+	next, disconnect := connect(t, server.URL, nil)
+	defer disconnect()
+	waitForClients(t, broker, 1)
+
+	require.Nil(t, broker.Publish(testEvent{name: "Thing"}))
+
+	assert.Equal(t, []string{
+		"id: 1",
+		"event: Thing",
+		`data: {"occurredOn":"2018-03-08 11:11:11"}`,
+	}, next())
+}
+
+func TestBrokerFiltersEvents(t *testing.T) {
+	broker := NewBroker(nil)
+	server := httptest.NewServer(broker)
+	defer server.Close()
+	defer broker.Close()
+
+	next, disconnect := connect(t, server.URL+"?events=Wanted,AlsoWanted", nil)
+	defer disconnect()
+	waitForClients(t, broker, 1)
+
+	require.Nil(t, broker.Publish(testEvent{name: "Unwanted"}))
+	require.Nil(t, broker.Publish(testEvent{name: "AlsoWanted"}))
+
+	assert.Equal(t, "event: AlsoWanted", next()[1])
+}
+
+func TestBrokerReplaysMissedEvents(t *testing.T) {
+	broker := NewBroker(&BrokerConfig{HistorySize: 2})
+	server := httptest.NewServer(broker)
+	defer server.Close()
+	defer broker.Close()
+
+	for _, name := range []string{"First", "Second", "Third", "Fourth"} {
+		require.Nil(t, broker.Publish(testEvent{name: name}))
+	}
+
+	// Only the last two are kept
+	next, disconnect := connect(t, server.URL, http.Header{"Last-Event-ID": {"1"}})
+	defer disconnect()
+
+	assert.Equal(t, []string{"id: 3", "event: Third"}, next()[:2])
+	assert.Equal(t, []string{"id: 4", "event: Fourth"}, next()[:2])
+
+	next, disconnect = connect(t, server.URL+"?lastEventId=3", nil)
+	defer disconnect()
+
+	assert.Equal(t, "id: 4", next()[0])
+}
+
+func TestBrokerDisconnectsSlowClients(t *testing.T) {
+	broker := NewBroker(&BrokerConfig{SendBufferSize: 1})
+
+	c := &client{events: parseEvents(""), send: make(chan *message, 1)}
+	require.True(t, broker.register(c, 0))
+
+	require.Nil(t, broker.Publish(testEvent{name: "First"}))
+	require.Nil(t, broker.Publish(testEvent{name: "Second"}))
+
+	assert.Equal(t, 0, broker.Count())
+	assert.Equal(t, "First", (<-c.send).name)
+	_, ok := <-c.send
+	assert.False(t, ok)
+}
+
+func TestBrokerClose(t *testing.T) {
+	broker := NewBroker(nil)
+	server := httptest.NewServer(broker)
+	defer server.Close()
+
+	_, disconnect := connect(t, server.URL, nil)
+	defer disconnect()
+	waitForClients(t, broker, 1)
+
+	broker.Close()
+	assert.Equal(t, 0, broker.Count())
+
+	resp, err := http.Get(server.URL)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
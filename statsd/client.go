@@ -0,0 +1,158 @@
+package statsd
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAddr   = "127.0.0.1:8125"
+	defaultPrefix = "gomainevents."
+)
+
+// Client sends metrics to a StatsD server, or the Datadog agent, over UDP.
+// Metrics are fire and forget: failing to send one never fails whatever is
+// being measured.
+type Client struct {
+	writer    io.Writer
+	conn      net.Conn
+	prefix    string
+	tags      []string
+	dogStatsD bool
+	onError   func(error)
+
+	// Guards writes to a writer that may not be safe to share.
+	mu sync.Mutex
+}
+
+type Config struct {
+	// Address of the StatsD server. Defaults to 127.0.0.1:8125, where the
+	// Datadog agent listens.
+	Addr string
+
+	// Provide your own writer instead, e.g. a buffer in tests. Each metric
+	// is written separately.
+	Writer io.Writer
+
+	// Prefix for every metric name. Defaults to "gomainevents.".
+	Prefix string
+
+	// Send tags in the DogStatsD format. Plain StatsD servers don't
+	// understand tags, so they're dropped otherwise.
+	DogStatsD bool
+
+	// Tags added to every metric, e.g. "env:production" or "service:app".
+	// Requires DogStatsD.
+	Tags []string
+
+	// Called when a metric can't be sent. Optional
+	OnError func(error)
+}
+
+func NewClient(config *Config) (*Client, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	prefix := defaultPrefix
+	if "" != config.Prefix {
+		prefix = config.Prefix
+	}
+
+	c := &Client{
+		writer:    config.Writer,
+		prefix:    prefix,
+		tags:      sanitizeTags(config.Tags),
+		dogStatsD: config.DogStatsD,
+		onError:   config.OnError,
+	}
+
+	if nil == c.writer {
+		addr := defaultAddr
+		if "" != config.Addr {
+			addr = config.Addr
+		}
+
+		conn, err := net.Dial("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+
+		c.writer, c.conn = conn, conn
+	}
+
+	return c, nil
+}
+
+// Count adds value to a counter.
+func (c *Client) Count(name string, value int64, tags ...string) {
+	c.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Incr adds one to a counter.
+func (c *Client) Incr(name string, tags ...string) {
+	c.Count(name, 1, tags...)
+}
+
+// Gauge sets a gauge.
+func (c *Client) Gauge(name string, value float64, tags ...string) {
+	c.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+// Timing records a duration, in milliseconds.
+func (c *Client) Timing(name string, d time.Duration, tags ...string) {
+	c.send(name, strconv.FormatFloat(d.Seconds()*1000, 'f', -1, 64), "ms", tags)
+}
+
+// Close closes the connection, if the client opened it.
+func (c *Client) Close() error {
+	if nil == c.conn {
+		return nil
+	}
+
+	return c.conn.Close()
+}
+
+func (c *Client) send(name string, value string, kind string, tags []string) {
+	line := sanitizeName(c.prefix+name) + ":" + value + "|" + kind
+
+	if c.dogStatsD {
+		all := append(append([]string{}, c.tags...), sanitizeTags(tags)...)
+		if len(all) > 0 {
+			line += "|#" + strings.Join(all, ",")
+		}
+	}
+
+	c.mu.Lock()
+	_, err := c.writer.Write([]byte(line))
+	c.mu.Unlock()
+
+	if err != nil && nil != c.onError {
+		c.onError(err)
+	}
+}
+
+// nameReplacer removes the characters that separate the parts of a metric.
+var nameReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", "\n", "_")
+
+// tagReplacer removes the characters that separate tags. Colons are left
+// alone since they separate a tag's key from its value.
+var tagReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+
+func sanitizeName(name string) string {
+	return nameReplacer.Replace(name)
+}
+
+func sanitizeTags(tags []string) []string {
+	sanitized := make([]string, len(tags))
+	for i, tag := range tags {
+		sanitized[i] = tagReplacer.Replace(tag)
+	}
+
+	return sanitized
+}
@@ -0,0 +1,114 @@
+package statsd
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// packets records each metric written.
+type packets struct {
+	mu    sync.Mutex
+	lines []string
+	err   error
+}
+
+func (p *packets) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if nil != p.err {
+		return 0, p.err
+	}
+
+	p.lines = append(p.lines, string(b))
+	return len(b), nil
+}
+
+func (p *packets) Lines() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string{}, p.lines...)
+}
+
+func TestNewClient(t *testing.T) {
+	client, err := NewClient(nil)
+	assert.Nil(t, client)
+	assert.NotNil(t, err)
+
+	client, err = NewClient(&Config{Writer: &packets{}})
+	assert.NotNil(t, client)
+	assert.Nil(t, err)
+	assert.Equal(t, defaultPrefix, client.prefix)
+}
+
+func TestClientFormats(t *testing.T) {
+	out := &packets{}
+	client, _ := NewClient(&Config{Writer: out, Prefix: "app."})
+
+	client.Incr("events")
+	client.Count("events", 5, "ignored:tag")
+	client.Gauge("lag", 1.5)
+	client.Timing("handle_time", 1500*time.Microsecond)
+
+	assert.Equal(t, []string{
+		"app.events:1|c",
+		"app.events:5|c",
+		"app.lag:1.5|g",
+		"app.handle_time:1.5|ms",
+	}, out.Lines())
+}
+
+func TestClientDogStatsDTags(t *testing.T) {
+	out := &packets{}
+	client, _ := NewClient(&Config{
+		Writer:    out,
+		DogStatsD: true,
+		Tags:      []string{"env:production"},
+	})
+
+	client.Incr("events", "event:Domain\\Event", "bad:a|b,c#d")
+	client.Incr("weird:name|here")
+
+	assert.Equal(t, []string{
+		"gomainevents.events:1|c|#env:production,event:Domain\\Event,bad:a_b_c_d",
+		"gomainevents.weird_name_here:1|c|#env:production",
+	}, out.Lines())
+}
+
+func TestClientReportsWriteErrors(t *testing.T) {
+	reported := []error{}
+	client, _ := NewClient(&Config{
+		Writer:  &packets{err: errors.New("Connection refused")},
+		OnError: func(err error) { reported = append(reported, err) },
+	})
+
+	client.Incr("events")
+
+	assert.Len(t, reported, 1)
+}
+
+func TestClientSendsOverUDP(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer server.Close()
+
+	client, err := NewClient(&Config{Addr: server.LocalAddr().String()})
+	require.Nil(t, err)
+	defer client.Close()
+
+	client.Incr("events")
+
+	buf := make([]byte, 1024)
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := server.ReadFrom(buf)
+
+	require.Nil(t, err)
+	assert.Equal(t, "gomainevents.events:1|c", string(buf[:n]))
+}
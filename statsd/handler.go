@@ -0,0 +1,79 @@
+package statsd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Handler wraps an EventHandler, counting the events it handles and timing
+// how long handling takes.
+//
+//	listener.RegisterHandler("ThingHappened", client.Handler(handleThing))
+//
+// It sends:
+//
+//   - events.handled, a counter tagged with event and status (ok or error)
+//   - events.handle_time, a timer tagged with event and status
+//   - events.age, a timer of how long ago the event happened when its
+//     handler finished, tagged with event and status. Alert on it to catch
+//     consumers falling behind even when the queue looks short. Events
+//     without an occurredOn or a sent time are left out; see
+//     gomainevents.OccurredAt.
+func (c *Client) Handler(fn gomainevents.EventHandler) gomainevents.EventHandler {
+	return func(event gomainevents.Event) error {
+		start := time.Now()
+		err := fn(event)
+		end := time.Now()
+
+		c.Timing("events.handle_time", end.Sub(start), eventTag(event.Name()), statusTag(err))
+		c.Incr("events.handled", eventTag(event.Name()), statusTag(err))
+
+		if age, ok := gomainevents.EventAge(event, end); ok {
+			c.Timing("events.age", age, eventTag(event.Name()), statusTag(err))
+		}
+
+		return err
+	}
+}
+
+// ErrorHandler wraps an ErrorHandler, which may be nil, counting the errors
+// passed to it as errors, tagged with the type of error. Errors from the
+// provider are tagged with the type of the underlying error and the phase
+// they happened in.
+//
+//	listener.RegisterErrorHandler(client.ErrorHandler(reportError))
+func (c *Client) ErrorHandler(fn gomainevents.ErrorHandler) gomainevents.ErrorHandler {
+	return func(err error) {
+		if providerErr, ok := err.(*gomainevents.ProviderError); ok {
+			c.Incr("errors", fmt.Sprintf("error:%T", providerErr.Err), phaseTag(providerErr.Phase))
+		} else {
+			c.Incr("errors", fmt.Sprintf("error:%T", err))
+		}
+
+		if nil != fn {
+			fn(err)
+		}
+	}
+}
+
+func eventTag(name string) string {
+	return "event:" + name
+}
+
+func phaseTag(phase gomainevents.ErrorPhase) string {
+	if gomainevents.PhaseUnknown == phase {
+		return "phase:unknown"
+	}
+
+	return "phase:" + string(phase)
+}
+
+func statusTag(err error) string {
+	if err != nil {
+		return "status:error"
+	}
+
+	return "status:ok"
+}
@@ -0,0 +1,98 @@
+package statsd
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerCountsAndTimes(t *testing.T) {
+	out := &packets{}
+	client, _ := NewClient(&Config{Writer: out, DogStatsD: true})
+
+	handler := client.Handler(func(event gomainevents.Event) error {
+		if event.Name() == "Broken" {
+			return errors.New("Nope")
+		}
+
+		return nil
+	})
+
+	assert.Nil(t, handler(testEvent{name: "Created"}))
+	assert.NotNil(t, handler(testEvent{name: "Broken"}))
+
+	lines := out.Lines()
+	assert.Len(t, lines, 4)
+	assert.True(t, strings.HasPrefix(lines[0], "gomainevents.events.handle_time:"))
+	assert.True(t, strings.HasSuffix(lines[0], "|ms|#event:Created,status:ok"))
+	assert.Equal(t, "gomainevents.events.handled:1|c|#event:Created,status:ok", lines[1])
+	assert.Equal(t, "gomainevents.events.handled:1|c|#event:Broken,status:error", lines[3])
+}
+
+type customError struct{}
+
+func (e *customError) Error() string {
+	return "custom"
+}
+
+func TestErrorHandlerCountsByType(t *testing.T) {
+	out := &packets{}
+	client, _ := NewClient(&Config{Writer: out, DogStatsD: true})
+
+	passed := []error{}
+	handler := client.ErrorHandler(func(err error) {
+		passed = append(passed, err)
+	})
+
+	handler(&customError{})
+
+	assert.Len(t, passed, 1)
+	assert.Equal(t, []string{"gomainevents.errors:1|c|#error:*statsd.customError"}, out.Lines())
+
+	// The wrapped handler is optional
+	client.ErrorHandler(nil)(errors.New("Nope"))
+	assert.Len(t, out.Lines(), 2)
+
+	// Provider errors are counted by what's inside
+	handler(gomainevents.NewProviderError(gomainevents.PhaseReceive, "queue", "", &customError{}))
+	handler(gomainevents.NewProviderError(gomainevents.PhaseUnknown, "", "", &customError{}))
+	assert.Equal(t, []string{
+		"gomainevents.errors:1|c|#error:*statsd.customError,phase:receive",
+		"gomainevents.errors:1|c|#error:*statsd.customError,phase:unknown",
+	}, out.Lines()[2:])
+}
+
+type agedEvent struct {
+	occurredOn time.Time
+}
+
+func (e agedEvent) Name() string {
+	return "Created"
+}
+
+func (e agedEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": e.occurredOn.Format(time.RFC3339Nano)}
+}
+
+func TestHandlerReportsEventAge(t *testing.T) {
+	out := &packets{}
+	client, _ := NewClient(&Config{Writer: out, DogStatsD: true})
+	handler := client.Handler(func(gomainevents.Event) error { return nil })
+
+	assert.Nil(t, handler(agedEvent{occurredOn: time.Now().Add(-90 * time.Second)}))
+
+	lines := out.Lines()
+	assert.Len(t, lines, 3)
+
+	age := strings.TrimPrefix(lines[2], "gomainevents.events.age:")
+	age = strings.TrimSuffix(age, "|ms|#event:Created,status:ok")
+	ms, err := strconv.ParseFloat(age, 64)
+
+	assert.Nil(t, err, lines[2])
+	assert.InDelta(t, 90000, ms, 1000)
+}
@@ -0,0 +1,87 @@
+package statsd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Publisher wraps another Publisher, counting the events it publishes and
+// timing how long publishing takes:
+//
+//   - events.published, a counter tagged with event and status (ok or error)
+//   - events.publish_time, a timer tagged with status
+type Publisher struct {
+	publisher gomainevents.Publisher
+	client    *Client
+}
+
+type PublisherConfig struct {
+	// Publisher to measure. Required
+	Publisher gomainevents.Publisher
+
+	// Client the metrics are sent with. Required
+	Client *Client
+}
+
+func NewPublisher(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Publisher {
+		return nil, errors.New("Publisher is required")
+	}
+
+	if nil == config.Client {
+		return nil, errors.New("Client is required")
+	}
+
+	return &Publisher{
+		publisher: config.Publisher,
+		client:    config.Client,
+	}, nil
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	start := time.Now()
+	err := p.publisher.Publish(event)
+
+	p.client.Timing("events.publish_time", time.Since(start), statusTag(err))
+	p.client.Incr("events.published", eventTag(event.Name()), statusTag(err))
+
+	return err
+}
+
+// PublishBatch passes the events on in a single batch if the wrapped
+// publisher supports them, or one at a time if it doesn't. A failed batch
+// counts every event in it as failed.
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	batchPublisher, ok := p.publisher.(gomainevents.BatchPublisher)
+	if !ok {
+		for _, event := range events {
+			if err := p.Publish(event); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	start := time.Now()
+	err := batchPublisher.PublishBatch(events)
+
+	p.client.Timing("events.publish_time", time.Since(start), statusTag(err), "batch:true")
+
+	counts := map[string]int64{}
+	for _, event := range events {
+		counts[event.Name()]++
+	}
+
+	for name, count := range counts {
+		p.client.Count("events.published", count, eventTag(name), statusTag(err))
+	}
+
+	return err
+}
@@ -0,0 +1,114 @@
+package statsd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+type testPublisher struct {
+	err error
+}
+
+func (p testPublisher) Publish(gomainevents.Event) error {
+	return p.err
+}
+
+type testBatchPublisher struct {
+	testPublisher
+	batches int
+}
+
+func (p *testBatchPublisher) PublishBatch([]gomainevents.Event) error {
+	p.batches++
+	return p.err
+}
+
+func TestNewPublisher(t *testing.T) {
+	client, _ := NewClient(&Config{Writer: &packets{}})
+
+	publisher, err := NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Client: client})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Publisher: testPublisher{}})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Publisher: testPublisher{}, Client: client})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+}
+
+func TestPublisherCountsPublishes(t *testing.T) {
+	out := &packets{}
+	client, _ := NewClient(&Config{Writer: out, DogStatsD: true})
+	publisher, _ := NewPublisher(&PublisherConfig{Publisher: testPublisher{}, Client: client})
+
+	assert.Nil(t, publisher.Publish(testEvent{name: "Created"}))
+
+	lines := out.Lines()
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "gomainevents.events.publish_time:"))
+	assert.Equal(t, "gomainevents.events.published:1|c|#event:Created,status:ok", lines[1])
+}
+
+func TestPublisherCountsFailures(t *testing.T) {
+	out := &packets{}
+	client, _ := NewClient(&Config{Writer: out, DogStatsD: true})
+	publisher, _ := NewPublisher(&PublisherConfig{
+		Publisher: testPublisher{err: errors.New("Throttled")},
+		Client:    client,
+	})
+
+	assert.NotNil(t, publisher.Publish(testEvent{name: "Created"}))
+	assert.Contains(t, out.Lines(), "gomainevents.events.published:1|c|#event:Created,status:error")
+}
+
+func TestPublisherBatches(t *testing.T) {
+	out := &packets{}
+	client, _ := NewClient(&Config{Writer: out, DogStatsD: true})
+	underlying := &testBatchPublisher{}
+	publisher, _ := NewPublisher(&PublisherConfig{Publisher: underlying, Client: client})
+
+	err := publisher.PublishBatch([]gomainevents.Event{
+		testEvent{name: "Created"},
+		testEvent{name: "Created"},
+		testEvent{name: "Updated"},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, underlying.batches)
+	assert.Contains(t, out.Lines(), "gomainevents.events.published:2|c|#event:Created,status:ok")
+	assert.Contains(t, out.Lines(), "gomainevents.events.published:1|c|#event:Updated,status:ok")
+}
+
+func TestPublisherBatchesOneAtATime(t *testing.T) {
+	out := &packets{}
+	client, _ := NewClient(&Config{Writer: out, DogStatsD: true})
+	publisher, _ := NewPublisher(&PublisherConfig{Publisher: testPublisher{}, Client: client})
+
+	err := publisher.PublishBatch([]gomainevents.Event{testEvent{name: "Created"}, testEvent{name: "Updated"}})
+
+	assert.Nil(t, err)
+	assert.Len(t, out.Lines(), 4)
+}
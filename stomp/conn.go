@@ -0,0 +1,67 @@
+package stomp
+
+import (
+	"crypto/tls"
+	"errors"
+
+	gostomp "github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+)
+
+// Conn is the part of go-stomp's Conn used by the Publisher and Provider.
+type Conn interface {
+	Send(destination, contentType string, body []byte, opts ...func(*frame.Frame) error) error
+	Subscribe(destination string, ack gostomp.AckMode, opts ...func(*frame.Frame) error) (*gostomp.Subscription, error)
+	Ack(msg *gostomp.Message) error
+	Nack(msg *gostomp.Message) error
+	Disconnect() error
+}
+
+// dialer holds the connection settings shared by the Publisher and
+// Provider configs.
+type dialer struct {
+	addr     string
+	login    string
+	passcode string
+	tls      *tls.Config
+	conn     Conn
+}
+
+// open returns the configured connection, or dials the broker. The
+// returned bool is true if the connection was opened here.
+func open(d dialer) (Conn, bool, error) {
+	if nil != d.conn {
+		return d.conn, false, nil
+	}
+
+	if "" == d.addr {
+		return nil, false, errors.New("Addr or Conn is required")
+	}
+
+	var opts []func(*gostomp.Conn) error
+	if "" != d.login {
+		opts = append(opts, gostomp.ConnOpt.Login(d.login, d.passcode))
+	}
+
+	if nil == d.tls {
+		conn, err := gostomp.Dial("tcp", d.addr, opts...)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return conn, true, nil
+	}
+
+	netConn, err := tls.Dial("tcp", d.addr, d.tls)
+	if err != nil {
+		return nil, false, err
+	}
+
+	conn, err := gostomp.Connect(netConn, opts...)
+	if err != nil {
+		netConn.Close()
+		return nil, false, err
+	}
+
+	return conn, true, nil
+}
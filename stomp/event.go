@@ -0,0 +1,88 @@
+package stomp
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	gostomp "github.com/go-stomp/stomp/v3"
+)
+
+const (
+	// headerEventName carries the event name, so that consumers can
+	// filter on it with a selector.
+	headerEventName = "eventName"
+
+	// headerRetryCount counts how many times the provider has requeued a
+	// message.
+	headerRetryCount = "retryCount"
+
+	// headerPersistent asks ActiveMQ to keep the message on disk.
+	headerPersistent = "persistent"
+)
+
+// Event implements the standard domain event interface for events consumed
+// from a STOMP destination.
+type Event struct {
+	name    string
+	data    map[string]interface{}
+	message *gostomp.Message
+
+	retryCount int
+}
+
+type encodedEvent struct {
+	Name string                 `json:"name"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// DecodeEvent builds an event from a message.
+func DecodeEvent(message *gostomp.Message) (*Event, error) {
+	e := &encodedEvent{}
+	if err := json.Unmarshal(message.Body, e); err != nil {
+		return nil, err
+	}
+
+	name := e.Name
+	if header := message.Header.Get(headerEventName); "" != header {
+		name = header
+	}
+
+	// A missing or mangled header counts as a first delivery
+	retryCount, _ := strconv.Atoi(message.Header.Get(headerRetryCount))
+
+	return &Event{
+		name:       name,
+		data:       e.Data,
+		message:    message,
+		retryCount: retryCount,
+	}, nil
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// Destination returns the destination the event was consumed from.
+func (e Event) Destination() string {
+	return e.message.Destination
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
+
+// Delay returns how long to wait before redelivering this event.
+func (e Event) Delay() time.Duration {
+	return time.Duration(math.Min(
+		math.Pow(2, float64(e.retryCount+1)),
+		15*60, // Max is 15 minutes
+	)) * time.Second
+}
@@ -0,0 +1,352 @@
+package stomp
+
+import (
+	"crypto/tls"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	gostomp "github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultMaximumRetryCount = 25
+	defaultPrefetch          = 10
+)
+
+// Provider consumes events from a STOMP destination with client-individual
+// acknowledgements, e.g. an ActiveMQ queue on Amazon MQ. Delete acks.
+// Requeue waits for the event's delay, then sends a copy back to the
+// destination with its retry count incremented and acks the original;
+// until then the original stays unacked, so the broker redelivers it if
+// the process dies. Events that run out of retries are moved to the dead
+// letter queue if there is one, or nacked so that the broker's redelivery
+// policy takes over.
+type Provider struct {
+	conn        Conn
+	ownsConn    bool
+	destination string
+	selector    string
+	prefetch    int
+	deadLetter  string
+
+	subscription *gostomp.Subscription
+
+	maximumRetryCount int
+	jitter            gomainevents.Jitter
+	clock             gomainevents.Clock
+	requeueDelay      func(Event) time.Duration
+	unsubscribe       func(*gostomp.Subscription) error
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+}
+
+type ProviderConfig struct {
+	// Broker to connect to, e.g. localhost:61613, or
+	// b-1234.mq.us-east-1.amazonaws.com:61614 for Amazon MQ. Either Addr
+	// or Conn is required.
+	Addr string
+
+	// Credentials for the broker. Optional
+	Login    string
+	Passcode string
+
+	// Connect over TLS, as Amazon MQ requires. Use an empty config for
+	// the defaults.
+	TLS *tls.Config
+
+	// An existing connection. It is not disconnected by Stop.
+	Conn Conn
+
+	// Destination to consume, e.g. /queue/app-events, or
+	// /queue/Consumer.app.VirtualTopic.domain-events to read a virtual
+	// topic. Required
+	Destination string
+
+	// ActiveMQ selector limiting which events are received, e.g.
+	// "eventName IN ('Created', 'Updated')". Optional
+	Selector string
+
+	// Number of unacknowledged events the broker hands out at once.
+	// Defaults to 10.
+	Prefetch int
+
+	// Destination that events exceeding MaximumRetryCount are sent to,
+	// e.g. /queue/app-events.dlq. Optional
+	DeadLetterQueue string
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+
+	// Randomizes retry delays, so that events that failed together aren't
+	// retried together. Defaults to none.
+	Jitter gomainevents.Jitter
+
+	// Clock requeue delays are measured on. Defaults to
+	// gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewProvider(config *ProviderConfig) (*Provider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.Destination {
+		return nil, errors.New("Destination is required")
+	}
+
+	conn, ownsConn, err := open(dialer{
+		addr:     config.Addr,
+		login:    config.Login,
+		passcode: config.Passcode,
+		tls:      config.TLS,
+		conn:     config.Conn,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prefetch := defaultPrefetch
+	if config.Prefetch > 0 {
+		prefetch = config.Prefetch
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	return &Provider{
+		conn:              conn,
+		ownsConn:          ownsConn,
+		destination:       config.Destination,
+		selector:          config.Selector,
+		prefetch:          prefetch,
+		deadLetter:        config.DeadLetterQueue,
+		maximumRetryCount: maximumRetryCount,
+		jitter:            config.Jitter,
+		clock:             gomainevents.ClockOrDefault(config.Clock),
+		requeueDelay:      Event.Delay,
+		unsubscribe: func(subscription *gostomp.Subscription) error {
+			return subscription.Unsubscribe()
+		},
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}, nil
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	opts := []func(*frame.Frame) error{
+		gostomp.SubscribeOpt.Header("activemq.prefetchSize", strconv.Itoa(p.prefetch)),
+	}
+	if "" != p.selector {
+		opts = append(opts, gostomp.SubscribeOpt.Header("selector", p.selector))
+	}
+
+	subscription, err := p.conn.Subscribe(p.destination, gostomp.AckClientIndividual, opts...)
+	if err != nil {
+		p.reportError(gomainevents.PhaseConnect, "", err)
+		return p.events, p.errors
+	}
+
+	p.subscription = subscription
+
+	go func() {
+		for message := range subscription.C {
+			if nil != message.Err {
+				p.reportError(gomainevents.PhaseReceive, "", message.Err)
+				continue
+			}
+
+			event, err := DecodeEvent(message)
+			if err != nil {
+				// It will never decode, so dead letter it.
+				p.reportError(gomainevents.PhaseDecode, "", err)
+				p.discard(message)
+				continue
+			}
+
+			if !p.deliver(*event) {
+				return
+			}
+		}
+
+		select {
+		case <-p.done:
+		default:
+			p.reportError(gomainevents.PhaseReceive, "", errors.New("Subscription closed"))
+		}
+	}()
+
+	return p.events, p.errors
+}
+
+// Delete an event that we're done with
+func (p *Provider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to STOMP flavor
+
+	if err := p.conn.Ack(evt.message); err != nil {
+		p.reportError(gomainevents.PhaseDelete, "", err)
+	}
+}
+
+// Requeue an event for later.
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to STOMP flavor
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		if err := p.discard(evt.message); err != nil {
+			return err
+		}
+
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	delay := p.jitter.Apply(p.requeueDelay(evt))
+	p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", evt.RetryCount()+1, delay)
+	p.resendAfter(evt, evt.RetryCount()+1, delay)
+
+	return nil
+}
+
+// Defer an event, sending it again after the delay with the same retry
+// count.
+func (p *Provider) Defer(event gomainevents.Event, delay time.Duration) error {
+	evt := event.(Event) // Cast to STOMP flavor
+
+	p.debugPrint("Deferring event. Delay: %s\n", delay)
+	p.resendAfter(evt, evt.RetryCount(), delay)
+
+	return nil
+}
+
+// resendAfter sends the event again once the delay has passed, then acks
+// the original.
+func (p *Provider) resendAfter(evt Event, retryCount int, delay time.Duration) {
+	p.clock.AfterFunc(delay, func() {
+		if err := p.send(p.destination, evt, retryCount); err != nil {
+			// Let the broker redeliver the original instead.
+			p.reportError(gomainevents.PhaseRequeue, "", err)
+			p.conn.Nack(evt.message)
+			return
+		}
+
+		if err := p.conn.Ack(evt.message); err != nil {
+			p.reportError(gomainevents.PhaseRequeue, "", err)
+		}
+	})
+}
+
+// discard moves a message to the dead letter queue, or nacks it if there
+// isn't one.
+func (p *Provider) discard(message *gostomp.Message) error {
+	if "" == p.deadLetter {
+		return p.conn.Nack(message)
+	}
+
+	retryCount, _ := strconv.Atoi(message.Header.Get(headerRetryCount))
+	if err := p.send(p.deadLetter, Event{message: message}, retryCount); err != nil {
+		// Leave it unacked, so the broker redelivers it.
+		return err
+	}
+
+	return p.conn.Ack(message)
+}
+
+// send puts a copy of the event's message on a destination with the given
+// retry count.
+func (p *Provider) send(destination string, event Event, retryCount int) error {
+	opts := []func(*frame.Frame) error{
+		gostomp.SendOpt.Receipt,
+		gostomp.SendOpt.Header(headerRetryCount, strconv.Itoa(retryCount)),
+		gostomp.SendOpt.Header(headerPersistent, "true"),
+	}
+	if name := event.message.Header.Get(headerEventName); "" != name {
+		opts = append(opts, gostomp.SendOpt.Header(headerEventName, name))
+	}
+
+	return p.conn.Send(destination, event.message.ContentType, event.message.Body, opts...)
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	close(p.done)
+
+	// Stop receiving, so the broker hands anything prefetched to other
+	// consumers.
+	if nil != p.subscription {
+		p.unsubscribe(p.subscription)
+	}
+
+	if p.ownsConn {
+		p.conn.Disconnect()
+	}
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, p.destination, messageID, err):
+	default:
+	}
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-stomp] "+format, values...)
+	}
+}
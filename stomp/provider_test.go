@@ -0,0 +1,261 @@
+package stomp
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	gostomp "github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sent struct {
+	destination string
+	contentType string
+	body        []byte
+	header      *frame.Header
+}
+
+type mockConn struct {
+	mu           sync.Mutex
+	sent         []sent
+	results      map[string]string
+	subscribed   *frame.Frame
+	ackMode      gostomp.AckMode
+	messages     chan *gostomp.Message
+	disconnected bool
+}
+
+func newMockConn(messages ...*gostomp.Message) *mockConn {
+	conn := &mockConn{
+		results:  map[string]string{},
+		messages: make(chan *gostomp.Message, len(messages)),
+	}
+	for _, message := range messages {
+		conn.messages <- message
+	}
+
+	return conn
+}
+
+func (m *mockConn) Send(destination, contentType string, body []byte, opts ...func(*frame.Frame) error) error {
+	f := &frame.Frame{Header: frame.NewHeader()}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, sent{destination: destination, contentType: contentType, body: body, header: f.Header})
+	return nil
+}
+
+func (m *mockConn) sentMessages() []sent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]sent{}, m.sent...)
+}
+
+func (m *mockConn) Subscribe(destination string, ack gostomp.AckMode, opts ...func(*frame.Frame) error) (*gostomp.Subscription, error) {
+	f := &frame.Frame{Header: frame.NewHeader("destination", destination)}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	m.subscribed = f
+	m.ackMode = ack
+
+	return &gostomp.Subscription{C: m.messages}, nil
+}
+
+func (m *mockConn) record(message *gostomp.Message, result string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.results[message.Header.Get("message-id")] = result
+	return nil
+}
+
+func (m *mockConn) result(id string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.results[id]
+}
+
+func (m *mockConn) Ack(message *gostomp.Message) error {
+	return m.record(message, "ack")
+}
+
+func (m *mockConn) Nack(message *gostomp.Message) error {
+	return m.record(message, "nack")
+}
+
+func (m *mockConn) Disconnect() error {
+	m.disconnected = true
+	return nil
+}
+
+func message(id, name, retries string) *gostomp.Message {
+	return &gostomp.Message{
+		Destination: "/queue/events",
+		ContentType: "application/json",
+		Header:      frame.NewHeader("message-id", id, headerEventName, name, headerRetryCount, retries),
+		Body:        []byte(`{"name":"` + name + `","data":{"occurredOn":"2018-03-08 11:11:11"}}`),
+	}
+}
+
+func newTestProvider(t *testing.T, config *ProviderConfig) *Provider {
+	provider, err := NewProvider(config)
+	require.Nil(t, err)
+
+	provider.requeueDelay = func(Event) time.Duration { return 0 }
+	provider.unsubscribe = func(*gostomp.Subscription) error { return nil }
+
+	return provider
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{Conn: newMockConn(), Destination: "/queue/events"})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Conn: newMockConn()})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{Destination: "/queue/events"})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+}
+
+func TestProviderSubscribes(t *testing.T) {
+	conn := newMockConn()
+	provider := newTestProvider(t, &ProviderConfig{
+		Conn:        conn,
+		Destination: "/queue/events",
+		Selector:    "eventName = 'Created'",
+		Prefetch:    5,
+	})
+
+	provider.Start()
+	provider.Stop()
+
+	assert.Equal(t, gostomp.AckClientIndividual, conn.ackMode)
+	assert.Equal(t, "/queue/events", conn.subscribed.Header.Get("destination"))
+	assert.Equal(t, "5", conn.subscribed.Header.Get("activemq.prefetchSize"))
+	assert.Equal(t, "eventName = 'Created'", conn.subscribed.Header.Get("selector"))
+
+	// The connection was provided, so it is left alone
+	assert.False(t, conn.disconnected)
+}
+
+func TestProviderAcksAndRequeues(t *testing.T) {
+	conn := newMockConn(message("1", "First", ""), message("2", "Second", "2"))
+	provider := newTestProvider(t, &ProviderConfig{Conn: conn, Destination: "/queue/events", MaximumRetryCount: 5})
+
+	events, _ := provider.Start()
+
+	received := []Event{}
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-events:
+			received = append(received, event.(Event))
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for event")
+		}
+	}
+
+	assert.Equal(t, "First", received[0].Name())
+	assert.Equal(t, 0, received[0].RetryCount())
+	assert.Equal(t, 2, received[1].RetryCount())
+	assert.Equal(t, "/queue/events", received[1].Destination())
+
+	provider.Delete(received[0])
+	assert.Equal(t, "ack", conn.result("1"))
+
+	assert.Nil(t, provider.Requeue(received[1]))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for conn.result("2") == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "ack", conn.result("2"))
+
+	// A copy goes back to the destination with the retry count bumped
+	resent := conn.sentMessages()
+	require.Len(t, resent, 1)
+	assert.Equal(t, "/queue/events", resent[0].destination)
+	assert.Equal(t, "Second", resent[0].header.Get(headerEventName))
+	assert.Equal(t, "3", resent[0].header.Get(headerRetryCount))
+	assert.Equal(t, "true", resent[0].header.Get(headerPersistent))
+
+	provider.Stop()
+}
+
+func TestProviderDeadLettersExhaustedEvents(t *testing.T) {
+	conn := newMockConn()
+	provider := newTestProvider(t, &ProviderConfig{
+		Conn:              conn,
+		Destination:       "/queue/events",
+		DeadLetterQueue:   "/queue/events.dlq",
+		MaximumRetryCount: 1,
+	})
+	defer provider.Stop()
+
+	event, err := DecodeEvent(message("1", "Thing", "2"))
+	require.Nil(t, err)
+
+	assert.IsType(t, &RetryAttemptsExceededError{}, provider.Requeue(*event))
+	assert.Equal(t, "ack", conn.result("1"))
+
+	deadLetters := conn.sentMessages()
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "/queue/events.dlq", deadLetters[0].destination)
+	assert.Equal(t, "2", deadLetters[0].header.Get(headerRetryCount))
+}
+
+func TestProviderNacksExhaustedEventsWithoutDeadLetterQueue(t *testing.T) {
+	conn := newMockConn()
+	provider := newTestProvider(t, &ProviderConfig{Conn: conn, Destination: "/queue/events", MaximumRetryCount: 1})
+	defer provider.Stop()
+
+	event, err := DecodeEvent(message("1", "Thing", "2"))
+	require.Nil(t, err)
+
+	assert.IsType(t, &RetryAttemptsExceededError{}, provider.Requeue(*event))
+	assert.Equal(t, "nack", conn.result("1"))
+	assert.Empty(t, conn.sentMessages())
+}
+
+func TestProviderDiscardsUndecodableMessages(t *testing.T) {
+	bad := message("1", "Thing", "")
+	bad.Body = []byte("nope")
+
+	conn := newMockConn(bad)
+	provider := newTestProvider(t, &ProviderConfig{Conn: conn, Destination: "/queue/events"})
+
+	_, errs := provider.Start()
+	defer provider.Stop()
+
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for error")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for conn.result("1") == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "nack", conn.result("1"))
+}
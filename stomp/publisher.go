@@ -0,0 +1,96 @@
+package stomp
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+
+	gostomp "github.com/go-stomp/stomp/v3"
+	"github.com/researchsquare/gomainevents"
+)
+
+const contentType = "application/json"
+
+// Publisher sends events to a STOMP destination, such as an ActiveMQ queue
+// or topic on Amazon MQ. Messages are persistent and carry the event name
+// in an eventName header.
+type Publisher struct {
+	conn        Conn
+	ownsConn    bool
+	destination string
+}
+
+type PublisherConfig struct {
+	// Broker to connect to, e.g. localhost:61613, or
+	// b-1234.mq.us-east-1.amazonaws.com:61614 for Amazon MQ. Either Addr
+	// or Conn is required.
+	Addr string
+
+	// Credentials for the broker. Optional
+	Login    string
+	Passcode string
+
+	// Connect over TLS, as Amazon MQ requires. Use an empty config for
+	// the defaults.
+	TLS *tls.Config
+
+	// An existing connection. It is not disconnected by Close.
+	Conn Conn
+
+	// Destination to send to, e.g. /topic/domain-events. Required
+	Destination string
+}
+
+func NewPublisher(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.Destination {
+		return nil, errors.New("Destination is required")
+	}
+
+	conn, ownsConn, err := open(dialer{
+		addr:     config.Addr,
+		login:    config.Login,
+		passcode: config.Passcode,
+		tls:      config.TLS,
+		conn:     config.Conn,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Publisher{
+		conn:        conn,
+		ownsConn:    ownsConn,
+		destination: config.Destination,
+	}, nil
+}
+
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	body, err := json.Marshal(&encodedEvent{
+		Name: event.Name(),
+		Data: event.Data(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Wait for the broker's receipt, so that an error means the event
+	// was not accepted
+	return p.conn.Send(p.destination, contentType, body,
+		gostomp.SendOpt.Receipt,
+		gostomp.SendOpt.Header(headerEventName, event.Name()),
+		gostomp.SendOpt.Header(headerPersistent, "true"),
+	)
+}
+
+// Close disconnects from the broker if the publisher connected.
+func (p *Publisher) Close() error {
+	if p.ownsConn {
+		return p.conn.Disconnect()
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package stomp
+
+import (
+	"testing"
+
+	gostomp "github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(&PublisherConfig{Conn: newMockConn(), Destination: "/topic/domain-events"})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Conn: newMockConn()})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(&PublisherConfig{Destination: "/topic/domain-events"})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestPublish(t *testing.T) {
+	conn := newMockConn()
+	publisher, err := NewPublisher(&PublisherConfig{Conn: conn, Destination: "/topic/domain-events"})
+	require.Nil(t, err)
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+
+	messages := conn.sentMessages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "/topic/domain-events", messages[0].destination)
+	assert.Equal(t, "application/json", messages[0].contentType)
+	assert.Equal(t, "Thing", messages[0].header.Get(headerEventName))
+	assert.Equal(t, "true", messages[0].header.Get(headerPersistent))
+	assert.JSONEq(t, `{"name":"Thing","data":{"occurredOn":"2018-03-08 11:11:11"}}`, string(messages[0].body))
+
+	event, err := DecodeEvent(&gostomp.Message{Header: messages[0].header, Body: messages[0].body})
+	require.Nil(t, err)
+	assert.Equal(t, "Thing", event.Name())
+	assert.Equal(t, 0, event.RetryCount())
+
+	// The connection was provided, so it is left open
+	require.Nil(t, publisher.Close())
+	assert.False(t, conn.disconnected)
+}
+
+func TestDecodeEventWithoutHeaders(t *testing.T) {
+	event, err := DecodeEvent(&gostomp.Message{
+		Header: frame.NewHeader(),
+		Body:   []byte(`{"name":"Thing","data":{}}`),
+	})
+	require.Nil(t, err)
+
+	assert.Equal(t, "Thing", event.Name())
+	assert.Equal(t, 0, event.RetryCount())
+}
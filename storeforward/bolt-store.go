@@ -0,0 +1,116 @@
+package storeforward
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltBucket = []byte("events")
+
+// BoltStore is a Store kept in a BoltDB file. Only one process can have the
+// file open at a time.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	if "" == path {
+		return nil, errors.New("Path is required")
+	}
+
+	// Fail rather than hang if another process has the file open
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Append(values [][]byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		for _, value := range values {
+			id, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			if err := bucket.Put(boltKey(id), value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *BoltStore) Oldest(limit int) ([]Record, error) {
+	records := []Record{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltBucket).Cursor()
+
+		for key, value := cursor.First(); key != nil && len(records) < limit; key, value = cursor.Next() {
+			// Values are only valid for the life of the transaction
+			records = append(records, Record{
+				ID:    binary.BigEndian.Uint64(key),
+				Value: append([]byte{}, value...),
+			})
+		}
+
+		return nil
+	})
+
+	return records, err
+}
+
+func (s *BoltStore) Remove(ids []uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		for _, id := range ids {
+			if err := bucket.Delete(boltKey(id)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *BoltStore) Len() (int, error) {
+	count := 0
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(boltBucket).Stats().KeyN
+		return nil
+	})
+
+	return count, err
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// boltKey encodes an ID so that keys sort in the order they were appended.
+func boltKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+
+	return key
+}
@@ -0,0 +1,61 @@
+package storeforward
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltStoreKeepsOrder(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "events.db"))
+	require.Nil(t, err)
+	defer store.Close()
+
+	require.Nil(t, store.Append([][]byte{[]byte("one"), []byte("two")}))
+	require.Nil(t, store.Append([][]byte{[]byte("three")}))
+
+	count, err := store.Len()
+	require.Nil(t, err)
+	assert.Equal(t, 3, count)
+
+	records, err := store.Oldest(2)
+	require.Nil(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "one", string(records[0].Value))
+	assert.Equal(t, "two", string(records[1].Value))
+
+	require.Nil(t, store.Remove([]uint64{records[0].ID, records[1].ID}))
+
+	records, err = store.Oldest(10)
+	require.Nil(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "three", string(records[0].Value))
+}
+
+func TestBoltStoreSurvivesReopening(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.db")
+
+	store, err := NewBoltStore(path)
+	require.Nil(t, err)
+	require.Nil(t, store.Append([][]byte{[]byte("one")}))
+	require.Nil(t, store.Close())
+
+	store, err = NewBoltStore(path)
+	require.Nil(t, err)
+	defer store.Close()
+
+	require.Nil(t, store.Append([][]byte{[]byte("two")}))
+
+	records, err := store.Oldest(10)
+	require.Nil(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "one", string(records[0].Value))
+	assert.Equal(t, "two", string(records[1].Value))
+}
+
+func TestNewBoltStoreRequiresPath(t *testing.T) {
+	_, err := NewBoltStore("")
+	assert.EqualError(t, err, "Path is required")
+}
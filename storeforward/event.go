@@ -0,0 +1,52 @@
+package storeforward
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// Event implements the standard domain event interface for events read back
+// from a Store. Their data has been through JSON, so numbers are float64.
+type Event struct {
+	name string
+	data map[string]interface{}
+}
+
+// encodedEvent is how events are kept in a Store.
+type encodedEvent struct {
+	Name string                 `json:"name"`
+	Data map[string]interface{} `json:"data"`
+}
+
+func encodeEvent(event gomainevents.Event) ([]byte, error) {
+	return json.Marshal(encodedEvent{
+		Name: event.Name(),
+		Data: event.Data(),
+	})
+}
+
+func decodeEvent(raw []byte) (*Event, error) {
+	e := &encodedEvent{}
+	if err := json.Unmarshal(raw, e); err != nil {
+		return nil, err
+	}
+
+	if "" == e.Name {
+		return nil, errors.New("Event has no name")
+	}
+
+	return &Event{
+		name: e.Name,
+		data: e.Data,
+	}, nil
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
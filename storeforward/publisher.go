@@ -0,0 +1,453 @@
+package storeforward
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/sns"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultRetryInterval = 5 * time.Second
+)
+
+// Publisher wraps another Publisher so that events are never lost when it
+// fails. Events that can't be published are kept in a local Store and
+// forwarded in order once the upstream publisher recovers. While anything
+// is waiting in the store, new events are stored behind it rather than
+// published directly, so that the upstream publisher sees them in the order
+// they were published.
+//
+// Events the upstream publisher can never publish, e.g. because they fail
+// validation or are too large, are not stored. Their errors are returned
+// by Publish, or reported if they were already stored.
+//
+// Delivery is at least once: an event may be published again if its
+// publish failed part way through.
+type Publisher struct {
+	publisher gomainevents.Publisher
+	store     Store
+	ownsStore bool
+
+	batchSize     int
+	retryInterval time.Duration
+	onError       gomainevents.ErrorHandler
+	clock         gomainevents.Clock
+
+	// Held while publishing so that stored and new events stay in order.
+	mu      sync.Mutex
+	pending int
+
+	done      chan bool
+	stopped   chan bool
+	closeOnce sync.Once
+	debug     bool
+}
+
+type Config struct {
+	// Publisher to forward events to. Publishers that retry on their own,
+	// such as sns.Publisher, can have their retries turned off, since
+	// failed events are retried here. Required
+	Publisher gomainevents.Publisher
+
+	// Path of the BoltDB file events are stored in. Required unless a
+	// Store is provided.
+	Path string
+
+	// Provide your own store instead of a BoltDB file. It is not closed by
+	// Close.
+	Store Store
+
+	// Maximum number of stored events forwarded at a time. Defaults to 100.
+	BatchSize int
+
+	// How long to wait between attempts to forward stored events.
+	// Defaults to 5 seconds.
+	RetryInterval time.Duration
+
+	// Called with errors from the upstream publisher. Optional
+	OnError gomainevents.ErrorHandler
+
+	// Clock retries are timed on. Defaults to gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewPublisher(config *Config) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if nil == config.Publisher {
+		return nil, errors.New("Publisher is required")
+	}
+
+	store, ownsStore := config.Store, false
+	if nil == store {
+		boltStore, err := NewBoltStore(config.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		store, ownsStore = boltStore, true
+	}
+
+	// Pick up anything left over from a previous run
+	pending, err := store.Len()
+	if err != nil {
+		if ownsStore {
+			store.Close()
+		}
+
+		return nil, err
+	}
+
+	batchSize := defaultBatchSize
+	if config.BatchSize > 0 {
+		batchSize = config.BatchSize
+	}
+
+	retryInterval := defaultRetryInterval
+	if config.RetryInterval > 0 {
+		retryInterval = config.RetryInterval
+	}
+
+	clock := gomainevents.ClockOrDefault(config.Clock)
+
+	p := &Publisher{
+		publisher:     config.Publisher,
+		store:         store,
+		ownsStore:     ownsStore,
+		batchSize:     batchSize,
+		retryInterval: retryInterval,
+		onError:       config.OnError,
+		clock:         clock,
+		pending:       pending,
+		done:          make(chan bool),
+		stopped:       make(chan bool),
+		debug:         true,
+	}
+
+	go p.run(pending > 0)
+
+	return p, nil
+}
+
+// Publish passes the event to the upstream publisher, or stores it to be
+// forwarded later. An error is only returned if the event could not be
+// stored, or can never be published.
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	return p.PublishBatch([]gomainevents.Event{event})
+}
+
+// PublishBatch passes the events to the upstream publisher, in a single
+// batch if it supports them, or stores them to be forwarded later. Only the
+// events that failed for now are stored.
+func (p *Publisher) PublishBatch(events []gomainevents.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	retry := events
+	var permanent error
+	if p.pending == 0 {
+		var err error
+		retry, permanent, err = p.publish(events)
+		if err != nil {
+			p.reportError(err)
+		}
+	}
+
+	if len(retry) == 0 {
+		return permanent
+	}
+
+	values := make([][]byte, len(retry))
+	for i, event := range retry {
+		value, err := encodeEvent(event)
+		if err != nil {
+			return err
+		}
+
+		values[i] = value
+	}
+
+	if err := p.store.Append(values); err != nil {
+		return err
+	}
+
+	p.pending += len(values)
+	p.debugPrint("Stored %d events. Pending: %d\n", len(values), p.pending)
+
+	return permanent
+}
+
+// Pending returns the number of events waiting to be forwarded.
+func (p *Publisher) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.pending
+}
+
+// Flush forwards stored events now rather than waiting for the next
+// attempt. It returns the upstream publisher's error if any are left.
+func (p *Publisher) Flush() error {
+	for {
+		more, err := p.forward()
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// Close stops forwarding. Stored events are kept for the next run.
+func (p *Publisher) Close() error {
+	var err error
+
+	p.closeOnce.Do(func() {
+		close(p.done)
+		<-p.stopped
+
+		if p.ownsStore {
+			err = p.store.Close()
+		}
+	})
+
+	return err
+}
+
+// run forwards stored events every RetryInterval, starting straight away if
+// there are some from a previous run.
+func (p *Publisher) run(leftOver bool) {
+	defer close(p.stopped)
+
+	if leftOver {
+		p.flushAndReport()
+	}
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-p.clock.After(p.retryInterval):
+			p.flushAndReport()
+		}
+	}
+}
+
+func (p *Publisher) flushAndReport() {
+	if err := p.Flush(); err != nil {
+		p.reportError(err)
+	}
+}
+
+// forward publishes a batch of stored events, returning whether there are
+// more to go.
+func (p *Publisher) forward() (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending == 0 {
+		return false, nil
+	}
+
+	records, err := p.store.Oldest(p.batchSize)
+	if err != nil {
+		return false, err
+	}
+
+	if len(records) == 0 {
+		// The store was emptied behind our back
+		p.pending = 0
+		return false, nil
+	}
+
+	// Records are removed once they're published, or will never be
+	ids := make([]uint64, 0, len(records))
+	stored := make(map[*Event]uint64, len(records))
+	events := make([]gomainevents.Event, 0, len(records))
+	for _, record := range records {
+		event, err := decodeEvent(record.Value)
+		if err != nil {
+			// It will never decode, so drop it
+			p.reportError(err)
+			ids = append(ids, record.ID)
+			continue
+		}
+
+		stored[event] = record.ID
+		events = append(events, event)
+	}
+
+	var publishErr error
+	if len(events) > 0 {
+		retry, permanent, err := p.publish(events)
+		if nil != permanent {
+			p.reportError(permanent)
+		}
+
+		keep, ok := storedIDs(stored, retry)
+		for _, id := range stored {
+			if ok && !keep[id] {
+				ids = append(ids, id)
+			}
+		}
+
+		publishErr = err
+	}
+
+	if err := p.store.Remove(ids); err != nil {
+		return false, err
+	}
+
+	p.pending -= len(ids)
+	if p.pending < 0 {
+		p.pending = 0
+	}
+
+	p.debugPrint("Forwarded %d events. Pending: %d\n", len(ids), p.pending)
+
+	if publishErr != nil {
+		return false, publishErr
+	}
+
+	return p.pending > 0, nil
+}
+
+// storedIDs returns the IDs of the records events were read from, or false
+// if any of them weren't, e.g. because the upstream publisher wrapped them.
+func storedIDs(stored map[*Event]uint64, events []gomainevents.Event) (map[uint64]bool, bool) {
+	ids := make(map[uint64]bool, len(events))
+	for _, event := range events {
+		evt, ok := event.(*Event) // Cast to storeforward flavor
+		if !ok {
+			return nil, false
+		}
+
+		id, ok := stored[evt]
+		if !ok {
+			return nil, false
+		}
+
+		ids[id] = true
+	}
+
+	return ids, true
+}
+
+// publish sends events upstream, as a batch if the publisher supports it.
+// It returns the events that failed for now and should be tried again,
+// with permanent set for any that will never be published, and err for
+// the rest.
+func (p *Publisher) publish(events []gomainevents.Event) (retry []gomainevents.Event, permanent error, err error) {
+	batchPublisher, ok := p.publisher.(gomainevents.BatchPublisher)
+	if ok && len(events) > 1 {
+		retry, failures, err := classify(events, batchPublisher.PublishBatch(events))
+		return retry, permanentError(failures), err
+	}
+
+	failures := []sns.BatchFailure{}
+	for i, event := range events {
+		retry, failed, err := classify(events[i:i+1], p.publisher.Publish(event))
+		failures = append(failures, failed...)
+
+		if len(retry) > 0 {
+			// Later events wait behind this one to keep them in order
+			return events[i:], permanentError(failures), err
+		}
+	}
+
+	return nil, permanentError(failures), nil
+}
+
+// classify sorts out the events an upstream publish failed for, returning
+// those worth trying again, with the error they failed with, and those
+// that will never be published.
+func classify(events []gomainevents.Event, err error) ([]gomainevents.Event, []sns.BatchFailure, error) {
+	if nil == err {
+		return nil, nil, nil
+	}
+
+	var batchErr *sns.BatchPublishError
+	if !errors.As(err, &batchErr) {
+		if isPermanent(err) {
+			failures := make([]sns.BatchFailure, len(events))
+			for i, event := range events {
+				failures[i] = sns.BatchFailure{Event: event, Err: err}
+			}
+
+			return nil, failures, nil
+		}
+
+		return events, nil, err
+	}
+
+	// Events not listed were published
+	retry := []gomainevents.Event{}
+	failures := []sns.BatchFailure{}
+	transient := []sns.BatchFailure{}
+	for _, failure := range batchErr.Failures {
+		if failure.SenderFault || isPermanent(failure.Err) {
+			failures = append(failures, failure)
+			continue
+		}
+
+		retry = append(retry, failure.Event)
+		transient = append(transient, failure)
+	}
+
+	if 0 == len(transient) {
+		return nil, failures, nil
+	}
+
+	return retry, failures, &sns.BatchPublishError{Failures: transient}
+}
+
+// permanentError returns an error for events that will never be published,
+// or nil if there are none.
+func permanentError(failures []sns.BatchFailure) error {
+	switch len(failures) {
+	case 0:
+		return nil
+	case 1:
+		if nil != failures[0].Err {
+			return failures[0].Err
+		}
+	}
+
+	return &sns.BatchPublishError{Failures: failures}
+}
+
+// isPermanent reports whether the upstream publisher failed in a way that
+// retrying will never fix, e.g. because the event is invalid or too large.
+func isPermanent(err error) bool {
+	var validationErr *sns.ValidationError
+	var tooLargeErr *sns.MessageTooLargeError
+	var publishErr *sns.PermanentPublishError
+
+	return gomainevents.IsPermanent(err) ||
+		errors.As(err, &validationErr) ||
+		errors.As(err, &tooLargeErr) ||
+		errors.As(err, &publishErr)
+}
+
+func (p *Publisher) reportError(err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	if nil != p.onError {
+		p.onError(err)
+	}
+}
+
+func (p *Publisher) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-storeforward] "+format, values...)
+	}
+}
@@ -0,0 +1,282 @@
+package storeforward
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/gomaineventstest"
+	"github.com/researchsquare/gomainevents/sns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return e.data
+}
+
+// flakyPublisher fails while it is down.
+type flakyPublisher struct {
+	mu        sync.Mutex
+	down      bool
+	published []string
+}
+
+func (p *flakyPublisher) Publish(event gomainevents.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.down {
+		return errors.New("Upstream is down")
+	}
+
+	p.published = append(p.published, event.Name())
+	return nil
+}
+
+func (p *flakyPublisher) setDown(down bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.down = down
+}
+
+func (p *flakyPublisher) names() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string{}, p.published...)
+}
+
+func newTestPublisher(t *testing.T, upstream gomainevents.Publisher, path string, onError gomainevents.ErrorHandler) *Publisher {
+	publisher, err := NewPublisher(&Config{
+		Publisher: upstream,
+		Path:      path,
+		OnError:   onError,
+
+		// Tests flush by hand
+		RetryInterval: time.Hour,
+	})
+	require.Nil(t, err)
+
+	publisher.debug = false
+
+	return publisher
+}
+
+func TestPublisherPassesEventsThrough(t *testing.T) {
+	upstream := &flakyPublisher{}
+	publisher := newTestPublisher(t, upstream, filepath.Join(t.TempDir(), "events.db"), nil)
+	defer publisher.Close()
+
+	require.Nil(t, publisher.Publish(testEvent{name: "First"}))
+
+	assert.Equal(t, []string{"First"}, upstream.names())
+	assert.Equal(t, 0, publisher.Pending())
+}
+
+func TestPublisherRetriesOnClock(t *testing.T) {
+	clock := gomaineventstest.NewClock(time.Now())
+	upstream := &flakyPublisher{down: true}
+
+	publisher, err := NewPublisher(&Config{
+		Publisher:     upstream,
+		Path:          filepath.Join(t.TempDir(), "events.db"),
+		RetryInterval: time.Minute,
+		Clock:         clock,
+	})
+	require.Nil(t, err)
+	publisher.debug = false
+	defer publisher.Close()
+
+	require.Nil(t, publisher.Publish(testEvent{name: "First"}))
+	upstream.setDown(false)
+
+	assert.Eventually(t, func() bool { return clock.Pending() == 1 }, 5*time.Second, time.Millisecond)
+	clock.Advance(59 * time.Second)
+	assert.Equal(t, 1, publisher.Pending())
+
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool { return publisher.Pending() == 0 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, []string{"First"}, upstream.names())
+}
+
+func TestPublisherStoresAndForwardsInOrder(t *testing.T) {
+	upstream := &flakyPublisher{down: true}
+	var reported []error
+	publisher := newTestPublisher(t, upstream, filepath.Join(t.TempDir(), "events.db"), func(err error) {
+		reported = append(reported, err)
+	})
+	defer publisher.Close()
+
+	require.Nil(t, publisher.Publish(testEvent{name: "First"}))
+	require.Nil(t, publisher.PublishBatch([]gomainevents.Event{testEvent{name: "Second"}, testEvent{name: "Third"}}))
+	assert.Equal(t, 3, publisher.Pending())
+
+	// Only the first failure reaches upstream; the rest queue behind it
+	assert.Len(t, reported, 1)
+
+	assert.EqualError(t, publisher.Flush(), "Upstream is down")
+	assert.Equal(t, 3, publisher.Pending())
+
+	// Upstream is back, but new events still queue behind the stored ones
+	upstream.setDown(false)
+	require.Nil(t, publisher.Publish(testEvent{name: "Fourth"}))
+	assert.Empty(t, upstream.names())
+
+	require.Nil(t, publisher.Flush())
+	assert.Equal(t, []string{"First", "Second", "Third", "Fourth"}, upstream.names())
+	assert.Equal(t, 0, publisher.Pending())
+
+	require.Nil(t, publisher.Publish(testEvent{name: "Fifth"}))
+	assert.Equal(t, []string{"First", "Second", "Third", "Fourth", "Fifth"}, upstream.names())
+}
+
+func TestPublisherForwardsEventsFromPreviousRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.db")
+
+	upstream := &flakyPublisher{down: true}
+	publisher := newTestPublisher(t, upstream, path, nil)
+	require.Nil(t, publisher.Publish(testEvent{name: "UserCreated", data: map[string]interface{}{"userId": 12}}))
+	require.Nil(t, publisher.Close())
+
+	upstream = &flakyPublisher{}
+	publisher, err := NewPublisher(&Config{Publisher: upstream, Path: path, RetryInterval: time.Hour})
+	require.Nil(t, err)
+	defer publisher.Close()
+
+	// Forwarded as soon as it starts
+	assert.Eventually(t, func() bool {
+		return len(upstream.names()) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"UserCreated"}, upstream.names())
+}
+
+func TestPublisherUsesBatches(t *testing.T) {
+	upstream := &batchPublisher{}
+	publisher := newTestPublisher(t, upstream, filepath.Join(t.TempDir(), "events.db"), nil)
+	defer publisher.Close()
+
+	require.Nil(t, publisher.PublishBatch([]gomainevents.Event{testEvent{name: "First"}, testEvent{name: "Second"}}))
+	assert.Equal(t, []int{2}, upstream.batches)
+}
+
+// partialPublisher publishes batches, failing the events named in
+// transient and invalid.
+type partialPublisher struct {
+	mu        sync.Mutex
+	transient map[string]bool
+	invalid   map[string]bool
+	published []string
+}
+
+func (p *partialPublisher) Publish(event gomainevents.Event) error {
+	return p.PublishBatch([]gomainevents.Event{event})
+}
+
+func (p *partialPublisher) PublishBatch(events []gomainevents.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	failures := []sns.BatchFailure{}
+	for _, event := range events {
+		switch {
+		case p.invalid[event.Name()]:
+			failures = append(failures, sns.BatchFailure{Event: event, Err: &sns.ValidationError{EventName: event.Name(), Err: errors.New("Invalid")}})
+		case p.transient[event.Name()]:
+			failures = append(failures, sns.BatchFailure{Event: event, Code: "InternalError"})
+		default:
+			p.published = append(p.published, event.Name())
+		}
+	}
+
+	if len(failures) > 0 {
+		return &sns.BatchPublishError{Failures: failures}
+	}
+
+	return nil
+}
+
+func (p *partialPublisher) setTransient(names ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.transient = map[string]bool{}
+	for _, name := range names {
+		p.transient[name] = true
+	}
+}
+
+func TestPublisherReturnsPermanentErrors(t *testing.T) {
+	upstream := &partialPublisher{invalid: map[string]bool{"Invalid": true}}
+	publisher := newTestPublisher(t, upstream, filepath.Join(t.TempDir(), "events.db"), nil)
+	defer publisher.Close()
+
+	var validationErr *sns.ValidationError
+	err := publisher.Publish(testEvent{name: "Invalid"})
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 0, publisher.Pending())
+}
+
+func TestPublisherStoresOnlyTransientBatchFailures(t *testing.T) {
+	upstream := &partialPublisher{invalid: map[string]bool{"Invalid": true}}
+	upstream.setTransient("Flaky")
+
+	var reported []error
+	publisher := newTestPublisher(t, upstream, filepath.Join(t.TempDir(), "events.db"), func(err error) {
+		reported = append(reported, err)
+	})
+	defer publisher.Close()
+
+	err := publisher.PublishBatch([]gomainevents.Event{testEvent{name: "First"}, testEvent{name: "Flaky"}, testEvent{name: "Invalid"}})
+	var validationErr *sns.ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Len(t, reported, 1)
+	assert.Equal(t, 1, publisher.Pending())
+
+	// Stored events that fail for good don't hold up the rest
+	upstream.setTransient("Flaky", "Second")
+	require.Nil(t, publisher.PublishBatch([]gomainevents.Event{testEvent{name: "Invalid"}, testEvent{name: "Second"}, testEvent{name: "Third"}}))
+	assert.Equal(t, 4, publisher.Pending())
+
+	upstream.setTransient("Second")
+	assert.NotNil(t, publisher.Flush())
+	assert.Equal(t, 1, publisher.Pending())
+
+	upstream.setTransient()
+	require.Nil(t, publisher.Flush())
+	assert.Equal(t, 0, publisher.Pending())
+
+	// Nothing was published twice
+	assert.Equal(t, []string{"First", "Flaky", "Third", "Second"}, upstream.published)
+}
+
+type batchPublisher struct {
+	batches []int
+}
+
+func (p *batchPublisher) Publish(event gomainevents.Event) error {
+	return p.PublishBatch([]gomainevents.Event{event})
+}
+
+func (p *batchPublisher) PublishBatch(events []gomainevents.Event) error {
+	p.batches = append(p.batches, len(events))
+	return nil
+}
+
+func TestNewPublisherRequiresPublisher(t *testing.T) {
+	_, err := NewPublisher(&Config{Path: filepath.Join(t.TempDir(), "events.db")})
+	assert.EqualError(t, err, "Publisher is required")
+}
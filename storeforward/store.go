@@ -0,0 +1,27 @@
+package storeforward
+
+// Store keeps events that could not be published, in the order they were
+// appended, until they have been forwarded.
+type Store interface {
+	// Append adds encoded events to the end of the store
+	Append(values [][]byte) error
+
+	// Oldest returns up to limit records from the front of the store
+	Oldest(limit int) ([]Record, error)
+
+	// Remove deletes records once they have been forwarded
+	Remove(ids []uint64) error
+
+	// Len returns the number of records in the store
+	Len() (int, error)
+
+	// Close releases the store's resources
+	Close() error
+}
+
+// Record is an encoded event held in a Store. IDs increase in the order
+// records were appended.
+type Record struct {
+	ID    uint64
+	Value []byte
+}
@@ -0,0 +1,71 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+type contextKey struct{}
+
+// tenantEvent is an event that carries the tenant it belongs to.
+type tenantEvent struct {
+	gomainevents.Event
+	tenant string
+}
+
+func wrap(event gomainevents.Event, tenant string) gomainevents.Event {
+	return tenantEvent{Event: event, tenant: tenant}
+}
+
+// ID returns the tenant the Router worked out an event belongs to, or ""
+// if it doesn't belong to one.
+func ID(event gomainevents.Event) string {
+	for nil != event {
+		if e, ok := event.(tenantEvent); ok {
+			return e.tenant
+		}
+
+		wrapper, ok := event.(interface{ Unwrap() gomainevents.Event })
+		if !ok {
+			break
+		}
+
+		event = wrapper.Unwrap()
+	}
+
+	return ""
+}
+
+// Context returns a context carrying the tenant an event belongs to, for
+// passing on to code that is tenant-aware. See FromContext.
+func Context(event gomainevents.Event) context.Context {
+	return NewContext(context.Background(), ID(event))
+}
+
+// NewContext returns a copy of ctx that carries a tenant ID.
+func NewContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant ID ctx carries, or "" if it doesn't carry
+// one.
+func FromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(contextKey{}).(string)
+	return tenant
+}
+
+// RawData passes on the JSON the original event was received as, so that
+// gomainevents.DecodeData stays cheap.
+func (e tenantEvent) RawData() []byte {
+	if raw, ok := e.Event.(gomainevents.RawEvent); ok {
+		return raw.RawData()
+	}
+
+	return nil
+}
+
+// Unwrap returns the original event.
+func (e tenantEvent) Unwrap() gomainevents.Event {
+	return e.Event
+}
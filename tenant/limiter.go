@@ -0,0 +1,76 @@
+package tenant
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// limiter keeps a token bucket per tenant.
+type limiter struct {
+	defaults Limit
+	limits   map[string]Limit
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+
+	// Hook for tests
+	now func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+func newLimiter(defaults Limit, limits map[string]Limit) *limiter {
+	copied := map[string]Limit{}
+	for tenant, limit := range limits {
+		copied[tenant] = limit
+	}
+
+	return &limiter{
+		defaults: defaults,
+		limits:   copied,
+		buckets:  map[string]*bucket{},
+		now:      time.Now,
+	}
+}
+
+// allow takes a token from a tenant's bucket, returning false if there
+// isn't one.
+func (l *limiter) allow(tenant string) bool {
+	limit, ok := l.limits[tenant]
+	if !ok {
+		limit = l.defaults
+	}
+
+	if limit.Rate <= 0 {
+		return true
+	}
+
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = math.Ceil(limit.Rate)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[tenant]
+	if !ok {
+		b = &bucket{tokens: burst, at: now}
+		l.buckets[tenant] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.at).Seconds()*limit.Rate)
+	b.at = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
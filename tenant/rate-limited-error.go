@@ -0,0 +1,16 @@
+package tenant
+
+import (
+	"fmt"
+)
+
+// RateLimitedError is returned for an event whose tenant is over its rate
+// limit, so that the Listener requeues it for later.
+type RateLimitedError struct {
+	EventName string
+	Tenant    string
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("Tenant %s is over its rate limit: %s", e.Tenant, e.EventName)
+}
@@ -0,0 +1,136 @@
+package tenant
+
+import (
+	"errors"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+// DataField is the field of an event's data the tenant ID is read from by
+// default.
+const DataField = "tenantId"
+
+// Router lets a single consumer serve many tenants. Each event's tenant is
+// worked out from the event, and its handlers are picked from those
+// registered for that tenant, falling back to the ones registered for
+// every tenant:
+//
+//	router.RegisterDefault("InvoicePaid", sendReceipt)
+//	router.Register("acme", "InvoicePaid", sendAcmeReceipt)
+//	listener.RegisterHandler("InvoicePaid", router.Handler("InvoicePaid"))
+//
+// Handlers are passed an event that carries its tenant; see ID and
+// Context. With a Rate set, each tenant's events are limited to it, so
+// that one busy tenant can't starve the others. Events over their
+// tenant's limit fail with a *RateLimitedError, so that the Listener
+// requeues them for later. Events without a tenant use the default
+// handlers and aren't limited.
+type Router struct {
+	tenantID  func(gomainevents.Event) string
+	limiter   *limiter
+	onLimited func(gomainevents.Event)
+
+	// Handlers by tenant, then event name. Default handlers are under "".
+	handlers map[string]map[string][]gomainevents.EventHandler
+}
+
+type Config struct {
+	// Returns the tenant an event belongs to, or "" if it doesn't belong
+	// to one. Defaults to the event's "tenantId" data field.
+	TenantID func(gomainevents.Event) string
+
+	// Events each tenant can have handled per second. Zero leaves tenants
+	// unlimited.
+	Rate float64
+
+	// Events a tenant can have handled at once over Rate, e.g. after being
+	// idle. Defaults to Rate, rounded up.
+	Burst int
+
+	// Limits for particular tenants, e.g. ones on a bigger plan, by
+	// tenant ID. They override Rate and Burst.
+	Limits map[string]Limit
+
+	// Called for each event that is over its tenant's limit.
+	OnLimited func(gomainevents.Event)
+}
+
+// Limit is how many events a tenant can have handled. See Config.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func NewRouter(config *Config) (*Router, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if config.Rate < 0 {
+		return nil, errors.New("Rate can't be negative")
+	}
+
+	tenantID := config.TenantID
+	if nil == tenantID {
+		tenantID = dataTenantID
+	}
+
+	return &Router{
+		tenantID:  tenantID,
+		limiter:   newLimiter(Limit{Rate: config.Rate, Burst: config.Burst}, config.Limits),
+		onLimited: config.OnLimited,
+		handlers:  map[string]map[string][]gomainevents.EventHandler{},
+	}, nil
+}
+
+// Register adds a handler for a tenant's events with the given name. A
+// tenant's handlers replace the default ones for that event, rather than
+// running as well.
+func (r *Router) Register(tenant string, name string, fn gomainevents.EventHandler) {
+	if nil == r.handlers[tenant] {
+		r.handlers[tenant] = map[string][]gomainevents.EventHandler{}
+	}
+
+	r.handlers[tenant][name] = append(r.handlers[tenant][name], fn)
+}
+
+// RegisterDefault adds a handler for the events with the given name of
+// tenants that don't have their own.
+func (r *Router) RegisterDefault(name string, fn gomainevents.EventHandler) {
+	r.Register("", name, fn)
+}
+
+// Handler returns a handler for the events with the given name that
+// passes each one to its tenant's handlers. Register it with a Listener.
+func (r *Router) Handler(name string) gomainevents.EventHandler {
+	return func(event gomainevents.Event) error {
+		tenant := r.tenantID(event)
+
+		if "" != tenant && !r.limiter.allow(tenant) {
+			if nil != r.onLimited {
+				r.onLimited(event)
+			}
+
+			return &RateLimitedError{EventName: event.Name(), Tenant: tenant}
+		}
+
+		handlers, ok := r.handlers[tenant][name]
+		if !ok {
+			handlers = r.handlers[""][name]
+		}
+
+		wrapped := wrap(event, tenant)
+		for _, fn := range handlers {
+			if err := fn(wrapped); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func dataTenantID(event gomainevents.Event) string {
+	id, _ := event.Data()[DataField].(string)
+	return id
+}
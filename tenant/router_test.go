@@ -0,0 +1,142 @@
+package tenant
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return e.data
+}
+
+func newEvent(tenant string) gomainevents.Event {
+	data := map[string]interface{}{}
+	if "" != tenant {
+		data[DataField] = tenant
+	}
+
+	return testEvent{name: "InvoicePaid", data: data}
+}
+
+func TestNewRouter(t *testing.T) {
+	_, err := NewRouter(nil)
+	assert.EqualError(t, err, "Configuration is required")
+
+	_, err = NewRouter(&Config{Rate: -1})
+	assert.EqualError(t, err, "Rate can't be negative")
+
+	router, err := NewRouter(&Config{})
+	assert.NoError(t, err)
+	assert.NotNil(t, router)
+}
+
+func TestHandlerRoutesByTenant(t *testing.T) {
+	router, _ := NewRouter(&Config{})
+
+	var handled []string
+	router.RegisterDefault("InvoicePaid", func(event gomainevents.Event) error {
+		handled = append(handled, "default:"+ID(event))
+		return nil
+	})
+	router.Register("acme", "InvoicePaid", func(event gomainevents.Event) error {
+		handled = append(handled, "acme:"+ID(event))
+		return nil
+	})
+
+	handler := router.Handler("InvoicePaid")
+	assert.NoError(t, handler(newEvent("acme")))
+	assert.NoError(t, handler(newEvent("globex")))
+	assert.NoError(t, handler(newEvent("")))
+
+	assert.Equal(t, []string{"acme:acme", "default:globex", "default:"}, handled)
+}
+
+func TestHandlerReturnsHandlerErrors(t *testing.T) {
+	router, _ := NewRouter(&Config{})
+	router.RegisterDefault("InvoicePaid", func(gomainevents.Event) error {
+		return errors.New("Failed")
+	})
+
+	assert.EqualError(t, router.Handler("InvoicePaid")(newEvent("acme")), "Failed")
+}
+
+func TestHandlerUsesTenantIDFunc(t *testing.T) {
+	router, _ := NewRouter(&Config{
+		TenantID: func(event gomainevents.Event) string {
+			return event.Data()["org"].(string)
+		},
+	})
+
+	var tenant string
+	router.RegisterDefault("InvoicePaid", func(event gomainevents.Event) error {
+		tenant = FromContext(Context(event))
+		return nil
+	})
+
+	event := testEvent{name: "InvoicePaid", data: map[string]interface{}{"org": "acme"}}
+	assert.NoError(t, router.Handler("InvoicePaid")(event))
+	assert.Equal(t, "acme", tenant)
+}
+
+func TestHandlerRateLimitsEachTenant(t *testing.T) {
+	var limited []gomainevents.Event
+	router, _ := NewRouter(&Config{
+		Rate:   1,
+		Burst:  2,
+		Limits: map[string]Limit{"globex": {Rate: 10}},
+		OnLimited: func(event gomainevents.Event) {
+			limited = append(limited, event)
+		},
+	})
+
+	now := time.Now()
+	router.limiter.now = func() time.Time { return now }
+
+	handled := 0
+	router.RegisterDefault("InvoicePaid", func(gomainevents.Event) error {
+		handled++
+		return nil
+	})
+
+	handler := router.Handler("InvoicePaid")
+	assert.NoError(t, handler(newEvent("acme")))
+	assert.NoError(t, handler(newEvent("acme")))
+
+	err := handler(newEvent("acme"))
+	assert.Equal(t, &RateLimitedError{EventName: "InvoicePaid", Tenant: "acme"}, err)
+	assert.Len(t, limited, 1)
+
+	// Other tenants and events without one aren't affected
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, handler(newEvent("globex")))
+		assert.NoError(t, handler(newEvent("")))
+	}
+	assert.Error(t, handler(newEvent("globex")))
+
+	now = now.Add(time.Second)
+	assert.NoError(t, handler(newEvent("acme")))
+	assert.Error(t, handler(newEvent("acme")))
+
+	assert.Equal(t, 23, handled)
+}
+
+func TestID(t *testing.T) {
+	event := newEvent("acme")
+	assert.Equal(t, "", ID(event))
+	assert.Equal(t, "acme", ID(wrap(event, "acme")))
+	assert.Equal(t, event, wrap(event, "acme").(tenantEvent).Unwrap())
+	assert.Equal(t, "", FromContext(Context(event)))
+}
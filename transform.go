@@ -0,0 +1,53 @@
+package gomainevents
+
+// Transformer turns an event received from the provider into the one its
+// handlers are passed, e.g. resolving references, renaming legacy fields or
+// attaching tenant information, so that each handler doesn't have to. It
+// returns the event unchanged if there's nothing to do, and an error to fail
+// the event as if a handler had. See Listener.RegisterTransformer.
+type Transformer func(Event) (Event, error)
+
+// RegisterTransformer adds a transformer that runs on each event with
+// handlers before they're passed it. Transformers run in the order they're
+// registered, each passed the event the previous one returned, and the
+// handlers run are those for the name of the event the last one returns.
+// The provider is still passed the event it delivered, to delete or requeue.
+func (l *Listener) RegisterTransformer(fn Transformer) {
+	l.transformers = append(l.transformers, fn)
+}
+
+// transform runs the transformers on an event.
+func (l *Listener) transform(event Event) (Event, error) {
+	for _, fn := range l.transformers {
+		transformed, err := fn(event)
+		if err != nil {
+			return nil, err
+		}
+
+		event = transformed
+	}
+
+	return event, nil
+}
+
+// WithData returns an event like the given one but with different data, for
+// transformers to return. The original event can be got back with Unwrap.
+func WithData(event Event, data map[string]interface{}) Event {
+	return dataEvent{Event: event, data: data}
+}
+
+// dataEvent is an event whose data was replaced. It deliberately doesn't
+// pass on RawData, which no longer matches.
+type dataEvent struct {
+	Event
+	data map[string]interface{}
+}
+
+func (e dataEvent) Data() map[string]interface{} {
+	return e.data
+}
+
+// Unwrap returns the original event.
+func (e dataEvent) Unwrap() Event {
+	return e.Event
+}
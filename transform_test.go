@@ -0,0 +1,91 @@
+package gomainevents
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenerTransformsEvents(t *testing.T) {
+	provider := &recordingProvider{events: make(chan Event)}
+
+	listener := NewListener(provider)
+	listener.debug = false
+
+	listener.RegisterTransformer(func(event Event) (Event, error) {
+		data := map[string]interface{}{}
+		for key, value := range event.Data() {
+			data[key] = value
+		}
+
+		// Normalize a legacy field name
+		if id, ok := data["user_id"]; ok {
+			data["userId"] = id
+			delete(data, "user_id")
+		}
+
+		return WithData(event, data), nil
+	})
+	listener.RegisterTransformer(func(event Event) (Event, error) {
+		if nil == event.Data()["userId"] {
+			return nil, errors.New("No user")
+		}
+
+		return event, nil
+	})
+
+	handled := make(chan Event, 1)
+	listener.RegisterHandler("Created", func(event Event) error {
+		handled <- event
+		return nil
+	})
+
+	go listener.Listen()
+	defer func() { listener.done <- true }()
+
+	original := testEvent{name: "Created", data: map[string]interface{}{"user_id": 1}}
+	provider.events <- original
+
+	event := <-handled
+	assert.Equal(t, map[string]interface{}{"userId": 1}, event.Data())
+	assert.Equal(t, original, event.(interface{ Unwrap() Event }).Unwrap())
+
+	// A failing transformer fails the event
+	provider.events <- testEvent{name: "Created", data: map[string]interface{}{}}
+	assert.Eventually(t, func() bool { return listener.Stats().Failed == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	provider.mu.Lock()
+	assert.Equal(t, []Event{original}, provider.deleted)
+	assert.Len(t, provider.requeued, 1)
+	provider.mu.Unlock()
+	assert.Len(t, handled, 0)
+}
+
+func TestListenerTransformSkipsEventsWithoutHandlers(t *testing.T) {
+	listener := NewListener(nil)
+	listener.debug = false
+
+	transformed := 0
+	listener.RegisterTransformer(func(event Event) (Event, error) {
+		transformed++
+		return testEvent{name: "Renamed"}, nil
+	})
+
+	renamed := 0
+	listener.RegisterHandler("Created", func(Event) error { return errors.New("Not renamed") })
+	listener.RegisterHandler("Renamed", func(Event) error {
+		renamed++
+		return nil
+	})
+
+	_, err := listener.handleEvent(testEvent{name: "Unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, transformed)
+
+	_, err = listener.handleEvent(testEvent{name: "Created"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, transformed)
+	assert.Equal(t, 1, renamed)
+}
@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"fmt"
+)
+
+// DeliveryError describes a failed delivery to a single endpoint.
+type DeliveryError struct {
+	URL string
+
+	// StatusCode is the last response status, or zero if no response was
+	// received.
+	StatusCode int
+
+	Attempts int
+	Err      error
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("Failed to deliver to %s after %d attempt(s): %s", e.URL, e.Attempts, e.Err)
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// PublishError is returned by Publish when the event could not be delivered
+// to one or more endpoints. Endpoints not listed received it.
+type PublishError struct {
+	EventName string
+	Failures  []*DeliveryError
+}
+
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("Failed to deliver event %s to %d endpoint(s)", e.EventName, len(e.Failures))
+}
@@ -0,0 +1,246 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultMaximumRetryCount = 3
+	defaultTimeout           = 10 * time.Second
+)
+
+// Endpoint is a webhook URL that events are delivered to.
+type Endpoint struct {
+	URL string
+
+	// Secret used to sign deliveries. Optional, but strongly recommended
+	// so receivers can check deliveries came from us.
+	Secret string
+
+	// Only deliver these events. Empty means every event.
+	Events []string
+}
+
+func (e Endpoint) wants(name string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+
+	for _, event := range e.Events {
+		if event == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Publisher POSTs events to webhook endpoints, e.g. ones run by external
+// partners. Each delivery is a JSON body of the form
+//
+//	{"name": "ThingHappened", "data": {...}}
+//
+// signed with the endpoint's secret; see Sign and Verify.
+type Publisher struct {
+	endpoints  []Endpoint
+	httpClient *http.Client
+	header     http.Header
+
+	maximumRetryCount int
+	retryDelay        func(attempt int) time.Duration
+
+	debug bool
+}
+
+type Config struct {
+	// Endpoints to deliver to. Required
+	Endpoints []Endpoint
+
+	// Provide your own HTTP client. Defaults to one with a 10 second
+	// timeout.
+	HTTPClient *http.Client
+
+	// Extra headers sent with every delivery, e.g. a User-Agent.
+	Header http.Header
+
+	// How many times a failed delivery is retried. Defaults to 3.
+	MaximumRetryCount int
+}
+
+type encodedEvent struct {
+	Name string                 `json:"name"`
+	Data map[string]interface{} `json:"data"`
+}
+
+func NewPublisher(config *Config) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if len(config.Endpoints) == 0 {
+		return nil, errors.New("Endpoints are required")
+	}
+
+	for _, endpoint := range config.Endpoints {
+		if "" == endpoint.URL {
+			return nil, errors.New("Endpoint URL is required")
+		}
+	}
+
+	httpClient := config.HTTPClient
+	if nil == httpClient {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	return &Publisher{
+		endpoints:         config.Endpoints,
+		httpClient:        httpClient,
+		header:            config.Header,
+		maximumRetryCount: maximumRetryCount,
+		retryDelay:        defaultRetryDelay,
+		debug:             true,
+	}, nil
+}
+
+// Publish delivers the event to every endpoint that wants it, in parallel.
+// It returns a PublishError listing the endpoints that still failed after
+// retrying.
+func (p *Publisher) Publish(event gomainevents.Event) error {
+	body, err := json.Marshal(&encodedEvent{
+		Name: event.Name(),
+		Data: event.Data(),
+	})
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures []*DeliveryError
+	)
+
+	for _, endpoint := range p.endpoints {
+		if !endpoint.wants(event.Name()) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(endpoint Endpoint) {
+			defer wg.Done()
+
+			if err := p.deliver(endpoint, event.Name(), body); err != nil {
+				mu.Lock()
+				failures = append(failures, err)
+				mu.Unlock()
+			}
+		}(endpoint)
+	}
+
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return &PublishError{EventName: event.Name(), Failures: failures}
+	}
+
+	return nil
+}
+
+// deliver POSTs the body to an endpoint, retrying server errors and
+// connection failures.
+func (p *Publisher) deliver(endpoint Endpoint, name string, body []byte) *DeliveryError {
+	var (
+		statusCode int
+		err        error
+	)
+
+	for attempt := 0; attempt <= p.maximumRetryCount; attempt++ {
+		if attempt > 0 {
+			p.debugPrint("Retrying delivery to %s (attempt %d): %s\n", endpoint.URL, attempt, err)
+			time.Sleep(p.retryDelay(attempt))
+		}
+
+		var retryable bool
+		statusCode, retryable, err = p.post(endpoint, name, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retryable {
+			return &DeliveryError{URL: endpoint.URL, StatusCode: statusCode, Attempts: attempt + 1, Err: err}
+		}
+	}
+
+	return &DeliveryError{URL: endpoint.URL, StatusCode: statusCode, Attempts: p.maximumRetryCount + 1, Err: err}
+}
+
+// post makes a single delivery attempt. It reports the response status and
+// whether a failure is worth retrying.
+func (p *Publisher) post(endpoint Endpoint, name string, body []byte) (int, bool, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+
+	for key, values := range p.header {
+		req.Header[key] = values
+	}
+
+	// Signed with the time of each attempt so retries aren't rejected as
+	// replays.
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, name)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if "" != endpoint.Secret {
+		req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+
+	retryable := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+	return resp.StatusCode, retryable, fmt.Errorf("Unexpected status: %s", resp.Status)
+}
+
+// defaultRetryDelay backs off exponentially from 500ms, up to 30 seconds.
+func defaultRetryDelay(attempt int) time.Duration {
+	delay := 500 * time.Millisecond << uint(attempt-1)
+	if delay > 30*time.Second || delay <= 0 {
+		return 30 * time.Second
+	}
+
+	return delay
+}
+
+func (p *Publisher) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-webhook] "+format, values...)
+	}
+}
@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func noDelay(int) time.Duration {
+	return 0
+}
+
+func newTestPublisher(t *testing.T, endpoints ...Endpoint) *Publisher {
+	publisher, err := NewPublisher(&Config{Endpoints: endpoints})
+	require.Nil(t, err)
+	publisher.retryDelay = noDelay
+
+	return publisher
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(&Config{Endpoints: []Endpoint{{URL: "http://localhost"}}})
+	assert.NotNil(t, publisher)
+	assert.Nil(t, err)
+
+	publisher, err = NewPublisher(&Config{Endpoints: []Endpoint{{}}})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(&Config{})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisher(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
+
+func TestPublishSignsDeliveries(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var body []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+	}))
+	defer server.Close()
+
+	publisher := newTestPublisher(t, Endpoint{URL: server.URL, Secret: "s3cret"})
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+
+	r := <-received
+	assert.Equal(t, "Thing", r.Header.Get(HeaderEvent))
+	assert.JSONEq(t, `{"name":"Thing","data":{"occurredOn":"2018-03-08 11:11:11"}}`, string(body))
+
+	timestamp, signature := r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature)
+	assert.Nil(t, Verify("s3cret", timestamp, signature, body, time.Minute))
+	assert.NotNil(t, Verify("wrong", timestamp, signature, body, time.Minute))
+	assert.NotNil(t, Verify("s3cret", timestamp, signature, []byte("tampered"), time.Minute))
+	assert.NotNil(t, Verify("s3cret", "1520507471", signature, body, time.Minute))
+}
+
+func TestPublishFiltersByEventName(t *testing.T) {
+	var wanted, unwanted int32
+
+	wantedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&wanted, 1)
+	}))
+	defer wantedServer.Close()
+
+	unwantedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&unwanted, 1)
+	}))
+	defer unwantedServer.Close()
+
+	publisher := newTestPublisher(t,
+		Endpoint{URL: wantedServer.URL, Events: []string{"Thing"}},
+		Endpoint{URL: unwantedServer.URL, Events: []string{"OtherThing"}},
+	)
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&wanted))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&unwanted))
+}
+
+func TestPublishRetries(t *testing.T) {
+	var attempts int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	publisher := newTestPublisher(t, Endpoint{URL: server.URL})
+	require.Nil(t, publisher.Publish(testEvent{name: "Thing"}))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestPublishFailures(t *testing.T) {
+	var attempts int32
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+
+	publisher := newTestPublisher(t, Endpoint{URL: unavailable.URL}, Endpoint{URL: rejecting.URL}, Endpoint{URL: ok.URL})
+	err := publisher.Publish(testEvent{name: "Thing"})
+	require.IsType(t, &PublishError{}, err)
+
+	failures := map[string]*DeliveryError{}
+	for _, failure := range err.(*PublishError).Failures {
+		failures[failure.URL] = failure
+	}
+	require.Len(t, failures, 2)
+
+	// Retried until attempts ran out
+	assert.Equal(t, http.StatusServiceUnavailable, failures[unavailable.URL].StatusCode)
+	assert.Equal(t, 4, failures[unavailable.URL].Attempts)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&attempts))
+
+	// Client errors aren't retried
+	assert.Equal(t, http.StatusBadRequest, failures[rejecting.URL].StatusCode)
+	assert.Equal(t, 1, failures[rejecting.URL].Attempts)
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderEvent     = "X-Gomainevents-Event"
+	HeaderTimestamp = "X-Gomainevents-Timestamp"
+	HeaderSignature = "X-Gomainevents-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign returns the signature header value for a delivery: an HMAC-SHA256 of
+// the timestamp and body, keyed with the endpoint's secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery's signature and that its timestamp is within
+// tolerance of now, to guard against replays. It is meant for receivers of
+// webhooks written in Go.
+func Verify(secret string, timestamp string, signature string, body []byte, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("Invalid timestamp")
+	}
+
+	if tolerance > 0 {
+		age := time.Since(time.Unix(ts, 0))
+		if time.Duration(math.Abs(float64(age))) > tolerance {
+			return errors.New("Timestamp outside of tolerance")
+		}
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return errors.New("Unsupported signature")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, ts, body))) {
+		return errors.New("Signature mismatch")
+	}
+
+	return nil
+}
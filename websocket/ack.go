@@ -0,0 +1,283 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Control message actions for the acknowledgement protocol. A client that
+// sends enable-acks receives an ID with every event and must reply with
+//
+//	{"action": "ack", "ids": ["..."]}
+//
+// once it has processed it, or
+//
+//	{"action": "nack", "ids": ["..."], "delay": 30}
+//
+// to have it redelivered after delay seconds. Events that are neither acked
+// nor nacked within the hub's AckTimeout are redelivered too.
+//
+// Unacked events are dropped when the connection closes, unless the client
+// identifies itself when enabling acks:
+//
+//	{"action": "enable-acks", "subscriber": "..."}
+//
+// The hub then keeps them for its AckRetention, and redelivers them when a
+// connection with the same subscriber ID enables acks. They're only kept in
+// the hub's memory, and events published while the subscriber is
+// disconnected aren't kept at all.
+const (
+	actionEnableAcks = "enable-acks"
+	actionAck        = "ack"
+	actionNack       = "nack"
+)
+
+const (
+	defaultAckTimeout   = 30 * time.Second
+	defaultAckRetention = 5 * time.Minute
+)
+
+// pendingFrame is an event waiting to be acknowledged or redelivered.
+type pendingFrame struct {
+	frame Frame
+	timer *time.Timer
+}
+
+// acks tracks events sent to a client that have not been acknowledged yet.
+type acks struct {
+	mu      sync.Mutex
+	enabled bool
+	stopped bool
+	timeout time.Duration
+	pending map[string]*pendingFrame
+
+	// Called to send an unacknowledged event again.
+	redeliver func(Frame)
+}
+
+func newAcks(timeout time.Duration, redeliver func(Frame)) *acks {
+	return &acks{
+		timeout:   timeout,
+		pending:   make(map[string]*pendingFrame),
+		redeliver: redeliver,
+	}
+}
+
+func (a *acks) isEnabled() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.enabled
+}
+
+// apply handles an acknowledgement control message from the client.
+func (a *acks) apply(msg *controlMessage) {
+	switch msg.Action {
+	case actionEnableAcks:
+		a.mu.Lock()
+		a.enabled = true
+		a.mu.Unlock()
+	case actionAck:
+		for _, id := range msg.IDs {
+			a.remove(id)
+		}
+	case actionNack:
+		delay := time.Duration(msg.Delay) * time.Second
+		for _, id := range msg.IDs {
+			a.reschedule(id, delay)
+		}
+	}
+}
+
+// track waits for the client to acknowledge the frame, redelivering it if it
+// doesn't within the timeout.
+func (a *acks) track(frame Frame) {
+	a.schedule(frame, a.timeout)
+}
+
+func (a *acks) schedule(frame Frame, delay time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stopped {
+		return
+	}
+
+	if existing, ok := a.pending[frame.ID]; ok {
+		existing.timer.Stop()
+	}
+
+	pending := &pendingFrame{frame: frame}
+	pending.timer = time.AfterFunc(delay, func() {
+		a.mu.Lock()
+		current := a.pending[frame.ID] == pending
+		if current {
+			delete(a.pending, frame.ID)
+		}
+		a.mu.Unlock()
+
+		if current {
+			frame.Attempt++
+			a.redeliver(frame)
+		}
+	})
+	a.pending[frame.ID] = pending
+}
+
+// reschedule redelivers a nacked event after the delay.
+func (a *acks) reschedule(id string, delay time.Duration) {
+	a.mu.Lock()
+	pending, ok := a.pending[id]
+	a.mu.Unlock()
+
+	if ok {
+		a.schedule(pending.frame, delay)
+	}
+}
+
+func (a *acks) remove(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if pending, ok := a.pending[id]; ok {
+		pending.timer.Stop()
+		delete(a.pending, id)
+	}
+}
+
+// count returns the number of events awaiting acknowledgement.
+func (a *acks) count() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.pending)
+}
+
+// resume redelivers events a subscriber hadn't acknowledged on an earlier
+// connection. It returns false if the connection has closed in the meantime.
+func (a *acks) resume(frames []Frame) bool {
+	a.mu.Lock()
+	stopped := a.stopped
+	a.mu.Unlock()
+
+	if stopped {
+		return false
+	}
+
+	for _, frame := range frames {
+		a.schedule(frame, 0)
+	}
+
+	return true
+}
+
+// stop forgets every pending event once the connection has closed,
+// returning them.
+func (a *acks) stop() []Frame {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stopped = true
+
+	frames := make([]Frame, 0, len(a.pending))
+	for id, pending := range a.pending {
+		pending.timer.Stop()
+		delete(a.pending, id)
+		frames = append(frames, pending.frame)
+	}
+
+	return frames
+}
+
+// retainedFrames are the unacknowledged events of a disconnected subscriber.
+type retainedFrames struct {
+	frames map[string]Frame
+	timer  *time.Timer
+}
+
+// retainedAcks keeps the unacknowledged events of subscribers that
+// identified themselves while they're disconnected, so that they can be
+// redelivered when they reconnect.
+type retainedAcks struct {
+	mu        sync.Mutex
+	retention time.Duration
+	retained  map[string]*retainedFrames
+}
+
+func newRetainedAcks(retention time.Duration) *retainedAcks {
+	return &retainedAcks{
+		retention: retention,
+		retained:  make(map[string]*retainedFrames),
+	}
+}
+
+// keep holds on to a subscriber's events until it reconnects or the
+// retention period passes.
+func (r *retainedAcks) keep(subscriber string, frames []Frame) {
+	if 0 == len(frames) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	retained, ok := r.retained[subscriber]
+	if !ok {
+		retained = &retainedFrames{frames: make(map[string]Frame)}
+		retained.timer = time.AfterFunc(r.retention, func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			if r.retained[subscriber] == retained {
+				delete(r.retained, subscriber)
+			}
+		})
+		r.retained[subscriber] = retained
+	}
+
+	for _, frame := range frames {
+		retained.frames[frame.ID] = frame
+	}
+}
+
+// take returns the events kept for a reconnecting subscriber.
+func (r *retainedAcks) take(subscriber string) []Frame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	retained, ok := r.retained[subscriber]
+	if !ok {
+		return nil
+	}
+
+	retained.timer.Stop()
+	delete(r.retained, subscriber)
+
+	frames := make([]Frame, 0, len(retained.frames))
+	for _, frame := range retained.frames {
+		frames = append(frames, frame)
+	}
+
+	return frames
+}
+
+// count returns the number of events kept for a subscriber.
+func (r *retainedAcks) count(subscriber string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if retained, ok := r.retained[subscriber]; ok {
+		return len(retained.frames)
+	}
+
+	return 0
+}
+
+// newEventID returns a random ID for an event that needs acknowledging.
+func newEventID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
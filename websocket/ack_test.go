@@ -0,0 +1,175 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitFor polls until the condition holds or fails the test.
+func waitFor(t *testing.T, description string, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", description)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// pendingAcks returns the number of events awaiting acknowledgement from
+// every client that enabled acks, or -1 if a client hasn't enabled them.
+func (h *Hub) pendingAcks() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	total := 0
+	for c := range h.clients {
+		if !c.acks.isEnabled() {
+			return -1
+		}
+		total += c.acks.count()
+	}
+
+	return total
+}
+
+func TestHubRedeliversUnackedEvents(t *testing.T) {
+	hub := NewHub(&HubConfig{AckTimeout: 50 * time.Millisecond})
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	defer hub.Close()
+
+	conn := dial(t, server)
+	defer conn.Close()
+	waitForClients(t, hub, 1)
+
+	require.Nil(t, conn.WriteJSON(&controlMessage{Action: actionEnableAcks}))
+	waitFor(t, "acks to be enabled", func() bool { return hub.pendingAcks() == 0 })
+
+	require.Nil(t, hub.Publish(testEvent{name: "Thing"}))
+
+	first := &Frame{}
+	require.Nil(t, conn.ReadJSON(first))
+	assert.NotEmpty(t, first.ID)
+	assert.Equal(t, 0, first.Attempt)
+
+	// Not acked in time
+	redelivered := &Frame{}
+	require.Nil(t, conn.ReadJSON(redelivered))
+	assert.Equal(t, first.ID, redelivered.ID)
+	assert.Equal(t, 1, redelivered.Attempt)
+
+	// Nacked
+	require.Nil(t, conn.WriteJSON(&controlMessage{Action: actionNack, IDs: []string{first.ID}}))
+	nacked := &Frame{}
+	require.Nil(t, conn.ReadJSON(nacked))
+	assert.Equal(t, first.ID, nacked.ID)
+	assert.Equal(t, 2, nacked.Attempt)
+
+	require.Nil(t, conn.WriteJSON(&controlMessage{Action: actionAck, IDs: []string{first.ID}}))
+	waitFor(t, "the ack", func() bool { return hub.pendingAcks() == 0 })
+}
+
+func TestHubOmitsIDsWithoutAcks(t *testing.T) {
+	hub := NewHub(nil)
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	defer hub.Close()
+
+	conn := dial(t, server)
+	defer conn.Close()
+	waitForClients(t, hub, 1)
+
+	require.Nil(t, hub.Publish(testEvent{name: "Thing"}))
+
+	_, message, err := conn.ReadMessage()
+	require.Nil(t, err)
+	assert.NotContains(t, string(message), `"id"`)
+}
+
+func TestProviderAcknowledges(t *testing.T) {
+	hub := NewHub(nil)
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	defer hub.Close()
+
+	provider, err := NewProvider(&ProviderConfig{URL: wsURL(server), Acknowledge: true})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	waitForClients(t, hub, 1)
+	waitFor(t, "acks to be enabled", func() bool { return hub.pendingAcks() == 0 })
+
+	require.Nil(t, hub.Publish(testEvent{name: "Thing"}))
+
+	select {
+	case event := <-events:
+		assert.NotEmpty(t, event.(Event).id)
+		assert.Equal(t, 1, hub.pendingAcks())
+
+		provider.Delete(event)
+		waitFor(t, "the ack", func() bool { return hub.pendingAcks() == 0 })
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+}
+
+func TestHubRedeliversUnackedEventsOnReconnect(t *testing.T) {
+	hub := NewHub(&HubConfig{AckTimeout: time.Minute})
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	defer hub.Close()
+
+	enableAcks := &controlMessage{Action: actionEnableAcks, Subscriber: "worker-1"}
+
+	conn := dial(t, server)
+	waitForClients(t, hub, 1)
+	require.Nil(t, conn.WriteJSON(enableAcks))
+	waitFor(t, "acks to be enabled", func() bool { return hub.pendingAcks() == 0 })
+
+	require.Nil(t, hub.Publish(testEvent{name: "Thing"}))
+
+	first := &Frame{}
+	require.Nil(t, conn.ReadJSON(first))
+
+	// Disconnected before acking
+	conn.Close()
+	waitFor(t, "the event to be kept", func() bool { return hub.retained.count("worker-1") == 1 })
+
+	conn = dial(t, server)
+	defer conn.Close()
+	waitForClients(t, hub, 1)
+	require.Nil(t, conn.WriteJSON(enableAcks))
+
+	redelivered := &Frame{}
+	require.Nil(t, conn.ReadJSON(redelivered))
+	assert.Equal(t, first.ID, redelivered.ID)
+	assert.Equal(t, 1, redelivered.Attempt)
+	assert.Equal(t, 0, hub.retained.count("worker-1"))
+
+	require.Nil(t, conn.WriteJSON(&controlMessage{Action: actionAck, IDs: []string{first.ID}}))
+	waitFor(t, "the ack", func() bool { return hub.pendingAcks() == 0 })
+}
+
+func TestRetainedAcksExpire(t *testing.T) {
+	retained := newRetainedAcks(10 * time.Millisecond)
+	retained.keep("worker-1", []Frame{{ID: "1234", Name: "Thing"}})
+	assert.Equal(t, 1, retained.count("worker-1"))
+
+	waitFor(t, "the events to expire", func() bool { return retained.count("worker-1") == 0 })
+	assert.Empty(t, retained.take("worker-1"))
+}
+
+func TestNewProviderRequiresAcknowledgeForSubscriberID(t *testing.T) {
+	_, err := NewProvider(&ProviderConfig{URL: "ws://localhost", SubscriberID: "worker-1"})
+	assert.NotNil(t, err)
+
+	_, err = NewProvider(&ProviderConfig{URL: "ws://localhost", SubscriberID: "worker-1", Acknowledge: true})
+	assert.Nil(t, err)
+}
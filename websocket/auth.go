@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/researchsquare/gomainevents"
+)
+
+// ErrMissingToken is returned by authenticators when the request carries no
+// token.
+var ErrMissingToken = errors.New("Missing token")
+
+// Principal identifies whoever opened a connection. It is whatever the
+// Authenticator returns, e.g. a user ID or a set of JWT claims.
+type Principal interface{}
+
+// Authenticator checks a websocket upgrade request and returns who is
+// connecting. Returning an error rejects the connection with a 401.
+type Authenticator func(r *http.Request) (Principal, error)
+
+// Authorizer decides whether a connection may receive an event.
+type Authorizer func(principal Principal, event gomainevents.Event) bool
+
+// BearerToken extracts a token from the Authorization header, or from the
+// access_token query parameter since browsers can't set headers on
+// websocket requests.
+func BearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+
+	return r.URL.Query().Get("access_token")
+}
+
+// ErrNoSigningMethods is returned by JWTAuthenticator for every request
+// when it isn't given the signing methods to accept.
+var ErrNoSigningMethods = errors.New("No JWT signing methods are allowed")
+
+// JWTAuthenticator validates the bearer token as a JWT using keyFunc to look
+// up the verification key. Only tokens signed with one of the methods, e.g.
+// "RS256", are accepted, so that a token can't pick a method its key
+// wasn't meant for. The principal is the token's jwt.MapClaims.
+func JWTAuthenticator(keyFunc jwt.Keyfunc, methods []string, options ...jwt.ParserOption) Authenticator {
+	parser := jwt.NewParser(append([]jwt.ParserOption{jwt.WithValidMethods(methods)}, options...)...)
+
+	return func(r *http.Request) (Principal, error) {
+		if 0 == len(methods) {
+			return nil, ErrNoSigningMethods
+		}
+
+		raw := BearerToken(r)
+		if "" == raw {
+			return nil, ErrMissingToken
+		}
+
+		claims := jwt.MapClaims{}
+		if _, err := parser.ParseWithClaims(raw, claims, keyFunc); err != nil {
+			return nil, err
+		}
+
+		return claims, nil
+	}
+}
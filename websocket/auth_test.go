@@ -0,0 +1,82 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	gorilla "github.com/gorilla/websocket"
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("secret")
+
+func signedToken(t *testing.T, userID string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": userID}).SignedString(testSecret)
+	require.Nil(t, err)
+
+	return token
+}
+
+func TestBearerToken(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?access_token=query", nil)
+	assert.Equal(t, "query", BearerToken(r))
+
+	r.Header.Set("Authorization", "Bearer header")
+	assert.Equal(t, "header", BearerToken(r))
+}
+
+func TestHubAuthentication(t *testing.T) {
+	hub := NewHub(&HubConfig{
+		Authenticate: JWTAuthenticator(func(*jwt.Token) (interface{}, error) {
+			return testSecret, nil
+		}, []string{"HS256"}),
+		Authorize: func(principal Principal, event gomainevents.Event) bool {
+			subject, _ := principal.(jwt.MapClaims).GetSubject()
+			return event.Name() == "For"+subject
+		},
+	})
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	defer hub.Close()
+
+	// No token
+	_, resp, err := gorilla.DefaultDialer.Dial(wsURL(server), nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Valid token only receives its own events
+	conn, _, err := gorilla.DefaultDialer.Dial(wsURL(server)+"?access_token="+signedToken(t, "Alice"), nil)
+	require.Nil(t, err)
+	defer conn.Close()
+	waitForClients(t, hub, 1)
+
+	require.Nil(t, hub.Publish(testEvent{name: "ForBob"}))
+	require.Nil(t, hub.Publish(testEvent{name: "ForAlice"}))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, message, err := conn.ReadMessage()
+	require.Nil(t, err)
+	assert.Contains(t, string(message), `"name":"ForAlice"`)
+}
+
+func TestJWTAuthenticatorChecksSigningMethod(t *testing.T) {
+	keyFunc := func(*jwt.Token) (interface{}, error) {
+		return testSecret, nil
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?access_token="+signedToken(t, "Alice"), nil)
+
+	_, err := JWTAuthenticator(keyFunc, []string{"HS256"})(r)
+	assert.Nil(t, err)
+
+	_, err = JWTAuthenticator(keyFunc, []string{"RS256"})(r)
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+
+	_, err = JWTAuthenticator(keyFunc, nil)(r)
+	assert.Equal(t, ErrNoSigningMethods, err)
+}
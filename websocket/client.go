@@ -0,0 +1,281 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+)
+
+// defaultSendBufferSize is the number of messages queued for a connection
+// before it is considered too slow to keep up.
+const defaultSendBufferSize = 256
+
+// client is a single connection registered with a Hub. Messages are queued
+// on send and written by the client's own write pump, so a slow connection
+// doesn't hold up publishing to the others.
+type client struct {
+	conn          *gorilla.Conn
+	keepalive     Keepalive
+	subscriptions *subscriptions
+
+	// Who opened the connection, as returned by the hub's Authenticator.
+	principal Principal
+	authorize Authorizer
+
+	// Guards send so that nothing is queued once it is closed.
+	mu      sync.Mutex
+	send    chan *gorilla.PreparedMessage
+	closing bool
+
+	overflow     OverflowPolicy
+	blockTimeout time.Duration
+
+	// Events awaiting acknowledgement, if the client enabled acks.
+	codec Codec
+	acks  *acks
+
+	// Where the events awaiting acknowledgement are kept once the
+	// connection closes, if the client identified itself as subscriber.
+	retained   *retainedAcks
+	subscriber string
+
+	done      chan bool
+	closeOnce sync.Once
+}
+
+func newClient(conn *gorilla.Conn, keepalive Keepalive, bufferSize int) *client {
+	c := &client{
+		conn:          conn,
+		keepalive:     keepalive,
+		subscriptions: newSubscriptions(),
+		send:          make(chan *gorilla.PreparedMessage, bufferSize),
+		blockTimeout:  defaultBlockTimeout,
+		codec:         JSONCodec{},
+		done:          make(chan bool),
+	}
+	c.acks = newAcks(defaultAckTimeout, c.redeliver)
+
+	return c
+}
+
+// enqueue queues a message for the write pump. It returns false if the
+// connection is closing, or its queue is full and the overflow policy says
+// the client should be dropped.
+func (c *client) enqueue(message *gorilla.PreparedMessage) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closing {
+		return false
+	}
+
+	select {
+	case c.send <- message:
+		return true
+	default:
+	}
+
+	switch c.overflow {
+	case DropOldest:
+		for {
+			select {
+			case <-c.send:
+			default:
+			}
+
+			select {
+			case c.send <- message:
+				return true
+			default:
+			}
+		}
+	case BlockWithTimeout:
+		timer := time.NewTimer(c.blockTimeout)
+		defer timer.Stop()
+
+		select {
+		case c.send <- message:
+			return true
+		case <-c.done:
+			return false
+		case <-timer.C:
+			return false
+		}
+	default:
+		return false
+	}
+}
+
+// enqueueTracked queues an event that the client has to acknowledge.
+func (c *client) enqueueTracked(message *gorilla.PreparedMessage, frame Frame) bool {
+	if !c.enqueue(message) {
+		return false
+	}
+
+	c.acks.track(frame)
+	return true
+}
+
+// redeliver sends an unacknowledged event again. If the client can't take
+// it, it isn't keeping up and is disconnected.
+func (c *client) redeliver(frame Frame) {
+	bytes, err := c.codec.Encode(&frame)
+	if err != nil {
+		return
+	}
+
+	message, err := gorilla.NewPreparedMessage(c.codec.MessageType(), bytes)
+	if err != nil {
+		return
+	}
+
+	if !c.enqueueTracked(message, frame) {
+		c.close()
+	}
+}
+
+// writePump writes queued messages and pings to the connection. Once the
+// queue is closed by shutdown, it sends a close frame and returns.
+func (c *client) writePump() {
+	ticker := time.NewTicker(c.keepalive.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(c.keepalive.writeDeadline())
+
+			if !ok {
+				closeMessage := gorilla.FormatCloseMessage(gorilla.CloseGoingAway, "Server shutting down")
+				c.conn.WriteMessage(gorilla.CloseMessage, closeMessage)
+				return
+			}
+
+			if err := c.conn.WritePreparedMessage(message); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(c.keepalive.writeDeadline())
+			if err := c.conn.WriteMessage(gorilla.PingMessage, nil); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// readPump handles control messages from the client. It returns once
+// the connection fails, goes quiet for too long, or is closed.
+func (c *client) readPump() {
+	c.keepalive.watch(c.conn)
+
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		c.keepalive.extend(c.conn)
+
+		if err := c.control(message); err != nil {
+			c.reply(map[string]string{"error": err.Error()})
+		}
+	}
+}
+
+// control applies a control message from the client.
+func (c *client) control(raw []byte) error {
+	msg := &controlMessage{}
+	if err := json.Unmarshal(raw, msg); err != nil {
+		return err
+	}
+
+	switch msg.Action {
+	case actionEnableAcks:
+		c.acks.apply(msg)
+		if "" != msg.Subscriber {
+			c.resume(msg.Subscriber)
+		}
+		return nil
+	case actionAck, actionNack:
+		c.acks.apply(msg)
+		return nil
+	default:
+		return c.subscriptions.apply(msg)
+	}
+}
+
+// resume redelivers the events the subscriber hadn't acknowledged when its
+// last connection closed, as long as it may still receive them.
+func (c *client) resume(subscriber string) {
+	if nil == c.retained {
+		return
+	}
+
+	c.mu.Lock()
+	c.subscriber = subscriber
+	c.mu.Unlock()
+
+	frames := []Frame{}
+	for _, frame := range c.retained.take(subscriber) {
+		event := Event{name: frame.Name, data: frame.Data}
+		if nil == c.authorize || c.authorize(c.principal, event) {
+			frames = append(frames, frame)
+		}
+	}
+
+	if !c.acks.resume(frames) {
+		c.retained.keep(subscriber, frames)
+	}
+}
+
+// reply queues a JSON message for the client.
+func (c *client) reply(value interface{}) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	message, err := gorilla.NewPreparedMessage(gorilla.TextMessage, bytes)
+	if err != nil {
+		return
+	}
+
+	c.enqueue(message)
+}
+
+// shutdown stops accepting messages. The write pump sends whatever is
+// already queued followed by a close frame.
+func (c *client) shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closing {
+		c.closing = true
+		close(c.send)
+	}
+}
+
+// close tears the connection down immediately. Events it hadn't
+// acknowledged are kept for the subscriber, if it identified itself.
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		frames := c.acks.stop()
+
+		c.mu.Lock()
+		subscriber := c.subscriber
+		c.mu.Unlock()
+
+		if "" != subscriber {
+			c.retained.keep(subscriber, frames)
+		}
+
+		c.conn.Close()
+	})
+}
@@ -0,0 +1,69 @@
+package websocket
+
+import (
+	"encoding/json"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Frame is what gets sent over the websocket for each event.
+type Frame struct {
+	Name string                 `json:"name" msgpack:"name"`
+	Data map[string]interface{} `json:"data" msgpack:"data"`
+
+	// Only set for connections that acknowledge events. Attempt counts
+	// redeliveries, starting at zero.
+	ID      string `json:"id,omitempty" msgpack:"id,omitempty"`
+	Attempt int    `json:"attempt,omitempty" msgpack:"attempt,omitempty"`
+}
+
+// Codec converts frames to and from websocket messages. Both ends of a
+// connection must use the same codec.
+type Codec interface {
+	Encode(*Frame) ([]byte, error)
+	Decode([]byte, *Frame) error
+
+	// MessageType is gorilla's TextMessage or BinaryMessage.
+	MessageType() int
+}
+
+// JSONCodec sends events as JSON text messages. This is the default.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(frame *Frame) ([]byte, error) {
+	return json.Marshal(frame)
+}
+
+func (JSONCodec) Decode(message []byte, frame *Frame) error {
+	return json.Unmarshal(message, frame)
+}
+
+func (JSONCodec) MessageType() int {
+	return gorilla.TextMessage
+}
+
+// MsgpackCodec sends events as MessagePack binary messages, which are
+// smaller and cheaper to encode than JSON for high-frequency streams.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Encode(frame *Frame) ([]byte, error) {
+	return msgpack.Marshal(frame)
+}
+
+func (MsgpackCodec) Decode(message []byte, frame *Frame) error {
+	return msgpack.Unmarshal(message, frame)
+}
+
+func (MsgpackCodec) MessageType() int {
+	return gorilla.BinaryMessage
+}
+
+// codecOrDefault falls back to JSON when no codec was configured.
+func codecOrDefault(codec Codec) Codec {
+	if nil == codec {
+		return JSONCodec{}
+	}
+
+	return codec
+}
@@ -0,0 +1,57 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecsRoundTrip(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec{}, MsgpackCodec{}} {
+		message, err := codec.Encode(&Frame{Name: "Thing", Data: map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}})
+		require.Nil(t, err)
+
+		event, err := decodeEvent(codec, message)
+		require.Nil(t, err)
+		assert.Equal(t, "Thing", event.Name())
+		assert.Equal(t, "2018-03-08 11:11:11", event.Data()["occurredOn"])
+	}
+}
+
+func TestCompressedBinaryStream(t *testing.T) {
+	hub := NewHub(&HubConfig{Codec: MsgpackCodec{}, EnableCompression: true})
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	defer hub.Close()
+
+	provider, err := NewProvider(&ProviderConfig{
+		URL:               wsURL(server),
+		Codec:             MsgpackCodec{},
+		EnableCompression: true,
+	})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+	waitForClients(t, hub, 1)
+
+	require.Nil(t, hub.Publish(testEvent{name: "Thing"}))
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "Thing", event.Name())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+}
+
+func TestDialerWithCompression(t *testing.T) {
+	dialer := dialerWithCompression(nil, true)
+
+	assert.True(t, dialer.EnableCompression)
+	assert.False(t, gorilla.DefaultDialer.EnableCompression)
+}
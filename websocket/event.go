@@ -0,0 +1,57 @@
+package websocket
+
+import (
+	"math"
+	"time"
+)
+
+// Event implements the standard domain event interface for events received
+// over a websocket.
+type Event struct {
+	name string
+	data map[string]interface{}
+
+	// Set when the Hub expects the event to be acknowledged.
+	id string
+
+	// How many times the event has been redelivered, either by the Hub
+	// or by the provider itself.
+	retryCount int
+}
+
+// decodeEvent builds an event from a websocket message.
+func decodeEvent(codec Codec, message []byte) (*Event, error) {
+	frame := &Frame{}
+	if err := codec.Decode(message, frame); err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		name:       frame.Name,
+		data:       frame.Data,
+		id:         frame.ID,
+		retryCount: frame.Attempt,
+	}, nil
+}
+
+func (e Event) Name() string {
+	return e.name
+}
+
+func (e Event) Data() map[string]interface{} {
+	return e.data
+}
+
+// RetryCount returns the number of times this event has been delivered, but
+// not processed.
+func (e Event) RetryCount() int {
+	return e.retryCount
+}
+
+// Delay returns how long to wait before redelivering this event.
+func (e Event) Delay() time.Duration {
+	return time.Duration(math.Min(
+		math.Pow(2, float64(e.retryCount+1)),
+		15*60, // Max is 15 minutes
+	)) * time.Second
+}
@@ -0,0 +1,344 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/researchsquare/gomainevents"
+)
+
+// Hub tracks any number of websocket connections and broadcasts published
+// events to all of them. It is a Publisher, and an http.Handler that accepts
+// new connections.
+type Hub struct {
+	upgrader  *gorilla.Upgrader
+	keepalive Keepalive
+	codec     Codec
+
+	authenticate Authenticator
+	authorize    Authorizer
+
+	sendBufferSize int
+	overflow       OverflowPolicy
+	blockTimeout   time.Duration
+	ackTimeout     time.Duration
+	retained       *retainedAcks
+
+	mu           sync.RWMutex
+	clients      map[*client]bool
+	shuttingDown bool
+
+	debug bool
+}
+
+type HubConfig struct {
+	// Provide your own upgrader, e.g. to check the request origin.
+	// Defaults to gorilla's default settings.
+	Upgrader *gorilla.Upgrader
+
+	// Ping and timeout settings for connections. Defaults are used when
+	// not provided.
+	Keepalive *Keepalive
+
+	// How events are encoded. Defaults to JSON text messages.
+	Codec Codec
+
+	// Negotiate permessage-deflate compression with clients that
+	// support it.
+	EnableCompression bool
+
+	// Authenticate checks each upgrade request before it is accepted.
+	// Optional; without it every request is accepted.
+	Authenticate Authenticator
+
+	// Authorize filters which events each connection receives, based on
+	// the principal returned by Authenticate. Optional.
+	Authorize Authorizer
+
+	// Number of events queued per connection before the overflow policy
+	// applies. Defaults to 256.
+	SendBufferSize int
+
+	// What to do with clients that can't keep up. Defaults to
+	// DropConnection.
+	Overflow OverflowPolicy
+
+	// How long BlockWithTimeout waits for room in a client's buffer.
+	// Defaults to 1 second.
+	BlockTimeout time.Duration
+
+	// How long clients that enable acknowledgements have to ack an event
+	// before it is redelivered. Defaults to 30 seconds.
+	AckTimeout time.Duration
+
+	// How long the unacknowledged events of a client that identified
+	// itself as a subscriber are kept after its connection closes, for it
+	// to reconnect and have them redelivered. They're kept in memory, so
+	// they don't survive the hub's process. Defaults to 5 minutes.
+	AckRetention time.Duration
+}
+
+func NewHub(config *HubConfig) *Hub {
+	if nil == config {
+		config = &HubConfig{}
+	}
+
+	upgrader := &gorilla.Upgrader{}
+	if nil != config.Upgrader {
+		copied := *config.Upgrader
+		upgrader = &copied
+	}
+
+	if config.EnableCompression {
+		upgrader.EnableCompression = true
+	}
+
+	sendBufferSize := defaultSendBufferSize
+	if config.SendBufferSize > 0 {
+		sendBufferSize = config.SendBufferSize
+	}
+
+	blockTimeout := defaultBlockTimeout
+	if config.BlockTimeout > 0 {
+		blockTimeout = config.BlockTimeout
+	}
+
+	ackTimeout := defaultAckTimeout
+	if config.AckTimeout > 0 {
+		ackTimeout = config.AckTimeout
+	}
+
+	ackRetention := defaultAckRetention
+	if config.AckRetention > 0 {
+		ackRetention = config.AckRetention
+	}
+
+	return &Hub{
+		upgrader:  upgrader,
+		keepalive: config.Keepalive.withDefaults(),
+		codec:     codecOrDefault(config.Codec),
+
+		authenticate: config.Authenticate,
+		authorize:    config.Authorize,
+
+		sendBufferSize: sendBufferSize,
+		overflow:       config.Overflow,
+		blockTimeout:   blockTimeout,
+		ackTimeout:     ackTimeout,
+		retained:       newRetainedAcks(ackRetention),
+
+		clients: make(map[*client]bool),
+		debug:   true,
+	}
+}
+
+// ServeHTTP upgrades the request to a websocket and registers it with the
+// hub until the connection is closed.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	shuttingDown := h.shuttingDown
+	h.mu.RUnlock()
+
+	if shuttingDown {
+		http.Error(w, "Shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	var principal Principal
+	if nil != h.authenticate {
+		var err error
+		if principal, err = h.authenticate(r); err != nil {
+			h.debugPrint("Authentication failed for %s: %s\n", r.RemoteAddr, err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already responded with an error.
+		h.debugPrint("Upgrade failed: %s\n", err)
+		return
+	}
+
+	h.RegisterAs(conn, principal)
+}
+
+// Register adds a connection that was upgraded elsewhere. It is removed from
+// the hub once it closes.
+func (h *Hub) Register(conn *gorilla.Conn) {
+	h.RegisterAs(conn, nil)
+}
+
+// RegisterAs adds a connection that was upgraded and authenticated
+// elsewhere. The principal is passed to the Authorizer.
+func (h *Hub) RegisterAs(conn *gorilla.Conn, principal Principal) {
+	c := newClient(conn, h.keepalive, h.sendBufferSize)
+	c.principal = principal
+	c.authorize = h.authorize
+	c.overflow = h.overflow
+	c.blockTimeout = h.blockTimeout
+	c.codec = h.codec
+	c.acks.timeout = h.ackTimeout
+	c.retained = h.retained
+
+	h.mu.Lock()
+	if h.shuttingDown {
+		h.mu.Unlock()
+		c.close()
+		return
+	}
+	h.clients[c] = true
+	h.mu.Unlock()
+
+	h.debugPrint("Client connected from %s\n", conn.RemoteAddr())
+
+	go c.writePump()
+	go func() {
+		c.readPump()
+		h.unregister(c)
+	}()
+}
+
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	_, ok := h.clients[c]
+	delete(h.clients, c)
+	h.mu.Unlock()
+
+	c.close()
+
+	if ok {
+		h.debugPrint("Client disconnected from %s\n", c.conn.RemoteAddr())
+	}
+}
+
+// Count returns the number of connected clients.
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.clients)
+}
+
+// Publish sends the event to every connected client subscribed to it. Clients
+// that can't keep up are handled according to the hub's OverflowPolicy;
+// that isn't considered a publish failure.
+func (h *Hub) Publish(event gomainevents.Event) error {
+	frame := Frame{
+		Name: event.Name(),
+		Data: event.Data(),
+	}
+
+	message, err := h.prepare(frame)
+	if err != nil {
+		return err
+	}
+
+	// Clients that acknowledge events get a copy with an ID, prepared
+	// the first time one is needed.
+	var tracked *gorilla.PreparedMessage
+	trackedFrame := frame
+
+	h.mu.RLock()
+	clients := make([]*client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.RUnlock()
+
+	for _, c := range clients {
+		if !c.subscriptions.matches(event.Name(), event.Data()) {
+			continue
+		}
+
+		if nil != h.authorize && !h.authorize(c.principal, event) {
+			continue
+		}
+
+		var ok bool
+		if c.acks.isEnabled() {
+			if nil == tracked {
+				trackedFrame.ID = newEventID()
+				if tracked, err = h.prepare(trackedFrame); err != nil {
+					return err
+				}
+			}
+
+			ok = c.enqueueTracked(tracked, trackedFrame)
+		} else {
+			ok = c.enqueue(message)
+		}
+
+		if !ok {
+			h.debugPrint("Client %s is too slow, disconnecting\n", c.conn.RemoteAddr())
+			h.unregister(c)
+		}
+	}
+
+	return nil
+}
+
+// prepare encodes a frame. Prepared messages are only encoded (and
+// compressed) once for all clients.
+func (h *Hub) prepare(frame Frame) (*gorilla.PreparedMessage, error) {
+	bytes, err := h.codec.Encode(&frame)
+	if err != nil {
+		return nil, err
+	}
+
+	return gorilla.NewPreparedMessage(h.codec.MessageType(), bytes)
+}
+
+// Shutdown stops accepting connections and closes existing ones gracefully:
+// queued events are sent, followed by a close frame. Connections that haven't
+// closed by the time ctx is done are closed forcefully.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.shuttingDown = true
+	clients := make([]*client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		c.shutdown()
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for h.Count() > 0 {
+		select {
+		case <-ctx.Done():
+			h.Close()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// Close disconnects every client immediately.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	clients := h.clients
+	h.clients = make(map[*client]bool)
+	h.mu.Unlock()
+
+	for c := range clients {
+		c.close()
+	}
+}
+
+func (h *Hub) debugPrint(format string, values ...interface{}) {
+	if h.debug {
+		log.Printf("[gomainevents-websocket] "+format, values...)
+	}
+}
@@ -0,0 +1,62 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dial(t *testing.T, server *httptest.Server) *gorilla.Conn {
+	conn, _, err := gorilla.DefaultDialer.Dial(wsURL(server), nil)
+	require.Nil(t, err)
+
+	return conn
+}
+
+func waitForClients(t *testing.T, hub *Hub, count int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.Count() != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d clients, have %d", count, hub.Count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHubBroadcasts(t *testing.T) {
+	hub := NewHub(nil)
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	defer hub.Close()
+
+	first, second := dial(t, server), dial(t, server)
+	defer first.Close()
+	defer second.Close()
+	waitForClients(t, hub, 2)
+
+	require.Nil(t, hub.Publish(testEvent{name: "Thing"}))
+
+	for _, conn := range []*gorilla.Conn{first, second} {
+		_, message, err := conn.ReadMessage()
+		require.Nil(t, err)
+		assert.JSONEq(t, `{"name":"Thing","data":{"occurredOn":"2018-03-08 11:11:11"}}`, string(message))
+	}
+}
+
+func TestHubRemovesDisconnectedClients(t *testing.T) {
+	hub := NewHub(nil)
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	conn := dial(t, server)
+	waitForClients(t, hub, 1)
+
+	conn.Close()
+	waitForClients(t, hub, 0)
+
+	assert.Nil(t, hub.Publish(testEvent{name: "Thing"}))
+}
@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+)
+
+const (
+	defaultPongWait     = 60 * time.Second
+	defaultWriteTimeout = 10 * time.Second
+)
+
+// Keepalive controls how connections are checked for liveness. The peer is
+// pinged regularly and the connection is closed if nothing, not even a pong,
+// is received within PongWait.
+type Keepalive struct {
+	// How often to ping the peer. Defaults to 90% of PongWait.
+	PingInterval time.Duration
+
+	// How long to wait for a pong or any other message before the
+	// connection is considered dead. Defaults to 60 seconds.
+	PongWait time.Duration
+
+	// Maximum time allowed for a single write. Defaults to 10 seconds.
+	WriteTimeout time.Duration
+}
+
+// withDefaults fills in any unset values. A nil Keepalive uses all defaults.
+func (k *Keepalive) withDefaults() Keepalive {
+	keepalive := Keepalive{}
+	if nil != k {
+		keepalive = *k
+	}
+
+	if keepalive.PongWait <= 0 {
+		keepalive.PongWait = defaultPongWait
+	}
+
+	if keepalive.PingInterval <= 0 {
+		keepalive.PingInterval = keepalive.PongWait * 9 / 10
+	}
+
+	if keepalive.WriteTimeout <= 0 {
+		keepalive.WriteTimeout = defaultWriteTimeout
+	}
+
+	return keepalive
+}
+
+// writeDeadline is the deadline for a write starting now.
+func (k Keepalive) writeDeadline() time.Time {
+	return time.Now().Add(k.WriteTimeout)
+}
+
+// watch makes reads on the connection fail once the peer has been silent
+// for longer than PongWait. Call extend after every message read.
+func (k Keepalive) watch(conn *gorilla.Conn) {
+	k.extend(conn)
+	conn.SetPongHandler(func(string) error {
+		k.extend(conn)
+		return nil
+	})
+}
+
+// extend pushes the read deadline back after hearing from the peer.
+func (k Keepalive) extend(conn *gorilla.Conn) {
+	conn.SetReadDeadline(time.Now().Add(k.PongWait))
+}
+
+// ping pings the peer every PingInterval until stop is closed or a ping
+// can't be written, in which case the connection is closed.
+func (k Keepalive) ping(conn *gorilla.Conn, stop <-chan bool) {
+	ticker := time.NewTicker(k.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// WriteControl is safe to call alongside other writers.
+			if err := conn.WriteControl(gorilla.PingMessage, nil, k.writeDeadline()); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
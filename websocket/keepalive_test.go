@@ -0,0 +1,49 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeepaliveDefaults(t *testing.T) {
+	var keepalive *Keepalive
+	defaults := keepalive.withDefaults()
+
+	assert.Equal(t, 60*time.Second, defaults.PongWait)
+	assert.Equal(t, 54*time.Second, defaults.PingInterval)
+	assert.Equal(t, 10*time.Second, defaults.WriteTimeout)
+}
+
+func TestHubDropsSilentClients(t *testing.T) {
+	hub := NewHub(&HubConfig{Keepalive: &Keepalive{
+		PingInterval: 20 * time.Millisecond,
+		PongWait:     100 * time.Millisecond,
+	}})
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	defer hub.Close()
+
+	// A client that reads answers pings and stays connected...
+	responsive := dial(t, server)
+	defer responsive.Close()
+	go func() {
+		for {
+			if _, _, err := responsive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// ...while one that never reads never sends a pong.
+	silent := dial(t, server)
+	defer silent.Close()
+
+	waitForClients(t, hub, 2)
+	waitForClients(t, hub, 1)
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 1, hub.Count())
+}
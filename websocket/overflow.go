@@ -0,0 +1,36 @@
+package websocket
+
+import "time"
+
+// OverflowPolicy decides what happens when a client's send buffer is full,
+// i.e. the client isn't reading events as fast as they are published.
+type OverflowPolicy int
+
+const (
+	// DropConnection disconnects the slow client. This is the default.
+	DropConnection OverflowPolicy = iota
+
+	// DropOldest discards the oldest queued event to make room for the new
+	// one. The client stays connected but misses events.
+	DropOldest
+
+	// BlockWithTimeout waits up to the configured timeout for room in the
+	// buffer, then disconnects the client. Publishing is held up while
+	// waiting, so keep the timeout short.
+	BlockWithTimeout
+)
+
+const defaultBlockTimeout = time.Second
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case DropConnection:
+		return "drop-connection"
+	case DropOldest:
+		return "drop-oldest"
+	case BlockWithTimeout:
+		return "block-with-timeout"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func preparedMessage(t *testing.T, text string) *gorilla.PreparedMessage {
+	message, err := gorilla.NewPreparedMessage(gorilla.TextMessage, []byte(text))
+	require.Nil(t, err)
+	return message
+}
+
+func TestOverflowDropConnection(t *testing.T) {
+	c := newClient(nil, Keepalive{}, 1)
+
+	assert.True(t, c.enqueue(preparedMessage(t, "one")))
+	assert.False(t, c.enqueue(preparedMessage(t, "two")))
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	c := newClient(nil, Keepalive{}, 2)
+	c.overflow = DropOldest
+
+	one, two, three := preparedMessage(t, "one"), preparedMessage(t, "two"), preparedMessage(t, "three")
+	assert.True(t, c.enqueue(one))
+	assert.True(t, c.enqueue(two))
+	assert.True(t, c.enqueue(three))
+
+	assert.Equal(t, two, <-c.send)
+	assert.Equal(t, three, <-c.send)
+}
+
+func TestOverflowBlockWithTimeout(t *testing.T) {
+	c := newClient(nil, Keepalive{}, 1)
+	c.overflow = BlockWithTimeout
+	c.blockTimeout = 50 * time.Millisecond
+
+	assert.True(t, c.enqueue(preparedMessage(t, "one")))
+
+	// Gives up once the timeout passes
+	start := time.Now()
+	assert.False(t, c.enqueue(preparedMessage(t, "two")))
+	assert.True(t, time.Since(start) >= c.blockTimeout)
+
+	// Succeeds if the buffer drains in time
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-c.send
+	}()
+	assert.True(t, c.enqueue(preparedMessage(t, "three")))
+}
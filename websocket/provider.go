@@ -0,0 +1,457 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/researchsquare/gomainevents"
+)
+
+const (
+	defaultMaximumRetryCount = 25
+	defaultReconnectDelay    = time.Second
+	defaultMaxReconnectDelay = time.Minute
+)
+
+// Provider receives events published over a websocket and hands them to the
+// Listener. It either dials a URL, reconnecting whenever the connection
+// drops, or reads from a connection that was accepted elsewhere.
+type Provider struct {
+	url    string
+	header http.Header
+	dialer *gorilla.Dialer
+	conn   *gorilla.Conn
+
+	keepalive   Keepalive
+	subscribe   []string
+	codec       Codec
+	acknowledge bool
+	subscriber  string
+
+	maximumRetryCount int
+	jitter            gomainevents.Jitter
+	clock             gomainevents.Clock
+	reconnectDelay    time.Duration
+	maxReconnectDelay time.Duration
+
+	events chan gomainevents.Event
+	errors chan error
+	done   chan bool
+	debug  bool
+
+	// Guards closing the channels while events are being delivered.
+	closeMu sync.RWMutex
+
+	connMu sync.Mutex
+
+	// Serializes acknowledgements written from the Listener's workers.
+	writeMu sync.Mutex
+}
+
+type ProviderConfig struct {
+	// URL to dial, e.g. ws://localhost:8994/events. Either URL or Conn is
+	// required.
+	URL string
+
+	// Extra headers sent when dialing, e.g. for authentication.
+	Header http.Header
+
+	// Provide your own dialer. Defaults to gorilla's DefaultDialer.
+	Dialer *gorilla.Dialer
+
+	// An already established connection, e.g. one accepted by a server.
+	// It is not reconnected if it drops.
+	Conn *gorilla.Conn
+
+	// Ping and timeout settings for the connection. Defaults are used
+	// when not provided.
+	Keepalive *Keepalive
+
+	// How events are decoded. Must match the publishing end. Defaults to
+	// JSON text messages.
+	Codec Codec
+
+	// Negotiate permessage-deflate compression when dialing.
+	EnableCompression bool
+
+	// Only receive these events. A subscribe message is sent to the Hub
+	// on the other end every time the connection is established.
+	Subscribe []string
+
+	// Acknowledge events to the Hub on the other end: Delete acks and
+	// Requeue nacks, and the Hub redelivers anything that isn't acked in
+	// time. Without this, the Hub sends each event once. Events that are
+	// still unacked when the connection drops are dropped by the Hub too,
+	// unless SubscriberID is set.
+	Acknowledge bool
+
+	// Identifies this subscriber to the Hub, e.g. by its hostname, so that
+	// events it hadn't acked when its connection dropped are redelivered
+	// once it reconnects, including from a restarted process, within the
+	// Hub's AckRetention. Events published while it's disconnected are
+	// still missed. Requires Acknowledge.
+	SubscriberID string
+
+	// This specifies the maximum number of times an event should be retried
+	MaximumRetryCount int
+
+	// Randomizes retry delays, so that events that failed together aren't
+	// retried together. Defaults to none.
+	Jitter gomainevents.Jitter
+
+	// Delay before the first reconnect attempt. Doubles on every failed
+	// attempt up to MaxReconnectDelay. Defaults to 1 second and 1 minute.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+
+	// How events are shared between instances. The Hub sends every event
+	// to every connection, so only broadcast is supported, which is the
+	// default.
+	Delivery gomainevents.Delivery
+
+	// Clock reconnect and redelivery delays are measured on. Defaults to
+	// gomainevents.SystemClock.
+	Clock gomainevents.Clock
+}
+
+func NewProvider(config *ProviderConfig) (*Provider, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.URL && nil == config.Conn {
+		return nil, errors.New("URL or Conn is required")
+	}
+
+	if gomainevents.DefaultDelivery != config.Delivery && gomainevents.Broadcast != config.Delivery {
+		return nil, fmt.Errorf("Delivery isn't supported: %s", config.Delivery)
+	}
+
+	if "" != config.SubscriberID && !config.Acknowledge {
+		return nil, errors.New("SubscriberID requires Acknowledge")
+	}
+
+	dialer := dialerWithCompression(config.Dialer, config.EnableCompression)
+
+	maximumRetryCount := defaultMaximumRetryCount
+	if config.MaximumRetryCount > 0 {
+		maximumRetryCount = config.MaximumRetryCount
+	}
+
+	reconnectDelay := defaultReconnectDelay
+	if config.ReconnectDelay > 0 {
+		reconnectDelay = config.ReconnectDelay
+	}
+
+	maxReconnectDelay := defaultMaxReconnectDelay
+	if config.MaxReconnectDelay > 0 {
+		maxReconnectDelay = config.MaxReconnectDelay
+	}
+
+	return &Provider{
+		url:               config.URL,
+		header:            config.Header,
+		dialer:            dialer,
+		conn:              config.Conn,
+		keepalive:         config.Keepalive.withDefaults(),
+		subscribe:         config.Subscribe,
+		acknowledge:       config.Acknowledge,
+		subscriber:        config.SubscriberID,
+		codec:             codecOrDefault(config.Codec),
+		maximumRetryCount: maximumRetryCount,
+		jitter:            config.Jitter,
+		clock:             gomainevents.ClockOrDefault(config.Clock),
+		reconnectDelay:    reconnectDelay,
+		maxReconnectDelay: maxReconnectDelay,
+
+		// Buffered channel makes it so that the listener will block while the channel is empty.
+		events: make(chan gomainevents.Event, 100),
+		errors: make(chan error, 1),
+		done:   make(chan bool),
+		debug:  true,
+	}, nil
+}
+
+// Delivery returns how events are shared between instances, which is
+// always broadcast.
+func (p *Provider) Delivery() gomainevents.Delivery {
+	return gomainevents.Broadcast
+}
+
+// Return a channel that can be used to retrieve events
+func (p *Provider) Start() (<-chan gomainevents.Event, <-chan error) {
+	go func() {
+		delay := p.reconnectDelay
+
+		for {
+			conn, err := p.connect()
+			if err != nil {
+				p.reportError(gomainevents.PhaseConnect, "", err)
+
+				select {
+				case <-p.done:
+					return
+				case <-p.clock.After(delay):
+				}
+
+				delay *= 2
+				if delay > p.maxReconnectDelay {
+					delay = p.maxReconnectDelay
+				}
+
+				continue
+			}
+
+			delay = p.reconnectDelay
+			p.read(conn)
+
+			// Accepted connections can't be re-established.
+			if "" == p.url {
+				p.debugPrint("Connection closed.\n")
+				return
+			}
+
+			select {
+			case <-p.done:
+				return
+			default:
+				p.debugPrint("Connection lost, reconnecting...\n")
+			}
+		}
+	}()
+
+	return p.events, p.errors
+}
+
+// dialerWithCompression returns a copy of the dialer (or the default one)
+// with compression enabled if requested.
+func dialerWithCompression(dialer *gorilla.Dialer, enableCompression bool) *gorilla.Dialer {
+	if nil == dialer {
+		dialer = gorilla.DefaultDialer
+	}
+
+	copied := *dialer
+	if enableCompression {
+		copied.EnableCompression = true
+	}
+
+	return &copied
+}
+
+// connect returns the connection to read from, dialing if necessary.
+func (p *Provider) connect() (*gorilla.Conn, error) {
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil, errors.New("Provider stopped")
+	default:
+	}
+
+	if "" == p.url {
+		return p.conn, nil
+	}
+
+	p.debugPrint("Connecting to %s\n", p.url)
+	conn, _, err := p.dialer.Dial(p.url, p.header)
+	if err != nil {
+		return nil, err
+	}
+
+	p.conn = conn
+
+	if p.acknowledge {
+		msg := &controlMessage{Action: actionEnableAcks, Subscriber: p.subscriber}
+		if err := p.writeControl(conn, msg); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if len(p.subscribe) > 0 {
+		msg := &controlMessage{Action: actionSubscribe, Events: p.subscribe}
+		if err := p.writeControl(conn, msg); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// read delivers events from the connection until it fails.
+func (p *Provider) read(conn *gorilla.Conn) {
+	defer conn.Close()
+
+	stop := make(chan bool)
+	defer close(stop)
+
+	go p.keepalive.ping(conn, stop)
+	p.keepalive.watch(conn)
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-p.done:
+			default:
+				p.reportError(gomainevents.PhaseReceive, "", err)
+			}
+
+			return
+		}
+
+		p.keepalive.extend(conn)
+
+		event, err := decodeEvent(p.codec, message)
+		if err != nil {
+			p.reportError(gomainevents.PhaseDecode, "", err)
+			continue
+		}
+
+		if !p.deliver(*event) {
+			return
+		}
+	}
+}
+
+// Delete an event that we're done with. If the Hub expects an
+// acknowledgement it is sent; otherwise there's nothing to do, as websocket
+// events aren't stored anywhere.
+func (p *Provider) Delete(event gomainevents.Event) {
+	evt := event.(Event) // Cast to websocket flavor
+
+	if "" != evt.id {
+		if err := p.sendControl(&controlMessage{Action: actionAck, IDs: []string{evt.id}}); err != nil {
+			p.reportError(gomainevents.PhaseDelete, evt.id, err)
+		}
+	}
+}
+
+// Requeue an event for later. Events the Hub expects an acknowledgement for
+// are nacked so the Hub redelivers them; others are redelivered by the
+// provider itself after a delay.
+func (p *Provider) Requeue(event gomainevents.Event) gomainevents.RequeuingEventFailedError {
+	evt := event.(Event) // Cast to websocket flavor
+
+	if evt.RetryCount() > p.maximumRetryCount {
+		// Stop the Hub from redelivering it forever.
+		if "" != evt.id {
+			p.sendControl(&controlMessage{Action: actionAck, IDs: []string{evt.id}})
+		}
+
+		return &RetryAttemptsExceededError{EventName: evt.Name()}
+	}
+
+	if "" != evt.id {
+		delay := p.jitter.Apply(evt.Delay())
+		p.debugPrint("Nacking event. Retries: %d, Delay: %s\n", evt.RetryCount(), delay)
+
+		msg := &controlMessage{Action: actionNack, IDs: []string{evt.id}, Delay: int(delay / time.Second)}
+		if err := p.sendControl(msg); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	delay := p.jitter.Apply(evt.Delay())
+	evt.retryCount++
+
+	p.debugPrint("Requeuing event. Retries: %d, Delay: %s\n", evt.RetryCount(), delay)
+	p.clock.AfterFunc(delay, func() {
+		p.deliver(evt)
+	})
+
+	return nil
+}
+
+// Stop the channel
+func (p *Provider) Stop() {
+	close(p.done)
+
+	p.connMu.Lock()
+	if nil != p.conn {
+		p.conn.Close()
+	}
+	p.connMu.Unlock()
+
+	p.closeMu.Lock()
+	close(p.events)
+	close(p.errors)
+	p.closeMu.Unlock()
+}
+
+// sendControl writes a control message to the current connection.
+func (p *Provider) sendControl(msg *controlMessage) error {
+	p.connMu.Lock()
+	conn := p.conn
+	p.connMu.Unlock()
+
+	if nil == conn {
+		return errors.New("Not connected")
+	}
+
+	return p.writeControl(conn, msg)
+}
+
+func (p *Provider) writeControl(conn *gorilla.Conn, msg *controlMessage) error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	conn.SetWriteDeadline(p.keepalive.writeDeadline())
+	return conn.WriteJSON(msg)
+}
+
+// deliver passes an event to the Listener, returning false if the provider
+// was stopped first.
+func (p *Provider) deliver(event Event) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// reportError passes an error on to whoever is reading the error channel
+// without blocking.
+func (p *Provider) reportError(phase gomainevents.ErrorPhase, messageID string, err error) {
+	p.debugPrint("Error: %s\n", err)
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	select {
+	case <-p.done:
+	case p.errors <- gomainevents.NewProviderError(phase, p.url, messageID, err):
+	default:
+	}
+}
+
+func (p *Provider) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-websocket] "+format, values...)
+	}
+}
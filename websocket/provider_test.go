@@ -0,0 +1,125 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/gomaineventstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEventServer starts a websocket server that publishes the given events
+// to every connection and then closes it.
+func newEventServer(events ...testEvent) *httptest.Server {
+	upgrader := gorilla.Upgrader{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		publisher := NewPublisher(conn)
+		for _, event := range events {
+			publisher.Publish(event)
+		}
+
+		// Wait for the client to go away
+		conn.ReadMessage()
+	}))
+}
+
+type testEvent struct {
+	name string
+}
+
+func (e testEvent) Name() string {
+	return e.name
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&ProviderConfig{URL: "ws://localhost"})
+	assert.NotNil(t, provider)
+	assert.Nil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{})
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(nil)
+	assert.Nil(t, provider)
+	assert.NotNil(t, err)
+
+	provider, err = NewProvider(&ProviderConfig{URL: "ws://localhost", Delivery: gomainevents.CompetingConsumers})
+	assert.Nil(t, provider)
+	assert.EqualError(t, err, "Delivery isn't supported: competing consumers")
+
+	provider, _ = NewProvider(&ProviderConfig{URL: "ws://localhost", Delivery: gomainevents.Broadcast})
+	assert.Equal(t, gomainevents.Broadcast, provider.Delivery())
+}
+
+func TestProviderReceivesEvents(t *testing.T) {
+	server := newEventServer(testEvent{name: "First"}, testEvent{name: "Second"})
+	defer server.Close()
+
+	provider, err := NewProvider(&ProviderConfig{URL: wsURL(server)})
+	require.Nil(t, err)
+
+	events, _ := provider.Start()
+	defer provider.Stop()
+
+	for _, name := range []string{"First", "Second"} {
+		select {
+		case event := <-events:
+			assert.Equal(t, name, event.Name())
+			assert.Equal(t, "2018-03-08 11:11:11", event.Data()["occurredOn"])
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for event")
+		}
+	}
+}
+
+func TestProviderRequeue(t *testing.T) {
+	provider, _ := NewProvider(&ProviderConfig{URL: "ws://localhost", MaximumRetryCount: 1})
+
+	err := provider.Requeue(Event{name: "Thing", retryCount: 2})
+	assert.IsType(t, &RetryAttemptsExceededError{}, err)
+
+	assert.Nil(t, provider.Requeue(Event{name: "Thing", retryCount: 0}))
+	assert.Equal(t, 4*time.Second, Event{retryCount: 1}.Delay())
+}
+
+func TestProviderRequeuesOnClock(t *testing.T) {
+	clock := gomaineventstest.NewClock(time.Now())
+	provider, err := NewProvider(&ProviderConfig{URL: "ws://localhost", Clock: clock})
+	require.Nil(t, err)
+	provider.debug = false
+
+	require.Nil(t, provider.Requeue(Event{name: "Thing"}))
+
+	clock.Advance(time.Second)
+	assert.Len(t, provider.events, 0)
+
+	clock.Advance(time.Second)
+	select {
+	case event := <-provider.events:
+		assert.Equal(t, 1, event.(Event).RetryCount())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+}
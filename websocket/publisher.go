@@ -1,28 +1,320 @@
 package websocket
 
 import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
 	gorilla "github.com/gorilla/websocket"
 	"github.com/researchsquare/gomainevents"
 )
 
+const defaultBufferSize = 1000
+
+// ErrBufferFull is returned by Publish when the publisher is disconnected and
+// has no room left to hold the event until it reconnects.
+var ErrBufferFull = errors.New("Websocket publisher buffer is full")
+
+// Publisher writes events to a single websocket connection. Publishers
+// created with NewPublisherWithConfig dial the connection themselves and,
+// when it drops, reconnect in the background while buffering events.
 type Publisher struct {
-	conn *gorilla.Conn
+	mu    sync.Mutex
+	conn  *gorilla.Conn
+	codec Codec
+
+	// Only set for publishers that dial their own connection.
+	url               string
+	header            http.Header
+	dialer            *gorilla.Dialer
+	reconnectDelay    time.Duration
+	maxReconnectDelay time.Duration
+	keepalive         *Keepalive
+
+	buffer       [][]byte
+	bufferSize   int
+	reconnecting bool
+	done         chan bool
+	closed       bool
+
+	debug bool
+}
+
+type PublisherConfig struct {
+	// URL to dial, e.g. ws://localhost:8994/events. Required
+	URL string
+
+	// Extra headers sent when dialing, e.g. for authentication.
+	Header http.Header
+
+	// Provide your own dialer. Defaults to gorilla's DefaultDialer.
+	Dialer *gorilla.Dialer
+
+	// Maximum number of events held while disconnected. Defaults to 1000.
+	BufferSize int
+
+	// Delay before the first reconnect attempt. Doubles on every failed
+	// attempt up to MaxReconnectDelay. Defaults to 1 second and 1 minute.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+
+	// Ping and timeout settings for the connection. Defaults are used
+	// when not provided.
+	Keepalive *Keepalive
+
+	// How events are encoded. Defaults to JSON text messages.
+	Codec Codec
+
+	// Negotiate permessage-deflate compression when dialing.
+	EnableCompression bool
 }
 
+// NewPublisher writes events to an existing connection. Publish fails once
+// the connection is lost.
 func NewPublisher(conn *gorilla.Conn) *Publisher {
-	return &Publisher{conn: conn}
+	return &Publisher{conn: conn, codec: JSONCodec{}, done: make(chan bool), debug: true}
 }
 
-type encodedEvent struct {
-	Name string                 `json:"name"`
-	Data map[string]interface{} `json:"data"`
+// NewPublisherWithConfig dials the configured URL in the background and
+// keeps reconnecting whenever the connection drops. Events published while
+// disconnected are buffered and sent in order once reconnected.
+func NewPublisherWithConfig(config *PublisherConfig) (*Publisher, error) {
+	if nil == config {
+		return nil, errors.New("Configuration is required")
+	}
+
+	if "" == config.URL {
+		return nil, errors.New("URL is required")
+	}
+
+	dialer := dialerWithCompression(config.Dialer, config.EnableCompression)
+
+	bufferSize := defaultBufferSize
+	if config.BufferSize > 0 {
+		bufferSize = config.BufferSize
+	}
+
+	reconnectDelay := defaultReconnectDelay
+	if config.ReconnectDelay > 0 {
+		reconnectDelay = config.ReconnectDelay
+	}
+
+	maxReconnectDelay := defaultMaxReconnectDelay
+	if config.MaxReconnectDelay > 0 {
+		maxReconnectDelay = config.MaxReconnectDelay
+	}
+
+	keepalive := config.Keepalive.withDefaults()
+
+	p := &Publisher{
+		keepalive:         &keepalive,
+		codec:             codecOrDefault(config.Codec),
+		url:               config.URL,
+		header:            config.Header,
+		dialer:            dialer,
+		reconnectDelay:    reconnectDelay,
+		maxReconnectDelay: maxReconnectDelay,
+		bufferSize:        bufferSize,
+		done:              make(chan bool),
+		debug:             true,
+	}
+
+	p.mu.Lock()
+	p.startReconnecting()
+	p.mu.Unlock()
+
+	return p, nil
 }
 
 func (p *Publisher) Publish(event gomainevents.Event) error {
-	evt := &encodedEvent{
+	message, err := p.codec.Encode(&Frame{
 		Name: event.Name(),
 		Data: event.Data(),
+	})
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return errors.New("Websocket publisher is closed")
+	}
+
+	if nil != p.conn && len(p.buffer) == 0 {
+		err := p.write(p.conn, message)
+		if err == nil {
+			return nil
+		}
+
+		p.conn.Close()
+		p.conn = nil
+
+		if "" == p.url {
+			return err
+		}
+
+		p.debugPrint("Write failed, reconnecting: %s\n", err)
+	}
+
+	if "" == p.url {
+		return errors.New("Websocket connection is closed")
+	}
+
+	if len(p.buffer) >= p.bufferSize {
+		return ErrBufferFull
+	}
+
+	p.buffer = append(p.buffer, message)
+	p.startReconnecting()
+
+	return nil
+}
+
+// Buffered returns the number of events waiting to be sent.
+func (p *Publisher) Buffered() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.buffer)
+}
+
+// Close stops reconnecting and closes the connection. Buffered events are
+// discarded.
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+
+	p.closed = true
+	close(p.done)
+
+	if nil != p.conn {
+		return p.conn.Close()
+	}
+
+	return nil
+}
+
+// startReconnecting starts the reconnect loop unless it is already running.
+// Must be called with the lock held.
+func (p *Publisher) startReconnecting() {
+	if p.reconnecting || p.closed {
+		return
+	}
+
+	p.reconnecting = true
+	go p.reconnect()
+}
+
+func (p *Publisher) reconnect() {
+	delay := p.reconnectDelay
+
+	for {
+		conn, _, err := p.dialer.Dial(p.url, p.header)
+		if err == nil && p.connected(conn) {
+			return
+		}
+
+		if err != nil {
+			p.debugPrint("Unable to connect to %s: %s\n", p.url, err)
+		}
+
+		select {
+		case <-p.done:
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > p.maxReconnectDelay {
+			delay = p.maxReconnectDelay
+		}
+	}
+}
+
+// connected flushes the buffer to a new connection. It returns false if the
+// connection failed before the buffer was drained.
+func (p *Publisher) connected(conn *gorilla.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		conn.Close()
+		return true
 	}
 
-	return p.conn.WriteJSON(evt)
+	for len(p.buffer) > 0 {
+		if err := p.write(conn, p.buffer[0]); err != nil {
+			p.debugPrint("Write failed while flushing buffer: %s\n", err)
+			conn.Close()
+			return false
+		}
+
+		p.buffer = p.buffer[1:]
+	}
+
+	p.debugPrint("Connected to %s\n", p.url)
+	p.conn = conn
+	p.reconnecting = false
+
+	go p.watch(conn)
+
+	return true
+}
+
+// write sends an encoded event, applying the write timeout if one is
+// configured.
+func (p *Publisher) write(conn *gorilla.Conn, message []byte) error {
+	if nil != p.keepalive {
+		conn.SetWriteDeadline(p.keepalive.writeDeadline())
+	}
+
+	return conn.WriteMessage(p.codec.MessageType(), message)
+}
+
+// watch pings a dialed connection and reads from it so that a dead peer is
+// noticed even when nothing is being published. The publisher reconnects
+// once the connection fails.
+func (p *Publisher) watch(conn *gorilla.Conn) {
+	stop := make(chan bool)
+	defer close(stop)
+
+	go p.keepalive.ping(conn, stop)
+	p.keepalive.watch(conn)
+
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			break
+		}
+
+		p.keepalive.extend(conn)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != conn {
+		return
+	}
+
+	conn.Close()
+	p.conn = nil
+
+	if !p.closed {
+		p.debugPrint("Connection lost, reconnecting...\n")
+		p.startReconnecting()
+	}
+}
+
+func (p *Publisher) debugPrint(format string, values ...interface{}) {
+	if p.debug {
+		log.Printf("[gomainevents-websocket] "+format, values...)
+	}
 }
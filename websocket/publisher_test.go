@@ -0,0 +1,63 @@
+package websocket
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisherBuffersUntilConnected(t *testing.T) {
+	// Reserve an address, but don't serve on it yet
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	publisher, err := NewPublisherWithConfig(&PublisherConfig{
+		URL:               "ws://" + addr,
+		BufferSize:        2,
+		ReconnectDelay:    10 * time.Millisecond,
+		MaxReconnectDelay: 50 * time.Millisecond,
+	})
+	require.Nil(t, err)
+	defer publisher.Close()
+
+	assert.Nil(t, publisher.Publish(testEvent{name: "First"}))
+	assert.Nil(t, publisher.Publish(testEvent{name: "Second"}))
+	assert.Equal(t, ErrBufferFull, publisher.Publish(testEvent{name: "Third"}))
+	assert.Equal(t, 2, publisher.Buffered())
+
+	// Bring the server up and let the publisher find it
+	hub := NewHub(nil)
+	listener, err = net.Listen("tcp", addr)
+	require.Nil(t, err)
+	server := &httptest.Server{Listener: listener, Config: &http.Server{Handler: hub}}
+	server.Start()
+	defer server.Close()
+	defer hub.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for publisher.Buffered() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Buffer was never flushed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Nil(t, publisher.Publish(testEvent{name: "Third"}))
+}
+
+func TestPublisherWithConfigValidation(t *testing.T) {
+	publisher, err := NewPublisherWithConfig(nil)
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+
+	publisher, err = NewPublisherWithConfig(&PublisherConfig{})
+	assert.Nil(t, publisher)
+	assert.NotNil(t, err)
+}
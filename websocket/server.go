@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/researchsquare/gomainevents"
+)
+
+const defaultPath = "/events"
+
+// Server is a ready-made websocket endpoint for streaming domain events to
+// browsers and other clients. It owns the upgrader, the connections and
+// their read/write pumps; applications mount it on a mux and publish to it.
+type Server struct {
+	hub  *Hub
+	path string
+}
+
+type ServerConfig struct {
+	// Path to serve the websocket endpoint on. Defaults to /events.
+	Path string
+
+	// Connection settings such as keepalive, codec and authentication.
+	HubConfig
+}
+
+func NewServer(config *ServerConfig) *Server {
+	if nil == config {
+		config = &ServerConfig{}
+	}
+
+	path := defaultPath
+	if "" != config.Path {
+		path = config.Path
+	}
+
+	return &Server{
+		hub:  NewHub(&config.HubConfig),
+		path: path,
+	}
+}
+
+// Serve registers the websocket endpoint on the mux.
+func (s *Server) Serve(mux *http.ServeMux) {
+	mux.Handle(s.path, s.hub)
+}
+
+// Publisher returns the Publisher that sends events to every connected
+// client.
+func (s *Server) Publisher() gomainevents.Publisher {
+	return s.hub
+}
+
+// Shutdown gracefully closes every connection. See Hub.Shutdown.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.hub.Shutdown(ctx)
+}
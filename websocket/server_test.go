@@ -0,0 +1,50 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer(&ServerConfig{Path: "/ws"})
+	mux := http.NewServeMux()
+	server.Serve(mux)
+
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	conn, _, err := gorilla.DefaultDialer.Dial(wsURL(httpServer)+"/ws", nil)
+	require.Nil(t, err)
+	defer conn.Close()
+	waitForClients(t, server.hub, 1)
+
+	require.Nil(t, server.Publisher().Publish(testEvent{name: "Thing"}))
+
+	// Queued events are delivered before the close frame
+	shutdown := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- server.Shutdown(ctx)
+	}()
+
+	_, message, err := conn.ReadMessage()
+	require.Nil(t, err)
+	assert.Contains(t, string(message), `"name":"Thing"`)
+
+	_, _, err = conn.ReadMessage()
+	assert.True(t, gorilla.IsCloseError(err, gorilla.CloseGoingAway))
+	assert.Nil(t, <-shutdown)
+
+	// No new connections once shut down
+	_, resp, err := gorilla.DefaultDialer.Dial(wsURL(httpServer)+"/ws", nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
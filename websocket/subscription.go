@@ -0,0 +1,97 @@
+package websocket
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Control message actions clients can send to a Hub.
+const (
+	actionSubscribe   = "subscribe"
+	actionUnsubscribe = "unsubscribe"
+)
+
+// allEvents can be used in place of an event name to subscribe to every
+// event, optionally filtered on data fields.
+const allEvents = "*"
+
+// controlMessage is sent by clients to change what they receive, e.g.
+//
+//	{"action": "subscribe", "events": ["ThingHappened"], "filters": {"userId": 12}}
+//
+// Filters are matched against the event data and apply to the events listed
+// in the same message. IDs, Delay and Subscriber are used by the
+// acknowledgement protocol.
+type controlMessage struct {
+	Action     string                 `json:"action"`
+	Events     []string               `json:"events,omitempty"`
+	Filters    map[string]interface{} `json:"filters,omitempty"`
+	IDs        []string               `json:"ids,omitempty"`
+	Delay      int                    `json:"delay,omitempty"`
+	Subscriber string                 `json:"subscriber,omitempty"`
+}
+
+// subscriptions tracks which events a connection wants. Connections that
+// never subscribe receive everything.
+type subscriptions struct {
+	mu      sync.RWMutex
+	active  bool
+	filters map[string]map[string]interface{}
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{filters: make(map[string]map[string]interface{})}
+}
+
+// apply updates the subscriptions from a client's control message.
+func (s *subscriptions) apply(msg *controlMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch msg.Action {
+	case actionSubscribe:
+		s.active = true
+		for _, name := range msg.Events {
+			s.filters[name] = msg.Filters
+		}
+	case actionUnsubscribe:
+		for _, name := range msg.Events {
+			delete(s.filters, name)
+		}
+	default:
+		return fmt.Errorf("Unknown action: %q", msg.Action)
+	}
+
+	return nil
+}
+
+// matches reports whether an event should be sent to the connection.
+func (s *subscriptions) matches(name string, data map[string]interface{}) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.active {
+		return true
+	}
+
+	for _, key := range []string{name, allEvents} {
+		if filters, ok := s.filters[key]; ok && matchesFilters(filters, data) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesFilters compares filter values to data fields by their string form,
+// so that a filter of 12 matches data decoded as 12.0.
+func matchesFilters(filters map[string]interface{}, data map[string]interface{}) bool {
+	for field, expected := range filters {
+		actual, ok := data[field]
+		if !ok || fmt.Sprint(actual) != fmt.Sprint(expected) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func control(t *testing.T, raw string) *controlMessage {
+	msg := &controlMessage{}
+	require.Nil(t, json.Unmarshal([]byte(raw), msg))
+	return msg
+}
+
+func TestSubscriptionsMatch(t *testing.T) {
+	subs := newSubscriptions()
+	data := map[string]interface{}{"userId": 12.0}
+
+	// Everything until the first subscribe
+	assert.True(t, subs.matches("Anything", data))
+
+	require.Nil(t, subs.apply(control(t, `{"action":"subscribe","events":["Created"]}`)))
+	require.Nil(t, subs.apply(control(t, `{"action":"subscribe","events":["Updated"],"filters":{"userId":12}}`)))
+
+	assert.True(t, subs.matches("Created", data))
+	assert.True(t, subs.matches("Updated", data))
+	assert.False(t, subs.matches("Updated", map[string]interface{}{"userId": 13.0}))
+	assert.False(t, subs.matches("Deleted", data))
+
+	require.Nil(t, subs.apply(control(t, `{"action":"unsubscribe","events":["Created"]}`)))
+	assert.False(t, subs.matches("Created", data))
+
+	require.Nil(t, subs.apply(control(t, `{"action":"subscribe","events":["*"],"filters":{"userId":"12"}}`)))
+	assert.True(t, subs.matches("Deleted", data))
+
+	assert.NotNil(t, subs.apply(control(t, `{"action":"dance"}`)))
+}
+
+func TestHubOnlySendsSubscribedEvents(t *testing.T) {
+	hub := NewHub(nil)
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	defer hub.Close()
+
+	conn := dial(t, server)
+	defer conn.Close()
+	waitForClients(t, hub, 1)
+
+	require.Nil(t, conn.WriteJSON(map[string]interface{}{"action": "subscribe", "events": []string{"Wanted"}}))
+
+	// Give the hub a moment to process the subscription
+	deadline := time.Now().Add(5 * time.Second)
+	for !hub.clientsSubscribed() {
+		if time.Now().After(deadline) {
+			t.Fatal("Subscription was never processed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	require.Nil(t, hub.Publish(testEvent{name: "Unwanted"}))
+	require.Nil(t, hub.Publish(testEvent{name: "Wanted"}))
+
+	_, message, err := conn.ReadMessage()
+	require.Nil(t, err)
+	assert.Contains(t, string(message), `"name":"Wanted"`)
+}
+
+// clientsSubscribed reports whether every client has subscribed to something.
+func (h *Hub) clientsSubscribed() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.clients {
+		c.subscriptions.mu.RLock()
+		active := c.subscriptions.active
+		c.subscriptions.mu.RUnlock()
+
+		if !active {
+			return false
+		}
+	}
+
+	return true
+}
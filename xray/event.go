@@ -0,0 +1,59 @@
+package xray
+
+import (
+	"context"
+
+	awsxray "github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/researchsquare/gomainevents"
+)
+
+// tracedEvent is an event that carries the context of the segment it was
+// published or handled in.
+type tracedEvent struct {
+	gomainevents.Event
+	ctx context.Context
+}
+
+// WithContext returns an event that is published as part of the segment in
+// ctx, so that its handlers join the same trace:
+//
+//	publisher.Publish(xray.WithContext(ctx, event))
+//
+// Events without a segment in ctx are returned as they are.
+func WithContext(ctx context.Context, event gomainevents.Event) gomainevents.Event {
+	if nil == awsxray.GetSegment(ctx) {
+		return event
+	}
+
+	return tracedEvent{Event: event, ctx: ctx}
+}
+
+// Context returns the context a Handler wrapped the event with, carrying the
+// handler's subsegment, so that calls the handler makes are traced too.
+// Other events get context.Background().
+func Context(event gomainevents.Event) context.Context {
+	for nil != event {
+		if traced, ok := event.(tracedEvent); ok {
+			return traced.ctx
+		}
+
+		wrapper, ok := event.(interface{ Unwrap() gomainevents.Event })
+		if !ok {
+			break
+		}
+
+		event = wrapper.Unwrap()
+	}
+
+	return context.Background()
+}
+
+// TraceHeader returns the header of the segment the event carries.
+func (e tracedEvent) TraceHeader() string {
+	return awsxray.GetSegment(e.ctx).DownstreamHeader().String()
+}
+
+// Unwrap returns the original event.
+func (e tracedEvent) Unwrap() gomainevents.Event {
+	return e.Event
+}
@@ -0,0 +1,59 @@
+package xray
+
+import (
+	"context"
+
+	"github.com/aws/aws-xray-sdk-go/header"
+	awsxray "github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/researchsquare/gomainevents"
+)
+
+// Handler wraps an EventHandler in a segment named after the service, which
+// continues the trace the event was published in if it carries a trace
+// header, and a subsegment named after the event around the handler itself.
+// Errors returned by the handler are recorded on both.
+//
+//	listener.RegisterHandler("ThingHappened", xray.Handler("billing", handleThing))
+//
+// The handler is passed an event carrying the subsegment: use Context to get
+// it, and WithContext on events it publishes in turn.
+func Handler(service string, fn gomainevents.EventHandler) gomainevents.EventHandler {
+	return func(event gomainevents.Event) error {
+		ctx, segment := awsxray.BeginSegment(context.Background(), service)
+
+		if traceHeader := TraceHeader(event); "" != traceHeader {
+			continueTrace(segment, header.FromString(traceHeader))
+		}
+
+		ctx, subsegment := awsxray.BeginSubsegment(ctx, event.Name())
+		subsegment.AddAnnotation("event", event.Name())
+
+		err := fn(tracedEvent{Event: event, ctx: ctx})
+
+		subsegment.Close(err)
+		segment.Close(err)
+
+		return err
+	}
+}
+
+// continueTrace makes a new segment part of the trace in h, keeping the
+// sampling decision made upstream when there was one.
+func continueTrace(segment *awsxray.Segment, h *header.Header) {
+	if "" == h.TraceID {
+		return
+	}
+
+	segment.Lock()
+	defer segment.Unlock()
+
+	segment.TraceID = h.TraceID
+	segment.ParentID = h.ParentID
+
+	switch h.SamplingDecision {
+	case header.Sampled:
+		segment.Sampled = true
+	case header.NotSampled:
+		segment.Sampled = false
+	}
+}
@@ -0,0 +1,66 @@
+package xray
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-xray-sdk-go/header"
+	awsxray "github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/researchsquare/gomainevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerContinuesTrace(t *testing.T) {
+	var handled gomainevents.Event
+	handler := Handler("billing", func(event gomainevents.Event) error {
+		handled = event
+		return nil
+	})
+
+	require.Nil(t, handler(testEvent{traceHeader: testHeader}))
+	require.NotNil(t, handled)
+
+	// The handler runs in a subsegment of the upstream trace
+	assert.NotNil(t, awsxray.GetSegment(Context(handled)))
+	downstream := header.FromString(TraceHeader(handled))
+	assert.Equal(t, "1-5759e988-bd862e3fe1be46a994272793", downstream.TraceID)
+	assert.NotEqual(t, "53995c3f42cd8ad8", downstream.ParentID)
+	assert.Equal(t, header.Sampled, downstream.SamplingDecision)
+
+	// and still sees the original event
+	assert.Equal(t, "Domain\\Event", handled.Name())
+	assert.Equal(t, "2018-03-08 11:11:11", handled.Data()["occurredOn"])
+}
+
+func TestHandlerKeepsSamplingDecision(t *testing.T) {
+	var handled gomainevents.Event
+	handler := Handler("billing", func(event gomainevents.Event) error {
+		handled = event
+		return nil
+	})
+
+	require.Nil(t, handler(testEvent{traceHeader: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0"}))
+	assert.Equal(t, header.NotSampled, header.FromString(TraceHeader(handled)).SamplingDecision)
+}
+
+func TestHandlerStartsTrace(t *testing.T) {
+	var handled gomainevents.Event
+	handler := Handler("billing", func(event gomainevents.Event) error {
+		handled = event
+		return nil
+	})
+
+	require.Nil(t, handler(testEvent{}))
+	assert.True(t, strings.HasPrefix(TraceHeader(handled), "Root=1-"))
+	assert.NotContains(t, TraceHeader(handled), "5759e988")
+}
+
+func TestHandlerReturnsError(t *testing.T) {
+	handler := Handler("billing", func(event gomainevents.Event) error {
+		return errors.New("Nope")
+	})
+
+	assert.EqualError(t, handler(testEvent{traceHeader: testHeader}), "Nope")
+}
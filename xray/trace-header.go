@@ -0,0 +1,81 @@
+package xray
+
+import (
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/sns"
+)
+
+// AttributeName is the message attribute trace headers are sent in. SQS
+// also passes it on as a system attribute when tracing is active.
+const AttributeName = "AWSTraceHeader"
+
+// TraceHeader returns the X-Ray trace header an event carries, e.g. one
+// received from SQS or wrapped with WithContext, or an empty string if it
+// doesn't carry one. Events that wrap another event, with an Unwrap method,
+// are looked through.
+func TraceHeader(event gomainevents.Event) string {
+	for nil != event {
+		if traced, ok := event.(interface{ TraceHeader() string }); ok {
+			if header := traced.TraceHeader(); "" != header {
+				return header
+			}
+		}
+
+		wrapper, ok := event.(interface{ Unwrap() gomainevents.Event })
+		if !ok {
+			break
+		}
+
+		event = wrapper.Unwrap()
+	}
+
+	return ""
+}
+
+// OptionsMapper wraps an SNS OptionsMapper, which may be nil, adding the
+// event's trace header to its message attributes:
+//
+//	sns.NewPublisher(&sns.PublisherConfig{
+//		...
+//		OptionsMapper: xray.OptionsMapper(nil),
+//	})
+func OptionsMapper(next sns.OptionsMapper) sns.OptionsMapper {
+	return func(event gomainevents.Event) *sns.PublishOptions {
+		options := &sns.PublishOptions{}
+		if nil != next {
+			if mapped := next(event); nil != mapped {
+				options = mapped
+			}
+		}
+
+		header := TraceHeader(event)
+		if "" == header {
+			return options
+		}
+
+		attributes := map[string]string{AttributeName: header}
+		for key, value := range options.MessageAttributes {
+			if key != AttributeName {
+				attributes[key] = value
+			}
+		}
+
+		return &sns.PublishOptions{Subject: options.Subject, MessageAttributes: attributes}
+	}
+}
+
+// MessageAttributes returns the event's trace header as message attributes,
+// for the SQS Publisher:
+//
+//	sqs.NewPublisher(&sqs.PublisherConfig{
+//		...
+//		MessageAttributes: xray.MessageAttributes,
+//	})
+func MessageAttributes(event gomainevents.Event) map[string]string {
+	header := TraceHeader(event)
+	if "" == header {
+		return nil
+	}
+
+	return map[string]string{AttributeName: header}
+}
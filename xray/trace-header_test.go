@@ -0,0 +1,85 @@
+package xray
+
+import (
+	"context"
+	"testing"
+
+	awsxray "github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/researchsquare/gomainevents"
+	"github.com/researchsquare/gomainevents/sns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHeader = "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"
+
+type testEvent struct {
+	traceHeader string
+}
+
+func (e testEvent) Name() string {
+	return "Domain\\Event"
+}
+
+func (e testEvent) Data() map[string]interface{} {
+	return map[string]interface{}{"occurredOn": "2018-03-08 11:11:11"}
+}
+
+func (e testEvent) TraceHeader() string {
+	return e.traceHeader
+}
+
+type wrappedEvent struct {
+	gomainevents.Event
+}
+
+func (e wrappedEvent) Unwrap() gomainevents.Event {
+	return e.Event
+}
+
+func TestTraceHeader(t *testing.T) {
+	assert.Equal(t, testHeader, TraceHeader(testEvent{traceHeader: testHeader}))
+	assert.Equal(t, testHeader, TraceHeader(wrappedEvent{testEvent{traceHeader: testHeader}}))
+	assert.Equal(t, "", TraceHeader(testEvent{}))
+}
+
+func TestWithContext(t *testing.T) {
+	event := testEvent{}
+
+	// Nothing to trace
+	assert.Equal(t, event, WithContext(context.Background(), event))
+
+	ctx, segment := awsxray.BeginSegment(context.Background(), "test")
+	defer segment.Close(nil)
+
+	traced := WithContext(ctx, event)
+	assert.Equal(t, segment.DownstreamHeader().String(), TraceHeader(traced))
+	assert.Equal(t, ctx, Context(traced))
+	assert.Equal(t, context.Background(), Context(event))
+}
+
+func TestOptionsMapper(t *testing.T) {
+	mapper := OptionsMapper(func(event gomainevents.Event) *sns.PublishOptions {
+		return &sns.PublishOptions{
+			Subject:           "Something happened",
+			MessageAttributes: map[string]string{"tenant": "acme"},
+		}
+	})
+
+	options := mapper(testEvent{traceHeader: testHeader})
+	assert.Equal(t, "Something happened", options.Subject)
+	assert.Equal(t, map[string]string{"tenant": "acme", AttributeName: testHeader}, options.MessageAttributes)
+
+	// Untraced events are left alone
+	options = mapper(testEvent{})
+	assert.Equal(t, map[string]string{"tenant": "acme"}, options.MessageAttributes)
+
+	options = OptionsMapper(nil)(testEvent{traceHeader: testHeader})
+	require.NotNil(t, options)
+	assert.Equal(t, map[string]string{AttributeName: testHeader}, options.MessageAttributes)
+}
+
+func TestMessageAttributes(t *testing.T) {
+	assert.Equal(t, map[string]string{AttributeName: testHeader}, MessageAttributes(testEvent{traceHeader: testHeader}))
+	assert.Nil(t, MessageAttributes(testEvent{}))
+}